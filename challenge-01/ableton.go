@@ -0,0 +1,342 @@
+package drum
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Ableton Live places notes in beats, a step is a sixteenth.
+const abletonStepsPerBeat = 4
+
+// abletonFirstKey is the key of the first pad of a drum rack, C1.
+const abletonFirstKey = 36
+
+// ErrNoDrumClips is returned by ReadAbleton for Live sets without MIDI clips
+// on tracks with a drum rack.
+var ErrNoDrumClips = errors.New("no drum clips")
+
+// abletonSet is the subset of an Ableton Live set (.als) read by
+// ReadAbleton and written by WriteAbleton.
+type abletonSet struct {
+	XMLName      xml.Name       `xml:"Ableton"`
+	MajorVersion string         `xml:"MajorVersion,attr"`
+	MinorVersion string         `xml:"MinorVersion,attr"`
+	Creator      string         `xml:"Creator,attr"`
+	Tracks       []abletonTrack `xml:"LiveSet>Tracks>MidiTrack"`
+	Tempo        *abletonValue  `xml:"LiveSet>MasterTrack>DeviceChain>Mixer>Tempo>Manual"`
+	MainTempo    *abletonValue  `xml:"LiveSet>MainTrack>DeviceChain>Mixer>Tempo>Manual"` // Live 12
+}
+
+// abletonValue is the element Live stores most values in, <Name Value="x"/>.
+type abletonValue struct {
+	Value string `xml:"Value,attr"`
+}
+
+func (v abletonValue) float() float64 {
+	f, _ := strconv.ParseFloat(v.Value, 64)
+	return f
+}
+
+type abletonTrack struct {
+	ID           int             `xml:"Id,attr"`
+	Name         abletonValue    `xml:"Name>EffectiveName"`
+	SessionClips []abletonClip   `xml:"DeviceChain>MainSequencer>ClipSlotList>ClipSlot>ClipSlot>Value>MidiClip"`
+	Clips        []abletonClip   `xml:"DeviceChain>MainSequencer>Sample>ArrangerAutomation>Events>MidiClip"`
+	Pads         []abletonBranch `xml:"DeviceChain>DeviceChain>Devices>DrumGroupDevice>Branches>DrumBranch"`
+}
+
+// abletonBranch is a pad of a drum rack. Live stores the MIDI key it
+// receives as 128 minus the key.
+type abletonBranch struct {
+	ID            int          `xml:"Id,attr"`
+	Name          abletonValue `xml:"Name>EffectiveName"`
+	ReceivingNote abletonValue `xml:"BranchInfo>ReceivingNote"`
+}
+
+type abletonClip struct {
+	ID        int               `xml:"Id,attr"`
+	Time      float64           `xml:"Time,attr"`
+	End       abletonValue      `xml:"CurrentEnd"`
+	LoopStart abletonValue      `xml:"Loop>LoopStart"`
+	LoopEnd   abletonValue      `xml:"Loop>LoopEnd"`
+	Name      abletonValue      `xml:"Name"`
+	KeyTracks []abletonKeyTrack `xml:"Notes>KeyTracks>KeyTrack"`
+}
+
+type abletonKeyTrack struct {
+	ID    int           `xml:"Id,attr"`
+	Notes []abletonNote `xml:"Notes>MidiNoteEvent"`
+	Key   abletonValue  `xml:"MidiKey"`
+}
+
+type abletonNote struct {
+	Time        float64 `xml:"Time,attr"`
+	Duration    float64 `xml:"Duration,attr"`
+	Velocity    float64 `xml:"Velocity,attr"`
+	OffVelocity float64 `xml:"OffVelocity,attr"`
+	Probability string  `xml:"Probability,attr,omitempty"` // Live 11 and later
+	IsEnabled   string  `xml:"IsEnabled,attr"`
+}
+
+// ReadAbleton reads the drum clips of an Ableton Live set (.als), gzip
+// compressed as saved by Live or uncompressed XML. Every MIDI clip of a
+// track with a drum rack, in the session and the arrangement, becomes a
+// pattern with the clip name, or the track name for unnamed clips, as
+// title and the tempo of the set. The keys of the clip become tracks with
+// the key as id, named after the pad of the drum rack playing it or by
+// General MIDI.
+//
+// Notes are placed on the closest sixteenth and the difference is kept as
+// micro timing offset, velocities and probabilities are kept, disabled
+// notes are skipped. The loop of the clip sets the length, clips longer
+// than MaxBars bars of 4/4 are cut and shorter than a bar get a matching
+// time signature. The velocities and timing of a step are shared by the
+// bars of a track, the ones of the first note win.
+func ReadAbleton(r io.Reader) ([]*Pattern, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("parse als: %v", err)
+		}
+		defer zr.Close()
+		r = zr
+	} else {
+		r = br
+	}
+	var set abletonSet
+	if err := xml.NewDecoder(r).Decode(&set); err != nil {
+		return nil, fmt.Errorf("parse als: %v", err)
+	}
+	tempo := float32(120)
+	for _, v := range []*abletonValue{set.Tempo, set.MainTempo} {
+		if v != nil && v.float() > 0 {
+			tempo = float32(v.float())
+		}
+	}
+	var patterns []*Pattern
+	for _, at := range set.Tracks {
+		if len(at.Pads) == 0 {
+			continue
+		}
+		pads := make(map[int]string)
+		for _, b := range at.Pads {
+			if key := 128 - int(b.ReceivingNote.float()); b.Name.Value != "" {
+				pads[key] = b.Name.Value
+			}
+		}
+		for _, c := range append(at.SessionClips, at.Clips...) {
+			p, err := c.pattern(tempo, pads)
+			if err != nil {
+				return nil, fmt.Errorf("parse als clip %q of track %q: %v", c.Name.Value, at.Name.Value, err)
+			}
+			title := c.Name.Value
+			if title == "" {
+				title = at.Name.Value
+			}
+			if err := p.SetMetadata(Metadata{Title: title}); err != nil {
+				return nil, fmt.Errorf("parse als: %v", err)
+			}
+			patterns = append(patterns, p)
+		}
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("parse als: %w", ErrNoDrumClips)
+	}
+	return patterns, nil
+}
+
+// pattern returns the pattern of the clip with the tracks named by pads.
+func (c abletonClip) pattern(tempo float32, pads map[int]string) (*Pattern, error) {
+	start, end := c.LoopStart.float(), c.LoopEnd.float()
+	if end <= start {
+		start, end = 0, c.End.float()-c.Time
+	}
+	steps := int(math.Round((end - start) * abletonStepsPerBeat))
+	if steps < 1 {
+		return nil, errors.New("empty clip")
+	}
+	steps = min(steps, MaxBars*stepsLength)
+	bars := (steps + stepsLength - 1) / stepsLength
+
+	var tracks []*Track
+	var enabled [][MaxBars]Steps
+	for _, kt := range c.KeyTracks {
+		key := int(kt.Key.float())
+		if key < 0 || key > 127 {
+			return nil, fmt.Errorf("invalid key %d", key)
+		}
+		name, ok := pads[key]
+		if !ok {
+			if name, ok = GMNoteName(uint8(key)); !ok {
+				name = fmt.Sprintf("note %d", key)
+			}
+		}
+		var t *Track
+		var on [MaxBars]Steps
+		var seen [stepsLength]bool
+		for _, n := range kt.Notes {
+			if n.IsEnabled == "false" {
+				continue
+			}
+			pos := (n.Time - start) * abletonStepsPerBeat
+			n16 := int(math.Round(pos))
+			if n16 < 0 || n16 >= steps {
+				continue
+			}
+			if t == nil {
+				var err error
+				if t, err = NewTrack(uint32(key), name, Steps{}); err != nil {
+					return nil, err
+				}
+			}
+			step := n16 % stepsLength
+			on[n16/stepsLength][step] = true
+			if seen[step] {
+				continue
+			}
+			seen[step] = true
+			v := uint8(math.Round(math.Max(1, math.Min(MaxVelocity, n.Velocity))))
+			t.SetVelocity(step, v)
+			offset := int(math.Round((pos - float64(n16)) * ticksPerStep))
+			t.timing[step] = int8(clamp(offset, -maxTimingTicks, maxTimingTicks))
+			if n.Probability != "" {
+				if prob, err := strconv.ParseFloat(n.Probability, 64); err == nil && prob < 1 {
+					t.SetProbability(step, uint8(math.Max(1, math.Round(prob*100))))
+				}
+			}
+		}
+		if t != nil {
+			t.steps = on[0]
+			tracks = append(tracks, t)
+			enabled = append(enabled, on)
+		}
+	}
+	p, err := NewPattern("als", tempo, tracks...)
+	if err != nil {
+		return nil, err
+	}
+	if steps < stepsLength {
+		p.SetTimeSignature(timeSignatureOfSteps(steps))
+	}
+	for bar := 1; bar < bars; bar++ {
+		if _, err := p.AddBar(0); err != nil {
+			return nil, err
+		}
+		for i, t := range p.tracks {
+			*t.barSteps(bar) = enabled[i][bar]
+		}
+	}
+	return p, nil
+}
+
+// abletonKeys returns the MIDI key of every track: its General MIDI note
+// if the name resolves to one not taken by an earlier track, else the
+// lowest free key from 36 on.
+func abletonKeys(p *Pattern) []int {
+	keys := make([]int, len(p.tracks))
+	used := make(map[int]bool)
+	for i, t := range p.tracks {
+		keys[i] = -1
+		if note, ok := ResolveGMNote(t.name); ok && !used[int(note)] {
+			keys[i] = int(note)
+			used[int(note)] = true
+		}
+	}
+	next := abletonFirstKey
+	for i := range keys {
+		for keys[i] < 0 {
+			if !used[next%128] {
+				keys[i] = next % 128
+				used[keys[i]] = true
+			}
+			next++
+		}
+	}
+	return keys
+}
+
+// WriteAbleton writes the pattern as minimal uncompressed Ableton Live set
+// XML to w, read back by ReadAbleton: a MIDI track with a drum rack with a
+// pad per track and a session clip with all bars of the pattern, named
+// after its title. Tracks are played by their General MIDI note or by free
+// keys from 36 on. Micro timing, swing, velocities, probabilities and
+// ratchets are written, conditions are not part of the format. The set is
+// not meant to be opened as project but to copy its clip and pad names
+// from, or to be read by tools.
+func WriteAbleton(w io.Writer, p *Pattern) error {
+	name := p.meta.Title
+	if name == "" {
+		name = "pattern"
+	}
+	keys := abletonKeys(p)
+	beats := float64(p.Bars()*p.BarSteps()) / abletonStepsPerBeat
+	length := strconv.FormatFloat(beats, 'g', -1, 64)
+	clip := abletonClip{
+		End:       abletonValue{length},
+		LoopStart: abletonValue{"0"},
+		LoopEnd:   abletonValue{length},
+		Name:      abletonValue{name},
+	}
+	track := abletonTrack{Name: abletonValue{"Drums"}}
+	swing := swingTicks(p.swing)
+	barSteps := p.BarSteps()
+	for i, t := range p.tracks {
+		kt := abletonKeyTrack{ID: i, Key: abletonValue{strconv.Itoa(keys[i])}}
+		for bar := 0; bar < p.Bars(); bar++ {
+			for s := 0; s < barSteps; s++ {
+				n := bar*barSteps + s
+				step := p.trackStep(t, n)
+				if !t.barSteps(bar)[step] {
+					continue
+				}
+				tick := n*ticksPerStep + t.offset(step)
+				if s%2 == 1 {
+					tick += swing
+				}
+				ratchet := t.Ratchet(step)
+				for r := 0; r < ratchet; r++ {
+					at := float64(max(tick, 0)+r*ticksPerStep/ratchet) / ticksPerStep
+					kt.Notes = append(kt.Notes, abletonNote{
+						Time:        at / abletonStepsPerBeat,
+						Duration:    1 / float64(abletonStepsPerBeat*ratchet),
+						Velocity:    float64(t.Velocity(step)),
+						OffVelocity: 64,
+						Probability: strconv.FormatFloat(float64(t.Probability(step))/100, 'g', -1, 64),
+						IsEnabled:   "true",
+					})
+				}
+			}
+		}
+		clip.KeyTracks = append(clip.KeyTracks, kt)
+		track.Pads = append(track.Pads, abletonBranch{
+			ID:            i,
+			Name:          abletonValue{t.name},
+			ReceivingNote: abletonValue{strconv.Itoa(128 - keys[i])},
+		})
+	}
+	track.SessionClips = []abletonClip{clip}
+	set := abletonSet{
+		MajorVersion: "5",
+		MinorVersion: "11.0_433",
+		Creator:      "Ableton Live 11.0",
+		Tracks:       []abletonTrack{track},
+		Tempo:        &abletonValue{strconv.FormatFloat(tempo64(p.tempo), 'g', -1, 64)},
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(set); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
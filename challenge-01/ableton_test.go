@@ -0,0 +1,130 @@
+package drum
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestAbletonRoundTrip(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.tracks[0].SetVelocity(0, 64)
+	p.tracks[1].SetProbability(4, 50)
+	if _, err := p.AddBar(0); err != nil {
+		t.Fatal(err)
+	}
+	p.tracks[1].SetBarStep(1, 14, true)
+	if err := p.SetMetadata(Metadata{Title: "four on the floor"}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteAbleton(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	patterns, err := ReadAbleton(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patterns) != 1 {
+		t.Fatalf("Expected 1 pattern but got %d", len(patterns))
+	}
+	got := patterns[0]
+	if got.Metadata().Title != "four on the floor" || got.tempo != p.tempo || got.Bars() != 2 || len(got.tracks) != len(p.tracks) {
+		t.Fatalf("unexpected pattern %v", got)
+	}
+	for i, tr := range p.tracks {
+		g := got.tracks[i]
+		if g.name != tr.name || g.steps != tr.steps || *g.barSteps(1) != *tr.barSteps(1) {
+			t.Errorf("Expected track %v but got %v", tr, g)
+		}
+	}
+	// kick and snare are played by their General MIDI notes
+	if got.tracks[0].id != 36 || got.tracks[1].id != 38 {
+		t.Errorf("Expected the General MIDI notes as ids but got %d and %d", got.tracks[0].id, got.tracks[1].id)
+	}
+	if got.tracks[0].Velocity(0) != 64 || got.tracks[1].Probability(4) != 50 {
+		t.Errorf("Expected velocity and probability kept but got %d and %d", got.tracks[0].Velocity(0), got.tracks[1].Probability(4))
+	}
+}
+
+const abletonSetXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Ableton MajorVersion="5" MinorVersion="11.0_433" Creator="Ableton Live 11.0">
+	<LiveSet>
+		<Tracks>
+			<AudioTrack Id="1"/>
+			<MidiTrack Id="2">
+				<Name><EffectiveName Value="808 Rack"/></Name>
+				<DeviceChain>
+					<MainSequencer>
+						<ClipSlotList>
+							<ClipSlot Id="0"><ClipSlot><Value>
+								<MidiClip Id="0" Time="0">
+									<CurrentEnd Value="2"/>
+									<Loop><LoopStart Value="0"/><LoopEnd Value="2"/></Loop>
+									<Name Value=""/>
+									<Notes><KeyTracks>
+										<KeyTrack Id="0">
+											<Notes>
+												<MidiNoteEvent Time="0" Duration="0.25" Velocity="100" OffVelocity="64" IsEnabled="true"/>
+												<MidiNoteEvent Time="1.02" Duration="0.25" Velocity="90" OffVelocity="64" IsEnabled="true"/>
+												<MidiNoteEvent Time="1.5" Duration="0.25" Velocity="90" OffVelocity="64" IsEnabled="false"/>
+											</Notes>
+											<MidiKey Value="37"/>
+										</KeyTrack>
+										<KeyTrack Id="1">
+											<Notes><MidiNoteEvent Time="0.5" Duration="0.25" Velocity="127" OffVelocity="64" IsEnabled="true"/></Notes>
+											<MidiKey Value="42"/>
+										</KeyTrack>
+									</KeyTracks></Notes>
+								</MidiClip>
+							</Value></ClipSlot></ClipSlot>
+						</ClipSlotList>
+					</MainSequencer>
+					<DeviceChain><Devices><DrumGroupDevice Id="0"><Branches>
+						<DrumBranch Id="0">
+							<Name><EffectiveName Value="Rim 808"/></Name>
+							<BranchInfo><ReceivingNote Value="91"/></BranchInfo>
+						</DrumBranch>
+					</Branches></DrumGroupDevice></Devices></DeviceChain>
+				</DeviceChain>
+			</MidiTrack>
+		</Tracks>
+		<MasterTrack><DeviceChain><Mixer><Tempo><Manual Value="92.5"/></Tempo></Mixer></DeviceChain></MasterTrack>
+	</LiveSet>
+</Ableton>`
+
+func TestReadAbleton(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(abletonSetXML))
+	zw.Close()
+	patterns, err := ReadAbleton(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := patterns[0]
+	if p.tempo != 92.5 || p.Metadata().Title != "808 Rack" || p.TimeSignature() != (TimeSignature{2, 4}) {
+		t.Fatalf("unexpected pattern %v", p)
+	}
+	rim, hat := p.tracks[0], p.tracks[1]
+	if rim.id != 37 || rim.name != "Rim 808" || hat.name != "Closed Hi-Hat" {
+		t.Errorf("unexpected tracks %v and %v", rim, hat)
+	}
+	if !rim.steps[0] || !rim.steps[4] || rim.steps[6] || !hat.steps[2] {
+		t.Errorf("unexpected steps %v and %v", rim.steps, hat.steps)
+	}
+	if rim.Velocity(4) != 90 || rim.timing[4] != 2 {
+		t.Errorf("Expected velocity 90 and 2 ticks late but got %d and %d", rim.Velocity(4), rim.timing[4])
+	}
+
+	_, err = ReadAbleton(strings.NewReader(`<Ableton><LiveSet><Tracks><MidiTrack/></Tracks></LiveSet></Ableton>`))
+	if !errors.Is(err, ErrNoDrumClips) {
+		t.Errorf("Expected %v but got %v", ErrNoDrumClips, err)
+	}
+}
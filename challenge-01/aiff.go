@@ -0,0 +1,82 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// ReadAIFFFile reads the AIFF file found at path, see ReadAIFF.
+func ReadAIFFFile(path string) (*Sample, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadAIFF(file)
+}
+
+// ReadAIFF reads an uncompressed AIFF file.
+func ReadAIFF(r io.Reader) (*Sample, error) {
+	var header struct {
+		FORM [4]byte
+		Size uint32
+		AIFF [4]byte
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, fmt.Errorf("parse aiff header: %v", err)
+	}
+	if string(header.FORM[:]) != "FORM" || string(header.AIFF[:]) != "AIFF" {
+		return nil, ErrUnsupportedFormat
+	}
+	var comm struct {
+		Channels int16
+		Frames   uint32
+		Bits     int16
+		Rate     [10]byte // 80 bit IEEE 754 extended precision
+	}
+	var haveComm bool
+	for {
+		var chunk struct {
+			ID   [4]byte
+			Size uint32
+		}
+		if err := binary.Read(r, binary.BigEndian, &chunk); err != nil {
+			return nil, fmt.Errorf("parse aiff chunk: %v", err)
+		}
+		data, err := readBytes(r, int(chunk.Size+chunk.Size%2))
+		if err != nil {
+			return nil, fmt.Errorf("parse aiff %s chunk: %v", chunk.ID[:], err)
+		}
+		switch string(chunk.ID[:]) {
+		case "COMM":
+			if err := binary.Read(bytes.NewReader(data), binary.BigEndian, &comm); err != nil {
+				return nil, fmt.Errorf("parse aiff common chunk: %v", err)
+			}
+			haveComm = true
+		case "SSND":
+			if !haveComm {
+				return nil, fmt.Errorf("parse aiff: sound data before common chunk")
+			}
+			if chunk.Size < 8 {
+				return nil, fmt.Errorf("parse aiff: invalid sound data chunk")
+			}
+			offset := binary.BigEndian.Uint32(data) + 8
+			if offset > chunk.Size {
+				return nil, fmt.Errorf("parse aiff: invalid sound data offset")
+			}
+			return decodePCM(data[offset:chunk.Size], int(comm.Channels), int(comm.Bits), extendedToInt(comm.Rate), true)
+		}
+	}
+}
+
+// extendedToInt converts an 80 bit extended precision float as used for the
+// AIFF sample rate.
+func extendedToInt(b [10]byte) int {
+	exp := int(binary.BigEndian.Uint16(b[:2])&0x7fff) - 16383
+	mantissa := binary.BigEndian.Uint64(b[2:])
+	return int(math.Round(math.Ldexp(float64(mantissa), exp-63)))
+}
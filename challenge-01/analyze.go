@@ -0,0 +1,142 @@
+package drum
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// TrackReport is the analysis of a track, see Analyze.
+type TrackReport struct {
+	ID          uint32  `json:"id"`
+	Name        string  `json:"name"`
+	Hits        int     `json:"hits"`        // enabled steps of the bar
+	Density     float64 `json:"density"`     // share of the steps of the loop that are enabled
+	Syncopation float64 `json:"syncopation"` // average syncopation of the hits
+}
+
+// Report is the analysis of a pattern returned by Analyze, for example to
+// sort a library by how busy the grooves are.
+type Report struct {
+	Version string        `json:"version"`
+	Tempo   float32       `json:"tempo"`
+	Tracks  []TrackReport `json:"tracks"`
+	Hits    int           `json:"hits"` // enabled steps of the bar of all tracks
+	// Density is the share of the steps of the bar any track plays on.
+	Density float64 `json:"density"`
+	// Syncopation is the average syncopation of the steps any track plays
+	// on, from 0 for hits on strong positions only to 4.
+	Syncopation float64 `json:"syncopation"`
+	// Backbeat reports whether a track of RoleSnare plays the second and fourth
+	// beat of a four beat bar.
+	Backbeat bool `json:"backbeat"`
+	// Complexity is the hits per step weighted by one plus the average
+	// syncopation of the hits within their tracks. It grows with the number of tracks, the number of hits and how
+	// syncopated they are, and is 0 for a silent pattern.
+	Complexity float64 `json:"complexity"`
+}
+
+// Analyze returns the statistics of the pattern computed on its bar, all
+// tracks are included whether muted or not. The syncopation of a hit
+// follows Longuet-Higgins and Lee: a hit followed by silence up to and
+// including a stronger position of the bar is syncopated by the difference
+// of the metric weights, downbeat, half bar, beats, eighths and sixteenths
+// being one level apart.
+func Analyze(p *Pattern) Report {
+	bar := p.BarSteps()
+	r := Report{Version: p.version, Tempo: p.tempo, Tracks: []TrackReport{}}
+	var all [stepsLength]bool
+	trackSync := 0
+	for _, t := range p.tracks {
+		var played [stepsLength]bool
+		for n := 0; n < bar; n++ {
+			played[n] = t.steps[p.trackStep(t, n)]
+			all[n] = all[n] || played[n]
+		}
+		hits, sync := syncopation(played[:bar])
+		r.Tracks = append(r.Tracks, TrackReport{
+			ID: t.id, Name: t.name, Hits: hits, Density: p.Density(t), Syncopation: average(sync, hits),
+		})
+		r.Hits += hits
+		trackSync += sync
+		if t.Role() == RoleSnare && p.TimeSignature().Numerator == 4 {
+			beat := bar / 4
+			r.Backbeat = r.Backbeat || played[beat] && played[3*beat]
+		}
+	}
+	onsets, sync := syncopation(all[:bar])
+	r.Density = float64(onsets) / float64(bar)
+	r.Syncopation = average(sync, onsets)
+	r.Complexity = float64(r.Hits+trackSync) / float64(bar)
+	return r
+}
+
+// syncopation returns the number of hits and their summed syncopation.
+func syncopation(steps []bool) (hits, total int) {
+	for i, on := range steps {
+		if !on {
+			continue
+		}
+		hits++
+		w := metricWeight(i, len(steps))
+		for j := i + 1; j <= len(steps); j++ {
+			if steps[j%len(steps)] {
+				break
+			}
+			if wj := metricWeight(j%len(steps), len(steps)); wj > w {
+				total += wj - w
+				break
+			}
+		}
+	}
+	return hits, total
+}
+
+func average(total, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(total) / float64(n)
+}
+
+// metricWeight returns the weight of the step in a bar of the given steps,
+// 0 for the downbeat down to -4 for off-beat sixteenths.
+func metricWeight(step, bar int) int {
+	if step == 0 {
+		return 0
+	}
+	w := -1
+	for div := bar / 2; div >= 1; div /= 2 {
+		if step%div == 0 {
+			return w
+		}
+		w--
+	}
+	return w
+}
+
+// WriteReportsCSV writes one row per report with the summary of the pattern
+// and the name the caller knows it by, for example its file.
+func WriteReportsCSV(w io.Writer, names []string, reports []Report) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"name", "version", "tempo", "tracks", "hits", "density", "syncopation", "backbeat", "complexity"})
+	for i, r := range reports {
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+		cw.Write([]string{
+			name,
+			r.Version,
+			strconv.FormatFloat(float64(r.Tempo), 'g', -1, 32),
+			strconv.Itoa(len(r.Tracks)),
+			strconv.Itoa(r.Hits),
+			formatFloat(r.Density),
+			formatFloat(r.Syncopation),
+			strconv.FormatBool(r.Backbeat),
+			formatFloat(r.Complexity),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,53 @@
+package drum
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Analyze(p)
+	if len(r.Tracks) != 6 || !r.Backbeat || r.Hits != 18 {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	if kick := r.Tracks[0]; kick.Density != 0.25 || kick.Syncopation != 0 || kick.Hits != 4 {
+		t.Errorf("Expected four on the floor to be straight but got %+v", kick)
+	}
+	// the clap plays 4 and 6, the eighth on 6 is two levels weaker than the
+	// silent half bar after it
+	if clap := r.Tracks[2]; clap.Syncopation != 1 {
+		t.Errorf("Expected no syncopation but got %+v", clap)
+	}
+
+	tr, _ := NewTrack(1, "snare", Steps{false, false, false, true})
+	offbeat, _ := NewPattern("0.808-alpha", 120, tr)
+	r = Analyze(offbeat)
+	// the sixteenth before the silent beat is two levels weaker
+	if r.Syncopation != 2 || r.Backbeat || r.Density != 1.0/16 || r.Complexity != 3.0/16 {
+		t.Errorf("unexpected report %+v", r)
+	}
+	if r := Analyze(&Pattern{}); r.Complexity != 0 || r.Hits != 0 {
+		t.Errorf("Expected an empty report but got %+v", r)
+	}
+}
+
+func TestWriteReportsCSV(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteReportsCSV(&buf, []string{"pattern_1.splice"}, []Report{Analyze(p)}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "pattern_1.splice,0.808-alpha,120,6,18,") {
+		t.Errorf("unexpected CSV:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,142 @@
+package drum
+
+import "unsafe"
+
+// Number of values allocated at once by an Arena.
+const (
+	arenaPatterns = 256
+	arenaTracks   = 4096
+	arenaText     = 64 << 10 // bytes of names and versions
+)
+
+// Arena allocates the patterns, tracks and names of many decoded patterns
+// in large blocks instead of one by one, which takes most of the load off
+// the garbage collector when indexing large libraries. Decoders reading the
+// whole file, like DecodeBytes, DecodeFile and Bank.LoadAll, allocate from
+// the arena passed with WithArena.
+//
+// Release hands the blocks back for the next patterns, so the patterns
+// decoded before must not be used anymore: their tracks and names are
+// overwritten. An Arena must not be used concurrently, the zero value is
+// ready to use.
+type Arena struct {
+	patterns arenaBlocks[Pattern]
+	tracks   arenaBlocks[Track]
+	refs     arenaBlocks[*Track]
+	text     arenaBlocks[byte]
+}
+
+// NewArena returns an empty arena.
+func NewArena() *Arena {
+	return &Arena{}
+}
+
+// WithArena makes the decoder allocate from the arena, see Arena.
+func WithArena(a *Arena) DecodeOption {
+	return func(o *decodeOptions) {
+		o.arena = a
+	}
+}
+
+// Release makes the arena reuse all its blocks for the patterns decoded
+// next.
+func (a *Arena) Release() {
+	a.patterns.release()
+	a.tracks.release()
+	a.refs.release()
+	a.text.release()
+}
+
+// pattern returns a new pattern, allocated by itself without an arena.
+func (a *Arena) pattern() *Pattern {
+	if a == nil {
+		return new(Pattern)
+	}
+	return &a.patterns.alloc(1, arenaPatterns)[0]
+}
+
+// trackSlab returns n new tracks.
+func (a *Arena) trackSlab(n int) []Track {
+	if a == nil {
+		return make([]Track, n)
+	}
+	return a.tracks.alloc(n, arenaTracks)
+}
+
+// trackRefs returns an empty slice for n tracks. Appending more than n
+// tracks moves it out of the arena.
+func (a *Arena) trackRefs(n int) []*Track {
+	if a == nil {
+		return make([]*Track, 0, n)
+	}
+	return a.refs.alloc(n, arenaTracks)[:0]
+}
+
+// string returns a copy of b.
+func (a *Arena) string(b []byte) string {
+	if a == nil {
+		return string(b)
+	}
+	if len(b) == 0 {
+		return ""
+	}
+	s := a.text.alloc(len(b), arenaText)
+	copy(s, b)
+	return unsafe.String(&s[0], len(s))
+}
+
+// bytes returns a copy of b with a capacity of its length.
+func (a *Arena) bytes(b []byte) []byte {
+	if a == nil {
+		return append([]byte(nil), b...)
+	}
+	s := a.text.alloc(len(b), arenaText)
+	copy(s, b)
+	return s
+}
+
+// arenaBlocks hands out the values of blocks of a type.
+type arenaBlocks[T any] struct {
+	cur  []T   // block values are taken from
+	used [][]T // full blocks
+	free [][]T // released blocks
+}
+
+// alloc slices n values off the current block with a capacity of n, so that
+// appending to them never overwrites the next ones. When the block is full
+// a released block is taken or a new one of size values, or n when larger,
+// is allocated.
+func (b *arenaBlocks[T]) alloc(n, size int) []T {
+	if cap(b.cur)-len(b.cur) < n {
+		if b.cur != nil {
+			b.used = append(b.used, b.cur)
+		}
+		b.cur = nil
+		for len(b.free) > 0 && b.cur == nil {
+			// released blocks too small for n are dropped
+			if last := b.free[len(b.free)-1]; cap(last) >= n {
+				b.cur = last
+			}
+			b.free = b.free[:len(b.free)-1]
+		}
+		if b.cur == nil {
+			b.cur = make([]T, 0, max(n, size))
+		}
+	}
+	v := b.cur[len(b.cur) : len(b.cur)+n : len(b.cur)+n]
+	b.cur = b.cur[:len(b.cur)+n]
+	return v
+}
+
+// release clears all blocks, so that they do not keep other values alive,
+// and makes them free.
+func (b *arenaBlocks[T]) release() {
+	if b.cur != nil {
+		b.used = append(b.used, b.cur)
+	}
+	for _, block := range b.used {
+		clear(block)
+		b.free = append(b.free, block[:0])
+	}
+	b.cur, b.used = nil, b.used[:0]
+}
@@ -0,0 +1,134 @@
+package drum
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func readFixtures(tb testing.TB) [][]byte {
+	files, err := filepath.Glob(path.Join("fixtures", "pattern_[1-4].splice"))
+	if err != nil || len(files) == 0 {
+		tb.Fatalf("no fixtures: %v", err)
+	}
+	var raw [][]byte
+	for _, name := range files {
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		raw = append(raw, b)
+	}
+	return raw
+}
+
+func TestArena(t *testing.T) {
+	raw := readFixtures(t)
+	a := NewArena()
+	var decoded []*Pattern
+	for i := 0; i < 3; i++ {
+		for _, b := range raw {
+			p, err := DecodeBytes(b, WithArena(a))
+			if err != nil {
+				t.Fatal(err)
+			}
+			exp, _ := DecodeBytes(b)
+			if !p.Equal(exp) || p.String() != exp.String() {
+				t.Fatalf("Expected\n%s\nbut got\n%s", exp, p)
+			}
+			decoded = append(decoded, p)
+		}
+	}
+	// appending to the tracks must not overwrite the next pattern
+	first, next := decoded[0], decoded[1].String()
+	first.tracks = append(first.tracks, first.tracks[0])
+	if decoded[1].String() != next {
+		t.Error("Expected the next pattern unchanged")
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		for _, b := range raw {
+			if _, err := DecodeBytes(b, WithArena(a)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		a.Release()
+	})
+	// the options and the pattern of every file
+	if max := float64(2 * len(raw)); allocs > max {
+		t.Errorf("Expected at most %v allocations but got %v", max, allocs)
+	}
+	a.Release()
+	p, _ := DecodeBytes(raw[0], WithArena(a))
+	if &a.patterns.cur[0] != p {
+		t.Error("Expected the released block to be reused")
+	}
+
+	var zero Arena
+	if p, err := DecodeBytes(raw[1], WithArena(&zero)); err != nil || len(p.tracks) == 0 {
+		t.Errorf("Expected the zero arena to be usable but got %v", err)
+	}
+}
+
+func TestBankLoadAllArena(t *testing.T) {
+	var b Bank
+	for i, raw := range readFixtures(t) {
+		p, err := DecodeBytes(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Add(fmt.Sprintf("pattern_%d.splice", i+1), p)
+	}
+	path := filepath.Join(t.TempDir(), "bank.zip")
+	if err := b.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	opened, err := OpenBank(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer opened.Close()
+	a := NewArena()
+	if err := opened.LoadAll(WithArena(a)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < b.Len(); i++ {
+		exp, _ := b.Entry(i).Pattern()
+		if p, _ := opened.Entry(i).Pattern(); !p.Equal(exp) {
+			t.Errorf("Expected\n%s\nbut got\n%s", exp, p)
+		}
+	}
+	if len(a.patterns.cur) != b.Len() || len(a.tracks.cur) == 0 {
+		t.Errorf("Expected the patterns in the arena but got %d", len(a.patterns.cur))
+	}
+}
+
+// BenchmarkDecodeLibrary decodes a library of patterns like an index does,
+// with and without an arena released after every bank of 1000 patterns. Run
+// with -benchmem to compare the allocations, gc/op counts the collections.
+func BenchmarkDecodeLibrary(b *testing.B) {
+	raw := readFixtures(b)
+	const bank = 1000
+	run := func(b *testing.B, a *Arena) {
+		b.ReportAllocs()
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < bank; j++ {
+				if _, err := DecodeBytes(raw[j%len(raw)], WithArena(a)); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if a != nil {
+				a.Release()
+			}
+		}
+		runtime.ReadMemStats(&after)
+		b.ReportMetric(float64(after.NumGC-before.NumGC)/float64(b.N), "gc/op")
+	}
+	b.Run("heap", func(b *testing.B) { run(b, nil) })
+	b.Run("arena", func(b *testing.B) { run(b, NewArena()) })
+}
@@ -0,0 +1,85 @@
+// Package audio plays a drum.Mixer through the speakers in real time.
+//
+// The output needs the oto library and is only built with the oto tag:
+//
+//	go get github.com/ebitengine/oto/v3
+//	go build -tags oto ./...
+//
+// Without the tag Open returns ErrNoBackend, so that programs using the
+// package still build everywhere. The player hands its steps to the mixer,
+// the output reads the mixed frames:
+//
+//	m, err := drum.NewMixer(kit)
+//	out, err := audio.Open(m, audio.WithBufferSize(20*time.Millisecond))
+//	defer out.Close()
+//	player := drum.NewPlayer(p, m.Handle)
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// DefaultBufferSize is the buffer size of outputs without WithBufferSize.
+const DefaultBufferSize = 50 * time.Millisecond
+
+// ErrNoBackend is returned by Open when the package is built without an
+// audio backend.
+var ErrNoBackend = errors.New("no audio backend, build with -tags oto")
+
+// Option configures an output.
+type Option func(*options)
+
+type options struct {
+	bufferSize time.Duration
+}
+
+// WithBufferSize sets the audio buffered ahead of the speakers. Smaller
+// buffers lower the latency between a step and its sound but drop out when
+// the system can not refill them in time.
+func WithBufferSize(d time.Duration) Option {
+	return func(o *options) {
+		o.bufferSize = d
+	}
+}
+
+// backend is an open audio device.
+type backend interface {
+	Close() error
+}
+
+// Output plays the frames of a mixer until closed.
+type Output struct {
+	backend backend
+	latency time.Duration
+}
+
+// Open starts playing the mixer on the default audio device.
+func Open(m *drum.Mixer, opts ...Option) (*Output, error) {
+	o := options{bufferSize: DefaultBufferSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.bufferSize <= 0 {
+		return nil, fmt.Errorf("audio: invalid buffer size %v", o.bufferSize)
+	}
+	b, err := open(m, o)
+	if err != nil {
+		return nil, err
+	}
+	return &Output{backend: b, latency: o.bufferSize}, nil
+}
+
+// Latency returns the time from a step handed to the mixer to its sound,
+// the buffer size.
+func (out *Output) Latency() time.Duration {
+	return out.latency
+}
+
+// Close stops playing.
+func (out *Output) Close() error {
+	return out.backend.Close()
+}
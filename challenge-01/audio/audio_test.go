@@ -0,0 +1,31 @@
+package audio
+
+import (
+	"errors"
+	"testing"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+func TestOpen(t *testing.T) {
+	kit := drum.NewKit("test")
+	kit.Add("kick", &drum.Sample{Rate: 44100, Left: []float32{1}, Right: []float32{1}})
+	m, err := drum.NewMixer(kit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(m, WithBufferSize(0)); err == nil {
+		t.Error("Expected an error for the buffer size")
+	}
+	out, err := Open(m, WithBufferSize(DefaultBufferSize/2))
+	if errors.Is(err, ErrNoBackend) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Skipf("no audio device: %v", err)
+	}
+	defer out.Close()
+	if out.Latency() != DefaultBufferSize/2 {
+		t.Errorf("Expected the buffer size as latency but got %v", out.Latency())
+	}
+}
@@ -0,0 +1,34 @@
+//go:build oto
+
+package audio
+
+import (
+	"fmt"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+	"github.com/ebitengine/oto/v3"
+)
+
+// otoContext is the oto context, of which there can only be one per process.
+var otoContext *oto.Context
+
+func open(m *drum.Mixer, o options) (backend, error) {
+	if otoContext == nil {
+		c, ready, err := oto.NewContext(&oto.NewContextOptions{
+			SampleRate:   m.Rate(),
+			ChannelCount: 2,
+			Format:       oto.FormatFloat32LE,
+			BufferSize:   o.bufferSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("audio: %v", err)
+		}
+		<-ready
+		otoContext = c
+	}
+	p := otoContext.NewPlayer(m)
+	// the player buffers on top of the otoContext, keep it to the buffer size
+	p.SetBufferSize(int(o.bufferSize.Seconds()*float64(m.Rate())) * 8)
+	p.Play()
+	return p, nil
+}
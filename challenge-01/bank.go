@@ -0,0 +1,240 @@
+package drum
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const spliceFileExt = ".splice"
+
+// Bank is a collection of patterns stored as .splice files in a zip archive,
+// as exported by the hardware backup function.
+// Patterns are decoded on demand only. Methods are not thread safe.
+type Bank struct {
+	archive  io.Closer
+	entries  []*BankEntry
+	others   []*zip.File // non pattern files, kept when the bank is written
+	manifest []byte      // signed manifest replacing the one of the archive, see Sign
+}
+
+// BankEntry is a single pattern within a Bank.
+type BankEntry struct {
+	name    string
+	file    *zip.File // nil for entries added after opening
+	pattern *Pattern  // decoded or replaced pattern, nil when not loaded yet
+	changed bool
+	mapped  []byte // file data within the mapped archive, see OpenBankMapped
+}
+
+// OpenBank opens the zip archive found at path. The archive must be closed
+// by the caller when the bank is not used anymore.
+func OpenBank(path string) (*Bank, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	return newBank(&archive.Reader, archive), nil
+}
+
+func newBank(r *zip.Reader, archive io.Closer) *Bank {
+	b := Bank{archive: archive}
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if !strings.EqualFold(filepath.Ext(f.Name), spliceFileExt) {
+			b.others = append(b.others, f)
+			continue
+		}
+		b.entries = append(b.entries, &BankEntry{name: f.Name, file: f})
+	}
+	return &b
+}
+
+// Close closes the underlying archive.
+func (b *Bank) Close() error {
+	if b.archive == nil {
+		return nil
+	}
+	return b.archive.Close()
+}
+
+// Len returns the number of patterns in the bank.
+func (b *Bank) Len() int {
+	return len(b.entries)
+}
+
+// Entry returns the i-th entry of the bank in archive order.
+func (b *Bank) Entry(i int) *BankEntry {
+	return b.entries[i]
+}
+
+// Lookup returns the entry with the given name or nil when not found.
+func (b *Bank) Lookup(name string) *BankEntry {
+	for _, e := range b.entries {
+		if e.name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// Add adds a new pattern or replaces the pattern of an existing entry with
+// the same name.
+func (b *Bank) Add(name string, p *Pattern) {
+	if e := b.Lookup(name); e != nil {
+		e.SetPattern(p)
+		return
+	}
+	b.entries = append(b.entries, &BankEntry{name: name, pattern: p, changed: true})
+}
+
+// Remove removes the entry with the given name. It returns false when there
+// is no such entry.
+func (b *Bank) Remove(name string) bool {
+	for i, e := range b.entries {
+		if e.name == name {
+			b.entries = append(b.entries[:i], b.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// LoadAll decodes all entries not loaded yet with the options, see
+// BankEntry.Pattern. It stops at the first entry that fails to decode.
+func (b *Bank) LoadAll(opts ...DecodeOption) error {
+	progress := newProgress(newDecodeOptions(opts).progress, len(b.entries))
+	for _, e := range b.entries {
+		if _, err := e.load(opts); err != nil {
+			return fmt.Errorf("%s: %v", e.name, err)
+		}
+		progress.step()
+	}
+	return nil
+}
+
+// Name returns the file name of the entry within the archive.
+func (e *BankEntry) Name() string {
+	return e.name
+}
+
+// Pattern decodes the entry with the options on first access. Subsequent
+// calls return the same pattern, so changes to it are written with the
+// bank.
+func (e *BankEntry) Pattern(opts ...DecodeOption) (*Pattern, error) {
+	return e.load(opts)
+}
+
+// load returns the pattern, decoding it with the options on first access.
+func (e *BankEntry) load(opts []DecodeOption) (*Pattern, error) {
+	if e.pattern != nil {
+		return e.pattern, nil
+	}
+	p, err := e.decode(opts)
+	if err != nil {
+		return nil, err
+	}
+	e.pattern = p
+	e.changed = true
+	return p, nil
+}
+
+// decode decodes the file of the entry, in place when it is mapped.
+func (e *BankEntry) decode(opts []DecodeOption) (*Pattern, error) {
+	if e.mapped != nil {
+		return DecodeBytes(e.mapped, opts...)
+	}
+	rc, err := e.open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return decodeAll(rc, opts...)
+}
+
+// open returns a reader of the uncompressed file data.
+func (e *BankEntry) open() (io.ReadCloser, error) {
+	if e.mapped != nil {
+		return ioutil.NopCloser(bytes.NewReader(e.mapped)), nil
+	}
+	return e.file.Open()
+}
+
+// SetPattern replaces the pattern of the entry.
+func (e *BankEntry) SetPattern(p *Pattern) {
+	e.pattern = p
+	e.changed = true
+}
+
+// Write writes the bank as zip archive to w. Entries that were never
+// decoded are copied without recompression.
+func (b *Bank) Write(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, e := range b.entries {
+		if !e.changed {
+			if err := zw.Copy(e.file); err != nil {
+				return err
+			}
+			continue
+		}
+		fw, err := zw.Create(e.name)
+		if err != nil {
+			return err
+		}
+		if err := Encode(fw, e.pattern); err != nil {
+			return err
+		}
+	}
+	for _, f := range b.others {
+		if b.manifest != nil && f.Name == bankManifestName {
+			continue
+		}
+		if err := zw.Copy(f); err != nil {
+			return err
+		}
+	}
+	if b.manifest != nil {
+		fw, err := zw.Create(bankManifestName)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(b.manifest); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// Save writes the bank to the zip archive at path with WriteFileAtomic, so
+// that a bank can be saved to the same path it was opened from.
+func (b *Bank) Save(path string, opts ...SaveOption) error {
+	return WriteFileAtomic(path, b.Write, opts...)
+}
+
+// Export saves every pattern of the bank as .splice file in dir, in the
+// directories of the entry names, with a SaveBatch: either all files are
+// saved or none is changed.
+func (b *Bank) Export(dir string, opts ...SaveOption) (SaveReport, error) {
+	batch := NewSaveBatch(opts...)
+	for _, e := range b.entries {
+		name := filepath.FromSlash(path.Clean("/" + e.name))
+		p, err := e.Pattern()
+		if err != nil {
+			return SaveReport{}, fmt.Errorf("export %s: %w", e.name, err)
+		}
+		target := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return SaveReport{}, err
+		}
+		batch.AddPattern(target, p)
+	}
+	return batch.Commit()
+}
@@ -0,0 +1,98 @@
+package drum
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestBank(t *testing.T, dir string, files ...string) string {
+	bankPath := filepath.Join(dir, "backup.zip")
+	f, err := os.Create(bankPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, name := range files {
+		raw, err := ioutil.ReadFile(path.Join("fixtures", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(raw)
+	}
+	w, _ := zw.Create("README.txt")
+	w.Write([]byte("backup"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bankPath
+}
+
+func TestBank(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	bankPath := writeTestBank(t, dir, "pattern_1.splice", "pattern_2.splice")
+
+	b, err := OpenBank(bankPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := 2, b.Len(); got != exp {
+		t.Fatalf("Expected %v entries but got %v", exp, got)
+	}
+	p, err := b.Entry(1).Pattern()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.tempo = 100
+	b.Remove("pattern_1.splice")
+	b.Add("new.splice", &Pattern{version: "0.909", tempo: 90})
+	if err := os.Chmod(bankPath, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Save(bankPath); err != nil {
+		t.Fatal(err)
+	}
+	newPath := filepath.Join(dir, "new.zip")
+	if err := b.Save(newPath); err != nil {
+		t.Fatal(err)
+	}
+	b.Close()
+	for file, exp := range map[string]os.FileMode{bankPath: 0640, newPath: 0644} {
+		if fi, err := os.Stat(file); err != nil || fi.Mode().Perm() != exp {
+			t.Errorf("Expected %s saved with mode %v but got %v", file, exp, fi.Mode())
+		}
+	}
+
+	b, err = OpenBank(bankPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	var got []string
+	for i := 0; i < b.Len(); i++ {
+		p, err := b.Entry(i).Pattern()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%s:%v", b.Entry(i).Name(), p.tempo))
+	}
+	if exp := "[pattern_2.splice:100 new.splice:90]"; fmt.Sprint(got) != exp {
+		t.Errorf("Expected '%v' but got '%v'", exp, got)
+	}
+	if len(b.others) != 1 {
+		t.Errorf("expected non pattern files to be kept")
+	}
+}
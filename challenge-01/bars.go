@@ -0,0 +1,278 @@
+package drum
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var chunkBars = chunkID{'B', 'A', 'R', 'S'}
+
+// FeatureBars is the chunk of the bars after the first and their play order.
+const FeatureBars Feature = "bars"
+
+// MaxBars is the maximum number of bars of a pattern, named A to H.
+const MaxBars = 8
+
+// maxBarOrder is the maximum number of bars of a play order.
+const maxBarOrder = 64
+
+// ErrInvalidBar is returned for bars out of range and invalid play orders.
+var ErrInvalidBar = errors.New("invalid bar")
+
+// Bars returns the number of bars of the pattern, 1 unless bars were added
+// with AddBar.
+func (p *Pattern) Bars() int {
+	return 1 + int(p.extraBars)
+}
+
+// AddBar adds a bar after the last one with the steps of every track copied
+// from the bar from and returns the new bar. The velocities and all other
+// step data are shared by the bars of a track, the bars only vary the
+// steps, like for a fill in the last bar of four.
+func (p *Pattern) AddBar(from int) (int, error) {
+	if from < 0 || from >= p.Bars() {
+		return 0, fmt.Errorf("%w %d", ErrInvalidBar, from)
+	}
+	if p.Bars() == MaxBars {
+		return 0, fmt.Errorf("%w: at most %d bars", ErrInvalidBar, MaxBars)
+	}
+	bar := p.Bars()
+	for _, t := range p.tracks {
+		*t.barSteps(bar) = *t.barSteps(from)
+	}
+	p.extraBars++
+	return bar, nil
+}
+
+// RemoveBar removes the bar from every track, the following bars move up.
+// The bar is removed from the play order as well. The last bar can not be
+// removed.
+func (p *Pattern) RemoveBar(bar int) error {
+	if bar < 0 || bar >= p.Bars() || p.Bars() == 1 {
+		return fmt.Errorf("%w %d", ErrInvalidBar, bar)
+	}
+	for _, t := range p.tracks {
+		for b := bar; b < p.Bars()-1; b++ {
+			*t.barSteps(b) = *t.barSteps(b + 1)
+		}
+		*t.barSteps(p.Bars() - 1) = Steps{}
+	}
+	p.extraBars--
+	if p.barOrder == nil {
+		return nil
+	}
+	var order []uint8
+	for _, b := range p.barOrder {
+		switch {
+		case int(b) > bar:
+			order = append(order, b-1)
+		case int(b) < bar:
+			order = append(order, b)
+		}
+	}
+	p.barOrder = order
+	return nil
+}
+
+// BarOrder returns the bars in the order they are played, repeated after
+// the last. It is every bar once in sequence unless set with SetBarOrder.
+func (p *Pattern) BarOrder() []int {
+	if p.barOrder == nil {
+		order := make([]int, p.Bars())
+		for i := range order {
+			order[i] = i
+		}
+		return order
+	}
+	order := make([]int, len(p.barOrder))
+	for i, b := range p.barOrder {
+		order[i] = int(b)
+	}
+	return order
+}
+
+// SetBarOrder sets the order the bars are played in, like A A A B for the
+// fill B after three times A, see ParseBarOrder. Bars can repeat and be
+// left out. An empty order plays every bar once in sequence again.
+func (p *Pattern) SetBarOrder(order []int) error {
+	if len(order) > maxBarOrder {
+		return fmt.Errorf("%w order: more than %d bars", ErrInvalidBar, maxBarOrder)
+	}
+	if len(order) == 0 {
+		p.barOrder = nil
+		return nil
+	}
+	bars := make([]uint8, len(order))
+	for i, b := range order {
+		if b < 0 || b >= p.Bars() {
+			return fmt.Errorf("%w %d in order", ErrInvalidBar, b)
+		}
+		bars[i] = uint8(b)
+	}
+	p.barOrder = bars
+	return nil
+}
+
+// ParseBarOrder returns the bar order written as letters, like "AAAB" or
+// "A A A B". Bar A is bar 0.
+func ParseBarOrder(s string) ([]int, error) {
+	var order []int
+	for _, r := range strings.ToUpper(s) {
+		switch {
+		case r == ' ':
+		case r >= 'A' && r < 'A'+MaxBars:
+			order = append(order, int(r-'A'))
+		default:
+			return nil, fmt.Errorf("%w %q in order", ErrInvalidBar, r)
+		}
+	}
+	return order, nil
+}
+
+// FormatBarOrder returns the bar order as letters, the reverse of
+// ParseBarOrder.
+func FormatBarOrder(order []int) string {
+	var b strings.Builder
+	for _, bar := range order {
+		b.WriteString(barName(bar))
+	}
+	return b.String()
+}
+
+func barName(bar int) string {
+	return string(rune('A' + bar))
+}
+
+// BarSteps returns a copy of the steps of the bar, of Steps for bar 0.
+func (t *Track) BarSteps(bar int) (Steps, error) {
+	if bar < 0 || bar >= MaxBars {
+		return Steps{}, fmt.Errorf("%w %d", ErrInvalidBar, bar)
+	}
+	return *t.barSteps(bar), nil
+}
+
+// SetBarStep enables or disables a step of a bar, like SetStep does for bar
+// 0.
+func (t *Track) SetBarStep(bar, step int, enabled bool) error {
+	if bar < 0 || bar >= MaxBars {
+		return fmt.Errorf("%w %d", ErrInvalidBar, bar)
+	}
+	if step < 0 || step >= stepsLength {
+		return ErrStepOutOfRange
+	}
+	t.barSteps(bar)[step] = enabled
+	return nil
+}
+
+// barSteps returns the steps of the bar, t.steps for bar 0 so that decoders
+// not knowing bars play the first.
+func (t *Track) barSteps(bar int) *Steps {
+	if bar == 0 {
+		return &t.steps
+	}
+	return &t.bars[bar-1]
+}
+
+// barOf returns the bar played as the bar counted from 0.
+func (p *Pattern) barOf(loop int) int {
+	if p.barOrder != nil {
+		return int(p.barOrder[loop%len(p.barOrder)])
+	}
+	return loop % p.Bars()
+}
+
+// hasBars reports whether the pattern has more than one bar or a play order.
+func (p *Pattern) hasBars() bool {
+	return p.extraBars != 0 || p.barOrder != nil
+}
+
+// The bars chunk stores
+//
+//	|Bars after the first (1 byte)|Order length (1 byte)|Order (1 byte per bar)|
+//
+// followed by |Track index (2 bytes)|Steps (2 bytes per bar after the
+// first)| for every track with steps in those bars. Bit n of the steps is
+// step n.
+
+func decodeBarsChunk(data []byte, p *Pattern) error {
+	if len(data) < 2 || len(data) < 2+int(data[1]) {
+		return errors.New("invalid size")
+	}
+	extra, n := int(data[0]), int(data[1])
+	if extra >= MaxBars {
+		return fmt.Errorf("%w: %d bars", ErrInvalidBar, extra+1)
+	}
+	p.extraBars = uint8(extra)
+	order := make([]int, n)
+	for i, b := range data[2 : 2+n] {
+		order[i] = int(b)
+	}
+	if err := p.SetBarOrder(order); err != nil {
+		return err
+	}
+	data = data[2+n:]
+	entryLength := 2 + 2*extra
+	if extra == 0 || len(data)%entryLength != 0 {
+		if len(data) == 0 {
+			return nil
+		}
+		return errors.New("invalid size")
+	}
+	for ; len(data) > 0; data = data[entryLength:] {
+		index := binary.LittleEndian.Uint16(data)
+		if int(index) >= len(p.tracks) {
+			return fmt.Errorf("track index %d out of range", index)
+		}
+		t := p.tracks[index]
+		for b := 0; b < extra; b++ {
+			mask := binary.LittleEndian.Uint16(data[2+2*b:])
+			for s := range t.bars[b] {
+				t.bars[b][s] = mask&(1<<s) != 0
+			}
+		}
+	}
+	return nil
+}
+
+func encodeBarsChunk(p *Pattern) []byte {
+	if !p.hasBars() {
+		return nil
+	}
+	data := []byte{p.extraBars, byte(len(p.barOrder))}
+	data = append(data, p.barOrder...)
+	for i, t := range p.tracks {
+		bars := t.bars[:p.extraBars]
+		if isZeroBars(bars) {
+			continue
+		}
+		data = binary.LittleEndian.AppendUint16(data, uint16(i))
+		for _, s := range bars {
+			var mask uint16
+			for n, enabled := range s {
+				if enabled {
+					mask |= 1 << n
+				}
+			}
+			data = binary.LittleEndian.AppendUint16(data, mask)
+		}
+	}
+	return data
+}
+
+func isZeroBars(bars []Steps) bool {
+	for _, s := range bars {
+		if s != (Steps{}) {
+			return false
+		}
+	}
+	return true
+}
+
+func clearBars(p *Pattern) {
+	p.extraBars, p.barOrder = 0, nil
+	for _, t := range p.tracks {
+		t.bars = [MaxBars - 1]Steps{}
+	}
+}
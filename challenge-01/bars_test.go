@@ -0,0 +1,178 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fillPattern returns a pattern of a four on the floor bar A and a fill bar
+// B played as A A A B.
+func fillPattern(t *testing.T) *Pattern {
+	kick, err := NewTrack(0, "kick", Steps{0: true, 4: true, 8: true, 12: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPattern("0.808-alpha", 120, kick)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bar, err := p.AddBar(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range []int{13, 14, 15} {
+		if err := kick.SetBarStep(bar, step, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	order, err := ParseBarOrder("AAAB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetBarOrder(order); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestBars(t *testing.T) {
+	p := fillPattern(t)
+	if p.Bars() != 2 {
+		t.Fatalf("Expected 2 bars but got %d", p.Bars())
+	}
+	if exp, got := "AAAB", FormatBarOrder(p.BarOrder()); got != exp {
+		t.Errorf("Expected order %s but got %s", exp, got)
+	}
+	b, err := p.tracks[0].BarSteps(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := (Steps{0: true, 4: true, 8: true, 12: true, 13: true, 14: true, 15: true}); b != exp {
+		t.Errorf("Expected %v but got %v", exp, b)
+	}
+	if p.tracks[0].Steps() != (Steps{0: true, 4: true, 8: true, 12: true}) {
+		t.Errorf("Expected bar A unchanged but got %v", p.tracks[0].Steps())
+	}
+
+	if _, err := p.AddBar(2); !errors.Is(err, ErrInvalidBar) {
+		t.Errorf("Expected %v but got %v", ErrInvalidBar, err)
+	}
+	if err := p.SetBarOrder([]int{0, 2}); !errors.Is(err, ErrInvalidBar) {
+		t.Errorf("Expected %v but got %v", ErrInvalidBar, err)
+	}
+	if _, err := ParseBarOrder("AX"); !errors.Is(err, ErrInvalidBar) {
+		t.Errorf("Expected %v but got %v", ErrInvalidBar, err)
+	}
+	for p.Bars() < MaxBars {
+		if _, err := p.AddBar(0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := p.AddBar(0); !errors.Is(err, ErrInvalidBar) {
+		t.Errorf("Expected %v but got %v", ErrInvalidBar, err)
+	}
+}
+
+func TestRemoveBar(t *testing.T) {
+	p := fillPattern(t)
+	b, _ := p.tracks[0].BarSteps(1)
+	if err := p.RemoveBar(0); err != nil {
+		t.Fatal(err)
+	}
+	if p.Bars() != 1 || p.tracks[0].Steps() != b {
+		t.Errorf("Expected bar B to move up but got %v", p)
+	}
+	if exp, got := "A", FormatBarOrder(p.BarOrder()); got != exp {
+		t.Errorf("Expected order %s but got %s", exp, got)
+	}
+	if err := p.RemoveBar(0); !errors.Is(err, ErrInvalidBar) {
+		t.Errorf("Expected %v but got %v", ErrInvalidBar, err)
+	}
+}
+
+func TestBarsRoundTrip(t *testing.T) {
+	p := fillPattern(t)
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(p) {
+		t.Errorf("Expected the bars to round trip but got %v", decoded)
+	}
+	if got := decoded.Features(); !reflect.DeepEqual(got, []Feature{FeatureBars}) {
+		t.Errorf("Expected the bars feature but got %v", got)
+	}
+	down, dropped := p.Downgrade(nil)
+	if down.Bars() != 1 || down.BarOrder() == nil || len(dropped) != 1 {
+		t.Errorf("Expected a single bar without the feature but got %v", down)
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Pattern
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !fromJSON.Equal(p) {
+		t.Errorf("Expected the bars to round trip JSON but got %s", data)
+	}
+
+	var fromText Pattern
+	if err := fromText.UnmarshalText([]byte(p.String())); err != nil {
+		t.Fatal(err)
+	}
+	if !fromText.Equal(p) {
+		t.Errorf("Expected the bars to round trip text but got %v", fromText)
+	}
+}
+
+func TestBarsPrintout(t *testing.T) {
+	exp := "Saved with HW Version: 0.808-alpha\n" +
+		"Tempo: 120\n" +
+		"Bar order: AAAB\n" +
+		"(0) kick\tA|x---|x---|x---|x---|\n" +
+		"\tB|x---|x---|x---|xxxx|\n"
+	if got := fillPattern(t).String(); got != exp {
+		t.Errorf("Expected\n%s\nbut got\n%s", exp, got)
+	}
+}
+
+func TestBarsPlayback(t *testing.T) {
+	p := fillPattern(t)
+	var scheduled []int
+	for _, e := range p.schedule(8) {
+		if e.tick/ticksPerStep%stepsLength == 13 {
+			scheduled = append(scheduled, e.tick/ticksPerStep/stepsLength)
+		}
+	}
+	// the fill is played in every fourth bar
+	if exp := "[3 7]"; fmt.Sprint(scheduled) != exp {
+		t.Errorf("Expected fills in bars %s but got %v", exp, scheduled)
+	}
+
+	pl := NewPlayer(p, nil)
+	var played []int
+	for i := 0; i < 8*stepsLength; i++ {
+		ev, _, err := pl.advance(time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.Step == 13 && len(ev.Tracks) > 0 {
+			played = append(played, i/stepsLength)
+		}
+	}
+	if fmt.Sprint(played) != fmt.Sprint(scheduled) {
+		t.Errorf("Expected the player to play the fills in bars %v but got %v", scheduled, played)
+	}
+}
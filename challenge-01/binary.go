@@ -0,0 +1,46 @@
+package drum
+
+import (
+	"bytes"
+	"io"
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler. The data is the pattern
+// in the drum machine file format as written by Encode, so extensions, unknown
+// chunks and raw extra data are kept. Mute and solo states are not stored.
+func (p *Pattern) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// BinaryWriterTo returns an io.WriterTo writing the pattern in the drum
+// machine file format with the options, for example to stream many patterns
+// into one archive without allocating the data of each as MarshalBinary does.
+func BinaryWriterTo(p *Pattern, opts ...EncodeOption) io.WriterTo {
+	return &binaryWriterTo{p: p, opts: opts}
+}
+
+type binaryWriterTo struct {
+	p    *Pattern
+	opts []EncodeOption
+}
+
+func (b *binaryWriterTo) WriteTo(w io.Writer) (int64, error) {
+	cw := countingWriter{w: w}
+	err := Encode(&cw, b.p, b.opts...)
+	return cw.n, err
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler for data written
+// by MarshalBinary or read from a .splice file.
+func (p *Pattern) UnmarshalBinary(data []byte) error {
+	np, err := DecodeBytes(data)
+	if err != nil {
+		return err
+	}
+	*p = *np
+	return nil
+}
@@ -0,0 +1,117 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestBinaryMarshaler(t *testing.T) {
+	for _, name := range []string{"pattern_1.splice", "pattern_5.splice"} {
+		raw, err := ioutil.ReadFile(path.Join("fixtures", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p Pattern
+		if err := p.UnmarshalBinary(raw); err != nil {
+			t.Fatal(err)
+		}
+		got, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, raw) {
+			t.Errorf("%s: Expected the file bytes back but got %q", name, got)
+		}
+	}
+
+	p, err := DecodeFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetSwing(20)
+	type message struct {
+		Name    string
+		Pattern *Pattern
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(message{"groove", p}); err != nil {
+		t.Fatal(err)
+	}
+	var got message
+	if err := gob.NewDecoder(&buf).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "groove" || !got.Pattern.Equal(p) {
+		t.Errorf("Expected '%v' but got '%v'", p, got.Pattern)
+	}
+
+	if err := new(Pattern).UnmarshalBinary([]byte("SPLICE")); err == nil {
+		t.Error("Expected error for truncated data")
+	}
+}
+
+func TestBinaryWriterTo(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_5.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetSwing(20)
+	exp, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	n, err := BinaryWriterTo(p).WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), exp) {
+		t.Errorf("Expected %q but got %q", exp, buf.Bytes())
+	}
+	if n != int64(len(exp)) {
+		t.Errorf("Expected %d bytes written but got %d", len(exp), n)
+	}
+
+	buf.Reset()
+	if _, err := BinaryWriterTo(p, WithChecksumTrailer()).WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeBytes(buf.Bytes(), WithChecksum()); err != nil {
+		t.Errorf("Expected the checksum to verify but got %v", err)
+	}
+
+	p.version = strings.Repeat("x", maxVersionLength+1)
+	buf.Reset()
+	if n, err := BinaryWriterTo(p).WriteTo(&buf); err != ErrVersionTooLong || n != 0 {
+		t.Errorf("Expected %v and nothing written but got %v after %d bytes", ErrVersionTooLong, err, n)
+	}
+}
+
+// BenchmarkExportBinary encodes 10000 patterns.
+func BenchmarkExportBinary(b *testing.B) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("MarshalBinary", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < 10000; j++ {
+				data, _ := p.MarshalBinary()
+				ioutil.Discard.Write(data)
+			}
+		}
+	})
+	b.Run("BinaryWriterTo", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < 10000; j++ {
+				BinaryWriterTo(p).WriteTo(ioutil.Discard)
+			}
+		}
+	})
+}
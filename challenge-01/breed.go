@@ -0,0 +1,160 @@
+package drum
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// breedCandidates is how many children per returned child are bred to be
+// ranked by the fitness function.
+const breedCandidates = 4
+
+// BreedConfig configures Breed.
+type BreedConfig struct {
+	// Children is the number of children returned, the number of parents
+	// unless set.
+	Children int
+	// Mutation is the probability every step of a child is flipped, a few
+	// percent keep the children close to their parents.
+	Mutation float64
+	// Fitness rates a child, higher is better. When set, more children are
+	// bred and only the fittest returned, the best first.
+	Fitness func(p *Pattern) float64
+	// Rand is the source of the random choices, a source seeded with 1 when
+	// nil, so the children only depend on the state of Rand.
+	Rand *rand.Rand
+}
+
+// ErrNoParents is returned by Breed without parents to breed from.
+var ErrNoParents = errors.New("no parents")
+
+// Breed returns children of the parents, for tools evolving a beat by
+// letting users pick the children to breed again. A child starts as a copy
+// of one parent and crosses every track with the same instrument of a
+// second parent: it keeps the track, takes the other one or switches to the
+// other one at a beat. Tracks only the second parent has are added to half
+// of the children. Finally steps are flipped by the mutation probability.
+func Breed(parents []*Pattern, cfg BreedConfig) ([]*Pattern, error) {
+	if len(parents) == 0 {
+		return nil, ErrNoParents
+	}
+	rng := cfg.Rand
+	if rng == nil {
+		rng = rand.New(rand.NewSource(1))
+	}
+	n := cfg.Children
+	if n <= 0 {
+		n = len(parents)
+	}
+	candidates := n
+	if cfg.Fitness != nil {
+		candidates *= breedCandidates
+	}
+	children := make([]*Pattern, candidates)
+	for i := range children {
+		a := parents[rng.Intn(len(parents))]
+		b := parents[rng.Intn(len(parents))]
+		children[i] = crossover(a, b, rng)
+		mutate(children[i], cfg.Mutation, rng)
+	}
+	if cfg.Fitness != nil {
+		fitness := make([]float64, len(children))
+		for i, c := range children {
+			fitness[i] = cfg.Fitness(c)
+		}
+		order := make([]int, len(children))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool { return fitness[order[i]] > fitness[order[j]] })
+		ranked := make([]*Pattern, n)
+		for i := range ranked {
+			ranked[i] = children[order[i]]
+		}
+		children = ranked
+	}
+	return children, nil
+}
+
+// crossover returns a copy of a with the tracks crossed with b.
+func crossover(a, b *Pattern, rng *rand.Rand) *Pattern {
+	c := a.Clone()
+	matched := make([]bool, len(b.tracks))
+	beat := c.TimeSignature().groupSteps()
+	beats := c.BarSteps() / beat
+	for _, t := range c.tracks {
+		i := matchingTrack(b, t, matched)
+		if i < 0 {
+			continue
+		}
+		matched[i] = true
+		switch rng.Intn(3) {
+		case 1:
+			t.crossFrom(b.tracks[i], 0)
+		case 2:
+			if beats > 1 {
+				t.crossFrom(b.tracks[i], beat*(1+rng.Intn(beats-1)))
+			}
+		}
+	}
+	for i, t := range b.tracks {
+		if matched[i] || rng.Intn(2) == 0 {
+			continue
+		}
+		added := t.Clone()
+		for c.TrackByID(added.id) != nil {
+			added.id++
+		}
+		c.tracks = append(c.tracks, added)
+	}
+	return c
+}
+
+// matchingTrack returns the index of the first track of p not matched yet
+// playing the same instrument as t, -1 if there is none.
+func matchingTrack(p *Pattern, t *Track, matched []bool) int {
+	key := instrumentKey(t.name)
+	for i, o := range p.tracks {
+		if !matched[i] && instrumentKey(o.name) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// instrumentKey returns what tracks playing the same instrument share, the
+// General MIDI drum or the normalized name when it names none.
+func instrumentKey(name string) string {
+	if note, ok := ResolveGMNote(name); ok {
+		return "gm:" + strconv.Itoa(int(note))
+	}
+	return "name:" + normalizeName(name)
+}
+
+// crossFrom takes the steps from the step on from o.
+func (t *Track) crossFrom(o *Track, from int) {
+	for step := from; step < stepsLength; step++ {
+		t.steps[step] = o.steps[step]
+		t.velocity[step] = o.velocity[step]
+		t.timing[step] = o.timing[step]
+		t.probability[step] = o.probability[step]
+		t.condition[step] = o.condition[step]
+		t.ratchet[step] = o.ratchet[step]
+	}
+}
+
+// mutate flips every step of the bar with the probability.
+func mutate(p *Pattern, probability float64, rng *rand.Rand) {
+	if probability <= 0 {
+		return
+	}
+	for _, t := range p.tracks {
+		for step := 0; step < p.BarSteps(); step++ {
+			if rng.Float64() < probability {
+				t.steps[step] = !t.steps[step]
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package drum
+
+import (
+	"math/rand"
+	"path"
+	"testing"
+)
+
+func TestBreed(t *testing.T) {
+	a, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := DecodeFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Breed(nil, BreedConfig{}); err != ErrNoParents {
+		t.Errorf("Expected %v but got %v", ErrNoParents, err)
+	}
+
+	children, err := Breed([]*Pattern{a, b}, BreedConfig{Children: 6, Rand: rand.New(rand.NewSource(3))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := Breed([]*Pattern{a, b}, BreedConfig{Children: 6, Rand: rand.New(rand.NewSource(3))})
+	if len(children) != 6 {
+		t.Fatalf("Expected 6 children but got %d", len(children))
+	}
+	for i, c := range children {
+		if !c.Equal(again[i]) {
+			t.Errorf("Expected the same children for the same seed")
+		}
+		// without mutation every step comes from a parent track
+		for _, tr := range c.tracks {
+			for step, enabled := range tr.steps {
+				if enabled && !parentPlays(tr, step, a, b) {
+					t.Errorf("Expected step %d of %s to come from a parent", step, tr.name)
+				}
+			}
+		}
+	}
+
+	// fewer steps are fitter
+	sparse := func(p *Pattern) float64 {
+		n := 0
+		for _, tr := range p.tracks {
+			for _, enabled := range tr.steps {
+				if enabled {
+					n++
+				}
+			}
+		}
+		return -float64(n)
+	}
+	mutated, err := Breed([]*Pattern{a, b}, BreedConfig{Children: 3, Mutation: 0.1, Fitness: sparse})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(mutated); i++ {
+		if sparse(mutated[i-1]) < sparse(mutated[i]) {
+			t.Errorf("Expected the fittest children first")
+		}
+	}
+	if len(a.tracks) != 6 || a.tracks[0].steps != (Steps{true, false, false, false, true, false, false, false, true, false, false, false, true}) {
+		t.Error("Expected the parents to be unchanged")
+	}
+}
+
+func parentPlays(t *Track, step int, parents ...*Pattern) bool {
+	for _, p := range parents {
+		for _, o := range p.tracks {
+			if instrumentKey(o.name) == instrumentKey(t.name) && o.steps[step] {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package drum
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CacheStats are the statistics of a DecodeCache.
+type CacheStats struct {
+	Hits      int64 // patterns found in memory
+	DiskHits  int64 // patterns found in the cache directory
+	Misses    int64 // patterns decoded, whether successfully or not
+	Evictions int64 // patterns dropped from memory to make room
+	Entries   int   // patterns in memory
+}
+
+// CacheOption configures a DecodeCache.
+type CacheOption func(*DecodeCache)
+
+// WithCacheDir makes the cache keep the files decoded by DecodeFile in dir
+// as well, so that files on slow storage like SD cards or network mounts
+// are read from the local copy by later processes as long as they do not
+// change. The directory is created when missing.
+func WithCacheDir(dir string) CacheOption {
+	return func(c *DecodeCache) {
+		c.dir = dir
+	}
+}
+
+// WithCacheDecodeOptions sets the options patterns are decoded with.
+func WithCacheDecodeOptions(opts ...DecodeOption) CacheOption {
+	return func(c *DecodeCache) {
+		c.decode = opts
+	}
+}
+
+// DecodeCache keeps the most recently decoded patterns, so that editors and
+// watchers reopening the same files do not parse unchanged data again.
+// Files are keyed by their path, modification time and size, data by its
+// content hash. Patterns are returned as copies that callers may change.
+// Errors are not cached. A DecodeCache is safe for concurrent use.
+type DecodeCache struct {
+	size   int
+	dir    string
+	decode []DecodeOption
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // of the lru list by key
+	lru     *list.List               // of *cacheEntry, most recently used first
+	stats   CacheStats
+}
+
+type cacheEntry struct {
+	key     string
+	pattern *Pattern
+}
+
+// NewDecodeCache returns a cache keeping up to size patterns in memory.
+func NewDecodeCache(size int, opts ...CacheOption) *DecodeCache {
+	c := &DecodeCache{size: max(size, 1), entries: make(map[string]*list.Element), lru: list.New()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// DecodeFile returns the pattern of the file at path, decoding it only when
+// the file changed since it was cached.
+func (c *DecodeCache) DecodeFile(path string) (*Pattern, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("file:%s:%d:%d", abs, info.ModTime().UnixNano(), info.Size())
+	if p := c.get(key); p != nil {
+		return p, nil
+	}
+	if p := c.readDisk(key); p != nil {
+		c.count(&c.stats.DiskHits)
+		return c.put(key, p), nil
+	}
+	c.count(&c.stats.Misses)
+	p, err := DecodeFile(path, c.decode...)
+	if err != nil {
+		return nil, err
+	}
+	c.writeDisk(key, p)
+	return c.put(key, p), nil
+}
+
+// DecodeBytes returns the pattern of the file data, decoding it only when
+// no data with the same content was cached. The cache directory is not used.
+func (c *DecodeCache) DecodeBytes(data []byte) (*Pattern, error) {
+	sum := sha256.Sum256(data)
+	key := "data:" + hex.EncodeToString(sum[:])
+	if p := c.get(key); p != nil {
+		return p, nil
+	}
+	c.count(&c.stats.Misses)
+	p, err := DecodeBytes(data, c.decode...)
+	if err != nil {
+		return nil, err
+	}
+	return c.put(key, p), nil
+}
+
+// Stats returns the statistics since the cache was created.
+func (c *DecodeCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Entries = c.lru.Len()
+	return s
+}
+
+// get returns a copy of the cached pattern or nil.
+func (c *DecodeCache) get(key string) *Pattern {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.stats.Hits++
+	c.lru.MoveToFront(e)
+	return e.Value.(*cacheEntry).pattern.Clone()
+}
+
+// count increments a counter of the stats.
+func (c *DecodeCache) count(counter *int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*counter++
+}
+
+// put caches the pattern and returns a copy.
+func (c *DecodeCache) put(key string, p *Pattern) *Pattern {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		// decoded concurrently
+		c.lru.MoveToFront(e)
+		return p.Clone()
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key, p})
+	for c.lru.Len() > c.size {
+		last := c.lru.Back()
+		c.lru.Remove(last)
+		delete(c.entries, last.Value.(*cacheEntry).key)
+		c.stats.Evictions++
+	}
+	return p.Clone()
+}
+
+// diskPath returns the file of the key in the cache directory.
+func (c *DecodeCache) diskPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+spliceFileExt)
+}
+
+// readDisk returns the pattern of the key in the cache directory or nil.
+func (c *DecodeCache) readDisk(key string) *Pattern {
+	if c.dir == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(c.diskPath(key))
+	if err != nil {
+		return nil
+	}
+	p, err := DecodeBytes(data, c.decode...)
+	if err != nil {
+		return nil
+	}
+	return p
+}
+
+// writeDisk stores the pattern of the key in the cache directory. Failures
+// only cost a decode later, so they are ignored.
+func (c *DecodeCache) writeDisk(key string, p *Pattern) {
+	if c.dir == "" {
+		return
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return
+	}
+	// written under another name first, so that readers never see a part
+	tmp, err := ioutil.TempFile(c.dir, "tmp-")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.diskPath(key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
@@ -0,0 +1,83 @@
+package drum
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDecodeCache(t *testing.T) {
+	dir := t.TempDir()
+	data1, err := ioutil.ReadFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data2, err := ioutil.ReadFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "a.splice")
+	if err := ioutil.WriteFile(file, data1, 0644); err != nil {
+		t.Fatal(err)
+	}
+	p1, _ := DecodeBytes(data1)
+	p2, _ := DecodeBytes(data2)
+
+	c := NewDecodeCache(1, WithCacheDir(filepath.Join(dir, "cache")))
+	for i := 0; i < 3; i++ {
+		p, err := c.DecodeFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.Equal(p1) {
+			t.Fatalf("Expected\n%s\nbut got\n%s", p1, p)
+		}
+		// copies are handed out
+		p.SetTempo(60)
+	}
+	if s := c.Stats(); s != (CacheStats{Hits: 2, Misses: 1, Entries: 1}) {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	// a changed file is decoded again
+	if err := ioutil.WriteFile(file, data2, 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := c.DecodeFile(file); err != nil || !p.Equal(p2) {
+		t.Fatalf("Expected the changed pattern but got %v, %v", p, err)
+	}
+	if s := c.Stats(); s.Misses != 2 || s.Evictions != 1 || s.Entries != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	// a new cache finds the file in the cache directory
+	c = NewDecodeCache(10, WithCacheDir(filepath.Join(dir, "cache")))
+	if p, err := c.DecodeFile(file); err != nil || !p.Equal(p2) {
+		t.Fatalf("Expected the pattern of the cache directory but got %v, %v", p, err)
+	}
+	if s := c.Stats(); s != (CacheStats{DiskHits: 1, Entries: 1}) {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	for i := 0; i < 2; i++ {
+		if p, err := c.DecodeBytes(data1); err != nil || !p.Equal(p1) {
+			t.Fatalf("Expected the pattern of the data but got %v, %v", p, err)
+		}
+	}
+	if _, err := c.DecodeBytes(data1[:20]); err == nil {
+		t.Error("Expected error")
+	}
+	if _, err := c.DecodeFile(filepath.Join(dir, "missing.splice")); err == nil {
+		t.Error("Expected error")
+	}
+	if s := c.Stats(); s != (CacheStats{Hits: 1, DiskHits: 1, Misses: 2, Entries: 2}) {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
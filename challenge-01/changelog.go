@@ -0,0 +1,194 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrUnknownOp is returned when applying an operation of an unknown kind.
+var ErrUnknownOp = errors.New("unknown operation")
+
+// OpKind is the kind of edit of an Op.
+type OpKind string
+
+// The kinds of operations.
+const (
+	OpToggleStep OpKind = "toggleStep"
+	OpSetTempo   OpKind = "setTempo"
+	OpAddTrack   OpKind = "addTrack"
+)
+
+// Op is an edit of a pattern that can be sent to other editors of the
+// pattern as JSON. Client and Clock identify the operation: the clock is a
+// Lamport clock of the ChangeLog the operation was made in, so operations
+// sort the same way for every editor. Which of the other fields are used
+// depends on the kind, see ToggleStepOp, SetTempoOp and AddTrackOp.
+type Op struct {
+	Kind   OpKind  `json:"kind"`
+	Client string  `json:"client"`
+	Clock  uint64  `json:"clock"`
+	Track  uint32  `json:"track,omitempty"`
+	Step   int     `json:"step,omitempty"`
+	Tempo  float32 `json:"tempo,omitempty"`
+	Name   string  `json:"name,omitempty"`
+	Steps  string  `json:"steps,omitempty"` // printout symbols like in JSON
+}
+
+// ToggleStepOp returns the operation turning the step of the track with the
+// id on when it is off and off when it is on.
+func ToggleStepOp(track uint32, step int) Op {
+	return Op{Kind: OpToggleStep, Track: track, Step: step}
+}
+
+// SetTempoOp returns the operation setting the tempo.
+func SetTempoOp(bpm float32) Op {
+	return Op{Kind: OpSetTempo, Tempo: bpm}
+}
+
+// AddTrackOp returns the operation appending a track with the id, name and
+// steps. Other data of the track is not part of the operation.
+func AddTrackOp(id uint32, name string, steps Steps) Op {
+	return Op{Kind: OpAddTrack, Track: id, Name: name, Steps: stepSymbols(steps)}
+}
+
+// Apply applies the operation to the pattern. It fails without changing
+// the pattern when the operation does not fit it, for example toggling a
+// step of a missing track or adding a track with an id already in use.
+func (op Op) Apply(p *Pattern) error {
+	switch op.Kind {
+	case OpToggleStep:
+		t := p.TrackByID(op.Track)
+		if t == nil {
+			return fmt.Errorf("toggle step: no track %d", op.Track)
+		}
+		if op.Step < 0 || op.Step >= stepsLength {
+			return ErrStepOutOfRange
+		}
+		t.steps[op.Step] = !t.steps[op.Step]
+		return nil
+	case OpSetTempo:
+		return p.SetTempo(op.Tempo)
+	case OpAddTrack:
+		if p.TrackByID(op.Track) != nil {
+			return fmt.Errorf("add track %d: %w", op.Track, ErrDuplicateTrackID)
+		}
+		t, err := trackJSON{ID: op.Track, Name: op.Name, Steps: op.Steps}.track()
+		if err != nil {
+			return fmt.Errorf("add track %d: %v", op.Track, err)
+		}
+		p.tracks = append(p.tracks, t)
+		return nil
+	}
+	return fmt.Errorf("%w %q", ErrUnknownOp, op.Kind)
+}
+
+// before reports whether op sorts before o in a change log.
+func (op Op) before(o Op) bool {
+	if op.Clock != o.Clock {
+		return op.Clock < o.Clock
+	}
+	return op.Client < o.Client
+}
+
+// ChangeLogOption configures a ChangeLog.
+type ChangeLogOption func(*ChangeLog)
+
+// WithOpHandler calls f with every operation made with Do, for example to
+// send it to the other editors.
+func WithOpHandler(f func(Op)) ChangeLogOption {
+	return func(c *ChangeLog) {
+		c.emit = f
+	}
+}
+
+// ChangeLog records the operations of an editor and those merged from other
+// editors of the same pattern, so that two or more clients can edit a
+// pattern together. Every client starts from the same base pattern, makes
+// its edits with Do, sends them to the others and merges theirs with Merge.
+// Replaying the log on the base pattern gives the same pattern for all
+// clients that merged the same operations, whatever the order they arrived
+// in. A ChangeLog is not safe for concurrent use.
+type ChangeLog struct {
+	client string
+	clock  uint64
+	ops    []Op // sorted by Op.before
+	emit   func(Op)
+}
+
+// NewChangeLog returns an empty change log of the client, which must be
+// unique among the editors of the pattern.
+func NewChangeLog(client string, opts ...ChangeLogOption) *ChangeLog {
+	c := &ChangeLog{client: client}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Do applies the operation to the pattern and records it stamped with the
+// client and clock of the log. The stamped operation is returned and passed
+// to the handler of the log. When the operation fails, nothing is recorded.
+func (c *ChangeLog) Do(p *Pattern, op Op) (Op, error) {
+	op.Client, op.Clock = c.client, c.clock+1
+	if err := op.Apply(p); err != nil {
+		return op, err
+	}
+	c.clock = op.Clock
+	c.ops = append(c.ops, op)
+	if c.emit != nil {
+		c.emit(op)
+	}
+	return op, nil
+}
+
+// Merge adds the operations of other editors to the log and returns the
+// number of operations not known before. Operations already in the log are
+// ignored, so logs can be exchanged with Since or completely.
+func (c *ChangeLog) Merge(ops ...Op) int {
+	added := 0
+	for _, op := range ops {
+		i := sort.Search(len(c.ops), func(i int) bool { return !c.ops[i].before(op) })
+		if i < len(c.ops) && c.ops[i].Clock == op.Clock && c.ops[i].Client == op.Client {
+			continue
+		}
+		c.ops = append(c.ops, Op{})
+		copy(c.ops[i+1:], c.ops[i:])
+		c.ops[i] = op
+		c.clock = max(c.clock, op.Clock)
+		added++
+	}
+	return added
+}
+
+// Ops returns a copy of the operations in the order they are replayed.
+func (c *ChangeLog) Ops() []Op {
+	return append([]Op(nil), c.ops...)
+}
+
+// Clock returns the clock of the latest operation in the log.
+func (c *ChangeLog) Clock() uint64 {
+	return c.clock
+}
+
+// Since returns the operations with a clock after the given one, for
+// example to send an editor the operations it has not seen yet.
+func (c *ChangeLog) Since(clock uint64) []Op {
+	i := sort.Search(len(c.ops), func(i int) bool { return c.ops[i].Clock > clock })
+	return append([]Op(nil), c.ops[i:]...)
+}
+
+// Replay applies the operations of the log in order to a copy of the base
+// pattern. Concurrent operations may conflict, like two clients adding a
+// track with the same id: operations that fail are skipped and returned, so
+// that all clients resolve the conflict the same way.
+func (c *ChangeLog) Replay(base *Pattern) (*Pattern, []Op) {
+	p := base.Clone()
+	var failed []Op
+	for _, op := range c.ops {
+		if err := op.Apply(p); err != nil {
+			failed = append(failed, op)
+		}
+	}
+	return p, failed
+}
@@ -0,0 +1,90 @@
+package drum
+
+import (
+	"encoding/json"
+	"errors"
+	"path"
+	"testing"
+)
+
+func TestChangeLog(t *testing.T) {
+	base, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent []Op
+	alice := NewChangeLog("alice", WithOpHandler(func(op Op) { sent = append(sent, op) }))
+	bob := NewChangeLog("bob")
+	pa, pb := base.Clone(), base.Clone()
+
+	if _, err := alice.Do(pa, ToggleStepOp(0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.Do(pa, SetTempoOp(100)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bob.Do(pb, SetTempoOp(90)); err != nil {
+		t.Fatal(err)
+	}
+	var steps Steps
+	steps[0] = true
+	if _, err := bob.Do(pb, AddTrackOp(9, "cowbell", steps)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bob.Do(pb, AddTrackOp(9, "clap", steps)); !errors.Is(err, ErrDuplicateTrackID) {
+		t.Errorf("Expected %v but got %v", ErrDuplicateTrackID, err)
+	}
+	if _, err := bob.Do(pb, ToggleStepOp(0, stepsLength)); err != ErrStepOutOfRange {
+		t.Errorf("Expected %v but got %v", ErrStepOutOfRange, err)
+	}
+	if len(sent) != 2 || sent[1].Client != "alice" || sent[1].Clock != 2 {
+		t.Errorf("Expected the operations of alice to be emitted but got %v", sent)
+	}
+
+	// exchange the operations as JSON
+	data, err := json.Marshal(alice.Since(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received []Op
+	if err := json.Unmarshal(data, &received); err != nil {
+		t.Fatal(err)
+	}
+	if n := bob.Merge(received...); n != 2 {
+		t.Errorf("Expected 2 new operations but got %d", n)
+	}
+	if n := bob.Merge(received...); n != 0 {
+		t.Errorf("Expected the operations to be known but got %d new", n)
+	}
+	alice.Merge(bob.Ops()...)
+
+	got, failed := alice.Replay(base)
+	exp, _ := bob.Replay(base)
+	if len(failed) != 0 {
+		t.Errorf("Expected no conflicts but got %v", failed)
+	}
+	if !got.Equal(exp) {
+		t.Errorf("Expected the same pattern for both clients but got %v", Diff(exp, got))
+	}
+	// bob set the tempo at clock 1 and alice at clock 2
+	if got.Tempo() != 100 || !got.tracks[0].steps[1] || got.TrackByID(9) == nil {
+		t.Errorf("Expected the edits of both clients but got:\n%v", got)
+	}
+	if alice.Clock() != 2 || len(alice.Since(1)) != 2 {
+		t.Errorf("Expected clock 2 and two operations after 1 but got %d and %v", alice.Clock(), alice.Since(1))
+	}
+
+	// concurrent tracks with the same id conflict
+	alice.Do(got, AddTrackOp(10, "clap", steps))
+	bob.Do(exp, AddTrackOp(10, "rim", steps))
+	alice.Merge(bob.Since(2)...)
+	bob.Merge(alice.Since(2)...)
+	got, failed = alice.Replay(base)
+	exp, _ = bob.Replay(base)
+	if len(failed) != 1 || failed[0].Client != "bob" || !got.Equal(exp) {
+		t.Errorf("Expected the track of bob to be skipped by both but got %v", failed)
+	}
+	if err := (Op{Kind: "removeTrack"}).Apply(got); !errors.Is(err, ErrUnknownOp) {
+		t.Errorf("Expected %v but got %v", ErrUnknownOp, err)
+	}
+}
@@ -0,0 +1,166 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+)
+
+var chunkChoke = chunkID{'C', 'H', 'O', 'K'}
+
+// FeatureChoke is the chunk of the choke groups of tracks.
+const FeatureChoke Feature = "choke"
+
+// MaxChokeGroup is the highest choke group.
+const MaxChokeGroup = 16
+
+// ErrInvalidChokeGroup is returned for choke groups outside of 0 and
+// MaxChokeGroup.
+var ErrInvalidChokeGroup = errors.New("invalid choke group")
+
+// chokeFade is the time in seconds a choked sound fades out instead of
+// stopping with a click.
+const chokeFade = 0.002
+
+// ChokeGroup returns the choke group of the track, 0 for none.
+func (t *Track) ChokeGroup() int {
+	return int(t.choke)
+}
+
+// SetChokeGroup puts the track into a choke group from 1 to MaxChokeGroup,
+// 0 takes it out. A track triggering cuts the sounds of the other tracks of
+// its group still ringing, like a closed hi-hat cuts the open one. The group
+// overrides the one of the kit, see Kit.SetChokeGroup.
+func (t *Track) SetChokeGroup(group int) error {
+	if group < 0 || group > MaxChokeGroup {
+		return fmt.Errorf("%w %d", ErrInvalidChokeGroup, group)
+	}
+	t.choke = uint8(group)
+	return nil
+}
+
+// SetChokeGroup sets the choke group of tracks with the given name that have
+// no group of their own, see Track.SetChokeGroup. Group 0 removes it.
+func (k *Kit) SetChokeGroup(name string, group int) error {
+	if group < 0 || group > MaxChokeGroup {
+		return fmt.Errorf("%w %d", ErrInvalidChokeGroup, group)
+	}
+	if group == 0 {
+		delete(k.chokes, normalizeName(name))
+		return nil
+	}
+	k.chokes[normalizeName(name)] = group
+	return nil
+}
+
+// ChokeGroup returns the choke group of the track, the one of the track or
+// else the one the kit sets for its name or alias. The kit may be nil.
+func (k *Kit) ChokeGroup(t *Track) int {
+	if t.choke != 0 || k == nil {
+		return int(t.choke)
+	}
+	key := normalizeName(t.name)
+	if g, ok := k.chokes[key]; ok {
+		return g
+	}
+	return k.chokes[k.aliases[key]]
+}
+
+// chokeFrames returns for every event the frame its sound is cut at by the
+// next event of another track of its choke group, -1 for sounds ringing
+// out. Of tracks of a group triggering together the last one is heard.
+func chokeFrames(p *Pattern, kit *Kit, events []noteEvent, frameAt func(tick int) int) []int {
+	type next struct {
+		frame, track int // nearest later event of the group
+		other        int // frame of the nearest one of another track
+	}
+	cuts := make([]int, len(events))
+	groups := make(map[int]*next)
+	for i := len(events) - 1; i >= 0; i-- {
+		e := events[i]
+		cuts[i] = -1
+		g := kit.ChokeGroup(p.tracks[e.track])
+		if g == 0 {
+			continue
+		}
+		start := frameAt(e.tick)
+		n, ok := groups[g]
+		if !ok {
+			groups[g] = &next{start, e.track, -1}
+			continue
+		}
+		if n.track != e.track {
+			cuts[i] = n.frame
+			n.frame, n.track, n.other = start, e.track, n.frame
+			continue
+		}
+		cuts[i] = n.other
+		n.frame = start
+	}
+	return cuts
+}
+
+// choked returns the tracks of the pattern not triggered whose choke group is
+// the one of a triggered track.
+func choked(p *Pattern, kit *Kit, triggered []*Track) []*Track {
+	groups := make(map[int]bool)
+	for _, t := range triggered {
+		if g := kit.ChokeGroup(t); g != 0 {
+			groups[g] = true
+		}
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	var cut []*Track
+	for _, t := range p.tracks {
+		if groups[kit.ChokeGroup(t)] && !containsTrack(triggered, t) {
+			cut = append(cut, t)
+		}
+	}
+	return cut
+}
+
+func containsTrack(tracks []*Track, t *Track) bool {
+	for _, o := range tracks {
+		if o == t {
+			return true
+		}
+	}
+	return false
+}
+
+// The choke chunk stores |Track index (2 bytes)|Group (1 byte)| for every
+// track in a choke group.
+
+func decodeChokeChunk(data []byte, p *Pattern) error {
+	const entryLength = 3
+	if len(data)%entryLength != 0 {
+		return errors.New("invalid size")
+	}
+	for ; len(data) > 0; data = data[entryLength:] {
+		index := int(data[0]) | int(data[1])<<8
+		if index >= len(p.tracks) {
+			return fmt.Errorf("track index %d out of range", index)
+		}
+		if err := p.tracks[index].SetChokeGroup(int(data[2])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeChokeChunk(p *Pattern) []byte {
+	var data []byte
+	for i, t := range p.tracks {
+		if t.choke != 0 {
+			data = append(data, byte(i), byte(i>>8), t.choke)
+		}
+	}
+	return data
+}
+
+func clearChoke(p *Pattern) {
+	for _, t := range p.tracks {
+		t.choke = 0
+	}
+}
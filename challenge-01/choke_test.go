@@ -0,0 +1,128 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// hatPattern returns an open hat on step 0 choked by a closed hat on step 1.
+func hatPattern() *Pattern {
+	return &Pattern{tempo: 120, tracks: []*Track{
+		{name: "hh-open", steps: Steps{true}},
+		{name: "hh-close", steps: Steps{false, true}},
+	}}
+}
+
+func TestChokeGroup(t *testing.T) {
+	tr := &Track{name: "hh-open"}
+	if err := tr.SetChokeGroup(MaxChokeGroup + 1); !errors.Is(err, ErrInvalidChokeGroup) {
+		t.Errorf("Expected %v but got %v", ErrInvalidChokeGroup, err)
+	}
+	kit := NewKit("test")
+	kit.Alias("hh-open", "open")
+	if err := kit.SetChokeGroup("open", 2); err != nil {
+		t.Fatal(err)
+	}
+	if g := kit.ChokeGroup(tr); g != 2 {
+		t.Errorf("Expected the group of the kit alias but got %d", g)
+	}
+	tr.SetChokeGroup(1)
+	if g := kit.ChokeGroup(tr); g != 1 {
+		t.Errorf("Expected the group of the track but got %d", g)
+	}
+	var none *Kit
+	if g := none.ChokeGroup(tr); g != 1 {
+		t.Errorf("Expected the group of the track without kit but got %d", g)
+	}
+}
+
+func TestChokeRender(t *testing.T) {
+	// 120 BPM at 8000 frames per second gives 1000 frames per step
+	ring := &Sample{Rate: 8000, Left: make([]float32, 3000), Right: make([]float32, 3000)}
+	for i := range ring.Left {
+		ring.Left[i], ring.Right[i] = 1, 1
+	}
+	kit := NewKit("test")
+	kit.Add("hh-open", ring)
+	kit.Add("hh-close", &Sample{Rate: 8000, Left: []float32{0}, Right: []float32{0}})
+	p := hatPattern()
+	out, err := Render(p, kit, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Left[1500] == 0 {
+		t.Fatal("Expected the open hat to ring without choke group")
+	}
+
+	kit.SetChokeGroup("hh-open", 1)
+	kit.SetChokeGroup("hh-close", 1)
+	if out, err = Render(p, kit, 1); err != nil {
+		t.Fatal(err)
+	}
+	if out.Left[999] == 0 {
+		t.Error("Expected the open hat until the closed hat")
+	}
+	if l := out.Left[1001]; l == 0 || l >= out.Left[999] {
+		t.Errorf("Expected the open hat to fade out but got %v", l)
+	}
+	if l := out.Left[1000+int(chokeFade*8000)]; l != 0 {
+		t.Errorf("Expected the open hat cut but got %v", l)
+	}
+}
+
+func TestChokePlayer(t *testing.T) {
+	p := hatPattern()
+	p.tracks[0].SetChokeGroup(1)
+	p.tracks[1].SetChokeGroup(1)
+	pl := NewPlayer(p, nil)
+	ev, _, err := pl.advance(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ev.Choked) != 1 || ev.Choked[0] != p.tracks[1] {
+		t.Errorf("Expected the closed hat choked but got %v", ev.Choked)
+	}
+	if ev, _, _ = pl.advance(time.Now()); len(ev.Choked) != 1 || ev.Choked[0] != p.tracks[0] {
+		t.Errorf("Expected the open hat choked but got %v", ev.Choked)
+	}
+	if ev, _, _ = pl.advance(time.Now()); ev.Choked != nil {
+		t.Errorf("Expected nothing choked without trigger but got %v", ev.Choked)
+	}
+}
+
+func TestChokeRoundTrip(t *testing.T) {
+	p, err := NewPattern("0.808-alpha", 120, hatPattern().tracks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.tracks[0].SetChokeGroup(MaxChokeGroup)
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(p) || decoded.tracks[0].ChokeGroup() != MaxChokeGroup {
+		t.Errorf("Expected the choke group to round trip but got %v", Diff(p, decoded))
+	}
+	if got := decoded.Features(); len(got) != 1 || got[0] != FeatureChoke {
+		t.Errorf("Expected the choke feature but got %v", got)
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Pattern
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !fromJSON.Equal(p) {
+		t.Errorf("Expected the choke group to round trip JSON but got %s", data)
+	}
+}
@@ -0,0 +1,564 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+	"github.com/alpe/go-challenge/challenge-01/audio"
+	"github.com/alpe/go-challenge/challenge-01/generate"
+	"github.com/alpe/go-challenge/challenge-01/library"
+	"github.com/alpe/go-challenge/challenge-01/pipeline"
+)
+
+func runShow(args []string) error {
+	name := arg(args, 0)
+	p, err := readPattern(name)
+	if err != nil {
+		return err
+	}
+	if outputFormat != "" {
+		return writeDocument(drum.NewPatternOutput(name, p))
+	}
+	fmt.Print(p)
+	return nil
+}
+
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	width := fs.Int("width", 80, "largest line length, 0 for no limit")
+	themeName := fs.String("theme", "", "print the steps of all tracks below the summary in the theme: "+strings.Join(drum.ThemeNames(), ", "))
+	fs.Parse(args)
+	var opts []drum.FormatOption
+	if *themeName != "" {
+		theme, ok := drum.ThemeByName(*themeName)
+		if !ok {
+			return fmt.Errorf("unknown theme %q", *themeName)
+		}
+		opts = []drum.FormatOption{drum.WithoutHeader(), drum.WithTheme(theme)}
+	}
+	names := fs.Args()
+	if len(names) == 0 {
+		names = []string{stdio}
+	}
+	pad := 0
+	for _, name := range names {
+		pad = max(pad, utf8.RuneCountInString(name))
+	}
+	for _, name := range names {
+		p, err := readPattern(name)
+		if err != nil {
+			return err
+		}
+		summaryWidth := 0
+		if *width > 0 {
+			summaryWidth = max(*width-pad-2, 1)
+		}
+		fmt.Printf("%-*s  %s\n", pad, name, p.Summary(summaryWidth))
+		if opts != nil {
+			if err := drum.Format(os.Stdout, p, opts...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func runInspect(args []string) error {
+	in, err := openInput(arg(args, 0))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return drum.Inspect(in, os.Stdout)
+}
+
+func runRepair(args []string) error {
+	in, err := openInput(arg(args, 0))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	p, report, err := drum.Repair(in)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, report)
+	return writePattern(arg(args, 1), p)
+}
+
+func runLint(args []string) error {
+	name := arg(args, 0)
+	p, err := readPattern(name)
+	if err != nil {
+		return err
+	}
+	issues := drum.Validate(p)
+	if outputFormat != "" {
+		if err := writeDocument(drum.NewIssuesOutput(name, issues)); err != nil {
+			return err
+		}
+	} else {
+		for _, i := range issues {
+			fmt.Println(i)
+		}
+	}
+	if drum.HasErrors(issues) {
+		return fmt.Errorf("pattern has errors")
+	}
+	return nil
+}
+
+func runBatch(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: batch <manifest>")
+	}
+	pl, err := pipeline.LoadManifest(args[0])
+	if err != nil {
+		return err
+	}
+	report, err := pl.Run(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Print(report)
+	if len(report.Failures) > 0 {
+		return fmt.Errorf("%d patterns failed", len(report.Failures))
+	}
+	return nil
+}
+
+func runUnpack(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: unpack <bank> <dir>")
+	}
+	b, err := drum.OpenBank(args[0])
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	if dryRun != nil {
+		for i := 0; i < b.Len(); i++ {
+			fmt.Fprintf(dryRun, "%s: would write\n", filepath.Join(args[1], b.Entry(i).Name()))
+		}
+		return nil
+	}
+	report, err := b.Export(args[1], saveOptions...)
+	fmt.Fprintln(os.Stderr, report)
+	return err
+}
+
+func runRenumber(args []string) error {
+	fs := flag.NewFlagSet("renumber", flag.ExitOnError)
+	mapFlag := fs.String("map", "", "comma separated old=new track ids, numbers the tracks by name when empty")
+	fs.Parse(args)
+	p, err := readPattern(arg(fs.Args(), 0))
+	if err != nil {
+		return err
+	}
+	from := p.Clone()
+	if *mapFlag == "" {
+		drum.NormalizeIDs(p)
+		return writeTransformed(arg(fs.Args(), 1), from, p)
+	}
+	mapping, err := parseIDMapping(*mapFlag)
+	if err != nil {
+		return err
+	}
+	if err := drum.RemapIDs(p, mapping); err != nil {
+		return err
+	}
+	return writeTransformed(arg(fs.Args(), 1), from, p)
+}
+
+func runLabel(args []string) error {
+	fs := flag.NewFlagSet("label", flag.ExitOnError)
+	track := fs.String("track", "", "label the track with the name instead of the pattern")
+	remove := fs.Bool("rm", false, "remove the labels instead of adding them")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return fmt.Errorf("missing labels")
+	}
+	p, err := readPattern(arg(fs.Args(), 1))
+	if err != nil {
+		return err
+	}
+	from := p.Clone()
+	labels, set := p.Labels(), p.SetLabels
+	if *track != "" {
+		t := p.TrackByName(*track)
+		if t == nil {
+			return fmt.Errorf("%w %q", drum.ErrTrackNotFound, *track)
+		}
+		labels, set = t.Labels(), t.SetLabels
+	}
+	for _, s := range strings.Split(fs.Arg(0), ",") {
+		name, color, _ := strings.Cut(strings.TrimSpace(s), "=")
+		labels = editLabels(labels, drum.Label{Name: name, Color: color}, *remove)
+	}
+	if err := set(labels...); err != nil {
+		return err
+	}
+	return writeTransformed(arg(fs.Args(), 2), from, p)
+}
+
+// editLabels returns the labels with l added, or recolored when set, or
+// removed.
+func editLabels(labels []drum.Label, l drum.Label, remove bool) []drum.Label {
+	for i, o := range labels {
+		if !strings.EqualFold(o.Name, l.Name) {
+			continue
+		}
+		if remove {
+			return append(labels[:i], labels[i+1:]...)
+		}
+		labels[i] = l
+		return labels
+	}
+	if remove {
+		return labels
+	}
+	return append(labels, l)
+}
+
+// parseIDMapping parses a list like "0=36,1=38".
+func parseIDMapping(s string) (map[uint32]uint32, error) {
+	mapping := make(map[uint32]uint32)
+	for _, pair := range strings.Split(s, ",") {
+		old, id, ok := strings.Cut(pair, "=")
+		from, err := strconv.ParseUint(strings.TrimSpace(old), 10, 32)
+		if err != nil || !ok {
+			return nil, fmt.Errorf("invalid id mapping %q", pair)
+		}
+		to, err := strconv.ParseUint(strings.TrimSpace(id), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id mapping %q", pair)
+		}
+		mapping[uint32(from)] = uint32(to)
+	}
+	return mapping, nil
+}
+
+func runTransform(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: transform <reverse|double|half|rotate=n|rotatebars=n|quantize=n> [in] [out]")
+	}
+	p, err := readPattern(arg(args, 1))
+	if err != nil {
+		return err
+	}
+	from := p.Clone()
+	op, value, _ := strings.Cut(args[0], "=")
+	switch op {
+	case "reverse":
+		p = p.Reverse()
+	case "double":
+		p = p.DoubleTime()
+	case "half":
+		p = p.HalfTime()
+	case "rotate":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid rotation %q", value)
+		}
+		p = p.Rotate(n)
+	case "rotatebars":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid rotation %q", value)
+		}
+		p = p.RotateBars(n)
+	case "quantize":
+		grid, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid grid %q", value)
+		}
+		if p, err = drum.Quantize(p, grid); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown transform %q", args[0])
+	}
+	return writeTransformed(arg(args, 2), from, p)
+}
+
+func runRetempo(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: retempo <bpm> [in] [out]")
+	}
+	bpm, err := strconv.ParseFloat(args[0], 32)
+	if err != nil {
+		return fmt.Errorf("invalid tempo %q", args[0])
+	}
+	p, err := readPattern(arg(args, 1))
+	if err != nil {
+		return err
+	}
+	from := p.Clone()
+	if err := p.SetTempo(float32(bpm)); err != nil {
+		return err
+	}
+	return writeTransformed(arg(args, 2), from, p)
+}
+
+func runGroove(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: groove <from> [in] [out]")
+	}
+	from, err := readPattern(args[0])
+	if err != nil {
+		return err
+	}
+	p, err := readPattern(arg(args, 1))
+	if err != nil {
+		return err
+	}
+	return writeTransformed(arg(args, 2), p, drum.ApplyGroove(p, drum.ExtractGroove(from)))
+}
+
+func runMerge(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: merge <from> [in] [out]")
+	}
+	from, err := readPattern(args[0])
+	if err != nil {
+		return err
+	}
+	p, err := readPattern(arg(args, 1))
+	if err != nil {
+		return err
+	}
+	return writeTransformed(arg(args, 2), p, drum.Merge(p, from))
+}
+
+func runScript(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: script <file> [in] [out]")
+	}
+	src, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	s, err := drum.ParseScript(string(src))
+	if err != nil {
+		return fmt.Errorf("%s: %v", args[0], err)
+	}
+	p, err := readPattern(arg(args, 1))
+	if err != nil {
+		return err
+	}
+	edited, err := s.Apply(p)
+	if err != nil {
+		return err
+	}
+	return writeTransformed(arg(args, 2), p, edited)
+}
+
+func runGenerate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	seed := fs.Int64("seed", 1, "seed of the random choices")
+	out := fs.String("o", stdio, "output file")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: generate [-seed n] [-o out] <file>...")
+	}
+	m := &generate.Model{}
+	for _, name := range fs.Args() {
+		p, err := readPattern(name)
+		if err != nil {
+			return err
+		}
+		m.Add(p)
+	}
+	p, err := m.Sample(rand.New(rand.NewSource(*seed)))
+	if err != nil {
+		return err
+	}
+	return writePattern(*out, p)
+}
+
+func runAnalyze(args []string) error {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	asCSV := fs.Bool("csv", false, "write one CSV row per file instead of JSON")
+	fs.Parse(args)
+	names := fs.Args()
+	if len(names) == 0 {
+		names = []string{stdio}
+	}
+	var reports []drum.Report
+	for _, name := range names {
+		p, err := readPattern(name)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, drum.Analyze(p))
+	}
+	if outputFormat != "" && !*asCSV {
+		return writeDocument(drum.NewAnalysisOutput(names, reports))
+	}
+	return writeOutput(stdio, func(w io.Writer) error {
+		if *asCSV {
+			return drum.WriteReportsCSV(w, names, reports)
+		}
+		if len(reports) == 1 {
+			return encodeJSON(w, reports[0])
+		}
+		return encodeJSON(w, reports)
+	})
+}
+
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	title := fs.String("title", "Pattern library", "title of the report")
+	tmplFile := fs.String("template", "", "template file instead of the HTML index")
+	text := fs.Bool("text", false, "execute the template as text/template instead of html/template")
+	out := fs.String("o", stdio, "output file")
+	fs.Parse(args)
+	if fs.NArg() == 0 || *text && *tmplFile == "" {
+		return fmt.Errorf("usage: report [-title s] [-template file [-text]] [-o out] <dir>...")
+	}
+	tmpl := ""
+	if *tmplFile != "" {
+		b, err := ioutil.ReadFile(*tmplFile)
+		if err != nil {
+			return err
+		}
+		tmpl = string(b)
+	}
+	c, err := library.NewCatalog(context.Background(), *title, fs.Args()...)
+	if err != nil {
+		return err
+	}
+	return writeOutput(*out, func(w io.Writer) error {
+		if *text {
+			return c.WriteText(w, tmpl)
+		}
+		return c.WriteHTML(w, tmpl)
+	})
+}
+
+func runPlay(args []string) error {
+	fs := flag.NewFlagSet("play", flag.ExitOnError)
+	bars := fs.Int("bars", 0, "number of bars to play, 0 to play until interrupted")
+	kitPath := fs.String("kit", "", "kit directory or manifest that must have a sample for every track")
+	link := fs.Bool("link", false, "follow the tempo and phase of an Ableton Link session")
+	midiPort := fs.String("midi", "", "MIDI port to send clock and notes to, \"list\" to list the ports")
+	sound := fs.Bool("audio", false, "play the samples of the kit through the speakers, needs a build with -tags oto")
+	buffer := fs.Duration("buffer", audio.DefaultBufferSize, "audio buffer size, smaller for less latency")
+	countIn := fs.Int("countin", 0, "bars of metronome to count in")
+	region := fs.String("loop", "", "steps to loop like 9-16, counted from 1")
+	keysDir := fs.String("keys", "", "play only patterns signed by a key in this directory, see sign")
+	fs.Parse(args)
+	if *midiPort == "list" {
+		ports, err := drum.MIDIOutPorts()
+		for _, port := range ports {
+			fmt.Printf("%s\t%s\n", port.Name, port.Path)
+		}
+		return err
+	}
+	var p *drum.Pattern
+	var err error
+	if *keysDir != "" {
+		p, err = readVerifiedPattern(arg(fs.Args(), 0), *keysDir)
+	} else {
+		p, err = readPattern(arg(fs.Args(), 0))
+	}
+	if err != nil {
+		return err
+	}
+	var kit *drum.Kit
+	if *kitPath != "" {
+		if kit, err = drum.LoadKit(*kitPath); err != nil {
+			return err
+		}
+		if err := kit.Validate(p); err != nil {
+			return err
+		}
+	}
+	var mixer *drum.Mixer
+	if *sound {
+		if kit == nil {
+			return fmt.Errorf("audio needs a kit, see -kit")
+		}
+		if mixer, err = drum.NewMixer(kit); err != nil {
+			return err
+		}
+		out, err := audio.Open(mixer, audio.WithBufferSize(*buffer))
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	var opts []drum.PlayerOption
+	if *midiPort != "" {
+		out, err := drum.OpenMIDIPort(*midiPort)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		opts = append(opts, drum.WithMIDIOut(out))
+	}
+	if *countIn > 0 {
+		opts = append(opts, drum.WithCountIn(*countIn))
+	}
+	if *link {
+		session, err := drum.JoinLink()
+		if err != nil {
+			return err
+		}
+		defer session.Close()
+		opts = append(opts, drum.WithSync(session))
+	}
+
+	stop := make(chan struct{})
+	var once sync.Once
+	halt := func() { once.Do(func() { close(stop) }) }
+	steps := 0
+	player := drum.NewPlayer(p, func(ev drum.StepEvent) {
+		if mixer != nil {
+			mixer.Handle(ev)
+		}
+		names := make([]string, len(ev.Tracks))
+		for i, t := range ev.Tracks {
+			names[i] = t.Name()
+		}
+		if ev.CountIn {
+			fmt.Printf("%2d %s (count in)\n", ev.Step+1, strings.Join(names, " "))
+			return
+		}
+		fmt.Printf("%2d %s\n", ev.Step+1, strings.Join(names, " "))
+		steps++
+		if *bars > 0 && steps == *bars*len(drum.Steps{}) {
+			halt()
+		}
+	}, opts...)
+	player.SetKit(kit)
+	if *region != "" {
+		var from, to int
+		if _, err := fmt.Sscanf(*region, "%d-%d", &from, &to); err != nil {
+			return fmt.Errorf("invalid loop %q, expected steps like 9-16", *region)
+		}
+		if err := player.SetLoopRegion(from-1, to-1); err != nil {
+			return err
+		}
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		halt()
+	}()
+	return player.Play(stop)
+}
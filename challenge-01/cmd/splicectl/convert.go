@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// convertOptions are the flags of the convert command used by some formats.
+type convertOptions struct {
+	bars   int
+	kit    *drum.Kit
+	render []drum.RenderOption // for wav
+}
+
+// formats are the output formats of the convert command.
+var formats = map[string]func(w io.Writer, p *drum.Pattern, o convertOptions) error{
+	"json": func(w io.Writer, p *drum.Pattern, o convertOptions) error {
+		return encodeJSON(w, p)
+	},
+	"yaml": func(w io.Writer, p *drum.Pattern, o convertOptions) error {
+		return p.ToYAML(w)
+	},
+	"csv": func(w io.Writer, p *drum.Pattern, o convertOptions) error {
+		return p.ToCSV(w)
+	},
+	"midi": func(w io.Writer, p *drum.Pattern, o convertOptions) error {
+		return drum.WriteMIDI(w, p, o.bars)
+	},
+	"wav": func(w io.Writer, p *drum.Pattern, o convertOptions) error {
+		if o.kit == nil {
+			return fmt.Errorf("wav needs a kit, see -kit")
+		}
+		return drum.RenderWAV(w, p, o.kit, o.bars, o.render...)
+	},
+	"webmidi": func(w io.Writer, p *drum.Pattern, o convertOptions) error {
+		return drum.WriteWebMIDI(w, p)
+	},
+	"hydrogen": func(w io.Writer, p *drum.Pattern, o convertOptions) error {
+		return drum.WriteHydrogen(w, p)
+	},
+	"h2pattern": func(w io.Writer, p *drum.Pattern, o convertOptions) error {
+		return drum.WriteHydrogenPattern(w, p)
+	},
+	"als": func(w io.Writer, p *drum.Pattern, o convertOptions) error {
+		return drum.WriteAbleton(w, p)
+	},
+	"tracker": func(w io.Writer, p *drum.Pattern, o convertOptions) error {
+		return drum.WriteTracker(w, p)
+	},
+	"musicxml": func(w io.Writer, p *drum.Pattern, o convertOptions) error {
+		return p.ToMusicXML(w)
+	},
+	"lilypond": func(w io.Writer, p *drum.Pattern, o convertOptions) error {
+		return p.ToLilyPond(w)
+	},
+	"sonicpi": func(w io.Writer, p *drum.Pattern, o convertOptions) error {
+		return drum.WriteSonicPi(w, p)
+	},
+	"supercollider": func(w io.Writer, p *drum.Pattern, o convertOptions) error {
+		return drum.WriteSuperCollider(w, p)
+	},
+	"svg": func(w io.Writer, p *drum.Pattern, o convertOptions) error {
+		return p.ToSVG(w, drum.DefaultGridStyle)
+	},
+	"html": func(w io.Writer, p *drum.Pattern, o convertOptions) error {
+		return p.ToHTML(w, drum.DefaultGridStyle)
+	},
+	"png": func(w io.Writer, p *drum.Pattern, o convertOptions) error {
+		return p.ToPNG(w, drum.DefaultGridStyle)
+	},
+	"markdown": func(w io.Writer, p *drum.Pattern, o convertOptions) error {
+		_, err := io.WriteString(w, p.ToMarkdown())
+		return err
+	},
+}
+
+func formatNames() string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func runDecode(args []string) error {
+	p, err := readPattern(arg(args, 0))
+	if err != nil {
+		return err
+	}
+	return writeOutput(arg(args, 1), func(w io.Writer) error {
+		return encodeJSON(w, p)
+	})
+}
+
+func runEncode(args []string) error {
+	in, err := openInput(arg(args, 0))
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(in)
+	in.Close()
+	if err != nil {
+		return err
+	}
+	var p drum.Pattern
+	if err := json.Unmarshal(data, &p); err != nil {
+		return fmt.Errorf("parse pattern: %v", err)
+	}
+	return writePattern(arg(args, 1), &p)
+}
+
+func runConvert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	to := fs.String("to", "", "output format: "+formatNames())
+	bars := fs.Int("bars", 1, "number of bars for midi and wav")
+	kitPath := fs.String("kit", "", "kit directory or manifest for wav")
+	trim := fs.Bool("trim", false, "detect the leading silence of the kit samples and start them early to land on the grid")
+	click := fs.Int("click", 0, "add a metronome track with the given clicks per beat")
+	accent := fs.Bool("accent", false, "accent the first beat of the metronome")
+	duck := fs.Float64("duck", 0, "duck the other tracks of wav by this depth from 0 to 1 when the kick hits")
+	limit := fs.String("limit", "", "limit wav to the ceiling in dBFS, like -0.3")
+	out := fs.String("out", "", "convert the files matching the glob arguments to this output path template, like '{{.Dir}}/{{.Name}}.mid'")
+	jobs := fs.Int("jobs", runtime.NumCPU(), "files converted at the same time with -out")
+	fs.Parse(args)
+	write, ok := formats[*to]
+	if !ok {
+		return fmt.Errorf("unknown format %q, use one of %s", *to, formatNames())
+	}
+	o := convertOptions{bars: *bars}
+	if *duck > 0 {
+		o.render = append(o.render, drum.WithDucking(drum.Ducking{Depth: *duck, Attack: 5 * time.Millisecond, Release: 150 * time.Millisecond}))
+	}
+	if *limit != "" {
+		ceiling, err := strconv.ParseFloat(*limit, 64)
+		if err != nil {
+			return fmt.Errorf("invalid ceiling %q", *limit)
+		}
+		o.render = append(o.render, drum.WithLimiter(ceiling))
+	}
+	if *kitPath != "" {
+		var err error
+		if o.kit, err = drum.LoadKit(*kitPath); err != nil {
+			return err
+		}
+		if *trim {
+			o.kit.DetectStartOffsets()
+		}
+	}
+	prepare := func(p *drum.Pattern) (*drum.Pattern, error) {
+		if *click > 0 {
+			return drum.AddMetronome(p, drum.MetronomeConfig{Subdivision: *click, Accent: *accent})
+		}
+		return p, nil
+	}
+	if *out != "" {
+		if fs.NArg() == 0 {
+			return fmt.Errorf("usage: convert -to <format> -out <template> <glob>...")
+		}
+		conv := fileConversion{format: *to, prepare: prepare, write: func(w io.Writer, p *drum.Pattern) error {
+			return write(w, p, o)
+		}}
+		return conv.run(fs.Args(), *out, *jobs, os.Stdout)
+	}
+	p, err := readPattern(arg(fs.Args(), 0))
+	if err != nil {
+		return err
+	}
+	if p, err = prepare(p); err != nil {
+		return err
+	}
+	return writeOutput(arg(fs.Args(), 1), func(w io.Writer) error {
+		return write(w, p, o)
+	})
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	from := fs.String("from", "hydrogen", "input format: hydrogen, als, an Ableton Live set, or grid, a plain text \"kick: x---x---\" file")
+	names := fs.String("instruments", "", "comma separated track names by Hydrogen instrument id")
+	clip := fs.String("clip", "", "name or number from 1 of the drum clip of a Live set, by default the first")
+	fs.Parse(args)
+	if *from != "hydrogen" && *from != "grid" && *from != "als" {
+		return fmt.Errorf("unknown input format %q", *from)
+	}
+	var instruments []string
+	if *names != "" {
+		instruments = strings.Split(*names, ",")
+	}
+	in, err := openInput(arg(fs.Args(), 0))
+	if err != nil {
+		return err
+	}
+	var p *drum.Pattern
+	switch *from {
+	case "grid":
+		p, err = drum.ParseGrid(in)
+	case "als":
+		var patterns []*drum.Pattern
+		if patterns, err = drum.ReadAbleton(in); err == nil {
+			p, err = selectClip(patterns, *clip)
+		}
+	default:
+		p, err = drum.ReadHydrogenPattern(bufio.NewReader(in), instruments)
+	}
+	in.Close()
+	if err != nil {
+		return err
+	}
+	return writePattern(arg(fs.Args(), 1), p)
+}
+
+// selectClip returns the pattern of the clip with the name or number
+// counted from 1, the first one for an empty name.
+func selectClip(patterns []*drum.Pattern, name string) (*drum.Pattern, error) {
+	if name == "" {
+		return patterns[0], nil
+	}
+	for _, p := range patterns {
+		if p.Metadata().Title == name {
+			return p, nil
+		}
+	}
+	if n, err := strconv.Atoi(name); err == nil && n >= 1 && n <= len(patterns) {
+		return patterns[n-1], nil
+	}
+	return nil, fmt.Errorf("no clip %q in %d drum clips", name, len(patterns))
+}
+
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	side := fs.Bool("side", false, "print the patterns side by side with the differences marked")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: diff [-side] <a> <b>")
+	}
+	a, err := readPattern(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, err := readPattern(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	if outputFormat != "" {
+		return writeDocument(drum.NewDiffOutput(fs.Arg(0), fs.Arg(1), a, b))
+	}
+	return writeOutput(stdio, func(w io.Writer) error {
+		if *side {
+			return drum.FormatSideBySide(w, a, b)
+		}
+		return writeDiff(w, a, b)
+	})
+}
+
+func writeDiff(w io.Writer, a, b *drum.Pattern) error {
+	for _, d := range drum.Diff(a, b) {
+		if _, err := fmt.Fprintln(w, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeJSON writes v as indented JSON to w.
+func encodeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeOutput calls write with the named output, see createOutput. A dry
+// run prints the size of the data for files instead, see dryRun.
+func writeOutput(name string, write func(w io.Writer) error) error {
+	if dryRun != nil && name != stdio {
+		return summarizeOutput(name, write)
+	}
+	if name != stdio {
+		return drum.WriteFileAtomic(name, write, saveOptions...)
+	}
+	out, err := createOutput(name)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(out)
+	if err := write(w); err != nil {
+		out.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// summarizeOutput prints the size of the data write would write to the
+// named file and how it compares to the file.
+func summarizeOutput(name string, write func(w io.Writer) error) error {
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		return err
+	}
+	change := "new file"
+	if old, err := ioutil.ReadFile(name); err == nil {
+		change = fmt.Sprintf("replacing %d bytes", len(old))
+		if bytes.Equal(old, buf.Bytes()) {
+			change = "unchanged"
+		}
+	}
+	_, err := fmt.Fprintf(dryRun, "%s: would write %d bytes, %s\n", name, buf.Len(), change)
+	return err
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+func TestDecodeEncode(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "splicectl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	fixture := filepath.Join("..", "..", "fixtures", "pattern_2.splice")
+	jsonFile := filepath.Join(tmp, "pattern.json")
+	spliceFile := filepath.Join(tmp, "pattern.splice")
+	if err := runDecode([]string{fixture, jsonFile}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runEncode([]string{jsonFile, spliceFile}); err != nil {
+		t.Fatal(err)
+	}
+	exp, err := drum.DecodeFile(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := drum.DecodeFile(spliceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(exp) {
+		t.Errorf("Expected:\n%v\nbut got:\n%v", exp, got)
+	}
+
+	var buf bytes.Buffer
+	got.SetTempo(100)
+	if err := writeDiff(&buf, exp, got); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "tempo: 98.4 -> 100\n"; buf.String() != exp {
+		t.Errorf("Expected '%v' but got '%v'", exp, buf.String())
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "splicectl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	fixture := filepath.Join("..", "..", "fixtures", "pattern_1.splice")
+	for name := range formats {
+		out := filepath.Join(tmp, "out."+name)
+		err := runConvert([]string{"-to", name, fixture, out})
+		if name == "wav" {
+			if err == nil {
+				t.Error("expected error for wav without kit")
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("convert to %s: %v", name, err)
+			continue
+		}
+		if info, err := os.Stat(out); err != nil || info.Size() == 0 {
+			t.Errorf("convert to %s: no output", name)
+		}
+	}
+	if err := runConvert([]string{"-to", "mp3", fixture}); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestImport(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "splicectl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	fixture := filepath.Join("..", "..", "fixtures", "pattern_1.splice")
+	h2 := filepath.Join(tmp, "pattern.h2pattern")
+	out := filepath.Join(tmp, "pattern.splice")
+	if err := runConvert([]string{"-to", "h2pattern", fixture, h2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runImport([]string{"-instruments", "kick,snare", h2, out}); err != nil {
+		t.Fatal(err)
+	}
+	exp, err := drum.DecodeFile(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := drum.DecodeFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracks := got.Tracks()
+	if len(tracks) != len(exp.Tracks()) || tracks[1].Name() != "snare" || tracks[2].Name() != "instrument 2" {
+		t.Fatalf("unexpected tracks:\n%v", got)
+	}
+	for i, tr := range tracks {
+		if tr.Steps() != exp.Tracks()[i].Steps() {
+			t.Errorf("track %d: expected %v but got %v", i, exp.Tracks()[i].Steps(), tr.Steps())
+		}
+	}
+
+	grid := filepath.Join(tmp, "pattern.txt")
+	if err := ioutil.WriteFile(grid, []byte("tempo: 98\nkick: x---x---x---x---\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runImport([]string{"-from", "grid", grid, out}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = drum.DecodeFile(out); err != nil || got.Tempo() != 98 || len(got.Tracks()) != 1 {
+		t.Errorf("unexpected grid import %v: %v", got, err)
+	}
+	als := filepath.Join(tmp, "pattern.als")
+	if err := runConvert([]string{"-to", "als", fixture, als}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runImport([]string{"-from", "als", "-clip", "1", als, out}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = drum.DecodeFile(out); err != nil || len(got.Tracks()) != len(exp.Tracks()) || got.Tracks()[0].Steps() != exp.Tracks()[0].Steps() {
+		t.Errorf("unexpected Live set import %v: %v", got, err)
+	}
+	if err := runImport([]string{"-from", "als", "-clip", "verse", als, out}); err == nil {
+		t.Error("expected error for a missing clip")
+	}
+	if err := runImport([]string{"-from", "midi", grid, out}); err == nil {
+		t.Error("expected error for unknown input format")
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+	"github.com/alpe/go-challenge/challenge-01/pipeline"
+)
+
+// outputName is the data of the output path template of convert.
+type outputName struct {
+	Dir    string // of the input file
+	Name   string // of the input file without extension
+	Format string // of the -to flag
+}
+
+// fileConversion converts many files at once, see run.
+type fileConversion struct {
+	format  string
+	prepare func(p *drum.Pattern) (*drum.Pattern, error)
+	write   func(w io.Writer, p *drum.Pattern) error
+	outputs map[string]string // output path by entry name
+}
+
+// run converts the files matching the glob patterns to the paths of the
+// output template in parallel and writes a table of the results to w. The
+// error tells how many failed.
+func (c *fileConversion) run(patterns []string, out string, jobs int, w io.Writer) error {
+	tmpl, err := template.New("out").Option("missingkey=error").Parse(out)
+	if err != nil {
+		return fmt.Errorf("parse output template: %v", err)
+	}
+	inputs, err := globFiles(patterns)
+	if err != nil {
+		return err
+	}
+	c.outputs = make(map[string]string, len(inputs))
+	entries := make([]pipeline.Entry, len(inputs))
+	byOutput := make(map[string]string)
+	for i, in := range inputs {
+		ext := filepath.Ext(in)
+		var b strings.Builder
+		if err := tmpl.Execute(&b, outputName{filepath.Dir(in), strings.TrimSuffix(filepath.Base(in), ext), c.format}); err != nil {
+			return fmt.Errorf("output template: %v", err)
+		}
+		o := filepath.Clean(b.String())
+		if prev, ok := byOutput[o]; ok {
+			return fmt.Errorf("%s and %s are both converted to %s", prev, in, o)
+		}
+		byOutput[o] = in
+		name := filepath.ToSlash(strings.TrimSuffix(in, ext))
+		if _, ok := c.outputs[name]; ok {
+			return fmt.Errorf("%s is given twice with different extensions", name)
+		}
+		c.outputs[name] = o
+		in := in
+		entries[i] = pipeline.Entry{Name: name, Load: func() (*drum.Pattern, error) { return drum.DecodeFile(in) }}
+	}
+	pl := pipeline.Pipeline{
+		Source:     entrySource(entries),
+		Transforms: []pipeline.Transform{pipeline.TransformFunc("prepare", c.prepare)},
+		Sinks:      []pipeline.Sink{c},
+		Workers:    jobs,
+	}
+	report, err := pl.Run(context.Background())
+	if err != nil {
+		return err
+	}
+	failed := make(map[string]pipeline.Failure, len(report.Failures))
+	for _, f := range report.Failures {
+		failed[f.Name] = f
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "INPUT\tOUTPUT\tRESULT")
+	for i, in := range inputs {
+		result := "ok"
+		if f, ok := failed[entries[i].Name]; ok {
+			result = fmt.Sprintf("%s: %v", f.Stage, f.Err)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", in, c.outputs[entries[i].Name], result)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%d of %d files converted, %d failed\n", report.Processed, report.Entries, len(report.Failures))
+	if len(report.Failures) > 0 {
+		return fmt.Errorf("%d of %d files failed", len(report.Failures), report.Entries)
+	}
+	return nil
+}
+
+// Name implements pipeline.Sink.
+func (c *fileConversion) Name() string {
+	return c.format
+}
+
+// Write implements pipeline.Sink writing the pattern to its output path,
+// creating the directory when missing unless in a dry run.
+func (c *fileConversion) Write(name string, p *drum.Pattern) error {
+	out := c.outputs[name]
+	if dryRun == nil {
+		if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+			return err
+		}
+	}
+	return writeOutput(out, func(w io.Writer) error {
+		return c.write(w, p)
+	})
+}
+
+// entrySource is a pipeline.Source of the entries.
+type entrySource []pipeline.Entry
+
+func (s entrySource) Entries() ([]pipeline.Entry, error) {
+	return s, nil
+}
+
+// globFiles returns the sorted files matching the glob patterns, each at
+// most once. Patterns matching nothing are an error.
+func globFiles(patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %v", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", pattern)
+		}
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				files = append(files, m)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+func TestConvertFiles(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "splicectl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	for _, name := range []string{"pattern_1.splice", "pattern_2.splice"} {
+		data, err := ioutil.ReadFile(filepath.Join("..", "..", "fixtures", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(tmp, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmp, "broken.splice"), []byte("SPLICE"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	conv := fileConversion{
+		format:  "midi",
+		prepare: func(p *drum.Pattern) (*drum.Pattern, error) { return p, nil },
+		write: func(w io.Writer, p *drum.Pattern) error {
+			return formats["midi"](w, p, convertOptions{bars: 1})
+		},
+	}
+	var out bytes.Buffer
+	err = conv.run([]string{filepath.Join(tmp, "*.splice"), filepath.Join(tmp, "pattern_1.splice")}, "{{.Dir}}/midi/{{.Name}}.{{.Format}}", 2, &out)
+	if err == nil || err.Error() != "1 of 3 files failed" {
+		t.Errorf("Expected the broken file to fail but got %v", err)
+	}
+	for _, name := range []string{"pattern_1.midi", "pattern_2.midi"} {
+		if info, err := os.Stat(filepath.Join(tmp, "midi", name)); err != nil || info.Size() == 0 {
+			t.Errorf("Expected %s to be written but got %v", name, err)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "INPUT") || !strings.Contains(lines[1], "load: parse payload size") ||
+		!strings.HasSuffix(lines[2], "ok") || lines[4] != "2 of 3 files converted, 1 failed" {
+		t.Errorf("Unexpected summary\n%s", out.String())
+	}
+
+	if err := conv.run([]string{filepath.Join(tmp, "*.splice")}, "{{.Dir}}/same.mid", 2, &out); err == nil {
+		t.Error("Expected an error for files converted to the same output")
+	}
+	if err := conv.run([]string{filepath.Join(tmp, "*.wav")}, "{{.Name}}.mid", 2, &out); err == nil {
+		t.Error("Expected an error for a glob matching nothing")
+	}
+	if err := runConvert([]string{"-to", "midi", "-out", filepath.Join(tmp, "{{.Name}}.mid"), filepath.Join(tmp, "pattern_*.splice")}); err != nil {
+		t.Errorf("convert -out: %v", err)
+	}
+}
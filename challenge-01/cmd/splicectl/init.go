@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// packManifest describes a pattern pack. It is written by init as template
+// for the vendor to fill in.
+type packManifest struct {
+	Name        string   `json:"name"`
+	Version     string   `json:"version"`
+	Author      string   `json:"author"`
+	Description string   `json:"description"`
+	Patterns    string   `json:"patterns"` // directory of the .splice files
+	Kits        string   `json:"kits"`     // directory of the sample kits
+	Tags        []string `json:"tags"`
+}
+
+// packValidation configures the checks run on the patterns of a pack.
+type packValidation struct {
+	MinTempo     float32 `json:"minTempo"`
+	MaxTempo     float32 `json:"maxTempo"`
+	AllowEmpty   bool    `json:"allowEmptyTracks"`
+	RequireNames bool    `json:"requireTrackNames"`
+}
+
+const (
+	manifestFile   = "pack.json"
+	validationFile = "validation.json"
+)
+
+func runInit(args []string) error {
+	if len(args) < 2 || args[0] != "pack" {
+		return fmt.Errorf("usage: init pack <dir>")
+	}
+	return initPack(args[1])
+}
+
+// initPack creates the directory layout of a new pattern pack. It fails when
+// dir already contains a manifest.
+func initPack(dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, manifestFile)); err == nil {
+		return fmt.Errorf("%s already contains a pack", dir)
+	}
+	for _, sub := range []string{"patterns", "kits"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return err
+		}
+	}
+	manifest := packManifest{
+		Name:     filepath.Base(dir),
+		Version:  "0.1.0",
+		Patterns: "patterns",
+		Kits:     "kits",
+		Tags:     []string{},
+	}
+	if err := writeJSON(filepath.Join(dir, manifestFile), manifest); err != nil {
+		return err
+	}
+	validation := packValidation{MinTempo: drum.MinTempo, MaxTempo: drum.MaxTempo, RequireNames: true}
+	if err := writeJSON(filepath.Join(dir, validationFile), validation); err != nil {
+		return err
+	}
+	return writeExamplePattern(filepath.Join(dir, "patterns", "example.splice"))
+}
+
+// writeExamplePattern writes a plain four on the floor beat as a starting
+// point.
+func writeExamplePattern(path string) error {
+	var kick, hat drum.Steps
+	for i := range kick {
+		kick[i] = i%4 == 0
+		hat[i] = i%4 == 2
+	}
+	kickTrack, err := drum.NewTrack(0, "kick", kick)
+	if err != nil {
+		return err
+	}
+	hatTrack, err := drum.NewTrack(1, "hh-close", hat)
+	if err != nil {
+		return err
+	}
+	p, err := drum.NewPattern("0.808-alpha", 120, kickTrack, hatTrack)
+	if err != nil {
+		return err
+	}
+	return drum.EncodeFile(path, p)
+}
+
+func writeJSON(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+func TestInitPack(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "pack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	dir := filepath.Join(tmp, "my-pack")
+
+	if err := initPack(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{manifestFile, validationFile, "kits"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("missing %s: %v", name, err)
+		}
+	}
+	p, err := drum.DecodeFile(filepath.Join(dir, "patterns", "example.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Tracks()) != 2 {
+		t.Errorf("unexpected example pattern:\n%v", p)
+	}
+	if err := initPack(dir); err == nil {
+		t.Errorf("expected error for existing pack")
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// stdio is the file name for stdin or stdout.
+const stdio = "-"
+
+// dryRun receives what the commands would write to files instead of
+// writing them, see the -dry-run flag. Nil writes the files.
+var dryRun io.Writer
+
+// saveOptions are the options files are written with, see the -backups flag.
+var saveOptions []drum.SaveOption
+
+// outputFormat is the format show, diff, lint and analyze write their
+// results in, see the -format flag. Empty prints text.
+var outputFormat drum.OutputFormat
+
+// writeDocument writes the output document to stdout in outputFormat.
+func writeDocument(v interface{}) error {
+	return writeOutput(stdio, func(w io.Writer) error {
+		return outputFormat.Write(w, v)
+	})
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// openInput opens the named file or stdin for "-".
+func openInput(name string) (io.ReadCloser, error) {
+	if name == stdio {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	return os.Open(name)
+}
+
+// createOutput creates the named file or returns stdout for "-".
+func createOutput(name string) (io.WriteCloser, error) {
+	if name == stdio {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(name)
+}
+
+// arg returns the i-th argument or stdio when there are not enough.
+func arg(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return stdio
+}
+
+// readPattern decodes the named file, stdin for "-" or the file at a http
+// or https URL. Downloads are revalidated with the copies kept in the user
+// cache directory.
+func readPattern(name string) (*drum.Pattern, error) {
+	if strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://") {
+		var opts []drum.FetchOption
+		if dir, err := os.UserCacheDir(); err == nil {
+			opts = append(opts, drum.WithURLCache(drum.NewURLCache(filepath.Join(dir, "splicectl", "urls"))))
+		}
+		return drum.DecodeURL(context.Background(), name, opts...)
+	}
+	in, err := openInput(name)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	return drum.Decode(bufio.NewReader(in))
+}
+
+// writePattern writes the pattern to the named output. A dry run prints the
+// differences to the pattern already in the file instead, see
+// writeTransformed.
+func writePattern(name string, p *drum.Pattern) error {
+	return writeTransformed(name, nil, p)
+}
+
+// writeTransformed writes the pattern changed from the pattern from to the
+// named output. A dry run prints the differences to from instead, or to the
+// pattern already in the file when from is nil.
+func writeTransformed(name string, from, p *drum.Pattern) error {
+	if err := writeOutput(name, func(w io.Writer) error {
+		return drum.Encode(w, p)
+	}); err != nil || dryRun == nil || name == stdio {
+		return err
+	}
+	if from == nil {
+		if existing, err := drum.DecodeFile(name); err == nil {
+			from = existing
+		}
+	}
+	if from == nil {
+		return nil
+	}
+	diffs := drum.Diff(from, p)
+	if len(diffs) == 0 {
+		_, err := fmt.Fprintln(dryRun, "\tno pattern changes")
+		return err
+	}
+	for _, d := range diffs {
+		if _, err := fmt.Fprintf(dryRun, "\t%v\n", d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
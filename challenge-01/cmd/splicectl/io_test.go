@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "splicectl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	raw, err := ioutil.ReadFile(filepath.Join("..", "..", "fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(tmp, "beat.splice")
+	if err := ioutil.WriteFile(file, raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	dryRun = &buf
+	defer func() { dryRun = nil }()
+
+	if err := runRetempo([]string{"120", file, file}); err != nil {
+		t.Fatal(err)
+	}
+	if exp := file + ": would write 157 bytes, replacing 157 bytes\n\ttempo: 98.4 -> 120\n"; buf.String() != exp {
+		t.Errorf("Expected\n%s\nbut got\n%s", exp, buf.String())
+	}
+	buf.Reset()
+	if err := runTransform([]string{"reverse", file, file}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "steps") {
+		t.Errorf("Expected the changed steps but got\n%s", buf.String())
+	}
+	buf.Reset()
+	if err := runMerge([]string{filepath.Join("..", "..", "fixtures", "pattern_1.splice"), file, file}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "steps") {
+		t.Errorf("Expected the merged steps but got\n%s", buf.String())
+	}
+	buf.Reset()
+	mid := filepath.Join(tmp, "beat.mid")
+	if err := runConvert([]string{"-to", "midi", file, mid}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), mid+": would write ") || !strings.HasSuffix(buf.String(), "bytes, new file\n") {
+		t.Errorf("Unexpected summary %s", buf.String())
+	}
+
+	if got, err := ioutil.ReadFile(file); err != nil || !bytes.Equal(got, raw) {
+		t.Errorf("Expected the pattern unchanged but got %v", err)
+	}
+	if _, err := os.Stat(mid); !os.IsNotExist(err) {
+		t.Errorf("Expected no MIDI file but got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+// Command splicectl decodes, edits and plays drum machine .splice files.
+//
+// Usage:
+//
+//	splicectl <command> [arguments]
+//
+// Every file argument may be "-" for stdin or stdout, which is also the
+// default when it is omitted. Commands can therefore be chained in pipelines:
+//
+//	curl -s https://example.com/beat.splice | splicectl retempo 120 | splicectl play -
+//
+// With -dry-run the commands print the sizes of the files they would write
+// and the changes to the patterns in them instead of writing them:
+//
+//	splicectl -dry-run retempo 120 beat.splice beat.splice
+//
+// Files are replaced atomically, -backups n keeps their previous versions.
+//
+// With -format json or -format yaml show, diff, lint and analyze write
+// versioned documents for scripts and CI checks instead of text, see
+// drum.OutputSchema:
+//
+//	splicectl -format json lint beat.splice | jq '.issues[].code'
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"show", "show [file]\n\tprint the pattern in the printout format", runShow},
+		{"list", "list [-width n] [-theme name] <file>...\n\tprint a one line summary of every pattern, with -theme followed by its steps", runList},
+		{"inspect", "inspect [file]\n\tprint an annotated hex dump and flag where parsing fails", runInspect},
+		{"repair", "repair [in] [out]\n\tfix the payload size, a truncated track and padding and write the pattern", runRepair},
+		{"analyze", "analyze [-csv] [file...]\n\tprint the density, syncopation, backbeat and complexity of the patterns", runAnalyze},
+		{"report", "report [-title s] [-template file [-text]] [-o out] <dir>...\n\twrite an HTML catalog of the patterns with thumbnails and a tempo histogram or execute a template", runReport},
+		{"lint", "lint [file]\n\tprint issues of the pattern and fail on errors", runLint},
+		{"retempo", "retempo <bpm> [in] [out]\n\tset the tempo and write the pattern", runRetempo},
+		{"label", "label [-track name] [-rm] <name[=#rrggbb],...> [in] [out]\n\tadd colored labels to the pattern or a track, or remove them, and write the pattern", runLabel},
+		{"renumber", "renumber [-map old=new,...] [in] [out]\n\tchange the track ids or number the tracks by name and write the pattern", runRenumber},
+		{"transform", "transform <reverse|double|half|rotate=n|rotatebars=n|quantize=n> [in] [out]\n\treverse, speed up, slow down, rotate or quantize the steps or rotate the bars and write the pattern", runTransform},
+		{"groove", "groove <from> [in] [out]\n\tapply the timing, accents and swing of another pattern and write the pattern", runGroove},
+		{"merge", "merge <from> [in] [out]\n\tadd the tracks and steps of another pattern and write the pattern", runMerge},
+		{"script", "script <file> [in] [out]\n\tedit the pattern with a script of drum.ParseScript and write the pattern", runScript},
+		{"generate", "generate [-seed n] [-o out] <file>...\n\twrite a new pattern in the style of the patterns", runGenerate},
+		{"decode", "decode [in] [out]\n\twrite the pattern as JSON", runDecode},
+		{"encode", "encode [in] [out]\n\twrite a JSON pattern in the .splice format", runEncode},
+		{"convert", "convert -to <format> [-bars n] [-kit dir [-trim]] [-click n [-accent]] [-duck depth] [-limit dB] [-out template [-jobs n] <glob>... | [in] [out]]\n\texport the pattern, for example as midi, wav or svg, -out converts many files", runConvert},
+		{"import", "import [-from hydrogen|als|grid] [-instruments name,...] [-clip name] [in] [out]\n\tconvert a Hydrogen .h2pattern file, a drum clip of an Ableton Live set or a plain text grid to the .splice format", runImport},
+		{"diff", "diff [-side] <a> <b>\n\tprint the differences between two patterns, -side prints them next to each other", runDiff},
+		{"play", "play [-bars n] [-kit dir [-audio [-buffer d]]] [-midi port] [-link] [-countin n] [-loop from-to] [-keys dir] [file]\n\tplay the pattern and print the triggered tracks", runPlay},
+		{"batch", "batch <manifest>\n\tconvert a collection of patterns as configured by a YAML pipeline manifest", runBatch},
+		{"init", "init pack <dir>\n\tcreate the layout of a new pattern pack", runInit},
+		{"unpack", "unpack <bank> <dir>\n\twrite all patterns of a bank to the directory, all or nothing", runUnpack},
+		{"keygen", "keygen <name>\n\twrite a new ed25519 key pair for signing to name.key and name.pub", runKeygen},
+		{"sign", "sign -key file <file>...\n\twrite the signatures of patterns next to them or add a signed manifest to banks", runSign},
+		{"verify", "verify -keys dir <file>...\n\tcheck the signatures of patterns and banks with the trusted public keys in dir", runVerify},
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [-offline] [-dry-run] [-backups n] [-format text|json|yaml] <command> [arguments]\n\nCommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %s\n", c.usage)
+	}
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("splicectl: ")
+	offline := flag.Bool("offline", drum.IsOffline(), "disable all network access")
+	dry := flag.Bool("dry-run", false, "print the changes and sizes of the files the command would write instead of writing them")
+	backups := flag.Int("backups", 0, "keep the n previous versions of replaced files as name.bak to name.bak.n")
+	format := flag.String("format", "text", "write the results of show, diff, lint and analyze as text, json or yaml")
+	flag.Usage = usage
+	flag.Parse()
+	switch f := drum.OutputFormat(*format); f {
+	case "text":
+	case drum.OutputJSON, drum.OutputYAML:
+		outputFormat = f
+	default:
+		log.Printf("unknown format %q", *format)
+		usage()
+		os.Exit(2)
+	}
+	drum.SetOffline(*offline)
+	saveOptions = []drum.SaveOption{drum.WithBackups(*backups)}
+	if *dry {
+		dryRun = os.Stdout
+	}
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+	name := flag.Arg(0)
+	for _, c := range commands {
+		if c.name == name {
+			if err := c.run(flag.Args()[1:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+	log.Printf("unknown command %q", name)
+	usage()
+	os.Exit(2)
+}
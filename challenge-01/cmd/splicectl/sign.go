@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+func runKeygen(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: keygen <name>")
+	}
+	pub, key, err := drum.GenerateKey()
+	if err != nil {
+		return err
+	}
+	if err := drum.SavePrivateKey(args[0]+".key", key); err != nil {
+		return err
+	}
+	if err := drum.SavePublicKey(args[0]+".pub", pub); err != nil {
+		return err
+	}
+	fmt.Printf("key %s: sign with %s.key, publish %s.pub\n", drum.KeyID(pub), args[0], args[0])
+	return nil
+}
+
+func runSign(args []string) error {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	keyFile := fs.String("key", "", "private key of keygen")
+	fs.Parse(args)
+	if *keyFile == "" || fs.NArg() == 0 {
+		return fmt.Errorf("usage: sign -key file <file>...")
+	}
+	key, err := drum.LoadPrivateKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	for _, name := range fs.Args() {
+		if isBank(name) {
+			err = signBank(name, key)
+		} else {
+			err = signFile(name, key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// signFile writes the signature of the file next to it.
+func signFile(name string, key ed25519.PrivateKey) error {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	// files that do not decode are not worth signing
+	if _, err := drum.DecodeBytes(data); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	sig := drum.Sign(data, key)
+	return writeOutput(name+drum.SignatureExt, func(w io.Writer) error {
+		text, _ := sig.MarshalText()
+		_, err := w.Write(text)
+		return err
+	})
+}
+
+// signBank adds the signed manifest to the bank.
+func signBank(name string, key ed25519.PrivateKey) error {
+	b, err := drum.OpenBank(name)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	if err := b.Sign(key); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	if dryRun != nil {
+		return summarizeOutput(name, b.Write)
+	}
+	return b.Save(name, saveOptions...)
+}
+
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	keysDir := fs.String("keys", "", "directory of the trusted public keys")
+	fs.Parse(args)
+	if *keysDir == "" || fs.NArg() == 0 {
+		return fmt.Errorf("usage: verify -keys dir <file>...")
+	}
+	keys, err := drum.LoadKeyring(*keysDir)
+	if err != nil {
+		return err
+	}
+	for _, name := range fs.Args() {
+		if isBank(name) {
+			err = verifyBank(name, keys)
+		} else {
+			_, err = drum.VerifyFile(name, keys)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: ok\n", name)
+	}
+	return nil
+}
+
+func verifyBank(name string, keys drum.Keyring) error {
+	b, err := drum.OpenBank(name)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	if err := b.Verify(keys); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// readVerifiedPattern decodes the named file after verifying its signature
+// with the trusted public keys in keysDir.
+func readVerifiedPattern(name, keysDir string) (*drum.Pattern, error) {
+	keys, err := drum.LoadKeyring(keysDir)
+	if err != nil {
+		return nil, err
+	}
+	return drum.VerifyFile(name, keys)
+}
+
+func isBank(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".zip")
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+func TestSignVerify(t *testing.T) {
+	dir := t.TempDir()
+	keys := filepath.Join(dir, "keys")
+	os.Mkdir(keys, 0755)
+	if err := runKeygen([]string{filepath.Join(keys, "publisher")}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join("..", "..", "fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "beat.splice")
+	ioutil.WriteFile(file, data, 0644)
+	if err := runVerify([]string{"-keys", keys, file}); !errors.Is(err, drum.ErrUnsigned) {
+		t.Errorf("Expected an unsigned file but got %v", err)
+	}
+	if err := runSign([]string{"-key", filepath.Join(keys, "publisher.key"), file}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runVerify([]string{"-keys", keys, file}); err != nil {
+		t.Errorf("Expected a valid signature but got %v", err)
+	}
+	if _, err := readVerifiedPattern(file, keys); err != nil {
+		t.Errorf("Expected the verified pattern but got %v", err)
+	}
+	data[len(data)-1] ^= 1
+	ioutil.WriteFile(file, data, 0644)
+	if _, err := readVerifiedPattern(file, keys); !errors.Is(err, drum.ErrInvalidSignature) {
+		t.Errorf("Expected the changed file to fail but got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// request is a single command sent to the daemon.
+type request struct {
+	Cmd      string         `json:"cmd"`
+	Path     string         `json:"path,omitempty"`
+	Features []drum.Feature `json:"features,omitempty"`
+}
+
+// response answers a request. Error is empty on success.
+type response struct {
+	Error    string         `json:"error,omitempty"`
+	Output   string         `json:"output,omitempty"`
+	Features []drum.Feature `json:"features,omitempty"`
+	Playing  bool           `json:"playing"`
+	Position int            `json:"position"`
+}
+
+// daemon holds the player shared by all connections.
+type daemon struct {
+	mu       sync.Mutex
+	pattern  *drum.Pattern
+	player   *drum.Player
+	playing  chan struct{} // closed to stop playback, nil when stopped
+	finished chan struct{} // closed when the playing goroutine returned
+	verbose  bool
+}
+
+func newDaemon(verbose bool) *daemon {
+	d := daemon{verbose: verbose}
+	d.player = drum.NewPlayer(nil, d.onStep)
+	return &d
+}
+
+func (d *daemon) onStep(ev drum.StepEvent) {
+	if !d.verbose {
+		return
+	}
+	names := make([]string, len(ev.Tracks))
+	for i, t := range ev.Tracks {
+		names[i] = t.Name()
+	}
+	log.Printf("%2d %s", ev.Step+1, strings.Join(names, " "))
+}
+
+// serve accepts connections until the listener is closed.
+func (d *daemon) serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("accept connection: %v", err)
+		}
+		go d.handleConn(conn)
+	}
+}
+
+func (d *daemon) handleConn(conn net.Conn) {
+	defer conn.Close()
+	enc := json.NewEncoder(conn)
+	s := bufio.NewScanner(conn)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		var req request
+		var resp response
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			resp = response{Error: fmt.Sprintf("parse command: %v", err)}
+		} else {
+			resp = d.handle(req)
+		}
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// handle executes a single command.
+func (d *daemon) handle(req request) response {
+	var out string
+	var features []drum.Feature
+	var err error
+	switch req.Cmd {
+	case "hello":
+		features = drum.Negotiate(drum.SupportedFeatures(), req.Features)
+	case "load":
+		err = d.load(req.Path, true)
+	case "switch":
+		err = d.load(req.Path, false)
+	case "play":
+		err = d.play()
+	case "stop":
+		d.stop()
+	case "render":
+		out, err = d.render()
+	case "status":
+	default:
+		err = fmt.Errorf("unknown command %q", req.Cmd)
+	}
+	resp := d.status()
+	resp.Output = out
+	resp.Features = features
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return resp
+}
+
+// load decodes the file and makes it the current pattern. When reset is set
+// playback starts from the first step again.
+func (d *daemon) load(path string, reset bool) error {
+	if path == "" {
+		return errors.New("missing path")
+	}
+	p, err := drum.DecodeFile(path)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pattern = p
+	d.player.SetPattern(p)
+	if reset {
+		d.player.Seek(0)
+	}
+	return nil
+}
+
+func (d *daemon) play() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.pattern == nil {
+		return drum.ErrNoPattern
+	}
+	if d.playing != nil {
+		return nil
+	}
+	stop, finished := make(chan struct{}), make(chan struct{})
+	d.playing, d.finished = stop, finished
+	go func() {
+		defer close(finished)
+		if err := d.player.Play(stop); err != nil {
+			log.Printf("play: %v", err)
+		}
+		// Play returns by itself on errors, so status and play must see
+		// the playback stopped
+		d.mu.Lock()
+		if d.playing == stop {
+			d.playing, d.finished = nil, nil
+		}
+		d.mu.Unlock()
+	}()
+	return nil
+}
+
+func (d *daemon) stop() {
+	d.mu.Lock()
+	stop, finished := d.playing, d.finished
+	d.playing, d.finished = nil, nil
+	d.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-finished
+}
+
+func (d *daemon) render() (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.pattern == nil {
+		return "", drum.ErrNoPattern
+	}
+	return d.pattern.String(), nil
+}
+
+func (d *daemon) status() response {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return response{Playing: d.playing != nil, Position: d.player.Position()}
+}
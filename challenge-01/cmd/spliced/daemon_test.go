@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDaemon(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spliced")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	l, err := net.Listen("unix", filepath.Join(dir, "splice.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newDaemon(false)
+	go d.serve(l)
+	defer l.Close()
+	defer d.stop()
+
+	conn, err := net.Dial("unix", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	send := func(cmd string) response {
+		if _, err := fmt.Fprintln(conn, cmd); err != nil {
+			t.Fatal(err)
+		}
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp response
+		if err := json.Unmarshal(line, &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := send(`{"cmd":"hello","features":["display","polyrhythm"]}`); fmt.Sprint(resp.Features) != "[display]" {
+		t.Errorf("unexpected features: %+v", resp)
+	}
+	if resp := send(`{"cmd":"play"}`); resp.Error == "" {
+		t.Errorf("expected error when playing without pattern")
+	}
+	fixture := filepath.Join("..", "..", "fixtures", "pattern_2.splice")
+	if resp := send(fmt.Sprintf(`{"cmd":"load","path":%q}`, fixture)); resp.Error != "" {
+		t.Fatal(resp.Error)
+	}
+	if resp := send(`{"cmd":"play"}`); resp.Error != "" || !resp.Playing {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if resp := send(`{"cmd":"render"}`); resp.Output == "" {
+		t.Errorf("expected printout but got: %+v", resp)
+	}
+	if resp := send(`{"cmd":"stop"}`); resp.Playing {
+		t.Errorf("expected playback to be stopped: %+v", resp)
+	}
+	if resp := send(`{"cmd":"dance"}`); resp.Error == "" {
+		t.Errorf("expected error for unknown command")
+	}
+	if resp := send(`not json`); resp.Error == "" {
+		t.Errorf("expected error for invalid command")
+	}
+}
+
+func TestDaemonPlayError(t *testing.T) {
+	d := newDaemon(false)
+	if err := d.load(filepath.Join("..", "..", "fixtures", "pattern_2.splice"), true); err != nil {
+		t.Fatal(err)
+	}
+	// an invalid tempo makes Play fail at the first step
+	d.player.SetTempo(-1)
+	if err := d.play(); err != nil {
+		t.Fatal(err)
+	}
+	d.mu.Lock()
+	finished := d.finished
+	d.mu.Unlock()
+	<-finished
+	if resp := d.status(); resp.Playing {
+		t.Errorf("Expected playback stopped after the error but got %+v", resp)
+	}
+	d.player.SetTempo(0)
+	if err := d.play(); err != nil {
+		t.Fatal(err)
+	}
+	if resp := d.status(); !resp.Playing {
+		t.Errorf("Expected play to start again but got %+v", resp)
+	}
+	d.stop()
+}
+
+func TestRemoveStaleSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spliced")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	stale, file := filepath.Join(dir, "stale.sock"), filepath.Join(dir, "beat.splice")
+	l, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+	if err := ioutil.WriteFile(file, []byte("SPLICE"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{stale, file, filepath.Join(dir, "missing.sock")} {
+		if err := removeStaleSocket(path); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+	if _, err := os.Lstat(stale); !os.IsNotExist(err) {
+		t.Errorf("Expected the stale socket removed but got %v", err)
+	}
+	if _, err := os.Lstat(file); err != nil {
+		t.Errorf("Expected the file kept but got %v", err)
+	}
+
+	running := filepath.Join(dir, "running.sock")
+	l, err = net.Listen("unix", running)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if err := removeStaleSocket(running); err == nil {
+		t.Error("Expected an error for the socket of a running daemon")
+	}
+	if _, err := os.Lstat(running); err != nil {
+		t.Errorf("Expected the socket of the running daemon kept but got %v", err)
+	}
+}
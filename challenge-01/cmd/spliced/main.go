@@ -0,0 +1,82 @@
+// Command spliced plays drum machine .splice files in the background and is
+// controlled with JSON commands over a Unix socket.
+//
+// Usage:
+//
+//	spliced --socket /run/splice.sock
+//
+// Every line sent to the socket is a command object; every command is
+// answered by one line with a response object:
+//
+//	{"cmd":"hello","features":["display"]} return the features supported by both sides
+//	{"cmd":"load","path":"beat.splice"}  load a pattern and make it current
+//	{"cmd":"play"}                       start playback of the current pattern
+//	{"cmd":"stop"}                       stop playback
+//	{"cmd":"switch","path":"fill.splice"} replace the pattern while playing
+//	{"cmd":"render"}                     return the printout of the pattern
+//	{"cmd":"status"}                     return playback state and position
+//
+// For example:
+//
+//	echo '{"cmd":"play"}' | nc -U /run/splice.sock
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	socket := flag.String("socket", "/run/splice.sock", "path of the control socket")
+	verbose := flag.Bool("v", false, "log every step played")
+	flag.Parse()
+	log.SetPrefix("spliced: ")
+
+	if err := removeStaleSocket(*socket); err != nil {
+		log.Fatal(err)
+	}
+	l, err := net.Listen("unix", *socket)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		l.Close()
+	}()
+
+	d := newDaemon(*verbose)
+	err = d.serve(l)
+	d.stop()
+	removeStaleSocket(*socket)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// removeStaleSocket removes the socket of a previous run, which would make
+// listen fail. A socket answered by a running daemon is an error, so that a
+// second daemon does not take it over. Anything else at path, like a file
+// named by a mistyped -socket, is left alone for listen to fail on.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if err != nil || fi.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+	conn, err := net.Dial("unix", path)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("socket %s is in use by a running daemon", path)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return nil
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,44 @@
+// Command splicerepl is an interactive playground to sketch and edit drum
+// patterns line by line.
+//
+// Usage:
+//
+//	splicerepl [file.splice]
+//
+// Without a file it starts with an empty pattern at 120 BPM. Commands:
+//
+//	load beat.splice
+//	preset house 124
+//	add kick
+//	toggle kick 1 5 9 13
+//	tempo 98.4
+//	play 2
+//	save beat.splice
+//
+// Type help for all commands. Tracks are given by name or by their number
+// in the printout, steps count from 1 on through the bars.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("splicerepl: ")
+	if len(os.Args) > 2 {
+		fmt.Fprintf(os.Stderr, "Usage: %s [file.splice]\n", os.Args[0])
+		os.Exit(2)
+	}
+	r := newREPL(os.Stdout)
+	if len(os.Args) == 2 {
+		if err := r.load(os.Args[1]); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := r.run(os.Stdin); err != nil {
+		log.Fatal(err)
+	}
+}
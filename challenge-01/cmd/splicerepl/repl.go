@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+	"github.com/alpe/go-challenge/challenge-01/presets"
+)
+
+const prompt = "> "
+
+// errQuit ends the loop.
+var errQuit = errors.New("quit")
+
+type command struct {
+	name  string
+	usage string
+	run   func(r *repl, args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"load", "load <file>\n\tload a .splice file", cmdLoad},
+		{"preset", "preset <name> [bpm]\n\tstart over from a factory pattern: " + strings.Join(presets.Names(), ", "), cmdPreset},
+		{"save", "save [file]\n\tsave the pattern, to the loaded file by default", cmdSave},
+		{"show", "show\n\tprint the pattern", cmdShow},
+		{"add", "add <name>\n\tadd an empty track", cmdAdd},
+		{"toggle", "toggle <track> <step>...\n\tturn steps on or off, the steps of later bars follow those of the first", cmdToggle},
+		{"clear", "clear <track>\n\tturn all steps of the track off", cmdClear},
+		{"mute", "mute <track>\n\tmute or unmute the track", cmdMute},
+		{"tempo", "tempo <bpm>\n\tset the tempo", cmdTempo},
+		{"play", "play [bars]\n\tplay the pattern and print the triggered tracks, 1 bar by default", cmdPlay},
+		{"undo", "undo\n\tundo the last edit", cmdUndo},
+		{"redo", "redo\n\tredo the last undone edit", cmdRedo},
+		{"help", "help\n\tlist the commands", cmdHelp},
+		{"quit", "quit\n\tleave, asks again when there are unsaved edits", cmdQuit},
+	}
+}
+
+// repl is the state of a session.
+type repl struct {
+	pattern *drum.Pattern
+	history *drum.History // records the edits of pattern
+	path    string        // of the loaded or saved file
+	dirty   bool          // edited since loaded or saved
+	quit    bool          // quit was requested with unsaved edits
+	out     io.Writer
+}
+
+func newREPL(out io.Writer) *repl {
+	p, _ := drum.NewPattern("", 120)
+	return &repl{pattern: p, history: drum.NewHistory(p), out: out}
+}
+
+// run executes the commands read line by line until quit or the end of
+// input. Failing commands print their error and the session goes on.
+func (r *repl) run(in io.Reader) error {
+	sc := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(r.out, prompt)
+		if !sc.Scan() {
+			fmt.Fprintln(r.out)
+			return sc.Err()
+		}
+		err := r.exec(sc.Text())
+		if err == errQuit {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(r.out, "error: %v\n", err)
+		}
+	}
+}
+
+// exec executes a single command line.
+func (r *repl) exec(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	name := fields[0]
+	if name == "exit" {
+		name = "quit"
+	}
+	if name != "quit" {
+		r.quit = false
+	}
+	for _, c := range commands {
+		if c.name == name {
+			return c.run(r, fields[1:])
+		}
+	}
+	return fmt.Errorf("unknown command %q, type help for the commands", name)
+}
+
+// edit applies the edit to the pattern and records it for undo.
+func (r *repl) edit(edit func(p *drum.Pattern) error) error {
+	if err := r.history.Do(edit); err != nil {
+		return err
+	}
+	r.dirty = true
+	return nil
+}
+
+// load replaces the pattern with the file and forgets the edits.
+func (r *repl) load(path string) error {
+	p, err := drum.DecodeFile(path)
+	if err != nil {
+		return err
+	}
+	r.reset(p, path)
+	return nil
+}
+
+// reset replaces the pattern and forgets the edits.
+func (r *repl) reset(p *drum.Pattern, path string) {
+	r.pattern, r.history, r.path, r.dirty = p, drum.NewHistory(p), path, false
+}
+
+// track returns the track of a name or of its number in the printout.
+func (r *repl) track(arg string) (*drum.Track, error) {
+	tracks := r.pattern.Tracks()
+	if n, err := strconv.Atoi(arg); err == nil {
+		if n < 1 || n > len(tracks) {
+			return nil, fmt.Errorf("no track %d, the pattern has %d", n, len(tracks))
+		}
+		return tracks[n-1], nil
+	}
+	if t := r.pattern.TrackByName(arg); t != nil {
+		return t, nil
+	}
+	return nil, fmt.Errorf("no track %q", arg)
+}
+
+func wantArgs(args []string, min, max int, usage string) error {
+	if len(args) < min || len(args) > max {
+		return fmt.Errorf("usage: %s", usage)
+	}
+	return nil
+}
+
+func cmdLoad(r *repl, args []string) error {
+	if err := wantArgs(args, 1, 1, "load <file>"); err != nil {
+		return err
+	}
+	if err := r.load(args[0]); err != nil {
+		return err
+	}
+	fmt.Fprintln(r.out, r.pattern.Summary(80))
+	return nil
+}
+
+func cmdPreset(r *repl, args []string) error {
+	if err := wantArgs(args, 1, 2, "preset <name> [bpm]"); err != nil {
+		return err
+	}
+	var bpm float64
+	if len(args) == 2 {
+		var err error
+		if bpm, err = strconv.ParseFloat(args[1], 32); err != nil {
+			return fmt.Errorf("invalid tempo %q", args[1])
+		}
+	}
+	p, err := presets.New(args[0], float32(bpm))
+	if err != nil {
+		return err
+	}
+	r.reset(p, "")
+	r.dirty = true
+	fmt.Fprint(r.out, p)
+	return nil
+}
+
+func cmdSave(r *repl, args []string) error {
+	if err := wantArgs(args, 0, 1, "save [file]"); err != nil {
+		return err
+	}
+	path := r.path
+	if len(args) == 1 {
+		path = args[0]
+	}
+	if path == "" {
+		return errors.New("no file loaded, usage: save <file>")
+	}
+	if err := drum.EncodeFile(path, r.pattern); err != nil {
+		return err
+	}
+	r.path, r.dirty = path, false
+	fmt.Fprintf(r.out, "saved %s\n", path)
+	return nil
+}
+
+func cmdShow(r *repl, args []string) error {
+	fmt.Fprint(r.out, r.pattern)
+	return nil
+}
+
+func cmdAdd(r *repl, args []string) error {
+	if err := wantArgs(args, 1, 1, "add <name>"); err != nil {
+		return err
+	}
+	var id uint32
+	for _, t := range r.pattern.Tracks() {
+		if t.ID() >= id {
+			id = t.ID() + 1
+		}
+	}
+	if err := r.edit(drum.AddTrackOp(id, args[0], drum.Steps{}).Apply); err != nil {
+		return err
+	}
+	tracks := r.pattern.Tracks()
+	return printTrack(r.out, r.pattern, tracks[len(tracks)-1])
+}
+
+func cmdToggle(r *repl, args []string) error {
+	// steps count on through the bars, 17 is step 1 of bar B in 4/4
+	last := r.pattern.Bars() * r.pattern.BarSteps()
+	if err := wantArgs(args, 2, last+1, "toggle <track> <step>..."); err != nil {
+		return err
+	}
+	t, err := r.track(args[0])
+	if err != nil {
+		return err
+	}
+	var steps []int
+	for _, arg := range args[1:] {
+		step, err := strconv.Atoi(arg)
+		if err != nil || step < 1 || step > last {
+			return fmt.Errorf("invalid step %q, steps count from 1 to %d", arg, last)
+		}
+		steps = append(steps, step-1)
+	}
+	bar := r.pattern.BarSteps()
+	if err := r.edit(func(*drum.Pattern) error {
+		for _, step := range steps {
+			on, err := t.BarSteps(step / bar)
+			if err != nil {
+				return err
+			}
+			if err := t.SetBarStep(step/bar, step%bar, !on[step%bar]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return printTrack(r.out, r.pattern, t)
+}
+
+func cmdClear(r *repl, args []string) error {
+	if err := wantArgs(args, 1, 1, "clear <track>"); err != nil {
+		return err
+	}
+	t, err := r.track(args[0])
+	if err != nil {
+		return err
+	}
+	if err := r.edit(func(p *drum.Pattern) error {
+		for bar := range p.Bars() {
+			for step := range t.Steps() {
+				t.SetBarStep(bar, step, false)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return printTrack(r.out, r.pattern, t)
+}
+
+func cmdMute(r *repl, args []string) error {
+	if err := wantArgs(args, 1, 1, "mute <track>"); err != nil {
+		return err
+	}
+	t, err := r.track(args[0])
+	if err != nil {
+		return err
+	}
+	var muted bool
+	if err := r.edit(func(*drum.Pattern) error {
+		muted = t.Mute()
+		return nil
+	}); err != nil {
+		return err
+	}
+	if muted {
+		fmt.Fprintf(r.out, "%s muted\n", t.Name())
+	} else {
+		fmt.Fprintf(r.out, "%s unmuted\n", t.Name())
+	}
+	return nil
+}
+
+func cmdTempo(r *repl, args []string) error {
+	if err := wantArgs(args, 1, 1, "tempo <bpm>"); err != nil {
+		return err
+	}
+	bpm, err := strconv.ParseFloat(args[0], 32)
+	if err != nil {
+		return fmt.Errorf("invalid tempo %q", args[0])
+	}
+	return r.edit(func(p *drum.Pattern) error { return p.SetTempo(float32(bpm)) })
+}
+
+func cmdPlay(r *repl, args []string) error {
+	if err := wantArgs(args, 0, 1, "play [bars]"); err != nil {
+		return err
+	}
+	bars := 1
+	if len(args) == 1 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid number of bars %q", args[0])
+		}
+		bars = n
+	}
+	stop := make(chan struct{})
+	steps := 0
+	// the player gets a copy, so that the pattern can not change under it
+	p := r.pattern.Clone()
+	player := drum.NewPlayer(p, func(ev drum.StepEvent) {
+		if steps == bars*p.BarSteps() {
+			return
+		}
+		names := make([]string, len(ev.Tracks))
+		for i, t := range ev.Tracks {
+			names[i] = t.Name()
+		}
+		fmt.Fprintf(r.out, "%2d %s\n", ev.Step+1, strings.Join(names, " "))
+		if steps++; steps == bars*p.BarSteps() {
+			close(stop)
+		}
+	})
+	return player.Play(stop)
+}
+
+func cmdUndo(r *repl, args []string) error {
+	if !r.history.Undo() {
+		return errors.New("nothing to undo")
+	}
+	r.dirty = true
+	return nil
+}
+
+func cmdRedo(r *repl, args []string) error {
+	if !r.history.Redo() {
+		return errors.New("nothing to redo")
+	}
+	r.dirty = true
+	return nil
+}
+
+func cmdHelp(r *repl, args []string) error {
+	for _, c := range commands {
+		fmt.Fprintf(r.out, "  %s\n", c.usage)
+	}
+	fmt.Fprintln(r.out, "Tracks are given by name or number, steps count from 1.")
+	return nil
+}
+
+func cmdQuit(r *repl, args []string) error {
+	if r.dirty && !r.quit {
+		r.quit = true
+		return errors.New("unsaved edits, save them or quit again")
+	}
+	return errQuit
+}
+
+// printTrack prints the track like the printout of the pattern, with its
+// bars and time signature.
+func printTrack(w io.Writer, from *drum.Pattern, t *drum.Track) error {
+	// a copy, AddBar overwrites the steps of the added bars
+	p, err := drum.NewPattern("", drum.MinTempo, t.Clone())
+	if err != nil {
+		return err
+	}
+	if err := p.SetTimeSignature(from.TimeSignature()); err != nil {
+		return err
+	}
+	for p.Bars() < from.Bars() {
+		bar, err := p.AddBar(0)
+		if err != nil {
+			return err
+		}
+		steps, _ := t.BarSteps(bar)
+		for i, on := range steps {
+			p.Tracks()[0].SetBarStep(bar, i, on)
+		}
+	}
+	return drum.Format(w, p, drum.WithoutHeader())
+}
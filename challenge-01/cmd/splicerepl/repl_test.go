@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+func TestREPL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "splicerepl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "out.splice")
+
+	script := strings.Join([]string{
+		"load " + filepath.Join("..", "..", "fixtures", "pattern_1.splice"),
+		"toggle snare 1 2",
+		"toggle 9 1",
+		"add shaker",
+		"toggle shaker 3",
+		"tempo 999",
+		"tempo 0",
+		"mute kick",
+		"undo",
+		"play",
+		"quit",
+		"save " + path,
+		"preset bossa 130",
+		"toggle kick 2",
+		"quit",
+		"quit",
+	}, "\n")
+	var out bytes.Buffer
+	r := newREPL(&out)
+	if err := r.run(strings.NewReader(script)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := drum.DecodeFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps := got.TrackByName("snare").Steps(); !steps[0] || !steps[1] {
+		t.Errorf("expected steps 1 and 2 of the snare toggled on, got %v", steps)
+	}
+	if shaker := got.TrackByName("shaker"); shaker == nil || !shaker.Steps()[2] || shaker.ID() != 6 {
+		t.Errorf("expected shaker with id 6 and step 3, got %v", shaker)
+	}
+	if got.Tempo() != 999 {
+		t.Errorf("expected tempo 999, got %v", got.Tempo())
+	}
+	if got.TrackByName("kick").Muted() {
+		t.Error("expected mute undone")
+	}
+	for _, exp := range []string{
+		"120bpm 6trk kick:",
+		"error: no track 9, the pattern has 6",
+		"(6) shaker\t|--x-|----|----|----|",
+		"error: invalid tempo",
+		"kick muted",
+		" 5 kick snare clap hh-close\n",
+		"error: unsaved edits, save them or quit again",
+		"saved " + path,
+		"Saved with HW Version: preset\nTempo: 130\n",
+		"(0) kick\t|xx-x|x--x|x--x|x--x|",
+	} {
+		if !strings.Contains(out.String(), exp) {
+			t.Errorf("Expected '%v' in:\n%s", exp, out.String())
+		}
+	}
+}
+
+func TestREPLErrors(t *testing.T) {
+	var out bytes.Buffer
+	r := newREPL(&out)
+	script := "preset polka\nsave\ntoggle kick 1\nadd kick\ntoggle kick 17\nredo\nplay 0\nbeat\n"
+	if err := r.run(strings.NewReader(script)); err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range []string{
+		`error: unknown preset "polka"`,
+		"error: no file loaded, usage: save <file>",
+		`error: no track "kick"`,
+		`error: invalid step "17", steps count from 1 to 16`,
+		"error: nothing to redo",
+		`error: invalid number of bars "0"`,
+		`error: unknown command "beat"`,
+	} {
+		if !strings.Contains(out.String(), exp) {
+			t.Errorf("Expected '%v' in:\n%s", exp, out.String())
+		}
+	}
+}
+
+func TestREPLBars(t *testing.T) {
+	dir, err := ioutil.TempDir("", "splicerepl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "waltz.splice")
+	kick, _ := drum.NewTrack(0, "kick", drum.Steps{0: true})
+	p, _ := drum.NewPattern("0.808-alpha", 120, kick)
+	p.SetTimeSignature(drum.TimeSignature{Numerator: 3, Denominator: 4})
+	p.AddBar(0)
+	if err := drum.EncodeFile(path, p); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	r := newREPL(&out)
+	script := "load " + path + "\ntoggle kick 13 18\ntoggle kick 25\nplay 2\nsave\n"
+	if err := r.run(strings.NewReader(script)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := drum.DecodeFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps, _ := got.TrackByName("kick").BarSteps(1); steps[0] || !steps[5] {
+		t.Errorf("Expected step 1 of bar B off and 6 on but got %v", steps)
+	}
+	for _, exp := range []string{
+		"(0) kick\tA|x---|----|----|\n\tB|----|-x--|----|\n",
+		`error: invalid step "25", steps count from 1 to 24`,
+		" 1 kick\n 2 \n",
+		" 6 kick\n",
+	} {
+		if !strings.Contains(out.String(), exp) {
+			t.Errorf("Expected '%v' in:\n%s", exp, out.String())
+		}
+	}
+	if n := strings.Count(out.String(), "\n12 \n"); n != 2 {
+		t.Errorf("Expected 2 bars of 12 steps played but got %d in:\n%s", n, out.String())
+	}
+}
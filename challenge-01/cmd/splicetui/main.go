@@ -0,0 +1,70 @@
+// Command splicetui is a terminal step sequencer for .splice files.
+//
+// Usage:
+//
+//	splicetui [-preset name] <file.splice>
+//
+// With -preset a file that does not exist yet starts from the factory
+// pattern of the presets package, like rock, house, hiphop or bossa.
+//
+// Keys:
+//
+//	arrows or h j k l   move the cursor
+//	space or x          toggle the step under the cursor
+//	m                   mute or unmute the track under the cursor
+//	+ -                 change the tempo by 1 BPM
+//	p                   start or stop playing
+//	c                   start or stop recording while playing
+//	< >                 zoom out to 1/8 or in to 1/32 steps
+//	1 to 9              record a hit of the track at the nearest step
+//	u                   undo the last edit
+//	r or ctrl-r         redo the last undone edit
+//	s                   save the pattern back to the file
+//	q                   quit
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+	"github.com/alpe/go-challenge/challenge-01/presets"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("splicetui: ")
+	preset := flag.String("preset", "", "pattern to start a new file with: "+strings.Join(presets.Names(), ", "))
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-preset name] <file.splice>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	path := flag.Arg(0)
+	p, err := drum.DecodeFile(path)
+	if errors.Is(err, os.ErrNotExist) && *preset != "" {
+		p, err = presets.New(*preset, 0)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	restore, err := rawMode()
+	if err != nil {
+		log.Fatalf("terminal not supported: %v", err)
+	}
+	s := newSequencer(p, path, os.Stdout)
+	err = s.run(bufio.NewReader(os.Stdin))
+	restore()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// key is a decoded key press.
+type key int
+
+const (
+	keyNone key = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyToggle
+	keyMute
+	keyFaster
+	keySlower
+	keyPlay
+	keySave
+	keyUndo
+	keyRedo
+	keyRecord
+	keyZoomIn
+	keyZoomOut
+	keyQuit
+	keyHit // followed by the keys hitting tracks 2 to 9
+)
+
+// readKey reads the next key press. Arrow keys arrive as escape sequences.
+func readKey(r *bufio.Reader) (key, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return keyNone, err
+	}
+	switch b {
+	case 'k':
+		return keyUp, nil
+	case 'j':
+		return keyDown, nil
+	case 'h':
+		return keyLeft, nil
+	case 'l':
+		return keyRight, nil
+	case ' ', 'x':
+		return keyToggle, nil
+	case 'm':
+		return keyMute, nil
+	case '+', '=':
+		return keyFaster, nil
+	case '-':
+		return keySlower, nil
+	case 'p':
+		return keyPlay, nil
+	case 's':
+		return keySave, nil
+	case 'u':
+		return keyUndo, nil
+	case 'r', 0x12: // ctrl-r
+		return keyRedo, nil
+	case 'c':
+		return keyRecord, nil
+	case '>':
+		return keyZoomIn, nil
+	case '<':
+		return keyZoomOut, nil
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return keyHit + key(b-'1'), nil
+	case 'q', 3: // ctrl-c in raw mode
+		return keyQuit, nil
+	case 0x1b:
+		if next, _ := r.Peek(2); len(next) == 2 && next[0] == '[' {
+			r.Discard(2)
+			switch next[1] {
+			case 'A':
+				return keyUp, nil
+			case 'B':
+				return keyDown, nil
+			case 'C':
+				return keyRight, nil
+			case 'D':
+				return keyLeft, nil
+			}
+		}
+	}
+	return keyNone, nil
+}
+
+// sequencer is the state of the step sequencer.
+type sequencer struct {
+	mu       sync.Mutex
+	pattern  *drum.Pattern
+	history  *drum.History       // records the edits of pattern
+	shared   *drum.SyncedPattern // copy of pattern played
+	path     string
+	out      io.Writer
+	track    int // cursor position
+	bar      int
+	step     int
+	zoom     drum.Resolution // grid cells per bar
+	playhead int             // steps played since the start, -1 while stopped
+	record   bool            // hits are recorded at the playhead
+	stop     chan struct{}
+	player   *drum.Player
+	status   string
+}
+
+func newSequencer(p *drum.Pattern, path string, out io.Writer) *sequencer {
+	s := &sequencer{pattern: p, history: drum.NewHistory(p), shared: drum.NewSyncedPattern(p), path: path, out: out, zoom: drum.Sixteenth, playhead: -1}
+	// the player plays copies, so edits never race with playing
+	s.player = drum.NewPlayer(nil, func(ev drum.StepEvent) {
+		s.mu.Lock()
+		s.playhead++
+		s.mu.Unlock()
+		s.draw()
+	}, drum.WithSyncedPattern(s.shared))
+	return s
+}
+
+// run handles key presses until quit or the end of input.
+func (s *sequencer) run(r *bufio.Reader) error {
+	s.draw()
+	for {
+		k, err := readKey(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !s.handle(k) {
+			s.setPlaying(false)
+			return nil
+		}
+		s.draw()
+	}
+}
+
+// handle applies a key press and reports whether to keep running.
+func (s *sequencer) handle(k key) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tracks := s.pattern.Tracks()
+	s.status = ""
+	switch k {
+	case keyUp:
+		if s.track > 0 {
+			s.track--
+		}
+	case keyDown:
+		if s.track < len(tracks)-1 {
+			s.track++
+		}
+	case keyLeft, keyRight:
+		s.move(k == keyRight)
+	case keyToggle:
+		if len(tracks) > 0 {
+			t, bar, step := tracks[s.track], s.bar, s.step
+			s.history.Do(func(*drum.Pattern) error {
+				steps, err := t.BarSteps(bar)
+				if err != nil {
+					return err
+				}
+				return t.SetBarStep(bar, step, !steps[step])
+			})
+		}
+	case keyZoomIn:
+		s.zoom = s.zoom.ZoomIn()
+	case keyZoomOut:
+		s.zoom = s.zoom.ZoomOut()
+	case keyMute:
+		if len(tracks) > 0 {
+			s.history.Do(func(*drum.Pattern) error {
+				tracks[s.track].Mute()
+				return nil
+			})
+		}
+	case keyFaster, keySlower:
+		bpm := s.pattern.Tempo() + 1
+		if k == keySlower {
+			bpm -= 2
+		}
+		if err := s.history.Do(func(p *drum.Pattern) error { return p.SetTempo(bpm) }); err != nil {
+			s.status = err.Error()
+		}
+	case keyUndo:
+		if !s.history.Undo() {
+			s.status = "nothing to undo"
+		}
+	case keyRedo:
+		if !s.history.Redo() {
+			s.status = "nothing to redo"
+		}
+	case keyPlay:
+		go s.setPlaying(s.stop == nil)
+	case keySave:
+		if err := drum.EncodeFile(s.path, s.pattern); err != nil {
+			s.status = err.Error()
+		} else {
+			s.status = "saved " + s.path
+		}
+	case keyRecord:
+		s.record = !s.record
+	case keyQuit:
+		return false
+	}
+	if k >= keyHit {
+		s.hit(tracks, int(k-keyHit))
+	}
+	s.clampCursor()
+	switch k {
+	case keyToggle, keyMute, keyFaster, keySlower, keyUndo, keyRedo:
+		s.shared.Store(s.pattern)
+	}
+	return true
+}
+
+// length returns the number of steps the track plays before it loops.
+func (s *sequencer) length(t *drum.Track) int {
+	if n := t.Length(); n != 0 {
+		return n
+	}
+	return s.pattern.BarSteps()
+}
+
+// cellSteps returns the number of steps the cursor moves per cell, more
+// than one when zoomed out.
+func (s *sequencer) cellSteps() int {
+	return max(1, len(drum.Steps{})/int(s.zoom))
+}
+
+// move moves the cursor a cell to the left or right, on to the neighbouring
+// bar at the end of the steps of the track.
+func (s *sequencer) move(right bool) {
+	tracks := s.pattern.Tracks()
+	if len(tracks) == 0 {
+		return
+	}
+	n, bars, unit := s.length(tracks[s.track]), s.pattern.Bars(), s.cellSteps()
+	switch {
+	case right && s.step+unit < n:
+		s.step += unit
+	case right:
+		s.step, s.bar = 0, (s.bar+1)%bars
+	case s.step > 0:
+		s.step -= unit
+	default:
+		s.step, s.bar = (n-1)/unit*unit, (s.bar+bars-1)%bars
+	}
+}
+
+// clampCursor keeps the cursor on the first step of a cell within the
+// steps of the track under it.
+func (s *sequencer) clampCursor() {
+	tracks := s.pattern.Tracks()
+	if s.bar >= s.pattern.Bars() {
+		s.bar = 0
+	}
+	if len(tracks) == 0 {
+		return
+	}
+	s.step -= s.step % s.cellSteps()
+	if n := s.length(tracks[s.track]); s.step >= n {
+		s.step = (n - 1) / s.cellSteps() * s.cellSteps()
+	}
+}
+
+// hit records a step of the track at the playhead, quantized to the
+// nearest step.
+func (s *sequencer) hit(tracks []*drum.Track, track int) {
+	switch {
+	case !s.record:
+		s.status = "press c to record"
+		return
+	case track >= len(tracks):
+		s.status = fmt.Sprintf("no track %d", track+1)
+		return
+	}
+	tr := s.player.Transport(time.Now())
+	var step int
+	err := s.history.Do(func(p *drum.Pattern) error {
+		var err error
+		step, err = p.Record(tracks[track], drum.MaxVelocity, tr)
+		return err
+	})
+	if err != nil {
+		s.status = err.Error()
+		return
+	}
+	s.status = fmt.Sprintf("%s on step %d", tracks[track].Name(), step+1)
+	s.shared.Store(s.pattern)
+}
+
+// setPlaying starts or stops the player.
+func (s *sequencer) setPlaying(on bool) {
+	s.mu.Lock()
+	if !on {
+		if s.stop != nil {
+			close(s.stop)
+			s.stop = nil
+		}
+		s.playhead = -1
+		s.mu.Unlock()
+		return
+	}
+	if s.stop != nil {
+		s.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	s.stop = stop
+	s.mu.Unlock()
+	if err := s.player.Play(stop); err != nil {
+		s.mu.Lock()
+		s.status = err.Error()
+		s.mu.Unlock()
+	}
+}
+
+// draw redraws the whole screen. The bars of every track are shown below
+// each other in the zoom resolution.
+func (s *sequencer) draw() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var buf bytes.Buffer
+	// raw mode needs explicit carriage returns
+	buf.WriteString("\x1b[H\x1b[2J")
+	fmt.Fprintf(&buf, "%s  %v BPM%s\r\n\r\n", s.pattern.Version(), s.pattern.Tempo(), labels(s.pattern.Labels()))
+	bars := s.pattern.Bars()
+	// cells per beat
+	group := max(1, int(s.zoom)/int(s.pattern.TimeSignature().Denominator))
+	played, playing := -1, -1
+	if s.playhead >= 0 {
+		order := s.pattern.BarOrder()
+		played = order[s.playhead/s.pattern.BarSteps()%len(order)]
+	}
+	for i, t := range s.pattern.Tracks() {
+		if s.playhead >= 0 {
+			playing = s.zoom.Cell(s.playhead % s.length(t))
+		}
+		for bar, cells := range s.pattern.ZoomBars(t, s.zoom) {
+			name := ""
+			if bar == 0 {
+				name = t.Name()
+				if icon := t.Display().Symbol(); icon != 0 {
+					name = string(icon) + " " + name
+				}
+				if t.Muted() {
+					name += " [muted]"
+				}
+			}
+			if bars > 1 {
+				name = fmt.Sprintf("%-14.14s %s", name, string(rune('A'+bar)))
+			}
+			// pad before coloring, the escape sequences take no space
+			fmt.Fprintf(&buf, "%s |", colored(t.Display().Color, fmt.Sprintf("%-16.16s", name)))
+			for cell, enabled := range cells {
+				symbol := "-"
+				if enabled {
+					symbol = "x"
+				}
+				switch {
+				case i == s.track && bar == s.bar && cell == s.zoom.Cell(s.step):
+					buf.WriteString("\x1b[7m" + symbol + "\x1b[0m")
+				case bar == played && cell == playing:
+					buf.WriteString("\x1b[32m" + symbol + "\x1b[0m")
+				case enabled:
+					buf.WriteString(colored(t.Display().Color, symbol))
+				default:
+					buf.WriteString(symbol)
+				}
+				if cell%group == group-1 || cell == len(cells)-1 {
+					buf.WriteByte('|')
+				}
+			}
+			if bar == 0 {
+				buf.WriteString(labels(t.Labels()))
+			}
+			buf.WriteString("\r\n")
+		}
+	}
+	record := "record"
+	if s.record {
+		record = "\x1b[31mrecording\x1b[0m [1-9] hit track"
+	}
+	fmt.Fprintf(&buf, "\r\n%s\r\n[space] toggle [m] mute [+/-] tempo [</>] zoom %v [p] play [c] %s [u/r] undo/redo [s] save [q] quit\r\n", s.status, s.zoom, record)
+	s.out.Write(buf.Bytes())
+}
+
+// labels returns the labels like " #live-set", each in its color.
+func labels(ls []drum.Label) string {
+	var b strings.Builder
+	for _, l := range ls {
+		b.WriteString(" " + colored(l.Color, "#"+l.Name))
+	}
+	return b.String()
+}
+
+// colored returns s in a "#rrggbb" color as 24 bit terminal color, s
+// itself for other colors.
+func colored(color, s string) string {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(color, "#%02x%02x%02x", &r, &g, &b); err != nil {
+		return s
+	}
+	return fmt.Sprintf("\x1b[38;2;%d;%d;%dm%s\x1b[0m", r, g, b, s)
+}
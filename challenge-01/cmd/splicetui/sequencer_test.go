@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+func TestSequencer(t *testing.T) {
+	p, err := drum.DecodeFile(filepath.Join("..", "..", "fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "splicetui")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "out.splice")
+
+	p.SetLabels(drum.Label{Name: "live-set", Color: "#ff8800"})
+	p.Tracks()[0].SetLabels(drum.Label{Name: "four-on-the-floor"})
+	var out bytes.Buffer
+	s := newSequencer(p, path, &out)
+	// toggle step 2 of the second track, slow down, mute and save
+	keys := "\x1b[B\x1b[C \x1b[Dx--ms\x1b[Aq"
+	if err := s.run(bufio.NewReader(strings.NewReader(keys))); err != nil {
+		t.Fatal(err)
+	}
+	got, err := drum.DecodeFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snare := got.Tracks()[1]
+	if steps := snare.Steps(); !steps[0] || !steps[1] {
+		t.Errorf("expected steps 1 and 2 toggled on, got %v", steps)
+	}
+	if got.Tempo() != p.Tempo() || got.Tempo() != 118 {
+		t.Errorf("expected tempo 118, got %v", got.Tempo())
+	}
+	if !p.Tracks()[1].Muted() || !strings.Contains(out.String(), "snare [muted]") {
+		t.Error("expected snare muted")
+	}
+	if !strings.Contains(out.String(), "BPM \x1b[38;2;255;136;0m#live-set\x1b[0m\r\n") || !strings.Contains(out.String(), "| #four-on-the-floor\r\n") {
+		t.Errorf("expected labels in output:\n%q", out.String())
+	}
+	if s.track != 0 || s.step != 0 {
+		t.Errorf("unexpected cursor %d/%d", s.track, s.step)
+	}
+	if !strings.Contains(out.String(), "saved "+path) {
+		t.Errorf("expected save status in output:\n%s", out.String())
+	}
+}
+
+func TestSequencerUndo(t *testing.T) {
+	p, err := drum.DecodeFile(filepath.Join("..", "..", "fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := p.Clone()
+	var out bytes.Buffer
+	s := newSequencer(p, "", &out)
+	// toggle two steps and slow down, undo all, redo the first toggle
+	keys := "\x1b[C \x1b[C -uuuurq"
+	if err := s.run(bufio.NewReader(strings.NewReader(keys))); err != nil {
+		t.Fatal(err)
+	}
+	steps := p.Tracks()[0].Steps()
+	if !steps[1] || steps[2] || p.Tempo() != original.Tempo() {
+		t.Errorf("expected only step 2 toggled on, got %v at %v BPM", steps, p.Tempo())
+	}
+	if !strings.Contains(out.String(), "nothing to undo") {
+		t.Errorf("expected undo status in output:\n%s", out.String())
+	}
+}
+
+func TestSequencerRecord(t *testing.T) {
+	p, err := drum.DecodeFile(filepath.Join("..", "..", "fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	s := newSequencer(p, "", &out)
+	if s.handle(keyHit); s.status != "press c to record" {
+		t.Errorf("unexpected status %q", s.status)
+	}
+	s.handle(keyRecord)
+	if s.handle(keyHit + 8); s.status != "no track 9" {
+		t.Errorf("unexpected status %q", s.status)
+	}
+	// hits are recorded while playing only
+	if s.handle(keyHit); s.status != drum.ErrNotPlaying.Error() {
+		t.Errorf("unexpected status %q", s.status)
+	}
+	if k, _ := readKey(bufio.NewReader(strings.NewReader("3"))); k != keyHit+2 {
+		t.Errorf("expected the hit of track 3 but got %v", k)
+	}
+}
+
+func TestSequencerDisplay(t *testing.T) {
+	p, err := drum.DecodeFile(filepath.Join("..", "..", "fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Tracks()[0].SetDisplay(drum.Display{Color: "#ff8800", Icon: "kick"})
+	p.Tracks()[1].SetDisplay(drum.Display{Icon: "tambourine"})
+	var out bytes.Buffer
+	s := newSequencer(p, "", &out)
+	if err := s.run(bufio.NewReader(strings.NewReader("q"))); err != nil {
+		t.Fatal(err)
+	}
+	orange := "\x1b[38;2;255;136;0m"
+	for _, exp := range []string{
+		"\r\n" + orange + "● kick          \x1b[0m |\x1b[7mx\x1b[0m---|" + orange + "x\x1b[0m---|",
+		"\r\nT snare          |----|x---|",
+	} {
+		if !strings.Contains(out.String(), exp) {
+			t.Errorf("expected %q in output:\n%q", exp, out.String())
+		}
+	}
+}
+
+func TestSequencerZoom(t *testing.T) {
+	p, err := drum.DecodeFile(filepath.Join("..", "..", "fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.AddBar(0)
+	p.Tracks()[1].SetLength(12)
+	var out bytes.Buffer
+	s := newSequencer(p, "", &out)
+	// zoomed out to 1/8 the cursor moves two steps: toggle step 5 of bar A,
+	// wrap to the last cell of bar B, toggle it and the last cell of the
+	// shorter snare below
+	keys := "<\x1b[C\x1b[C \x1b[D\x1b[D\x1b[D \x1b[B q"
+	if err := s.run(bufio.NewReader(strings.NewReader(keys))); err != nil {
+		t.Fatal(err)
+	}
+	kickA, _ := p.Tracks()[0].BarSteps(0)
+	kickB, _ := p.Tracks()[0].BarSteps(1)
+	snareB, _ := p.Tracks()[1].BarSteps(1)
+	if kickA[4] || !kickB[14] || !snareB[10] {
+		t.Errorf("unexpected steps %v %v %v", kickA, kickB, snareB)
+	}
+	if s.bar != 1 || s.step != 10 {
+		t.Errorf("unexpected cursor %d/%d", s.bar, s.step)
+	}
+	screen := out.String()[strings.LastIndex(out.String(), "\x1b[2J"):]
+	for _, exp := range []string{
+		"[</>] zoom 1/8 ",
+		"\r\nkick           A |x-|--|x-|x-|",
+		"\r\n               B |x-|x-|x-|xx|",
+		"\r\nsnare          A |--|x-|--|",
+		"\r\n               B |--|x-|-\x1b[7mx\x1b[0m|",
+	} {
+		if !strings.Contains(screen, exp) {
+			t.Errorf("Expected %q in the screen:\n%q", exp, screen)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package main
+
+import "errors"
+
+// rawMode is not implemented for the Windows console.
+func rawMode() (func(), error) {
+	return nil, errors.New("raw mode not supported on windows")
+}
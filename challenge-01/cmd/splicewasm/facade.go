@@ -0,0 +1,57 @@
+// Command splicewasm exposes the codec to JavaScript when compiled to
+// WebAssembly, so that a browser can show .splice files without a server:
+//
+//	GOOS=js GOARCH=wasm go build -o splice.wasm ./cmd/splicewasm
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" .
+//
+// The page loads wasm_exec.js and splice.js, which runs the module and
+// resolves to an object with the functions:
+//
+//	decode(bytes)     the pattern of a .splice file as object, see Pattern.MarshalJSON
+//	encode(pattern)   the .splice file of a pattern object or JSON string as Uint8Array
+//	toJSON(bytes)     the pattern of a .splice file as JSON string
+//	toSVG(bytes)      the step grid of a .splice file as SVG string
+//
+// Bytes are Uint8Arrays. The functions throw an Error when the input is
+// invalid:
+//
+//	const splice = await loadSplice("splice.wasm");
+//	const pattern = splice.decode(new Uint8Array(await file.arrayBuffer()));
+package main
+
+import (
+	"bytes"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// toJSON returns the pattern of the .splice file as JSON.
+func toJSON(data []byte) ([]byte, error) {
+	p, err := drum.DecodeBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return p.MarshalJSON()
+}
+
+// encode returns the .splice file of the pattern JSON.
+func encode(data []byte) ([]byte, error) {
+	var p drum.Pattern
+	if err := p.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return p.MarshalBinary()
+}
+
+// toSVG returns the step grid of the .splice file.
+func toSVG(data []byte) ([]byte, error) {
+	p, err := drum.DecodeBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := p.ToSVG(&buf, drum.DefaultGridStyle); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFacade(t *testing.T) {
+	raw, err := ioutil.ReadFile(filepath.Join("..", "..", "fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := toJSON(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte(`{"version":"0.808-alpha","tempo":120,`)) {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+	encoded, err := encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, raw) {
+		t.Errorf("Expected the file back but got %q", encoded)
+	}
+	svg, err := toSVG(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(svg), "<svg") {
+		t.Errorf("Expected an SVG but got %q", svg)
+	}
+
+	if _, err := toJSON(raw[:10]); err == nil {
+		t.Error("Expected an error for a truncated file")
+	}
+	if _, err := encode([]byte(`{"tempo": -1}`)); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
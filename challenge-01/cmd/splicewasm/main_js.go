@@ -0,0 +1,71 @@
+//go:build js && wasm
+
+package main
+
+import "syscall/js"
+
+func main() {
+	api := js.Global().Get("Object").New()
+	api.Set("decode", function(func(args []js.Value) (interface{}, error) {
+		data, err := toJSON(bytesArg(args))
+		if err != nil {
+			return nil, err
+		}
+		return js.Global().Get("JSON").Call("parse", string(data)), nil
+	}))
+	api.Set("encode", function(func(args []js.Value) (interface{}, error) {
+		pattern := js.Undefined()
+		if len(args) > 0 {
+			pattern = args[0]
+		}
+		if pattern.Type() != js.TypeString {
+			pattern = js.Global().Get("JSON").Call("stringify", pattern)
+		}
+		data, err := encode([]byte(pattern.String()))
+		if err != nil {
+			return nil, err
+		}
+		return uint8Array(data), nil
+	}))
+	api.Set("toJSON", function(func(args []js.Value) (interface{}, error) {
+		data, err := toJSON(bytesArg(args))
+		return string(data), err
+	}))
+	api.Set("toSVG", function(func(args []js.Value) (interface{}, error) {
+		data, err := toSVG(bytesArg(args))
+		return string(data), err
+	}))
+	// splice.js waits for the functions before resolving
+	if cb := js.Global().Get("onspliceready"); cb.Type() == js.TypeFunction {
+		cb.Invoke(api)
+	}
+	select {}
+}
+
+// function wraps f for JavaScript. Errors are returned as {error: message}
+// objects which splice.js throws.
+func function(f func(args []js.Value) (interface{}, error)) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		v, err := f(args)
+		if err != nil {
+			return map[string]interface{}{"error": err.Error()}
+		}
+		return map[string]interface{}{"value": v}
+	})
+}
+
+// bytesArg returns the content of the first argument, a Uint8Array.
+func bytesArg(args []js.Value) []byte {
+	if len(args) == 0 || !args[0].InstanceOf(js.Global().Get("Uint8Array")) {
+		return nil
+	}
+	b := make([]byte, args[0].Length())
+	js.CopyBytesToGo(b, args[0])
+	return b
+}
+
+func uint8Array(b []byte) js.Value {
+	a := js.Global().Get("Uint8Array").New(len(b))
+	js.CopyBytesToJS(a, b)
+	return a
+}
@@ -0,0 +1,32 @@
+// loadSplice runs splicewasm and resolves to its functions, which throw an
+// Error for invalid input. The source is the URL of splice.wasm or its
+// bytes. wasm_exec.js of the Go release building it must be loaded first.
+async function loadSplice(source) {
+  const go = new Go();
+  const ready = new Promise((resolve) => {
+    globalThis.onspliceready = resolve;
+  });
+  const { instance } = typeof source === "string"
+    ? await WebAssembly.instantiateStreaming(fetch(source), go.importObject)
+    : await WebAssembly.instantiate(source, go.importObject);
+  go.run(instance);
+  const api = await ready;
+  delete globalThis.onspliceready;
+  const wrap = (f) => (...args) => {
+    const result = f(...args);
+    if (result.error !== undefined) {
+      throw new Error(result.error);
+    }
+    return result.value;
+  };
+  return {
+    decode: wrap(api.decode),
+    encode: wrap(api.encode),
+    toJSON: wrap(api.toJSON),
+    toSVG: wrap(api.toSVG),
+  };
+}
+
+if (typeof module !== "undefined") {
+  module.exports = { loadSplice };
+}
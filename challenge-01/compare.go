@@ -0,0 +1,96 @@
+package drum
+
+import (
+	"bytes"
+	"math"
+)
+
+// DefaultTempoTolerance is the tempo difference in beats per minute up to
+// which Equal treats two patterns as equal. The tempo is stored as float32
+// so values parsed from text often differ in the last bits.
+const DefaultTempoTolerance = 0.001
+
+// Clone returns a deep copy of the pattern that can be modified without
+// affecting p.
+func (p *Pattern) Clone() *Pattern {
+	c := *p
+	c.tracks = make([]*Track, len(p.tracks))
+	for i, t := range p.tracks {
+		c.tracks[i] = t.Clone()
+	}
+	c.meta = p.Metadata()
+	c.labels = p.Labels()
+	c.tempoMap = append(TempoMap(nil), p.tempoMap...)
+	if p.barOrder != nil {
+		c.barOrder = append([]uint8(nil), p.barOrder...)
+	}
+	c.rawVersion = cloneBytes(p.rawVersion)
+	c.rawExtra = cloneBytes(p.rawExtra)
+	c.chunks = make([]rawChunk, len(p.chunks))
+	for i, ch := range p.chunks {
+		c.chunks[i] = rawChunk{ch.id, cloneBytes(ch.data)}
+	}
+	return &c
+}
+
+// Clone returns a copy of the track.
+func (t *Track) Clone() *Track {
+	c := *t
+	return &c
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
+
+// Equal reports whether both patterns have the same version, swing, time
+// signature, tempo map, number of bars, bar order and tracks, compared with
+// Track.Equal in order, and a tempo within the DefaultTempoTolerance. The
+// metadata and the labels of the pattern are not compared, although Clone
+// copies them, and neither are the raw version bytes, the unknown chunks,
+// the raw extra bytes and the tempo as read before a correction.
+func (p *Pattern) Equal(o *Pattern) bool {
+	return p.EqualTolerance(o, DefaultTempoTolerance)
+}
+
+// EqualTolerance is like Equal but with a custom tempo tolerance in beats
+// per minute.
+func (p *Pattern) EqualTolerance(o *Pattern, tolerance float64) bool {
+	if p == nil || o == nil {
+		return p == o
+	}
+	if p.version != o.version || p.swing != o.swing || p.timeSig != o.timeSig || len(p.tracks) != len(o.tracks) {
+		return false
+	}
+	if math.Abs(float64(p.tempo)-float64(o.tempo)) > tolerance {
+		return false
+	}
+	if !p.tempoMap.equal(o.tempoMap) {
+		return false
+	}
+	if p.extraBars != o.extraBars || !bytes.Equal(p.barOrder, o.barOrder) {
+		return false
+	}
+	for i, t := range p.tracks {
+		if !t.Equal(o.tracks[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal reports whether both tracks have the same id, name, steps of every
+// bar, step data and settings like the display metadata, labels and volume.
+// The mute and solo state is not compared.
+func (t *Track) Equal(o *Track) bool {
+	if t == nil || o == nil {
+		return t == o
+	}
+	a, b := *t, *o
+	a.muted, a.solo = false, false
+	b.muted, b.solo = false, false
+	return a == b
+}
@@ -0,0 +1,111 @@
+package drum
+
+import (
+	"path"
+	"testing"
+)
+
+func TestCloneAndEqual(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_5.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := p.Clone()
+	if !p.Equal(c) {
+		t.Fatalf("clone should be equal:\n%v\n%v", p, c)
+	}
+	c.tracks[0].steps[1] = true
+	c.rawExtra[0] = 'x'
+	if p.tracks[0].steps[1] || p.rawExtra[0] == 'x' {
+		t.Errorf("clone shares data with the original")
+	}
+	if p.Equal(c) {
+		t.Errorf("patterns with different steps should not be equal")
+	}
+}
+
+func TestEqualTempoTolerance(t *testing.T) {
+	a := &Pattern{version: "0.909", tempo: 98.4}
+	b := &Pattern{version: "0.909", tempo: 98.4004}
+	if !a.Equal(b) {
+		t.Errorf("expected equal within default tolerance")
+	}
+	if a.EqualTolerance(b, 0) {
+		t.Errorf("expected not equal without tolerance")
+	}
+	if a.Equal(&Pattern{version: "0.808", tempo: 98.4}) {
+		t.Errorf("expected not equal for different versions")
+	}
+	if a.Equal(nil) || !(*Pattern)(nil).Equal(nil) {
+		t.Errorf("unexpected nil handling")
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := p.Clone()
+	c.version = "other"
+	if p.Fingerprint() != c.Fingerprint() {
+		t.Error("expected version to be ignored")
+	}
+	c.tracks[0].steps[1] = !c.tracks[0].steps[1]
+	if p.Fingerprint() == c.Fingerprint() {
+		t.Error("expected different fingerprint for changed steps")
+	}
+}
+
+func TestHash(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the canonical serialization must not change without a new version
+	if exp, got := "9da7aa261e182eeee1325fe92f6007a2dc2c1bfaeaeb35db631c6885a29fc363", p.Hash(); got != exp {
+		t.Errorf("Expected hash %v but got %v", exp, got)
+	}
+
+	same := map[string]func(c *Pattern){
+		"version":       func(c *Pattern) { c.version = "0.909" },
+		"track order":   func(c *Pattern) { c.tracks[0], c.tracks[5] = c.tracks[5], c.tracks[0] },
+		"track ids":     func(c *Pattern) { c.tracks[1].id = 42 },
+		"name spelling": func(c *Pattern) { c.tracks[3].name = "HH Open" },
+		"empty track":   func(c *Pattern) { c.tracks = append(c.tracks, &Track{id: 9, name: "tom"}) },
+		"tempo bits":    func(c *Pattern) { c.tempo = 120.0001 },
+		"display":       func(c *Pattern) { c.tracks[0].display = Display{Color: "#ff0000"} },
+		"metadata":      func(c *Pattern) { c.meta = Metadata{Title: "four on the floor"} },
+		"mute":          func(c *Pattern) { c.tracks[0].Mute() },
+		"raw extra":     func(c *Pattern) { c.rawExtra = []byte("extra") },
+		"disabled step": func(c *Pattern) { c.tracks[0].SetVelocity(1, 10); c.tracks[0].SetTiming(1, 0.25) },
+		"max velocity":  func(c *Pattern) { c.tracks[0].velocity[0] = MaxVelocity },
+	}
+	for msg, change := range same {
+		c := p.Clone()
+		change(c)
+		if p.Hash() != c.Hash() {
+			t.Errorf("%s: expected same hash", msg)
+		}
+	}
+	different := map[string]func(c *Pattern){
+		"step":          func(c *Pattern) { c.tracks[0].steps[1] = true },
+		"tempo":         func(c *Pattern) { c.tempo = 120.01 },
+		"swing":         func(c *Pattern) { c.swing = 50 },
+		"name":          func(c *Pattern) { c.tracks[0].name = "kick2" },
+		"velocity":      func(c *Pattern) { c.tracks[0].SetVelocity(0, 10) },
+		"timing":        func(c *Pattern) { c.tracks[0].SetTiming(0, 0.25) },
+		"volume":        func(c *Pattern) { c.tracks[0].SetVolume(64) },
+		"pan":           func(c *Pattern) { c.tracks[0].SetPan(-10) },
+		"duplicate":     func(c *Pattern) { c.tracks = append(c.tracks, c.tracks[0].Clone()) },
+		"removed track": func(c *Pattern) { c.tracks = c.tracks[1:] },
+		"steps swapped": func(c *Pattern) { c.tracks[0].steps, c.tracks[1].steps = c.tracks[1].steps, c.tracks[0].steps },
+	}
+	for msg, change := range different {
+		c := p.Clone()
+		change(c)
+		if p.Hash() == c.Hash() {
+			t.Errorf("%s: expected different hash", msg)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrUnknownDefaults is returned by DefaultsFor for versions of the
+// defaults that do not exist.
+var ErrUnknownDefaults = errors.New("unknown defaults")
+
+// LatestDefaults is the version of the most recent defaults, see
+// DefaultsFor.
+const LatestDefaults = 2
+
+// Config bundles the options of the subsystems of the package, so that
+// programs configure decoding, encoding, saving, rendering, playing and
+// printing in one place and opt into new defaults by version instead of
+// collecting options by hand. The methods call the functions of the same
+// name with the options of the config followed by the options passed, which
+// therefore win.
+//
+// The package level functions called without options keep the behavior of
+// V1Defaults, so existing callers of DecodeFile or Pattern.String are not
+// affected by new defaults. Changed defaults get a new version.
+type Config struct {
+	Version       int // of the defaults the config started from
+	DecodeOptions []DecodeOption
+	EncodeOptions []EncodeOption
+	SaveOptions   []SaveOption // for EncodeFile, see WithSaveOptions
+	RenderOptions []RenderOption
+	PlayerOptions []PlayerOption
+	FormatOptions []FormatOption // for Format and String
+}
+
+// V1Defaults returns the defaults of the functions called without options.
+func V1Defaults() Config {
+	return Config{Version: 1}
+}
+
+// V2Defaults returns the recommended defaults for new programs. Compared to
+// V1Defaults, decoding corrects tempos out of range, see
+// WithTempoCorrection, saving keeps a backup of replaced files, see
+// WithBackups, and printouts number the beats, see WithBeatNumbers.
+func V2Defaults() Config {
+	return Config{
+		Version:       2,
+		DecodeOptions: []DecodeOption{WithTempoCorrection(DefaultTempo)},
+		SaveOptions:   []SaveOption{WithBackups(1)},
+		FormatOptions: []FormatOption{WithBeatNumbers()},
+	}
+}
+
+// DefaultsFor returns the defaults of the version, for example read from a
+// configuration file, up to LatestDefaults.
+func DefaultsFor(version int) (Config, error) {
+	switch version {
+	case 1:
+		return V1Defaults(), nil
+	case 2:
+		return V2Defaults(), nil
+	}
+	return Config{}, fmt.Errorf("%w %d", ErrUnknownDefaults, version)
+}
+
+// joinOptions returns the options of the config followed by opts in a new
+// slice, so that configs can be shared by goroutines.
+func joinOptions[T any](config, opts []T) []T {
+	return append(append(make([]T, 0, len(config)+len(opts)), config...), opts...)
+}
+
+// DecodeFile decodes the file at path, see DecodeFile.
+func (c Config) DecodeFile(path string, opts ...DecodeOption) (*Pattern, error) {
+	return DecodeFile(path, joinOptions(c.DecodeOptions, opts)...)
+}
+
+// Decode decodes a pattern from r, see Decode.
+func (c Config) Decode(r io.Reader, opts ...DecodeOption) (*Pattern, error) {
+	return Decode(r, joinOptions(c.DecodeOptions, opts)...)
+}
+
+// EncodeFile writes the pattern to path with the save options of the
+// config, see EncodeFile.
+func (c Config) EncodeFile(path string, p *Pattern, opts ...EncodeOption) error {
+	return EncodeFile(path, p, c.EncodeFileOptions(opts...)...)
+}
+
+// EncodeFileOptions returns the options EncodeFile passes on: the encode
+// options of the config, its save options and opts.
+func (c Config) EncodeFileOptions(opts ...EncodeOption) []EncodeOption {
+	encode := joinOptions(c.EncodeOptions, nil)
+	if len(c.SaveOptions) > 0 {
+		encode = append(encode, WithSaveOptions(c.SaveOptions...))
+	}
+	return append(encode, opts...)
+}
+
+// Encode writes the pattern to w, see Encode.
+func (c Config) Encode(w io.Writer, p *Pattern, opts ...EncodeOption) error {
+	return Encode(w, p, joinOptions(c.EncodeOptions, opts)...)
+}
+
+// Render mixes the pattern with the samples of the kit, see Render.
+func (c Config) Render(p *Pattern, kit *Kit, bars int, opts ...RenderOption) (*Sample, error) {
+	return Render(p, kit, bars, joinOptions(c.RenderOptions, opts)...)
+}
+
+// NewPlayer returns a player for the pattern, see NewPlayer.
+func (c Config) NewPlayer(p *Pattern, handler func(StepEvent), opts ...PlayerOption) *Player {
+	return NewPlayer(p, handler, joinOptions(c.PlayerOptions, opts)...)
+}
+
+// NewSongPlayer returns a player for the song, see NewSongPlayer.
+func (c Config) NewSongPlayer(s *Song, handler func(StepEvent), opts ...PlayerOption) *Player {
+	return NewSongPlayer(s, handler, joinOptions(c.PlayerOptions, opts)...)
+}
+
+// Format writes the printout of the pattern to w, see Format.
+func (c Config) Format(w io.Writer, p *Pattern, opts ...FormatOption) error {
+	return Format(w, p, joinOptions(c.FormatOptions, opts)...)
+}
+
+// String returns the printout of the pattern, see Pattern.String.
+func (c Config) String(p *Pattern) string {
+	var b strings.Builder
+	c.Format(&b, p)
+	return b.String()
+}
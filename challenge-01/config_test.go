@@ -0,0 +1,63 @@
+package drum
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	data, err := ioutil.ReadFile(filepath.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint32(data[typeHeaderLength+8+maxVersionLength:], math.Float32bits(0))
+	broken := filepath.Join(dir, "broken.splice")
+	if err := ioutil.WriteFile(broken, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	v1, err := DefaultsFor(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := v1.DecodeFile(broken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.tempo != 0 || v1.String(p) != p.String() {
+		t.Errorf("Expected the behavior of the package functions but got tempo %v", p.tempo)
+	}
+
+	v2 := V2Defaults()
+	if p, err = v2.DecodeFile(broken); err != nil {
+		t.Fatal(err)
+	}
+	if p.tempo != DefaultTempo {
+		t.Errorf("Expected tempo %v but got %v", DefaultTempo, p.tempo)
+	}
+	if v2.String(p) == p.String() {
+		t.Errorf("Expected beat numbers but got\n%s", v2.String(p))
+	}
+	for i := 0; i < 2; i++ {
+		if err := v2.EncodeFile(broken, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(broken + ".bak"); err != nil {
+		t.Errorf("Expected a backup but got %v", err)
+	}
+
+	if _, err := DefaultsFor(LatestDefaults + 1); !errors.Is(err, ErrUnknownDefaults) {
+		t.Errorf("Expected %v but got %v", ErrUnknownDefaults, err)
+	}
+}
@@ -0,0 +1,17 @@
+package drum
+
+import (
+	"io"
+
+	"github.com/alpe/go-challenge/challenge-01/splice"
+)
+
+// The pattern and song decoders handle their frames in splice.Decode.
+func init() {
+	splice.Register(spliceTypePattern, func(r io.Reader) (interface{}, error) {
+		return Decode(r)
+	})
+	splice.Register(spliceTypeSong, func(r io.Reader) (interface{}, error) {
+		return DecodeSong(r)
+	})
+}
@@ -0,0 +1,50 @@
+package drum
+
+import (
+	"bytes"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/alpe/go-challenge/challenge-01/splice"
+)
+
+func TestSpliceRegistry(t *testing.T) {
+	if exp, got := []string{spliceTypePattern, spliceTypeSong}, splice.Types(); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("Expected the types %q but got %q", exp, got)
+	}
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	frame, err := splice.ReadFrame(bytes.NewReader(buf.Bytes()), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame.Type != spliceTypePattern || len(frame.Payload) != buf.Len()-splice.HeaderLength {
+		t.Errorf("Expected the whole payload in the frame but got %q with %d bytes", frame.Type, len(frame.Payload))
+	}
+	typ, v, err := splice.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := v.(*Pattern); typ != spliceTypePattern || !ok || !got.Equal(p) {
+		t.Errorf("Expected the pattern but got %q %v", typ, v)
+	}
+
+	buf.Reset()
+	if err := EncodeSong(&buf, testSong(t)); err != nil {
+		t.Fatal(err)
+	}
+	typ, v, err = splice.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := v.(*Song); typ != spliceTypeSong || !ok || s.Title != "demo" {
+		t.Errorf("Expected the song but got %q %v", typ, v)
+	}
+}
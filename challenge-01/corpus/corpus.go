@@ -0,0 +1,190 @@
+// Package corpus generates drum machine .splice byte streams to seed fuzz
+// tests of decoders, in this module and in projects reading the format.
+//
+// Generate writes structurally valid streams of random patterns and the
+// mutations break them in targeted ways the decoders must reject without
+// panicking or allocating beyond their limits:
+//
+//	func FuzzDecode(f *testing.F) {
+//		for _, seed := range corpus.Seeds(1, 20) {
+//			f.Add(seed)
+//		}
+//		f.Fuzz(func(t *testing.T, data []byte) {
+//			drum.Decode(bytes.NewReader(data))
+//		})
+//	}
+//
+// The package writes the format itself instead of using the encoder, so the
+// streams do not depend on the code they test.
+package corpus
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+)
+
+// Layout of the format.
+const (
+	headerLength  = 14 // type "SPLICE" and the big endian payload size
+	versionLength = 32
+	tempoLength   = 4
+	stepsLength   = 16
+)
+
+// Generate returns the stream of a random valid pattern with up to
+// maxTracks tracks. The version, tempo, track ids, names and steps are
+// chosen by r, so the same seed generates the same stream.
+func Generate(r *rand.Rand, maxTracks int) []byte {
+	payload := make([]byte, versionLength, versionLength+tempoLength)
+	copy(payload, "0.808-"+randomName(r, 10))
+	payload = binary.LittleEndian.AppendUint32(payload, math.Float32bits(float32(40+r.Intn(200))+r.Float32()))
+	for i := r.Intn(maxTracks + 1); i > 0; i-- {
+		name := randomName(r, 20)
+		payload = binary.LittleEndian.AppendUint32(payload, r.Uint32()%1000)
+		payload = append(payload, uint8(len(name)))
+		payload = append(payload, name...)
+		for s := 0; s < stepsLength; s++ {
+			payload = append(payload, uint8(r.Intn(2)))
+		}
+	}
+	data := append([]byte("SPLICE"), make([]byte, 8)...)
+	binary.BigEndian.PutUint64(data[6:], uint64(len(payload)))
+	return append(data, payload...)
+}
+
+func randomName(r *rand.Rand, max int) string {
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789-_ "
+	b := make([]byte, 1+r.Intn(max))
+	for i := range b {
+		b[i] = chars[r.Intn(len(chars))]
+	}
+	return string(b)
+}
+
+// Mutation breaks a valid stream of Generate in a targeted way. Mutations
+// return a modified copy and leave the stream unchanged, streams they do
+// not apply to, like ones without tracks for name mutations, are returned
+// as they are.
+type Mutation struct {
+	Name   string
+	Mutate func(data []byte) []byte
+}
+
+// Mutations are the mutations applied by Seeds.
+var Mutations = []Mutation{
+	{"flip payload size", FlipPayloadSize},
+	{"grow payload size", GrowPayloadSize},
+	{"shrink payload size", ShrinkPayloadSize},
+	{"truncate mid-name", TruncateMidName},
+	{"oversize name length", OversizeNameLength},
+	{"invalid step value", InvalidStepValue},
+	{"truncate header", TruncateHeader},
+}
+
+// FlipPayloadSize flips bit 40 of the declared payload size, making it far
+// larger than any limit.
+func FlipPayloadSize(data []byte) []byte {
+	if len(data) < headerLength {
+		return data
+	}
+	c := clone(data)
+	binary.BigEndian.PutUint64(c[6:], binary.BigEndian.Uint64(c[6:])^1<<40)
+	return c
+}
+
+// GrowPayloadSize declares one byte more than the payload holds.
+func GrowPayloadSize(data []byte) []byte {
+	return addPayloadSize(data, 1)
+}
+
+// ShrinkPayloadSize declares one byte less than the payload holds, cutting
+// the last step of the last track.
+func ShrinkPayloadSize(data []byte) []byte {
+	return addPayloadSize(data, -1)
+}
+
+func addPayloadSize(data []byte, n int) []byte {
+	if len(data) < headerLength {
+		return data
+	}
+	c := clone(data)
+	binary.BigEndian.PutUint64(c[6:], uint64(int64(binary.BigEndian.Uint64(c[6:]))+int64(n)))
+	return c
+}
+
+// TruncateMidName cuts the stream in the middle of the name of the last
+// track.
+func TruncateMidName(data []byte) []byte {
+	offsets := nameLengthOffsets(data)
+	if len(offsets) == 0 {
+		return data
+	}
+	last := offsets[len(offsets)-1]
+	return clone(data[:last+1+int(data[last])/2])
+}
+
+// OversizeNameLength sets the name length of the first track to the largest
+// value, so that the name runs into the steps and past the payload.
+func OversizeNameLength(data []byte) []byte {
+	offsets := nameLengthOffsets(data)
+	if len(offsets) == 0 {
+		return data
+	}
+	c := clone(data)
+	c[offsets[0]] = math.MaxUint8
+	return c
+}
+
+// InvalidStepValue sets the first step of the first track to 2, steps are
+// either 0 or 1.
+func InvalidStepValue(data []byte) []byte {
+	offsets := nameLengthOffsets(data)
+	if len(offsets) == 0 {
+		return data
+	}
+	c := clone(data)
+	c[offsets[0]+1+int(c[offsets[0]])] = 2
+	return c
+}
+
+// TruncateHeader cuts the stream within the payload size.
+func TruncateHeader(data []byte) []byte {
+	if len(data) < headerLength {
+		return data
+	}
+	return clone(data[:headerLength-3])
+}
+
+// nameLengthOffsets returns the offsets of the name length bytes of the
+// tracks of a valid stream.
+func nameLengthOffsets(data []byte) []int {
+	var offsets []int
+	for i := headerLength + versionLength + tempoLength; i+4 < len(data); {
+		i += 4 // id
+		offsets = append(offsets, i)
+		i += 1 + int(data[i]) + stepsLength
+	}
+	return offsets
+}
+
+func clone(data []byte) []byte {
+	return append([]byte(nil), data...)
+}
+
+// Seeds returns n valid streams generated from seed followed by every
+// mutation of each of them.
+func Seeds(seed int64, n int) [][]byte {
+	r := rand.New(rand.NewSource(seed))
+	var valid, seeds [][]byte
+	for i := 0; i < n; i++ {
+		valid = append(valid, Generate(r, 8))
+	}
+	seeds = append(seeds, valid...)
+	for _, data := range valid {
+		for _, m := range Mutations {
+			seeds = append(seeds, m.Mutate(data))
+		}
+	}
+	return seeds
+}
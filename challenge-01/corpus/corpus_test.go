@@ -0,0 +1,67 @@
+package corpus
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+func TestGenerate(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		data := Generate(r, 8)
+		p, err := drum.DecodeBytes(data)
+		if err != nil {
+			t.Fatalf("Expected a valid stream but got %v for %x", err, data)
+		}
+		if len(p.Tracks()) > 8 || len(nameLengthOffsets(data)) != len(p.Tracks()) {
+			t.Fatalf("unexpected tracks of %v", p)
+		}
+		var buf bytes.Buffer
+		if err := drum.Encode(&buf, p); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Fatalf("Expected the stream of the encoder\n%x\nbut got\n%x", buf.Bytes(), data)
+		}
+	}
+	if a, b := Seeds(7, 3), Seeds(7, 3); len(a) != 3*(1+len(Mutations)) || !bytes.Equal(a[len(a)-1], b[len(b)-1]) {
+		t.Error("Expected the same seeds for the same seed")
+	}
+}
+
+func TestMutations(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	data := Generate(r, 8)
+	for len(nameLengthOffsets(data)) == 0 {
+		data = Generate(r, 8)
+	}
+	orig := clone(data)
+	for _, m := range Mutations {
+		mutated := m.Mutate(data)
+		if _, err := drum.Decode(bytes.NewReader(mutated)); err == nil {
+			t.Errorf("%s: expected an error", m.Name)
+		}
+		if _, err := drum.DecodeBytes(mutated); err == nil {
+			t.Errorf("%s: expected an error of DecodeBytes", m.Name)
+		}
+	}
+	if !bytes.Equal(data, orig) {
+		t.Error("Expected the stream unchanged")
+	}
+	if _, err := drum.DecodeBytes(FlipPayloadSize(data)); !errors.Is(err, drum.ErrPayloadTooLarge) {
+		t.Errorf("Expected %v but got %v", drum.ErrPayloadTooLarge, err)
+	}
+	if _, err := drum.DecodeBytes(InvalidStepValue(data)); !errors.Is(err, drum.ErrInvalidStepValue) {
+		t.Errorf("Expected %v but got %v", drum.ErrInvalidStepValue, err)
+	}
+	empty := []byte("SPLICE")
+	for _, m := range Mutations {
+		if !bytes.Equal(m.Mutate(empty), empty) {
+			t.Errorf("%s: expected streams it does not apply to unchanged", m.Name)
+		}
+	}
+}
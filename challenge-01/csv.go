@@ -0,0 +1,124 @@
+package drum
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csv cells of the steps, an empty cell is a disabled step too.
+const (
+	csvStepEnabled  = "x"
+	csvStepDisabled = ""
+)
+
+// ToCSV writes the step grid of the pattern as CSV for editing in a
+// spreadsheet: a row with the version and the tempo, a row with the column
+// names and a row per track with the id, the name and a cell per step,
+// "x" for enabled steps and empty cells for disabled ones:
+//
+//	version,0.808-alpha,tempo,120
+//	id,name,1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16
+//	0,kick,x,,,,x,,,,x,,,,x,,,
+//
+// Everything else, like velocities or metadata, is not written.
+func (p *Pattern) ToCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"version", p.version, "tempo", FormatTempo(p.tempo)})
+	row := []string{"id", "name"}
+	for i := 1; i <= stepsLength; i++ {
+		row = append(row, strconv.Itoa(i))
+	}
+	cw.Write(row)
+	for _, t := range p.tracks {
+		row = append(row[:0], strconv.FormatUint(uint64(t.id), 10), t.name)
+		for _, enabled := range t.steps {
+			cell := csvStepDisabled
+			if enabled {
+				cell = csvStepEnabled
+			}
+			row = append(row, cell)
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// FromCSV reads a pattern written by ToCSV and edited in a spreadsheet.
+// Steps are enabled by "x" or "1" and disabled by empty cells, "-", "." or
+// "0", in any case and with surrounding spaces. Spreadsheets pad rows with
+// empty cells and add empty rows, both are ignored. All values are
+// validated like by the setters.
+func FromCSV(r io.Reader) (*Pattern, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	var rows [][]string
+	var lines []int // of the rows in the input, for the errors
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse csv: %v", err)
+		}
+		for len(row) > 0 && strings.TrimSpace(row[len(row)-1]) == "" {
+			row = row[:len(row)-1]
+		}
+		if len(row) > 0 {
+			line, _ := cr.FieldPos(0)
+			rows, lines = append(rows, row), append(lines, line)
+		}
+	}
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("parse csv: missing header rows")
+	}
+	header := rows[0]
+	if len(header) != 4 || header[0] != "version" || header[2] != "tempo" {
+		return nil, fmt.Errorf("parse csv: line %d: expected version,<version>,tempo,<tempo> but got %q", lines[0], strings.Join(header, ","))
+	}
+	tempo, err := ParseTempo(header[3])
+	if err != nil {
+		return nil, fmt.Errorf("parse csv: line %d: invalid tempo %q", lines[0], header[3])
+	}
+	if columns := rows[1]; len(columns) < 2 || columns[0] != "id" || columns[1] != "name" {
+		return nil, fmt.Errorf("parse csv: line %d: expected the column names id,name,1..%d", lines[1], stepsLength)
+	}
+	var tracks []*Track
+	for i, row := range rows[2:] {
+		t, err := csvTrack(row)
+		if err != nil {
+			return nil, fmt.Errorf("parse csv: line %d: %v", lines[i+2], err)
+		}
+		tracks = append(tracks, t)
+	}
+	return NewPattern(header[1], tempo, tracks...)
+}
+
+func csvTrack(row []string) (*Track, error) {
+	if len(row) > 2+stepsLength {
+		return nil, fmt.Errorf("expected %d steps but got %d", stepsLength, len(row)-2)
+	}
+	id, err := strconv.ParseUint(strings.TrimSpace(row[0]), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id %q", row[0])
+	}
+	name := ""
+	if len(row) > 1 {
+		name = row[1]
+	}
+	var steps Steps
+	for i := 2; i < len(row); i++ {
+		switch cell := strings.ToLower(strings.TrimSpace(row[i])); cell {
+		case csvStepEnabled, "1":
+			steps[i-2] = true
+		case csvStepDisabled, "-", ".", "0":
+		default:
+			return nil, fmt.Errorf("step %d: %v %q", i-1, ErrInvalidStepValue, row[i])
+		}
+	}
+	return NewTrack(uint32(id), name, steps)
+}
@@ -0,0 +1,70 @@
+package drum
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestCSV(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := p.ToCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	exp := `version,0.808-alpha,tempo,98.4
+id,name,1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16
+0,kick,x,,,,,,,,x,,,,,,,
+1,snare,,,,,x,,,,,,,,x,,,
+3,hh-open,,,x,,,,x,,x,,x,,,,x,
+5,cowbell,,,,,,,,,x,,,,,,,
+`
+	if buf.String() != exp {
+		t.Errorf("Expected:\n%v\nbut got:\n%v", exp, buf.String())
+	}
+	got, err := FromCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(p) {
+		t.Errorf("Expected the pattern back but got %v", Diff(p, got))
+	}
+
+	// as exported by a spreadsheet after editing
+	sheet := "version,0.909,tempo,120,,,,,,,,,,,,,,\r\n" +
+		"id,name,1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16\r\n" +
+		"1,Kick,X,-,-,-, x ,-,-,-,1,0,.,,x,,,\r\n" +
+		",,,,,,,,,,,,,,,,,\r\n" +
+		"2,\"Hi, Hat\",,,x\r\n"
+	got, err = FromCSV(strings.NewReader(sheet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expPrintout := "Saved with HW Version: 0.909\nTempo: 120\n(1) Kick\t|x---|x---|x---|x---|\n(2) Hi, Hat\t|--x-|----|----|----|\n"
+	if got.String() != expPrintout {
+		t.Errorf("Expected:\n%v\nbut got:\n%v", expPrintout, got)
+	}
+
+	testCases := []struct {
+		csv, err string
+	}{
+		{"", "missing header rows"},
+		{"tempo,120\nid,name\n", "line 1: expected version,<version>,tempo,<tempo>"},
+		{"version,x,tempo,fast\nid,name\n", `line 1: invalid tempo "fast"`},
+		{"version,x,tempo,-1\nid,name\n", ErrInvalidTempo.Error()},
+		{"version,x,tempo,120\n1,kick,x\n", "line 2: expected the column names"},
+		{"version,x,tempo,120\nid,name\n\nkick,x\n", `line 4: invalid id "kick"`},
+		{"version,x,tempo,120\nid,name\n1,kick,x,o\n", `line 3: step 2: invalid step value "o"`},
+		{"version,x,tempo,120\nid,name\n1,kick" + strings.Repeat(",x", 17) + "\n", "line 3: expected 16 steps but got 17"},
+		{"version,x,tempo,120\nid,name\n1,\"kick\n", "parse csv: "},
+	}
+	for _, testCase := range testCases {
+		if _, err := FromCSV(strings.NewReader(testCase.csv)); err == nil || !strings.Contains(err.Error(), testCase.err) {
+			t.Errorf("%q: Expected an error with '%v' but got %v", testCase.csv, testCase.err, err)
+		}
+	}
+}
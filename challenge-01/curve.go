@@ -0,0 +1,101 @@
+package drum
+
+import (
+	"sort"
+	"strings"
+)
+
+// VelocityCurve holds a velocity for every step of a track.
+type VelocityCurve [stepsLength]uint8
+
+// Velocity curve presets. The backbeat is on beats 2 and 4, steps 5 and 13.
+var (
+	// Flat plays every step at MaxVelocity.
+	Flat = curve(func(int) uint8 { return MaxVelocity })
+	// GhostNotes keeps the backbeat loud and turns all other hits, typically
+	// of a snare, into quiet ghost notes.
+	GhostNotes = curve(func(step int) uint8 {
+		if isBackbeat(step) {
+			return MaxVelocity
+		}
+		return 40
+	})
+	// Crescendo raises the velocity evenly over the bar, for example for
+	// hats leading into the next pattern.
+	Crescendo = curve(func(step int) uint8 {
+		return uint8(40 + (MaxVelocity-40)*step/(stepsLength-1))
+	})
+	// BackbeatAccent accents beats 2 and 4.
+	BackbeatAccent = curve(func(step int) uint8 {
+		if isBackbeat(step) {
+			return MaxVelocity
+		}
+		return 96
+	})
+	// DownbeatAccent accents every beat and plays the steps in between softer.
+	DownbeatAccent = curve(func(step int) uint8 {
+		if step%blockSize == 0 {
+			return MaxVelocity
+		}
+		return 80
+	})
+)
+
+// velocityCurves are the presets by name.
+var velocityCurves = map[string]VelocityCurve{
+	"flat":            Flat,
+	"ghost-notes":     GhostNotes,
+	"crescendo":       Crescendo,
+	"backbeat-accent": BackbeatAccent,
+	"downbeat-accent": DownbeatAccent,
+}
+
+func curve(fn func(step int) uint8) VelocityCurve {
+	var c VelocityCurve
+	for i := range c {
+		c[i] = fn(i)
+	}
+	return c
+}
+
+func isBackbeat(step int) bool {
+	return step == blockSize || step == 3*blockSize
+}
+
+// VelocityCurveByName returns the preset with the given name, case
+// insensitive.
+func VelocityCurveByName(name string) (VelocityCurve, bool) {
+	c, ok := velocityCurves[strings.ToLower(name)]
+	return c, ok
+}
+
+// VelocityCurveNames returns the names of all presets sorted.
+func VelocityCurveNames() []string {
+	names := make([]string, 0, len(velocityCurves))
+	for n := range velocityCurves {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyCurve sets the velocity of every step of the track from the curve.
+// Velocities of 0 in the curve are treated as MaxVelocity.
+func (t *Track) ApplyCurve(c VelocityCurve) {
+	for step, v := range c {
+		if v > MaxVelocity {
+			v = MaxVelocity
+		}
+		t.SetVelocity(step, v)
+	}
+}
+
+// Scale returns the curve with all velocities scaled by factor and
+// limited to 1 and MaxVelocity, to combine a preset with a track level.
+func (c VelocityCurve) Scale(factor float64) VelocityCurve {
+	var s VelocityCurve
+	for i, v := range c {
+		s[i] = uint8(clamp(int(float64(v)*factor+0.5), 1, MaxVelocity))
+	}
+	return s
+}
@@ -0,0 +1,34 @@
+package drum
+
+import (
+	"path"
+	"testing"
+)
+
+func TestApplyCurve(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, ok := VelocityCurveByName("Ghost-Notes")
+	if !ok {
+		t.Fatal("preset not found")
+	}
+	snare := p.TrackByName("snare")
+	snare.ApplyCurve(c)
+	if snare.Velocity(4) != MaxVelocity || snare.Velocity(5) != 40 {
+		t.Errorf("unexpected velocities %v %v", snare.Velocity(4), snare.Velocity(5))
+	}
+	if Crescendo[0] != 40 || Crescendo[stepsLength-1] != MaxVelocity {
+		t.Errorf("unexpected crescendo %v", Crescendo)
+	}
+	if got := BackbeatAccent.Scale(0.5); got[4] != 64 || got[0] != 48 {
+		t.Errorf("unexpected scaled curve %v", got)
+	}
+	if _, ok := VelocityCurveByName("unknown"); ok {
+		t.Errorf("expected unknown preset not to be found")
+	}
+	if exp, got := len(velocityCurves), len(VelocityCurveNames()); got != exp {
+		t.Errorf("Expected %v names but got %v", exp, got)
+	}
+}
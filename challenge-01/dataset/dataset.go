@@ -0,0 +1,235 @@
+// Package dataset exports drum patterns as matrices for training groove
+// models.
+//
+// Every track of every pattern becomes one row of 16 step values. A step
+// holds the velocity it is played with, 0 when it is off. Patterns are
+// assigned to the train, validation or test split by their fingerprint so
+// that the same groove always ends up in the same split, even when it is
+// contained more than once in a library.
+package dataset
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// Format is the output format of Export.
+type Format string
+
+const (
+	// CSV writes one row per track with metadata columns followed by the
+	// step columns s1 to s16.
+	CSV Format = "csv"
+	// NPZ writes a NumPy archive with one array per column. The steps and
+	// timing arrays have the shape (rows, 16), all others (rows,).
+	NPZ Format = "npz"
+)
+
+// Split is the dataset partition a pattern belongs to.
+type Split uint8
+
+// The splits stored in the split column.
+const (
+	Train Split = iota
+	Validation
+	Test
+)
+
+// Percentage of patterns in the train and validation split. The remaining
+// patterns are used for testing.
+const (
+	TrainPercent      = 80
+	ValidationPercent = 10
+)
+
+func (s Split) String() string {
+	switch s {
+	case Train:
+		return "train"
+	case Validation:
+		return "validation"
+	default:
+		return "test"
+	}
+}
+
+// SplitOf returns the split the pattern is assigned to. It only depends on
+// the fingerprint of the pattern.
+func SplitOf(p *drum.Pattern) Split {
+	return splitOf(p.Fingerprint())
+}
+
+func splitOf(fingerprint string) Split {
+	b, err := hex.DecodeString(fingerprint)
+	if err != nil || len(b) < 8 {
+		return Train
+	}
+	switch n := binary.BigEndian.Uint64(b) % 100; {
+	case n < TrainPercent:
+		return Train
+	case n < TrainPercent+ValidationPercent:
+		return Validation
+	default:
+		return Test
+	}
+}
+
+// row is a single track of the dataset.
+type row struct {
+	pattern     int
+	fingerprint string
+	split       Split
+	version     string
+	tempo       float32
+	swing       uint8
+	trackID     uint32
+	trackName   string
+	steps       [16]uint8
+	timing      [16]float32
+}
+
+func rows(patterns []*drum.Pattern) []row {
+	var rs []row
+	for i, p := range patterns {
+		fp := p.Fingerprint()
+		for _, t := range p.Tracks() {
+			r := row{
+				pattern:     i,
+				fingerprint: fp,
+				split:       splitOf(fp),
+				version:     p.Version(),
+				tempo:       p.Tempo(),
+				swing:       p.Swing(),
+				trackID:     t.ID(),
+				trackName:   t.Name(),
+			}
+			for n, enabled := range t.Steps() {
+				if enabled {
+					r.steps[n] = t.Velocity(n)
+					r.timing[n] = float32(t.Timing(n))
+				}
+			}
+			rs = append(rs, r)
+		}
+	}
+	return rs
+}
+
+// Export writes the tracks of all patterns to w in the given format.
+func Export(w io.Writer, patterns []*drum.Pattern, format Format) error {
+	switch format {
+	case CSV:
+		return writeCSV(w, rows(patterns))
+	case NPZ:
+		return writeNPZ(w, rows(patterns))
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+func writeCSV(w io.Writer, rs []row) error {
+	cw := csv.NewWriter(w)
+	header := []string{"split", "fingerprint", "pattern", "version", "tempo", "swing", "track_id", "track_name"}
+	for n := 1; n <= 16; n++ {
+		header = append(header, "s"+strconv.Itoa(n))
+	}
+	cw.Write(header)
+	for _, r := range rs {
+		record := []string{
+			r.split.String(),
+			r.fingerprint,
+			strconv.Itoa(r.pattern),
+			r.version,
+			strconv.FormatFloat(float64(r.tempo), 'g', -1, 32),
+			strconv.Itoa(int(r.swing)),
+			strconv.FormatUint(uint64(r.trackID), 10),
+			r.trackName,
+		}
+		for _, v := range r.steps {
+			record = append(record, strconv.Itoa(int(v)))
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeNPZ(w io.Writer, rs []row) error {
+	var (
+		steps, splits, swings []uint8
+		timing, tempos        []float32
+		patterns, trackIDs    []uint32
+	)
+	for _, r := range rs {
+		steps = append(steps, r.steps[:]...)
+		timing = append(timing, r.timing[:]...)
+		splits = append(splits, uint8(r.split))
+		swings = append(swings, r.swing)
+		tempos = append(tempos, r.tempo)
+		patterns = append(patterns, uint32(r.pattern))
+		trackIDs = append(trackIDs, r.trackID)
+	}
+	arrays := []struct {
+		name  string
+		dtype string
+		shape []int
+		data  interface{}
+	}{
+		{"steps", "|u1", []int{len(rs), 16}, steps},
+		{"timing", "<f4", []int{len(rs), 16}, timing},
+		{"split", "|u1", []int{len(rs)}, splits},
+		{"pattern", "<u4", []int{len(rs)}, patterns},
+		{"track_id", "<u4", []int{len(rs)}, trackIDs},
+		{"tempo", "<f4", []int{len(rs)}, tempos},
+		{"swing", "|u1", []int{len(rs)}, swings},
+	}
+	zw := zip.NewWriter(w)
+	for _, a := range arrays {
+		f, err := zw.Create(a.name + ".npy")
+		if err != nil {
+			return fmt.Errorf("write %s: %v", a.name, err)
+		}
+		if err := writeNPY(f, a.dtype, a.shape, a.data); err != nil {
+			return fmt.Errorf("write %s: %v", a.name, err)
+		}
+	}
+	return zw.Close()
+}
+
+// writeNPY writes a C ordered array in the NumPy format version 1.0.
+func writeNPY(w io.Writer, dtype string, shape []int, data interface{}) error {
+	dims := make([]string, len(shape))
+	for i, n := range shape {
+		dims[i] = strconv.Itoa(n)
+	}
+	tuple := strings.Join(dims, ", ")
+	if len(shape) == 1 {
+		tuple += ","
+	}
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%s), }", dtype, tuple)
+	// magic, version and header length plus the header end with a newline
+	// on a 64 byte boundary
+	const prefixLength = 10
+	pad := 64 - (prefixLength+len(header)+1)%64
+	if pad == 64 {
+		pad = 0
+	}
+	header += string(bytes.Repeat([]byte{' '}, pad)) + "\n"
+
+	var buf bytes.Buffer
+	buf.WriteString("\x93NUMPY\x01\x00")
+	binary.Write(&buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+	binary.Write(&buf, binary.LittleEndian, data)
+	_, err := buf.WriteTo(w)
+	return err
+}
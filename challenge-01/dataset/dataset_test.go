@@ -0,0 +1,95 @@
+package dataset
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+func fixtures(t *testing.T, names ...string) []*drum.Pattern {
+	var ps []*drum.Pattern
+	for _, name := range names {
+		p, err := drum.DecodeFile(path.Join("..", "fixtures", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+func TestExportCSV(t *testing.T) {
+	ps := fixtures(t, "pattern_5.splice")
+	var buf bytes.Buffer
+	if err := Export(&buf, ps, CSV); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if exp, got := 1+len(ps[0].Tracks()), len(lines); got != exp {
+		t.Fatalf("Expected %d lines but got %d", exp, got)
+	}
+	exp := SplitOf(ps[0]).String() + "," + ps[0].Fingerprint() + ",0,0.708-alpha,999,0,1,Kick,127,0,0,0,0,0,0,0,127,0,0,0,0,0,0,0"
+	if lines[1] != exp {
+		t.Errorf("Expected '%v' but got '%v'", exp, lines[1])
+	}
+}
+
+func TestExportNPZ(t *testing.T) {
+	ps := fixtures(t, "pattern_1.splice", "pattern_2.splice")
+	var buf bytes.Buffer
+	if err := Export(&buf, ps, NPZ); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := len(ps[0].Tracks()) + len(ps[1].Tracks())
+	for _, f := range zr.File {
+		if f.Name != "steps.npy" {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		headerLength := int(data[8]) | int(data[9])<<8
+		if (10+headerLength)%64 != 0 {
+			t.Errorf("header not aligned: %d", headerLength)
+		}
+		if exp, got := 10+headerLength+rows*16, len(data); got != exp {
+			t.Errorf("Expected %d bytes but got %d", exp, got)
+		}
+		if !bytes.Contains(data, []byte("'shape': (10, 16)")) {
+			t.Errorf("unexpected header: %q", data[10:10+headerLength])
+		}
+		return
+	}
+	t.Error("steps.npy not found")
+}
+
+func TestSplitDeterministic(t *testing.T) {
+	a := fixtures(t, "pattern_1.splice")[0]
+	b := a.Clone()
+	b.SetRawExtra([]byte("other tool"))
+	if a.Fingerprint() != b.Fingerprint() || SplitOf(a) != SplitOf(b) {
+		t.Error("expected same split for same groove")
+	}
+	counts := map[Split]int{}
+	for n := 0; n < 1000; n++ {
+		counts[splitOf(fmt.Sprintf("%016x", n))]++
+	}
+	if counts[Train] != 800 || counts[Validation] != 100 || counts[Test] != 100 {
+		t.Errorf("unexpected split distribution: %v", counts)
+	}
+}
@@ -1,120 +1,408 @@
 package drum
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash"
+	"hash/crc32"
 	"io"
+	"io/ioutil"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 const (
 	spliceTypePattern = "SPLICE"
-	typeHeaderLength  = uint8(len(spliceTypePattern))
+	typeHeaderLength  = len(spliceTypePattern)
 )
 
-// ErrUnsupportedFileFormat is returned when the file to decode does not match
-// the expected format.
-var ErrUnsupportedFileFormat = errors.New("unsupported file format")
+var (
+	// ErrUnsupportedFileFormat is returned when the file to decode does not match
+	// the expected format.
+	ErrUnsupportedFileFormat = errors.New("unsupported file format")
+	// ErrChecksumMismatch is returned when the CRC32 trailer does not match
+	// the payload.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrInvalidStepValue is returned for a step byte other than 0 or 1.
+	ErrInvalidStepValue = errors.New("invalid step value")
+	// ErrTruncatedPayload is returned when the file ends within a field.
+	ErrTruncatedPayload = errors.New("truncated payload")
+	// ErrPayloadTooLarge is returned when the declared payload size exceeds
+	// the MaxPayloadSize of the Limits.
+	ErrPayloadTooLarge = errors.New("payload too large")
+	// ErrTooManyTracks is returned when a file has more tracks than the
+	// MaxTracks of the Limits.
+	ErrTooManyTracks = errors.New("too many tracks")
+	// ErrPayloadSizeMismatch is returned when the declared payload size ends
+	// within a track, which is reported with the payload offset of the last
+	// track boundary.
+	ErrPayloadSizeMismatch = errors.New("payload size mismatch")
+)
+
+// DecodeError is returned by the decoder for files that can not be decoded.
+// It tells the field that failed and wraps the class of the failure, one of
+// the Err variables like ErrTruncatedPayload, so that callers can tell them
+// apart with errors.Is.
+type DecodeError struct {
+	Field string // like "tempo" or "track name"
+	Track int    // number of the track from 1, 0 for fields outside of tracks
+	Err   error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Track > 0 {
+		return fmt.Sprintf("parse %s (track %d): %v", e.Field, e.Track, e.Err)
+	}
+	return fmt.Sprintf("parse %s: %v", e.Field, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// parseError returns the DecodeError of the field. Reading past the end of
+// the data is reported as ErrTruncatedPayload.
+func parseError(field string, track int, err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = fmt.Errorf("%w: %w", ErrTruncatedPayload, err)
+	}
+	return &DecodeError{Field: field, Track: track, Err: err}
+}
+
+// sizeMismatch returns the error of the declared payload size ending within
+// the track starting at the payload offset boundary.
+func sizeMismatch(track int, declared, boundary int64) error {
+	return parseError("track", track, fmt.Errorf("%w: payload declared %d bytes, track boundary at %d", ErrPayloadSizeMismatch, declared, boundary))
+}
+
+// DecodeOption configures the decoder.
+type DecodeOption func(*decodeOptions)
+
+type decodeOptions struct {
+	checksum bool
+	limits   Limits                // see WithLimits
+	trailing TrailingPolicy        // see WithTrailing
+	steps    stepMode              // see WithLenientSteps
+	progress func(done, total int) // see WithProgress
+	logger   *slog.Logger          // see WithLogger
+	stats    func(DecodeStats)     // see WithStats
+	padding  payloadPadding        // see WithPaddedPayload
+	arena    *Arena                // see WithArena
+	rec      *statsRecorder        // of the running decode, nil without stats
+
+	tempoFallback float32 // see WithTempoCorrection, 0 when tempos are kept
+}
+
+// newDecodeOptions applies opts to the defaults. The options are only
+// allocated when there are any, which keeps DecodeInto free of allocations.
+func newDecodeOptions(opts []DecodeOption) decodeOptions {
+	if len(opts) == 0 {
+		return decodeOptions{limits: defaultLimits}
+	}
+	o := &decodeOptions{limits: defaultLimits}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return *o
+}
+
+// WithChecksum makes the decoder expect a CRC32 (IEEE) trailer of 4 bytes
+// (big endian) directly after the payload. ErrChecksumMismatch is returned
+// when it does not match the payload read.
+func WithChecksum() DecodeOption {
+	return func(o *decodeOptions) {
+		o.checksum = true
+	}
+}
 
 // DecodeFile decodes the drum machine file found at the provided path
 // and returns a pointer to a parsed pattern which is the entry point to the
 // rest of the data.
-func DecodeFile(path string) (*Pattern, error) {
+func DecodeFile(path string, opts ...DecodeOption) (*Pattern, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
-	return decode(bufio.NewReader(file))
+	return decodeAll(file, opts...)
+}
+
+// DecodeDir decodes all .splice files in dir, not including sub-directories.
+// The patterns are returned by file name.
+func DecodeDir(dir string, opts ...DecodeOption) (map[string]*Pattern, error) {
+	return DecodeDirContext(context.Background(), dir, opts...)
 }
 
-func decode(r io.Reader) (*Pattern, error) {
-	p, err := newPayloadReader(r)
+// DecodeDirContext decodes like DecodeDir and stops with the context error
+// when the context is done before all files are decoded.
+func DecodeDirContext(ctx context.Context, dir string, opts ...DecodeOption) (map[string]*Pattern, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range files {
+		if !f.IsDir() && strings.EqualFold(filepath.Ext(f.Name()), spliceFileExt) {
+			names = append(names, f.Name())
+		}
+	}
+	progress := newProgress(newDecodeOptions(opts).progress, len(names))
+	patterns := make(map[string]*Pattern)
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		p, err := DecodeFile(filepath.Join(dir, name), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		patterns[name] = p
+		progress.step()
+	}
+	return patterns, nil
+}
+
+// Decode decodes a drum machine file from r.
+func Decode(r io.Reader, opts ...DecodeOption) (*Pattern, error) {
+	return decode(r, opts...)
+}
+
+func decode(r io.Reader, opts ...DecodeOption) (pattern *Pattern, err error) {
+	o := newDecodeOptions(opts)
+	o.rec = newStatsRecorder(o.stats)
+	r, read := o.rec.reader(r)
+	defer func() { o.rec.finish(read, 0, err) }()
+	r, log := newDecodeLogger(r, o.logger)
+	p, err := newPayloadReader(r, o, log)
+	if err != nil {
+		return nil, err
+	}
+	crc := crc32.NewIEEE()
+	if o.checksum {
+		p.R = io.TeeReader(p.R, crc)
+	}
+	declared := p.N
+	pattern, err = decodePattern(p, o, log)
+	o.rec.payloadRead(declared - p.N)
+	if err != nil {
+		return nil, err
+	}
+	if o.checksum {
+		if err := verifyChecksum(r, crc, log); err != nil {
+			return nil, err
+		}
+	}
+	offset := log.offset()
+	trailing, err := ioutil.ReadAll(o.limits.extraReader(r))
 	if err != nil {
+		return nil, fmt.Errorf("read trailing data: %v", err)
+	}
+	if err := o.limits.checkExtraSize(int64(len(trailing))); err != nil {
+		return nil, parseError("trailing data", 0, err)
+	}
+	if len(trailing) > 0 {
+		log.field("trailing data", offset, len(trailing))
+		if !bytes.HasPrefix(trailing, []byte(extensionMagic)) {
+			log.warn("unrecognized trailing data", offset, "length", len(trailing))
+		}
+	}
+	if err := decodeExtensions(trailing, pattern); err != nil {
+		return nil, err
+	}
+	if err := applyTrailing(pattern, o.trailing); err != nil {
 		return nil, err
 	}
-	return decodePattern(p)
+	return pattern, nil
 }
 
-func newPayloadReader(r io.Reader) (*io.LimitedReader, error) {
+// verifyChecksum reads the checksum trailer from r and compares it with the
+// checksum of the payload read.
+func verifyChecksum(r io.Reader, crc hash.Hash32, log *decodeLogger) error {
+	offset := log.offset()
+	var sum uint32
+	if err := binary.Read(r, binary.BigEndian, &sum); err != nil {
+		return parseError("checksum", 0, err)
+	}
+	log.field("checksum", offset, sum)
+	if sum != crc.Sum32() {
+		return parseError("checksum", 0, ErrChecksumMismatch)
+	}
+	return nil
+}
+
+func newPayloadReader(r io.Reader, o decodeOptions, log *decodeLogger) (*io.LimitedReader, error) {
 	typeHeader, err := readBytes(r, typeHeaderLength)
 	if err != nil {
-		return nil, fmt.Errorf("parse type header: %v", err)
+		return nil, parseError("type header", 0, err)
 	}
+	log.field("type header", 0, string(typeHeader))
 	if !bytes.Equal(typeHeader, []byte(spliceTypePattern)) {
-		return nil, ErrUnsupportedFileFormat
+		return nil, parseError("type header", 0, ErrUnsupportedFileFormat)
 	}
 	var payloadSize int64
 	if err := binary.Read(r, binary.BigEndian, &payloadSize); err != nil {
-		return nil, fmt.Errorf("parse payload size: %v", err)
-
+		return nil, parseError("payload size", 0, err)
 	}
-
-	return &io.LimitedReader{r, payloadSize}, nil
+	log.field("payload size", int64(typeHeaderLength), payloadSize)
+	o.rec.declare(payloadSize)
+	if payloadSize < 0 {
+		log.warn("negative payload size", int64(typeHeaderLength), "size", payloadSize)
+	}
+	if err := o.limits.checkPayloadSize(payloadSize); err != nil {
+		return nil, parseError("payload size", 0, err)
+	}
+	return &io.LimitedReader{R: r, N: payloadSize}, nil
 }
 
-func decodePattern(r *io.LimitedReader) (*Pattern, error) {
+func decodePattern(r *io.LimitedReader, o decodeOptions, log *decodeLogger) (*Pattern, error) {
 	var pattern Pattern
+	header, err := decodeHeader(r, log)
+	if err != nil {
+		return nil, err
+	}
+	pattern.version, pattern.tempo, pattern.rawVersion = header.Version, header.Tempo, header.rawVersion
+	o.correctTempo(&pattern, log)
+	err = decodeTracks(r, o, log, func(t *Track) error {
+		pattern.tracks = append(pattern.tracks, t)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pattern, nil
+}
+
+func decodeHeader(r io.Reader, log *decodeLogger) (PatternHeader, error) {
+	var h PatternHeader
+	offset := log.offset()
 	v, err := readBytes(r, maxVersionLength)
 	if err != nil {
-		return nil, fmt.Errorf("parse version: %v", err)
+		return h, parseError("version", 0, err)
+	}
+	h.Version = cropToString(v)
+	h.rawVersion = v
+	log.field("version", offset, h.Version)
+
+	offset = log.offset()
+	if err := binary.Read(r, binary.LittleEndian, &h.Tempo); err != nil {
+		return h, parseError("tempo", 0, err)
 	}
-	pattern.version = cropToString(v)
+	log.field("tempo", offset, h.Tempo)
+	if !validTempo(h.Tempo) {
+		log.warn("tempo out of range", offset, "tempo", h.Tempo)
+	}
+	return h, nil
+}
 
-	if err := binary.Read(r, binary.LittleEndian, &pattern.tempo); err != nil {
-		return nil, fmt.Errorf("parse tempo: %v", err)
+// decodeTracks calls visit for every track until the end of the payload.
+// Errors returned by visit are returned as is.
+func decodeTracks(r *io.LimitedReader, o decodeOptions, log *decodeLogger, visit func(*Track) error) error {
+	var ids map[uint32]bool // only used for logging
+	if log != nil {
+		ids = make(map[uint32]bool)
 	}
-	for r.N > 0 {
-		tr, err := decodeTrack(r)
+	// the offsets in the payload, whose header is read
+	boundary := int64(maxVersionLength + 4)
+	declared := boundary + r.N
+	o.rec.boundary(boundary)
+	for n := 1; r.N > 0; n++ {
+		if err := o.limits.checkTracks(n); err != nil {
+			return parseError("track", n, err)
+		}
+		var err error
+		if r, log, err = o.padding.peek(r, log); err != nil {
+			return parseError("track", n, err)
+		}
+		if r.N == 0 {
+			break
+		}
+		offset := log.offset()
+		tr, err := decodeTrack(r, o, log)
+		if err != nil && r.N == 0 && errors.Is(err, ErrTruncatedPayload) {
+			// the payload ended, not the file
+			return sizeMismatch(n, declared, boundary)
+		}
 		if err != nil {
-			return nil, err
+			var de *DecodeError
+			if errors.As(err, &de) {
+				de.Track = n
+			}
+			return err
+		}
+		if ids[tr.id] {
+			log.warn("duplicate track id", offset, "id", tr.id)
+		}
+		if ids != nil {
+			ids[tr.id] = true
+		}
+		boundary += int64(trackHeaderLength + len(tr.name) + stepsLength)
+		o.rec.track()
+		o.rec.boundary(boundary)
+		if err := visit(tr); err != nil {
+			return err
 		}
-		pattern.tracks = append(pattern.tracks, tr)
 	}
-	return &pattern, nil
+	return nil
 }
 
-func decodeTrack(r io.Reader) (*Track, error) {
+func decodeTrack(r io.Reader, o decodeOptions, log *decodeLogger) (*Track, error) {
 	var track Track
+	offset := log.offset()
 	if err := binary.Read(r, binary.LittleEndian, &track.id); err != nil {
-		return nil, fmt.Errorf("parse track id: %v", err)
+		return nil, parseError("track id", 0, err)
 	}
+	log.field("track id", offset, track.id)
 	var lenName uint8
 	if err := binary.Read(r, binary.LittleEndian, &lenName); err != nil {
-		return nil, fmt.Errorf("parse track name length: %v", err)
+		return nil, parseError("track name length", 0, err)
+	}
+	log.field("track name length", offset+4, lenName)
+	if err := o.limits.checkNameLength(int(lenName)); err != nil {
+		return nil, parseError("track name length", 0, err)
 	}
-	b, err := readBytes(r, lenName)
+	b, err := readBytes(r, int(lenName))
 	if err != nil {
-		return nil, fmt.Errorf("parse track name: %v", err)
+		return nil, parseError("track name", 0, err)
 	}
 	track.name = string(b)
+	log.field("track name", offset+5, track.name)
+	if lenName == 0 {
+		log.warn("empty track name", offset+5, "id", track.id)
+	}
 
-	if track.steps, err = decodeSteps(r); err != nil {
-		return nil, err
+	offset = log.offset()
+	if b, err = readBytes(r, stepsLength); err != nil {
+		return nil, parseError("steps", 0, err)
+	}
+	if err := o.steps.decode(b, &track); err != nil {
+		return nil, parseError("steps", 0, err)
 	}
+	log.field("track steps", offset, stepSymbols(track.steps))
 	return &track, nil
 }
 
-func decodeSteps(r io.Reader) (Steps, error) {
-	var steps Steps
-	stepsAsBytes, err := readBytes(r, stepsLength)
-	if err != nil {
-		return steps, fmt.Errorf("parse steps: %v", err)
-	}
-	for i, v := range stepsAsBytes {
-		steps[i] = (v == 1)
+// byteToBool accepts only 0 and 1 so that no information is lost when the
+// steps are encoded again.
+func byteToBool(b byte) (bool, error) {
+	switch b {
+	case 0:
+		return false, nil
+	case 1:
+		return true, nil
 	}
-	return steps, nil
+	return false, ErrInvalidStepValue
 }
 
 // readBytes reads exactly n bytes from r into a new slice
 // The error is EOF only if no bytes were read.
 // If an EOF happens after reading some but not all the bytes,
 // ReadFull returns ErrUnexpectedEOF.
-func readBytes(r io.Reader, n uint8) ([]byte, error) {
+func readBytes(r io.Reader, n int) ([]byte, error) {
 	buf := make([]byte, n)
 	if _, err := io.ReadFull(r, buf); err != nil {
 		return nil, err
@@ -2,8 +2,12 @@ package drum
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"path"
+	"strings"
 	"testing"
 )
 
@@ -77,11 +81,70 @@ Tempo: 999
 
 func TestInvalidFileFormat(t *testing.T) {
 	p, err := decode(bytes.NewBufferString("Invalid Type Header"))
-	if err != ErrUnsupportedFileFormat {
-		t.Logf("expected error '%s' but got '%s'", ErrUnsupportedFileFormat, err)
+	if !errors.Is(err, ErrUnsupportedFileFormat) {
+		t.Errorf("expected error '%s' but got '%s'", ErrUnsupportedFileFormat, err)
 	}
 	if p != nil {
-		t.Logf("patter should be nil but was: '%v'", p)
+		t.Errorf("patter should be nil but was: '%v'", p)
+	}
+}
+
+func TestInvalidStepValue(t *testing.T) {
+	raw, err := ioutil.ReadFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[len(raw)-1] = 0x7f
+	_, err = decode(bytes.NewReader(raw))
+	if err == nil || !strings.Contains(err.Error(), ErrInvalidStepValue.Error()) {
+		t.Errorf("expected error '%s' but got '%v'", ErrInvalidStepValue, err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	raw, err := ioutil.ReadFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the steps of the first track start behind the header and its name
+	const headerLength = typeHeaderLength + 8 + maxVersionLength + 4
+	firstSteps := headerLength + trackHeaderLength + int(raw[headerLength+4])
+	invalidStep := append([]byte(nil), raw...)
+	invalidStep[firstSteps+3] = 2
+	bigPayload := append([]byte(nil), raw...)
+	bigPayload[typeHeaderLength+5] = 0x10
+	shortPayload := append([]byte(nil), raw...)
+	shortPayload[typeHeaderLength+7] -= 3 // ends within the steps of the last track
+	testCases := []struct {
+		name  string
+		data  []byte
+		opts  []DecodeOption
+		class error
+		err   string
+	}{
+		{"empty", nil, nil, ErrTruncatedPayload, "parse type header: truncated payload: EOF"},
+		{"other format", []byte("FORMAT00000000"), nil, ErrUnsupportedFileFormat, "parse type header: unsupported file format"},
+		{"no payload size", raw[:10], nil, ErrTruncatedPayload, "parse payload size: truncated payload: unexpected EOF"},
+		{"large payload", bigPayload, nil, ErrPayloadTooLarge, "parse payload size: payload too large (limit exceeded): 1048773 bytes exceed 1048576"},
+		{"truncated version", raw[:30], nil, ErrTruncatedPayload, "parse version: truncated payload: unexpected EOF"},
+		{"truncated tempo", raw[:headerLength-2], nil, ErrTruncatedPayload, "parse tempo: truncated payload: unexpected EOF"},
+		{"truncated name", raw[:firstSteps-1], nil, ErrTruncatedPayload, "parse track name (track 1): truncated payload: unexpected EOF"},
+		{"truncated steps", raw[:firstSteps+4], nil, ErrTruncatedPayload, "parse steps (track 1): truncated payload: unexpected EOF"},
+		{"short payload size", shortPayload, nil, ErrPayloadSizeMismatch, "parse track (track 6): payload size mismatch: payload declared 194 bytes, track boundary at 169"},
+		{"invalid step", invalidStep, nil, ErrInvalidStepValue, "parse steps (track 1): step 4: invalid step value"},
+		{"long name", raw, []DecodeOption{WithLimits(Limits{MaxNameLength: 4})}, ErrNameTooLong, "parse track name length (track 2): track name too long (limit exceeded): 5 bytes exceed 4"},
+		{"many tracks", raw, []DecodeOption{WithLimits(Limits{MaxTracks: 3})}, ErrTooManyTracks, "parse track (track 4): too many tracks (limit exceeded): more than 3"},
+		{"no checksum", raw, []DecodeOption{WithChecksum()}, ErrTruncatedPayload, "parse checksum: truncated payload: EOF"},
+	}
+	for _, testCase := range testCases {
+		_, err := Decode(bytes.NewReader(testCase.data), testCase.opts...)
+		_, bytesErr := DecodeBytes(testCase.data, testCase.opts...)
+		for _, err := range []error{err, bytesErr} {
+			var de *DecodeError
+			if !errors.As(err, &de) || !errors.Is(err, testCase.class) || err.Error() != testCase.err {
+				t.Errorf("%s: Expected a DecodeError '%v' but got '%v'", testCase.name, testCase.err, err)
+			}
+		}
 	}
 }
 
@@ -104,3 +167,63 @@ func TestCropToString(t *testing.T) {
 	}
 
 }
+
+func TestNewPattern(t *testing.T) {
+	kick, err := NewTrack(1, "kick", Steps{true, false, false, false, true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPattern("0.909", 98.4, kick)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "Saved with HW Version: 0.909\nTempo: 98.4\n(1) kick\t|x---|x---|----|----|\n"
+	if got := p.String(); got != exp {
+		t.Errorf("Expected '%v' but got '%v'", exp, got)
+	}
+	if p.Version() != "0.909" || p.Tempo() != 98.4 || len(p.Tracks()) != 1 {
+		t.Errorf("unexpected accessor values: %v %v %v", p.Version(), p.Tempo(), p.Tracks())
+	}
+	if tr := p.Tracks()[0]; tr.ID() != 1 || tr.Name() != "kick" || !tr.Steps()[4] {
+		t.Errorf("unexpected track values: %v %v %v", tr.ID(), tr.Name(), tr.Steps())
+	}
+	if tr := p.Tracks()[0]; tr.SetStep(4, false) != nil || tr.Steps()[4] || tr.SetStep(stepsLength, true) != ErrStepOutOfRange {
+		t.Errorf("unexpected steps after SetStep: %v", tr.Steps())
+	}
+
+	invalid := []struct {
+		version string
+		tempo   float32
+	}{
+		{strings.Repeat("x", 33), 120},
+		{"a\x00b", 120},
+		{"0.909", 0},
+		{"0.909", -1},
+	}
+	for _, testCase := range invalid {
+		if _, err := NewPattern(testCase.version, testCase.tempo); err == nil {
+			t.Errorf("expected error for %q %v", testCase.version, testCase.tempo)
+		}
+	}
+	if _, err := NewTrack(1, strings.Repeat("x", 256), Steps{}); err != ErrNameTooLong {
+		t.Errorf("expected error '%s' but got '%v'", ErrNameTooLong, err)
+	}
+}
+
+func TestDecodeDir(t *testing.T) {
+	patterns, err := DecodeDir("fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patterns) != 5 || patterns["pattern_3.splice"].Tempo() != 118 {
+		t.Errorf("unexpected patterns %v", patterns)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := DecodeDirContext(ctx, "fixtures"); err != context.Canceled {
+		t.Errorf("Expected error '%v' but got '%v'", context.Canceled, err)
+	}
+	if _, err := DecodeDir("missing"); err == nil {
+		t.Error("Expected error for missing directory")
+	}
+}
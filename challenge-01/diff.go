@@ -0,0 +1,116 @@
+package drum
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// Difference is a single difference between two patterns found by Diff.
+type Difference struct {
+	Track string `json:"track,omitempty"` // "(id) name" of the track, empty for pattern fields
+	Field string `json:"field"`           // name of the differing field
+	From  string `json:"from"`            // empty when a track was added
+	To    string `json:"to"`              // empty when a track was removed
+}
+
+func (d Difference) String() string {
+	field := d.Field
+	if d.Track != "" {
+		field = "track " + d.Track + " " + d.Field
+	}
+	switch {
+	case d.Field == "track" && d.From == "":
+		return fmt.Sprintf("track %s added", d.Track)
+	case d.Field == "track" && d.To == "":
+		return fmt.Sprintf("track %s removed", d.Track)
+	}
+	return fmt.Sprintf("%s: %s -> %s", field, d.From, d.To)
+}
+
+// Diff returns the differences from a to b. Tracks are matched by id in
+// the order of the patterns. Data compared by Equal is diffed, so the
+// result is empty when a.Equal(b).
+func Diff(a, b *Pattern) []Difference {
+	var diffs []Difference
+	add := func(track, field string, from, to interface{}) {
+		f, t := fmt.Sprint(from), fmt.Sprint(to)
+		if f != t {
+			diffs = append(diffs, Difference{track, field, f, t})
+		}
+	}
+	add("", "version", a.version, b.version)
+	if math.Abs(float64(a.tempo)-float64(b.tempo)) > DefaultTempoTolerance {
+		add("", "tempo", a.tempo, b.tempo)
+	}
+	add("", "swing", a.swing, b.swing)
+	add("", "time signature", a.TimeSignature(), b.TimeSignature())
+	add("", "tempo map", a.tempoMap, b.tempoMap)
+
+	matched := make([]bool, len(b.tracks))
+	for _, ta := range a.tracks {
+		j := -1
+		for i, tb := range b.tracks {
+			if !matched[i] && tb.id == ta.id {
+				j = i
+				break
+			}
+		}
+		label := fmt.Sprintf("(%v) %v", ta.id, ta.name)
+		if j < 0 {
+			diffs = append(diffs, Difference{Track: label, Field: "track", From: label})
+			continue
+		}
+		matched[j] = true
+		tb := b.tracks[j]
+		add(label, "name", ta.name, tb.name)
+		add(label, "steps", stepsString(ta.steps), stepsString(tb.steps))
+		add(label, "velocity", velocities(ta), velocities(tb))
+		add(label, "timing", ta.timing, tb.timing)
+		add(label, "probability", probabilities(ta), probabilities(tb))
+		add(label, "conditions", conditions(ta), conditions(tb))
+		add(label, "ratchet", ta.ratchet, tb.ratchet)
+		add(label, "length", ta.length, tb.length)
+		add(label, "volume", ta.Volume(), tb.Volume())
+		add(label, "pan", ta.pan, tb.pan)
+		add(label, "display", ta.display, tb.display)
+	}
+	for i, tb := range b.tracks {
+		if !matched[i] {
+			label := fmt.Sprintf("(%v) %v", tb.id, tb.name)
+			diffs = append(diffs, Difference{Track: label, Field: "track", To: label})
+		}
+	}
+	return diffs
+}
+
+// stepsString returns the steps in the printout format.
+func stepsString(s Steps) string {
+	var buf bytes.Buffer
+	defaultFormatter.appendSteps(&buf, s[:], blockSize)
+	return buf.String()
+}
+
+func probabilities(t *Track) []uint8 {
+	p := make([]uint8, stepsLength)
+	for i := range p {
+		p[i] = t.Probability(i)
+	}
+	return p
+}
+
+func conditions(t *Track) []Condition {
+	c := make([]Condition, stepsLength)
+	for i := range c {
+		c[i] = t.Condition(i)
+	}
+	return c
+}
+
+func velocities(t *Track) []uint8 {
+	v := make([]uint8, stepsLength)
+	for i := range v {
+		v[i] = t.Velocity(i)
+	}
+	return v
+}
@@ -0,0 +1,53 @@
+package drum
+
+import (
+	"fmt"
+	"path"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a, err := DecodeFile(path.Join("fixtures", "pattern_5.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := Diff(a, a.Clone()); len(d) != 0 {
+		t.Errorf("expected no differences but got %v", d)
+	}
+	b := a.Clone()
+	b.SetTempo(120)
+	b.tracks[0].steps[1] = true
+	b.tracks[1].SetPan(-10)
+	b.tracks = append(b.tracks[:0:0], b.tracks[0], &Track{id: 7, name: "snare"})
+	exp := "[tempo: 999 -> 120 track (1) Kick steps: |x---|----|x---|----| -> |xx--|----|x---|----| track (2) HiHat removed track (7) snare added]"
+	if got := fmt.Sprint(Diff(a, b)); got != exp {
+		t.Errorf("Expected '%v' but got '%v'", exp, got)
+	}
+}
+
+func TestPatternJSON(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetSwing(20)
+	p.tracks[0].SetVelocity(0, 64)
+	p.tracks[1].SetTiming(2, -0.25)
+	p.tracks[2].SetVolume(50)
+	p.tracks[2].SetPan(12)
+	p.tracks[3].SetDisplay(Display{Color: "#ff0000"})
+	data, err := p.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Pattern
+	if err := decoded.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if d := Diff(p, &decoded); len(d) != 0 {
+		t.Errorf("expected no differences but got %v in %s", d, data)
+	}
+	if err := decoded.UnmarshalJSON([]byte(`{"version":"x","tempo":120,"tracks":[{"id":1,"steps":"x---"}]}`)); err == nil {
+		t.Error("expected error for short steps")
+	}
+}
@@ -0,0 +1,166 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var chunkDisplay = chunkID{'D', 'I', 'S', 'P'}
+
+// Display holds optional metadata used by front-ends to show a track
+// consistently. The zero value means no preference.
+type Display struct {
+	Color string // hex color like "#ff8800"
+	Icon  string // icon name, for example "kick"
+}
+
+// Display returns the display metadata of the track.
+func (t *Track) Display() Display {
+	return t.display
+}
+
+// SetDisplay sets the display metadata of the track. An error is returned
+// when the color is neither empty nor a "#rrggbb" hex value.
+func (t *Track) SetDisplay(d Display) error {
+	if err := validateColor(d.Color); err != nil {
+		return err
+	}
+	t.display = d
+	return nil
+}
+
+// iconSymbols are the symbols of common icon names shown by terminals.
+var iconSymbols = map[string]rune{
+	"kick": '●', "snare": '◆', "clap": '✱', "rim": '◇',
+	"hat": '△', "hihat": '△', "cymbal": '○', "crash": '○', "ride": '○',
+	"tom": '■', "cowbell": '▼', "perc": '▲', "shaker": '∴',
+}
+
+// Symbol returns the symbol terminals show for the icon: a shape for common
+// icons like "kick" or "hat", the upper case first letter of other icons and
+// 0 without icon.
+func (d Display) Symbol() rune {
+	if d.Icon == "" {
+		return 0
+	}
+	if r, ok := iconSymbols[strings.ToLower(d.Icon)]; ok {
+		return r
+	}
+	r, _ := utf8.DecodeRuneInString(d.Icon)
+	return unicode.ToUpper(r)
+}
+
+func validateColor(c string) error {
+	if c == "" {
+		return nil
+	}
+	if len(c) != 7 || c[0] != '#' {
+		return fmt.Errorf("invalid color %q", c)
+	}
+	for _, r := range c[1:] {
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F') {
+			return fmt.Errorf("invalid color %q", c)
+		}
+	}
+	return nil
+}
+
+// decodeDisplayChunk decodes the display metadata. Each entry is stored as
+// |Track index (2 bytes)|Color length (1 byte)|Color|Icon length (1 byte)|Icon|
+// where the index is the position of the track within the pattern.
+func decodeDisplayChunk(data []byte, p *Pattern) error {
+	r := bytes.NewReader(data)
+	for r.Len() > 0 {
+		var index uint16
+		if err := binary.Read(r, binary.LittleEndian, &index); err != nil {
+			return fmt.Errorf("parse track index: %v", err)
+		}
+		if int(index) >= len(p.tracks) {
+			return fmt.Errorf("track index %d out of range", index)
+		}
+		color, err := readShortString(r)
+		if err != nil {
+			return fmt.Errorf("parse color: %v", err)
+		}
+		icon, err := readShortString(r)
+		if err != nil {
+			return fmt.Errorf("parse icon: %v", err)
+		}
+		if err := p.tracks[index].SetDisplay(Display{color, icon}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeDisplayChunk(p *Pattern) []byte {
+	var buf bytes.Buffer
+	for i, t := range p.tracks {
+		if t.display == (Display{}) {
+			continue
+		}
+		buf.Write(binary.LittleEndian.AppendUint16(nil, uint16(i)))
+		writeShortString(&buf, t.display.Color)
+		writeShortString(&buf, t.display.Icon)
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+func clearDisplay(p *Pattern) {
+	for _, t := range p.tracks {
+		t.display = Display{}
+	}
+}
+
+// readShortString reads a string prefixed by its length in one byte.
+func readShortString(r io.Reader) (string, error) {
+	var n uint8
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return "", err
+	}
+	b, err := readBytes(r, int(n))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// writeShortString writes s prefixed by its length in one byte. Longer
+// strings are cut at 255 bytes.
+func writeShortString(buf *bytes.Buffer, s string) {
+	if len(s) > 255 {
+		s = s[:255]
+	}
+	buf.WriteByte(uint8(len(s)))
+	buf.WriteString(s)
+}
+
+// joinShortStrings returns the strings each prefixed by its length in one
+// byte. Tracks keep lists of strings in this form so that they stay
+// comparable. The strings must not be longer than 255 bytes.
+func joinShortStrings(list []string) string {
+	var buf bytes.Buffer
+	for _, s := range list {
+		writeShortString(&buf, s)
+	}
+	return buf.String()
+}
+
+// splitShortStrings returns the strings joined by joinShortStrings.
+func splitShortStrings(s string) []string {
+	var list []string
+	for len(s) > 0 {
+		n := int(s[0]) + 1
+		list = append(list, s[1:n])
+		s = s[n:]
+	}
+	return list
+}
@@ -2,6 +2,12 @@
 // See golang-challenge.com/go-challenge1/ for more information
 package drum
 
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
 const (
 	maxVersionLength = 32
 	stepsLength      = 16
@@ -10,18 +16,132 @@ const (
 // Pattern is the high level representation of the
 // drum pattern contained in a .splice file.
 type Pattern struct {
-	version string
-	tempo   float32
-	tracks  []*Track
+	version  string
+	tempo    float32
+	tracks   []*Track
+	swing    uint8 // swing amount in percent
+	meta     Metadata
+	labels   []Label
+	timeSig  TimeSignature // zero for 4/4
+	tempoMap TempoMap      // tempo changes after step 0, see SetTempoMap
+
+	extraBars uint8   // bars after the first, see AddBar
+	barOrder  []uint8 // nil to play the bars in sequence
+
+	decodedTempo   float32 // tempo as read when it was corrected
+	tempoCorrected bool    // see WithTempoCorrection
+
+	rawVersion []byte     // version field as read, including the padding
+	chunks     []rawChunk // extension chunks unknown to this package
+	rawExtra   []byte     // unrecognized data behind the payload
+}
+
+// NewPattern returns a pattern with the given tracks. The version must fit
+// into the 32 bytes of the file format and the tempo must be within
+// MinTempo and MaxTempo.
+func NewPattern(version string, tempo float32, tracks ...*Track) (*Pattern, error) {
+	if len(version) > maxVersionLength {
+		return nil, ErrVersionTooLong
+	}
+	if strings.IndexByte(version, endOfString) >= 0 {
+		return nil, fmt.Errorf("invalid version %q", version)
+	}
+	if !validTempo(tempo) {
+		return nil, ErrInvalidTempo
+	}
+	for i, t := range tracks {
+		if t == nil {
+			return nil, fmt.Errorf("track %d is nil", i)
+		}
+	}
+	return &Pattern{version: version, tempo: tempo, tracks: tracks}, nil
+}
+
+// Version returns the hardware version the pattern was saved with.
+func (p *Pattern) Version() string {
+	return p.version
+}
+
+// Tempo returns the tempo in beats per minute.
+func (p *Pattern) Tempo() float32 {
+	return p.tempo
+}
+
+// SetTempo sets the tempo in beats per minute. ErrInvalidTempo is returned
+// for a value outside of MinTempo and MaxTempo.
+func (p *Pattern) SetTempo(bpm float32) error {
+	if !validTempo(bpm) {
+		return ErrInvalidTempo
+	}
+	p.tempo, p.tempoCorrected = bpm, false
+	return nil
+}
+
+// Tracks returns the tracks of the pattern in file order. The returned slice
+// is a copy but the tracks are shared with the pattern.
+func (p *Pattern) Tracks() []*Track {
+	return append([]*Track(nil), p.tracks...)
 }
 
 // A Track represents an audio sample loaded by the drum machine,
 // allowing the programmer to schedule the playback of the sound.
 // The scheduling of the playback is done using the concept of steps.
 type Track struct {
-	id    uint32
-	name  string
-	steps Steps
+	id       uint32
+	name     string
+	steps    Steps
+	bars     [MaxBars - 1]Steps // steps of the bars after the first
+	display  Display
+	role     Role               // RoleAuto to infer it from the name
+	aliases  string             // previous names, see joinShortStrings
+	labels   string             // names and colors, see joinShortStrings
+	velocity [stepsLength]uint8 // 0 for MaxVelocity
+	timing   [stepsLength]int8  // micro timing offset in ticks
+	muted    bool
+	solo     bool
+
+	attenuation uint8 // MaxVolume minus the volume
+	pan         int8
+
+	probability [stepsLength]uint8 // 0 for 100 percent
+	condition   [stepsLength]uint8 // packed Condition
+	ratchet     [stepsLength]uint8 // triggers per step, 0 for 1
+	length      uint8              // steps per loop, 0 to follow the bar
+	choke       uint8              // choke group, 0 for none
+	nudge       int8               // timing offset of all steps in ticks
+}
+
+// NewTrack returns a track with the given steps. The name must not be longer
+// than 255 bytes.
+func NewTrack(id uint32, name string, steps Steps) (*Track, error) {
+	if len(name) > math.MaxUint8 {
+		return nil, ErrNameTooLong
+	}
+	return &Track{id: id, name: name, steps: steps}, nil
+}
+
+// ID returns the id of the track.
+func (t *Track) ID() uint32 {
+	return t.id
+}
+
+// Name returns the name of the track.
+func (t *Track) Name() string {
+	return t.name
+}
+
+// Steps returns a copy of the steps of the track.
+func (t *Track) Steps() Steps {
+	return t.steps
+}
+
+// SetStep turns the step on or off.
+func (t *Track) SetStep(step int, enabled bool) error {
+	if step < 0 || step >= stepsLength {
+		return ErrStepOutOfRange
+	}
+	t.steps[step] = enabled
+	return nil
 }
 
 // Steps are one of the parts of the measure that are being programmed
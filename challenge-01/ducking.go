@@ -0,0 +1,102 @@
+package drum
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Ducking lowers the volume of tracks while a trigger track hits, like a
+// compressor with a sidechain from the kick, see WithDucking.
+type Ducking struct {
+	// Trigger is the role of the tracks whose steps duck, RoleKick when
+	// RoleAuto.
+	Trigger Role
+	// Tracks are the names of the ducked tracks, compared like in
+	// Pattern.TrackByName. All tracks not of the trigger role are ducked
+	// when empty.
+	Tracks []string
+	// Input is external audio ducked along with the tracks, like a bass
+	// line, mixed in from the start of the rendering. It must have the
+	// sample rate of the kit.
+	Input *Sample
+	// Depth is the gain reduction from 0 for none to 1 for silence.
+	Depth float64
+	// Attack is the time the volume takes to go down after a hit, Release
+	// the time it takes to come back up.
+	Attack, Release time.Duration
+}
+
+// WithDucking makes the rendering duck tracks on every step of the trigger
+// tracks. The release of the hits near the end continues at the start, so
+// that the rendering still loops.
+func WithDucking(d Ducking) RenderOption {
+	return func(o *renderOptions) {
+		o.ducking = &d
+	}
+}
+
+func (d *Ducking) validate(rate int) error {
+	if d.Depth < 0 || d.Depth > 1 || math.IsNaN(d.Depth) {
+		return fmt.Errorf("render: invalid ducking depth %v", d.Depth)
+	}
+	if d.Attack < 0 || d.Release < 0 {
+		return fmt.Errorf("render: invalid ducking attack %v or release %v", d.Attack, d.Release)
+	}
+	if d.Input != nil && d.Input.Rate != rate {
+		return ErrSampleRate
+	}
+	return nil
+}
+
+func (d *Ducking) trigger() Role {
+	if d.Trigger == RoleAuto {
+		return RoleKick
+	}
+	return d.Trigger
+}
+
+// ducks reports whether the track is ducked.
+func (d *Ducking) ducks(t *Track) bool {
+	if len(d.Tracks) == 0 {
+		return t.Role() != d.trigger()
+	}
+	for _, name := range d.Tracks {
+		if normalizeName(name) == normalizeName(t.name) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply mixes the ducked bus into out with the gain lowered at the frames
+// the trigger tracks hit.
+func (d *Ducking) apply(out, bus *Sample, hits []int) {
+	if d.Input != nil {
+		for i := 0; i < d.Input.Frames() && i < bus.Frames(); i++ {
+			bus.Left[i] += d.Input.Left[i]
+			bus.Right[i] += d.Input.Right[i]
+		}
+	}
+	n := out.Frames()
+	attack := int(d.Attack.Seconds() * float64(out.Rate))
+	release := int(d.Release.Seconds() * float64(out.Rate))
+	// env is the amount of ducking from 0 to 1, the highest of all hits
+	env := make([]float64, n)
+	for _, h := range hits {
+		for i := 0; i < attack+release && i < n; i++ {
+			v := 1 - float64(i-attack)/float64(release+1)
+			if i < attack {
+				v = float64(i+1) / float64(attack)
+			}
+			if f := (h + i) % n; v > env[f] {
+				env[f] = v
+			}
+		}
+	}
+	for f := 0; f < n; f++ {
+		g := float32(1 - d.Depth*env[f])
+		out.Left[f] += bus.Left[f] * g
+		out.Right[f] += bus.Right[f] * g
+	}
+}
@@ -0,0 +1,69 @@
+package drum
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDucking(t *testing.T) {
+	// 120 BPM at 8000 frames per second gives 1000 frames per step
+	p := &Pattern{tempo: 120, tracks: []*Track{
+		{name: "kick", steps: Steps{4: true, 15: true}},
+		{name: "pad", steps: Steps{true}},
+	}}
+	ones := &Sample{Rate: 8000, Left: make([]float32, 16000), Right: make([]float32, 16000)}
+	for i := range ones.Left {
+		ones.Left[i], ones.Right[i] = 1, 1
+	}
+	kit := NewKit("test")
+	kit.Add("kick", &Sample{Rate: 8000, Left: []float32{0}, Right: []float32{0}})
+	kit.Add("pad", ones)
+	duck := Ducking{Depth: 0.5, Attack: 10 * time.Millisecond, Release: 250 * time.Millisecond}
+	out, err := Render(p, kit, 1, WithDucking(duck))
+	if err != nil {
+		t.Fatal(err)
+	}
+	specs := map[int]float32{
+		3999:  1,    // before the kick
+		4039:  0.75, // half of the attack
+		4079:  0.5,  // end of the attack
+		4080:  0.5,
+		6100:  1,                              // after the release
+		15079: 0.5,                            // the last kick
+		500:   1 - 0.5*(1-float32(1420)/2001), // the release wraps
+	}
+	center := float32(math.Cos(math.Pi / 4 * 64 / 63.5))
+	for f, exp := range specs {
+		if got := out.Left[f]; !near(got, exp*center) {
+			t.Errorf("frame %d: expected %v but got %v", f, exp, got)
+		}
+	}
+
+	duck.Tracks = []string{"hat"}
+	if out, err = Render(p, kit, 1, WithDucking(duck)); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.Left[4079]; got != center {
+		t.Errorf("Expected the pad not ducked but got %v", got)
+	}
+
+	// external input instead of the pad
+	p.tracks[1].steps = Steps{}
+	duck.Tracks, duck.Input = []string{"pad"}, ones
+	if out, err = Render(p, kit, 1, WithDucking(duck)); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.Left[4079]; !near(got, 0.5) {
+		t.Errorf("Expected the input ducked but got %v", got)
+	}
+
+	duck.Input = &Sample{Rate: 44100}
+	if _, err := Render(p, kit, 1, WithDucking(duck)); !errors.Is(err, ErrSampleRate) {
+		t.Errorf("Expected %v but got %v", ErrSampleRate, err)
+	}
+	if _, err := Render(p, kit, 1, WithDucking(Ducking{Depth: 2})); err == nil {
+		t.Error("Expected an error for the depth")
+	}
+}
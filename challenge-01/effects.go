@@ -0,0 +1,152 @@
+package drum
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// limiterLookahead is the time in seconds the limiter lowers the gain
+	// ahead of a peak.
+	limiterLookahead = 0.0015
+	// limiterRelease is the time in seconds the limiter takes to recover
+	// the full gain.
+	limiterRelease = 0.05
+)
+
+// TrackEffects are the effects applied to a track in the rendering, in the
+// order gain, filters and saturation, see WithTrackEffects.
+type TrackEffects struct {
+	// Gain in dB on top of the track volume.
+	Gain float64
+	// LowPass and HighPass are the cutoff frequencies in Hz of second order
+	// filters removing the frequencies above and below, 0 for none.
+	LowPass, HighPass float64
+	// Drive is the amount of tanh saturation from 0 for none to 1. Peaks of
+	// full scale stay at full scale.
+	Drive float64
+}
+
+// WithTrackEffects applies the effects to the tracks of the name, compared
+// like in Pattern.TrackByName.
+func WithTrackEffects(track string, fx TrackEffects) RenderOption {
+	return func(o *renderOptions) {
+		if o.effects == nil {
+			o.effects = make(map[string]TrackEffects)
+		}
+		o.effects[normalizeName(track)] = fx
+	}
+}
+
+// WithLimiter applies a brickwall limiter to the mix so that no frame
+// exceeds the ceiling in dBFS, like -0.3. The gain is lowered shortly ahead
+// of peaks and recovers within 50ms, so that overlapping tracks do not clip
+// the exported WAV.
+func WithLimiter(ceiling float64) RenderOption {
+	return func(o *renderOptions) {
+		o.limiter = &ceiling
+	}
+}
+
+func (fx TrackEffects) validate(rate int) error {
+	nyquist := float64(rate) / 2
+	for _, f := range []float64{fx.LowPass, fx.HighPass} {
+		if f < 0 || f >= nyquist || math.IsNaN(f) {
+			return fmt.Errorf("render: invalid filter frequency %vHz at %dHz", f, rate)
+		}
+	}
+	if fx.Drive < 0 || fx.Drive > 1 || math.IsNaN(fx.Drive) {
+		return fmt.Errorf("render: invalid drive %v", fx.Drive)
+	}
+	if math.IsNaN(fx.Gain) || math.IsInf(fx.Gain, 0) {
+		return fmt.Errorf("render: invalid gain %v", fx.Gain)
+	}
+	return nil
+}
+
+// apply processes both channels of the sample in place.
+func (fx TrackEffects) apply(s *Sample) {
+	gain := float32(math.Pow(10, fx.Gain/20))
+	var filters []biquad
+	if fx.HighPass != 0 {
+		filters = append(filters, highPass(fx.HighPass, s.Rate))
+	}
+	if fx.LowPass != 0 {
+		filters = append(filters, lowPass(fx.LowPass, s.Rate))
+	}
+	// tanh(k) keeps full scale at full scale
+	k := 1 + 9*fx.Drive
+	norm := math.Tanh(k)
+	for _, ch := range [][]float32{s.Left, s.Right} {
+		state := make([]biquadState, len(filters))
+		for i, x := range ch {
+			v := float64(x * gain)
+			for n, f := range filters {
+				v = state[n].process(f, v)
+			}
+			if fx.Drive != 0 {
+				v = math.Tanh(k*v) / norm
+			}
+			ch[i] = float32(v)
+		}
+	}
+}
+
+// biquad holds the normalized coefficients of a second order filter.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+}
+
+type biquadState struct {
+	x1, x2, y1, y2 float64
+}
+
+func (s *biquadState) process(f biquad, x float64) float64 {
+	y := f.b0*x + f.b1*s.x1 + f.b2*s.x2 - f.a1*s.y1 - f.a2*s.y2
+	s.x1, s.x2, s.y1, s.y2 = x, s.x1, y, s.y1
+	return y
+}
+
+// lowPass and highPass return Butterworth filters of the cookbook by Robert
+// Bristow-Johnson.
+func lowPass(freq float64, rate int) biquad {
+	w := 2 * math.Pi * freq / float64(rate)
+	cos, alpha := math.Cos(w), math.Sin(w)/math.Sqrt2
+	a0 := 1 + alpha
+	return biquad{(1 - cos) / 2 / a0, (1 - cos) / a0, (1 - cos) / 2 / a0, -2 * cos / a0, (1 - alpha) / a0}
+}
+
+func highPass(freq float64, rate int) biquad {
+	w := 2 * math.Pi * freq / float64(rate)
+	cos, alpha := math.Cos(w), math.Sin(w)/math.Sqrt2
+	a0 := 1 + alpha
+	return biquad{(1 + cos) / 2 / a0, -(1 + cos) / a0, (1 + cos) / 2 / a0, -2 * cos / a0, (1 - alpha) / a0}
+}
+
+// limit lowers the gain of the sample wherever a frame exceeds the ceiling
+// in dBFS.
+func limit(s *Sample, ceiling float64) {
+	c := math.Pow(10, ceiling/20)
+	n := s.Frames()
+	gain := make([]float64, n)
+	for f := range gain {
+		gain[f] = 1
+		if peak := math.Max(math.Abs(float64(s.Left[f])), math.Abs(float64(s.Right[f]))); peak > c {
+			gain[f] = c / peak
+		}
+	}
+	// ramp down ahead of peaks and up after them, never above the gain
+	// needed by a frame
+	attack := 1 / math.Max(1, limiterLookahead*float64(s.Rate))
+	for f := n - 2; f >= 0; f-- {
+		gain[f] = math.Min(gain[f], gain[f+1]+attack)
+	}
+	release := 1 / math.Max(1, limiterRelease*float64(s.Rate))
+	for f := 1; f < n; f++ {
+		gain[f] = math.Min(gain[f], gain[f-1]+release)
+	}
+	for f, g := range gain {
+		s.Left[f] *= float32(g)
+		s.Right[f] *= float32(g)
+	}
+}
@@ -0,0 +1,94 @@
+package drum
+
+import (
+	"math"
+	"testing"
+)
+
+// sine returns a second of a sine wave of the frequency at 8000 Hz.
+func sine(freq float64) *Sample {
+	s := &Sample{Rate: 8000, Left: make([]float32, 8000), Right: make([]float32, 8000)}
+	for i := range s.Left {
+		v := float32(math.Sin(2 * math.Pi * freq * float64(i) / 8000))
+		s.Left[i], s.Right[i] = v, v
+	}
+	return s
+}
+
+// peak returns the highest level of the left channel after the first 1000
+// frames, when the filters settled.
+func peak(s *Sample) float64 {
+	var max float64
+	for _, v := range s.Left[1000:] {
+		max = math.Max(max, math.Abs(float64(v)))
+	}
+	return max
+}
+
+func TestTrackEffects(t *testing.T) {
+	specs := map[string]struct {
+		fx       TrackEffects
+		freq     float64
+		min, max float64
+	}{
+		"gain":             {TrackEffects{Gain: -6}, 100, 0.49, 0.51},
+		"low pass passes":  {TrackEffects{LowPass: 1000}, 50, 0.99, 1.01},
+		"low pass cuts":    {TrackEffects{LowPass: 200}, 2000, 0, 0.02},
+		"high pass cuts":   {TrackEffects{HighPass: 1000}, 50, 0, 0.01},
+		"high pass passes": {TrackEffects{HighPass: 100}, 2000, 0.99, 1.01},
+		"drive":            {TrackEffects{Drive: 1, Gain: -20}, 100, 0.7, 0.8},
+	}
+	for name, spec := range specs {
+		s := sine(spec.freq)
+		spec.fx.apply(s)
+		if got := peak(s); got < spec.min || got > spec.max {
+			t.Errorf("%s: expected a peak within %v and %v but got %v", name, spec.min, spec.max, got)
+		}
+	}
+	if err := (TrackEffects{LowPass: 4000}).validate(8000); err == nil {
+		t.Error("Expected an error for a cutoff at the Nyquist frequency")
+	}
+	if err := (TrackEffects{Drive: -1}).validate(8000); err == nil {
+		t.Error("Expected an error for the drive")
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	// two tracks at full scale on the same step clip without the limiter
+	p := &Pattern{tempo: 120, tracks: []*Track{
+		{name: "kick", steps: Steps{true}},
+		{name: "snare", steps: Steps{true}},
+	}}
+	kit := NewKit("test")
+	kit.Add("kick", sine(100))
+	kit.Add("snare", sine(100))
+	out, err := Render(p, kit, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := peak(out); got < 1 {
+		t.Fatalf("Expected the mix to clip but got a peak of %v", got)
+	}
+	if out, err = Render(p, kit, 1, WithLimiter(-1)); err != nil {
+		t.Fatal(err)
+	}
+	ceiling := math.Pow(10, -1.0/20)
+	if got := peak(out); got > ceiling+1e-6 || got < ceiling-0.05 {
+		t.Errorf("Expected a peak just below %v but got %v", ceiling, got)
+	}
+
+	// the effects of a track apply before the mix
+	if out, err = Render(p, kit, 1, WithTrackEffects("Snare", TrackEffects{Gain: math.Inf(-1)})); err == nil {
+		t.Error("Expected an error for the gain")
+	}
+	if out, err = Render(p, kit, 1, WithTrackEffects("Snare", TrackEffects{Gain: -200})); err != nil {
+		t.Fatal(err)
+	}
+	single, err := Render(&Pattern{tempo: 120, tracks: p.tracks[:1]}, kit, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := peak(out), peak(single); math.Abs(got-exp) > 1e-6 {
+		t.Errorf("Expected the snare silenced with a peak of %v but got %v", exp, got)
+	}
+}
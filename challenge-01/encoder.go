@@ -0,0 +1,132 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+
+	"github.com/alpe/go-challenge/challenge-01/splice"
+)
+
+var (
+	// ErrVersionTooLong is returned when the version does not fit into the
+	// fixed size version field.
+	ErrVersionTooLong = errors.New("version too long")
+	// ErrNameTooLong is returned when a track name exceeds 255 bytes or, when
+	// decoding, the MaxNameLength of the Limits.
+	ErrNameTooLong = errors.New("track name too long")
+)
+
+// EncodeOption configures the encoder.
+type EncodeOption func(*encodeOptions)
+
+type encodeOptions struct {
+	checksum bool
+	padding  payloadPadding // see WithPayloadPadding
+	save     []SaveOption   // see WithSaveOptions
+}
+
+// WithChecksumTrailer makes the encoder write a CRC32 (IEEE) of the payload
+// directly after it. Such files must be decoded with the WithChecksum option.
+func WithChecksumTrailer() EncodeOption {
+	return func(o *encodeOptions) {
+		o.checksum = true
+	}
+}
+
+// WithSaveOptions sets how EncodeFile writes the file, for example
+// WithBackups to keep the previous versions.
+func WithSaveOptions(opts ...SaveOption) EncodeOption {
+	return func(o *encodeOptions) {
+		o.save = opts
+	}
+}
+
+// EncodeFile encodes the pattern in the drum machine file format and writes
+// it to the provided path with WriteFileAtomic, so that an existing file is
+// only replaced once the pattern is completely written.
+func EncodeFile(path string, p *Pattern, opts ...EncodeOption) error {
+	var o encodeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return WriteFileAtomic(path, func(w io.Writer) error {
+		return Encode(w, p, opts...)
+	}, o.save...)
+}
+
+// Encode writes the pattern in the drum machine file format to w.
+// It is the counterpart of the decoder: the output of Encode decodes to an
+// equal pattern.
+func Encode(w io.Writer, p *Pattern, opts ...EncodeOption) error {
+	var o encodeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	// the payload is built in a scratch buffer as its size comes first
+	buf := scratchBuffers.Get().(*bytes.Buffer)
+	defer scratchBuffers.Put(buf)
+	buf.Reset()
+	if err := encodePattern(buf, p); err != nil {
+		return err
+	}
+	o.padding.pad(buf)
+	payload := buf.Bytes()
+	if err := splice.WriteFrame(w, spliceTypePattern, payload); err != nil {
+		return fmt.Errorf("write frame: %v", err)
+	}
+	if o.checksum {
+		var sum [4]byte
+		binary.BigEndian.PutUint32(sum[:], crc32.ChecksumIEEE(payload))
+		if _, err := w.Write(sum[:]); err != nil {
+			return fmt.Errorf("write checksum: %v", err)
+		}
+	}
+	return encodeExtensions(w, p)
+}
+
+func encodePattern(buf *bytes.Buffer, p *Pattern) error {
+	if len(p.version) > maxVersionLength {
+		return ErrVersionTooLong
+	}
+	if len(p.rawVersion) == maxVersionLength && cropToString(p.rawVersion) == p.version {
+		// keep the padding found when decoding
+		buf.Write(p.rawVersion)
+	} else {
+		var v [maxVersionLength]byte
+		copy(v[:], p.version)
+		buf.Write(v[:])
+	}
+	var tempo [4]byte
+	binary.LittleEndian.PutUint32(tempo[:], math.Float32bits(p.tempo))
+	buf.Write(tempo[:])
+	for _, t := range p.tracks {
+		if err := encodeTrack(buf, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeTrack(buf *bytes.Buffer, t *Track) error {
+	if len(t.name) > math.MaxUint8 {
+		return fmt.Errorf("encode track %d: %v", t.id, ErrNameTooLong)
+	}
+	var id [4]byte
+	binary.LittleEndian.PutUint32(id[:], t.id)
+	buf.Write(id[:])
+	buf.WriteByte(uint8(len(t.name)))
+	buf.WriteString(t.name)
+	for _, enabled := range t.steps {
+		if enabled {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package drum
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	for _, fileName := range []string{"pattern_1.splice", "pattern_2.splice", "pattern_3.splice", "pattern_4.splice", "pattern_5.splice"} {
+		raw, err := ioutil.ReadFile(path.Join("fixtures", fileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := decode(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("something went wrong decoding %s - %v", fileName, err)
+		}
+		var buf bytes.Buffer
+		if err := Encode(&buf, p); err != nil {
+			t.Fatalf("something went wrong encoding %s - %v", fileName, err)
+		}
+		if !bytes.Equal(buf.Bytes(), raw) {
+			t.Errorf("%s wasn't encoded as expected.\nGot:\n%x\nExpected:\n%x", fileName, buf.Bytes(), raw)
+		}
+	}
+}
+
+func TestEncodeDisplayChunk(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.tracks[3].SetDisplay(Display{"#00ff00", "bell"}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := p.tracks[3].Display(), decoded.tracks[3].Display(); got != exp {
+		t.Errorf("Expected '%v' but got '%v'", exp, got)
+	}
+}
+
+func TestEncodeInvalid(t *testing.T) {
+	testCases := []struct {
+		p   *Pattern
+		err string
+	}{
+		{&Pattern{version: strings.Repeat("x", 33)}, ErrVersionTooLong.Error()},
+		{&Pattern{tracks: []*Track{{name: strings.Repeat("x", 256)}}}, ErrNameTooLong.Error()},
+	}
+	for _, testCase := range testCases {
+		err := Encode(ioutil.Discard, testCase.p)
+		if err == nil || !strings.Contains(err.Error(), testCase.err) {
+			t.Errorf("Expected error '%v' but got '%v'", testCase.err, err)
+		}
+	}
+}
+
+func TestChecksumTrailer(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, p, WithChecksumTrailer()); err != nil {
+		t.Fatal(err)
+	}
+	raw := buf.Bytes()
+	if _, err := decode(bytes.NewReader(raw), WithChecksum()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	corrupted := append([]byte{}, raw...)
+	corrupted[len(corrupted)-5] ^= 1 // flip a step bit of the last track
+	if _, err := decode(bytes.NewReader(corrupted), WithChecksum()); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected error '%s' but got '%v'", ErrChecksumMismatch, err)
+	}
+
+	if _, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"), WithChecksum()); err == nil {
+		t.Errorf("expected error for missing checksum trailer")
+	}
+}
@@ -0,0 +1,184 @@
+// Command studio is a minimal drum machine built on the public API of the
+// drum package. It decodes a pattern, applies edits, renders it to a WAV
+// file and plays it, printing every step:
+//
+//	studio -select 'beat 4 of tracks matching "snare"' -toggle \
+//		-curve backbeat-accent -swing 55 -kit ./kit -wav out.wav \
+//		fixtures/pattern_1.splice
+//
+// The kit is a directory of samples or a kit manifest. Tracks without a
+// sample get a synthesized click so that the demo works without any audio
+// files.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strings"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// sampleRate is the rate of the synthesized clicks.
+const sampleRate = 44100
+
+type config struct {
+	input string
+	query string
+	op    string // enable, disable or toggle the selection
+	curve string
+	swing int
+	tempo float64
+	kit   string
+	wav   string
+	bars  int
+	play  bool
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("studio: ")
+	var c config
+	flag.StringVar(&c.query, "select", "", "select steps to edit, for example 'beat 1 of tracks matching \"kick\"'")
+	enable := flag.Bool("enable", false, "enable the selected steps")
+	disable := flag.Bool("disable", false, "disable the selected steps")
+	toggle := flag.Bool("toggle", false, "toggle the selected steps")
+	flag.StringVar(&c.curve, "curve", "", "apply a velocity curve to all tracks: "+strings.Join(drum.VelocityCurveNames(), ", "))
+	flag.IntVar(&c.swing, "swing", -1, "set the swing amount in percent")
+	flag.Float64Var(&c.tempo, "tempo", 0, "set the tempo in BPM")
+	flag.StringVar(&c.kit, "kit", "", "kit directory or manifest")
+	flag.StringVar(&c.wav, "wav", "", "render the pattern to this WAV file")
+	flag.IntVar(&c.bars, "bars", 2, "number of bars to render and play")
+	flag.BoolVar(&c.play, "play", true, "play the pattern")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <file.splice>\n", os.Args[0])
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	c.input = flag.Arg(0)
+	switch {
+	case *enable:
+		c.op = "enable"
+	case *disable:
+		c.op = "disable"
+	case *toggle:
+		c.op = "toggle"
+	}
+	if err := run(c, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(c config, out io.Writer) error {
+	p, err := drum.DecodeFile(c.input)
+	if err != nil {
+		return err
+	}
+	if err := edit(p, c); err != nil {
+		return err
+	}
+	fmt.Fprint(out, p)
+
+	kit, err := loadKit(p, c.kit)
+	if err != nil {
+		return err
+	}
+	if c.wav != "" {
+		if err := drum.RenderWAVFile(c.wav, p, kit, c.bars); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "rendered %d bars to %s\n", c.bars, c.wav)
+	}
+	if c.play {
+		return play(p, kit, c.bars, out)
+	}
+	return nil
+}
+
+func edit(p *drum.Pattern, c config) error {
+	if c.query != "" {
+		s, err := p.Select(c.query)
+		if err != nil {
+			return err
+		}
+		switch c.op {
+		case "enable":
+			s.Enable()
+		case "disable":
+			s.Disable()
+		case "toggle":
+			s.Toggle()
+		default:
+			return errors.New("-select needs one of -enable, -disable or -toggle")
+		}
+	}
+	if c.curve != "" {
+		curve, ok := drum.VelocityCurveByName(c.curve)
+		if !ok {
+			return fmt.Errorf("unknown velocity curve %q", c.curve)
+		}
+		for _, t := range p.Tracks() {
+			t.ApplyCurve(curve)
+		}
+	}
+	if c.swing >= 0 {
+		if err := p.SetSwing(uint8(c.swing)); err != nil {
+			return err
+		}
+	}
+	if c.tempo != 0 {
+		return p.SetTempo(float32(c.tempo))
+	}
+	return nil
+}
+
+// loadKit loads the kit found at path and adds a synthesized click for
+// every track without sample.
+func loadKit(p *drum.Pattern, path string) (*drum.Kit, error) {
+	kit := drum.NewKit("clicks")
+	if path != "" {
+		var err error
+		if kit, err = drum.LoadKit(path); err != nil {
+			return nil, err
+		}
+	}
+	for i, t := range p.Tracks() {
+		if _, ok := kit.Sample(t); !ok {
+			kit.AddID(t.ID(), click(200*math.Pow(1.25, float64(i))))
+		}
+	}
+	return kit, nil
+}
+
+// click returns a short decaying sine tone.
+func click(freq float64) *drum.Sample {
+	data := make([]float32, sampleRate/10)
+	for i := range data {
+		t := float64(i) / sampleRate
+		data[i] = float32(math.Sin(2*math.Pi*freq*t) * math.Exp(-t*40))
+	}
+	return &drum.Sample{Rate: sampleRate, Left: data, Right: data}
+}
+
+func play(p *drum.Pattern, kit *drum.Kit, bars int, out io.Writer) error {
+	stop := make(chan struct{})
+	steps := 0
+	pl := drum.NewPlayer(p, func(ev drum.StepEvent) {
+		names := make([]string, len(ev.Tracks))
+		for i, t := range ev.Tracks {
+			names[i] = t.Name()
+		}
+		fmt.Fprintf(out, "%2d %s\n", ev.Step+1, strings.Join(names, " "))
+		if steps++; steps == bars*len(drum.Steps{}) {
+			close(stop)
+		}
+	})
+	pl.SetKit(kit)
+	return pl.Play(stop)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+func TestRun(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "studio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	wav := filepath.Join(tmp, "out.wav")
+
+	var out bytes.Buffer
+	c := config{
+		input: filepath.Join("..", "..", "fixtures", "pattern_1.splice"),
+		query: "step 2 of tracks matching 'kick'",
+		op:    "enable",
+		curve: "backbeat-accent",
+		swing: 50,
+		tempo: 960,
+		wav:   wav,
+		bars:  1,
+		play:  true,
+	}
+	if err := run(c, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "(0) kick\t|xx--|x---|x---|x---|") {
+		t.Errorf("edit not applied:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), " 2 kick\n") {
+		t.Errorf("edited step not played:\n%s", out.String())
+	}
+	f, err := os.Open(wav)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	s, err := drum.ReadWAV(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Frames() == 0 {
+		t.Error("expected rendered audio")
+	}
+}
@@ -0,0 +1,191 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Extensions are stored behind the declared payload so that decoders which
+// only know the original format stop reading before them.
+//
+//	|SPLX (4 bytes)|Extension size (4 bytes)|          => Extension Header
+//	|Chunk id (4 bytes)|Chunk size (4 bytes)|Data (n bytes)| => First Chunk
+//	|Chunk id (4 bytes)|Chunk size (4 bytes)|Data (n bytes)| => Next Chunk
+//	...
+//
+// Sizes are big endian like the payload size, values within the chunk data
+// little endian like the payload fields.
+const extensionMagic = "SPLX"
+
+// chunkID identifies the content of an extension chunk.
+type chunkID [4]byte
+
+func (id chunkID) String() string {
+	return string(id[:])
+}
+
+// chunkCodec reads and writes the data of a single chunk kind.
+type chunkCodec struct {
+	id      chunkID
+	feature Feature
+	// decode applies the chunk data to the decoded pattern.
+	decode func(data []byte, p *Pattern) error
+	// encode returns the chunk data for the pattern or nil when there is
+	// nothing to store.
+	encode func(p *Pattern) []byte
+	// clear removes the chunk data from the pattern.
+	clear func(p *Pattern)
+}
+
+// chunkCodecs lists the known chunks in the order they are written.
+// Chunks with an unknown id are kept as is.
+var chunkCodecs = []chunkCodec{
+	{chunkDisplay, FeatureDisplay, decodeDisplayChunk, encodeDisplayChunk, clearDisplay},
+	{chunkSwing, FeatureSwing, decodeSwingChunk, encodeSwingChunk, clearSwing},
+	{chunkTempoMap, FeatureTempoMap, decodeTempoMapChunk, encodeTempoMapChunk, clearTempoMap},
+	{chunkVelocity, FeatureVelocity, decodeVelocityChunk, encodeVelocityChunk, clearVelocity},
+	{chunkTiming, FeatureTiming, decodeTimingChunk, encodeTimingChunk, clearTiming},
+	{chunkProbability, FeatureProbability, decodeProbabilityChunk, encodeProbabilityChunk, clearProbability},
+	{chunkCondition, FeatureCondition, decodeConditionChunk, encodeConditionChunk, clearCondition},
+	{chunkRatchet, FeatureRatchet, decodeRatchetChunk, encodeRatchetChunk, clearRatchet},
+	{chunkLength, FeatureLength, decodeLengthChunk, encodeLengthChunk, clearLength},
+	{chunkMix, FeatureMix, decodeMixChunk, encodeMixChunk, clearMix},
+	{chunkRole, FeatureRole, decodeRoleChunk, encodeRoleChunk, clearRole},
+	{chunkAlias, FeatureAlias, decodeAliasChunk, encodeAliasChunk, clearAliases},
+	{chunkBars, FeatureBars, decodeBarsChunk, encodeBarsChunk, clearBars},
+	{chunkChoke, FeatureChoke, decodeChokeChunk, encodeChokeChunk, clearChoke},
+	{chunkNudge, FeatureNudge, decodeNudgeChunk, encodeNudgeChunk, clearNudge},
+	{chunkMetadata, FeatureMetadata, decodeMetadataChunk, encodeMetadataChunk, clearMetadata},
+	{chunkLabels, FeatureLabels, decodeLabelsChunk, encodeLabelsChunk, clearLabels},
+}
+
+func findChunkCodec(id chunkID) (chunkCodec, bool) {
+	for _, c := range chunkCodecs {
+		if c.id == id {
+			return c, true
+		}
+	}
+	return chunkCodec{}, false
+}
+
+// RawExtra returns the data found behind the payload that could not be
+// interpreted. It is written back as is by the encoder.
+func (p *Pattern) RawExtra() []byte {
+	return p.rawExtra
+}
+
+// SetRawExtra sets the opaque data written behind the payload and the
+// extensions. Decoders ignore it.
+func (p *Pattern) SetRawExtra(b []byte) {
+	p.rawExtra = b
+}
+
+// rawChunk is an extension chunk kept as is.
+type rawChunk struct {
+	id   chunkID
+	data []byte
+}
+
+// decodeExtensions decodes the data found behind the payload. When it starts
+// with an extension block the known chunks are applied to the pattern and
+// unknown ones are kept. All other data is kept as raw extra.
+func decodeExtensions(data []byte, p *Pattern) error {
+	const headerLength = len(extensionMagic) + 4
+	if len(data) < headerLength || !bytes.HasPrefix(data, []byte(extensionMagic)) {
+		p.rawExtra = nonEmpty(data)
+		return nil
+	}
+	size := binary.BigEndian.Uint32(data[len(extensionMagic):])
+	if uint64(size) > uint64(len(data)-headerLength) {
+		p.rawExtra = nonEmpty(data)
+		return nil
+	}
+	ext := bytes.NewReader(data[headerLength : headerLength+int(size)])
+	p.rawExtra = nonEmpty(data[headerLength+int(size):])
+	for ext.Len() > 0 {
+		var id chunkID
+		if _, err := io.ReadFull(ext, id[:]); err != nil {
+			return fmt.Errorf("parse chunk id: %v", err)
+		}
+		var chunkSize uint32
+		if err := binary.Read(ext, binary.BigEndian, &chunkSize); err != nil {
+			return fmt.Errorf("parse %s chunk size: %v", id, err)
+		}
+		if int64(chunkSize) > int64(ext.Len()) {
+			return fmt.Errorf("parse %s chunk: size %d exceeds extension", id, chunkSize)
+		}
+		data, err := readBytes(ext, int(chunkSize))
+		if err != nil {
+			return fmt.Errorf("parse %s chunk: %v", id, err)
+		}
+		codec, ok := findChunkCodec(id)
+		if !ok {
+			p.chunks = append(p.chunks, rawChunk{id, data})
+			continue
+		}
+		if err := codec.decode(data, p); err != nil {
+			return fmt.Errorf("parse %s chunk: %v", id, err)
+		}
+	}
+	return nil
+}
+
+func nonEmpty(b []byte) []byte {
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
+
+// encodeExtensions writes the extension block for all chunks with data
+// followed by the raw extra data.
+func encodeExtensions(w io.Writer, p *Pattern) error {
+	if err := encodeChunks(w, p); err != nil {
+		return err
+	}
+	if _, err := w.Write(p.rawExtra); err != nil {
+		return fmt.Errorf("write raw extra: %v", err)
+	}
+	return nil
+}
+
+// encodeChunks writes the extension block. The known chunks are written
+// first, then the unknown ones kept by the decoder. Nothing is written when
+// there is no chunk data at all.
+func encodeChunks(w io.Writer, p *Pattern) error {
+	ext := scratchBuffers.Get().(*bytes.Buffer)
+	defer scratchBuffers.Put(ext)
+	ext.Reset()
+	writeChunk := func(id chunkID, data []byte) {
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(data)))
+		ext.Write(id[:])
+		ext.Write(size[:])
+		ext.Write(data)
+	}
+	for _, c := range chunkCodecs {
+		if data := c.encode(p); data != nil {
+			writeChunk(c.id, data)
+		}
+	}
+	for _, c := range p.chunks {
+		writeChunk(c.id, c.data)
+	}
+	if ext.Len() == 0 {
+		return nil
+	}
+	if _, err := io.WriteString(w, extensionMagic); err != nil {
+		return fmt.Errorf("write extension header: %v", err)
+	}
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(ext.Len()))
+	if _, err := w.Write(size[:]); err != nil {
+		return fmt.Errorf("write extension size: %v", err)
+	}
+	if _, err := ext.WriteTo(w); err != nil {
+		return fmt.Errorf("write extension chunks: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+// withExtension appends an extension block with the given chunks to the
+// fixture data.
+func withExtension(t *testing.T, fixture string, chunks ...[]byte) []byte {
+	data, err := ioutil.ReadFile(path.Join("fixtures", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ext := bytes.Join(chunks, nil)
+	buf := bytes.NewBuffer(data)
+	buf.WriteString(extensionMagic)
+	binary.Write(buf, binary.BigEndian, uint32(len(ext)))
+	buf.Write(ext)
+	return buf.Bytes()
+}
+
+func chunk(id chunkID, data []byte) []byte {
+	buf := bytes.NewBuffer(id[:])
+	binary.Write(buf, binary.BigEndian, uint32(len(data)))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func TestDecodeDisplayChunk(t *testing.T) {
+	data := []byte{1, 0, 7}
+	data = append(data, "#ff8800"...)
+	data = append(data, 5)
+	data = append(data, "snare"...)
+	raw := withExtension(t, "pattern_1.splice",
+		chunk(chunkID{'U', 'N', 'K', 'N'}, []byte{1, 2, 3}),
+		chunk(chunkDisplay, data))
+
+	p, err := decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := (Display{"#ff8800", "snare"}), p.tracks[1].Display(); got != exp {
+		t.Errorf("Expected '%v' but got '%v'", exp, got)
+	}
+	if exp, got := (Display{}), p.tracks[0].Display(); got != exp {
+		t.Errorf("Expected '%v' but got '%v'", exp, got)
+	}
+}
+
+func TestDecodeDisplayChunkInvalid(t *testing.T) {
+	testCases := [][]byte{
+		{9, 0, 0, 0},
+		{0, 0, 3, 'r', 'e', 'd', 0},
+		{0, 0, 7, '#'},
+	}
+	for _, data := range testCases {
+		raw := withExtension(t, "pattern_1.splice", chunk(chunkDisplay, data))
+		if _, err := decode(bytes.NewReader(raw)); err == nil {
+			t.Errorf("expected error for chunk data %v", data)
+		}
+	}
+}
+
+func TestUnknownChunksAndRawExtraRoundTrip(t *testing.T) {
+	raw := withExtension(t, "pattern_1.splice",
+		chunk(chunkDisplay, []byte{0, 0, 0, 4, 'k', 'i', 'c', 'k'}),
+		chunk(chunkID{'U', 'N', 'K', 'N'}, []byte{1, 2, 3}))
+	raw = append(raw, "junk"...)
+
+	p, err := decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := "junk", string(p.RawExtra()); got != exp {
+		t.Errorf("Expected raw extra '%v' but got '%v'", exp, got)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), raw) {
+		t.Errorf("round trip failed.\nGot:\n%x\nExpected:\n%x", buf.Bytes(), raw)
+	}
+}
+
+func TestDecodeRawExtra(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_5.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := 31, len(p.RawExtra()); got != exp {
+		t.Errorf("Expected %v bytes raw extra but got %v", exp, got)
+	}
+	if p, _ = DecodeFile(path.Join("fixtures", "pattern_1.splice")); p.RawExtra() != nil {
+		t.Errorf("Expected no raw extra but got %v", p.RawExtra())
+	}
+}
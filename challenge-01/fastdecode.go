@@ -0,0 +1,277 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"math"
+	"sync"
+)
+
+// trackHeaderLength is the length of the track id and name length fields.
+const trackHeaderLength = 5
+
+// scratchBuffers are reused to read complete files, see decodeAll.
+var scratchBuffers = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// decodeAll reads r to the end into a scratch buffer and decodes the data
+// with DecodeBytes.
+func decodeAll(r io.Reader, opts ...DecodeOption) (*Pattern, error) {
+	buf := scratchBuffers.Get().(*bytes.Buffer)
+	defer scratchBuffers.Put(buf)
+	if err := readFile(buf, r, newDecodeOptions(opts).limits); err != nil {
+		return nil, err
+	}
+	return DecodeBytes(buf.Bytes(), opts...)
+}
+
+// readFile replaces the content of buf with the data read from r. It stops
+// one byte after the largest file within the limits, so that decoding fails
+// the checks instead of the memory being exhausted.
+func readFile(buf *bytes.Buffer, r io.Reader, lim Limits) error {
+	buf.Reset()
+	max := lim.MaxFileSize()
+	for max <= 0 || int64(buf.Len()) <= max {
+		buf.Grow(bytes.MinRead)
+		b := buf.AvailableBuffer()
+		b = b[:cap(b)]
+		if max > 0 && int64(len(b)) > max+1-int64(buf.Len()) {
+			b = b[:max+1-int64(buf.Len())]
+		}
+		n, err := r.Read(b)
+		buf.Write(b[:n])
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeBytes decodes a drum machine file held in data. It returns the same
+// pattern and errors as Decode but works on the data in place instead of
+// reading field by field, which makes it the faster choice when the whole
+// file is available. The pattern does not reference data.
+func DecodeBytes(data []byte, opts ...DecodeOption) (*Pattern, error) {
+	p := newDecodeOptions(opts).arena.pattern()
+	if err := decodeBytes(data, p, opts...); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// DecodeInto decodes a drum machine file from r into p, reusing its track
+// slice and tracks instead of allocating new ones. Names and the version are
+// only allocated when they changed, so reloading a file being edited hardly
+// allocates.
+//
+// All data of p is replaced. Tracks obtained from p before, for example with
+// Tracks or TracksSeq, are overwritten in place and may hold data of another
+// track afterwards, so they must not be used anymore. The same applies to the
+// tracks and the slice passed to NewPattern for p. The pattern must not be
+// played or encoded concurrently and its content is undefined when an error
+// is returned.
+func DecodeInto(r io.Reader, p *Pattern, opts ...DecodeOption) error {
+	buf := scratchBuffers.Get().(*bytes.Buffer)
+	defer scratchBuffers.Put(buf)
+	if err := readFile(buf, r, newDecodeOptions(opts).limits); err != nil {
+		return err
+	}
+	return decodeBytes(buf.Bytes(), p, opts...)
+}
+
+func decodeBytes(data []byte, p *Pattern, opts ...DecodeOption) (err error) {
+	o := newDecodeOptions(opts)
+	if o.logger != nil {
+		// the reader keeps track of the offsets to log
+		np, err := decode(bytes.NewReader(data), opts...)
+		if err != nil {
+			return err
+		}
+		*p = *np
+		return nil
+	}
+	o.rec = newStatsRecorder(o.stats)
+	defer func(n int) { o.rec.finish(nil, int64(n), err) }(len(data))
+	header, err := take(&data, typeHeaderLength)
+	if err != nil {
+		return parseError("type header", 0, err)
+	}
+	if string(header) != spliceTypePattern {
+		return parseError("type header", 0, ErrUnsupportedFileFormat)
+	}
+	sizeField, err := take(&data, 8)
+	if err != nil {
+		return parseError("payload size", 0, err)
+	}
+	size := int64(binary.BigEndian.Uint64(sizeField))
+	o.rec.declare(size)
+	if err := o.limits.checkPayloadSize(size); err != nil {
+		return parseError("payload size", 0, err)
+	}
+	n := len(data)
+	switch {
+	case size < 0:
+		n = 0
+	case size < int64(n):
+		n = int(size)
+	}
+	payload, data := data[:n], data[n:]
+	if err := decodePayload(payload, size, o, p); err != nil {
+		return err
+	}
+	if o.checksum {
+		sum, err := take(&data, 4)
+		if err != nil {
+			return parseError("checksum", 0, err)
+		}
+		if binary.BigEndian.Uint32(sum) != crc32.ChecksumIEEE(payload) {
+			return parseError("checksum", 0, ErrChecksumMismatch)
+		}
+	}
+	if err := o.limits.checkExtraSize(int64(len(data))); err != nil {
+		return parseError("trailing data", 0, err)
+	}
+	if err := decodeExtensions(append([]byte(nil), data...), p); err != nil {
+		return err
+	}
+	return applyTrailing(p, o.trailing)
+}
+
+// decodePayload decodes the payload read of the declared size into p. Data
+// after the end of a shorter payload is reported missing like by
+// decodePattern.
+func decodePayload(payload []byte, size int64, o decodeOptions, p *Pattern) error {
+	b := payload
+	defer func() { o.rec.payloadRead(int64(len(payload) - len(b))) }()
+	// count the tracks first to allocate them at once
+	count := 0
+	for off := maxVersionLength + 4; off < len(payload); count++ {
+		if off+trackHeaderLength > len(payload) {
+			count++
+			break
+		}
+		off += trackHeaderLength + int(payload[off+4]) + stepsLength
+	}
+	// new strings are substrings of a single copy of the payload
+	var text string
+	substring := func(start, end int, old string) string {
+		if old == string(payload[start:end]) {
+			return old
+		}
+		if text == "" {
+			text = o.arena.string(payload)
+		}
+		return text[start:end]
+	}
+	reuse := p.tracks
+	tracks := p.tracks[:0]
+	if cap(tracks) < count {
+		tracks = o.arena.trackRefs(count)
+	}
+	var slab []Track
+	*p = Pattern{version: p.version, rawVersion: p.rawVersion[:0]}
+
+	v, err := take(&b, maxVersionLength)
+	if err != nil {
+		return parseError("version", 0, err)
+	}
+	end := bytes.IndexByte(v, endOfString)
+	if end < 0 {
+		end = len(v)
+	}
+	p.version = substring(0, end, p.version)
+	if cap(p.rawVersion) < len(v) {
+		p.rawVersion = o.arena.bytes(v)
+	} else {
+		p.rawVersion = append(p.rawVersion, v...)
+	}
+	tempo, err := take(&b, 4)
+	if err != nil {
+		return parseError("tempo", 0, err)
+	}
+	p.tempo = math.Float32frombits(binary.LittleEndian.Uint32(tempo))
+	o.correctTempo(p, nil)
+
+	boundary := int64(len(payload) - len(b))
+	o.rec.boundary(boundary)
+	// running out of a complete payload is the declared size ending within
+	// a track
+	fail := func(field string, track int, err error) error {
+		if int64(len(payload)) == size && (err == io.EOF || err == io.ErrUnexpectedEOF) {
+			return sizeMismatch(track, size, boundary)
+		}
+		return parseError(field, track, err)
+	}
+
+	for i := 0; int64(len(payload)-len(b)) < size; i++ {
+		if int64(len(payload)) == size && o.padding.strips(b) {
+			b = b[len(b):]
+			break
+		}
+		if err := o.limits.checkTracks(i + 1); err != nil {
+			return parseError("track", i+1, err)
+		}
+		var t *Track
+		if i < len(reuse) {
+			t = reuse[i]
+		} else {
+			if len(slab) == 0 {
+				// at least one for a payload shorter than declared
+				slab = o.arena.trackSlab(count - i + 1)
+			}
+			t, slab = &slab[0], slab[1:]
+		}
+		*t = Track{name: t.name}
+		id, err := take(&b, 4)
+		if err != nil {
+			return fail("track id", i+1, err)
+		}
+		t.id = binary.LittleEndian.Uint32(id)
+		lenName, err := take(&b, 1)
+		if err != nil {
+			return fail("track name length", i+1, err)
+		}
+		if err := o.limits.checkNameLength(int(lenName[0])); err != nil {
+			return parseError("track name length", i+1, err)
+		}
+		start := len(payload) - len(b)
+		if _, err := take(&b, int(lenName[0])); err != nil {
+			return fail("track name", i+1, err)
+		}
+		t.name = substring(start, start+int(lenName[0]), t.name)
+		steps, err := take(&b, stepsLength)
+		if err != nil {
+			return fail("steps", i+1, err)
+		}
+		if err := o.steps.decode(steps, t); err != nil {
+			return parseError("steps", i+1, err)
+		}
+		tracks = append(tracks, t)
+		boundary = int64(len(payload) - len(b))
+		o.rec.track()
+		o.rec.boundary(boundary)
+	}
+	p.tracks = tracks
+	return nil
+}
+
+// take slices the next n bytes off b. Like io.ReadFull the error is EOF
+// only if b is empty.
+func take(b *[]byte, n int) ([]byte, error) {
+	if len(*b) < n {
+		if len(*b) == 0 {
+			return nil, io.EOF
+		}
+		*b = nil
+		return nil, io.ErrUnexpectedEOF
+	}
+	v := (*b)[:n]
+	*b = (*b)[n:]
+	return v, nil
+}
@@ -0,0 +1,139 @@
+package drum
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"reflect"
+	"testing"
+)
+
+// decodeBoth decodes data with the reader and the in place decoder and fails
+// when the results differ.
+func decodeBoth(t *testing.T, msg string, data []byte, opts ...DecodeOption) {
+	exp, expErr := decode(bytes.NewReader(data), opts...)
+	got, err := DecodeBytes(data, opts...)
+	if fmt.Sprint(err) != fmt.Sprint(expErr) {
+		t.Fatalf("%s: Expected error '%v' but got '%v'", msg, expErr, err)
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Fatalf("%s: Expected '%#v' but got '%#v'", msg, exp, got)
+	}
+}
+
+func TestDecodeBytes(t *testing.T) {
+	for i := 1; i <= 5; i++ {
+		name := fmt.Sprintf("pattern_%d.splice", i)
+		raw, err := ioutil.ReadFile(path.Join("fixtures", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for n := 0; n <= len(raw); n++ {
+			decodeBoth(t, fmt.Sprintf("%s[:%d]", name, n), raw[:n])
+		}
+		p, _ := decode(bytes.NewReader(raw))
+		var buf bytes.Buffer
+		Encode(&buf, p, WithChecksumTrailer())
+		withSum := buf.Bytes()
+		for n := 0; n <= len(withSum); n++ {
+			decodeBoth(t, fmt.Sprintf("%s with checksum[:%d]", name, n), withSum[:n], WithChecksum())
+		}
+		withSum[len(withSum)-1]++
+		decodeBoth(t, name+" with wrong checksum", withSum, WithChecksum())
+	}
+
+	raw, _ := ioutil.ReadFile(path.Join("fixtures", "pattern_1.splice"))
+	for _, size := range []uint64{0, 30, 1 << 62, 1 << 63} {
+		data := append([]byte(nil), raw...)
+		for i := 0; i < 8; i++ {
+			data[typeHeaderLength+i] = byte(size >> uint(56-8*i))
+		}
+		decodeBoth(t, fmt.Sprintf("payload size %d", size), data)
+	}
+
+	// the pattern does not reference the data
+	data := append([]byte(nil), raw...)
+	p, _ := DecodeBytes(data)
+	exp := p.String()
+	for i := range data {
+		data[i] = 0
+	}
+	if p.String() != exp {
+		t.Errorf("Expected '%s' but got '%s'", exp, p)
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	raw, err := ioutil.ReadFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("reader", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := Decode(bytes.NewReader(raw)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("bytes", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := DecodeBytes(raw); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestDecodeInto(t *testing.T) {
+	raw1, _ := ioutil.ReadFile(path.Join("fixtures", "pattern_1.splice"))
+	raw2, _ := ioutil.ReadFile(path.Join("fixtures", "pattern_2.splice"))
+	var p Pattern
+	for _, raw := range [][]byte{raw2, raw1, raw2, raw2} {
+		first := p.tracks
+		if err := DecodeInto(bytes.NewReader(raw), &p); err != nil {
+			t.Fatal(err)
+		}
+		exp, _ := decode(bytes.NewReader(raw))
+		if !reflect.DeepEqual(&p, exp) {
+			t.Fatalf("Expected '%#v' but got '%#v'", exp, &p)
+		}
+		for i := 0; i < len(first) && i < len(p.tracks); i++ {
+			if first[i] != p.tracks[i] {
+				t.Errorf("Expected track %d to be reused", i)
+			}
+		}
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := DecodeInto(bytes.NewReader(raw2), &p); err != nil {
+			t.Fatal(err)
+		}
+	})
+	// the reader only
+	if allocs > 1 && !raceEnabled {
+		t.Errorf("Expected no allocations reloading the same file but got %v", allocs)
+	}
+
+	if err := DecodeInto(bytes.NewReader(raw2[:30]), &p); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func BenchmarkDecodeInto(b *testing.B) {
+	raw, err := ioutil.ReadFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	var p Pattern
+	r := bytes.NewReader(raw)
+	for i := 0; i < b.N; i++ {
+		r.Reset(raw)
+		if err := DecodeInto(r, &p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package drum
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Feature names an optional part of the format beyond the 16 step grid.
+// Peers exchange the features they support to agree on what can be sent
+// without losing data.
+type Feature string
+
+// Known features.
+const (
+	FeatureDisplay  Feature = "display"  // track display metadata chunk
+	FeatureChecksum Feature = "checksum" // CRC32 trailer
+)
+
+// SupportedFeatures returns all features supported by this package, sorted
+// by name.
+func SupportedFeatures() []Feature {
+	features := []Feature{FeatureChecksum}
+	for _, c := range chunkCodecs {
+		features = append(features, c.feature)
+	}
+	sortFeatures(features)
+	return features
+}
+
+// Negotiate returns the features supported by both sides, sorted by name.
+func Negotiate(local, remote []Feature) []Feature {
+	known := make(map[Feature]bool, len(remote))
+	for _, f := range remote {
+		known[f] = true
+	}
+	var common []Feature
+	for _, f := range local {
+		if known[f] {
+			common = append(common, f)
+			delete(known, f)
+		}
+	}
+	sortFeatures(common)
+	return common
+}
+
+// Features returns the chunk features used by the pattern, sorted by name.
+func (p *Pattern) Features() []Feature {
+	var features []Feature
+	for _, c := range chunkCodecs {
+		if c.encode(p) != nil {
+			features = append(features, c.feature)
+		}
+	}
+	sortFeatures(features)
+	return features
+}
+
+// Downgrade returns a copy of the pattern without the data of features not
+// contained in supported, together with the features that were dropped so
+// that callers can warn instead of silently losing data. Unknown chunks and
+// raw extra data are kept as they are opaque to both sides.
+func (p *Pattern) Downgrade(supported []Feature) (*Pattern, []Feature) {
+	ok := make(map[Feature]bool, len(supported))
+	for _, f := range supported {
+		ok[f] = true
+	}
+	c := p.Clone()
+	var dropped []Feature
+	for _, codec := range chunkCodecs {
+		if ok[codec.feature] || codec.encode(c) == nil {
+			continue
+		}
+		codec.clear(c)
+		dropped = append(dropped, codec.feature)
+	}
+	sortFeatures(dropped)
+	return c, dropped
+}
+
+// Upgrade returns a copy of the pattern with the data of the features not
+// contained in supported taken from base. It undoes Downgrade for a pattern
+// that was edited by a peer that does not know these features, so that the
+// peer does not silently drop their data. Tracks are matched by id, tracks
+// not found in base get no data of these features.
+func (p *Pattern) Upgrade(base *Pattern, supported []Feature) (*Pattern, error) {
+	ok := make(map[Feature]bool, len(supported))
+	for _, f := range supported {
+		ok[f] = true
+	}
+	c := p.Clone()
+	// base with its tracks at the positions of the tracks of c, as the chunk
+	// data refers to tracks by index
+	ref := *base
+	ref.tracks = make([]*Track, len(c.tracks))
+	used := make(map[*Track]bool, len(base.tracks))
+	for i, t := range c.tracks {
+		ref.tracks[i] = &Track{id: t.id, name: t.name}
+		for _, bt := range base.tracks {
+			if bt.id == t.id && !used[bt] {
+				ref.tracks[i], used[bt] = bt, true
+				break
+			}
+		}
+	}
+	for _, codec := range chunkCodecs {
+		if ok[codec.feature] {
+			continue
+		}
+		codec.clear(c)
+		if data := codec.encode(&ref); data != nil {
+			if err := codec.decode(data, c); err != nil {
+				return nil, fmt.Errorf("upgrade %s: %v", codec.feature, err)
+			}
+		}
+	}
+	return c, nil
+}
+
+func sortFeatures(f []Feature) {
+	sort.Slice(f, func(i, j int) bool { return f[i] < f[j] })
+}
@@ -0,0 +1,89 @@
+package drum
+
+import (
+	"fmt"
+	"path"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	got := Negotiate(SupportedFeatures(), []Feature{"stems", FeatureVelocity, FeatureLength, FeatureDisplay})
+	if exp := "[display length velocity]"; fmt.Sprint(got) != exp {
+		t.Errorf("Expected '%v' but got '%v'", exp, got)
+	}
+	if got := Negotiate(SupportedFeatures(), nil); len(got) != 0 {
+		t.Errorf("Expected no features but got '%v'", got)
+	}
+}
+
+func TestSupportedFeatures(t *testing.T) {
+	supported := make(map[Feature]bool)
+	for _, f := range SupportedFeatures() {
+		supported[f] = true
+	}
+	seen := make(map[Feature]chunkID)
+	for _, c := range chunkCodecs {
+		if !supported[c.feature] {
+			t.Errorf("chunk %s: feature %q is not supported", c.id, c.feature)
+		}
+		if id, ok := seen[c.feature]; ok {
+			t.Errorf("chunk %s: feature %q is used by chunk %s too", c.id, c.feature, id)
+		}
+		seen[c.feature] = c.id
+	}
+}
+
+func TestDowngrade(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.tracks[0].SetDisplay(Display{Icon: "kick"}); err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := "[display]", fmt.Sprint(p.Features()); got != exp {
+		t.Errorf("Expected '%v' but got '%v'", exp, got)
+	}
+
+	d, dropped := p.Downgrade(nil)
+	if exp := "[display]"; fmt.Sprint(dropped) != exp {
+		t.Errorf("Expected dropped '%v' but got '%v'", exp, dropped)
+	}
+	if d.tracks[0].Display() != (Display{}) || p.tracks[0].Display() == (Display{}) {
+		t.Errorf("display should be removed from the copy only")
+	}
+	if _, dropped := p.Downgrade([]Feature{FeatureDisplay}); len(dropped) != 0 {
+		t.Errorf("Expected nothing dropped but got '%v'", dropped)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.tracks[0].SetVelocity(0, 40)
+	p.tracks[1].SetLength(12)
+	p.tracks[1].SetDisplay(Display{Icon: "snare"})
+	supported := []Feature{FeatureDisplay}
+	down, _ := p.Downgrade(supported)
+
+	// the peer swaps the first tracks, edits a step and the display
+	down.tracks[0], down.tracks[1] = down.tracks[1], down.tracks[0]
+	down.tracks[1].SetStep(1, true)
+	down.tracks[0].SetDisplay(Display{Icon: "clap"})
+	up, err := down.Upgrade(p, supported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kick, snare := up.tracks[1], up.tracks[0]
+	if kick.Velocity(0) != 40 || snare.Length() != 12 || kick.Length() != 0 {
+		t.Errorf("Expected the velocity and length to follow the tracks but got %v and %v", kick.Velocity(0), snare.Length())
+	}
+	if !kick.steps[1] || snare.Display().Icon != "clap" {
+		t.Errorf("Expected the edits of the peer to be kept but got %v and %v", kick.steps, snare.Display())
+	}
+	if down.tracks[1].hasVelocity() {
+		t.Errorf("the downgraded pattern should not be modified")
+	}
+}
@@ -0,0 +1,239 @@
+package drum
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrFetch is returned when a pattern could not be fetched from a URL.
+var ErrFetch = errors.New("fetch failed")
+
+// RetryPolicy sets how often DecodeURL tries a request. Requests failing
+// with a network error, a 429 Too Many Requests or a 5xx status are tried
+// again after Backoff, which doubles with every further attempt.
+type RetryPolicy struct {
+	Attempts int // 1 or less tries once
+	Backoff  time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy of DecodeURL without WithRetry.
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, Backoff: 200 * time.Millisecond}
+
+// FetchOption configures DecodeURL.
+type FetchOption func(*fetchOptions)
+
+type fetchOptions struct {
+	client *http.Client
+	cache  *URLCache
+	retry  RetryPolicy
+	decode []DecodeOption
+}
+
+// WithHTTPClient makes DecodeURL send the requests with c instead of
+// http.DefaultClient, for example to set timeouts or proxies.
+func WithHTTPClient(c *http.Client) FetchOption {
+	return func(o *fetchOptions) {
+		o.client = c
+	}
+}
+
+// WithURLCache makes DecodeURL keep the fetched files with their ETag in
+// the cache and revalidate them instead of downloading them again.
+func WithURLCache(c *URLCache) FetchOption {
+	return func(o *fetchOptions) {
+		o.cache = c
+	}
+}
+
+// WithRetry sets the retry policy of DecodeURL.
+func WithRetry(p RetryPolicy) FetchOption {
+	return func(o *fetchOptions) {
+		o.retry = p
+	}
+}
+
+// WithFetchDecodeOptions sets the options fetched files are decoded with.
+// Their Limits also limit the size of the download.
+func WithFetchDecodeOptions(opts ...DecodeOption) FetchOption {
+	return func(o *fetchOptions) {
+		o.decode = opts
+	}
+}
+
+// DecodeURL fetches the .splice file at the http or https URL and decodes
+// it. Responses larger than the MaxFileSize of the decode limits are
+// rejected without reading them completely. With a URLCache the file is
+// only downloaded again when its ETag changed and the cached file is decoded
+// in offline mode, otherwise offline mode fails with ErrOffline.
+func DecodeURL(ctx context.Context, rawURL string, opts ...FetchOption) (*Pattern, error) {
+	o := fetchOptions{client: http.DefaultClient, retry: DefaultRetryPolicy}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if u, err := url.Parse(rawURL); err != nil || u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: %s: not a http or https URL", ErrFetch, rawURL)
+	}
+	cached, hasCached := o.cache.get(rawURL)
+	if err := AllowNetwork("fetch"); err != nil {
+		if hasCached {
+			return DecodeBytes(cached.data, o.decode...)
+		}
+		return nil, err
+	}
+	data, etag, err := o.fetch(ctx, rawURL, cached.etag)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		// not modified
+		return DecodeBytes(cached.data, o.decode...)
+	}
+	p, err := DecodeBytes(data, o.decode...)
+	if err != nil {
+		return nil, err
+	}
+	if etag != "" {
+		o.cache.put(rawURL, urlCacheEntry{etag: etag, data: data})
+	}
+	return p, nil
+}
+
+// fetch downloads the file, sending the ETag of the cached copy when there
+// is one. It returns nil data when the cached copy is still current.
+func (o *fetchOptions) fetch(ctx context.Context, rawURL, etag string) ([]byte, string, error) {
+	backoff := o.retry.Backoff
+	for attempt := 1; ; attempt++ {
+		data, newETag, retry, err := o.get(ctx, rawURL, etag)
+		if err == nil || !retry || attempt >= o.retry.Attempts {
+			return data, newETag, err
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, "", ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// get sends one request and reports whether a failure is worth a retry.
+func (o *fetchOptions) get(ctx context.Context, rawURL, etag string) (data []byte, newETag string, retry bool, err error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("%w: %v", ErrFetch, err)
+	}
+	req = req.WithContext(ctx)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, "", ctx.Err() == nil, fmt.Errorf("%w: %v", ErrFetch, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotModified && etag != "":
+		return nil, etag, false, nil
+	case resp.StatusCode != http.StatusOK:
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return nil, "", retry, fmt.Errorf("%w: %s: unexpected status %s", ErrFetch, rawURL, resp.Status)
+	}
+	lim := newDecodeOptions(o.decode).limits
+	if max := lim.MaxFileSize(); max > 0 && resp.ContentLength > max {
+		return nil, "", false, fmt.Errorf("%w (%w): %s: %d bytes exceed %d", ErrFetch, ErrLimitExceeded, rawURL, resp.ContentLength, max)
+	}
+	var buf bytes.Buffer
+	if err := readFile(&buf, resp.Body, lim); err != nil {
+		return nil, "", ctx.Err() == nil, fmt.Errorf("%w: %s: %v", ErrFetch, rawURL, err)
+	}
+	return buf.Bytes(), resp.Header.Get("ETag"), false, nil
+}
+
+// URLCache keeps the files fetched by DecodeURL with their ETag, in memory
+// and optionally in a directory so that later processes revalidate them
+// too. A URLCache is safe for concurrent use.
+type URLCache struct {
+	dir string
+
+	mu      sync.Mutex
+	entries map[string]urlCacheEntry // by URL
+}
+
+type urlCacheEntry struct {
+	etag string
+	data []byte
+}
+
+// NewURLCache returns a cache keeping the files in dir as well, or only in
+// memory when dir is empty. The directory is created when missing.
+func NewURLCache(dir string) *URLCache {
+	return &URLCache{dir: dir, entries: make(map[string]urlCacheEntry)}
+}
+
+// get returns the cached file of the URL, reading it from the directory when
+// it is not in memory.
+func (c *URLCache) get(rawURL string) (urlCacheEntry, bool) {
+	if c == nil {
+		return urlCacheEntry{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[rawURL]; ok {
+		return e, true
+	}
+	if c.dir == "" {
+		return urlCacheEntry{}, false
+	}
+	base := c.diskPath(rawURL)
+	etag, err := ioutil.ReadFile(base + ".etag")
+	if err != nil {
+		return urlCacheEntry{}, false
+	}
+	data, err := ioutil.ReadFile(base + spliceFileExt)
+	if err != nil {
+		return urlCacheEntry{}, false
+	}
+	e := urlCacheEntry{etag: strings.TrimSpace(string(etag)), data: data}
+	c.entries[rawURL] = e
+	return e, true
+}
+
+// put caches the file of the URL. Failures writing the directory only cost
+// a download later, so they are ignored.
+func (c *URLCache) put(rawURL string, e urlCacheEntry) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[rawURL] = e
+	if c.dir == "" || os.MkdirAll(c.dir, 0755) != nil {
+		return
+	}
+	// the data is written first, so that an ETag always has its data
+	base := c.diskPath(rawURL)
+	if ioutil.WriteFile(base+spliceFileExt, e.data, 0644) == nil {
+		ioutil.WriteFile(base+".etag", []byte(e.etag), 0644)
+	}
+}
+
+// diskPath returns the file name of the URL in the cache directory without
+// extension.
+func (c *URLCache) diskPath(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,109 @@
+package drum
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// patternServer serves the fixture with an ETag, failing the first
+// requests with the status of fail.
+func patternServer(t *testing.T, fail []int) (*httptest.Server, *int32, *int32) {
+	data, err := ioutil.ReadFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&requests, 1))
+		if n <= len(fail) {
+			w.WriteHeader(fail[n-1])
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests, &notModified
+}
+
+func TestDecodeURL(t *testing.T) {
+	exp, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, requests, notModified := patternServer(t, nil)
+	dir := t.TempDir()
+	cache := NewURLCache(dir)
+	for i := 0; i < 2; i++ {
+		p, err := DecodeURL(context.Background(), server.URL+"/a.splice", WithURLCache(cache))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.Equal(exp) {
+			t.Errorf("Expected %v but got %v", exp, p)
+		}
+	}
+	// a later process revalidates the files of the directory
+	if _, err := DecodeURL(context.Background(), server.URL+"/a.splice", WithURLCache(NewURLCache(dir))); err != nil {
+		t.Fatal(err)
+	}
+	if *requests != 3 || *notModified != 2 {
+		t.Errorf("Expected 3 requests with 2 revalidated but got %d with %d", *requests, *notModified)
+	}
+
+	SetOffline(true)
+	defer SetOffline(false)
+	if _, err := DecodeURL(context.Background(), server.URL+"/a.splice", WithURLCache(cache)); err != nil {
+		t.Errorf("Expected the cached file in offline mode but got %v", err)
+	}
+	if _, err := DecodeURL(context.Background(), server.URL+"/a.splice"); !errors.Is(err, ErrOffline) {
+		t.Errorf("Expected ErrOffline without cache but got %v", err)
+	}
+}
+
+func TestDecodeURLRetry(t *testing.T) {
+	server, requests, _ := patternServer(t, []int{http.StatusServiceUnavailable, http.StatusTooManyRequests})
+	retry := WithRetry(RetryPolicy{Attempts: 3, Backoff: time.Millisecond})
+	if _, err := DecodeURL(context.Background(), server.URL, retry); err != nil {
+		t.Fatal(err)
+	}
+	if *requests != 3 {
+		t.Errorf("Expected 3 attempts but got %d", *requests)
+	}
+
+	server, requests, _ = patternServer(t, []int{http.StatusNotFound})
+	if _, err := DecodeURL(context.Background(), server.URL, retry); !errors.Is(err, ErrFetch) {
+		t.Errorf("Expected ErrFetch but got %v", err)
+	}
+	if *requests != 1 {
+		t.Errorf("Expected no retry of a missing file but got %d attempts", *requests)
+	}
+}
+
+func TestDecodeURLErrors(t *testing.T) {
+	server, _, _ := patternServer(t, nil)
+	small := WithFetchDecodeOptions(WithLimits(Limits{MaxPayloadSize: 10, MaxExtraSize: 10}))
+	if _, err := DecodeURL(context.Background(), server.URL, small); !errors.Is(err, ErrFetch) || !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected the size limit to fail the fetch but got %v", err)
+	}
+	if _, err := DecodeURL(context.Background(), "file:///etc/passwd"); !errors.Is(err, ErrFetch) {
+		t.Errorf("Expected ErrFetch for a file URL but got %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := DecodeURL(ctx, server.URL); err == nil {
+		t.Error("Expected an error with a cancelled context")
+	}
+}
@@ -0,0 +1,182 @@
+package drum
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// The General MIDI notes of crash cymbals.
+const (
+	gmCrash1 = 49
+	gmCrash2 = 57
+)
+
+// fillMinVelocity is the velocity the crescendo of a fill starts with.
+const fillMinVelocity = 50
+
+// FillGenerator derives fills from the patterns of a song, so that
+// arrangements breathe without writing every fill by hand: a snare roll at
+// the end of every Nth bar of a section, twice as long at the end of the
+// section, and a crash on the downbeat of the bar after a fill. Variations
+// are made once per pattern and cached. All methods are safe for
+// concurrent use.
+type FillGenerator struct {
+	every, steps int
+	seed         int64
+
+	mu    sync.Mutex
+	cache map[fillKey]*Pattern
+}
+
+type fillKey struct {
+	p     *Pattern
+	steps int // 0 for no fill
+	crash bool
+}
+
+// FillOption configures a FillGenerator.
+type FillOption func(*FillGenerator)
+
+// WithFillEvery plays a fill in every nth bar of a section, 4 by default.
+func WithFillEvery(n int) FillOption {
+	return func(g *FillGenerator) {
+		g.every = max(n, 1)
+	}
+}
+
+// WithFillSteps sets the steps of a fill at the end of a bar, 4 by default.
+// Fills ending a section are twice as long, at most a bar.
+func WithFillSteps(n int) FillOption {
+	return func(g *FillGenerator) {
+		g.steps = max(n, 1)
+	}
+}
+
+// WithFillSeed seeds the random variation of the rolls, so that the same
+// seed gives the same fills.
+func WithFillSeed(seed int64) FillOption {
+	return func(g *FillGenerator) {
+		g.seed = seed
+	}
+}
+
+// NewFillGenerator returns a generator of fills, see WithFills to play
+// songs with them.
+func NewFillGenerator(opts ...FillOption) *FillGenerator {
+	g := &FillGenerator{every: 4, steps: 4, cache: make(map[fillKey]*Pattern)}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Variation returns the pattern to play in the bar of a section of bars
+// bars with the pattern p, counted from 0, and whether it is a fill. A fill
+// is played in every nth bar and in the last bar of sections of at least n
+// bars. The bar after a fill, as told by afterFill, starts with a crash.
+// Other bars play p itself.
+func (g *FillGenerator) Variation(p *Pattern, bar, bars int, afterFill bool) (*Pattern, bool) {
+	steps := 0
+	switch {
+	case bars < g.every:
+	case bar == bars-1:
+		steps = 2 * g.steps
+	case (bar+1)%g.every == 0:
+		steps = g.steps
+	}
+	if steps == 0 && !afterFill {
+		return p, false
+	}
+	key := fillKey{p, steps, afterFill}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	v, ok := g.cache[key]
+	if !ok {
+		v = p
+		if steps > 0 {
+			v = g.Fill(v, steps)
+		}
+		if afterFill {
+			v = Crash(v)
+		}
+		g.cache[key] = v
+	}
+	return v, steps > 0
+}
+
+// Fill returns a copy of the pattern ending every bar with a snare roll of
+// the steps: the last half of them played on every step, the first half on
+// every second step and some in between, with velocities rising to the
+// end. The first track of RoleSnare plays the roll, or of RoleTom for
+// patterns without snare; hats are left out under the roll to make room.
+// Patterns with neither get no roll.
+func (g *FillGenerator) Fill(p *Pattern, steps int) *Pattern {
+	f := p.Clone()
+	bar := f.BarSteps()
+	steps = min(steps, bar)
+	from := bar - steps
+	rolls := f.TracksByRole(RoleSnare)
+	if len(rolls) == 0 {
+		rolls = f.TracksByRole(RoleTom)
+	}
+	if len(rolls) == 0 {
+		return f
+	}
+	rng := rand.New(rand.NewSource(g.seed + int64(steps)))
+	t := rolls[0]
+	for s := from; s < f.trackLength(t); s++ {
+		i := s - from
+		on := i >= steps/2 || i%2 == 0 || rng.Intn(3) == 0
+		for b := 0; b < f.Bars(); b++ {
+			t.barSteps(b)[s] = on
+		}
+		if on {
+			t.SetVelocity(s, uint8(fillMinVelocity+(MaxVelocity-fillMinVelocity)*(i+1)/steps))
+			t.probability[s], t.condition[s] = 0, 0
+		}
+	}
+	for _, h := range f.TracksByRole(RoleHat) {
+		if isCrash(h) {
+			continue
+		}
+		for s := from; s < f.trackLength(h); s++ {
+			for b := 0; b < f.Bars(); b++ {
+				h.barSteps(b)[s] = false
+			}
+		}
+	}
+	return f
+}
+
+// Crash returns a copy of the pattern with a crash on the first step of
+// every bar, played by the crash track of the pattern or a new track
+// "crash" added with the next free id.
+func Crash(p *Pattern) *Pattern {
+	c := p.Clone()
+	var crash *Track
+	id := uint32(0)
+	for _, t := range c.tracks {
+		if crash == nil && isCrash(t) {
+			crash = t
+		}
+		if t.id >= id {
+			id = t.id + 1
+		}
+	}
+	if crash == nil {
+		crash = &Track{id: id, name: "crash"}
+		c.tracks = append(c.tracks, crash)
+	}
+	for b := 0; b < c.Bars(); b++ {
+		crash.barSteps(b)[0] = true
+	}
+	crash.SetVelocity(0, MaxVelocity)
+	crash.probability[0], crash.condition[0] = 0, 0
+	return c
+}
+
+// isCrash reports whether the track plays a crash cymbal by its name.
+func isCrash(t *Track) bool {
+	note, ok := ResolveGMNote(t.name)
+	return ok && (note == gmCrash1 || note == gmCrash2)
+}
@@ -0,0 +1,100 @@
+package drum
+
+import (
+	"fmt"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestFillVariation(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewFillGenerator(WithFillEvery(2), WithFillSeed(1))
+	var got []string
+	afterFill := false
+	for bar := 0; bar < 4; bar++ {
+		v, fill := g.Variation(p, bar, 4, afterFill)
+		switch {
+		case fill && afterFill:
+			got = append(got, "fill+crash")
+		case fill:
+			got = append(got, "fill")
+		case afterFill:
+			got = append(got, "crash")
+		case v == p:
+			got = append(got, "base")
+		}
+		afterFill = fill
+	}
+	if exp := "[base fill crash fill]"; fmt.Sprint(got) != exp {
+		t.Errorf("Expected %v but got %v", exp, got)
+	}
+	if v, fill := g.Variation(p, 0, 1, false); v != p || fill {
+		t.Error("Expected no fill in sections shorter than every")
+	}
+	if a, _ := g.Variation(p, 1, 4, false); a != g.cache[fillKey{p, 4, false}] {
+		t.Error("Expected the variation cached")
+	}
+
+	fill := g.Fill(p, 8)
+	snare := fill.TracksByRole(RoleSnare)[0]
+	if !snare.steps[8] || !snare.steps[12] || !snare.steps[13] || !snare.steps[15] {
+		t.Errorf("Expected a snare roll but got %v", snare.steps)
+	}
+	if snare.Velocity(8) >= snare.Velocity(15) || snare.Velocity(15) != MaxVelocity {
+		t.Errorf("Expected a crescendo but got %d to %d", snare.Velocity(8), snare.Velocity(15))
+	}
+	for _, h := range fill.TracksByRole(RoleHat) {
+		for s := 8; s < stepsLength; s++ {
+			if h.steps[s] {
+				t.Errorf("Expected no hats under the roll but got %v", h.steps)
+			}
+		}
+	}
+	if p.TracksByRole(RoleSnare)[0].steps[13] {
+		t.Error("Expected the pattern unchanged")
+	}
+
+	crash := Crash(p)
+	last := crash.tracks[len(crash.tracks)-1]
+	if len(crash.tracks) != len(p.tracks)+1 || last.name != "crash" || last.id != 6 || !last.steps[0] {
+		t.Errorf("Expected a new crash track but got %v", last)
+	}
+	if again := Crash(crash); len(again.tracks) != len(crash.tracks) {
+		t.Error("Expected the crash track reused")
+	}
+}
+
+func TestPlayerFills(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	song := &Song{Sections: []Section{{Pattern: p, Repeat: 2}, {Pattern: p, Repeat: 1}}}
+	pl := NewSongPlayer(song, nil, WithFills(NewFillGenerator(WithFillEvery(2))))
+	crashes := 0
+	var rolls []int
+	for i := 0; i < 3*stepsLength; i++ {
+		ev, _, err := pl.advance(time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tr := range ev.Tracks {
+			if tr.name == "crash" {
+				crashes++
+				if i != 2*stepsLength {
+					t.Errorf("Expected the crash on the downbeat after the fill but got step %d", i)
+				}
+			}
+			if tr.Role() == RoleSnare && ev.Step == 15 {
+				rolls = append(rolls, i/stepsLength)
+			}
+		}
+	}
+	if crashes != 1 || fmt.Sprint(rolls) != "[1]" {
+		t.Errorf("Expected 1 crash and a fill in bar 1 but got %d and %v", crashes, rolls)
+	}
+}
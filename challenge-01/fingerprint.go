@@ -0,0 +1,106 @@
+package drum
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"sort"
+)
+
+// Fingerprint returns a hex encoded SHA-256 of the musical content of the
+// pattern: tempo, swing and the tracks with their steps, velocities and
+// timing. The version, display metadata and unknown data are not included,
+// so the same groove saved by different tools has the same fingerprint. See
+// Hash for a digest that also ignores the arrangement of the tracks.
+func (p *Pattern) Fingerprint() string {
+	h := sha256.New()
+	binary.Write(h, binary.LittleEndian, math.Float32bits(p.tempo))
+	h.Write([]byte{p.swing})
+	for _, t := range p.tracks {
+		binary.Write(h, binary.LittleEndian, t.id)
+		binary.Write(h, binary.LittleEndian, uint32(len(t.name)))
+		h.Write([]byte(t.name))
+		for _, enabled := range t.steps {
+			if enabled {
+				h.Write([]byte{1})
+			} else {
+				h.Write([]byte{0})
+			}
+		}
+		for i := range t.velocity {
+			h.Write([]byte{t.Velocity(i)})
+		}
+		binary.Write(h, binary.LittleEndian, t.timing)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// canonicalVersion is the version of the canonical serialization. It is
+// part of the hashed data, so a change of the rules changes every hash.
+const canonicalVersion = 1
+
+// Hash returns a hex encoded SHA-256 of the canonical serialization of the
+// musical content of the pattern. Unlike Fingerprint it identifies a groove
+// regardless of how a tool arranged it:
+//
+//   - the version, display metadata, metadata, mute and solo states and
+//     data unknown to this package are ignored
+//   - the tempo is rounded to DefaultTempoTolerance
+//   - tracks are identified by their name ignoring case, white space, '-'
+//     and '_', the track ids are ignored
+//   - tracks without any enabled step are ignored
+//   - velocity and timing are only included for enabled steps
+//   - the order of the tracks is ignored
+//
+// The serialization starts with a version that changes with the rules, see
+// canonical for the layout.
+func (p *Pattern) Hash() string {
+	sum := sha256.Sum256(p.canonical())
+	return hex.EncodeToString(sum[:])
+}
+
+// canonical returns the canonical serialization hashed by Hash:
+//
+//	|SPLH (4 bytes)|Version (1 byte)|Tempo in 1/1000 BPM (4 bytes)|Swing (1 byte)|Track count (2 bytes)|
+//	|Name length (1 byte)|Name|Steps bit mask (2 bytes)|Velocity and timing per enabled step (2 bytes each)|Volume (1 byte)|Pan (1 byte)|
+//	...
+//
+// with the fields little endian and the tracks sorted by their bytes.
+func (p *Pattern) canonical() []byte {
+	var tracks [][]byte
+	for _, t := range p.tracks {
+		var mask uint16
+		for i, enabled := range t.steps {
+			if enabled {
+				mask |= 1 << uint(i)
+			}
+		}
+		if mask == 0 {
+			continue
+		}
+		var b bytes.Buffer
+		writeShortString(&b, normalizeName(t.name))
+		binary.Write(&b, binary.LittleEndian, mask)
+		for i, enabled := range t.steps {
+			if enabled {
+				b.Write([]byte{t.Velocity(i), byte(t.timing[i])})
+			}
+		}
+		b.Write([]byte{t.Volume(), byte(t.pan)})
+		tracks = append(tracks, b.Bytes())
+	}
+	sort.Slice(tracks, func(i, j int) bool { return bytes.Compare(tracks[i], tracks[j]) < 0 })
+
+	var b bytes.Buffer
+	b.WriteString("SPLH")
+	b.WriteByte(canonicalVersion)
+	binary.Write(&b, binary.LittleEndian, int32(math.Round(tempo64(p.tempo)/DefaultTempoTolerance)))
+	b.WriteByte(p.swing)
+	binary.Write(&b, binary.LittleEndian, uint16(len(tracks)))
+	for _, t := range tracks {
+		b.Write(t)
+	}
+	return b.Bytes()
+}
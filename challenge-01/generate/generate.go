@@ -0,0 +1,218 @@
+// Package generate creates new drum patterns in the style of a collection of
+// patterns.
+//
+// A Model learns for every instrument how likely each step is played
+// depending on whether the step before it was played, a Markov chain whose
+// transitions depend on the position in the bar. Tracks are matched across
+// patterns by their General MIDI drum, so "BD" and "Kick" are the same
+// instrument, and by their name when it does not name a drum.
+package generate
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// Version is the version string of sampled patterns.
+const Version = "generated"
+
+// stepsLength is the number of steps of a track.
+const stepsLength = len(drum.Steps{})
+
+// Model holds the step statistics learned from patterns, see Train. The zero
+// value is an empty model.
+type Model struct {
+	patterns int
+	tempos   []float32
+	voices   map[string]*voice
+}
+
+// voice is the statistics of an instrument.
+type voice struct {
+	key      string
+	patterns int                    // patterns with the instrument
+	names    map[string]int         // track names with their count
+	ids      map[uint32]int         // track ids with their count
+	first    [2]int                 // patterns with the first step off and on
+	next     [stepsLength][2][2]int // transitions into a step by the previous and the step state
+	hits     [stepsLength]int
+	velocity [stepsLength]int // sum of the velocities of the hits
+}
+
+// Train returns a model trained on the patterns.
+func Train(patterns ...*drum.Pattern) *Model {
+	m := &Model{}
+	for _, p := range patterns {
+		m.Add(p)
+	}
+	return m
+}
+
+// Add trains the model on another pattern. Steps beyond the bar of the
+// pattern are ignored.
+func (m *Model) Add(p *drum.Pattern) {
+	if m.voices == nil {
+		m.voices = make(map[string]*voice)
+	}
+	m.patterns++
+	m.tempos = append(m.tempos, p.Tempo())
+	bar := p.BarSteps()
+	seen := make(map[string]bool)
+	for _, t := range p.Tracks() {
+		key := voiceKey(t.Name())
+		v := m.voices[key]
+		if v == nil {
+			v = &voice{key: key, names: make(map[string]int), ids: make(map[uint32]int)}
+			m.voices[key] = v
+		}
+		if !seen[key] {
+			seen[key] = true
+			v.patterns++
+		}
+		v.names[t.Name()]++
+		v.ids[t.ID()]++
+		steps := t.Steps()
+		for i := 0; i < bar; i++ {
+			on := state(steps[i])
+			if i == 0 {
+				v.first[on]++
+			} else {
+				v.next[i][state(steps[i-1])][on]++
+			}
+			if steps[i] {
+				v.hits[i]++
+				v.velocity[i] += int(t.Velocity(i))
+			}
+		}
+	}
+}
+
+// Patterns returns the number of patterns the model was trained on.
+func (m *Model) Patterns() int {
+	return m.patterns
+}
+
+// Sample returns a new pattern in the style of the trained patterns. Every
+// instrument is included as often as it occurs in them, at least one is
+// always included. The tempo is the one of a random trained pattern. The
+// result only depends on the state of rng. Sampling an untrained model
+// fails.
+func (m *Model) Sample(rng *rand.Rand) (*drum.Pattern, error) {
+	if m.patterns == 0 {
+		return nil, fmt.Errorf("generate: model is not trained")
+	}
+	voices := m.sortedVoices()
+	var chosen []*voice
+	for _, v := range voices {
+		if rng.Intn(m.patterns) < v.patterns {
+			chosen = append(chosen, v)
+		}
+	}
+	if len(chosen) == 0 {
+		chosen = voices[:1]
+	}
+	used := make(map[uint32]bool)
+	var tracks []*drum.Track
+	for _, v := range chosen {
+		id := mostCommon(v.ids)
+		for used[id] {
+			id++
+		}
+		used[id] = true
+		t, err := drum.NewTrack(id, mostCommonName(v.names), v.sample(rng))
+		if err != nil {
+			return nil, fmt.Errorf("generate: %v", err)
+		}
+		for i, enabled := range t.Steps() {
+			if enabled && v.hits[i] > 0 {
+				t.SetVelocity(i, uint8(v.velocity[i]/v.hits[i]))
+			}
+		}
+		tracks = append(tracks, t)
+	}
+	p, err := drum.NewPattern(Version, m.tempos[rng.Intn(len(m.tempos))], tracks...)
+	if err != nil {
+		return nil, fmt.Errorf("generate: %v", err)
+	}
+	return p, nil
+}
+
+// sample walks the chain of the voice. Steps without a transition seen
+// from the previous state are played as often as they were played at all.
+func (v *voice) sample(rng *rand.Rand) drum.Steps {
+	var steps drum.Steps
+	steps[0] = chance(rng, v.first[1], v.first[0]+v.first[1])
+	for i := 1; i < stepsLength; i++ {
+		n := v.next[i][state(steps[i-1])]
+		if n[0]+n[1] > 0 {
+			steps[i] = chance(rng, n[1], n[0]+n[1])
+		} else {
+			steps[i] = chance(rng, v.hits[i], v.first[0]+v.first[1])
+		}
+	}
+	return steps
+}
+
+// sortedVoices returns the voices, the most common first.
+func (m *Model) sortedVoices() []*voice {
+	voices := make([]*voice, 0, len(m.voices))
+	for _, v := range m.voices {
+		voices = append(voices, v)
+	}
+	sort.Slice(voices, func(i, j int) bool {
+		a, b := voices[i], voices[j]
+		if a.patterns != b.patterns {
+			return a.patterns > b.patterns
+		}
+		return a.key < b.key
+	})
+	return voices
+}
+
+// voiceKey returns the instrument a track name stands for.
+func voiceKey(name string) string {
+	if note, ok := drum.ResolveGMNote(name); ok {
+		return fmt.Sprintf("gm%d", note)
+	}
+	return strings.ToLower(name)
+}
+
+func state(on bool) int {
+	if on {
+		return 1
+	}
+	return 0
+}
+
+// chance returns true with the probability n out of total.
+func chance(rng *rand.Rand, n, total int) bool {
+	return total > 0 && rng.Intn(total) < n
+}
+
+// mostCommon returns the id counted most, the lowest of ties.
+func mostCommon(counts map[uint32]int) uint32 {
+	var best uint32
+	max := -1
+	for id, n := range counts {
+		if n > max || n == max && id < best {
+			best, max = id, n
+		}
+	}
+	return best
+}
+
+// mostCommonName returns the name counted most, the first in order of ties.
+func mostCommonName(counts map[string]int) string {
+	var best string
+	max := -1
+	for name, n := range counts {
+		if n > max || n == max && name < best {
+			best, max = name, n
+		}
+	}
+	return best
+}
@@ -0,0 +1,71 @@
+package generate
+
+import (
+	"math/rand"
+	"path"
+	"testing"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+func fixtures(t *testing.T, names ...string) []*drum.Pattern {
+	var ps []*drum.Pattern
+	for _, name := range names {
+		p, err := drum.DecodeFile(path.Join("..", "fixtures", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+func TestSampleSingle(t *testing.T) {
+	p := fixtures(t, "pattern_1.splice")[0]
+	p.Tracks()[0].SetVelocity(4, 90)
+	got, err := Train(p).Sample(rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version() != Version || got.Tempo() != p.Tempo() || len(got.Tracks()) != len(p.Tracks()) {
+		t.Fatalf("unexpected pattern:\n%v", got)
+	}
+	// a single pattern has one transition per step to learn
+	for _, exp := range p.Tracks() {
+		tr := got.TrackByID(exp.ID())
+		if tr == nil || tr.Name() != exp.Name() || tr.Steps() != exp.Steps() {
+			t.Errorf("Expected track %v but got %v", exp, tr)
+		}
+	}
+	if v := got.TrackByID(0).Velocity(4); v != 90 {
+		t.Errorf("Expected the learned velocity but got %d", v)
+	}
+}
+
+func TestSample(t *testing.T) {
+	m := Train(fixtures(t, "pattern_1.splice", "pattern_2.splice", "pattern_3.splice", "pattern_4.splice")...)
+	if m.Patterns() != 4 {
+		t.Fatalf("Expected 4 patterns but got %d", m.Patterns())
+	}
+	a, err := m.Sample(rand.New(rand.NewSource(7)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := m.Sample(rand.New(rand.NewSource(7)))
+	if !a.Equal(b) {
+		t.Errorf("Expected the same pattern for the same seed:\n%v\n%v", a, b)
+	}
+	ids := make(map[uint32]bool)
+	for _, tr := range a.Tracks() {
+		if ids[tr.ID()] {
+			t.Errorf("duplicate id %d", tr.ID())
+		}
+		ids[tr.ID()] = true
+		if _, ok := m.voices[voiceKey(tr.Name())]; !ok {
+			t.Errorf("unexpected track %q", tr.Name())
+		}
+	}
+	if _, err := (&Model{}).Sample(rand.New(rand.NewSource(1))); err == nil {
+		t.Error("Expected an error for an untrained model")
+	}
+}
@@ -0,0 +1,107 @@
+package drum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+)
+
+// Generator creates variations of a pattern, for example by asking an
+// external groove generation model. Implementations for other transports
+// like gRPC only have to satisfy this interface.
+type Generator interface {
+	Generate(ctx context.Context, p *Pattern) (*Pattern, error)
+}
+
+// GeneratorFunc adapts a function to the Generator interface.
+type GeneratorFunc func(ctx context.Context, p *Pattern) (*Pattern, error)
+
+// Generate calls f(ctx, p).
+func (f GeneratorFunc) Generate(ctx context.Context, p *Pattern) (*Pattern, error) {
+	return f(ctx, p)
+}
+
+// maxGeneratorResponse limits the size of a response read by HTTPGenerator.
+const maxGeneratorResponse = 1 << 20
+
+// HTTPGenerator asks a HTTP service for a variation of a pattern. The pattern
+// is posted as Web MIDI JSON and the response is expected in the same
+// structure. The response is clamped into a legal pattern, see
+// WebMIDIPattern.Pattern.
+type HTTPGenerator struct {
+	URL    string
+	Client *http.Client // http.DefaultClient when nil
+}
+
+// Generate implements Generator. It fails with ErrOffline in offline mode.
+func (g *HTTPGenerator) Generate(ctx context.Context, p *Pattern) (*Pattern, error) {
+	if err := AllowNetwork("generator"); err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(p.WebMIDI())
+	if err != nil {
+		return nil, fmt.Errorf("generate: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, g.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("generate: %v", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("generate: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("generate: unexpected status %s", resp.Status)
+	}
+	var w WebMIDIPattern
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxGeneratorResponse)).Decode(&w); err != nil {
+		return nil, fmt.Errorf("generate: parse response: %v", err)
+	}
+	v := w.Pattern()
+	v.version, v.swing = p.version, p.swing
+	return v, nil
+}
+
+// Pattern converts the Web MIDI structure into a pattern. Values out of
+// range are clamped so that the result is always legal: the tempo to
+// MinTempo and MaxTempo, velocities to MaxVelocity, names to 255 bytes and
+// steps to the 16 steps of a bar. Missing steps are off.
+func (w WebMIDIPattern) Pattern() *Pattern {
+	tempo := math.Max(MinTempo, math.Min(w.BPM, MaxTempo))
+	if math.IsNaN(tempo) {
+		tempo = MinTempo
+	}
+	p := &Pattern{tempo: float32(tempo)}
+	for _, wt := range w.Tracks {
+		name := wt.Name
+		if len(name) > math.MaxUint8 {
+			name = name[:math.MaxUint8]
+		}
+		t := &Track{id: wt.ID, name: name}
+		for step, v := range wt.Steps {
+			if step >= stepsLength {
+				break
+			}
+			if v <= 0 {
+				continue
+			}
+			t.steps[step] = true
+			if v < MaxVelocity {
+				t.velocity[step] = uint8(v)
+			}
+		}
+		p.tracks = append(p.tracks, t)
+	}
+	return p
+}
@@ -0,0 +1,56 @@
+package drum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+)
+
+func TestHTTPGenerator(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in WebMIDIPattern
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			t.Error(err)
+		}
+		// an out of range reply that must be clamped
+		in.BPM = 5000
+		in.Tracks[0].Steps = append([]int{300, -1}, make([]int, 20)...)
+		json.NewEncoder(w).Encode(in)
+	}))
+	defer server.Close()
+
+	g := &HTTPGenerator{URL: server.URL}
+	v, err := g.Generate(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.version != p.version || v.tempo != MaxTempo || len(v.tracks) != len(p.tracks) {
+		t.Errorf("unexpected variation:\n%v", v)
+	}
+	kick := v.tracks[0]
+	if kick.Steps() != (Steps{true}) || kick.Velocity(0) != MaxVelocity {
+		t.Errorf("steps not clamped: %v", kick.steps)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, v); err != nil {
+		t.Errorf("variation is not legal: %v", err)
+	}
+}
+
+func TestHTTPGeneratorOffline(t *testing.T) {
+	SetOffline(true)
+	defer SetOffline(false)
+	g := &HTTPGenerator{URL: "http://example.com"}
+	if _, err := g.Generate(context.Background(), &Pattern{}); !errors.Is(err, ErrOffline) {
+		t.Errorf("expected offline error but got %v", err)
+	}
+}
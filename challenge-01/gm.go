@@ -0,0 +1,210 @@
+package drum
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// fallbackGMNote is used for tracks whose name is not recognized. It is the
+// General MIDI claves note which stands out in most kits.
+const fallbackGMNote = 75
+
+// gmNames are the names of the General MIDI percussion notes.
+var gmNames = map[uint8]string{
+	35: "Acoustic Bass Drum", 36: "Bass Drum 1", 37: "Side Stick", 38: "Acoustic Snare",
+	39: "Hand Clap", 40: "Electric Snare", 41: "Low Floor Tom", 42: "Closed Hi-Hat",
+	43: "High Floor Tom", 44: "Pedal Hi-Hat", 45: "Low Tom", 46: "Open Hi-Hat",
+	47: "Low-Mid Tom", 48: "Hi-Mid Tom", 49: "Crash Cymbal 1", 50: "High Tom",
+	51: "Ride Cymbal 1", 52: "Chinese Cymbal", 53: "Ride Bell", 54: "Tambourine",
+	55: "Splash Cymbal", 56: "Cowbell", 57: "Crash Cymbal 2", 58: "Vibraslap",
+	59: "Ride Cymbal 2", 60: "Hi Bongo", 61: "Low Bongo", 62: "Mute Hi Conga",
+	63: "Open Hi Conga", 64: "Low Conga", 65: "High Timbale", 66: "Low Timbale",
+	67: "High Agogo", 68: "Low Agogo", 69: "Cabasa", 70: "Maracas",
+	71: "Short Whistle", 72: "Long Whistle", 73: "Short Guiro", 74: "Long Guiro",
+	75: "Claves", 76: "Hi Wood Block", 77: "Low Wood Block", 78: "Mute Cuica",
+	79: "Open Cuica", 80: "Mute Triangle", 81: "Open Triangle",
+}
+
+// gmAbbreviations map common short names to General MIDI percussion notes.
+var gmAbbreviations = map[string]uint8{
+	"bd": 36, "sd": 38, "hh": 42, "ch": 42, "oh": 46, "cp": 39, "rs": 37,
+	"lt": 45, "mt": 47, "ht": 50, "cb": 56, "cy": 49, "rc": 51,
+}
+
+// gmKeywords are matched against the normalized track name in order, so more
+// specific keywords must come first.
+var gmKeywords = []struct {
+	keyword string
+	note    uint8
+}{
+	{"lowconga", 64}, {"conga", 63},
+	{"open", 46}, {"closed", 42}, {"close", 42}, {"hihat", 42}, {"hat", 42},
+	{"kick", 36}, {"bass", 36}, {"snare", 38}, {"clap", 39}, {"rim", 37},
+	{"lowtom", 45}, {"floortom", 41}, {"midtom", 47}, {"hitom", 50}, {"hightom", 50}, {"tom", 47},
+	{"cowbell", 56}, {"crash", 49}, {"ride", 51}, {"cymbal", 49},
+	{"maracas", 70}, {"shaker", 70}, {"tamb", 54}, {"clave", 75},
+	{"click", 76}, {"metronome", 76}, {"woodblock", 76},
+}
+
+// GMMap maps track names to General MIDI percussion notes. Names are
+// compared case insensitively and without separators, so "Hi-Hat" and
+// "hihat" are the same. A name is looked up in the aliases, the General
+// MIDI note names, the built in abbreviations like "bd" or "oh" and then
+// searched for keywords like "kick" or "open". Finally misspelt names and
+// words of names, like "snar drum", match the closest alias, note name or
+// keyword with one typo, two for longer words. A GMMap is safe for
+// concurrent use.
+type GMMap struct {
+	mu      sync.RWMutex
+	aliases map[string]uint8
+}
+
+// DefaultGMMap is the map used by the exporters and kits, aliases added to
+// it apply to all of them.
+var DefaultGMMap = NewGMMap()
+
+// NewGMMap returns a map without aliases.
+func NewGMMap() *GMMap {
+	return &GMMap{aliases: make(map[string]uint8)}
+}
+
+// Alias makes tracks named name map to the note, in front of all built in
+// names.
+func (m *GMMap) Alias(name string, note uint8) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.aliases[normalizeName(name)] = note
+}
+
+// Resolve returns the General MIDI percussion note for a track name. When
+// the name is not recognized, it returns the claves note, which stands out
+// in most kits, and false.
+func (m *GMMap) Resolve(name string) (uint8, bool) {
+	key := normalizeName(name)
+	m.mu.RLock()
+	note, ok := m.aliases[key]
+	m.mu.RUnlock()
+	if ok {
+		return note, true
+	}
+	if note, ok := gmNotesByName[key]; ok {
+		return note, true
+	}
+	if note, ok := gmAbbreviations[key]; ok {
+		return note, true
+	}
+	for _, k := range gmKeywords {
+		if strings.Contains(key, k.keyword) {
+			return k.note, true
+		}
+	}
+	return m.closest(name)
+}
+
+// closest returns the note of the name closest to the name or one of its
+// words within the allowed number of typos.
+func (m *GMMap) closest(name string) (uint8, bool) {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '_' || unicode.IsSpace(r)
+	})
+	words = append(words, name)
+	m.mu.RLock()
+	candidates := make(map[string]uint8, len(m.aliases)+len(gmNotesByName)+len(gmKeywords))
+	for alias, note := range m.aliases {
+		candidates[alias] = note
+	}
+	m.mu.RUnlock()
+	for key, note := range gmNotesByName {
+		if _, ok := candidates[key]; !ok {
+			candidates[key] = note
+		}
+	}
+	for _, k := range gmKeywords {
+		if _, ok := candidates[k.keyword]; !ok {
+			candidates[k.keyword] = k.note
+		}
+	}
+	// sorted for a deterministic choice between candidates as close
+	keys := make([]string, 0, len(candidates))
+	for key := range candidates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	best, bestDistance := uint8(fallbackGMNote), -1
+	for _, word := range words {
+		word = normalizeName(word)
+		allowed := 1
+		if len(word) < 4 {
+			continue
+		} else if len(word) >= 7 {
+			allowed = 2
+		}
+		for _, key := range keys {
+			d := editDistance(word, key)
+			if d <= allowed && (bestDistance < 0 || d < bestDistance) {
+				best, bestDistance = candidates[key], d
+			}
+		}
+	}
+	return best, bestDistance >= 0
+}
+
+// Aliases returns the aliases of the note in alphabetical order.
+func (m *GMMap) Aliases(note uint8) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var names []string
+	for name, n := range m.aliases {
+		if n == note {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveGMNote returns the General MIDI percussion note for a track name
+// with the DefaultGMMap.
+func ResolveGMNote(name string) (uint8, bool) {
+	return DefaultGMMap.Resolve(name)
+}
+
+// GMNoteName returns the General MIDI name of a percussion note like
+// "Open Hi-Hat" for 46.
+func GMNoteName(note uint8) (string, bool) {
+	name, ok := gmNames[note]
+	return name, ok
+}
+
+// gmNotesByName maps the normalized General MIDI names to their notes.
+var gmNotesByName = func() map[string]uint8 {
+	notes := make(map[string]uint8, len(gmNames))
+	for note, name := range gmNames {
+		notes[normalizeName(name)] = note
+	}
+	return notes
+}()
+
+// editDistance returns the number of inserted, deleted or replaced bytes
+// between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,52 @@
+package drum
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveGMNote(t *testing.T) {
+	testCases := []struct {
+		name string
+		note uint8
+		ok   bool
+	}{
+		{"hh open", 46, true}, {"open hat", 46, true}, {"OH", 46, true},
+		{"Pedal Hi-Hat", 44, true}, {"side stick", 37, true}, {"Vibraslap", 58, true},
+		{"snar", 38, true}, {"Snar Drum", 38, true}, {"crsh", 49, true}, {"tambourin", 54, true},
+		{"kik", fallbackGMNote, false}, {"laser", fallbackGMNote, false},
+	}
+	for _, testCase := range testCases {
+		note, ok := ResolveGMNote(testCase.name)
+		if note != testCase.note || ok != testCase.ok {
+			t.Errorf("Expected %v %v but got %v %v for %q", testCase.note, testCase.ok, note, ok, testCase.name)
+		}
+	}
+}
+
+func TestGMMapAlias(t *testing.T) {
+	m := NewGMMap()
+	m.Alias("Laser", 81)
+	m.Alias("zap", 81)
+	m.Alias("kick", 35)
+	for name, exp := range map[string]uint8{"laser": 81, "lazer": 81, "Kick": 35, "snare": 38} {
+		if note, ok := m.Resolve(name); !ok || note != exp {
+			t.Errorf("Expected %d for %q but got %d %v", exp, name, note, ok)
+		}
+	}
+	if got := m.Aliases(81); !reflect.DeepEqual(got, []string{"laser", "zap"}) {
+		t.Errorf("unexpected aliases %v", got)
+	}
+	if note, _ := ResolveGMNote("laser"); note != fallbackGMNote {
+		t.Error("Expected the default map to be unchanged")
+	}
+}
+
+func TestGMNoteName(t *testing.T) {
+	if name, ok := GMNoteName(46); !ok || name != "Open Hi-Hat" {
+		t.Errorf("unexpected name %q", name)
+	}
+	if _, ok := GMNoteName(20); ok {
+		t.Error("Expected no name outside of the percussion notes")
+	}
+}
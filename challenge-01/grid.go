@@ -0,0 +1,227 @@
+package drum
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// GridStyle configures the graphical renderings of the step grid. Colors
+// are "#rrggbb" hex values. Zero fields take the value of DefaultGridStyle.
+type GridStyle struct {
+	CellSize   int    // width and height of a step in pixels
+	Enabled    string // enabled steps of tracks without display color
+	Disabled   string // disabled steps
+	Beat       string // disabled steps on the first step of a beat
+	Background string
+	Text       string
+	// Resolution is the number of cells a bar is drawn with, Sixteenth by
+	// default. Bars after the first are drawn below the first one.
+	Resolution Resolution
+}
+
+// DefaultGridStyle is a light grid with orange steps.
+var DefaultGridStyle = GridStyle{
+	CellSize:   24,
+	Enabled:    "#f28c28",
+	Disabled:   "#e6e6e6",
+	Beat:       "#cccccc",
+	Background: "#ffffff",
+	Text:       "#222222",
+}
+
+// withDefaults returns the style with all zero fields set to the default.
+func (s GridStyle) withDefaults() GridStyle {
+	d := DefaultGridStyle
+	if s.CellSize <= 0 {
+		s.CellSize = d.CellSize
+	}
+	s.Enabled = orDefault(s.Enabled, d.Enabled)
+	s.Disabled = orDefault(s.Disabled, d.Disabled)
+	s.Beat = orDefault(s.Beat, d.Beat)
+	s.Background = orDefault(s.Background, d.Background)
+	s.Text = orDefault(s.Text, d.Text)
+	s.Resolution = s.Resolution.orDefault()
+	return s
+}
+
+func orDefault(v, d string) string {
+	if v == "" {
+		return d
+	}
+	return v
+}
+
+// cellColor returns the color of a cell of the track. The display color of
+// the track is preferred for enabled cells.
+func (s GridStyle) cellColor(t *Track, enabled, beat bool) string {
+	switch {
+	case enabled && t.display.Color != "":
+		return t.display.Color
+	case enabled:
+		return s.Enabled
+	case beat:
+		return s.Beat
+	default:
+		return s.Disabled
+	}
+}
+
+// gridRow is a row of the graphical renderings, a bar of a track.
+type gridRow struct {
+	track *Track
+	bar   int
+	cells []bool
+}
+
+// gridRows returns the rows of the tracks in the resolution with the bars
+// of every track below each other.
+func (p *Pattern) gridRows(r Resolution) []gridRow {
+	var rows []gridRow
+	for _, t := range p.tracks {
+		for bar, cells := range p.ZoomBars(t, r) {
+			rows = append(rows, gridRow{t, bar, cells})
+		}
+	}
+	return rows
+}
+
+// gridLayout holds the pixel positions shared by the graphical renderings.
+type gridLayout struct {
+	cell, gap, label, header int
+	block                    int // cells between the extra gaps
+	columns, rows            int
+	width, height            int
+}
+
+func newGridLayout(p *Pattern, style GridStyle) gridLayout {
+	cell := style.CellSize
+	l := gridLayout{cell: cell, gap: cell / 8, header: cell * 3 / 2}
+	l.block = max(1, p.timeSig.groupSteps()*int(style.Resolution)/stepsLength)
+	longest := 0
+	for _, t := range p.tracks {
+		n := len(fmt.Sprintf("(%v) %v", t.id, t.name))
+		for _, lb := range t.Labels() {
+			n += len(lb.Name) + 2
+		}
+		if n > longest {
+			longest = n
+		}
+	}
+	// a rough estimate of the text width
+	l.label = longest*cell*3/8 + cell
+	if p.Bars() > 1 {
+		// room for the bar letters
+		l.label += cell
+	}
+	for _, r := range p.gridRows(style.Resolution) {
+		l.columns = max(l.columns, len(r.cells))
+		l.rows++
+	}
+	l.resize()
+	return l
+}
+
+// resize sets the size to fit all rows and columns.
+func (l *gridLayout) resize() {
+	l.width = l.x(l.columns) + l.gap
+	l.height = l.y(l.rows) + l.gap
+}
+
+// x returns the left edge of a cell with an extra gap between blocks.
+func (l gridLayout) x(cell int) int {
+	return l.label + cell*(l.cell+l.gap) + cell/l.block*l.gap*2
+}
+
+// y returns the top edge of a row.
+func (l gridLayout) y(row int) int {
+	return l.header + row*(l.cell+l.gap)
+}
+
+// caption is the title line of the graphical renderings.
+func (p *Pattern) caption() string {
+	return fmt.Sprintf("%s – %s BPM", p.version, FormatTempo(p.tempo))
+}
+
+// ToSVG writes the step grid with track labels and tempo as SVG image to w.
+// The bars of a pattern with more than one bar are drawn below each other,
+// labelled with their letters.
+func (p *Pattern) ToSVG(w io.Writer, style GridStyle) error {
+	s := style.withDefaults()
+	l := newGridLayout(p, s)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="%d">`+"\n",
+		l.width, l.height, l.width, l.height, s.CellSize/2)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", s.Background)
+	fmt.Fprintf(bw, `<text x="%d" y="%d" fill="%s">%s%s</text>`+"\n", l.gap, l.header*2/3, s.Text, html.EscapeString(p.caption()), svgLabels(p.labels))
+	for i, r := range p.gridRows(s.Resolution) {
+		y := l.y(i)
+		if r.bar == 0 {
+			fmt.Fprintf(bw, `<text x="%d" y="%d" fill="%s">%s%s</text>`+"\n",
+				l.gap, y+s.CellSize*2/3, s.Text, html.EscapeString(fmt.Sprintf("(%v) %v", r.track.id, r.track.name)), svgLabels(r.track.Labels()))
+		}
+		if p.Bars() > 1 {
+			fmt.Fprintf(bw, `<text x="%d" y="%d" fill="%s">%s</text>`+"\n", l.label-s.CellSize, y+s.CellSize*2/3, s.Text, barName(r.bar))
+		}
+		for c, enabled := range r.cells {
+			fmt.Fprintf(bw, `<rect x="%d" y="%d" width="%d" height="%d" rx="%d" fill="%s"/>`+"\n",
+				l.x(c), y, s.CellSize, s.CellSize, l.gap, s.cellColor(r.track, enabled, c%l.block == 0))
+		}
+	}
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
+
+// ToHTML writes the step grid as HTML table with the version and tempo as
+// caption to w. The styles are inlined so that the table can be embedded
+// into any page.
+func (p *Pattern) ToHTML(w io.Writer, style GridStyle) error {
+	s := style.withDefaults()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<table class="splice-pattern" style="border-collapse:separate;border-spacing:%dpx;background:%s;color:%s">`+"\n",
+		s.CellSize/8, s.Background, s.Text)
+	fmt.Fprintf(bw, "<caption>%s%s</caption>\n", html.EscapeString(p.caption()), htmlLabels(p.labels))
+	for _, t := range p.tracks {
+		fmt.Fprintf(bw, "<tr><th>%s%s</th>", html.EscapeString(fmt.Sprintf("(%v) %v", t.id, t.name)), htmlLabels(t.Labels()))
+		for step, enabled := range t.steps {
+			title := "off"
+			if enabled {
+				title = "on"
+			}
+			fmt.Fprintf(bw, `<td title="%d %s" style="width:%dpx;height:%dpx;background:%s"></td>`,
+				step+1, title, s.CellSize, s.CellSize, s.cellColor(t, enabled, step%blockSize == 0))
+		}
+		bw.WriteString("</tr>\n")
+	}
+	bw.WriteString("</table>\n")
+	return bw.Flush()
+}
+
+// svgLabels returns the labels as text spans like " #live-set" in their
+// colors.
+func svgLabels(labels []Label) string {
+	var b strings.Builder
+	for _, l := range labels {
+		b.WriteString(" <tspan")
+		if l.Color != "" {
+			fmt.Fprintf(&b, ` fill="%s"`, l.Color)
+		}
+		fmt.Fprintf(&b, ">#%s</tspan>", html.EscapeString(l.Name))
+	}
+	return b.String()
+}
+
+// htmlLabels returns the labels as badges in their colors.
+func htmlLabels(labels []Label) string {
+	var b strings.Builder
+	for _, l := range labels {
+		style := "border:1px solid currentColor"
+		if l.Color != "" {
+			style = "background:" + l.Color
+		}
+		fmt.Fprintf(&b, ` <span class="splice-label" style="padding:0 .3em;border-radius:.3em;%s">%s</span>`, style, html.EscapeString(l.Name))
+	}
+	return b.String()
+}
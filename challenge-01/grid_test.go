@@ -0,0 +1,118 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/xml"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestToSVG(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_5.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.tracks[1].SetDisplay(Display{Color: "#00ff00"})
+	p.tracks[1].SetLabels(Label{Name: "fill", Color: "#0000ff"})
+	var buf bytes.Buffer
+	if err := p.ToSVG(&buf, GridStyle{CellSize: 10, Enabled: "#000000"}); err != nil {
+		t.Fatal(err)
+	}
+	var svg struct {
+		Rects []struct {
+			Fill  string `xml:"fill,attr"`
+			Width string `xml:"width,attr"`
+		} `xml:"rect"`
+		Texts []string `xml:"text"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &svg); err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := 1+2*stepsLength, len(svg.Rects); got != exp {
+		t.Fatalf("Expected %d rects but got %d", exp, got)
+	}
+	if r := svg.Rects[1]; r.Fill != "#000000" || r.Width != "10" {
+		t.Errorf("unexpected kick step: %+v", r)
+	}
+	if r := svg.Rects[1+stepsLength]; r.Fill != "#00ff00" {
+		t.Errorf("expected display color but got %+v", r)
+	}
+	if r := svg.Rects[2]; r.Fill != DefaultGridStyle.Disabled {
+		t.Errorf("expected default disabled color but got %+v", r)
+	}
+	if exp := "0.708-alpha – 999 BPM"; len(svg.Texts) != 3 || svg.Texts[0] != exp {
+		t.Errorf("Expected caption '%v' but got %v", exp, svg.Texts)
+	}
+	if exp := `(2) HiHat <tspan fill="#0000ff">#fill</tspan></text>`; !strings.Contains(buf.String(), exp) {
+		t.Errorf("Expected '%v' in:\n%v", exp, buf.String())
+	}
+}
+
+func TestToSVGZoom(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_5.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.AddBar(0)
+	p.tracks[1].SetLength(12)
+	type svg struct {
+		Rects []struct {
+			Fill string `xml:"fill,attr"`
+		} `xml:"rect"`
+		Texts []string `xml:"text"`
+	}
+	for r, exp := range map[Resolution]int{Eighth: 2 * (8 + 6), Sixteenth: 2 * (16 + 12), ThirtySecond: 2 * (32 + 24)} {
+		var svg svg
+		var buf bytes.Buffer
+		if err := p.ToSVG(&buf, GridStyle{Resolution: r}); err != nil {
+			t.Fatal(err)
+		}
+		if err := xml.Unmarshal(buf.Bytes(), &svg); err != nil {
+			t.Fatal(err)
+		}
+		if got := len(svg.Rects) - 1; got != exp {
+			t.Errorf("%v: Expected %d cells but got %d", r, exp, got)
+		}
+		// the caption, the track names and the bar letters of both tracks
+		if got := strings.Join(svg.Texts, ","); !strings.HasSuffix(got, "(1) Kick,A,B,(2) HiHat,A,B") {
+			t.Errorf("%v: Expected the bars labelled but got %v", r, got)
+		}
+	}
+	// the beat color marks the first cell of every beat
+	var buf bytes.Buffer
+	if err := p.ToSVG(&buf, GridStyle{Resolution: Eighth}); err != nil {
+		t.Fatal(err)
+	}
+	var eighth svg
+	if err := xml.Unmarshal(buf.Bytes(), &eighth); err != nil {
+		t.Fatal(err)
+	}
+	if got := eighth.Rects[3].Fill; got != DefaultGridStyle.Beat {
+		t.Errorf("Expected the beat color on cell 2 but got %v", got)
+	}
+}
+
+func TestToHTML(t *testing.T) {
+	p := &Pattern{version: "<v>", tempo: 120, tracks: []*Track{{id: 1, name: "kick", steps: Steps{true}}, {id: 2, name: "snare"}}}
+	p.SetLabels(Label{Name: "live-set", Color: "#ff0000"})
+	p.tracks[1].SetLabels(Label{Name: "<ghost>"})
+	var buf bytes.Buffer
+	if err := p.ToHTML(&buf, GridStyle{}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, exp := range []string{
+		`<caption>&lt;v&gt; – 120 BPM <span class="splice-label" style="padding:0 .3em;border-radius:.3em;background:#ff0000">live-set</span></caption>`,
+		"<th>(1) kick</th>",
+		`;border:1px solid currentColor">&lt;ghost&gt;</span></th>`,
+		`title="1 on"`,
+	} {
+		if !strings.Contains(out, exp) {
+			t.Errorf("Expected '%v' in:\n%v", exp, out)
+		}
+	}
+	if n := strings.Count(out, "<td"); n != 2*stepsLength {
+		t.Errorf("Expected %d cells but got %d", 2*stepsLength, n)
+	}
+}
@@ -0,0 +1,66 @@
+package drum
+
+import "math"
+
+// Groove is the feel of a pattern independent of the steps it plays: the
+// micro timing and the accents of every step and the swing. Extract it from
+// one pattern with ExtractGroove and apply it to another with ApplyGroove.
+type Groove struct {
+	// Timing is the offset of every step as fraction of a step.
+	Timing [stepsLength]float64 `json:"timing"`
+	// Velocity is the velocity of every step relative to the loudest one,
+	// 0 for steps without notes in the pattern the groove came from.
+	Velocity [stepsLength]float64 `json:"velocity"`
+	// Swing is the swing amount in percent.
+	Swing uint8 `json:"swing"`
+}
+
+// ExtractGroove returns the groove of the pattern. The timing and velocity
+// of a step are the averages over the tracks with the step enabled.
+func ExtractGroove(p *Pattern) Groove {
+	g := Groove{Swing: p.swing}
+	var peak float64
+	for step := 0; step < stepsLength; step++ {
+		var n, ticks, velocity int
+		for _, t := range p.tracks {
+			if t.steps[step] {
+				n++
+				ticks += int(t.timing[step])
+				velocity += int(t.Velocity(step))
+			}
+		}
+		if n == 0 {
+			continue
+		}
+		g.Timing[step] = float64(ticks) / float64(n) / ticksPerStep
+		g.Velocity[step] = float64(velocity) / float64(n)
+		peak = math.Max(peak, g.Velocity[step])
+	}
+	for step := range g.Velocity {
+		if peak > 0 {
+			g.Velocity[step] /= peak
+		}
+	}
+	return g
+}
+
+// ApplyGroove returns a copy of the pattern with the groove applied. The
+// enabled steps get the timing of the groove and their velocity is scaled
+// by the groove velocity, so accents of the pattern are kept. Steps the
+// groove has no velocity for are left unchanged. The swing is replaced.
+func ApplyGroove(p *Pattern, g Groove) *Pattern {
+	c := p.Clone()
+	c.swing = uint8(clamp(int(g.Swing), 0, MaxSwing))
+	for _, t := range c.tracks {
+		for step, enabled := range t.steps {
+			if !enabled || g.Velocity[step] <= 0 {
+				continue
+			}
+			v := int(math.Round(float64(t.Velocity(step)) * math.Min(g.Velocity[step], 1)))
+			t.SetVelocity(step, uint8(clamp(v, 1, MaxVelocity)))
+			ticks := int(math.Round(g.Timing[step] * ticksPerStep))
+			t.timing[step] = int8(clamp(ticks, -maxTimingTicks, maxTimingTicks))
+		}
+	}
+	return c
+}
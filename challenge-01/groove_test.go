@@ -0,0 +1,52 @@
+package drum
+
+import (
+	"path"
+	"testing"
+)
+
+func TestGroove(t *testing.T) {
+	src, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a laid back snare and soft off-beat hihats
+	src.SetSwing(30)
+	snare, hihat := src.tracks[1], src.tracks[3]
+	snare.SetTiming(4, 0.25)
+	for step, enabled := range hihat.steps {
+		if enabled && step%4 != 0 {
+			hihat.SetVelocity(step, 64)
+		}
+	}
+	g := ExtractGroove(src)
+	if g.Swing != 30 || g.Velocity[0] != 1 || g.Velocity[1] != 0 {
+		t.Fatalf("unexpected groove %+v", g)
+	}
+
+	dst, err := DecodeFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ApplyGroove(dst, g)
+	if got.Swing() != 30 || dst.Swing() != 0 {
+		t.Errorf("Expected the swing on the copy only")
+	}
+	// the snare is late in one of the four tracks on step 4
+	if ticks := got.tracks[1].timing[4]; ticks != 2 {
+		t.Errorf("Expected the averaged timing of 2 ticks but got %d", ticks)
+	}
+	for _, tr := range got.tracks {
+		for step, enabled := range tr.steps {
+			if !enabled {
+				continue
+			}
+			if g.Velocity[step] == 0 && (tr.Velocity(step) != MaxVelocity || tr.timing[step] != 0) {
+				t.Errorf("Expected step %d of %s without groove to be unchanged", step, tr.name)
+			}
+			if step == 2 && tr.Velocity(step) >= MaxVelocity {
+				t.Errorf("Expected the off-beat accent on %s", tr.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,486 @@
+// Package grpc serves the splice.v1.PatternService defined in
+// proto/service.proto, so that services not written in Go can decode,
+// encode, validate, diff and render patterns with any gRPC client.
+//
+// The server is built on net/http and speaks gRPC over HTTP/2 without TLS
+// (h2c), the default of gRPC clients on internal networks. Compressed
+// messages are not supported, clients must not enable compression:
+//
+//	grpcurl -plaintext -import-path proto -proto service.proto \
+//		-d '{"splice": "..."}' localhost:9090 splice.v1.PatternService/Decode
+package grpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+	"github.com/alpe/go-challenge/challenge-01/metrics"
+)
+
+// MaxMessageSize is the largest request message accepted, the default of
+// gRPC.
+const MaxMessageSize = 4 << 20
+
+const (
+	servicePath = "/splice.v1.PatternService/"
+	contentType = "application/grpc"
+	// frameHeaderLength is the compression flag and the length in front of
+	// every message.
+	frameHeaderLength = 5
+)
+
+// service is the service label of the metrics.
+const service = "grpc"
+
+// recordDecode is passed to every decode of a request.
+var recordDecode = drum.WithStats(metrics.DecodeObserver(service))
+
+// gRPC status codes.
+const (
+	codeOK                = 0
+	codeInvalidArgument   = 3
+	codeResourceExhausted = 8
+	codeUnimplemented     = 12
+	codeInternal          = 13
+)
+
+// statusError is an error returned to the client with a status code.
+type statusError struct {
+	code    int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return e.message
+}
+
+func invalidArgument(format string, args ...interface{}) error {
+	return &statusError{codeInvalidArgument, fmt.Sprintf(format, args...)}
+}
+
+// Metadata of the feature negotiation. Clients send the features they
+// support, see drum.SupportedFeatures, comma separated in featuresHeader.
+// The server answers with the features supported by both sides in the same
+// header and returns patterns without the data of the other features, which
+// are listed in the droppedTrailer, see drum.Pattern.Downgrade. Without the
+// header patterns are returned with all data.
+const (
+	featuresHeader = "Splice-Features"
+	droppedTrailer = "Splice-Dropped-Features"
+)
+
+// server answers the calls with the defaults of its config.
+type server struct {
+	config drum.Config
+}
+
+// call is a single call of a method.
+type call struct {
+	*server
+	features []drum.Feature        // negotiated with the client, nil for all
+	dropped  map[drum.Feature]bool // features dropped from returned patterns
+}
+
+// method answers a single request message.
+type method func(c *call, req []byte) ([]byte, error)
+
+// unaryMethods take one request and return one response.
+var unaryMethods = map[string]method{
+	"Decode":   (*call).decode,
+	"Encode":   (*call).encode,
+	"Validate": (*call).validate,
+	"Diff":     (*call).diff,
+	"Render":   (*call).render,
+}
+
+// streamMethods answer every message of the request stream with one
+// message of the response stream.
+var streamMethods = map[string]method{
+	"DecodeStream":   (*call).decodeResult,
+	"ValidateStream": (*call).validate,
+}
+
+// New returns the handler of the service, which decodes, encodes and prints
+// patterns with the options of the config, like drum.V2Defaults(). It must
+// be served with HTTP/2, see Serve.
+func New(c drum.Config) http.Handler {
+	return http.HandlerFunc((&server{config: c}).serve)
+}
+
+// Handler returns the handler of the service with the defaults of
+// drum.V1Defaults().
+//
+// Deprecated: Use New, which takes the defaults to serve with.
+func Handler() http.Handler {
+	return New(drum.V1Defaults())
+}
+
+// Serve serves the service of New on the TCP address addr with HTTP/2
+// without TLS. HTTP/1 is accepted too, to answer plain HTTP clients with an
+// error instead of closing the connection, and to serve the Prometheus
+// metrics on GET /metrics. It fails with drum.ErrOffline in offline mode.
+func Serve(addr string, c drum.Config) error {
+	if err := drum.AllowNetwork("grpc"); err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(servicePath, New(c))
+	mux.Handle("/metrics", metrics.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, Protocols: new(http.Protocols)}
+	srv.Protocols.SetHTTP1(true)
+	srv.Protocols.SetUnencryptedHTTP2(true)
+	return srv.ListenAndServe()
+}
+
+// ListenAndServe serves the service with the defaults of drum.V1Defaults()
+// on the TCP address addr.
+//
+// Deprecated: Use Serve, which takes the defaults to serve with.
+func ListenAndServe(addr string) error {
+	return Serve(addr, drum.V1Defaults())
+}
+
+func (s *server) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.ProtoMajor != 2 {
+		http.Error(w, "gRPC requires HTTP/2", http.StatusHTTPVersionNotSupported)
+		return
+	}
+	if ct := r.Header.Get("Content-Type"); ct != contentType && !strings.HasPrefix(ct, contentType+"+proto") {
+		http.Error(w, "unsupported content type "+ct, http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	c := &call{server: s, dropped: make(map[drum.Feature]bool)}
+	if values, ok := r.Header[featuresHeader]; ok {
+		c.features = drum.Negotiate(drum.SupportedFeatures(), parseFeatures(values))
+		if c.features == nil {
+			c.features = []drum.Feature{}
+		}
+		w.Header().Set(featuresHeader, joinFeatures(c.features))
+	}
+	code, message := codeOK, ""
+	if err := c.run(w, r); err != nil {
+		code, message = codeInternal, err.Error()
+		var se *statusError
+		if errors.As(err, &se) {
+			code = se.code
+		}
+	}
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", encodeMessage(message))
+	}
+	if len(c.dropped) > 0 {
+		var dropped []drum.Feature
+		for f := range c.dropped {
+			dropped = append(dropped, f)
+		}
+		sort.Slice(dropped, func(i, j int) bool { return dropped[i] < dropped[j] })
+		w.Header().Set(http.TrailerPrefix+droppedTrailer, joinFeatures(dropped))
+	}
+}
+
+// parseFeatures returns the features of the comma separated header values.
+func parseFeatures(values []string) []drum.Feature {
+	var features []drum.Feature
+	for _, v := range values {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				features = append(features, drum.Feature(f))
+			}
+		}
+	}
+	return features
+}
+
+func joinFeatures(features []drum.Feature) string {
+	names := make([]string, len(features))
+	for i, f := range features {
+		names[i] = string(f)
+	}
+	return strings.Join(names, ",")
+}
+
+// run runs the method of the request path.
+func (c *call) run(w http.ResponseWriter, r *http.Request) error {
+	name := strings.TrimPrefix(r.URL.Path, servicePath)
+	m, unary := unaryMethods[name]
+	if !unary {
+		var ok bool
+		if m, ok = streamMethods[name]; !ok {
+			return &statusError{codeUnimplemented, "unknown method " + r.URL.Path}
+		}
+	}
+	rc := http.NewResponseController(w)
+	for {
+		req, err := readFrame(r.Body)
+		if err == io.EOF {
+			if unary {
+				return invalidArgument("missing request message")
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		resp, err := m(c, req)
+		if err != nil {
+			return err
+		}
+		if err := writeFrame(w, resp); err != nil {
+			return err
+		}
+		if unary {
+			return nil
+		}
+		// stream every response as soon as it is ready
+		if err := rc.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// readFrame reads a length prefixed message. It returns io.EOF when the
+// client closed the stream before a message.
+func readFrame(r io.Reader) ([]byte, error) {
+	var header [frameHeaderLength]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, invalidArgument("read message header: %v", err)
+	}
+	if header[0] != 0 {
+		return nil, &statusError{codeUnimplemented, "compressed messages are not supported"}
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > MaxMessageSize {
+		return nil, &statusError{codeResourceExhausted, fmt.Sprintf("message of %d bytes exceeds %d bytes", size, MaxMessageSize)}
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, invalidArgument("read message: %v", err)
+	}
+	return msg, nil
+}
+
+func writeFrame(w io.Writer, msg []byte) error {
+	var header [frameHeaderLength]byte
+	binary.BigEndian.PutUint32(header[1:], uint32(len(msg)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(msg)
+	return err
+}
+
+// encodeMessage percent encodes the status message as gRPC requires for
+// the grpc-message trailer.
+func encodeMessage(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// decodeOptions returns the options of every decode of a request.
+func (s *server) decodeOptions() []drum.DecodeOption {
+	opts := append([]drum.DecodeOption(nil), s.config.DecodeOptions...)
+	return append(opts, recordDecode)
+}
+
+func (c *call) decode(req []byte) ([]byte, error) {
+	data, _, err := readDecodeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	p, err := drum.DecodeBytes(data, c.decodeOptions()...)
+	if err != nil {
+		return nil, invalidArgument("decode: %v", err)
+	}
+	return c.toProto(p), nil
+}
+
+// decodeResult answers a request of DecodeStream, failures to decode are
+// part of the result.
+func (c *call) decodeResult(req []byte) ([]byte, error) {
+	data, name, err := readDecodeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	var m message
+	m.string(1, name)
+	p, err := drum.DecodeBytes(data, c.decodeOptions()...)
+	if err != nil {
+		m.string(3, err.Error())
+	} else {
+		m.bytes(2, c.toProto(p), true)
+	}
+	return m.Bytes(), nil
+}
+
+func readDecodeRequest(req []byte) (data []byte, name string, err error) {
+	err = readMessage(req, func(f field) error {
+		switch {
+		case f.is(1, wireBytes):
+			data = f.data
+		case f.is(2, wireBytes):
+			name = string(f.data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", invalidArgument("parse request: %v", err)
+	}
+	return data, name, nil
+}
+
+func (c *call) encode(req []byte) ([]byte, error) {
+	p, err := readPattern(req)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := c.config.Encode(&buf, p); err != nil {
+		return nil, invalidArgument("encode: %v", err)
+	}
+	var m message
+	m.bytes(1, buf.Bytes(), false)
+	return m.Bytes(), nil
+}
+
+func (c *call) validate(req []byte) ([]byte, error) {
+	p, err := readPattern(req)
+	if err != nil {
+		return nil, err
+	}
+	var m message
+	for _, i := range drum.Validate(p) {
+		var im message
+		im.string(1, i.Severity.String())
+		im.string(2, i.Code)
+		im.int32(3, int32(i.Track))
+		im.string(4, i.Message)
+		m.bytes(1, im.Bytes(), true)
+	}
+	return m.Bytes(), nil
+}
+
+func (c *call) diff(req []byte) ([]byte, error) {
+	var a, b []byte
+	err := readMessage(req, func(f field) error {
+		switch {
+		case f.is(1, wireBytes):
+			a = f.data
+		case f.is(2, wireBytes):
+			b = f.data
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, invalidArgument("parse request: %v", err)
+	}
+	pa, err := readPattern(a)
+	if err != nil {
+		return nil, err
+	}
+	pb, err := readPattern(b)
+	if err != nil {
+		return nil, err
+	}
+	var m message
+	for _, d := range drum.Diff(pa, pb) {
+		var dm message
+		dm.string(1, d.Track)
+		dm.string(2, d.Field)
+		dm.string(3, d.From)
+		dm.string(4, d.To)
+		m.bytes(1, dm.Bytes(), true)
+	}
+	return m.Bytes(), nil
+}
+
+func (c *call) render(req []byte) ([]byte, error) {
+	var pattern []byte
+	format := "svg"
+	err := readMessage(req, func(f field) error {
+		switch {
+		case f.is(1, wireBytes):
+			pattern = f.data
+		case f.is(2, wireBytes):
+			format = string(f.data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, invalidArgument("parse request: %v", err)
+	}
+	p, err := readPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	var mediaType string
+	start := time.Now()
+	switch format {
+	case "svg":
+		mediaType, err = "image/svg+xml", p.ToSVG(&buf, drum.DefaultGridStyle)
+	case "png":
+		mediaType, err = "image/png", p.ToPNG(&buf, drum.DefaultGridStyle)
+	case "printout":
+		mediaType, err = "text/plain; charset=utf-8", c.config.Format(&buf, p)
+	case "markdown":
+		mediaType = "text/markdown; charset=utf-8"
+		buf.WriteString(p.ToMarkdown())
+	default:
+		return nil, invalidArgument("unknown format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("render %s: %v", format, err)
+	}
+	metrics.ObserveRender(service, format, time.Since(start))
+	var m message
+	m.bytes(1, buf.Bytes(), false)
+	m.string(2, mediaType)
+	return m.Bytes(), nil
+}
+
+// toProto returns the pattern message without the data of the features not
+// negotiated with the client.
+func (c *call) toProto(p *drum.Pattern) []byte {
+	if c.features != nil {
+		var dropped []drum.Feature
+		p, dropped = p.Downgrade(c.features)
+		for _, f := range dropped {
+			c.dropped[f] = true
+		}
+	}
+	return drum.ToProto(p)
+}
+
+func readPattern(data []byte) (*drum.Pattern, error) {
+	p, err := drum.FromProto(data)
+	if err != nil {
+		return nil, invalidArgument("parse pattern: %v", err)
+	}
+	return p, nil
+}
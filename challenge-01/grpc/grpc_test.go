@@ -0,0 +1,294 @@
+package grpc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+	"github.com/alpe/go-challenge/challenge-01/metrics"
+)
+
+// newServer starts the service with the defaults of the config with HTTP/2
+// without TLS and returns a client speaking it.
+func newServer(t *testing.T, c drum.Config) (*httptest.Server, *http.Client) {
+	srv := httptest.NewUnstartedServer(New(c))
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetHTTP1(true)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+	transport := &http.Transport{Protocols: new(http.Protocols)}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	return srv, &http.Client{Transport: transport}
+}
+
+// invoke calls the method with the request messages and returns the
+// response messages and the status.
+func invoke(t *testing.T, srv *httptest.Server, client *http.Client, method string, reqs ...[]byte) ([][]byte, string, string) {
+	msgs, resp := invokeWithHeader(t, srv, client, method, nil, reqs...)
+	return msgs, resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+}
+
+// invokeWithHeader calls the method with the request metadata and messages
+// and returns the response messages and the response with the trailer.
+func invokeWithHeader(t *testing.T, srv *httptest.Server, client *http.Client, method string, header http.Header, reqs ...[]byte) ([][]byte, *http.Response) {
+	var body bytes.Buffer
+	for _, req := range reqs {
+		writeFrame(&body, req)
+	}
+	r, err := http.NewRequest("POST", srv.URL+servicePath+method, &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	r.Header.Set("Content-Type", contentType)
+	resp, err := client.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s: Expected status 200 but got %d", method, resp.StatusCode)
+	}
+	var msgs [][]byte
+	for {
+		msg, err := readFrame(resp.Body)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, resp
+}
+
+func decodeRequest(data []byte, name string) []byte {
+	var m message
+	m.bytes(1, data, false)
+	m.string(2, name)
+	return m.Bytes()
+}
+
+func TestService(t *testing.T) {
+	raw, err := ioutil.ReadFile(filepath.Join("..", "fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp, err := drum.DecodeBytes(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, client := newServer(t, drum.V1Defaults())
+	defer srv.Close()
+
+	msgs, status, _ := invoke(t, srv, client, "Decode", decodeRequest(raw, "pattern_1.splice"))
+	if status != "0" || len(msgs) != 1 {
+		t.Fatalf("Expected one message and status 0 but got %d and %s", len(msgs), status)
+	}
+	p, err := drum.FromProto(msgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Equal(exp) {
+		t.Errorf("Expected:\n%v\nbut got:\n%v", exp, p)
+	}
+
+	msgs, status, _ = invoke(t, srv, client, "Encode", drum.ToProto(exp))
+	var encoded []byte
+	readMessage(msgs[0], func(f field) error {
+		encoded = f.data
+		return nil
+	})
+	if status != "0" || !bytes.Equal(encoded, raw) {
+		t.Errorf("Expected the file back but got status %s and %q", status, encoded)
+	}
+
+	changed := exp.Clone()
+	changed.SetTempo(100)
+	var diffReq message
+	diffReq.bytes(1, drum.ToProto(exp), true)
+	diffReq.bytes(2, drum.ToProto(changed), true)
+	msgs, status, _ = invoke(t, srv, client, "Diff", diffReq.Bytes())
+	if status != "0" || !bytes.Contains(msgs[0], []byte("tempo")) || !bytes.Contains(msgs[0], []byte("100")) {
+		t.Errorf("Expected the tempo difference but got status %s and %q", status, msgs)
+	}
+
+	var renderReq message
+	renderReq.bytes(1, drum.ToProto(exp), true)
+	renderReq.string(2, "printout")
+	msgs, status, _ = invoke(t, srv, client, "Render", renderReq.Bytes())
+	if status != "0" || !bytes.Contains(msgs[0], []byte(exp.String())) {
+		t.Errorf("Expected the printout but got status %s and %q", status, msgs)
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	for _, exp := range []string{`splice_decodes_total{service="grpc",result="ok"} `, `splice_render_duration_seconds_count{service="grpc",format="printout"} 1`} {
+		if !strings.Contains(rec.Body.String(), exp) {
+			t.Errorf("Expected '%v' in the metrics:\n%s", exp, rec.Body)
+		}
+	}
+
+	empty, _ := drum.NewPattern("0.808-alpha", 120)
+	msgs, status, _ = invoke(t, srv, client, "Validate", drum.ToProto(empty))
+	if status != "0" || !bytes.Contains(msgs[0], []byte(drum.CodeEmptyPattern)) {
+		t.Errorf("Expected the empty pattern issue but got status %s and %q", status, msgs)
+	}
+}
+
+func TestServiceStream(t *testing.T) {
+	srv, client := newServer(t, drum.V1Defaults())
+	defer srv.Close()
+	var reqs [][]byte
+	names := []string{"pattern_1.splice", "pattern_2.splice", "broken.splice"}
+	for _, name := range names[:2] {
+		raw, err := ioutil.ReadFile(filepath.Join("..", "fixtures", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reqs = append(reqs, decodeRequest(raw, name))
+	}
+	reqs = append(reqs, decodeRequest([]byte("SPLICE"), names[2]))
+	msgs, status, _ := invoke(t, srv, client, "DecodeStream", reqs...)
+	if status != "0" || len(msgs) != len(reqs) {
+		t.Fatalf("Expected %d results and status 0 but got %d and %s", len(reqs), len(msgs), status)
+	}
+	for i, msg := range msgs {
+		var name, decodeErr string
+		var pattern []byte
+		readMessage(msg, func(f field) error {
+			switch f.number {
+			case 1:
+				name = string(f.data)
+			case 2:
+				pattern = f.data
+			case 3:
+				decodeErr = string(f.data)
+			}
+			return nil
+		})
+		if name != names[i] {
+			t.Errorf("Expected result %d of %s but got %s", i, names[i], name)
+		}
+		if broken := i == 2; broken != (decodeErr != "") || broken != (pattern == nil) {
+			t.Errorf("%s: Unexpected result %q, %q", name, pattern, decodeErr)
+		}
+	}
+}
+
+func TestServiceErrors(t *testing.T) {
+	srv, client := newServer(t, drum.V1Defaults())
+	defer srv.Close()
+	p, err := drum.DecodeFile(filepath.Join("..", "fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gif message
+	gif.bytes(1, drum.ToProto(p), true)
+	gif.string(2, "gif")
+	testCases := []struct {
+		method string
+		reqs   [][]byte
+		status string
+		msg    string
+	}{
+		{"Decode", [][]byte{decodeRequest([]byte("garbage"), "")}, "3", "decode: "},
+		{"Decode", nil, "3", "missing request message"},
+		{"Render", [][]byte{gif.Bytes()}, "3", `unknown format "gif"`},
+		{"Play", [][]byte{nil}, "12", "unknown method"},
+	}
+	for _, testCase := range testCases {
+		_, status, msg := invoke(t, srv, client, testCase.method, testCase.reqs...)
+		if status != testCase.status || !strings.Contains(msg, testCase.msg) {
+			t.Errorf("%s: Expected status %s with '%s' but got %s with '%s'", testCase.method, testCase.status, testCase.msg, status, msg)
+		}
+	}
+
+	resp, err := http.Post(srv.URL+servicePath+"Decode", contentType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusHTTPVersionNotSupported {
+		t.Errorf("Expected HTTP/1 to be rejected but got %d", resp.StatusCode)
+	}
+}
+
+func TestServiceConfig(t *testing.T) {
+	raw, err := ioutil.ReadFile(filepath.Join("..", "fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := drum.DecodeBytes(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := drum.V2Defaults()
+	srv, client := newServer(t, c)
+	defer srv.Close()
+
+	var renderReq message
+	renderReq.bytes(1, drum.ToProto(p), true)
+	renderReq.string(2, "printout")
+	msgs, status, _ := invoke(t, srv, client, "Render", renderReq.Bytes())
+	if exp := c.String(p); status != "0" || len(msgs) != 1 || !bytes.Contains(msgs[0], []byte(exp)) || exp == p.String() {
+		t.Errorf("Expected the printout with beat numbers but got status %s and %q", status, msgs)
+	}
+}
+
+func TestServiceFeatures(t *testing.T) {
+	p, err := drum.DecodeFile(filepath.Join("..", "fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Tracks()[0].SetVelocity(0, 40)
+	p.Tracks()[1].SetDisplay(drum.Display{Icon: "snare"})
+	raw, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, client := newServer(t, drum.V1Defaults())
+	defer srv.Close()
+
+	// features sent by the client: features and dropped features returned,
+	// velocity and icon of the decoded pattern
+	for header, exp := range map[string]string{
+		"":                ` "" 40 "snare"`,
+		"display, stems":  `display "velocity" 127 "snare"`,
+		"velocity,length": `length,velocity "display" 40 ""`,
+	} {
+		var h http.Header
+		if header != "" {
+			h = http.Header{featuresHeader: {header}}
+		}
+		msgs, resp := invokeWithHeader(t, srv, client, "DecodeStream", h, decodeRequest(raw, "a"), decodeRequest(raw, "b"))
+		if len(msgs) != 2 {
+			t.Fatalf("%q: Expected 2 messages but got %d", header, len(msgs))
+		}
+		var pattern []byte
+		readMessage(msgs[1], func(f field) error {
+			if f.is(2, wireBytes) {
+				pattern = f.data
+			}
+			return nil
+		})
+		got, err := drum.FromProto(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := fmt.Sprintf("%s %q %d %q", resp.Header.Get(featuresHeader), resp.Trailer.Get(droppedTrailer), got.Tracks()[0].Velocity(0), got.Tracks()[1].Display().Icon)
+		if result != exp {
+			t.Errorf("%q: Expected %s but got %s", header, exp, result)
+		}
+	}
+}
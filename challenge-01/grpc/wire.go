@@ -0,0 +1,113 @@
+package grpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Protocol buffer wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// errTruncated is returned for a message ending within a field.
+var errTruncated = errors.New("unexpected end of message")
+
+// message builds a protocol buffer message of the service, the patterns
+// within are encoded by drum.ToProto. Fields with the default value are
+// left out like proto3 does.
+type message struct {
+	bytes.Buffer
+}
+
+func (m *message) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	m.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (m *message) varint(field int, v uint64) {
+	if v != 0 {
+		m.uvarint(uint64(field)<<3 | wireVarint)
+		m.uvarint(v)
+	}
+}
+
+// int32 writes a signed field, negative values take ten bytes.
+func (m *message) int32(field int, v int32) {
+	m.varint(field, uint64(int64(v)))
+}
+
+// bytes writes a length delimited field, empty values only when always is
+// set as for embedded and repeated messages.
+func (m *message) bytes(field int, b []byte, always bool) {
+	if len(b) > 0 || always {
+		m.uvarint(uint64(field)<<3 | wireBytes)
+		m.uvarint(uint64(len(b)))
+		m.Write(b)
+	}
+}
+
+func (m *message) string(field int, s string) {
+	m.bytes(field, []byte(s), false)
+}
+
+// field is a single field read from a message.
+type field struct {
+	number   int
+	wireType int
+	value    uint64 // varint and fixed values
+	data     []byte // length delimited values
+}
+
+func (f field) is(number, wireType int) bool {
+	return f.number == number && f.wireType == wireType
+}
+
+// readMessage calls fn for every field of the message in wire order.
+func readMessage(b []byte, fn func(field) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errTruncated
+		}
+		b = b[n:]
+		f := field{number: int(key >> 3), wireType: int(key & 7)}
+		if f.number == 0 {
+			return errors.New("invalid field number 0")
+		}
+		switch f.wireType {
+		case wireVarint:
+			if f.value, n = binary.Uvarint(b); n <= 0 {
+				return errTruncated
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return errTruncated
+			}
+			f.value, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return errTruncated
+			}
+			f.value, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return errTruncated
+			}
+			f.data, b = b[n:n+int(size)], b[n+int(size):]
+		default:
+			return fmt.Errorf("unsupported wire type %d of field %d", f.wireType, f.number)
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
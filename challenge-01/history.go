@@ -0,0 +1,98 @@
+package drum
+
+// DefaultHistoryLimit is the number of edits a History can undo by default.
+const DefaultHistoryLimit = 100
+
+// History records the edits of a pattern so that they can be undone and
+// redone, for editors built on this package. Every edit made through Do is
+// recorded with a snapshot of the pattern taken with Clone before it. Edits
+// made to the pattern directly are not recorded and are lost on Undo.
+//
+// Undo and Redo restore the pattern in place, so the pattern stays the one
+// passed to NewHistory, but its tracks are replaced by copies. Callers must
+// not keep tracks across Undo and Redo. A History is not safe for concurrent
+// use.
+type History struct {
+	pattern *Pattern
+	undo    []*Pattern // snapshots before the edits, latest last
+	redo    []*Pattern // snapshots after undone edits, latest last
+	limit   int
+}
+
+// HistoryOption configures a History.
+type HistoryOption func(*History)
+
+// WithHistoryLimit sets the number of edits that can be undone. The oldest
+// edits are forgotten beyond it. Values below 1 keep all edits.
+func WithHistoryLimit(n int) HistoryOption {
+	return func(h *History) {
+		h.limit = n
+	}
+}
+
+// NewHistory returns an empty history of edits of the pattern.
+func NewHistory(p *Pattern, opts ...HistoryOption) *History {
+	h := &History{pattern: p, limit: DefaultHistoryLimit}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Pattern returns the edited pattern.
+func (h *History) Pattern() *Pattern {
+	return h.pattern
+}
+
+// Do applies the edit to the pattern and records it. When the edit returns
+// an error, the pattern is restored and the error returned, so that a failed
+// edit like an invalid tempo leaves neither the pattern nor the history
+// changed. Recording an edit discards the edits that could be redone.
+func (h *History) Do(edit func(p *Pattern) error) error {
+	snapshot := h.pattern.Clone()
+	if err := edit(h.pattern); err != nil {
+		*h.pattern = *snapshot
+		return err
+	}
+	h.undo = append(h.undo, snapshot)
+	if h.limit > 0 && len(h.undo) > h.limit {
+		h.undo = append(h.undo[:0], h.undo[len(h.undo)-h.limit:]...)
+	}
+	h.redo = nil
+	return nil
+}
+
+// Undo reverts the last recorded edit and reports whether there was one.
+func (h *History) Undo() bool {
+	return h.restore(&h.undo, &h.redo)
+}
+
+// Redo applies the last undone edit again and reports whether there was
+// one.
+func (h *History) Redo() bool {
+	return h.restore(&h.redo, &h.undo)
+}
+
+// CanUndo reports whether there is an edit to undo.
+func (h *History) CanUndo() bool {
+	return len(h.undo) > 0
+}
+
+// CanRedo reports whether there is an undone edit to redo.
+func (h *History) CanRedo() bool {
+	return len(h.redo) > 0
+}
+
+// restore sets the pattern to the latest snapshot of from and saves the
+// current state to to.
+func (h *History) restore(from, to *[]*Pattern) bool {
+	n := len(*from)
+	if n == 0 {
+		return false
+	}
+	*to = append(*to, h.pattern.Clone())
+	*h.pattern = *(*from)[n-1]
+	(*from)[n-1] = nil
+	*from = (*from)[:n-1]
+	return true
+}
@@ -0,0 +1,79 @@
+package drum
+
+import (
+	"errors"
+	"path"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := p.Clone()
+	h := NewHistory(p)
+	if h.CanUndo() || h.CanRedo() || h.Undo() || h.Redo() {
+		t.Fatal("Expected an empty history")
+	}
+	if err := h.Do(func(p *Pattern) error { return p.tracks[0].SetStep(1, true) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Do(func(p *Pattern) error { return p.SetTempo(100) }); err != nil {
+		t.Fatal(err)
+	}
+	edited := p.Clone()
+
+	// failed edits are rolled back and not recorded
+	errEdit := errors.New("edit failed")
+	err = h.Do(func(p *Pattern) error {
+		p.tracks[0].SetStep(2, true)
+		return errEdit
+	})
+	if err != errEdit || !p.Equal(edited) {
+		t.Errorf("Expected the failed edit to be rolled back but got %v", Diff(edited, p))
+	}
+
+	if !h.Undo() || !h.Undo() || h.Undo() {
+		t.Fatal("Expected two edits to undo")
+	}
+	if h.Pattern() != p || !p.Equal(original) {
+		t.Errorf("Expected the original pattern in place but got %v", Diff(original, p))
+	}
+	if !h.Redo() || !h.Redo() || h.Redo() {
+		t.Fatal("Expected two edits to redo")
+	}
+	if !p.Equal(edited) {
+		t.Errorf("Expected the edits to be redone but got %v", Diff(edited, p))
+	}
+
+	h.Undo()
+	h.Do(func(p *Pattern) error {
+		p.tracks[0].Mute()
+		return nil
+	})
+	if h.CanRedo() {
+		t.Error("Expected a new edit to discard the redo history")
+	}
+	h.Undo()
+	if p.tracks[0].Muted() {
+		t.Error("Expected the mute to be undone")
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHistory(p, WithHistoryLimit(2))
+	for _, bpm := range []float32{100, 110, 120} {
+		bpm := bpm
+		h.Do(func(p *Pattern) error { return p.SetTempo(bpm) })
+	}
+	for h.Undo() {
+	}
+	if p.Tempo() != 100 {
+		t.Errorf("Expected the oldest edit to be forgotten but got tempo %v", p.Tempo())
+	}
+}
@@ -0,0 +1,51 @@
+package drum
+
+import (
+	"math/rand"
+)
+
+// HumanizeConfig configures the amount of variation added by Humanize.
+type HumanizeConfig struct {
+	// Timing is the maximum timing offset as fraction of a step, up to 0.5.
+	Timing float64
+	// Velocity is the maximum amount the velocity of a step is lowered.
+	Velocity uint8
+}
+
+// Humanize returns a copy of the pattern with random micro timing offsets
+// and velocity variation on all enabled steps. The variation is added to
+// existing values. The result only depends on the state of rng, so renders
+// are reproducible when it is created with a fixed seed.
+func Humanize(p *Pattern, cfg HumanizeConfig, rng *rand.Rand) *Pattern {
+	if cfg.Timing > 0.5 {
+		cfg.Timing = 0.5
+	}
+	maxTicks := int(cfg.Timing * ticksPerStep)
+	c := p.Clone()
+	for _, t := range c.tracks {
+		for step, enabled := range t.steps {
+			if !enabled {
+				continue
+			}
+			if maxTicks > 0 {
+				o := int(t.timing[step]) + rng.Intn(2*maxTicks+1) - maxTicks
+				t.timing[step] = int8(clamp(o, -maxTimingTicks, maxTimingTicks))
+			}
+			if cfg.Velocity > 0 {
+				v := int(t.Velocity(step)) - rng.Intn(int(cfg.Velocity)+1)
+				t.SetVelocity(step, uint8(clamp(v, 1, MaxVelocity)))
+			}
+		}
+	}
+	return c
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
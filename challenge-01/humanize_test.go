@@ -0,0 +1,75 @@
+package drum
+
+import (
+	"bytes"
+	"math/rand"
+	"path"
+	"testing"
+)
+
+func TestHumanize(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := HumanizeConfig{Timing: 0.1, Velocity: 20}
+	a := Humanize(p, cfg, rand.New(rand.NewSource(42)))
+	b := Humanize(p, cfg, rand.New(rand.NewSource(42)))
+	if !a.Equal(b) {
+		t.Errorf("results with the same seed should be equal")
+	}
+	if a.Equal(p) || p.tracks[0].hasVelocity() || p.tracks[0].hasTiming() {
+		t.Errorf("expected a modified copy only")
+	}
+	for _, tr := range a.tracks {
+		for step, enabled := range tr.steps {
+			if !enabled {
+				if tr.timing[step] != 0 || tr.velocity[step] != 0 {
+					t.Errorf("disabled step %d of %s was modified", step, tr.name)
+				}
+				continue
+			}
+			if o := tr.Timing(step); o < -0.1 || o > 0.1 {
+				t.Errorf("timing %v out of range", o)
+			}
+			if v := tr.Velocity(step); v < MaxVelocity-20 {
+				t.Errorf("velocity %v out of range", v)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(a) {
+		t.Errorf("velocity and timing were not round tripped")
+	}
+}
+
+func TestScheduleVelocityAndTiming(t *testing.T) {
+	tr := &Track{steps: Steps{true, false, false, false, true}}
+	tr.SetVelocity(0, 64)
+	tr.SetTiming(4, -0.25)
+	p := &Pattern{tempo: 120, tracks: []*Track{tr}}
+	events := p.schedule(1)
+	if len(events) != 2 {
+		t.Fatalf("unexpected events %v", events)
+	}
+	if events[0].velocity != 64 || events[1].velocity != MaxVelocity {
+		t.Errorf("unexpected velocities %v", events)
+	}
+	if exp := 4*ticksPerStep - 6; events[1].tick != exp {
+		t.Errorf("Expected tick %v but got %v", exp, events[1].tick)
+	}
+	if err := tr.SetTiming(0, 0.6); err == nil {
+		t.Errorf("expected error for timing out of range")
+	}
+	if err := tr.SetVelocity(stepsLength, 1); err != ErrStepOutOfRange {
+		t.Errorf("expected error '%s' but got '%v'", ErrStepOutOfRange, err)
+	}
+}
@@ -0,0 +1,270 @@
+package drum
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// Hydrogen places notes on a grid of 48 ticks per quarter note.
+const hydrogenTicksPerStep = 12
+
+// hydrogenSong is the subset of the Hydrogen song XML written by
+// WriteHydrogen.
+type hydrogenSong struct {
+	XMLName     xml.Name             `xml:"song"`
+	Version     string               `xml:"version"`
+	BPM         float64              `xml:"bpm"`
+	Volume      float64              `xml:"volume"`
+	Name        string               `xml:"name"`
+	Mode        string               `xml:"mode"`
+	Instruments []hydrogenInstrument `xml:"instrumentList>instrument"`
+	Patterns    []hydrogenPattern    `xml:"patternList>pattern"`
+	Sequence    []string             `xml:"patternSequence>group>patternID"`
+}
+
+type hydrogenInstrument struct {
+	ID      int     `xml:"id"`
+	Name    string  `xml:"name"`
+	Volume  float64 `xml:"volume"`
+	Muted   bool    `xml:"isMuted"`
+	Soloed  bool    `xml:"isSoloed"`
+	PanL    float64 `xml:"pan_L"`
+	PanR    float64 `xml:"pan_R"`
+	Channel int     `xml:"midiOutChannel"`
+	Note    int     `xml:"midiOutNote"`
+}
+
+type hydrogenPattern struct {
+	Name  string         `xml:"name"`
+	Size  int            `xml:"size"`
+	Notes []hydrogenNote `xml:"noteList>note"`
+}
+
+type hydrogenNote struct {
+	Position   int     `xml:"position"`
+	LeadLag    float64 `xml:"leadlag"`
+	Velocity   float64 `xml:"velocity"`
+	PanL       float64 `xml:"pan_L"`
+	PanR       float64 `xml:"pan_R"`
+	Pitch      int     `xml:"pitch"`
+	Key        string  `xml:"key,omitempty"`
+	Length     int     `xml:"length"`
+	Instrument int     `xml:"instrument"`
+	NoteOff    bool    `xml:"note_off"`
+}
+
+// hydrogenDrumkitPattern is a single pattern exported by Hydrogen
+// (.h2pattern). Its notes refer to the instruments of a drumkit by id.
+type hydrogenDrumkitPattern struct {
+	XMLName  xml.Name       `xml:"drumkit_pattern"`
+	Xmlns    string         `xml:"xmlns,attr,omitempty"`
+	Drumkit  string         `xml:"drumkit_name"`
+	Name     string         `xml:"pattern>pattern_name"`
+	Info     string         `xml:"pattern>info"`
+	Category string         `xml:"pattern>category"`
+	Size     int            `xml:"pattern>size"`
+	Notes    []hydrogenNote `xml:"pattern>noteList>note"`
+}
+
+// hydrogenPatternNamespace is the XML namespace of .h2pattern files.
+const hydrogenPatternNamespace = "http://www.hydrogen-music.org/drumkit_pattern"
+
+// WriteHydrogenFile writes the pattern as Hydrogen song to path, see
+// WriteHydrogen.
+func WriteHydrogenFile(path string, p *Pattern) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteHydrogen(file, p); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// WriteHydrogen writes the pattern as Hydrogen drum machine song (.h2song)
+// to w. Every track becomes an instrument line with its volume, pan, mute
+// and solo state and every enabled step a note of a single pattern.
+func WriteHydrogen(w io.Writer, p *Pattern) error {
+	const name = "pattern"
+	song := hydrogenSong{
+		Version:  "0.9.7",
+		BPM:      tempo64(p.tempo),
+		Volume:   0.5,
+		Name:     "Untitled Song",
+		Mode:     "pattern",
+		Sequence: []string{name},
+	}
+	for i, t := range p.tracks {
+		note, _ := ResolveGMNote(t.name)
+		l, r := hydrogenPan(t.pan)
+		song.Instruments = append(song.Instruments, hydrogenInstrument{
+			ID:      i,
+			Name:    t.name,
+			Volume:  float64(t.Volume()) / MaxVolume,
+			Muted:   t.muted,
+			Soloed:  t.solo,
+			PanL:    l,
+			PanR:    r,
+			Channel: gmPercussionChannel,
+			Note:    int(note),
+		})
+	}
+	pattern := hydrogenPattern{Name: name, Size: stepsLength * hydrogenTicksPerStep}
+	for _, e := range p.scheduleTracks(1, false) {
+		pattern.Notes = append(pattern.Notes, hydrogenNote{
+			// round to the coarser grid of Hydrogen
+			Position:   (e.tick*hydrogenTicksPerStep + ticksPerStep/2) / ticksPerStep,
+			Velocity:   float64(e.velocity) / MaxVelocity,
+			PanL:       0.5,
+			PanR:       0.5,
+			Length:     -1,
+			Instrument: e.track,
+		})
+	}
+	song.Patterns = []hydrogenPattern{pattern}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", " ")
+	if err := enc.Encode(song); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// hydrogenPan converts a pan position into the gains of the left and right
+// channel used by Hydrogen, which are both 1 at the center.
+func hydrogenPan(pan int8) (left, right float64) {
+	switch {
+	case pan < 0:
+		return 1, 1 - float64(pan)/MinPan
+	case pan > 0:
+		return 1 - float64(pan)/MaxPan, 1
+	default:
+		return 1, 1
+	}
+}
+
+// WriteHydrogenPattern writes the pattern as single Hydrogen pattern
+// (.h2pattern) to w, which can be loaded into any Hydrogen song. The file
+// does not name instruments, so every note refers to the instrument whose id
+// is the index of its track; the tempo and the mix are not part of the
+// format.
+func WriteHydrogenPattern(w io.Writer, p *Pattern) error {
+	name := p.meta.Title
+	if name == "" {
+		name = "pattern"
+	}
+	hp := hydrogenDrumkitPattern{
+		Xmlns:    hydrogenPatternNamespace,
+		Drumkit:  "GMRockKit",
+		Name:     name,
+		Category: "unknown",
+		Size:     p.BarSteps() * hydrogenTicksPerStep,
+	}
+	for _, e := range p.scheduleTracks(1, false) {
+		hp.Notes = append(hp.Notes, hydrogenNote{
+			Position:   (e.tick*hydrogenTicksPerStep + ticksPerStep/2) / ticksPerStep,
+			Velocity:   float64(e.velocity) / MaxVelocity,
+			PanL:       0.5,
+			PanR:       0.5,
+			Key:        "C0",
+			Length:     -1,
+			Instrument: e.track,
+		})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", " ")
+	if err := enc.Encode(hp); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ReadHydrogenPattern reads a single Hydrogen pattern (.h2pattern) from r.
+// Every instrument with notes becomes a track with the instrument id as track
+// id, named by instruments at that index or "instrument <id>" beyond it.
+// Notes are placed on the closest step and the difference is kept as micro
+// timing offset. Patterns shorter than 16 steps get a matching time
+// signature, longer ones are rejected. The tempo, which the format does not
+// carry, is set to 120 BPM.
+func ReadHydrogenPattern(r io.Reader, instruments []string) (*Pattern, error) {
+	var hp hydrogenDrumkitPattern
+	if err := xml.NewDecoder(r).Decode(&hp); err != nil {
+		return nil, fmt.Errorf("parse h2pattern: %v", err)
+	}
+	steps := (hp.Size + hydrogenTicksPerStep - 1) / hydrogenTicksPerStep
+	if steps < 1 || steps > stepsLength {
+		return nil, fmt.Errorf("parse h2pattern: %d steps not supported", steps)
+	}
+	var tracks []*Track
+	byID := make(map[int]*Track)
+	for _, n := range hp.Notes {
+		if n.NoteOff {
+			continue
+		}
+		if n.Instrument < 0 || n.Position < 0 {
+			return nil, errors.New("parse h2pattern: invalid note")
+		}
+		t := byID[n.Instrument]
+		if t == nil {
+			name := fmt.Sprintf("instrument %d", n.Instrument)
+			if n.Instrument < len(instruments) {
+				name = instruments[n.Instrument]
+			}
+			var err error
+			if t, err = NewTrack(uint32(n.Instrument), name, Steps{}); err != nil {
+				return nil, fmt.Errorf("parse h2pattern: %v", err)
+			}
+			byID[n.Instrument] = t
+			tracks = append(tracks, t)
+		}
+		step := (n.Position + hydrogenTicksPerStep/2) / hydrogenTicksPerStep
+		if step >= steps {
+			continue
+		}
+		t.steps[step] = true
+		v := uint8(math.Round(math.Max(0, math.Min(1, n.Velocity)) * MaxVelocity))
+		t.SetVelocity(step, max(v, 1))
+		offset := (n.Position - step*hydrogenTicksPerStep) * ticksPerStep / hydrogenTicksPerStep
+		t.timing[step] = int8(clamp(offset, -maxTimingTicks, maxTimingTicks))
+	}
+	p, err := NewPattern("h2pattern", 120, tracks...)
+	if err != nil {
+		return nil, fmt.Errorf("parse h2pattern: %v", err)
+	}
+	sort.SliceStable(p.tracks, func(i, j int) bool { return p.tracks[i].id < p.tracks[j].id })
+	if steps != p.BarSteps() {
+		p.SetTimeSignature(timeSignatureOfSteps(steps))
+	}
+	if err := p.SetMetadata(Metadata{Title: hp.Name}); err != nil {
+		return nil, fmt.Errorf("parse h2pattern: %v", err)
+	}
+	return p, nil
+}
+
+// timeSignatureOfSteps returns the time signature of bars with the number of
+// sixteenth steps, in quarters or eighths where possible.
+func timeSignatureOfSteps(steps int) TimeSignature {
+	switch {
+	case steps%4 == 0:
+		return TimeSignature{uint8(steps / 4), 4}
+	case steps%2 == 0:
+		return TimeSignature{uint8(steps / 2), 8}
+	}
+	return TimeSignature{uint8(steps), 16}
+}
@@ -0,0 +1,109 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/xml"
+	"path"
+	"testing"
+)
+
+func TestWriteHydrogen(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_5.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.tracks[1].Mute()
+	p.tracks[1].SetPan(MinPan)
+	var buf bytes.Buffer
+	if err := WriteHydrogen(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	var song hydrogenSong
+	if err := xml.Unmarshal(buf.Bytes(), &song); err != nil {
+		t.Fatal(err)
+	}
+	if song.BPM != 999 || len(song.Instruments) != 2 || len(song.Patterns) != 1 {
+		t.Fatalf("unexpected song: %+v", song)
+	}
+	if hat := song.Instruments[1]; hat.Name != "HiHat" || !hat.Muted || hat.PanL != 1 || hat.PanR != 0 {
+		t.Errorf("unexpected instrument: %+v", hat)
+	}
+	// kick on 1 and 9, hihat on every second step
+	notes := song.Patterns[0].Notes
+	if len(notes) != 10 {
+		t.Fatalf("Expected 10 notes but got %d", len(notes))
+	}
+	if n := notes[len(notes)-1]; n.Position != 14*hydrogenTicksPerStep || n.Instrument != 1 {
+		t.Errorf("unexpected last note: %+v", n)
+	}
+}
+
+func TestHydrogenPattern(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.tracks[0].SetVelocity(0, 64)
+	if err := p.SetMetadata(Metadata{Title: "four on the floor"}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteHydrogenPattern(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(p.tracks))
+	for i, tr := range p.tracks {
+		names[i] = tr.name
+	}
+	got, err := ReadHydrogenPattern(&buf, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Metadata().Title != "four on the floor" || len(got.tracks) != len(p.tracks) {
+		t.Fatalf("unexpected pattern:\n%v", got)
+	}
+	for i, tr := range got.tracks {
+		if tr.id != uint32(i) || tr.name != p.tracks[i].name || tr.steps != p.tracks[i].steps {
+			t.Errorf("Expected track %d to be %v but got %v", i, p.tracks[i], tr)
+		}
+	}
+	if v := got.tracks[0].Velocity(0); v < 63 || v > 65 {
+		t.Errorf("Expected the velocity to be kept but got %d", v)
+	}
+}
+
+func TestReadHydrogenPattern(t *testing.T) {
+	const short = `<?xml version="1.0" encoding="UTF-8"?>
+<drumkit_pattern xmlns="http://www.hydrogen-music.org/drumkit_pattern">
+ <drumkit_name>GMRockKit</drumkit_name>
+ <pattern>
+  <pattern_name>waltz</pattern_name>
+  <size>144</size>
+  <noteList>
+   <note><position>0</position><velocity>0.8</velocity><instrument>0</instrument></note>
+   <note><position>26</position><velocity>0.5</velocity><instrument>3</instrument></note>
+   <note><position>48</position><velocity>0.5</velocity><instrument>3</instrument><note_off>true</note_off></note>
+  </noteList>
+ </pattern>
+</drumkit_pattern>`
+	p, err := ReadHydrogenPattern(bytes.NewBufferString(short), []string{"kick"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts := p.TimeSignature(); ts != (TimeSignature{3, 4}) {
+		t.Errorf("Expected 3/4 but got %v", ts)
+	}
+	if len(p.tracks) != 2 || p.tracks[0].name != "kick" || p.tracks[1].name != "instrument 3" {
+		t.Fatalf("unexpected tracks:\n%v", p)
+	}
+	// 26 ticks is step 2 played 2/12 of a step late
+	hat := p.tracks[1]
+	if !hat.steps[2] || hat.steps[4] || hat.timing[2] != 4 {
+		t.Errorf("unexpected note: %v timing %d", hat.steps, hat.timing[2])
+	}
+
+	long := bytes.NewBufferString(`<drumkit_pattern><pattern><size>384</size></pattern></drumkit_pattern>`)
+	if _, err := ReadHydrogenPattern(long, nil); err == nil {
+		t.Error("Expected an error for a pattern longer than a bar")
+	}
+}
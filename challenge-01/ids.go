@@ -0,0 +1,72 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrDuplicateTrackID is returned when remapping would give two tracks of a
+// pattern the same id.
+var ErrDuplicateTrackID = errors.New("duplicate track id")
+
+// RemapIDs changes the id of every track found in mapping from the key to
+// the value, so that patterns of devices numbering the same instruments
+// differently can be merged. Tracks without entry keep their id. The
+// pattern is left unchanged when the result has duplicate ids that were not
+// there before.
+func RemapIDs(p *Pattern, mapping map[uint32]uint32) error {
+	ids := make([]uint32, len(p.tracks))
+	before := make(map[uint32]int)
+	after := make(map[uint32]int)
+	for i, t := range p.tracks {
+		ids[i] = t.id
+		if id, ok := mapping[t.id]; ok {
+			ids[i] = id
+		}
+		before[t.id]++
+		after[ids[i]]++
+	}
+	for _, id := range ids {
+		if after[id] > 1 && after[id] > before[id] {
+			return fmt.Errorf("%w %d", ErrDuplicateTrackID, id)
+		}
+	}
+	for i, t := range p.tracks {
+		t.id = ids[i]
+	}
+	return nil
+}
+
+// NormalizeIDs numbers the tracks from 0 in the order of their names, tracks
+// of the same name in their current order. The track order itself is kept.
+// It returns the mapping applied, see RemapIDs, which is ambiguous for a
+// pattern that had duplicate ids.
+func NormalizeIDs(p *Pattern) map[uint32]uint32 {
+	sorted := append([]*Track(nil), p.tracks...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].name < sorted[j].name
+	})
+	mapping := make(map[uint32]uint32)
+	for i, t := range sorted {
+		mapping[t.id] = uint32(i)
+		t.id = uint32(i)
+	}
+	return mapping
+}
+
+// RemapIDs applies RemapIDs with the same mapping to all patterns of the
+// bank. It stops at the first pattern that fails to decode or remap, the
+// patterns before are changed already.
+func (b *Bank) RemapIDs(mapping map[uint32]uint32) error {
+	for _, e := range b.entries {
+		p, err := e.Pattern()
+		if err != nil {
+			return fmt.Errorf("%s: %v", e.name, err)
+		}
+		if err := RemapIDs(p, mapping); err != nil {
+			return fmt.Errorf("%s: %v", e.name, err)
+		}
+	}
+	return nil
+}
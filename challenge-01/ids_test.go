@@ -0,0 +1,103 @@
+package drum
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func trackIDs(p *Pattern) []uint32 {
+	var ids []uint32
+	for _, t := range p.tracks {
+		ids = append(ids, t.id)
+	}
+	return ids
+}
+
+func TestRemapIDs(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// (0) kick (1) snare (2) clap (3) hh-open (4) hh-close (5) cowbell
+	if err := RemapIDs(p, map[uint32]uint32{0: 36, 1: 38, 7: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := []uint32{36, 38, 2, 3, 4, 5}, trackIDs(p); !reflect.DeepEqual(exp, got) {
+		t.Errorf("Expected %v but got %v", exp, got)
+	}
+	err = RemapIDs(p, map[uint32]uint32{2: 3})
+	if !errors.Is(err, ErrDuplicateTrackID) {
+		t.Errorf("Expected duplicate track id but got %v", err)
+	}
+	if exp, got := []uint32{36, 38, 2, 3, 4, 5}, trackIDs(p); !reflect.DeepEqual(exp, got) {
+		t.Errorf("Expected the pattern unchanged but got %v", got)
+	}
+	// swapping is fine
+	if err := RemapIDs(p, map[uint32]uint32{2: 3, 3: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := []uint32{36, 38, 3, 2, 4, 5}, trackIDs(p); !reflect.DeepEqual(exp, got) {
+		t.Errorf("Expected %v but got %v", exp, got)
+	}
+}
+
+func TestNormalizeIDs(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.tracks[0].id = 99
+	mapping := NormalizeIDs(p)
+	// clap, cowbell, hh-close, hh-open, kick, snare
+	if exp, got := []uint32{4, 5, 0, 3, 2, 1}, trackIDs(p); !reflect.DeepEqual(exp, got) {
+		t.Errorf("Expected %v but got %v", exp, got)
+	}
+	if mapping[99] != 4 || mapping[1] != 5 || len(mapping) != 6 {
+		t.Errorf("Unexpected mapping %v", mapping)
+	}
+	if p.tracks[0].name != "kick" {
+		t.Error("Expected the track order kept")
+	}
+}
+
+func TestBankRemapIDs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var b Bank
+	for _, name := range []string{"pattern_1.splice", "pattern_2.splice"} {
+		p, err := DecodeFile(path.Join("fixtures", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Add(name, p)
+	}
+	if err := b.RemapIDs(map[uint32]uint32{0: 36}); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "bank.zip")
+	if err := b.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := OpenBank(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer saved.Close()
+	for e := range saved.Entries() {
+		p, err := e.Pattern()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.tracks[0].id != 36 {
+			t.Errorf("%s: Expected id 36 but got %d", e.Name(), p.tracks[0].id)
+		}
+	}
+}
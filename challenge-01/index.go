@@ -0,0 +1,153 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrPatternOutOfRange is returned for a pattern index outside of a
+// multi-pattern file.
+var ErrPatternOutOfRange = errors.New("pattern out of range")
+
+// PatternIndex holds the offsets of the patterns in a multi-pattern file,
+// which is a sequence of drum machine files as written by Encode, each
+// optionally followed by its checksum trailer and extensions. Data behind a
+// pattern that neither is an extension nor starts the next pattern is taken
+// as raw extra of that pattern and ends the file, as does a header whose
+// payload size exceeds the file.
+type PatternIndex struct {
+	offsets []int64 // start of every pattern followed by the end of the last
+}
+
+// IndexPatterns builds the index of the multi-pattern file read from rs. Only
+// the headers are read, the payloads and extensions are skipped by seeking
+// over them. WithChecksum is required when the patterns have a checksum
+// trailer.
+func IndexPatterns(rs io.ReadSeeker, opts ...DecodeOption) (*PatternIndex, error) {
+	return indexPatterns(rs, -1, newDecodeOptions(opts))
+}
+
+// indexPatterns indexes the patterns up to and including the one at limit,
+// all when limit is negative.
+func indexPatterns(rs io.ReadSeeker, limit int, o decodeOptions) (*PatternIndex, error) {
+	size, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	ix := PatternIndex{offsets: []int64{0}}
+	for off := int64(0); off < size && (limit < 0 || ix.Len() <= limit); {
+		end, last, err := skipPattern(rs, off, size, o.checksum)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %d: %v", ix.Len(), err)
+		}
+		ix.offsets = append(ix.offsets, end)
+		if last {
+			break
+		}
+		off = end
+	}
+	return &ix, nil
+}
+
+// skipPattern returns the end of the pattern starting at off and whether it
+// is the last one of the file.
+func skipPattern(rs io.ReadSeeker, off, size int64, checksum bool) (int64, bool, error) {
+	header, err := peek(rs, off, typeHeaderLength+8)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(header) < typeHeaderLength+8 {
+		return 0, false, parseError("header", 0, io.ErrUnexpectedEOF)
+	}
+	if !bytes.HasPrefix(header, []byte(spliceTypePattern)) {
+		return 0, false, parseError("type header", 0, ErrUnsupportedFileFormat)
+	}
+	payloadSize := int64(binary.BigEndian.Uint64(header[typeHeaderLength:]))
+	end := off + int64(len(header)) + payloadSize
+	if checksum {
+		end += 4
+	}
+	if payloadSize < 0 || end < off || end > size {
+		return 0, false, parseError("payload size", 0, fmt.Errorf("%w: %d bytes exceed the file", ErrTruncatedPayload, payloadSize))
+	}
+	ext, err := peek(rs, end, len(extensionMagic)+4)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(ext) == len(extensionMagic)+4 && bytes.HasPrefix(ext, []byte(extensionMagic)) {
+		extEnd := end + int64(len(ext)) + int64(binary.BigEndian.Uint32(ext[len(extensionMagic):]))
+		if extEnd > size {
+			// kept as raw extra by the decoder
+			return size, true, nil
+		}
+		end = extEnd
+	}
+	if end == size {
+		return end, true, nil
+	}
+	next, err := peek(rs, end, typeHeaderLength+8)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(next) < typeHeaderLength+8 || !bytes.HasPrefix(next, []byte(spliceTypePattern)) {
+		return size, true, nil
+	}
+	// raw extra data might look like a header, like in pattern_5.splice
+	nextSize := int64(binary.BigEndian.Uint64(next[typeHeaderLength:]))
+	if nextSize < 0 || nextSize > size-end-int64(len(next)) {
+		return size, true, nil
+	}
+	return end, false, nil
+}
+
+// peek reads up to n bytes at off.
+func peek(rs io.ReadSeeker, off int64, n int) ([]byte, error) {
+	if _, err := rs.Seek(off, io.SeekStart); err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	n, err := io.ReadFull(rs, b)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return b[:n], err
+}
+
+// Len returns the number of patterns indexed.
+func (ix *PatternIndex) Len() int {
+	return len(ix.offsets) - 1
+}
+
+// Offset returns the position of the i-th pattern within the file.
+func (ix *PatternIndex) Offset(i int) int64 {
+	return ix.offsets[i]
+}
+
+// Decode seeks to the i-th pattern of the indexed file read from rs and
+// decodes it.
+func (ix *PatternIndex) Decode(rs io.ReadSeeker, i int, opts ...DecodeOption) (*Pattern, error) {
+	if i < 0 || i >= ix.Len() {
+		return nil, ErrPatternOutOfRange
+	}
+	if _, err := rs.Seek(ix.offsets[i], io.SeekStart); err != nil {
+		return nil, err
+	}
+	return decodeAll(io.LimitReader(rs, ix.offsets[i+1]-ix.offsets[i]), opts...)
+}
+
+// DecodeAt decodes the pattern at index of the multi-pattern file read from
+// rs, see PatternIndex. Only the headers of the patterns before are read.
+// Build a PatternIndex with IndexPatterns to access many patterns of a file.
+func DecodeAt(rs io.ReadSeeker, index int, opts ...DecodeOption) (*Pattern, error) {
+	if index < 0 {
+		return nil, ErrPatternOutOfRange
+	}
+	ix, err := indexPatterns(rs, index, newDecodeOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return ix.Decode(rs, index, opts...)
+}
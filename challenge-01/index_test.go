@@ -0,0 +1,107 @@
+package drum
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"testing"
+)
+
+// countingReadSeeker counts the bytes read.
+type countingReadSeeker struct {
+	io.ReadSeeker
+	n int
+}
+
+func (r *countingReadSeeker) Read(b []byte) (int, error) {
+	n, err := r.ReadSeeker.Read(b)
+	r.n += n
+	return n, err
+}
+
+// multiPatternFile encodes the fixtures one after the other, the one with
+// raw extra data last.
+func multiPatternFile(t *testing.T, opts ...EncodeOption) ([]byte, []*Pattern) {
+	var buf bytes.Buffer
+	var patterns []*Pattern
+	for _, i := range []int{1, 2, 3, 4, 1, 5} {
+		p, err := DecodeFile(path.Join("fixtures", fmt.Sprintf("pattern_%d.splice", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 2 {
+			p.SetSwing(30)
+		}
+		if err := Encode(&buf, p, opts...); err != nil {
+			t.Fatal(err)
+		}
+		patterns = append(patterns, p)
+	}
+	return buf.Bytes(), patterns
+}
+
+func TestPatternIndex(t *testing.T) {
+	data, patterns := multiPatternFile(t)
+	ix, err := IndexPatterns(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ix.Len() != len(patterns) {
+		t.Fatalf("Expected %d patterns but got %d", len(patterns), ix.Len())
+	}
+	for i, exp := range patterns {
+		got, err := ix.Decode(bytes.NewReader(data), i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, exp) {
+			t.Errorf("%d: Expected '%#v' but got '%#v'", i, exp, got)
+		}
+	}
+	if _, err := ix.Decode(bytes.NewReader(data), ix.Len()); err != ErrPatternOutOfRange {
+		t.Errorf("Expected error '%v' but got '%v'", ErrPatternOutOfRange, err)
+	}
+
+	data, patterns = multiPatternFile(t, WithChecksumTrailer())
+	got, err := DecodeAt(bytes.NewReader(data), 1, WithChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, patterns[1]) {
+		t.Errorf("Expected '%#v' but got '%#v'", patterns[1], got)
+	}
+}
+
+func TestDecodeAt(t *testing.T) {
+	data, patterns := multiPatternFile(t)
+	last := len(patterns) - 2 // without the raw extra data
+	r := &countingReadSeeker{ReadSeeker: bytes.NewReader(data)}
+	got, err := DecodeAt(r, last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, patterns[last]) {
+		t.Errorf("Expected '%#v' but got '%#v'", patterns[last], got)
+	}
+	if ix, _ := IndexPatterns(bytes.NewReader(data)); r.n >= int(ix.Offset(last)) {
+		t.Errorf("Expected the patterns before to be skipped but read %d bytes", r.n)
+	}
+
+	for _, index := range []int{-1, len(patterns)} {
+		if _, err := DecodeAt(bytes.NewReader(data), index); err != ErrPatternOutOfRange {
+			t.Errorf("%d: Expected error '%v' but got '%v'", index, ErrPatternOutOfRange, err)
+		}
+	}
+	invalid := map[string][]byte{
+		"short header": data[:10],
+		"format":       append([]byte("SPLICX"), data[6:]...),
+		"payload size": data[:30],
+	}
+	for msg, b := range invalid {
+		if _, err := IndexPatterns(bytes.NewReader(b)); err == nil {
+			t.Errorf("%s: Expected error", msg)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package drum
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+)
+
+// dumpWidth is the number of bytes per line of the Inspect hex dump.
+const dumpWidth = 16
+
+// Inspect writes an annotated hex dump of the drum machine file read from r
+// to w. Every field of the header, the tracks and the extensions is shown on
+// its own lines with its decoded value. The byte where parsing fails is
+// flagged and the dump ends there; the parse error is returned after the
+// dump was written.
+func Inspect(r io.Reader, w io.Writer) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	d := dumper{w: bufio.NewWriter(w), data: data}
+	perr := d.pattern()
+	if perr != nil {
+		fmt.Fprintf(d.w, "%08x  ^^ %v\n", d.off, perr)
+	}
+	if err := d.w.Flush(); err != nil {
+		return err
+	}
+	if perr != nil {
+		return fmt.Errorf("offset %d: %v", d.off, perr)
+	}
+	return nil
+}
+
+// dumper walks the fields of a file. off is the start of the next field or
+// the byte where parsing failed.
+type dumper struct {
+	w    *bufio.Writer
+	data []byte
+	off  int
+}
+
+// field dumps the next n bytes with the annotation returned by describe.
+// When fewer bytes are left they are dumped without annotation and the
+// offset is moved to the end of the data.
+func (d *dumper) field(n int, describe func(b []byte) string) ([]byte, error) {
+	if d.off+n > len(d.data) {
+		d.dump(d.data[d.off:], "")
+		d.off = len(d.data)
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := d.data[d.off : d.off+n]
+	d.dump(b, describe(b))
+	d.off += n
+	return b, nil
+}
+
+// dump writes b at the current offset in lines of dumpWidth bytes, the note
+// on the first one.
+func (d *dumper) dump(b []byte, note string) {
+	for i := 0; i == 0 || i < len(b); i += dumpWidth {
+		line := b[i:]
+		if len(line) > dumpWidth {
+			line = line[:dumpWidth]
+		}
+		hex := make([]string, len(line))
+		ascii := make([]byte, len(line))
+		for n, c := range line {
+			hex[n] = fmt.Sprintf("%02x", c)
+			ascii[n] = '.'
+			if c >= 0x20 && c < 0x7f {
+				ascii[n] = c
+			}
+		}
+		out := fmt.Sprintf("%08x  %-*s  %-*s  %s", d.off+i, dumpWidth*3-1, strings.Join(hex, " "), dumpWidth, ascii, note)
+		fmt.Fprintln(d.w, strings.TrimRight(out, " "))
+		note = ""
+	}
+}
+
+func (d *dumper) pattern() error {
+	_, err := d.field(typeHeaderLength, func(b []byte) string { return fmt.Sprintf("type header %q", b) })
+	if err != nil {
+		return err
+	}
+	if string(d.data[:typeHeaderLength]) != spliceTypePattern {
+		d.off = 0
+		return ErrUnsupportedFileFormat
+	}
+	var size int64
+	_, err = d.field(8, func(b []byte) string {
+		size = int64(binary.BigEndian.Uint64(b))
+		return fmt.Sprintf("payload size %d", size)
+	})
+	if err != nil {
+		return err
+	}
+	end := int64(d.off) + size
+	if size < 0 || end > int64(len(d.data)) {
+		d.off -= 8
+		return fmt.Errorf("payload size %d exceeds the %d bytes left", size, len(d.data)-d.off-8)
+	}
+	if _, err := d.field(maxVersionLength, func(b []byte) string { return fmt.Sprintf("version %q", cropToString(b)) }); err != nil {
+		return err
+	}
+	if _, err := d.field(4, func(b []byte) string {
+		return fmt.Sprintf("tempo %v", math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}); err != nil {
+		return err
+	}
+	for i := 0; int64(d.off) < end; i++ {
+		if err := d.track(i, int(end)); err != nil {
+			return err
+		}
+	}
+	return d.extensions()
+}
+
+func (d *dumper) track(i, end int) error {
+	// fields must not exceed the payload
+	data := d.data
+	d.data = data[:end]
+	defer func() { d.data = data }()
+	if _, err := d.field(4, func(b []byte) string {
+		return fmt.Sprintf("track %d id %d", i, binary.LittleEndian.Uint32(b))
+	}); err != nil {
+		return err
+	}
+	b, err := d.field(1, func(b []byte) string { return fmt.Sprintf("track %d name length %d", i, b[0]) })
+	if err != nil {
+		return err
+	}
+	if _, err := d.field(int(b[0]), func(b []byte) string { return fmt.Sprintf("track %d name %q", i, b) }); err != nil {
+		return err
+	}
+	start := d.off
+	b, err = d.field(stepsLength, func(b []byte) string {
+		var steps Steps
+		for n, v := range b {
+			steps[n] = v == 1
+		}
+		return fmt.Sprintf("track %d steps %s", i, stepSymbols(steps))
+	})
+	if err != nil {
+		return err
+	}
+	for n, v := range b {
+		if _, err := byteToBool(v); err != nil {
+			d.off = start + n
+			return fmt.Errorf("step %d: %v", n+1, err)
+		}
+	}
+	return nil
+}
+
+// extensions dumps the extension block and raw extra data behind the
+// payload.
+func (d *dumper) extensions() error {
+	rest := d.data[d.off:]
+	const headerLength = len(extensionMagic) + 4
+	if len(rest) < headerLength || string(rest[:len(extensionMagic)]) != extensionMagic ||
+		uint64(binary.BigEndian.Uint32(rest[len(extensionMagic):])) > uint64(len(rest)-headerLength) {
+		if len(rest) > 0 {
+			d.field(len(rest), func([]byte) string { return fmt.Sprintf("raw extra data, %d bytes", len(rest)) })
+		}
+		return nil
+	}
+	var size int
+	d.field(headerLength, func(b []byte) string {
+		size = int(binary.BigEndian.Uint32(b[len(extensionMagic):]))
+		return fmt.Sprintf("extension header, %d bytes", size)
+	})
+	end := d.off + size
+	for d.off < end {
+		if d.off+8 > end {
+			return fmt.Errorf("chunk header: %v", io.ErrUnexpectedEOF)
+		}
+		var id chunkID
+		var n int
+		d.field(8, func(b []byte) string {
+			copy(id[:], b)
+			n = int(binary.BigEndian.Uint32(b[4:]))
+			return fmt.Sprintf("%s chunk, %d bytes", id, n)
+		})
+		if d.off+n > end {
+			d.off -= 8
+			return fmt.Errorf("%s chunk size %d exceeds extension", id, n)
+		}
+		d.field(n, func([]byte) string { return fmt.Sprintf("%s chunk data", id) })
+	}
+	if d.off < len(d.data) {
+		n := len(d.data) - d.off
+		d.field(n, func([]byte) string { return fmt.Sprintf("raw extra data, %d bytes", n) })
+	}
+	return nil
+}
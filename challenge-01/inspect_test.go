@@ -0,0 +1,83 @@
+package drum
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	raw, err := ioutil.ReadFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Inspect(bytes.NewReader(raw), &buf); err != nil {
+		t.Fatal(err)
+	}
+	exp := `00000000  53 50 4c 49 43 45                                SPLICE            type header "SPLICE"
+00000006  00 00 00 00 00 00 00 c5                          ........          payload size 197
+0000000e  30 2e 38 30 38 2d 61 6c 70 68 61 00 00 00 00 00  0.808-alpha.....  version "0.808-alpha"
+0000001e  00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00  ................
+0000002e  00 00 f0 42                                      ...B              tempo 120
+00000032  00 00 00 00                                      ....              track 0 id 0
+00000036  04                                               .                 track 0 name length 4
+00000037  6b 69 63 6b                                      kick              track 0 name "kick"
+0000003b  01 00 00 00 01 00 00 00 01 00 00 00 01 00 00 00  ................  track 0 steps x---x---x---x---
+`
+	if got := buf.String(); !strings.HasPrefix(got, exp) {
+		t.Errorf("Expected dump starting with\n%s\nbut got\n%s", exp, got)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 5+4*6 {
+		t.Errorf("Expected %d lines but got %d", 5+4*6, n)
+	}
+
+	// the extensions and raw extra data are dumped
+	p, _ := Decode(bytes.NewReader(raw))
+	p.SetSwing(20)
+	var encoded bytes.Buffer
+	Encode(&encoded, p)
+	encoded.WriteString("tail")
+	buf.Reset()
+	if err := Inspect(&encoded, &buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, note := range []string{"extension header, 9 bytes", "SWNG chunk, 1 bytes", "SWNG chunk data", "raw extra data, 4 bytes"} {
+		if !strings.Contains(buf.String(), note) {
+			t.Errorf("Expected %q in\n%s", note, buf.String())
+		}
+	}
+}
+
+func TestInspectInvalid(t *testing.T) {
+	raw, err := ioutil.ReadFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalidStep := append([]byte(nil), raw...)
+	invalidStep[0x3b+5] = 2
+	// the payload ends behind the id of the first track
+	truncated := append([]byte(nil), raw[:14+40]...)
+	truncated[13] = 40
+	specs := map[string]struct {
+		data []byte
+		exp  string
+	}{
+		"format":       {[]byte("SPLICX"), "00000000  ^^ unsupported file format\n"},
+		"payload size": {raw[:40], "00000006  ^^ payload size 197 exceeds the 26 bytes left\n"},
+		"step":         {invalidStep, "00000040  ^^ step 6: invalid step value\n"},
+		"truncated":    {truncated, "00000036  ^^ unexpected EOF\n"},
+	}
+	for msg, spec := range specs {
+		var buf bytes.Buffer
+		err := Inspect(bytes.NewReader(spec.data), &buf)
+		if err == nil {
+			t.Errorf("%s: Expected error", msg)
+		}
+		if !strings.HasSuffix(buf.String(), spec.exp) {
+			t.Errorf("%s: Expected dump ending with %q but got\n%s", msg, spec.exp, buf.String())
+		}
+	}
+}
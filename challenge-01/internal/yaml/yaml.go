@@ -0,0 +1,605 @@
+// Package yaml converts between JSON and the subset of YAML used for
+// patterns and manifests, so that the YAML representations share the
+// validation of the JSON ones without a YAML dependency.
+//
+// The subset is block mappings and sequences, single line flow sequences
+// and mappings, plain, single and double quoted scalars and comments.
+// Anchors, tags, block scalars and multiple documents are not supported.
+// Plain scalars are resolved like YAML 1.2 core schema: null, booleans and
+// numbers, everything else is a string.
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupported is returned for YAML outside of the supported subset.
+var ErrUnsupported = errors.New("unsupported yaml")
+
+type kind int
+
+const (
+	scalarNode kind = iota
+	mappingNode
+	sequenceNode
+)
+
+// node is a value of a document. Mappings keep the order of their keys.
+type node struct {
+	kind   kind
+	keys   []string
+	values []*node
+	value  string // JSON of a scalar: a string, number, boolean or null
+}
+
+// FromJSON returns the JSON value as YAML document. Arrays of scalars are
+// written in flow style on one line, everything else in block style.
+func FromJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	n, err := readJSON(dec)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	switch {
+	case n.kind == mappingNode && len(n.keys) > 0:
+		writeMapping(&buf, n, 0, "")
+	case n.kind == sequenceNode && len(n.values) > 0 && !n.flow():
+		writeSequence(&buf, n, 0)
+	default:
+		buf.WriteString(n.inline())
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func readJSON(dec *json.Decoder) (*node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		n := &node{kind: sequenceNode}
+		if tok == '{' {
+			n.kind = mappingNode
+		}
+		for dec.More() {
+			if n.kind == mappingNode {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.keys = append(n.keys, key.(string))
+			}
+			v, err := readJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.values = append(n.values, v)
+		}
+		// the closing delimiter
+		_, err := dec.Token()
+		return n, err
+	case string:
+		return stringNode(tok), nil
+	case nil:
+		return &node{value: "null"}, nil
+	default:
+		return &node{value: fmt.Sprint(tok)}, nil
+	}
+}
+
+func stringNode(s string) *node {
+	b, _ := json.Marshal(s)
+	return &node{value: string(b)}
+}
+
+// flow reports whether the node is written on a single line.
+func (n *node) flow() bool {
+	if n.kind == scalarNode || len(n.values) == 0 {
+		return true
+	}
+	if n.kind == mappingNode {
+		return false
+	}
+	for _, v := range n.values {
+		if v.kind != scalarNode {
+			return false
+		}
+	}
+	return true
+}
+
+// inline returns the node in flow style.
+func (n *node) inline() string {
+	switch n.kind {
+	case mappingNode:
+		return "{}" // only empty mappings are written in flow style
+	case sequenceNode:
+		items := make([]string, len(n.values))
+		for i, v := range n.values {
+			items[i] = v.inline()
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	if s, ok := n.str(); ok && plain(s) {
+		return s
+	}
+	return n.value
+}
+
+// str returns the value of a string scalar.
+func (n *node) str() (string, bool) {
+	if n.kind != scalarNode || !strings.HasPrefix(n.value, `"`) {
+		return "", false
+	}
+	var s string
+	err := json.Unmarshal([]byte(n.value), &s)
+	return s, err == nil
+}
+
+func writeMapping(buf *bytes.Buffer, n *node, indent int, first string) {
+	for i, key := range n.keys {
+		prefix := strings.Repeat(" ", indent)
+		if i == 0 && first != "" {
+			prefix = first
+		}
+		buf.WriteString(prefix)
+		buf.WriteString(stringNode(key).inline())
+		buf.WriteByte(':')
+		writeValue(buf, n.values[i], indent)
+	}
+}
+
+func writeSequence(buf *bytes.Buffer, n *node, indent int) {
+	for _, v := range n.values {
+		prefix := strings.Repeat(" ", indent) + "- "
+		switch {
+		case v.kind == mappingNode && len(v.keys) > 0:
+			writeMapping(buf, v, indent+2, prefix)
+		case v.flow():
+			buf.WriteString(prefix + v.inline() + "\n")
+		default:
+			buf.WriteString(strings.TrimRight(prefix, " ") + "\n")
+			writeSequence(buf, v, indent+2)
+		}
+	}
+}
+
+// writeValue writes the value of a mapping key after the colon.
+func writeValue(buf *bytes.Buffer, v *node, indent int) {
+	switch {
+	case v.flow():
+		buf.WriteString(" " + v.inline() + "\n")
+	case v.kind == mappingNode:
+		buf.WriteByte('\n')
+		writeMapping(buf, v, indent+2, "")
+	default:
+		buf.WriteByte('\n')
+		writeSequence(buf, v, indent+2)
+	}
+}
+
+// plain reports whether the string can be written without quotes and
+// still reads back as the same string.
+func plain(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) || resolve(s) != "" {
+		return false
+	}
+	switch strings.ToLower(s) {
+	case "yes", "no", "on", "off", "y", "n":
+		// booleans of YAML 1.1 readers
+		return false
+	}
+	if strings.ContainsAny(s[:1], "?:,[]{}#&*!|>'\"%@`") {
+		return false
+	}
+	if s[0] == '-' && (len(s) == 1 || s[1] == ' ') {
+		return false
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+	for _, r := range s {
+		if r < ' ' || r == 0x7f || r == ',' || r == '[' || r == ']' || r == '{' || r == '}' {
+			return false
+		}
+	}
+	return true
+}
+
+// resolve returns the JSON of a plain scalar that is no string, or "" for
+// a string.
+func resolve(s string) string {
+	switch s {
+	case "null", "Null", "NULL", "~":
+		return "null"
+	case "true", "True", "TRUE":
+		return "true"
+	case "false", "False", "FALSE":
+		return "false"
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return strconv.FormatInt(i, 10)
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !strings.ContainsAny(s, "xXpPnNiI_") {
+		b, _ := json.Marshal(f)
+		return string(b)
+	}
+	return ""
+}
+
+// line is a line of a document without comment and indentation.
+type line struct {
+	number int
+	indent int
+	text   string
+}
+
+// ToJSON returns the YAML document as JSON. The keys of mappings keep their
+// order.
+func ToJSON(data []byte) ([]byte, error) {
+	lines, err := splitLines(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return []byte("null"), nil
+	}
+	p := &parser{lines: lines}
+	n, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected indentation")
+	}
+	var buf bytes.Buffer
+	writeJSON(&buf, n)
+	return buf.Bytes(), nil
+}
+
+func splitLines(data []byte) ([]line, error) {
+	var lines []line
+	for i, text := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimLeft(text, " ")
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		trimmed = strings.TrimSpace(stripComment(trimmed))
+		switch {
+		case trimmed == "":
+			continue
+		case trimmed == "---" && len(lines) == 0:
+			continue
+		case trimmed == "..." || trimmed == "---":
+			return nil, fmt.Errorf("line %d: %w: multiple documents", i+1, ErrUnsupported)
+		}
+		lines = append(lines, line{i + 1, len(text) - len(strings.TrimLeft(text, " ")), trimmed})
+	}
+	return lines, nil
+}
+
+// stripComment removes a comment outside of quotes.
+func stripComment(s string) string {
+	quote := byte(0)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.ContainsRune(" [{,:-", rune(s[i-1])) {
+				quote = c
+			}
+		case c == '#' && (i == 0 || s[i-1] == ' '):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+type parser struct {
+	lines []line
+	pos   int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	number := 0
+	if p.pos < len(p.lines) {
+		number = p.lines[p.pos].number
+	} else if len(p.lines) > 0 {
+		number = p.lines[len(p.lines)-1].number
+	}
+	return fmt.Errorf("line %d: "+format, append([]interface{}{number}, args...)...)
+}
+
+// block parses the block node starting at the current line with the
+// indentation.
+func (p *parser) block(indent int) (*node, error) {
+	l := p.lines[p.pos]
+	switch {
+	case l.text == "-" || strings.HasPrefix(l.text, "- "):
+		return p.sequence(indent)
+	case mappingKey(l.text) >= 0:
+		return p.mapping(indent)
+	}
+	p.pos++
+	return scalar(l.text)
+}
+
+func (p *parser) sequence(indent int) (*node, error) {
+	n := &node{kind: sequenceNode}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		dash := l.text == "-" || strings.HasPrefix(l.text, "- ")
+		if l.indent < indent || l.indent == indent && !dash {
+			// the next key of a mapping the sequence is the value of
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf("expected a sequence item")
+		}
+		item := strings.TrimSpace(strings.TrimPrefix(l.text, "-"))
+		var v *node
+		var err error
+		if item == "" {
+			p.pos++
+			v, err = p.nested(indent, false)
+		} else {
+			// the item continues as if the dash were indentation
+			p.lines[p.pos] = line{l.number, indent + len(l.text) - len(item), item}
+			v, err = p.block(p.lines[p.pos].indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		n.values = append(n.values, v)
+	}
+	return n, nil
+}
+
+func (p *parser) mapping(indent int) (*node, error) {
+	n := &node{kind: mappingNode}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		i := mappingKey(l.text)
+		if l.indent > indent || i < 0 {
+			return nil, p.errorf("expected a mapping key")
+		}
+		key, err := scalar(strings.TrimSpace(l.text[:i]))
+		if err != nil {
+			return nil, p.errorf("%w", err)
+		}
+		k, ok := key.str()
+		if !ok {
+			// keys that resolve to other types are still strings in JSON
+			k = strings.TrimSpace(l.text[:i])
+		}
+		for _, existing := range n.keys {
+			if existing == k {
+				return nil, p.errorf("duplicate key %q", k)
+			}
+		}
+		value := strings.TrimSpace(l.text[i+1:])
+		p.pos++
+		var v *node
+		if value == "" {
+			v, err = p.nested(indent, true)
+		} else {
+			v, err = scalar(value)
+		}
+		if err != nil {
+			return nil, p.errorf("%w", err)
+		}
+		n.keys = append(n.keys, k)
+		n.values = append(n.values, v)
+	}
+	return n, nil
+}
+
+// nested parses the block value of a key or item without inline value. A
+// sequence may be the value of a key at the indentation of the key.
+func (p *parser) nested(indent int, key bool) (*node, error) {
+	if p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent > indent || key && l.indent == indent && (l.text == "-" || strings.HasPrefix(l.text, "- ")) {
+			return p.block(l.indent)
+		}
+	}
+	return &node{value: "null"}, nil
+}
+
+// mappingKey returns the index of the colon after the key of the line, or
+// -1 when the line is no mapping entry.
+func mappingKey(s string) int {
+	if strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{") {
+		return -1
+	}
+	if q := s[0]; q == '"' || q == '\'' {
+		end := closingQuote(s)
+		if end < 0 || end+1 >= len(s) || s[end+1] != ':' {
+			return -1
+		}
+		if end+2 < len(s) && s[end+2] != ' ' {
+			return -1
+		}
+		return end + 1
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == ':' && (i+1 == len(s) || s[i+1] == ' ') {
+			return i
+		}
+	}
+	return -1
+}
+
+// closingQuote returns the index of the quote closing the scalar at the
+// start of s.
+func closingQuote(s string) int {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case s[i] == q && q == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+// scalar parses a single line value: a flow collection, a quoted or a
+// plain scalar.
+func scalar(s string) (*node, error) {
+	f := &flowParser{s: s}
+	n, err := f.value()
+	if err != nil {
+		return nil, err
+	}
+	if f.skipSpace(); f.pos < len(f.s) {
+		return nil, fmt.Errorf("unexpected %q after value", f.s[f.pos:])
+	}
+	return n, nil
+}
+
+// flowParser parses values within a line.
+type flowParser struct {
+	s   string
+	pos int
+}
+
+func (f *flowParser) skipSpace() {
+	for f.pos < len(f.s) && f.s[f.pos] == ' ' {
+		f.pos++
+	}
+}
+
+func (f *flowParser) value() (*node, error) {
+	f.skipSpace()
+	if f.pos == len(f.s) {
+		return &node{value: "null"}, nil
+	}
+	switch c := f.s[f.pos]; c {
+	case '[', '{':
+		return f.collection()
+	case '"', '\'':
+		end := closingQuote(f.s[f.pos:])
+		if end < 0 {
+			return nil, errors.New("missing closing quote")
+		}
+		quoted := f.s[f.pos : f.pos+end+1]
+		f.pos += end + 1
+		if c == '\'' {
+			return stringNode(strings.ReplaceAll(quoted[1:len(quoted)-1], "''", "'")), nil
+		}
+		var s string
+		if err := json.Unmarshal([]byte(quoted), &s); err != nil {
+			return nil, fmt.Errorf("invalid double quoted string %s", quoted)
+		}
+		return stringNode(s), nil
+	case '|', '>', '&', '*', '!':
+		return nil, fmt.Errorf("%w: %q", ErrUnsupported, c)
+	}
+	// a plain scalar ends at the end of the line or a flow indicator
+	start := f.pos
+	for f.pos < len(f.s) && !strings.ContainsRune(",]}", rune(f.s[f.pos])) {
+		if f.s[f.pos] == ':' && f.pos+1 < len(f.s) && f.s[f.pos+1] == ' ' {
+			break
+		}
+		f.pos++
+	}
+	s := strings.TrimSpace(f.s[start:f.pos])
+	if v := resolve(s); v != "" {
+		return &node{value: v}, nil
+	}
+	return stringNode(s), nil
+}
+
+func (f *flowParser) collection() (*node, error) {
+	open := f.s[f.pos]
+	closing, n := byte(']'), &node{kind: sequenceNode}
+	if open == '{' {
+		closing, n.kind = '}', mappingNode
+	}
+	f.pos++
+	for {
+		f.skipSpace()
+		if f.pos == len(f.s) {
+			return nil, fmt.Errorf("missing %q", closing)
+		}
+		if f.s[f.pos] == closing {
+			f.pos++
+			return n, nil
+		}
+		if n.kind == mappingNode {
+			key, err := f.value()
+			if err != nil {
+				return nil, err
+			}
+			k, ok := key.str()
+			if !ok {
+				k = key.value
+			}
+			if f.skipSpace(); f.pos == len(f.s) || f.s[f.pos] != ':' {
+				return nil, fmt.Errorf("expected ':' after key %q", k)
+			}
+			f.pos++
+			n.keys = append(n.keys, k)
+		}
+		v, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		n.values = append(n.values, v)
+		if f.skipSpace(); f.pos < len(f.s) && f.s[f.pos] == ',' {
+			f.pos++
+		} else if f.pos < len(f.s) && f.s[f.pos] != closing {
+			return nil, fmt.Errorf("expected ',' or %q", closing)
+		}
+	}
+}
+
+func writeJSON(w io.Writer, n *node) {
+	switch n.kind {
+	case scalarNode:
+		io.WriteString(w, n.value)
+	case mappingNode:
+		io.WriteString(w, "{")
+		for i, key := range n.keys {
+			if i > 0 {
+				io.WriteString(w, ",")
+			}
+			b, _ := json.Marshal(key)
+			w.Write(b)
+			io.WriteString(w, ":")
+			writeJSON(w, n.values[i])
+		}
+		io.WriteString(w, "}")
+	case sequenceNode:
+		io.WriteString(w, "[")
+		for i, v := range n.values {
+			if i > 0 {
+				io.WriteString(w, ",")
+			}
+			writeJSON(w, v)
+		}
+		io.WriteString(w, "]")
+	}
+}
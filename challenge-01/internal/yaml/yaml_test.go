@@ -0,0 +1,75 @@
+package yaml
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFromJSON(t *testing.T) {
+	testCases := []struct {
+		json, yaml string
+	}{
+		{`{"a":1,"b":"x","c":[1,2],"d":{"e":null}}`, "a: 1\nb: x\nc: [1, 2]\nd:\n  e: null\n"},
+		{`{"s":["true","1","","a: b","- x","--x-"," x","on"]}`, "s: [\"true\", \"1\", \"\", \"a: b\", \"- x\", --x-, \" x\", \"on\"]\n"},
+		{`[{"a":1,"b":[]},{"c":{}},[1]]`, "- a: 1\n  b: []\n- c: {}\n- [1]\n"},
+		{`{"a":[{"b":[{"c":1}]}]}`, "a:\n  - b:\n      - c: 1\n"},
+		{`"plain"`, "plain\n"},
+		{`{"key with: colon":true}`, "\"key with: colon\": true\n"},
+	}
+	for _, testCase := range testCases {
+		got, err := FromJSON([]byte(testCase.json))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != testCase.yaml {
+			t.Errorf("%s: Expected:\n%v\nbut got:\n%v", testCase.json, testCase.yaml, string(got))
+		}
+		// and back
+		back, err := ToJSON(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(back) != testCase.json {
+			t.Errorf("Expected %v back but got %v", testCase.json, string(back))
+		}
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	testCases := []struct {
+		yaml, json string
+	}{
+		{"---\n# comment\na: 1 # one\nb: 'it''s'\nc: \"tab\\t\"\n", `{"a":1,"b":"it's","c":"tab\t"}`},
+		{"list:\n- a\n- b: 1\n  c: [x, 'y', {z: 2}]\nnext: 1.50\n", `{"list":["a",{"b":1,"c":["x","y",{"z":2}]}],"next":1.5}`},
+		{"a:\nb: ~\nc: 007\nd: 1e3\ne: 0x10\nf: #x\n", `{"a":null,"b":null,"c":7,"d":1000,"e":"0x10","f":null}`},
+		{"- - 1\n  - 2\n-\n  - 3\n", `[[1,2],[3]]`},
+		{"url: http://example.com/a#b\n", `{"url":"http://example.com/a#b"}`},
+		{"", `null`},
+	}
+	for _, testCase := range testCases {
+		got, err := ToJSON([]byte(testCase.yaml))
+		if err != nil {
+			t.Fatalf("%q: %v", testCase.yaml, err)
+		}
+		if string(got) != testCase.json {
+			t.Errorf("%q: Expected %v but got %v", testCase.yaml, testCase.json, string(got))
+		}
+	}
+
+	for doc, exp := range map[string]string{
+		"a: 1\n  b: 2\n":    "line 2: expected a mapping key",
+		"a: 1\na: 2\n":      `line 2: duplicate key "a"`,
+		"a: [1, 2\n":        `line 1: missing ']'`,
+		"a:\n\t- 1\n":       "line 2: tabs are not allowed for indentation",
+		"a: \"open\n":       "line 1: missing closing quote",
+		"- 1\nb: 2\n":       "line 2: unexpected indentation",
+		"a: 1\n---\nb: 2\n": "line 2: unsupported yaml: multiple documents",
+	} {
+		if _, err := ToJSON([]byte(doc)); err == nil || err.Error() != exp {
+			t.Errorf("%q: Expected '%v' but got %v", doc, exp, err)
+		}
+	}
+	if _, err := ToJSON([]byte("a: &anchor 1\n")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected %v but got %v", ErrUnsupported, err)
+	}
+}
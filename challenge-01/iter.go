@@ -0,0 +1,76 @@
+package drum
+
+import (
+	"bufio"
+	"errors"
+	"iter"
+)
+
+// errStopIteration ends decoding when the caller stops ranging.
+var errStopIteration = errors.New("stop iteration")
+
+// TracksSeq returns an iterator over the tracks of the pattern in file
+// order.
+func (p *Pattern) TracksSeq() iter.Seq[*Track] {
+	return func(yield func(*Track) bool) {
+		for _, t := range p.tracks {
+			if !yield(t) {
+				return
+			}
+		}
+	}
+}
+
+// OnSteps returns an iterator over the indexes of the enabled steps.
+func (s Steps) OnSteps() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i, enabled := range s {
+			if enabled && !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+// Entries returns an iterator over the entries of the bank in archive order.
+func (b *Bank) Entries() iter.Seq[*BankEntry] {
+	return func(yield func(*BankEntry) bool) {
+		for _, e := range b.entries {
+			if !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// TracksSeq returns an iterator over the tracks of the entry. Entries not
+// loaded yet are decoded with DecodeStream while iterating, so the pattern is
+// neither kept in memory nor marked as changed. A decoding error is yielded
+// as last element with a nil track.
+func (e *BankEntry) TracksSeq() iter.Seq2[*Track, error] {
+	return func(yield func(*Track, error) bool) {
+		if e.pattern != nil {
+			for _, t := range e.pattern.tracks {
+				if !yield(t, nil) {
+					return
+				}
+			}
+			return
+		}
+		rc, err := e.open()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer rc.Close()
+		err = DecodeStream(bufio.NewReader(rc), func(_ PatternHeader, t *Track) error {
+			if !yield(t, nil) {
+				return errStopIteration
+			}
+			return nil
+		})
+		if err != nil && err != errStopIteration {
+			yield(nil, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package drum
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestTracksSeq(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for tr := range p.TracksSeq() {
+		names = append(names, tr.Name())
+		if len(names) == 2 {
+			break
+		}
+	}
+	if exp := []string{"kick", "snare"}; !reflect.DeepEqual(names, exp) {
+		t.Errorf("Expected %v but got %v", exp, names)
+	}
+
+	var on []int
+	for i := range p.tracks[0].Steps().OnSteps() {
+		on = append(on, i)
+	}
+	if exp := []int{0, 4, 8, 12}; !reflect.DeepEqual(on, exp) {
+		t.Errorf("Expected %v but got %v", exp, on)
+	}
+}
+
+func TestBankEntriesTracksSeq(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	b, err := OpenBank(writeTestBank(t, dir, "pattern_1.splice", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	counts := map[string]int{}
+	for e := range b.Entries() {
+		for tr, err := range e.TracksSeq() {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tr.Name() == "" {
+				t.Errorf("%s: Expected track name", e.Name())
+			}
+			counts[e.Name()]++
+		}
+		if e.pattern != nil || e.changed {
+			t.Errorf("%s: Expected entry not to be loaded", e.Name())
+		}
+	}
+	if exp := map[string]int{"pattern_1.splice": 6, "pattern_2.splice": 4}; !reflect.DeepEqual(counts, exp) {
+		t.Errorf("Expected %v but got %v", exp, counts)
+	}
+
+	e := b.Entry(0)
+	p, _ := e.Pattern()
+	p.tracks = p.tracks[:1]
+	n := 0
+	for range e.TracksSeq() {
+		n++
+	}
+	if n != 1 {
+		t.Errorf("Expected the tracks of the loaded pattern but got %d", n)
+	}
+}
@@ -0,0 +1,377 @@
+package drum
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// patternJSON is the JSON representation of a pattern. Steps are written in
+// the printout symbols without block separators, optional data is left out
+// when it has the default value.
+type patternJSON struct {
+	Version  string      `json:"version" yaml:"version"`
+	Tempo    jsonTempo   `json:"tempo" yaml:"tempo"`
+	Swing    uint8       `json:"swing,omitempty" yaml:"swing,omitempty"`
+	TimeSig  string      `json:"timeSignature,omitempty" yaml:"timeSignature,omitempty"`
+	TempoMap []tempoJSON `json:"tempoMap,omitempty" yaml:"tempoMap,omitempty"`
+	Bars     int         `json:"bars,omitempty" yaml:"bars,omitempty"`
+	BarOrder string      `json:"barOrder,omitempty" yaml:"barOrder,omitempty"`
+	Meta     *metaJSON   `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Labels   []labelJSON `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Tracks   []trackJSON `json:"tracks" yaml:"tracks"`
+}
+
+type labelJSON struct {
+	Name  string `json:"name" yaml:"name"`
+	Color string `json:"color,omitempty" yaml:"color,omitempty"`
+}
+
+func toLabelsJSON(labels []Label) []labelJSON {
+	var v []labelJSON
+	for _, l := range labels {
+		v = append(v, labelJSON(l))
+	}
+	return v
+}
+
+func fromLabelsJSON(v []labelJSON) []Label {
+	var labels []Label
+	for _, l := range v {
+		labels = append(labels, Label(l))
+	}
+	return labels
+}
+
+type tempoJSON struct {
+	Step  int     `json:"step" yaml:"step"`
+	Tempo float32 `json:"tempo" yaml:"tempo"`
+	Ramp  bool    `json:"ramp,omitempty" yaml:"ramp,omitempty"`
+}
+
+type metaJSON struct {
+	Title   string     `json:"title,omitempty" yaml:"title,omitempty"`
+	Author  string     `json:"author,omitempty" yaml:"author,omitempty"`
+	Tags    []string   `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Created *time.Time `json:"created,omitempty" yaml:"created,omitempty"`
+}
+
+type trackJSON struct {
+	ID          uint32       `json:"id" yaml:"id"`
+	Name        string       `json:"name" yaml:"name"`
+	Steps       string       `json:"steps" yaml:"steps"`
+	Bars        []string     `json:"bars,omitempty" yaml:"bars,omitempty"` // steps of the bars after the first
+	Velocity    []uint8      `json:"velocity,omitempty" yaml:"velocity,omitempty"`
+	Timing      []float64    `json:"timing,omitempty" yaml:"timing,omitempty"`
+	Probability []uint8      `json:"probability,omitempty" yaml:"probability,omitempty"`
+	Conditions  []string     `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+	Ratchet     []int        `json:"ratchet,omitempty" yaml:"ratchet,omitempty"`
+	Length      int          `json:"length,omitempty" yaml:"length,omitempty"`
+	Choke       int          `json:"choke,omitempty" yaml:"choke,omitempty"`
+	Nudge       float64      `json:"nudge,omitempty" yaml:"nudge,omitempty"`
+	Volume      *uint8       `json:"volume,omitempty" yaml:"volume,omitempty"`
+	Pan         int8         `json:"pan,omitempty" yaml:"pan,omitempty"`
+	Display     *displayJSON `json:"display,omitempty" yaml:"display,omitempty"`
+	Role        string       `json:"role,omitempty" yaml:"role,omitempty"` // set by Track.SetRole only
+	Aliases     []string     `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Labels      []labelJSON  `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Muted       bool         `json:"muted,omitempty" yaml:"muted,omitempty"`
+	Solo        bool         `json:"solo,omitempty" yaml:"solo,omitempty"`
+}
+
+type displayJSON struct {
+	Color string `json:"color,omitempty" yaml:"color,omitempty"`
+	Icon  string `json:"icon,omitempty" yaml:"icon,omitempty"`
+}
+
+// MarshalJSON returns the pattern as JSON. Data not interpreted by this
+// package, like unknown chunks and raw extra bytes, is not included.
+func (p *Pattern) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.toJSON())
+}
+
+// toJSON returns the JSON representation of the pattern.
+func (p *Pattern) toJSON() patternJSON {
+	v := patternJSON{Version: p.version, Tempo: jsonTempo(p.tempo), Swing: p.swing, Tracks: []trackJSON{}}
+	if p.timeSig != (TimeSignature{}) {
+		v.TimeSig = p.timeSig.String()
+	}
+	for _, pt := range p.tempoMap {
+		v.TempoMap = append(v.TempoMap, tempoJSON(pt))
+	}
+	if p.extraBars != 0 {
+		v.Bars = p.Bars()
+	}
+	if p.barOrder != nil {
+		v.BarOrder = FormatBarOrder(p.BarOrder())
+	}
+	if m := p.meta; !m.isZero() {
+		v.Meta = &metaJSON{Title: m.Title, Author: m.Author, Tags: m.Tags}
+		if !m.Created.IsZero() {
+			v.Meta.Created = &m.Created
+		}
+	}
+	v.Labels = toLabelsJSON(p.labels)
+	for _, t := range p.tracks {
+		tj := trackJSON{ID: t.id, Name: t.name, Steps: stepSymbols(t.steps), Length: t.Length(), Choke: t.ChokeGroup(), Nudge: t.Nudge(), Pan: t.pan, Muted: t.muted, Solo: t.solo}
+		if bars := t.bars[:p.extraBars]; !isZeroBars(bars) {
+			for _, s := range bars {
+				tj.Bars = append(tj.Bars, stepSymbols(s))
+			}
+		}
+		if t.hasVelocity() {
+			for i := range t.steps {
+				tj.Velocity = append(tj.Velocity, t.Velocity(i))
+			}
+		}
+		if t.hasTiming() {
+			for i := range t.steps {
+				tj.Timing = append(tj.Timing, t.Timing(i))
+			}
+		}
+		if t.hasProbability() {
+			tj.Probability = probabilities(t)
+		}
+		if t.hasCondition() {
+			for i := range t.steps {
+				tj.Conditions = append(tj.Conditions, t.Condition(i).String())
+			}
+		}
+		if t.hasRatchet() {
+			for i := range t.steps {
+				tj.Ratchet = append(tj.Ratchet, t.Ratchet(i))
+			}
+		}
+		if t.attenuation != 0 {
+			v := t.Volume()
+			tj.Volume = &v
+		}
+		if t.display != (Display{}) {
+			tj.Display = &displayJSON{t.display.Color, t.display.Icon}
+		}
+		if t.role != RoleAuto {
+			tj.Role = t.role.String()
+		}
+		tj.Aliases = t.Aliases()
+		tj.Labels = toLabelsJSON(t.Labels())
+		v.Tracks = append(v.Tracks, tj)
+	}
+	return v
+}
+
+// UnmarshalJSON sets the pattern from JSON written by MarshalJSON. All
+// values are validated like by the setters.
+func (p *Pattern) UnmarshalJSON(data []byte) error {
+	var v patternJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	np, err := v.pattern()
+	if err != nil {
+		return err
+	}
+	*p = *np
+	return nil
+}
+
+// pattern returns the pattern of the JSON representation with all values
+// validated.
+func (v patternJSON) pattern() (*Pattern, error) {
+	var tracks []*Track
+	for _, tj := range v.Tracks {
+		t, err := tj.track()
+		if err != nil {
+			return nil, fmt.Errorf("track %d: %v", tj.ID, err)
+		}
+		tracks = append(tracks, t)
+	}
+	np, err := NewPattern(v.Version, float32(v.Tempo), tracks...)
+	if err != nil {
+		return nil, err
+	}
+	if v.Bars < 0 || v.Bars > MaxBars {
+		return nil, fmt.Errorf("%w: %d bars", ErrInvalidBar, v.Bars)
+	}
+	if v.Bars > 1 {
+		np.extraBars = uint8(v.Bars - 1)
+	}
+	for _, tj := range v.Tracks {
+		if len(tj.Bars) > int(np.extraBars) {
+			return nil, fmt.Errorf("track %d: %w: %d bars", tj.ID, ErrInvalidBar, len(tj.Bars)+1)
+		}
+	}
+	if v.BarOrder != "" {
+		order, err := ParseBarOrder(v.BarOrder)
+		if err != nil {
+			return nil, err
+		}
+		if err := np.SetBarOrder(order); err != nil {
+			return nil, err
+		}
+	}
+	if err := np.SetSwing(v.Swing); err != nil {
+		return nil, err
+	}
+	if v.TimeSig != "" {
+		ts, err := ParseTimeSignature(v.TimeSig)
+		if err != nil {
+			return nil, err
+		}
+		np.SetTimeSignature(ts)
+	}
+	if v.TempoMap != nil {
+		m := make(TempoMap, len(v.TempoMap))
+		for i, pt := range v.TempoMap {
+			m[i] = TempoPoint(pt)
+		}
+		if err := np.SetTempoMap(m); err != nil {
+			return nil, err
+		}
+	}
+	if mj := v.Meta; mj != nil {
+		m := Metadata{Title: mj.Title, Author: mj.Author, Tags: mj.Tags}
+		if mj.Created != nil {
+			m.Created = *mj.Created
+		}
+		if err := np.SetMetadata(m); err != nil {
+			return nil, err
+		}
+	}
+	if err := np.SetLabels(fromLabelsJSON(v.Labels)...); err != nil {
+		return nil, err
+	}
+	return np, nil
+}
+
+// stepSymbols returns the steps in the printout symbols without block
+// separators.
+func stepSymbols(s Steps) string {
+	symbols := make([]rune, stepsLength)
+	for i, enabled := range s {
+		symbols[i] = symbolStepDisabled
+		if enabled {
+			symbols[i] = symbolStepEnabled
+		}
+	}
+	return string(symbols)
+}
+
+// parseStepSymbols returns the steps written by stepSymbols.
+func parseStepSymbols(s string) (Steps, error) {
+	var steps Steps
+	if len(s) != stepsLength {
+		return steps, fmt.Errorf("expected %d steps but got %q", stepsLength, s)
+	}
+	for i, r := range s {
+		switch r {
+		case symbolStepEnabled:
+			steps[i] = true
+		case symbolStepDisabled:
+		default:
+			return steps, fmt.Errorf("invalid step %q", r)
+		}
+	}
+	return steps, nil
+}
+
+func (tj trackJSON) track() (*Track, error) {
+	steps, err := parseStepSymbols(tj.Steps)
+	if err != nil {
+		return nil, err
+	}
+	t, err := NewTrack(tj.ID, tj.Name, steps)
+	if err != nil {
+		return nil, err
+	}
+	if len(tj.Bars) >= MaxBars {
+		return nil, fmt.Errorf("%w: %d bars", ErrInvalidBar, len(tj.Bars)+1)
+	}
+	for i, b := range tj.Bars {
+		if t.bars[i], err = parseStepSymbols(b); err != nil {
+			return nil, fmt.Errorf("bar %s: %v", barName(i+1), err)
+		}
+	}
+	if tj.Velocity != nil && len(tj.Velocity) != stepsLength {
+		return nil, fmt.Errorf("expected %d velocities", stepsLength)
+	}
+	for i, v := range tj.Velocity {
+		if err := t.SetVelocity(i, v); err != nil {
+			return nil, err
+		}
+	}
+	if tj.Timing != nil && len(tj.Timing) != stepsLength {
+		return nil, fmt.Errorf("expected %d timing offsets", stepsLength)
+	}
+	for i, o := range tj.Timing {
+		if err := t.SetTiming(i, o); err != nil {
+			return nil, err
+		}
+	}
+	if tj.Probability != nil && len(tj.Probability) != stepsLength {
+		return nil, fmt.Errorf("expected %d probabilities", stepsLength)
+	}
+	for i, p := range tj.Probability {
+		if err := t.SetProbability(i, p); err != nil {
+			return nil, err
+		}
+	}
+	if tj.Conditions != nil && len(tj.Conditions) != stepsLength {
+		return nil, fmt.Errorf("expected %d conditions", stepsLength)
+	}
+	for i, s := range tj.Conditions {
+		c, err := ParseCondition(s)
+		if err != nil {
+			return nil, err
+		}
+		t.SetCondition(i, c)
+	}
+	if tj.Ratchet != nil && len(tj.Ratchet) != stepsLength {
+		return nil, fmt.Errorf("expected %d ratchet counts", stepsLength)
+	}
+	for i, n := range tj.Ratchet {
+		if err := t.SetRatchet(i, n); err != nil {
+			return nil, err
+		}
+	}
+	if err := t.SetLength(tj.Length); err != nil {
+		return nil, err
+	}
+	if err := t.SetChokeGroup(tj.Choke); err != nil {
+		return nil, err
+	}
+	if err := t.SetNudge(tj.Nudge); err != nil {
+		return nil, err
+	}
+	if tj.Volume != nil {
+		if err := t.SetVolume(*tj.Volume); err != nil {
+			return nil, err
+		}
+	}
+	if err := t.SetPan(tj.Pan); err != nil {
+		return nil, err
+	}
+	if tj.Display != nil {
+		if err := t.SetDisplay(Display{tj.Display.Color, tj.Display.Icon}); err != nil {
+			return nil, err
+		}
+	}
+	if tj.Role != "" {
+		r, err := ParseRole(tj.Role)
+		if err != nil {
+			return nil, err
+		}
+		t.role = r
+	}
+	for _, a := range tj.Aliases {
+		if len(a) > math.MaxUint8 {
+			return nil, ErrNameTooLong
+		}
+	}
+	t.aliases = joinShortStrings(tj.Aliases)
+	if err := t.SetLabels(fromLabelsJSON(tj.Labels)...); err != nil {
+		return nil, err
+	}
+	t.muted, t.solo = tj.Muted, tj.Solo
+	return t, nil
+}
@@ -0,0 +1,249 @@
+package drum
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrMissingSample is returned when a kit has no sample for a track.
+var ErrMissingSample = errors.New("missing sample")
+
+// Kit maps the tracks of patterns to audio samples.
+//
+// A sample is looked up for a track by its id, its name, an alias of the
+// name and finally by any sample whose name is the same General MIDI drum,
+// so that a track "hh-open" uses the sample "open_hat" when there is no
+// better match. Names are compared like in Pattern.TrackByName.
+type Kit struct {
+	Name    string
+	byID    map[uint32]*Sample
+	byName  map[string]*Sample // by normalized name
+	aliases map[string]string  // normalized alias to normalized name
+	chokes  map[string]int     // normalized name to choke group
+	offsets map[*Sample]time.Duration
+}
+
+// NewKit returns an empty kit.
+func NewKit(name string) *Kit {
+	return &Kit{
+		Name:    name,
+		byID:    make(map[uint32]*Sample),
+		byName:  make(map[string]*Sample),
+		aliases: make(map[string]string),
+		chokes:  make(map[string]int),
+		offsets: make(map[*Sample]time.Duration),
+	}
+}
+
+// Add adds the sample for tracks with the given name.
+func (k *Kit) Add(name string, s *Sample) {
+	k.byName[normalizeName(name)] = s
+}
+
+// AddID adds the sample for tracks with the given id.
+func (k *Kit) AddID(id uint32, s *Sample) {
+	k.byID[id] = s
+}
+
+// Alias makes tracks named alias use the sample with the given name.
+func (k *Kit) Alias(alias, name string) {
+	k.aliases[normalizeName(alias)] = normalizeName(name)
+}
+
+// Sample returns the sample for the track.
+func (k *Kit) Sample(t *Track) (*Sample, bool) {
+	if s, ok := k.byID[t.id]; ok {
+		return s, true
+	}
+	key := normalizeName(t.name)
+	if s, ok := k.byName[key]; ok {
+		return s, true
+	}
+	if s, ok := k.byName[k.aliases[key]]; ok {
+		return s, true
+	}
+	note, ok := ResolveGMNote(t.name)
+	if !ok {
+		return nil, false
+	}
+	// sorted for a deterministic choice between several matches
+	names := make([]string, 0, len(k.byName))
+	for name := range k.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if n, ok := ResolveGMNote(name); ok && n == note {
+			return k.byName[name], true
+		}
+	}
+	return nil, false
+}
+
+// Validate returns an error wrapping ErrMissingSample that lists all tracks
+// of the pattern without a sample.
+func (k *Kit) Validate(p *Pattern) error {
+	var missing []string
+	for _, t := range p.tracks {
+		if _, ok := k.Sample(t); !ok {
+			missing = append(missing, strconv.Quote(t.name))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w for %s", ErrMissingSample, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// kitManifest is the JSON structure of a kit manifest. Paths are relative to
+// the manifest.
+//
+//	{
+//		"name": "808",
+//		"samples": {"kick": "bd.wav", "open_hat": "oh.aiff"},
+//		"ids": {"36": "bd.wav"},
+//		"aliases": {"hh-open": "open_hat"},
+//		"chokes": {"open_hat": 1, "closed_hat": 1},
+//		"offsets": {"kick": "4ms", "open_hat": "auto"}
+//	}
+//
+// Offsets are start offsets, see Kit.SetStartOffset, as durations or "auto"
+// to detect them. "autoOffsets": true detects them for all other samples.
+type kitManifest struct {
+	Name    string            `json:"name"`
+	Samples map[string]string `json:"samples"`
+	IDs     map[string]string `json:"ids"`
+	Aliases map[string]string `json:"aliases"`
+	Chokes  map[string]int    `json:"chokes"`
+	Offsets map[string]string `json:"offsets"`
+	Auto    bool              `json:"autoOffsets"`
+}
+
+// LoadKit loads a kit from a JSON manifest or from a directory. All WAV and
+// AIFF files of a directory are added with their file name without extension
+// as sample name.
+func LoadKit(path string) (*Kit, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return loadKitDir(path)
+	}
+	return loadKitManifest(path)
+}
+
+func loadKitDir(dir string) (*Kit, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	k := NewKit(filepath.Base(dir))
+	for _, f := range files {
+		ext := filepath.Ext(f.Name())
+		if f.IsDir() || !isSampleFile(ext) {
+			continue
+		}
+		s, err := ReadSampleFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("load kit: %v", err)
+		}
+		k.Add(strings.TrimSuffix(f.Name(), ext), s)
+	}
+	return k, nil
+}
+
+func loadKitManifest(path string) (*Kit, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m kitManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse kit manifest: %v", err)
+	}
+	k := NewKit(m.Name)
+	dir := filepath.Dir(path)
+	// files used more than once are only read once
+	loaded := make(map[string]*Sample)
+	load := func(file string) (*Sample, error) {
+		if s, ok := loaded[file]; ok {
+			return s, nil
+		}
+		s, err := ReadSampleFile(filepath.Join(dir, file))
+		if err != nil {
+			return nil, fmt.Errorf("load kit: %v", err)
+		}
+		loaded[file] = s
+		return s, nil
+	}
+	for name, file := range m.Samples {
+		s, err := load(file)
+		if err != nil {
+			return nil, err
+		}
+		k.Add(name, s)
+	}
+	for id, file := range m.IDs {
+		n, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("parse kit manifest: invalid track id %q", id)
+		}
+		s, err := load(file)
+		if err != nil {
+			return nil, err
+		}
+		k.AddID(uint32(n), s)
+	}
+	for alias, name := range m.Aliases {
+		k.Alias(alias, name)
+	}
+	for name, group := range m.Chokes {
+		if err := k.SetChokeGroup(name, group); err != nil {
+			return nil, fmt.Errorf("parse kit manifest: %v", err)
+		}
+	}
+	for name, value := range m.Offsets {
+		var offset time.Duration
+		if value == "auto" {
+			if s, ok := k.byName[normalizeName(name)]; ok {
+				offset = DetectOnset(s)
+			}
+		} else if offset, err = time.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("parse kit manifest: invalid offset %q of %q", value, name)
+		}
+		if err := k.SetStartOffset(name, offset); err != nil {
+			return nil, fmt.Errorf("parse kit manifest: %v", err)
+		}
+	}
+	if m.Auto {
+		k.DetectStartOffsets()
+	}
+	return k, nil
+}
+
+func isSampleFile(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".wav", ".aif", ".aiff":
+		return true
+	}
+	return false
+}
+
+// ReadSampleFile reads a WAV or AIFF file depending on the file extension.
+func ReadSampleFile(path string) (*Sample, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".aif", ".aiff":
+		return ReadAIFFFile(path)
+	default:
+		return ReadWAVFile(path)
+	}
+}
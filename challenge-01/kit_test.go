@@ -0,0 +1,110 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKitSample(t *testing.T) {
+	kick, hat, ride := &Sample{}, &Sample{}, &Sample{}
+	k := NewKit("test")
+	k.Add("Kick", kick)
+	k.Add("open_hat", hat)
+	k.Add("bell", ride)
+	k.Alias("ride", "bell")
+	k.AddID(7, ride)
+	testCases := []struct {
+		track *Track
+		exp   *Sample
+	}{
+		{&Track{name: "kick"}, kick},
+		{&Track{name: "hh-open"}, hat},
+		{&Track{name: "Ride"}, ride},
+		{&Track{id: 7, name: "kick"}, ride},
+		{&Track{name: "snare"}, nil},
+	}
+	for _, testCase := range testCases {
+		got, _ := k.Sample(testCase.track)
+		if got != testCase.exp {
+			t.Errorf("unexpected sample for track %q", testCase.track.name)
+		}
+	}
+	p := &Pattern{tracks: []*Track{{name: "kick"}, {name: "snare"}, {name: "cowbell"}}}
+	err := k.Validate(p)
+	if !errors.Is(err, ErrMissingSample) {
+		t.Fatalf("expected error '%s' but got '%v'", ErrMissingSample, err)
+	}
+	if exp := `missing sample for "snare", "cowbell"`; err.Error() != exp {
+		t.Errorf("Expected '%s' but got '%s'", exp, err)
+	}
+}
+
+func TestLoadKit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var wav bytes.Buffer
+	WriteWAV(&wav, &Sample{Rate: 44100, Left: []float32{0.5}, Right: []float32{0.5}})
+	if err := ioutil.WriteFile(filepath.Join(dir, "bd.wav"), wav.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "snare.aiff"), testAIFF(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifest := `{"name": "808", "samples": {"kick": "bd.wav"}, "ids": {"2": "snare.aiff"}, "chokes": {"kick": 3}, "offsets": {"kick": "auto"}}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "kit.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := LoadKit(filepath.Join(dir, "kit.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Pattern{tracks: []*Track{{id: 1, name: "Kick"}, {id: 2, name: "SD"}}}
+	if err := k.Validate(p); err != nil {
+		t.Error(err)
+	}
+	if g := k.ChokeGroup(p.tracks[0]); g != 3 {
+		t.Errorf("Expected choke group 3 but got %d", g)
+	}
+	if s, _ := k.Sample(p.tracks[1]); s.Rate != 44100 || s.Frames() != 2 || !near(s.Left[1], -0.5) {
+		t.Errorf("unexpected aiff sample %+v", s)
+	}
+
+	k, err = LoadKit(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Validate(p); err != nil {
+		t.Error(err)
+	}
+}
+
+// testAIFF returns a mono 16 bit AIFF file with the frames 0.5 and -0.5.
+func testAIFF() []byte {
+	var comm, ssnd bytes.Buffer
+	binary.Write(&comm, binary.BigEndian, []int16{1})
+	binary.Write(&comm, binary.BigEndian, uint32(2))
+	binary.Write(&comm, binary.BigEndian, []int16{16})
+	comm.Write([]byte{0x40, 0x0e, 0xac, 0x44, 0, 0, 0, 0, 0, 0}) // 44100
+	binary.Write(&ssnd, binary.BigEndian, []uint32{0, 0})
+	binary.Write(&ssnd, binary.BigEndian, []int16{1 << 14, -1 << 14})
+
+	var buf bytes.Buffer
+	buf.WriteString("FORM")
+	binary.Write(&buf, binary.BigEndian, uint32(4+8+comm.Len()+8+ssnd.Len()))
+	buf.WriteString("AIFFCOMM")
+	binary.Write(&buf, binary.BigEndian, uint32(comm.Len()))
+	comm.WriteTo(&buf)
+	buf.WriteString("SSND")
+	binary.Write(&buf, binary.BigEndian, uint32(ssnd.Len()))
+	ssnd.WriteTo(&buf)
+	return buf.Bytes()
+}
@@ -0,0 +1,217 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+var chunkLabels = chunkID{'L', 'A', 'B', 'L'}
+
+// FeatureLabels is the chunk of the labels of patterns and tracks.
+const FeatureLabels Feature = "labels"
+
+// ErrInvalidLabel is returned for labels that are empty, too long, have an
+// invalid color or are set twice.
+var ErrInvalidLabel = errors.New("invalid label")
+
+// Label is a tag users assign to patterns and tracks to organize big
+// libraries, like "live-set", optionally with a color to show it in.
+// Names are compared case insensitive.
+type Label struct {
+	Name  string
+	Color string // hex color like "#ff8800", empty for none
+}
+
+func (l Label) String() string {
+	if l.Color == "" {
+		return l.Name
+	}
+	return l.Name + " (" + l.Color + ")"
+}
+
+// validateLabels checks the labels for SetLabels: at most 255 with names of
+// 1 to 255 bytes, each name once and valid colors.
+func validateLabels(labels []Label) error {
+	if len(labels) > math.MaxUint8 {
+		return fmt.Errorf("%w: %d labels", ErrInvalidLabel, len(labels))
+	}
+	for i, l := range labels {
+		if l.Name == "" || len(l.Name) > math.MaxUint8 {
+			return fmt.Errorf("%w %q", ErrInvalidLabel, l.Name)
+		}
+		if err := validateColor(l.Color); err != nil {
+			return fmt.Errorf("%w %q: %v", ErrInvalidLabel, l.Name, err)
+		}
+		for _, o := range labels[:i] {
+			if strings.EqualFold(o.Name, l.Name) {
+				return fmt.Errorf("%w %q: set twice", ErrInvalidLabel, l.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func hasLabel(labels []Label, name string) bool {
+	for _, l := range labels {
+		if strings.EqualFold(l.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Labels returns a copy of the labels of the pattern. The labels of its
+// tracks are returned by Track.Labels.
+func (p *Pattern) Labels() []Label {
+	return append([]Label(nil), p.labels...)
+}
+
+// SetLabels replaces the labels of the pattern. There may be at most 255
+// labels with names of 1 to 255 bytes, each set once, and colors empty or
+// "#rrggbb" hex values.
+func (p *Pattern) SetLabels(labels ...Label) error {
+	if err := validateLabels(labels); err != nil {
+		return err
+	}
+	p.labels = append([]Label(nil), labels...)
+	if len(p.labels) == 0 {
+		p.labels = nil
+	}
+	return nil
+}
+
+// HasLabel reports whether the pattern or one of its tracks has the label.
+func (p *Pattern) HasLabel(name string) bool {
+	if hasLabel(p.labels, name) {
+		return true
+	}
+	for _, t := range p.tracks {
+		if t.HasLabel(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Labels returns the labels of the track.
+func (t *Track) Labels() []Label {
+	fields := splitShortStrings(t.labels)
+	var labels []Label
+	for i := 0; i+1 < len(fields); i += 2 {
+		labels = append(labels, Label{fields[i], fields[i+1]})
+	}
+	return labels
+}
+
+// SetLabels replaces the labels of the track, see Pattern.SetLabels.
+func (t *Track) SetLabels(labels ...Label) error {
+	if err := validateLabels(labels); err != nil {
+		return err
+	}
+	fields := make([]string, 0, 2*len(labels))
+	for _, l := range labels {
+		fields = append(fields, l.Name, l.Color)
+	}
+	t.labels = joinShortStrings(fields)
+	return nil
+}
+
+// HasLabel reports whether the track has the label.
+func (t *Track) HasLabel(name string) bool {
+	return hasLabel(t.Labels(), name)
+}
+
+// The labels chunk holds the labels of the pattern followed by the ones of
+// the tracks having any:
+//
+//	|Label count (1 byte)|Labels|
+//	|Track index (2 bytes)|Label count (1 byte)|Labels|...
+//
+// Every label is stored as |Name length (1 byte)|Name|Color length (1 byte)|Color|.
+func decodeLabelsChunk(data []byte, p *Pattern) error {
+	r := bytes.NewReader(data)
+	readLabels := func() ([]Label, error) {
+		n, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("parse label count: %v", err)
+		}
+		labels := make([]Label, n)
+		for i := range labels {
+			if labels[i].Name, err = readShortString(r); err != nil {
+				return nil, fmt.Errorf("parse label: %v", err)
+			}
+			if labels[i].Color, err = readShortString(r); err != nil {
+				return nil, fmt.Errorf("parse label color: %v", err)
+			}
+		}
+		return labels, nil
+	}
+	labels, err := readLabels()
+	if err != nil {
+		return err
+	}
+	if err := p.SetLabels(labels...); err != nil {
+		return err
+	}
+	for r.Len() > 0 {
+		var index uint16
+		if err := binary.Read(r, binary.LittleEndian, &index); err != nil {
+			return fmt.Errorf("parse track index: %v", err)
+		}
+		if int(index) >= len(p.tracks) {
+			return fmt.Errorf("track index %d out of range", index)
+		}
+		labels, err := readLabels()
+		if err != nil {
+			return err
+		}
+		if err := p.tracks[index].SetLabels(labels...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeLabelsChunk(p *Pattern) []byte {
+	var buf bytes.Buffer
+	writeLabels := func(labels []Label) {
+		buf.WriteByte(uint8(len(labels)))
+		for _, l := range labels {
+			writeShortString(&buf, l.Name)
+			writeShortString(&buf, l.Color)
+		}
+	}
+	writeLabels(p.labels)
+	for i, t := range p.tracks {
+		if t.labels == "" {
+			continue
+		}
+		buf.Write(binary.LittleEndian.AppendUint16(nil, uint16(i)))
+		writeLabels(t.Labels())
+	}
+	if buf.Len() == 1 {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+func clearLabels(p *Pattern) {
+	p.labels = nil
+	for _, t := range p.tracks {
+		t.labels = ""
+	}
+}
+
+// appendLabels writes the labels separated by commas.
+func appendLabels(w printoutWriter, labels []Label) {
+	for i, l := range labels {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		w.WriteString(l.String())
+	}
+}
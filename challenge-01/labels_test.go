@@ -0,0 +1,89 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSetLabels(t *testing.T) {
+	p := &Pattern{version: "0.909", tempo: 120, tracks: []*Track{{name: "kick"}, {name: "snare"}}}
+	if err := p.SetLabels(Label{Name: "live-set", Color: "#ff0000"}, Label{Name: "WIP"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.tracks[1].SetLabels(Label{Name: "ghost-notes", Color: "#00ff00"}); err != nil {
+		t.Fatal(err)
+	}
+	if !p.HasLabel("wip") || !p.HasLabel("Ghost-Notes") || p.HasLabel("fill") {
+		t.Errorf("unexpected labels %v and %v", p.Labels(), p.tracks[1].Labels())
+	}
+	if p.tracks[0].HasLabel("ghost-notes") || !p.tracks[1].HasLabel("ghost-notes") {
+		t.Error("Expected the label on the snare only")
+	}
+
+	specs := map[string][]Label{
+		"empty name":    {{Name: ""}},
+		"too long":      {{Name: strings.Repeat("l", 256)}},
+		"invalid color": {{Name: "live-set", Color: "red"}},
+		"set twice":     {{Name: "live-set"}, {Name: "Live-Set"}},
+		"too many":      make([]Label, 256),
+	}
+	for msg, labels := range specs {
+		if err := p.SetLabels(labels...); !errors.Is(err, ErrInvalidLabel) {
+			t.Errorf("%s: Expected %v but got %v", msg, ErrInvalidLabel, err)
+		}
+		if err := p.tracks[0].SetLabels(labels...); !errors.Is(err, ErrInvalidLabel) {
+			t.Errorf("%s: Expected %v but got %v", msg, ErrInvalidLabel, err)
+		}
+	}
+	if len(p.Labels()) != 2 {
+		t.Errorf("Expected the labels unchanged but got %v", p.Labels())
+	}
+}
+
+func TestLabelsRoundTrip(t *testing.T) {
+	p := &Pattern{version: "0.909", tempo: 120, tracks: []*Track{{name: "kick"}, {name: "snare"}}}
+	p.SetLabels(Label{Name: "live-set", Color: "#ff0000"}, Label{Name: "wip"})
+	p.tracks[1].SetLabels(Label{Name: "ghost-notes", Color: "#00ff00"})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(p) || fmt.Sprint(decoded.Labels()) != fmt.Sprint(p.Labels()) {
+		t.Errorf("Expected labels %v but got %v", p.Labels(), decoded.Labels())
+	}
+	if exp, got := "[labels]", fmt.Sprint(p.Features()); got != exp {
+		t.Errorf("Expected features %v but got %v", exp, got)
+	}
+	if exp := "Labels: live-set (#ff0000), wip\n"; !strings.Contains(decoded.String(), exp) {
+		t.Errorf("Expected '%v' in the printout:\n%v", exp, decoded)
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`"labels":[{"name":"ghost-notes","color":"#00ff00"}]`)) {
+		t.Errorf("Expected the track labels in %s", data)
+	}
+	var fromJSON Pattern
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !fromJSON.Equal(p) || fmt.Sprint(fromJSON.Labels()) != fmt.Sprint(p.Labels()) {
+		t.Errorf("Expected %v but got %v", p, &fromJSON)
+	}
+
+	down, dropped := p.Downgrade(nil)
+	if fmt.Sprint(dropped) != "[labels]" || down.HasLabel("live-set") || down.HasLabel("ghost-notes") {
+		t.Errorf("Expected the labels dropped but got %v", dropped)
+	}
+}
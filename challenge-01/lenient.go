@@ -0,0 +1,46 @@
+package drum
+
+import "fmt"
+
+// stepMode tells how step bytes other than 0 and 1 are decoded.
+type stepMode uint8
+
+const (
+	strictSteps   stepMode = iota // reject them with ErrInvalidStepValue
+	lenientSteps                  // enable the step
+	velocitySteps                 // enable the step and keep the value as velocity
+)
+
+// WithLenientSteps makes the decoder accept any non-zero step byte as an
+// enabled step instead of failing with ErrInvalidStepValue, for files of
+// firmware writing velocities into the step bytes. With velocity set the
+// byte is kept as velocity of the step, values of 1 and above MaxVelocity
+// play at MaxVelocity. The steps are encoded as 0 and 1 again, so the raw
+// bytes are not written back.
+func WithLenientSteps(velocity bool) DecodeOption {
+	return func(o *decodeOptions) {
+		o.steps = lenientSteps
+		if velocity {
+			o.steps = velocitySteps
+		}
+	}
+}
+
+// decode sets the steps of t from the step bytes b.
+func (m stepMode) decode(b []byte, t *Track) error {
+	for i, v := range b {
+		enabled, err := byteToBool(v)
+		switch {
+		case err == nil:
+		case m == strictSteps:
+			return fmt.Errorf("step %d: %w", i+1, err)
+		case m == velocitySteps && v < MaxVelocity:
+			t.velocity[i] = v
+			fallthrough
+		default:
+			enabled = true
+		}
+		t.steps[i] = enabled
+	}
+	return nil
+}
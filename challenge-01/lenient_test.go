@@ -0,0 +1,57 @@
+package drum
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestLenientSteps(t *testing.T) {
+	raw, err := ioutil.ReadFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the steps of the first track (0) kick start at 59
+	data := append([]byte(nil), raw...)
+	data[59], data[60], data[63], data[67] = 0x7f, 0x40, 0xff, 1
+
+	if _, err := DecodeBytes(data); err == nil {
+		t.Error("Expected invalid step value")
+	}
+	if _, err := Decode(bytes.NewReader(data)); err == nil {
+		t.Error("Expected invalid step value")
+	}
+
+	exp := Steps{true, true, false, false, true, false, false, false, true, false, false, false, true}
+	for name, p := range map[string]func(opts ...DecodeOption) (*Pattern, error){
+		"Decode":      func(opts ...DecodeOption) (*Pattern, error) { return Decode(bytes.NewReader(data), opts...) },
+		"DecodeBytes": func(opts ...DecodeOption) (*Pattern, error) { return DecodeBytes(data, opts...) },
+	} {
+		lenient, err := p(WithLenientSteps(false))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		kick := lenient.Tracks()[0]
+		if kick.Steps() != exp {
+			t.Errorf("%s: Expected steps %v but got %v", name, exp, kick.Steps())
+		}
+		if kick.hasVelocity() {
+			t.Errorf("%s: Expected no velocity", name)
+		}
+
+		withVelocity, err := p(WithLenientSteps(true))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		kick = withVelocity.Tracks()[0]
+		if kick.Steps() != exp {
+			t.Errorf("%s: Expected steps %v but got %v", name, exp, kick.Steps())
+		}
+		for step, v := range map[int]uint8{0: MaxVelocity, 1: 0x40, 4: MaxVelocity, 8: MaxVelocity} {
+			if got := kick.Velocity(step); got != v {
+				t.Errorf("%s: Expected velocity %d of step %d but got %d", name, v, step, got)
+			}
+		}
+	}
+}
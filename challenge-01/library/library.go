@@ -0,0 +1,232 @@
+// Package library indexes collections of drum patterns stored as .splice
+// files and banks for search and deduplication.
+//
+// Patterns are keyed by their content hash, so the same groove saved under
+// another name, with another version string or with the tracks in another
+// order is found as duplicate. The
+// index is persisted as JSON. A Catalog adds the analysis and thumbnails of
+// the patterns for reports written with templates.
+package library
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// Entry describes an indexed pattern.
+type Entry struct {
+	Path    string   `json:"path"`           // path of the .splice file or the bank
+	Name    string   `json:"name,omitempty"` // entry name within a bank
+	Hash    string   `json:"hash"`           // content hash, see drum.Pattern.Hash
+	Version string   `json:"version"`
+	Tempo   float32  `json:"tempo"`
+	Tracks  []string `json:"tracks"`
+	Aliases []string `json:"aliases,omitempty"` // previous names of renamed tracks
+	Title   string   `json:"title,omitempty"`
+	Author  string   `json:"author,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Labels  []string `json:"labels,omitempty"` // of the pattern and its tracks
+}
+
+// String returns the location of the pattern.
+func (e Entry) String() string {
+	if e.Name == "" {
+		return e.Path
+	}
+	return e.Path + ":" + e.Name
+}
+
+// Index is a searchable list of patterns.
+type Index struct {
+	Entries []Entry           `json:"entries"`
+	Skipped map[string]string `json:"skipped,omitempty"` // files that could not be read with the reason
+}
+
+// Scan indexes all .splice files and banks (.zip) found in the directory
+// trees or files of roots. Files that fail to decode are recorded as
+// skipped, only errors walking the trees are returned.
+func Scan(roots ...string) (*Index, error) {
+	return ScanContext(context.Background(), roots...)
+}
+
+// ScanContext scans like Scan and stops with the context error when the
+// context is done before all files are indexed.
+func ScanContext(ctx context.Context, roots ...string) (*Index, error) {
+	ix := &Index{}
+	if err := ix.walk(ctx, roots, ix.Add); err != nil {
+		return nil, err
+	}
+	ix.sort()
+	return ix, nil
+}
+
+// walk calls add for every pattern of the .splice files and banks of roots
+// and records the files that fail to decode as skipped.
+func (ix *Index) walk(ctx context.Context, roots []string, add func(path, name string, p *drum.Pattern)) error {
+	for _, root := range roots {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".splice":
+				p, err := drum.DecodeFile(path)
+				if err != nil {
+					ix.skip(path, err)
+					return nil
+				}
+				add(path, "", p)
+			case ".zip":
+				return ix.addBank(ctx, path, add)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addBank adds the patterns of the bank. Only the context error is returned.
+func (ix *Index) addBank(ctx context.Context, path string, add func(path, name string, p *drum.Pattern)) error {
+	b, err := drum.OpenBank(path)
+	if err != nil {
+		ix.skip(path, err)
+		return nil
+	}
+	defer b.Close()
+	for i := 0; i < b.Len(); i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		e := b.Entry(i)
+		p, err := e.Pattern()
+		if err != nil {
+			ix.skip(path+":"+e.Name(), err)
+			continue
+		}
+		add(path, e.Name(), p)
+	}
+	return nil
+}
+
+func (ix *Index) skip(path string, err error) {
+	if ix.Skipped == nil {
+		ix.Skipped = make(map[string]string)
+	}
+	ix.Skipped[path] = err.Error()
+}
+
+// Add adds the pattern found at path and name, see Entry.
+func (ix *Index) Add(path, name string, p *drum.Pattern) {
+	ix.Entries = append(ix.Entries, newEntry(path, name, p))
+}
+
+// Update replaces the entry of the pattern at path and name, for example
+// after tracks were renamed with drum.Pattern.RenameTrack, or adds it when
+// it is not indexed yet.
+func (ix *Index) Update(path, name string, p *drum.Pattern) {
+	for i, e := range ix.Entries {
+		if e.Path == path && e.Name == name {
+			ix.Entries[i] = newEntry(path, name, p)
+			return
+		}
+	}
+	ix.Add(path, name, p)
+	ix.sort()
+}
+
+func newEntry(path, name string, p *drum.Pattern) Entry {
+	e := Entry{Path: path, Name: name, Hash: p.Hash(), Version: p.Version(), Tempo: p.Tempo(), Tracks: []string{}}
+	addLabels := func(labels []drum.Label) {
+		for _, l := range labels {
+			if !anyMatch(e.Labels, func(name string) bool { return strings.EqualFold(name, l.Name) }) {
+				e.Labels = append(e.Labels, l.Name)
+			}
+		}
+	}
+	addLabels(p.Labels())
+	for _, t := range p.Tracks() {
+		e.Tracks = append(e.Tracks, t.Name())
+		e.Aliases = append(e.Aliases, t.Aliases()...)
+		addLabels(t.Labels())
+	}
+	m := p.Metadata()
+	e.Title, e.Author, e.Tags = m.Title, m.Author, m.Tags
+	return e
+}
+
+func (ix *Index) sort() {
+	sort.SliceStable(ix.Entries, func(i, j int) bool {
+		return entryLess(ix.Entries[i], ix.Entries[j])
+	})
+}
+
+// entryLess orders entries by path and name within a bank.
+func entryLess(a, b Entry) bool {
+	return a.Path < b.Path || a.Path == b.Path && a.Name < b.Name
+}
+
+// Lookup returns the entries with the content hash.
+func (ix *Index) Lookup(hash string) []Entry {
+	var entries []Entry
+	for _, e := range ix.Entries {
+		if e.Hash == hash {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Duplicates returns the groups of entries sharing a content hash, ordered
+// by the first entry of each group.
+func (ix *Index) Duplicates() [][]Entry {
+	groups := make(map[string][]Entry)
+	var order []string
+	for _, e := range ix.Entries {
+		if _, ok := groups[e.Hash]; !ok {
+			order = append(order, e.Hash)
+		}
+		groups[e.Hash] = append(groups[e.Hash], e)
+	}
+	var dups [][]Entry
+	for _, h := range order {
+		if len(groups[h]) > 1 {
+			dups = append(dups, groups[h])
+		}
+	}
+	return dups
+}
+
+// Load reads an index saved with Save.
+func Load(path string) (*Index, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ix := &Index{}
+	if err := json.Unmarshal(b, ix); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// Save writes the index as JSON to path.
+func (ix *Index) Save(path string) error {
+	b, err := json.MarshalIndent(ix, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
@@ -0,0 +1,167 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// testLibrary creates a directory with the fixtures, a copy of pattern 1
+// saved with another version, a bank holding pattern 2 and a broken file.
+func testLibrary(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "library")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures := filepath.Join("..", "fixtures")
+	patterns := make([]*drum.Pattern, 5)
+	for i := range patterns {
+		name := fmt.Sprintf("pattern_%d.splice", i+1)
+		b, err := ioutil.ReadFile(filepath.Join(fixtures, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if patterns[i], err = drum.DecodeFile(filepath.Join(fixtures, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Mkdir(filepath.Join(dir, "copies"), 0755)
+	p, err := drum.NewPattern("0.909", patterns[0].Tempo(), patterns[0].Tracks()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := drum.EncodeFile(filepath.Join(dir, "copies", "renamed.splice"), p); err != nil {
+		t.Fatal(err)
+	}
+	var bank drum.Bank
+	bank.Add("groove.splice", patterns[1])
+	if err := bank.Save(filepath.Join(dir, "copies", "bank.zip")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "broken.splice"), []byte("SPLICE"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestScan(t *testing.T) {
+	dir := testLibrary(t)
+	defer os.RemoveAll(dir)
+	ix, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ix.Entries) != 7 || len(ix.Skipped) != 1 || ix.Skipped[filepath.Join(dir, "broken.splice")] == "" {
+		t.Fatalf("unexpected index %+v", ix)
+	}
+
+	dups := ix.Duplicates()
+	var got []string
+	for _, group := range dups {
+		got = append(got, fmt.Sprint(group[0].String()[len(dir)+1:], " ", group[1].String()[len(dir)+1:]))
+	}
+	exp := fmt.Sprint([]string{"copies/bank.zip:groove.splice pattern_2.splice", "copies/renamed.splice pattern_1.splice"})
+	if fmt.Sprint(got) != exp {
+		t.Errorf("Expected duplicates %v but got %v", exp, got)
+	}
+	if got := ix.Lookup(dups[1][0].Hash); len(got) != 2 || got[0].Version != "0.909" || got[1].Version != "0.808-alpha" {
+		t.Errorf("unexpected lookup %+v", got)
+	}
+
+	path := filepath.Join(dir, "index.json")
+	if err := ix.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(loaded) != fmt.Sprint(ix) {
+		t.Errorf("Expected loaded index %v but got %v", ix, loaded)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ix := &Index{Entries: []Entry{
+		{Path: "a", Tempo: 120, Tracks: []string{"kick", "Clap"}, Tags: []string{"house"}, Author: "alpe"},
+		{Path: "b", Tempo: 128, Tracks: []string{"kick", "hh-open"}},
+		{Path: "c", Tempo: 98.4, Tracks: []string{"kick", "clap"}},
+		{Path: "d", Tempo: 90, Tracks: []string{"Rim"}, Aliases: []string{"snare"}, Labels: []string{"live-set"}},
+	}}
+	specs := map[string]string{
+		"tempo between 120 and 128, has a track named clap": "[a]",
+		"tempo 120–128":               "[a b]",
+		"tempo 98.4":                  "[c]",
+		"track 'hh-*' and track kick": "[b]",
+		"has track clap":              "[a c]",
+		"tagged HOUSE and by alpe":    "[a]",
+		"track snare":                 "[d]",
+		"tagged 'Live-Set'":           "[d]",
+		"":                            "[a b c d]",
+	}
+	for query, exp := range specs {
+		entries, err := ix.Search(query)
+		if err != nil {
+			t.Errorf("%q: %v", query, err)
+			continue
+		}
+		if got := fmt.Sprint(entries); got != exp {
+			t.Errorf("%q: expected %v but got %v", query, exp, got)
+		}
+	}
+	for _, query := range []string{"tempo", "tempo fast", "tempo between 128 and 120", "has a drum", "track", "bpm 120", "tempo 120 or tempo 128", "track '[a'"} {
+		if _, err := ix.Search(query); err == nil {
+			t.Errorf("%q: expected error", query)
+		}
+	}
+}
+
+func TestScanContext(t *testing.T) {
+	dir := testLibrary(t)
+	defer os.RemoveAll(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ScanContext(ctx, dir); err != context.Canceled {
+		t.Errorf("Expected error '%v' but got '%v'", context.Canceled, err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	p, err := drum.DecodeFile(filepath.Join("..", "fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix := &Index{}
+	ix.Add("b.splice", "", p)
+	ix.Update("a.splice", "", p)
+	if err := p.RenameTrack("kick", "boom"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetLabels(drum.Label{Name: "Live-Set", Color: "#ff0000"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Tracks()[1].SetLabels(drum.Label{Name: "live-set"}, drum.Label{Name: "fill"}); err != nil {
+		t.Fatal(err)
+	}
+	ix.Update("b.splice", "", p)
+	if len(ix.Entries) != 2 || ix.Entries[0].Path != "a.splice" {
+		t.Fatalf("unexpected entries %v", ix.Entries)
+	}
+	if got := ix.Entries[1]; got.Tracks[0] != "boom" || fmt.Sprint(got.Aliases) != "[kick]" {
+		t.Errorf("Expected renamed track with alias but got %+v", got)
+	}
+	if got := ix.Find(Query{Tracks: []string{"kick"}}); len(got) != 2 {
+		t.Errorf("Expected both entries for the old name but got %v", got)
+	}
+	if got, _ := ix.Search("tagged live-set and tagged fill"); fmt.Sprint(got) != "[b.splice]" || fmt.Sprint(got[0].Labels) != "[Live-Set fill]" {
+		t.Errorf("Expected the labelled entry but got %v", got)
+	}
+}
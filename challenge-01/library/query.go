@@ -0,0 +1,211 @@
+package library
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Query filters the entries of an index. All set conditions must match.
+type Query struct {
+	MinTempo, MaxTempo float32  // 0 for no bound
+	Tracks             []string // globs that must each match a track name or alias
+	Tags               []string // tags or labels that must all be set
+	Author             string
+}
+
+// ParseQuery parses a query of conditions separated by "and" or commas, for
+// example:
+//
+//	tempo between 120 and 128, has a track named clap
+//	tempo 120-128 and track 'hh-*'
+//	tempo 90 and tagged boom-bap and by alpe
+//
+// Track names and tags are matched case insensitive, track names with
+// path.Match glob rules. Tags also match the labels of the patterns and
+// their tracks.
+func ParseQuery(s string) (Query, error) {
+	tokens, err := tokenize(strings.NewReplacer(",", " , ", "–", "-").Replace(s))
+	if err != nil {
+		return Query{}, fmt.Errorf("parse query %q: %v", s, err)
+	}
+	var q Query
+	for i := 0; i < len(tokens); {
+		n, err := q.parseCondition(tokens[i:])
+		if err != nil {
+			return Query{}, fmt.Errorf("parse query %q: %v", s, err)
+		}
+		i += n
+		if i < len(tokens) {
+			if t := strings.ToLower(tokens[i]); t != "and" && t != "," {
+				return Query{}, fmt.Errorf("parse query %q: unexpected %q", s, tokens[i])
+			}
+			i++
+		}
+	}
+	return q, nil
+}
+
+// parseCondition parses the condition at the start of tokens and returns the
+// number of tokens used.
+func (q *Query) parseCondition(tokens []string) (int, error) {
+	word := func(i int) string {
+		if i < len(tokens) {
+			return strings.ToLower(tokens[i])
+		}
+		return ""
+	}
+	value := func(i int) (string, error) {
+		if i >= len(tokens) || word(i) == "," {
+			return "", fmt.Errorf("missing value after %q", tokens[i-1])
+		}
+		return tokens[i], nil
+	}
+	switch word(0) {
+	case "tempo":
+		if word(1) == "between" {
+			if word(3) != "and" {
+				return 0, fmt.Errorf("expected tempo between <min> and <max>")
+			}
+			return 5, q.setTempo(word(2), word(4))
+		}
+		v, err := value(1)
+		if err != nil {
+			return 0, err
+		}
+		min, max := v, v
+		if n := strings.IndexByte(v, '-'); n > 0 {
+			min, max = v[:n], v[n+1:]
+		}
+		return 2, q.setTempo(min, max)
+	case "has", "track":
+		i := 0
+		if word(i) == "has" {
+			i++
+			if word(i) == "a" {
+				i++
+			}
+			if word(i) != "track" {
+				return 0, fmt.Errorf("expected track but got %q", word(i))
+			}
+		}
+		i++
+		if word(i) == "named" || word(i) == "matching" {
+			i++
+		}
+		v, err := value(i)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := path.Match(v, ""); err != nil {
+			return 0, fmt.Errorf("invalid track pattern %q", v)
+		}
+		q.Tracks = append(q.Tracks, strings.ToLower(v))
+		return i + 1, nil
+	case "tag", "tagged":
+		v, err := value(1)
+		q.Tags = append(q.Tags, strings.ToLower(v))
+		return 2, err
+	case "by", "author":
+		v, err := value(1)
+		q.Author = v
+		return 2, err
+	}
+	return 0, fmt.Errorf("unknown condition %q", tokens[0])
+}
+
+func (q *Query) setTempo(min, max string) error {
+	lo, err := strconv.ParseFloat(min, 32)
+	if err != nil {
+		return fmt.Errorf("invalid tempo %q", min)
+	}
+	hi, err := strconv.ParseFloat(max, 32)
+	if err != nil || hi < lo {
+		return fmt.Errorf("invalid tempo %q", max)
+	}
+	q.MinTempo, q.MaxTempo = float32(lo), float32(hi)
+	return nil
+}
+
+// Match reports whether the entry matches all conditions.
+func (q Query) Match(e Entry) bool {
+	if q.MinTempo != 0 && e.Tempo < q.MinTempo || q.MaxTempo != 0 && e.Tempo > q.MaxTempo {
+		return false
+	}
+	if q.Author != "" && !strings.EqualFold(q.Author, e.Author) {
+		return false
+	}
+	for _, glob := range q.Tracks {
+		match := func(name string) bool {
+			ok, _ := path.Match(glob, strings.ToLower(name))
+			return ok
+		}
+		if !anyMatch(e.Tracks, match) && !anyMatch(e.Aliases, match) {
+			return false
+		}
+	}
+	for _, tag := range q.Tags {
+		match := func(t string) bool { return strings.EqualFold(t, tag) }
+		if !anyMatch(e.Tags, match) && !anyMatch(e.Labels, match) {
+			return false
+		}
+	}
+	return true
+}
+
+func anyMatch(values []string, match func(string) bool) bool {
+	for _, v := range values {
+		if match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Search returns the entries matching the query, see ParseQuery.
+func (ix *Index) Search(query string) ([]Entry, error) {
+	q, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return ix.Find(q), nil
+}
+
+// Find returns the entries matching q.
+func (ix *Index) Find(q Query) []Entry {
+	var entries []Entry
+	for _, e := range ix.Entries {
+		if q.Match(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// tokenize splits s at white spaces. Single or double quoted strings are
+// returned as one token without the quotes.
+func tokenize(s string) ([]string, error) {
+	var tokens []string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return tokens, nil
+		}
+		if c := s[0]; c == '\'' || c == '"' {
+			n := strings.IndexByte(s[1:], c)
+			if n < 0 {
+				return nil, fmt.Errorf("unterminated quote")
+			}
+			tokens = append(tokens, s[1:n+1])
+			s = s[n+2:]
+			continue
+		}
+		n := strings.IndexAny(s, " \t")
+		if n < 0 {
+			n = len(s)
+		}
+		tokens = append(tokens, s[:n])
+		s = s[n:]
+	}
+}
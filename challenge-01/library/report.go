@@ -0,0 +1,163 @@
+package library
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	texttemplate "text/template"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// TempoBinWidth is the range of tempos in BPM counted by a TempoBin.
+const TempoBinWidth = 10
+
+// ThumbnailStyle is the style of the SVG thumbnails of a catalog.
+var ThumbnailStyle = drum.GridStyle{CellSize: 8}
+
+// Catalog is the data of report templates: the patterns of a library with
+// their analysis and thumbnail and a histogram of their tempos.
+type Catalog struct {
+	Title    string
+	Patterns []CatalogEntry
+	Tempos   []TempoBin
+	Skipped  map[string]string // files that could not be read with the reason
+}
+
+// CatalogEntry is an indexed pattern with its analysis.
+type CatalogEntry struct {
+	Entry
+	Report drum.Report
+	SVG    template.HTML // step grid in ThumbnailStyle, see drum.Pattern.ToSVG
+}
+
+// TempoBin counts the patterns with a tempo from From up to To.
+type TempoBin struct {
+	From, To float32
+	Count    int
+	Percent  int // of the largest bin, for example for the width of bars
+}
+
+// NewCatalog scans the roots like Scan and analyzes every pattern found.
+func NewCatalog(ctx context.Context, title string, roots ...string) (*Catalog, error) {
+	c := &Catalog{Title: title, Patterns: []CatalogEntry{}}
+	ix := &Index{}
+	err := ix.walk(ctx, roots, func(path, name string, p *drum.Pattern) {
+		ix.Add(path, name, p)
+		var svg bytes.Buffer
+		p.ToSVG(&svg, ThumbnailStyle) // writing to a buffer does not fail
+		c.Patterns = append(c.Patterns, CatalogEntry{
+			Entry:  ix.Entries[len(ix.Entries)-1],
+			Report: drum.Analyze(p),
+			SVG:    template.HTML(svg.String()),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(c.Patterns, func(i, j int) bool {
+		return entryLess(c.Patterns[i].Entry, c.Patterns[j].Entry)
+	})
+	c.Skipped = ix.Skipped
+	c.Tempos = tempoHistogram(c.Patterns)
+	return c, nil
+}
+
+// tempoHistogram returns the bins from the slowest to the fastest pattern,
+// including the empty ones in between.
+func tempoHistogram(entries []CatalogEntry) []TempoBin {
+	if len(entries) == 0 {
+		return nil
+	}
+	bin := func(tempo float32) int {
+		return int(math.Floor(float64(tempo) / TempoBinWidth))
+	}
+	first, last := bin(entries[0].Tempo), bin(entries[0].Tempo)
+	for _, e := range entries {
+		first, last = min(first, bin(e.Tempo)), max(last, bin(e.Tempo))
+	}
+	bins := make([]TempoBin, last-first+1)
+	for i := range bins {
+		bins[i].From = float32((first + i) * TempoBinWidth)
+		bins[i].To = bins[i].From + TempoBinWidth
+	}
+	largest := 0
+	for _, e := range entries {
+		b := &bins[bin(e.Tempo)-first]
+		b.Count++
+		largest = max(largest, b.Count)
+	}
+	for i := range bins {
+		bins[i].Percent = bins[i].Count * 100 / largest
+	}
+	return bins
+}
+
+// WriteHTML executes the html/template tmpl with the catalog, the
+// DefaultHTMLTemplate when tmpl is empty.
+func (c *Catalog) WriteHTML(w io.Writer, tmpl string) error {
+	if tmpl == "" {
+		tmpl = DefaultHTMLTemplate
+	}
+	t, err := template.New("report").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("parse report template: %v", err)
+	}
+	return t.Execute(w, c)
+}
+
+// WriteText executes the text/template tmpl with the catalog, for example to
+// write Markdown or CSV reports.
+func (c *Catalog) WriteText(w io.Writer, tmpl string) error {
+	t, err := texttemplate.New("report").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("parse report template: %v", err)
+	}
+	return t.Execute(w, c)
+}
+
+// DefaultHTMLTemplate is a self-contained HTML index of the catalog with the
+// tempo histogram and a table of the patterns with their thumbnails.
+const DefaultHTMLTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { padding: 4px 8px; text-align: left; vertical-align: middle; }
+tr:nth-child(even) { background: #f6f6f6; }
+.bar { background: #f28c28; height: 1em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<h2>Tempos</h2>
+<table>
+{{- range .Tempos}}
+<tr><td>{{.From}}–{{.To}} BPM</td><td>{{.Count}}</td><td style="width: 300px"><div class="bar" style="width: {{.Percent}}%"></div></td></tr>
+{{- end}}
+</table>
+<h2>Patterns</h2>
+<table>
+<tr><th></th><th>Pattern</th><th>Tempo</th><th>Tracks</th><th>Density</th><th>Syncopation</th><th>Complexity</th><th>Backbeat</th><th>Tags</th></tr>
+{{- range .Patterns}}
+<tr><td>{{.SVG}}</td><td>{{if .Title}}{{.Title}}<br>{{end}}<small>{{.String}}</small></td><td>{{.Tempo}}</td><td>{{len .Tracks}}</td><td>{{printf "%.2f" .Report.Density}}</td><td>{{printf "%.2f" .Report.Syncopation}}</td><td>{{printf "%.2f" .Report.Complexity}}</td><td>{{if .Report.Backbeat}}yes{{end}}</td><td>{{range $i, $t := .Tags}}{{if $i}}, {{end}}{{$t}}{{end}}</td></tr>
+{{- end}}
+</table>
+{{- if .Skipped}}
+<h2>Skipped</h2>
+<ul>
+{{- range $path, $reason := .Skipped}}
+<li>{{$path}}: {{$reason}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+`
@@ -0,0 +1,59 @@
+package library
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+	dir := testLibrary(t)
+	defer os.RemoveAll(dir)
+	c, err := NewCatalog(context.Background(), "Fixtures", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Patterns) != 7 || len(c.Skipped) != 1 {
+		t.Fatalf("unexpected catalog %+v", c)
+	}
+	for i, e := range c.Patterns[1:] {
+		if !entryLess(c.Patterns[i].Entry, e.Entry) {
+			t.Errorf("Expected the patterns ordered by path but got %v before %v", c.Patterns[i], e)
+		}
+		if !strings.HasPrefix(string(e.SVG), "<svg") || e.Report.Tempo != e.Tempo {
+			t.Errorf("Expected %v with thumbnail and analysis", e)
+		}
+	}
+	total := 0
+	for i, b := range c.Tempos {
+		total += b.Count
+		if b.To-b.From != TempoBinWidth || i > 0 && b.From != c.Tempos[i-1].To || b.Percent > 100 {
+			t.Errorf("unexpected bin %+v", b)
+		}
+	}
+	if total != 7 || c.Tempos[0].Count == 0 || c.Tempos[len(c.Tempos)-1].Count == 0 {
+		t.Errorf("Expected all patterns in the histogram but got %+v", c.Tempos)
+	}
+
+	var buf bytes.Buffer
+	if err := c.WriteHTML(&buf, ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range []string{"<title>Fixtures</title>", "<td><svg", "pattern_1.splice", "broken.splice: "} {
+		if !strings.Contains(buf.String(), exp) {
+			t.Errorf("Expected the index to contain %q but got\n%s", exp, buf.String())
+		}
+	}
+	buf.Reset()
+	if err := c.WriteText(&buf, "{{range .Tempos}}{{.From}}:{{.Count}} {{end}}"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "90:1 ") {
+		t.Errorf("unexpected text report %q", buf.String())
+	}
+	if err := c.WriteText(&buf, "{{.Missing"); err == nil || !strings.HasPrefix(err.Error(), "parse report template") {
+		t.Errorf("Expected a parse error but got %v", err)
+	}
+}
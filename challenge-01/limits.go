@@ -0,0 +1,88 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ErrLimitExceeded is returned when a file exceeds the Limits of the decoder.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// Limits cap the resources a file may make the decoder use, so that hostile
+// input cannot exhaust the memory. A zero field disables the check.
+type Limits struct {
+	MaxPayloadSize int64 // declared size of the payload in bytes
+	MaxTracks      int   // number of tracks
+	MaxNameLength  int   // length of a track name in bytes
+	MaxExtraSize   int64 // size of the extensions and raw extra data
+}
+
+// defaultLimits are far above anything the hardware writes.
+var defaultLimits = Limits{
+	MaxPayloadSize: 1 << 20,
+	MaxTracks:      1024,
+	MaxNameLength:  math.MaxUint8,
+	MaxExtraSize:   1 << 20,
+}
+
+// DefaultLimits returns the limits enforced when WithLimits is not given.
+func DefaultLimits() Limits {
+	return defaultLimits
+}
+
+// WithLimits replaces the default limits of the decoder. Use Limits{} to
+// decode trusted files of any size.
+func WithLimits(l Limits) DecodeOption {
+	return func(o *decodeOptions) {
+		o.limits = l
+	}
+}
+
+func (l Limits) checkPayloadSize(size int64) error {
+	if l.MaxPayloadSize > 0 && size > l.MaxPayloadSize {
+		return fmt.Errorf("%w (%w): %d bytes exceed %d", ErrPayloadTooLarge, ErrLimitExceeded, size, l.MaxPayloadSize)
+	}
+	return nil
+}
+
+func (l Limits) checkTracks(n int) error {
+	if l.MaxTracks > 0 && n > l.MaxTracks {
+		return fmt.Errorf("%w (%w): more than %d", ErrTooManyTracks, ErrLimitExceeded, l.MaxTracks)
+	}
+	return nil
+}
+
+func (l Limits) checkNameLength(n int) error {
+	if l.MaxNameLength > 0 && n > l.MaxNameLength {
+		return fmt.Errorf("%w (%w): %d bytes exceed %d", ErrNameTooLong, ErrLimitExceeded, n, l.MaxNameLength)
+	}
+	return nil
+}
+
+func (l Limits) checkExtraSize(n int64) error {
+	if l.MaxExtraSize > 0 && n > l.MaxExtraSize {
+		return fmt.Errorf("%w: %d bytes behind the payload exceed %d", ErrLimitExceeded, n, l.MaxExtraSize)
+	}
+	return nil
+}
+
+// MaxFileSize returns the size of the largest file within the limits, with
+// a checksum trailer, 0 when it is not limited. Services can cap uploads at
+// it before decoding.
+func (l Limits) MaxFileSize() int64 {
+	if l.MaxPayloadSize <= 0 || l.MaxExtraSize <= 0 {
+		return 0
+	}
+	return int64(typeHeaderLength) + 8 + l.MaxPayloadSize + 4 + l.MaxExtraSize
+}
+
+// extraReader limits r to the data behind the payload within the limits plus
+// one byte.
+func (l Limits) extraReader(r io.Reader) io.Reader {
+	if l.MaxExtraSize <= 0 {
+		return r
+	}
+	return io.LimitReader(r, l.MaxExtraSize+1)
+}
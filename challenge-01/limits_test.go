@@ -0,0 +1,122 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/alpe/go-challenge/challenge-01/corpus"
+)
+
+// endlessTracks returns a file declaring the given payload size and tracks
+// without end.
+func endlessTracks(size int64) io.Reader {
+	var header bytes.Buffer
+	header.WriteString(spliceTypePattern)
+	binary.Write(&header, binary.BigEndian, size)
+	header.Write(make([]byte, maxVersionLength))
+	binary.Write(&header, binary.LittleEndian, float32(120))
+	track := append([]byte{1, 0, 0, 0, 1, 'x'}, make([]byte, stepsLength)...)
+	return io.MultiReader(&header, &repeatReader{data: track})
+}
+
+type repeatReader struct {
+	data []byte
+	off  int
+}
+
+func (r *repeatReader) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = r.data[r.off]
+		r.off = (r.off + 1) % len(r.data)
+	}
+	return len(b), nil
+}
+
+func TestLimits(t *testing.T) {
+	raw, err := ioutil.ReadFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	junk := append(append([]byte(nil), raw...), make([]byte, 2<<20)...)
+	huge := append([]byte(nil), raw...)
+	binary.BigEndian.PutUint64(huge[typeHeaderLength:], 1<<40)
+
+	specs := map[string]struct {
+		r    io.Reader
+		opts []DecodeOption
+	}{
+		"payload size":  {bytes.NewReader(huge), nil},
+		"tracks":        {endlessTracks(1 << 20), nil},
+		"name length":   {bytes.NewReader(raw), []DecodeOption{WithLimits(Limits{MaxNameLength: 4})}},
+		"extra size":    {bytes.NewReader(junk), nil},
+		"custom tracks": {bytes.NewReader(raw), []DecodeOption{WithLimits(Limits{MaxTracks: 5})}},
+	}
+	for msg, spec := range specs {
+		data, _ := ioutil.ReadAll(io.LimitReader(spec.r, 4<<20))
+		if _, err := Decode(bytes.NewReader(data), spec.opts...); !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("%s: Expected limit exceeded but got %v", msg, err)
+		}
+		if _, err := DecodeBytes(data, spec.opts...); !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("%s: Expected limit exceeded from DecodeBytes but got %v", msg, err)
+		}
+	}
+	if _, err := Decode(endlessTracks(1 << 40)); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected limit exceeded for endless payload but got %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "limits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "junk.splice")
+	if err := ioutil.WriteFile(file, junk, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeFile(file); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected limit exceeded from DecodeFile but got %v", err)
+	}
+	p, err := DecodeFile(file, WithLimits(Limits{}))
+	if err != nil {
+		t.Fatalf("Expected no limits but got %v", err)
+	}
+	if len(p.rawExtra) != 2<<20 {
+		t.Errorf("Expected the junk kept as raw extra but got %d bytes", len(p.rawExtra))
+	}
+	if DefaultLimits() != defaultLimits {
+		t.Error("Expected the default limits")
+	}
+}
+
+// FuzzDecode locks in that no input makes the decoders panic or allocate
+// beyond the limits, and that both decoders agree.
+func FuzzDecode(f *testing.F) {
+	files, _ := filepath.Glob(filepath.Join("fixtures", "*.splice"))
+	for _, name := range files {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	for _, data := range corpus.Seeds(1, 20) {
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p, err := Decode(bytes.NewReader(data))
+		fp, ferr := DecodeBytes(data)
+		if (err == nil) != (ferr == nil) {
+			t.Fatalf("Decode returned %v but DecodeBytes %v", err, ferr)
+		}
+		if err == nil && !p.Equal(fp) {
+			t.Fatalf("Decode returned %v but DecodeBytes %v", p, fp)
+		}
+	})
+}
@@ -0,0 +1,279 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The Ableton Link protocol consists of the discovery of peers by multicast
+// messages announcing their session timeline and of the measurement of the
+// session clock by pings to a peer. Both use payload entries of a four byte
+// key, a four byte size and the value, all big endian.
+//
+//	|_asdp_v\x01 (8 bytes)|Type (1 byte)|TTL (1 byte)|Group (2 bytes)|Node id (8 bytes)|Entries|  => Discovery
+//	|_link_v\x01 (8 bytes)|Type (1 byte)|Entries|                                           => Measurement
+const linkMulticastAddr = "224.76.78.75:20808"
+
+var (
+	linkDiscoveryHeader   = []byte("_asdp_v\x01")
+	linkMeasurementHeader = []byte("_link_v\x01")
+)
+
+// Message types.
+const (
+	linkAlive    = 1
+	linkResponse = 2
+	linkByeBye   = 3
+
+	linkPing = 1
+	linkPong = 2
+)
+
+// Payload keys.
+const (
+	linkKeyTimeline  = 't'<<24 | 'm'<<16 | 'l'<<8 | 'n'
+	linkKeySession   = 's'<<24 | 'e'<<16 | 's'<<8 | 's'
+	linkKeyEndpoint  = 'm'<<24 | 'e'<<16 | 'p'<<8 | '4'
+	linkKeyHostTime  = '_'<<24 | '_'<<16 | 'h'<<8 | 't'
+	linkKeyGhostTime = '_'<<24 | '_'<<16 | 'g'<<8 | 't'
+)
+
+// linkPings is the number of pings of a measurement, the median of the
+// measured clock offsets is used.
+const linkPings = 5
+
+// linkPingTimeout is the time to wait for a pong.
+const linkPingTimeout = 500 * time.Millisecond
+
+var errLinkMessage = errors.New("invalid link message")
+
+// linkTimeline maps the beats of a session to its clock, the ghost time.
+type linkTimeline struct {
+	MicrosPerBeat int64
+	BeatOrigin    int64 // in millionths of a beat
+	TimeOrigin    int64 // ghost time in microseconds
+}
+
+// linkPeer is the state a peer announces.
+type linkPeer struct {
+	id       [8]byte
+	session  [8]byte
+	timeline linkTimeline
+	endpoint *net.UDPAddr // measurement endpoint
+	expires  time.Time
+}
+
+// parseLinkEntries returns the payload entries by key.
+func parseLinkEntries(b []byte) (map[uint32][]byte, error) {
+	entries := make(map[uint32][]byte)
+	for len(b) > 0 {
+		if len(b) < 8 {
+			return nil, errLinkMessage
+		}
+		key, size := binary.BigEndian.Uint32(b), binary.BigEndian.Uint32(b[4:])
+		if uint64(size) > uint64(len(b)-8) {
+			return nil, errLinkMessage
+		}
+		entries[key] = b[8 : 8+size]
+		b = b[8+size:]
+	}
+	return entries, nil
+}
+
+// parseLinkDiscovery parses a discovery message received at now. The
+// timeline and endpoint are only set for alive and response messages.
+func parseLinkDiscovery(b []byte, now time.Time) (peer linkPeer, typ byte, err error) {
+	const headerLength = 20
+	if len(b) < headerLength || !bytes.HasPrefix(b, linkDiscoveryHeader) {
+		return peer, 0, errLinkMessage
+	}
+	typ = b[8]
+	peer.expires = now.Add(time.Duration(b[9]) * time.Second)
+	copy(peer.id[:], b[12:20])
+	if typ == linkByeBye {
+		return peer, typ, nil
+	}
+	entries, err := parseLinkEntries(b[headerLength:])
+	if err != nil {
+		return peer, 0, err
+	}
+	tl, sess, ep := entries[linkKeyTimeline], entries[linkKeySession], entries[linkKeyEndpoint]
+	if len(tl) != 24 || len(sess) != 8 || len(ep) != 6 {
+		return peer, 0, errLinkMessage
+	}
+	binary.Read(bytes.NewReader(tl), binary.BigEndian, &peer.timeline)
+	if peer.timeline.MicrosPerBeat <= 0 {
+		return peer, 0, errLinkMessage
+	}
+	copy(peer.session[:], sess)
+	peer.endpoint = &net.UDPAddr{IP: net.IP(append([]byte(nil), ep[:4]...)), Port: int(binary.BigEndian.Uint16(ep[4:]))}
+	return peer, typ, nil
+}
+
+// appendLinkEntry appends a payload entry with an int64 value.
+func appendLinkEntry(b []byte, key uint32, v int64) []byte {
+	var entry [16]byte
+	binary.BigEndian.PutUint32(entry[:], key)
+	binary.BigEndian.PutUint32(entry[4:], 8)
+	binary.BigEndian.PutUint64(entry[8:], uint64(v))
+	return append(b, entry[:]...)
+}
+
+// LinkSession follows the tempo and beat timeline of an Ableton Link session
+// on the local network and implements Sync. It only listens, peers don't see
+// it as a participant and tempo changes are not sent to the session.
+type LinkSession struct {
+	conn  net.PacketConn
+	start time.Time // origin of the host time
+
+	mu        sync.Mutex
+	peer      *linkPeer // peer whose timeline is followed
+	offset    int64     // ghost time minus host time in microseconds
+	measured  bool
+	closed    bool
+	measuring sync.WaitGroup
+}
+
+// JoinLink joins the Link multicast group and follows the first session
+// found. It fails with ErrOffline in offline mode.
+func JoinLink() (*LinkSession, error) {
+	if err := AllowNetwork("link"); err != nil {
+		return nil, err
+	}
+	addr, err := net.ResolveUDPAddr("udp4", linkMulticastAddr)
+	if err != nil {
+		return nil, fmt.Errorf("link: %v", err)
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, addr)
+	if err != nil {
+		return nil, fmt.Errorf("link: %v", err)
+	}
+	return newLinkSession(conn), nil
+}
+
+// newLinkSession follows the discovery messages received on conn.
+func newLinkSession(conn net.PacketConn) *LinkSession {
+	s := &LinkSession{conn: conn, start: time.Now()}
+	go s.listen()
+	return s
+}
+
+// Close leaves the session.
+func (s *LinkSession) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	err := s.conn.Close()
+	s.measuring.Wait()
+	return err
+}
+
+// Timeline implements Sync. It has no timeline until the clock of a session
+// has been measured or after the last peer of the session left.
+func (s *LinkSession) Timeline(t time.Time) (tempo, beat float64, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.peer == nil || !s.measured || t.After(s.peer.expires) {
+		return 0, 0, false
+	}
+	tl := s.peer.timeline
+	ghost := s.hostTime(t) + s.offset
+	beat = float64(tl.BeatOrigin)/1e6 + float64(ghost-tl.TimeOrigin)/float64(tl.MicrosPerBeat)
+	return 6e7 / float64(tl.MicrosPerBeat), beat, true
+}
+
+// hostTime returns the local clock in microseconds.
+func (s *LinkSession) hostTime(t time.Time) int64 {
+	return int64(t.Sub(s.start) / time.Microsecond)
+}
+
+func (s *LinkSession) listen() {
+	buf := make([]byte, 1<<16)
+	for {
+		n, _, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		s.handleDiscovery(buf[:n], time.Now())
+	}
+}
+
+// handleDiscovery updates the followed timeline. A new session is followed
+// when the peer of the current one left or expired.
+func (s *LinkSession) handleDiscovery(b []byte, now time.Time) {
+	peer, typ, err := parseLinkDiscovery(b, now)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current := s.peer != nil && now.Before(s.peer.expires)
+	switch {
+	case typ == linkByeBye:
+		if s.peer != nil && s.peer.id == peer.id {
+			s.peer, s.measured = nil, false
+		}
+	case typ != linkAlive && typ != linkResponse:
+	case current && s.peer.session == peer.session:
+		s.peer = &peer
+	case !current:
+		s.peer, s.measured = &peer, false
+		if !s.closed {
+			s.measuring.Add(1)
+			go s.measure(peer)
+		}
+	}
+}
+
+// measure estimates the offset of the session clock to the host clock by
+// pings to the peer.
+func (s *LinkSession) measure(peer linkPeer) {
+	defer s.measuring.Done()
+	conn, err := net.DialUDP("udp", nil, peer.endpoint)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	var offsets []int64
+	buf := make([]byte, 512)
+	for i := 0; i < linkPings; i++ {
+		ping := append(append([]byte(nil), linkMeasurementHeader...), linkPing)
+		ping = appendLinkEntry(ping, linkKeyHostTime, s.hostTime(time.Now()))
+		if _, err := conn.Write(ping); err != nil {
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(linkPingTimeout))
+		n, err := conn.Read(buf)
+		if err != nil {
+			continue
+		}
+		received := s.hostTime(time.Now())
+		b := buf[:n]
+		if len(b) < 9 || !bytes.HasPrefix(b, linkMeasurementHeader) || b[8] != linkPong {
+			continue
+		}
+		entries, err := parseLinkEntries(b[9:])
+		ht, gt := entries[linkKeyHostTime], entries[linkKeyGhostTime]
+		if err != nil || len(ht) != 8 || len(gt) != 8 {
+			continue
+		}
+		// the ghost time was taken half way through the round trip
+		sent := int64(binary.BigEndian.Uint64(ht))
+		offsets = append(offsets, int64(binary.BigEndian.Uint64(gt))-(sent+received)/2)
+	}
+	if len(offsets) == 0 {
+		return
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.peer != nil && s.peer.session == peer.session {
+		s.offset, s.measured = offsets[len(offsets)/2], true
+	}
+}
@@ -0,0 +1,117 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net"
+	"testing"
+	"time"
+)
+
+// linkAliveMessage returns a discovery message of a peer at 120 BPM whose
+// beat 0 is at the ghost time of 1s.
+func linkAliveMessage(endpoint *net.UDPAddr) []byte {
+	var b bytes.Buffer
+	b.Write(linkDiscoveryHeader)
+	b.Write([]byte{linkAlive, 5, 0, 0})
+	b.WriteString("peer0001")
+	entry := func(key uint32, v interface{}) {
+		var data bytes.Buffer
+		binary.Write(&data, binary.BigEndian, v)
+		binary.Write(&b, binary.BigEndian, []uint32{key, uint32(data.Len())})
+		data.WriteTo(&b)
+	}
+	entry(linkKeyTimeline, linkTimeline{MicrosPerBeat: 500000, BeatOrigin: 0, TimeOrigin: 1000000})
+	entry(linkKeySession, []byte("session1"))
+	entry(linkKeyEndpoint, append(endpoint.IP.To4(), byte(endpoint.Port>>8), byte(endpoint.Port)))
+	return b.Bytes()
+}
+
+// servePongs answers pings with a ghost time one second ahead of the host
+// time of the ping.
+func servePongs(conn *net.UDPConn) {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		entries, err := parseLinkEntries(buf[9:n])
+		if err != nil || buf[8] != linkPing {
+			continue
+		}
+		ht := int64(binary.BigEndian.Uint64(entries[linkKeyHostTime]))
+		pong := append(append([]byte(nil), linkMeasurementHeader...), linkPong)
+		pong = appendLinkEntry(pong, linkKeyGhostTime, ht+1000000)
+		pong = appendLinkEntry(pong, linkKeyHostTime, ht)
+		conn.WriteToUDP(pong, addr)
+	}
+}
+
+func TestLinkSession(t *testing.T) {
+	peer, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+	go servePongs(peer)
+
+	discovery, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newLinkSession(discovery)
+	defer s.Close()
+	if _, _, ok := s.Timeline(time.Now()); ok {
+		t.Fatal("expected no timeline before discovery")
+	}
+	s.handleDiscovery(linkAliveMessage(peer.LocalAddr().(*net.UDPAddr)), time.Now())
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, _, ok := s.Timeline(time.Now()); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timeline not measured")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	tempo, beat, _ := s.Timeline(s.start.Add(time.Second))
+	if tempo != 120 || math.Abs(beat-2) > 0.02 {
+		t.Errorf("expected beat 2 at 120 BPM but got %v at %v", beat, tempo)
+	}
+
+	bye := append(append([]byte(nil), linkDiscoveryHeader...), linkByeBye, 0, 0, 0)
+	s.handleDiscovery(append(bye, "peer0001"...), time.Now())
+	if _, _, ok := s.Timeline(time.Now()); ok {
+		t.Error("expected no timeline after the peer left")
+	}
+}
+
+func TestParseLinkDiscovery(t *testing.T) {
+	msg := linkAliveMessage(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 20000})
+	now := time.Now()
+	peer, typ, err := parseLinkDiscovery(msg, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ != linkAlive || string(peer.session[:]) != "session1" || peer.endpoint.String() != "10.0.0.1:20000" ||
+		peer.timeline.MicrosPerBeat != 500000 || !peer.expires.Equal(now.Add(5*time.Second)) {
+		t.Errorf("unexpected peer %+v", peer)
+	}
+	for _, b := range [][]byte{msg[:19], msg[:len(msg)-1], append([]byte("_asdp_v\x02"), msg[8:]...)} {
+		if _, _, err := parseLinkDiscovery(b, now); err == nil {
+			t.Errorf("expected error for % x", b)
+		}
+	}
+}
+
+func TestJoinLinkOffline(t *testing.T) {
+	SetOffline(true)
+	defer SetOffline(false)
+	if _, err := JoinLink(); err == nil {
+		t.Error("expected offline error")
+	}
+}
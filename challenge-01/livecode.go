@@ -0,0 +1,155 @@
+package drum
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// sonicPiSamples map General MIDI drums to samples built into Sonic Pi.
+var sonicPiSamples = map[uint8]string{
+	36: "bd_haus", 37: "drum_snare_soft", 38: "sn_dolf", 39: "perc_snap",
+	41: "drum_tom_lo_hard", 42: "drum_cymbal_closed", 44: "drum_cymbal_pedal",
+	45: "drum_tom_lo_soft", 46: "drum_cymbal_open", 47: "drum_tom_mid_soft",
+	49: "drum_splash_hard", 50: "drum_tom_hi_soft", 51: "drum_cymbal_soft",
+	54: "drum_tambourine_soft", 56: "drum_cowbell", 63: "drum_tom_hi_hard",
+	64: "drum_tom_mid_hard", 70: "drum_cymbal_hard", 75: "perc_bell",
+}
+
+// WriteSonicPi writes a Sonic Pi live_loop playing the pattern to w. Tracks
+// use the built in sample of their General MIDI drum with the velocity and
+// volume as amp and the track pan. The swing amount is applied, muted tracks
+// are left out.
+func WriteSonicPi(w io.Writer, p *Pattern) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Saved with HW Version: %s\nuse_bpm %v\n\n", p.version, tempo64(p.tempo))
+	tracks, names := p.liveTracks()
+	for n, i := range tracks {
+		fmt.Fprintf(bw, "%s = (ring %s)\n", names[n], strings.Join(p.tracks[i].amps(), ", "))
+	}
+	bw.WriteString("\nlive_loop :drums do\n  tick\n")
+	for n, i := range tracks {
+		t := p.tracks[i]
+		note, _ := ResolveGMNote(t.name)
+		sample, ok := sonicPiSamples[note]
+		if !ok {
+			sample = sonicPiSamples[fallbackGMNote]
+		}
+		fmt.Fprintf(bw, "  sample :%s, amp: %s.look", sample, names[n])
+		if t.pan != 0 {
+			fmt.Fprintf(bw, ", pan: %s", formatFloat(livePan(t.pan)))
+		}
+		fmt.Fprintf(bw, " if %s.look > 0\n", names[n])
+	}
+	long, short := swingDurations(p.swing)
+	if long == short {
+		fmt.Fprintf(bw, "  sleep %s\n", formatFloat(long))
+	} else {
+		fmt.Fprintf(bw, "  sleep(look.even? ? %s : %s)\n", formatFloat(long), formatFloat(short))
+	}
+	bw.WriteString("end\n")
+	return bw.Flush()
+}
+
+// WriteSuperCollider writes a SuperCollider Ppar of one Pbind per track
+// playing the pattern to w. The instrument of a Pbind is the track name
+// converted into a symbol, so a SynthDef with that name must exist.
+// Velocities and volume set the amp, swing and pan are applied and muted
+// tracks are left out.
+func WriteSuperCollider(w io.Writer, p *Pattern) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "// Saved with HW Version: %s\nTempoClock.default.tempo = %s;\n\nPpar([\n", p.version, formatFloat(tempo64(p.tempo)/60))
+	long, short := swingDurations(p.swing)
+	tracks, names := p.liveTracks()
+	for n, i := range tracks {
+		t := p.tracks[i]
+		amps := t.amps()
+		for step, a := range amps {
+			if a == "0" {
+				amps[step] = "Rest()"
+			}
+		}
+		fmt.Fprintf(bw, "\tPbind(\\instrument, \\%s, \\dur, Pseq([%s, %s], inf), \\amp, Pseq([%s], inf)",
+			names[n], formatFloat(long), formatFloat(short), strings.Join(amps, ", "))
+		if t.pan != 0 {
+			fmt.Fprintf(bw, ", \\pan, %s", formatFloat(livePan(t.pan)))
+		}
+		bw.WriteString("),\n")
+	}
+	bw.WriteString("]).play;\n")
+	return bw.Flush()
+}
+
+// liveTracks returns the indexes of the audible tracks and unique
+// identifiers derived from their names.
+func (p *Pattern) liveTracks() ([]int, []string) {
+	var tracks []int
+	var names []string
+	used := make(map[string]bool)
+	solo := p.hasSolo()
+	for i, t := range p.tracks {
+		if !t.audible(solo) {
+			continue
+		}
+		name := identifier(t.name)
+		for n := 2; used[name]; n++ {
+			name = identifier(t.name) + "_" + strconv.Itoa(n)
+		}
+		used[name] = true
+		tracks = append(tracks, i)
+		names = append(names, name)
+	}
+	return tracks, names
+}
+
+// identifier converts a track name into a lower case identifier valid in
+// Ruby and SuperCollider.
+func identifier(name string) string {
+	id := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToLower(r)
+		}
+		return '_'
+	}, name)
+	if id == "" || !unicode.IsLetter(rune(id[0])) {
+		id = "t" + id
+	}
+	return id
+}
+
+// amps returns the amplitude of every step from 0 to 1 formatted for code.
+func (t *Track) amps() []string {
+	amps := make([]string, stepsLength)
+	for step, enabled := range t.steps {
+		a := 0.0
+		if enabled {
+			a = float64(t.Velocity(step)) / MaxVelocity * float64(t.Volume()) / MaxVolume
+		}
+		amps[step] = formatFloat(math.Round(a*1000) / 1000)
+	}
+	return amps
+}
+
+// swingDurations returns the duration in beats of the first and the second
+// step of a pair.
+func swingDurations(swing uint8) (long, short float64) {
+	const step = 1.0 / blockSize
+	delay := step * float64(swing) / (2 * MaxSwing)
+	return step + delay, step - delay
+}
+
+// livePan converts a pan position into the range -1 to 1.
+func livePan(pan int8) float64 {
+	if pan < 0 {
+		return -float64(pan) / MinPan
+	}
+	return float64(pan) / MaxPan
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
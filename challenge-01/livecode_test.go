@@ -0,0 +1,66 @@
+package drum
+
+import (
+	"bytes"
+	"path"
+	"testing"
+)
+
+func TestWriteSonicPi(t *testing.T) {
+	p := &Pattern{version: "0.909", tempo: 120, swing: 50, tracks: []*Track{
+		{name: "Kick", steps: Steps{true, false, false, false, true}},
+		{name: "hh open", steps: Steps{false, false, true}},
+	}}
+	p.tracks[1].SetVelocity(2, 64)
+	p.tracks[1].SetPan(MaxPan)
+	var buf bytes.Buffer
+	if err := WriteSonicPi(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	exp := `# Saved with HW Version: 0.909
+use_bpm 120
+
+kick = (ring 1, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+hh_open = (ring 0, 0, 0.504, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+
+live_loop :drums do
+  tick
+  sample :bd_haus, amp: kick.look if kick.look > 0
+  sample :drum_cymbal_open, amp: hh_open.look, pan: 1 if hh_open.look > 0
+  sleep(look.even? ? 0.3125 : 0.1875)
+end
+`
+	if got := buf.String(); got != exp {
+		t.Errorf("Expected:\n%v\nbut got:\n%v", exp, got)
+	}
+}
+
+func TestWriteSuperCollider(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_5.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.tracks[1].Mute()
+	var buf bytes.Buffer
+	if err := WriteSuperCollider(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	exp := `// Saved with HW Version: 0.708-alpha
+TempoClock.default.tempo = 16.65;
+
+Ppar([
+	Pbind(\instrument, \kick, \dur, Pseq([0.25, 0.25], inf), \amp, Pseq([1, Rest(), Rest(), Rest(), Rest(), Rest(), Rest(), Rest(), 1, Rest(), Rest(), Rest(), Rest(), Rest(), Rest(), Rest()], inf)),
+]).play;
+`
+	if got := buf.String(); got != exp {
+		t.Errorf("Expected:\n%v\nbut got:\n%v", exp, got)
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	for name, exp := range map[string]string{"Low Conga": "low_conga", "808": "t808", "hh-öpen": "hh__pen", "": "t"} {
+		if got := identifier(name); got != exp {
+			t.Errorf("Expected '%v' but got '%v' for '%v'", exp, got, name)
+		}
+	}
+}
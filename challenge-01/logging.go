@@ -0,0 +1,80 @@
+package drum
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+)
+
+// WithLogger makes the decoder emit a debug record with the offset and the
+// value of every field parsed and warnings for suspicious data, like empty
+// track names or tempos outside of MinTempo and MaxTempo, to l.
+func WithLogger(l *slog.Logger) DecodeOption {
+	return func(o *decodeOptions) {
+		o.logger = l
+	}
+}
+
+// offsetReader counts the bytes read so far.
+type offsetReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *offsetReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.n += int64(n)
+	return n, err
+}
+
+// decodeLogger logs the fields read by the decoder. All methods are no-ops
+// on a nil logger, the default.
+type decodeLogger struct {
+	log *slog.Logger
+	r   *offsetReader
+}
+
+// newDecodeLogger returns the reader to decode from and its logger, nil
+// without logger.
+func newDecodeLogger(r io.Reader, l *slog.Logger) (io.Reader, *decodeLogger) {
+	if l == nil {
+		return r, nil
+	}
+	or := &offsetReader{r: r}
+	return or, &decodeLogger{log: l, r: or}
+}
+
+// offset returns the position of the next field.
+func (l *decodeLogger) offset() int64 {
+	if l == nil {
+		return 0
+	}
+	return l.r.n
+}
+
+// replay returns a reader of data read before at offset and the logger to
+// log its fields with.
+func (l *decodeLogger) replay(data []byte, offset int64) (io.Reader, *decodeLogger) {
+	r := bytes.NewReader(data)
+	if l == nil {
+		return r, nil
+	}
+	or := &offsetReader{r: r, n: offset}
+	return or, &decodeLogger{log: l.log, r: or}
+}
+
+// field logs a field read at offset.
+func (l *decodeLogger) field(name string, offset int64, value interface{}) {
+	if l == nil {
+		return
+	}
+	l.log.Debug("parsed field", "field", name, "offset", offset, "value", value)
+}
+
+// warn logs suspicious data found at offset.
+func (l *decodeLogger) warn(msg string, offset int64, args ...interface{}) {
+	if l == nil {
+		return
+	}
+	l.log.Warn(msg, append([]interface{}{"offset", offset}, args...)...)
+}
@@ -0,0 +1,104 @@
+package drum
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"path"
+	"strings"
+	"testing"
+)
+
+// testLogger returns a logger writing debug records without time to buf.
+func testLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"), WithLogger(testLogger(&buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp, _ := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if !p.Equal(exp) {
+		t.Errorf("Expected '%v' but got '%v'", exp, p)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for _, line := range []string{
+		`level=DEBUG msg="parsed field" field="type header" offset=0 value=SPLICE`,
+		`level=DEBUG msg="parsed field" field="payload size" offset=6 value=197`,
+		`level=DEBUG msg="parsed field" field=version offset=14 value=0.808-alpha`,
+		`level=DEBUG msg="parsed field" field=tempo offset=46 value=120`,
+		`level=DEBUG msg="parsed field" field="track id" offset=50 value=0`,
+		`level=DEBUG msg="parsed field" field="track name length" offset=54 value=4`,
+		`level=DEBUG msg="parsed field" field="track name" offset=55 value=kick`,
+		`level=DEBUG msg="parsed field" field="track steps" offset=59 value=x---x---x---x---`,
+	} {
+		if !contains(lines, line) {
+			t.Errorf("Expected record %s in\n%s", line, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "WARN") {
+		t.Errorf("unexpected warning in\n%s", buf.String())
+	}
+}
+
+func TestWithLoggerWarnings(t *testing.T) {
+	raw, err := ioutil.ReadFile(path.Join("fixtures", "pattern_5.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	track, _ := NewTrack(1, "", Steps{})
+	var buf bytes.Buffer
+	if err := Encode(&buf, &Pattern{version: "x", tempo: 120, tracks: []*Track{track, track}}); err != nil {
+		t.Fatal(err)
+	}
+	suspicious := buf.Bytes()
+	// the encoder rejects a tempo of 1000, so the payload is patched
+	copy(suspicious[46:], []byte{0, 0, 0x7a, 0x44})
+
+	specs := map[string]struct {
+		data []byte
+		exp  string
+	}{
+		"raw extra": {raw, `[level=WARN msg="unrecognized trailing data" offset=101 length=31]`},
+		"suspicious": {suspicious, `[level=WARN msg="tempo out of range" offset=46 tempo=1000 ` +
+			`level=WARN msg="empty track name" offset=55 id=1 ` +
+			`level=WARN msg="empty track name" offset=76 id=1 ` +
+			`level=WARN msg="duplicate track id" offset=71 id=1]`},
+	}
+	for msg, spec := range specs {
+		var buf bytes.Buffer
+		if _, err := Decode(bytes.NewReader(spec.data), WithLogger(testLogger(&buf))); err != nil {
+			t.Fatal(err)
+		}
+		var warnings []string
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if strings.HasPrefix(line, "level=WARN") {
+				warnings = append(warnings, line)
+			}
+		}
+		if got := fmt.Sprint(warnings); got != spec.exp {
+			t.Errorf("%s: Expected %s but got %s", msg, spec.exp, got)
+		}
+	}
+}
+
+func contains(lines []string, line string) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package drum
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TrackByID returns the first track with the given id or nil when there is
+// none.
+func (p *Pattern) TrackByID(id uint32) *Track {
+	for _, t := range p.tracks {
+		if t.id == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// TrackByName returns the track with the given name or nil when there is
+// none. An exact match is preferred, otherwise names are compared case
+// insensitive and ignoring spaces, dashes and underscores, so that
+// "Low Conga" matches "low-conga".
+func (p *Pattern) TrackByName(name string) *Track {
+	for _, t := range p.tracks {
+		if t.name == name {
+			return t
+		}
+	}
+	key := normalizeName(name)
+	for _, t := range p.tracks {
+		if normalizeName(t.name) == key {
+			return t
+		}
+	}
+	return nil
+}
+
+// TracksWithPrefix returns all tracks whose name starts with prefix,
+// compared like in TrackByName.
+func (p *Pattern) TracksWithPrefix(prefix string) []*Track {
+	key := normalizeName(prefix)
+	return p.FilterTracks(func(t *Track) bool {
+		return strings.HasPrefix(normalizeName(t.name), key)
+	})
+}
+
+// FilterTracks returns all tracks for which keep returns true in file order.
+func (p *Pattern) FilterTracks(keep func(*Track) bool) []*Track {
+	var tracks []*Track
+	for _, t := range p.tracks {
+		if keep(t) {
+			tracks = append(tracks, t)
+		}
+	}
+	return tracks
+}
+
+// normalizeName returns the lower cased name without separators.
+func normalizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+}
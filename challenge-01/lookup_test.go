@@ -0,0 +1,34 @@
+package drum
+
+import (
+	"path"
+	"testing"
+)
+
+func TestTrackLookup(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_4.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr := p.TrackByID(99); tr == nil || tr.name != "Maracas" {
+		t.Errorf("unexpected track for id 99: %v", tr)
+	}
+	if tr := p.TrackByID(7); tr != nil {
+		t.Errorf("expected no track but got %v", tr)
+	}
+	for _, name := range []string{"Low Conga", "low-conga", "LOW_CONGA"} {
+		if tr := p.TrackByName(name); tr == nil || tr.id != 255 {
+			t.Errorf("unexpected track for name %q: %v", name, tr)
+		}
+	}
+	if tr := p.TrackByName("conga"); tr != nil {
+		t.Errorf("expected no track but got %v", tr)
+	}
+	if got := p.TracksWithPrefix("sub"); len(got) != 1 || got[0].id != 0 {
+		t.Errorf("unexpected tracks for prefix: %v", got)
+	}
+	got := p.FilterTracks(func(t *Track) bool { return t.steps[0] })
+	if len(got) != 2 || got[0].name != "Kick" || got[1].name != "Maracas" {
+		t.Errorf("unexpected filtered tracks: %v", got)
+	}
+}
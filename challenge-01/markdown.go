@@ -0,0 +1,42 @@
+package drum
+
+import (
+	"fmt"
+	"strings"
+)
+
+// markdownEnabled marks an enabled step in the Markdown table.
+const markdownEnabled = "✓"
+
+// ToMarkdown returns the pattern as GitHub flavored Markdown table with the
+// tracks as rows and the steps as columns, preceded by a caption line with
+// the version and tempo.
+func (p *Pattern) ToMarkdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s*\n\n", markdownEscape(p.caption()))
+	b.WriteString("| Track |")
+	for step := 1; step <= stepsLength; step++ {
+		fmt.Fprintf(&b, " %d |", step)
+	}
+	b.WriteString("\n| --- |")
+	b.WriteString(strings.Repeat(" :-: |", stepsLength))
+	b.WriteString("\n")
+	for _, t := range p.tracks {
+		fmt.Fprintf(&b, "| %s |", markdownEscape(fmt.Sprintf("(%v) %v", t.id, t.name)))
+		for _, enabled := range t.steps {
+			if enabled {
+				b.WriteString(" " + markdownEnabled + " |")
+			} else {
+				b.WriteString("   |")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// markdownEscape escapes characters with a meaning in Markdown tables and
+// emphasis.
+var markdownEscape = strings.NewReplacer(
+	`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`", "<", "&lt;",
+).Replace
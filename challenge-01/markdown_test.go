@@ -0,0 +1,25 @@
+package drum
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToMarkdown(t *testing.T) {
+	p := &Pattern{version: "0.808-alpha", tempo: 120, tracks: []*Track{
+		{id: 1, name: "kick|bd", steps: Steps{true, false, true}},
+	}}
+	lines := strings.Split(p.ToMarkdown(), "\n")
+	if exp := "*0.808-alpha – 120 BPM*"; lines[0] != exp {
+		t.Errorf("Expected caption '%v' but got '%v'", exp, lines[0])
+	}
+	if !strings.HasPrefix(lines[2], "| Track | 1 | 2 |") || !strings.HasSuffix(lines[2], " 16 |") {
+		t.Errorf("unexpected header '%v'", lines[2])
+	}
+	if exp := "| (1) kick\\|bd | ✓ |   | ✓ |   |"; !strings.HasPrefix(lines[4], exp) {
+		t.Errorf("Expected row starting with '%v' but got '%v'", exp, lines[4])
+	}
+	if n := strings.Count(lines[4], " |"); n != 1+stepsLength {
+		t.Errorf("Expected %d columns but got %d", 1+stepsLength, n)
+	}
+}
@@ -0,0 +1,35 @@
+package drum
+
+// Merge returns a copy of p with the tracks of from merged in, for example
+// to layer a hi-hat groove over a kick and snare pattern. Tracks are matched
+// by name like in TrackByName: the enabled steps of a track of from are
+// turned on in the matching track, keeping its velocities and all other step
+// data. Tracks without match are added, with a new id when p already uses
+// theirs. Bar b of p gets bar b of from, or bar b modulo the bars of from
+// when from has fewer. The tempo and all other pattern data of p are kept.
+func Merge(p, from *Pattern) *Pattern {
+	c := p.Clone()
+	var maxID uint32
+	for _, t := range c.tracks {
+		maxID = max(maxID, t.id)
+	}
+	for _, ft := range from.tracks {
+		t := c.TrackByName(ft.name)
+		if t == nil {
+			t = ft.Clone()
+			t.steps, t.bars = Steps{}, [MaxBars - 1]Steps{}
+			if c.TrackByID(t.id) != nil {
+				maxID++
+				t.id = maxID
+			}
+			c.tracks = append(c.tracks, t)
+		}
+		for bar := range c.Bars() {
+			steps := t.barSteps(bar)
+			for i, on := range ft.barSteps(bar % from.Bars()) {
+				steps[i] = steps[i] || on
+			}
+		}
+	}
+	return c
+}
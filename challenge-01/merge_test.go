@@ -0,0 +1,42 @@
+package drum
+
+import (
+	"path"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.AddBar(0)
+	kick, _ := NewTrack(1, "Kick", Steps{1: true})
+	ride, _ := NewTrack(0, "ride", Steps{0: true, 8: true})
+	ride.SetVelocity(8, 90)
+	from, _ := NewPattern("0.808-alpha", 98, kick, ride)
+
+	got := Merge(p, from)
+	if len(p.Tracks()) != 6 || len(got.Tracks()) != 7 {
+		t.Fatalf("Expected 7 tracks in the copy of 6 but got %d of %d", len(got.Tracks()), len(p.Tracks()))
+	}
+	if got.Tempo() != p.Tempo() {
+		t.Errorf("Expected the tempo %v but got %v", p.Tempo(), got.Tempo())
+	}
+	for bar := range got.Bars() {
+		steps, _ := got.Tracks()[0].BarSteps(bar)
+		if exp := "xx--x---x---x---"; stepSymbols(steps) != exp {
+			t.Errorf("Expected the kick of bar %s %s but got %s", barName(bar), exp, stepSymbols(steps))
+		}
+	}
+	added := got.Tracks()[6]
+	if added.ID() != 6 || added.Name() != "ride" || added.Velocity(8) != 90 {
+		t.Errorf("Expected the ride added as 6 with its velocities but got %d %q %d", added.ID(), added.Name(), added.Velocity(8))
+	}
+	if steps, _ := added.BarSteps(1); stepSymbols(steps) != "x-------x-------" {
+		t.Errorf("Expected the ride in bar B but got %s", stepSymbols(steps))
+	}
+	if stepSymbols(p.Tracks()[0].Steps()) != "x---x---x---x---" {
+		t.Errorf("Expected p unchanged but got %s", stepSymbols(p.Tracks()[0].Steps()))
+	}
+}
@@ -0,0 +1,149 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+var chunkMetadata = chunkID{'M', 'E', 'T', 'A'}
+
+// FeatureMetadata is the pattern metadata chunk.
+const FeatureMetadata Feature = "metadata"
+
+// ErrInvalidMetadata is returned for metadata that does not fit into the
+// file format.
+var ErrInvalidMetadata = errors.New("invalid metadata")
+
+// Metadata identifies a pattern within a library. The zero value means no
+// metadata.
+type Metadata struct {
+	Title   string
+	Author  string
+	Tags    []string
+	Created time.Time // zero when unknown
+}
+
+func (m Metadata) isZero() bool {
+	return m.Title == "" && m.Author == "" && len(m.Tags) == 0 && m.Created.IsZero()
+}
+
+// Metadata returns a copy of the metadata of the pattern.
+func (p *Pattern) Metadata() Metadata {
+	m := p.meta
+	m.Tags = append([]string(nil), m.Tags...)
+	return m
+}
+
+// SetMetadata sets the metadata of the pattern. Title, author and every tag
+// must not be longer than 255 bytes, tags must not be empty and there may be
+// at most 255 of them. The creation date is stored in seconds.
+func (p *Pattern) SetMetadata(m Metadata) error {
+	if len(m.Title) > math.MaxUint8 || len(m.Author) > math.MaxUint8 || len(m.Tags) > math.MaxUint8 {
+		return ErrInvalidMetadata
+	}
+	for _, tag := range m.Tags {
+		if tag == "" || len(tag) > math.MaxUint8 {
+			return fmt.Errorf("%v: tag %q", ErrInvalidMetadata, tag)
+		}
+	}
+	m.Tags = append([]string(nil), m.Tags...)
+	if !m.Created.IsZero() {
+		m.Created = m.Created.Truncate(time.Second)
+	}
+	p.meta = m
+	return nil
+}
+
+// decodeMetadataChunk decodes the pattern metadata stored as
+// |Title length (1 byte)|Title|Author length (1 byte)|Author|Tag count (1 byte)|
+// followed by the tags each prefixed by its length in one byte, the
+// creation date in Unix seconds (8 bytes), 0 when unknown, and the time
+// signature numerator and denominator (1 byte each) unless it is 4/4.
+func decodeMetadataChunk(data []byte, p *Pattern) error {
+	r := bytes.NewReader(data)
+	var m Metadata
+	var err error
+	if m.Title, err = readShortString(r); err != nil {
+		return fmt.Errorf("parse title: %v", err)
+	}
+	if m.Author, err = readShortString(r); err != nil {
+		return fmt.Errorf("parse author: %v", err)
+	}
+	var n uint8
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return fmt.Errorf("parse tag count: %v", err)
+	}
+	for i := 0; i < int(n); i++ {
+		tag, err := readShortString(r)
+		if err != nil {
+			return fmt.Errorf("parse tag %d: %v", i, err)
+		}
+		m.Tags = append(m.Tags, tag)
+	}
+	var created int64
+	if err := binary.Read(r, binary.LittleEndian, &created); err != nil {
+		return fmt.Errorf("parse creation date: %v", err)
+	}
+	if created != 0 {
+		m.Created = time.Unix(created, 0).UTC()
+	}
+	if r.Len() == 2 {
+		var ts TimeSignature
+		binary.Read(r, binary.LittleEndian, &ts)
+		if err := p.SetTimeSignature(ts); err != nil {
+			return fmt.Errorf("parse time signature: %v", err)
+		}
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%d bytes left", r.Len())
+	}
+	return p.SetMetadata(m)
+}
+
+func encodeMetadataChunk(p *Pattern) []byte {
+	if p.meta.isZero() && p.timeSig == (TimeSignature{}) {
+		return nil
+	}
+	var buf bytes.Buffer
+	writeShortString(&buf, p.meta.Title)
+	writeShortString(&buf, p.meta.Author)
+	buf.WriteByte(uint8(len(p.meta.Tags)))
+	for _, tag := range p.meta.Tags {
+		writeShortString(&buf, tag)
+	}
+	var created int64
+	if !p.meta.Created.IsZero() {
+		created = p.meta.Created.Unix()
+	}
+	binary.Write(&buf, binary.LittleEndian, created)
+	if p.timeSig != (TimeSignature{}) {
+		buf.Write([]byte{p.timeSig.Numerator, p.timeSig.Denominator})
+	}
+	return buf.Bytes()
+}
+
+func clearMetadata(p *Pattern) {
+	p.meta = Metadata{}
+	p.timeSig = TimeSignature{}
+}
+
+// appendMetadata appends the header lines of the metadata that is set.
+func appendMetadata(w printoutWriter, m Metadata) {
+	if m.Title != "" {
+		fmt.Fprintf(w, "Title: %s\n", m.Title)
+	}
+	if m.Author != "" {
+		fmt.Fprintf(w, "Author: %s\n", m.Author)
+	}
+	if len(m.Tags) > 0 {
+		fmt.Fprintf(w, "Tags: %s\n", strings.Join(m.Tags, ", "))
+	}
+	if !m.Created.IsZero() {
+		fmt.Fprintf(w, "Created: %s\n", m.Created.Format("2006-01-02"))
+	}
+}
@@ -0,0 +1,86 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetadata(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Metadata{
+		Title:   "Boom Bap",
+		Author:  "alpe",
+		Tags:    []string{"hip-hop", "90s"},
+		Created: time.Date(2015, 3, 1, 12, 30, 15, 500, time.UTC),
+	}
+	if err := p.SetMetadata(m); err != nil {
+		t.Fatal(err)
+	}
+	tags := m.Tags
+	m.Tags = []string{"hip-hop", "90s"}
+	tags[0] = "changed"
+	if got := p.Metadata().Tags[0]; got != "hip-hop" {
+		t.Errorf("Expected tags to be copied but got '%v'", got)
+	}
+	m.Created = m.Created.Truncate(time.Second)
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.Metadata(); !reflect.DeepEqual(got, m) {
+		t.Errorf("Expected '%v' but got '%v'", m, got)
+	}
+	exp := "Saved with HW Version: 0.808-alpha\nTempo: 98.4\nTitle: Boom Bap\nAuthor: alpe\nTags: hip-hop, 90s\nCreated: 2015-03-01\n(0) kick"
+	if got := decoded.String(); !strings.HasPrefix(got, exp) {
+		t.Errorf("Expected printout starting with '%v' but got '%v'", exp, got)
+	}
+
+	js, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Pattern
+	if err := json.Unmarshal(js, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if got := fromJSON.Metadata(); !reflect.DeepEqual(got, m) {
+		t.Errorf("Expected '%v' from JSON but got '%v'", m, got)
+	}
+
+	clearMetadata(decoded)
+	if got := encodeMetadataChunk(decoded); got != nil {
+		t.Errorf("Expected no chunk without metadata but got %q", got)
+	}
+}
+
+func TestInvalidMetadata(t *testing.T) {
+	p := &Pattern{}
+	for _, m := range []Metadata{
+		{Title: strings.Repeat("x", 256)},
+		{Author: strings.Repeat("x", 256)},
+		{Tags: []string{""}},
+		{Tags: make([]string, 256)},
+	} {
+		if err := p.SetMetadata(m); err == nil {
+			t.Errorf("Expected error for %v", m)
+		}
+	}
+	for _, data := range [][]byte{{}, {1}, {0, 0, 1, 3, 'a'}, {0, 0, 0, 1, 2, 3}} {
+		if err := decodeMetadataChunk(data, p); err == nil {
+			t.Errorf("Expected error for chunk %q", data)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Package metrics counts the work of the pattern services for monitoring
+// with Prometheus.
+//
+// The HTTP and the gRPC service record every decode by its result, the
+// payload sizes and the durations of decodes and renders. Handler serves
+// them in the Prometheus text format, the services mount it on /metrics:
+//
+//	splice_decodes_total{service="http",result="truncated_payload"} 3
+//	splice_decode_payload_bytes_bucket{service="http",le="1024"} 17
+//	splice_render_duration_seconds_sum{service="grpc",format="svg"} 0.042
+//
+// An alert on the rate of failed decodes catches spikes of malformed
+// uploads. The format is written by this package, so the services keep no
+// dependencies.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// ResultOK is the result label of successful decodes. Failures are labeled
+// with their drum.ErrorClass in snake case, like "truncated_payload".
+const ResultOK = "ok"
+
+var (
+	sizeBuckets     = []float64{64, 256, 1024, 4096, 16384, 65536, 262144, 1048576}
+	durationBuckets = []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1}
+)
+
+// registry holds the metric families.
+type registry struct {
+	families []*family // sorted by name
+}
+
+func (r *registry) add(f *family) *family {
+	r.families = append(r.families, f)
+	sort.Slice(r.families, func(i, j int) bool { return r.families[i].name < r.families[j].name })
+	return f
+}
+
+var defaultRegistry registry
+
+var (
+	decodes = defaultRegistry.add(&family{
+		name:   "splice_decodes_total",
+		help:   "Decoded .splice files by result.",
+		kind:   "counter",
+		labels: []string{"service", "result"},
+	})
+	payloadSizes = defaultRegistry.add(&family{
+		name:    "splice_decode_payload_bytes",
+		help:    "Declared payload sizes of decoded .splice files.",
+		kind:    "histogram",
+		labels:  []string{"service"},
+		buckets: sizeBuckets,
+	})
+	decodeDurations = defaultRegistry.add(&family{
+		name:    "splice_decode_duration_seconds",
+		help:    "Durations of decodes.",
+		kind:    "histogram",
+		labels:  []string{"service"},
+		buckets: durationBuckets,
+	})
+	renderDurations = defaultRegistry.add(&family{
+		name:    "splice_render_duration_seconds",
+		help:    "Durations of renders by format.",
+		kind:    "histogram",
+		labels:  []string{"service", "format"},
+		buckets: durationBuckets,
+	})
+)
+
+// DecodeObserver returns the hook for drum.WithStats recording the decodes
+// of the service.
+func DecodeObserver(service string) func(drum.DecodeStats) {
+	return func(s drum.DecodeStats) {
+		ObserveDecode(service, s)
+	}
+}
+
+// ObserveDecode records a decode of the service.
+func ObserveDecode(service string, s drum.DecodeStats) {
+	result := ResultOK
+	if s.Err != nil {
+		result = strings.ReplaceAll(drum.ErrorClass(s.Err), " ", "_")
+	}
+	decodes.observe(1, service, result)
+	if s.DeclaredPayload > 0 {
+		payloadSizes.observe(float64(s.DeclaredPayload), service)
+	}
+	decodeDurations.observe(s.Duration.Seconds(), service)
+}
+
+// ObserveRender records a render of the service in the format.
+func ObserveRender(service, format string, d time.Duration) {
+	renderDurations.observe(d.Seconds(), service, format)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		defaultRegistry.write(w)
+	})
+}
+
+// family is a metric with all its label values. Counters count in the sum
+// of their series.
+type family struct {
+	name    string
+	help    string
+	kind    string // counter or histogram
+	labels  []string
+	buckets []float64 // upper bounds of a histogram
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labels string // formatted label pairs
+	sum    float64
+	count  uint64
+	counts []uint64 // by bucket, not cumulative
+}
+
+// observe adds v to the series of the label values, a counter adds v to
+// its value.
+func (f *family) observe(v float64, values ...string) {
+	var b strings.Builder
+	for i, l := range f.labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=%q", l, escape(values[i]))
+	}
+	key := b.String()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.series == nil {
+		f.series = make(map[string]*series)
+	}
+	s := f.series[key]
+	if s == nil {
+		s = &series{labels: key, counts: make([]uint64, len(f.buckets))}
+		f.series[key] = s
+	}
+	s.sum += v
+	s.count++
+	for i, le := range f.buckets {
+		if v <= le {
+			s.counts[i]++
+			break
+		}
+	}
+}
+
+// escape replaces the characters %q would escape differently than the
+// Prometheus text format.
+func escape(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+func (r *registry) write(w io.Writer) error {
+	for _, f := range r.families {
+		if err := f.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *family) write(w io.Writer) error {
+	f.mu.Lock()
+	all := make([]series, 0, len(f.series))
+	for _, s := range f.series {
+		c := *s
+		c.counts = append([]uint64(nil), s.counts...)
+		all = append(all, c)
+	}
+	f.mu.Unlock()
+	sort.Slice(all, func(i, j int) bool { return all[i].labels < all[j].labels })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+	for _, s := range all {
+		if f.kind == "counter" {
+			fmt.Fprintf(&b, "%s{%s} %s\n", f.name, s.labels, formatValue(s.sum))
+			continue
+		}
+		var cumulative uint64
+		for i, le := range f.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(&b, "%s_bucket{%s,le=\"%s\"} %d\n", f.name, s.labels, formatValue(le), cumulative)
+		}
+		fmt.Fprintf(&b, "%s_bucket{%s,le=\"+Inf\"} %d\n", f.name, s.labels, s.count)
+		fmt.Fprintf(&b, "%s_sum{%s} %s\n", f.name, s.labels, formatValue(s.sum))
+		fmt.Fprintf(&b, "%s_count{%s} %d\n", f.name, s.labels, s.count)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// handlerRuns counts the runs of TestHandler, which observes into the
+// global registry under a service of its own per run, so that -count=2
+// starts from fresh series.
+var handlerRuns int
+
+func TestHandler(t *testing.T) {
+	handlerRuns++
+	service := fmt.Sprintf("test%d", handlerRuns)
+	raw := []byte("SPLICE\x00\x00\x00\x00\x00\x00\x00\x24")
+	observe := drum.WithStats(DecodeObserver(service))
+	drum.DecodeBytes(raw, observe)
+	drum.DecodeBytes([]byte("GIF89a"), observe)
+	drum.DecodeBytes([]byte("GIF89a"), observe)
+	ObserveDecode(service, drum.DecodeStats{DeclaredPayload: 300, Duration: 2 * time.Millisecond})
+	ObserveRender(service, "svg", 30*time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := ioutil.ReadAll(rec.Body)
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %s", ct)
+	}
+	for _, exp := range []string{
+		"# HELP splice_decodes_total Decoded .splice files by result.\n# TYPE splice_decodes_total counter\n",
+		`splice_decodes_total{service="test",result="ok"} 1` + "\n",
+		`splice_decodes_total{service="test",result="truncated_payload"} 1` + "\n",
+		`splice_decodes_total{service="test",result="unsupported_file_format"} 2` + "\n",
+		"# TYPE splice_decode_payload_bytes histogram\n",
+		`splice_decode_payload_bytes_bucket{service="test",le="64"} 1` + "\n" +
+			`splice_decode_payload_bytes_bucket{service="test",le="256"} 1` + "\n" +
+			`splice_decode_payload_bytes_bucket{service="test",le="1024"} 2` + "\n",
+		`splice_decode_payload_bytes_bucket{service="test",le="1048576"} 2` + "\n" +
+			`splice_decode_payload_bytes_bucket{service="test",le="+Inf"} 2` + "\n" +
+			`splice_decode_payload_bytes_sum{service="test"} 336` + "\n" +
+			`splice_decode_payload_bytes_count{service="test"} 2` + "\n",
+		`splice_decode_duration_seconds_count{service="test"} 4` + "\n",
+		`splice_render_duration_seconds_bucket{service="test",format="svg",le="0.01"} 0` + "\n" +
+			`splice_render_duration_seconds_bucket{service="test",format="svg",le="0.05"} 1` + "\n",
+		`splice_render_duration_seconds_sum{service="test",format="svg"} 0.03` + "\n",
+	} {
+		exp = strings.ReplaceAll(exp, `service="test"`, `service="`+service+`"`)
+		if !strings.Contains(string(body), exp) {
+			t.Errorf("Expected '%v' in:\n%s", exp, body)
+		}
+	}
+	// families are sorted by name
+	if i, j := strings.Index(string(body), "splice_decode_duration"), strings.Index(string(body), "splice_render"); i < 0 || i > j {
+		t.Errorf("Expected the families in order:\n%s", body)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	f := &family{name: "x", kind: "counter", labels: []string{"l"}}
+	f.observe(1, "a\"b\\c\nd")
+	var b strings.Builder
+	f.write(&b)
+	if exp := `x{l="a\"b\\c_d"} 1`; !strings.Contains(b.String(), exp) {
+		t.Errorf("Expected '%v' in:\n%s", exp, b.String())
+	}
+}
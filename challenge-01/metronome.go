@@ -0,0 +1,76 @@
+package drum
+
+import (
+	"errors"
+)
+
+// MetronomeName is the name of the click track, it maps to the General MIDI
+// high wood block.
+const MetronomeName = "click"
+
+const (
+	// metronomeBeatVelocity is the velocity of the beats after an accented
+	// first beat.
+	metronomeBeatVelocity = 96
+	// metronomeSubdivisionVelocity is the velocity of the clicks between the
+	// beats.
+	metronomeSubdivisionVelocity = 64
+)
+
+// ErrInvalidSubdivision is returned for a metronome subdivision that does
+// not evenly divide the beat into steps.
+var ErrInvalidSubdivision = errors.New("invalid subdivision")
+
+// MetronomeConfig configures the click track returned by Metronome.
+type MetronomeConfig struct {
+	// Subdivision is the number of clicks per beat, 1 unless set. The clicks
+	// between the beats are played softer.
+	Subdivision int
+	// Accent plays the first beat of the bar louder than the other beats.
+	Accent bool
+}
+
+// Metronome returns a click track for the pattern, for example to render
+// practice loops to play along with. It clicks on every beat of the time
+// signature, which in compound meters like 6/8 spans three notes, and gets
+// an id not used by the pattern.
+func Metronome(p *Pattern, cfg MetronomeConfig) (*Track, error) {
+	ts := p.TimeSignature()
+	beat := ts.groupSteps()
+	sub := cfg.Subdivision
+	if sub == 0 {
+		sub = 1
+	}
+	if sub < 0 || beat%sub != 0 {
+		return nil, ErrInvalidSubdivision
+	}
+	var id uint32
+	for _, t := range p.tracks {
+		if t.id >= id {
+			id = t.id + 1
+		}
+	}
+	t := &Track{id: id, name: MetronomeName}
+	for step := 0; step < p.BarSteps(); step += beat / sub {
+		t.steps[step] = true
+		switch {
+		case step%beat != 0:
+			t.SetVelocity(step, metronomeSubdivisionVelocity)
+		case step != 0 && cfg.Accent:
+			t.SetVelocity(step, metronomeBeatVelocity)
+		}
+	}
+	return t, nil
+}
+
+// AddMetronome returns a copy of the pattern with the click track of
+// Metronome added as last track.
+func AddMetronome(p *Pattern, cfg MetronomeConfig) (*Pattern, error) {
+	t, err := Metronome(p, cfg)
+	if err != nil {
+		return nil, err
+	}
+	c := p.Clone()
+	c.tracks = append(c.tracks, t)
+	return c, nil
+}
@@ -0,0 +1,46 @@
+package drum
+
+import (
+	"path"
+	"testing"
+)
+
+func TestMetronome(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	click, err := Metronome(p, MetronomeConfig{Subdivision: 2, Accent: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if click.id != 6 || click.name != MetronomeName {
+		t.Errorf("unexpected track %v", click)
+	}
+	if got := stepsString(click.steps); got != "|x-x-|x-x-|x-x-|x-x-|" {
+		t.Errorf("unexpected clicks %s", got)
+	}
+	for step, exp := range map[int]uint8{0: MaxVelocity, 2: metronomeSubdivisionVelocity, 4: metronomeBeatVelocity} {
+		if v := click.Velocity(step); v != exp {
+			t.Errorf("Expected velocity %d at step %d but got %d", exp, step, v)
+		}
+	}
+	if note, _ := ResolveGMNote(click.name); note != 76 {
+		t.Errorf("Expected the wood block but got %d", note)
+	}
+
+	p.SetTimeSignature(TimeSignature{6, 8})
+	with, err := AddMetronome(p, MetronomeConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.tracks) == len(with.tracks) {
+		t.Fatal("Expected the click track to be added to the copy")
+	}
+	if got := stepsString(with.tracks[len(with.tracks)-1].steps); got != "|x---|--x-|----|----|" {
+		t.Errorf("Expected dotted quarter beats in 6/8 but got %s", got)
+	}
+	if _, err := Metronome(p, MetronomeConfig{Subdivision: 4}); err != ErrInvalidSubdivision {
+		t.Errorf("Expected %v but got %v", ErrInvalidSubdivision, err)
+	}
+}
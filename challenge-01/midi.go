@@ -0,0 +1,169 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// noteLength is the length of an exported note in ticks. Drum sounds are
+// one shots, so only the start of the note matters.
+const noteLength = ticksPerStep / 2
+
+// WriteMIDIFile writes the pattern as Standard MIDI File to path, see
+// WriteMIDI.
+func WriteMIDIFile(path string, p *Pattern, bars int) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteMIDI(file, p, bars); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// WriteMIDI writes the pattern repeated for the number of bars as Standard
+// MIDI File (format 0) to w. Tracks are mapped to General MIDI percussion
+// notes on channel 10 and the swing amount is applied. As all tracks share
+// the channel, the track volume and pan are sent as controller 7 and 10
+// before every note whose track mixes differently from the previous one.
+// A tempo map is written as tempo events, ramps change the tempo every step.
+func WriteMIDI(w io.Writer, p *Pattern, bars int) error {
+	return writeMIDI(w, []midiSection{{p, bars, p.tempo}})
+}
+
+// midiSection is a pattern played for a number of bars at a tempo.
+type midiSection struct {
+	pattern *Pattern
+	bars    int
+	tempo   float32
+}
+
+// midiEvent is a channel or meta event with its absolute position.
+type midiEvent struct {
+	tick int
+	data []byte
+}
+
+// tempoEvent returns the tempo meta event for a quarter note lasting us
+// microseconds.
+func tempoEvent(tick int, us uint32) midiEvent {
+	return midiEvent{tick, []byte{0xff, 0x51, 3, byte(us >> 16), byte(us >> 8), byte(us)}}
+}
+
+// sectionTempos returns the tempo events of the section starting at offset
+// when the tempo sent last lasts us microseconds per quarter note, together
+// with the tempo sent last after the section.
+func sectionTempos(s midiSection, offset int, us uint32) ([]midiEvent, uint32) {
+	var events []midiEvent
+	if len(s.pattern.tempoMap) == 0 {
+		if v := uint32(math.Round(6e7 / tempo64(s.tempo))); v != us {
+			events = append(events, tempoEvent(offset, v))
+			us = v
+		}
+		return events, us
+	}
+	for i := 0; i < s.bars*s.pattern.BarSteps(); i++ {
+		d := s.pattern.stepLength(s.tempo, i)
+		if v := uint32(math.Round(float64(d*blockSize) / float64(time.Microsecond))); v != us {
+			events = append(events, tempoEvent(offset+i*ticksPerStep, v))
+			us = v
+		}
+	}
+	return events, us
+}
+
+// timeSignatureEvent returns the time signature meta event with a metronome
+// click per beat, per dotted beat in compound meters.
+func timeSignatureEvent(tick int, ts TimeSignature) midiEvent {
+	clocks := ts.groupSteps() * clocksPerStep
+	denominator := byte(0)
+	for d := ts.Denominator; d > 1; d /= 2 {
+		denominator++
+	}
+	return midiEvent{tick, []byte{0xff, 0x58, 4, ts.Numerator, denominator, byte(clocks), 8}}
+}
+
+func writeMIDI(w io.Writer, sections []midiSection) error {
+	var events []midiEvent
+	var us uint32 // tempo sent last
+	// controller values sent last, -1 while none was sent
+	volume, pan := -1, -1
+	offset := 0
+	var timeSig TimeSignature
+	for n, s := range sections {
+		var tempos []midiEvent
+		tempos, us = sectionTempos(s, offset, us)
+		events = append(events, tempos...)
+		if ts := s.pattern.TimeSignature(); n == 0 || ts != timeSig {
+			events = append(events, timeSignatureEvent(offset, ts))
+			timeSig = ts
+		}
+		for _, e := range s.pattern.schedule(s.bars) {
+			t := s.pattern.tracks[e.track]
+			note, _ := ResolveGMNote(t.name)
+			tick := offset + e.tick
+			if s.pattern.hasMix() || volume != -1 {
+				if v := int(t.Volume()); v != volume {
+					events = append(events, midiEvent{tick, []byte{0xb0 | gmPercussionChannel, 7, byte(v)}})
+					volume = v
+				}
+				if v := int(t.pan) - MinPan; v != pan {
+					events = append(events, midiEvent{tick, []byte{0xb0 | gmPercussionChannel, 10, byte(v)}})
+					pan = v
+				}
+			}
+			events = append(events,
+				midiEvent{tick, []byte{0x90 | gmPercussionChannel, note, e.velocity}},
+				midiEvent{tick + min(noteLength, e.length/2), []byte{0x80 | gmPercussionChannel, note, 0}})
+		}
+		offset += s.bars * s.pattern.BarSteps() * ticksPerStep
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].tick < events[j].tick })
+
+	var track bytes.Buffer
+	last := 0
+	for _, e := range events {
+		writeVarInt(&track, e.tick-last)
+		track.Write(e.data)
+		last = e.tick
+	}
+	if offset < last {
+		offset = last
+	}
+	writeMeta(&track, offset-last, 0x2f, nil)
+
+	var buf bytes.Buffer
+	buf.WriteString("MThd")
+	binary.Write(&buf, binary.BigEndian, []uint16{0, 6, 0, 1, ticksPerBeat})
+	buf.WriteString("MTrk")
+	binary.Write(&buf, binary.BigEndian, uint32(track.Len()))
+	track.WriteTo(&buf)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func writeMeta(buf *bytes.Buffer, delta int, kind byte, data []byte) {
+	writeVarInt(buf, delta)
+	buf.Write([]byte{0xff, kind})
+	writeVarInt(buf, len(data))
+	buf.Write(data)
+}
+
+// writeVarInt writes v as MIDI variable length quantity.
+func writeVarInt(buf *bytes.Buffer, v int) {
+	var b [5]byte
+	n := len(b) - 1
+	b[n] = byte(v & 0x7f)
+	for v >>= 7; v > 0; v >>= 7 {
+		n--
+		b[n] = byte(v&0x7f) | 0x80
+	}
+	buf.Write(b[n:])
+}
@@ -0,0 +1,197 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// MIDI real time and channel messages sent by the Player.
+const (
+	midiClock    = 0xf8
+	midiStart    = 0xfa
+	midiContinue = 0xfb
+	midiStop     = 0xfc
+)
+
+// clocksPerStep is the number of MIDI clock pulses per sixteenth note at the
+// standard 24 pulses per quarter note.
+const clocksPerStep = 24 / blockSize
+
+// ErrNoMIDIPort is returned by OpenMIDIPort when no port of the name exists.
+var ErrNoMIDIPort = errors.New("no such MIDI port")
+
+// WithMIDIOut makes the player send MIDI clock, start or continue and stop,
+// and the notes played on the General MIDI percussion channel to w in real
+// time, for example to drive external drum machines. Notes are delayed by
+// their micro timing offsets, see StepEvent.Offsets, and like with the
+// Mixer notes early are sent on their step. Every note is released after a
+// step. The clock keeps a steady 24 pulses per quarter note while swung
+// steps start late.
+func WithMIDIOut(w io.Writer) PlayerOption {
+	return func(pl *Player) { pl.midiOut = w }
+}
+
+// midiStart sends start when playing from the beginning of the pattern and
+// continue otherwise.
+func (pl *Player) midiStart() error {
+	pl.clockBase = time.Time{}
+	msg := byte(midiStart)
+	if pl.Position() != 0 {
+		msg = midiContinue
+	}
+	return pl.sendMIDI(msg)
+}
+
+// midiNote is a note on message sent at a time.
+type midiNote struct {
+	at  time.Time
+	msg []byte
+}
+
+// midiStep plays the step ev starting at start for d: it releases the notes
+// of the previous step, sends the notes of ev at their offsets within the
+// step and the clock pulses due until the end of the step. The pulses are
+// spaced from the step length without swing and restart with every step
+// on the grid, so they do not follow swung steps. It reports false when stop
+// was closed.
+func (pl *Player) midiStep(stop <-chan struct{}, ev StepEvent, start time.Time, d time.Duration) (bool, error) {
+	if !pl.late || pl.clockBase.IsZero() {
+		pl.clockBase, pl.clockPulse = start, 0
+	}
+	end := start.Add(d)
+	msg, now := pl.notesOff(), []byte(nil)
+	var later []midiNote
+	for i, t := range ev.Tracks {
+		note, _ := ResolveGMNote(t.name)
+		on := []byte{0x90 | gmPercussionChannel, note, ev.Velocities[i]}
+		pl.notesOn = append(pl.notesOn, note)
+		if i < len(ev.Offsets) && ev.Offsets[i] > 0 {
+			later = append(later, midiNote{start.Add(min(ev.Offsets[i], d)), on})
+			continue
+		}
+		now = append(now, on...)
+	}
+	sort.SliceStable(later, func(i, j int) bool { return later[i].at.Before(later[j].at) })
+	// a pulse due with the step precedes its notes
+	if !pl.nextPulse().After(start) {
+		msg = append(msg, midiClock)
+		pl.clockPulse++
+	}
+	if err := pl.sendMIDI(append(msg, now...)...); err != nil {
+		return false, err
+	}
+	for {
+		at := pl.nextPulse()
+		pulse := at.Before(end)
+		if !pulse {
+			at = end
+		}
+		if len(later) > 0 && !later[0].at.After(at) {
+			if !wait(stop, later[0].at) {
+				return false, nil
+			}
+			if err := pl.sendMIDI(later[0].msg...); err != nil {
+				return false, err
+			}
+			later = later[1:]
+			continue
+		}
+		if !pulse {
+			return wait(stop, end), nil
+		}
+		if !wait(stop, at) {
+			return false, nil
+		}
+		if err := pl.sendMIDI(midiClock); err != nil {
+			return false, err
+		}
+		pl.clockPulse++
+	}
+}
+
+// nextPulse returns the time of the next MIDI clock pulse.
+func (pl *Player) nextPulse() time.Time {
+	if pl.clockPulse == clocksPerStep {
+		pl.clockBase, pl.clockPulse = pl.clockBase.Add(pl.unswung), 0
+	}
+	return pl.clockBase.Add(pl.unswung * time.Duration(pl.clockPulse) / clocksPerStep)
+}
+
+// midiStop releases all notes and sends stop.
+func (pl *Player) midiStop() error {
+	return pl.sendMIDI(append(pl.notesOff(), midiStop)...)
+}
+
+// notesOff returns the note off messages for all notes on and forgets them.
+func (pl *Player) notesOff() []byte {
+	var msg []byte
+	for _, note := range pl.notesOn {
+		msg = append(msg, 0x80|gmPercussionChannel, note, 0)
+	}
+	pl.notesOn = pl.notesOn[:0]
+	return msg
+}
+
+func (pl *Player) sendMIDI(msg ...byte) error {
+	if _, err := pl.midiOut.Write(msg); err != nil {
+		return fmt.Errorf("midi out: %v", err)
+	}
+	return nil
+}
+
+// MIDIPort is a raw MIDI device of the operating system.
+type MIDIPort struct {
+	Name string // name of the device node, for example midiC1D0
+	Path string
+}
+
+// midiDeviceGlobs are the raw MIDI device nodes per operating system. There
+// is no backend for systems without them, like Windows and macOS.
+var midiDeviceGlobs = map[string][]string{
+	"linux":   {"/dev/snd/midiC*D*", "/dev/midi*"},
+	"freebsd": {"/dev/umidi*", "/dev/midi*"},
+	"openbsd": {"/dev/rmidi*"},
+	"netbsd":  {"/dev/rmidi*"},
+}
+
+// MIDIOutPorts returns the raw MIDI devices sorted by name. The list is
+// empty on systems without raw MIDI devices.
+func MIDIOutPorts() ([]MIDIPort, error) {
+	var ports []MIDIPort
+	seen := make(map[string]bool)
+	for _, glob := range midiDeviceGlobs[runtime.GOOS] {
+		paths, err := filepath.Glob(glob)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			name := filepath.Base(path)
+			if !seen[name] {
+				seen[name] = true
+				ports = append(ports, MIDIPort{name, path})
+			}
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].Name < ports[j].Name })
+	return ports, nil
+}
+
+// OpenMIDIPort opens the port with the name for writing, see WithMIDIOut.
+func OpenMIDIPort(name string) (io.WriteCloser, error) {
+	ports, err := MIDIOutPorts()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range ports {
+		if p.Name == name {
+			return os.OpenFile(p.Path, os.O_WRONLY, 0)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNoMIDIPort, name)
+}
@@ -0,0 +1,100 @@
+package drum
+
+import (
+	"bytes"
+	"path"
+	"testing"
+)
+
+func TestPlayerMIDIOut(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	steps := 0
+	var out bytes.Buffer
+	pl := NewPlayer(p, func(ev StepEvent) {
+		if steps++; steps == 2 {
+			close(stop)
+		}
+	}, WithMIDIOut(&out))
+	pl.SetTempo(MaxTempo)
+	if err := pl.Play(stop); err != nil {
+		t.Fatal(err)
+	}
+	exp := []byte{
+		midiStart,
+		midiClock, 0x99, 36, 127, 0x99, 42, 127,
+		midiClock, midiClock, midiClock, midiClock, midiClock,
+		0x89, 36, 0, 0x89, 42, 0, midiClock,
+		midiStop,
+	}
+	if !bytes.Equal(out.Bytes(), exp) {
+		t.Errorf("expected % x but got % x", exp, out.Bytes())
+	}
+
+	out.Reset()
+	stop = make(chan struct{})
+	steps = 1
+	if err := pl.Play(stop); err != nil {
+		t.Fatal(err)
+	}
+	if b := out.Bytes(); len(b) == 0 || b[0] != midiContinue {
+		t.Errorf("expected continue when not at the start, got % x", b)
+	}
+}
+
+func TestPlayerMIDIOutSwingAndNudge(t *testing.T) {
+	kick, _ := NewTrack(0, "kick", Steps{0: true, 1: true, 2: true})
+	snare, _ := NewTrack(1, "snare", Steps{0: true})
+	snare.SetNudge(0.25)
+	p := &Pattern{version: "0.909", tempo: MaxTempo, tracks: []*Track{kick, snare}}
+	// off-beats are late by a tenth of a step, less than a clock pulse
+	p.SetSwing(20)
+	stop := make(chan struct{})
+	steps := 0
+	var out bytes.Buffer
+	pl := NewPlayer(p, func(ev StepEvent) {
+		if steps++; steps == 3 {
+			close(stop)
+		}
+	}, WithMIDIOut(&out))
+	if err := pl.Play(stop); err != nil {
+		t.Fatal(err)
+	}
+	clocks := func(n int) []byte {
+		return bytes.Repeat([]byte{midiClock}, n)
+	}
+	var exp []byte
+	for _, b := range [][]byte{
+		{midiStart},
+		// the nudged snare a quarter step late, after the second pulse; the
+		// first pulse of the next grid step falls into the longer step
+		{midiClock, 0x99, 36, 127}, clocks(1), {0x99, 38, 127}, clocks(5),
+		// the late off-beat starts between pulses and gets one less
+		{0x89, 36, 0, 0x89, 38, 0, 0x99, 36, 127}, clocks(5),
+		{0x89, 36, 0, midiClock, 0x99, 36, 127},
+		{0x89, 36, 0, midiStop},
+	} {
+		exp = append(exp, b...)
+	}
+	if !bytes.Equal(out.Bytes(), exp) {
+		t.Errorf("expected % x but got % x", exp, out.Bytes())
+	}
+}
+
+func TestMIDIOutPorts(t *testing.T) {
+	ports, err := MIDIOutPorts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range ports {
+		if p.Name == "" || p.Path == "" {
+			t.Errorf("unexpected port %+v", p)
+		}
+	}
+	if _, err := OpenMIDIPort("no-such-port"); err == nil {
+		t.Error("expected error for unknown port")
+	}
+}
@@ -0,0 +1,128 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var chunkMix = chunkID{'M', 'I', 'X', 'R'}
+
+// FeatureMix is the per track volume and pan chunk.
+const FeatureMix Feature = "mix"
+
+// Volume and pan ranges follow the MIDI controllers 7 and 10.
+const (
+	MaxVolume = 127
+	MinPan    = -64 // hard left
+	MaxPan    = 63  // hard right
+)
+
+var (
+	// ErrInvalidVolume is returned for a volume above MaxVolume.
+	ErrInvalidVolume = errors.New("invalid volume")
+	// ErrInvalidPan is returned for a pan position outside of MinPan and
+	// MaxPan.
+	ErrInvalidPan = errors.New("invalid pan")
+)
+
+// Volume returns the volume of the track from 0 (silent) to MaxVolume, the
+// default.
+func (t *Track) Volume() uint8 {
+	return MaxVolume - t.attenuation
+}
+
+// SetVolume sets the volume of the track.
+func (t *Track) SetVolume(v uint8) error {
+	if v > MaxVolume {
+		return ErrInvalidVolume
+	}
+	t.attenuation = MaxVolume - v
+	return nil
+}
+
+// Pan returns the stereo position of the track from MinPan to MaxPan. 0 is
+// the center, the default.
+func (t *Track) Pan() int8 {
+	return t.pan
+}
+
+// SetPan sets the stereo position of the track.
+func (t *Track) SetPan(pan int8) error {
+	if pan < MinPan || pan > MaxPan {
+		return ErrInvalidPan
+	}
+	t.pan = pan
+	return nil
+}
+
+// hasMix reports whether the volume or pan of the track differs from the
+// default.
+func (t *Track) hasMix() bool {
+	return t.attenuation != 0 || t.pan != 0
+}
+
+// panGains returns the gain of the left and right channel for the track
+// using a constant power pan law.
+func (t *Track) panGains() (left, right float64) {
+	a := float64(int(t.pan)-MinPan) / float64(MaxPan-MinPan) * math.Pi / 2
+	return math.Cos(a), math.Sin(a)
+}
+
+// The mix chunk stores an entry for every track with a volume or pan set:
+//
+//	|Track index (2 bytes)|Volume (1 byte)|Pan (1 byte, signed)|
+const mixChunkEntryLength = 4
+
+func decodeMixChunk(data []byte, p *Pattern) error {
+	if len(data)%mixChunkEntryLength != 0 {
+		return errors.New("invalid size")
+	}
+	for ; len(data) > 0; data = data[mixChunkEntryLength:] {
+		index := binary.LittleEndian.Uint16(data)
+		if int(index) >= len(p.tracks) {
+			return fmt.Errorf("track index %d out of range", index)
+		}
+		t := p.tracks[index]
+		if err := t.SetVolume(data[2]); err != nil {
+			return err
+		}
+		if err := t.SetPan(int8(data[3])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeMixChunk(p *Pattern) []byte {
+	var buf bytes.Buffer
+	for i, t := range p.tracks {
+		if !t.hasMix() {
+			continue
+		}
+		buf.Write(binary.LittleEndian.AppendUint16(nil, uint16(i)))
+		buf.Write([]byte{t.Volume(), byte(t.pan)})
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+func clearMix(p *Pattern) {
+	for _, t := range p.tracks {
+		t.attenuation, t.pan = 0, 0
+	}
+}
+
+// hasMix reports whether any track of the pattern has a volume or pan set.
+func (p *Pattern) hasMix() bool {
+	for _, t := range p.tracks {
+		if t.hasMix() {
+			return true
+		}
+	}
+	return false
+}
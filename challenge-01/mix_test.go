@@ -0,0 +1,47 @@
+package drum
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMixRoundTrip(t *testing.T) {
+	p := &Pattern{version: "0.909", tempo: 120, tracks: []*Track{{name: "kick"}, {name: "snare"}}}
+	if err := p.tracks[1].SetVolume(MaxVolume + 1); err != ErrInvalidVolume {
+		t.Errorf("expected error '%s' but got '%v'", ErrInvalidVolume, err)
+	}
+	if err := p.tracks[1].SetPan(MinPan - 1); err != ErrInvalidPan {
+		t.Errorf("expected error '%s' but got '%v'", ErrInvalidPan, err)
+	}
+	p.tracks[1].SetVolume(90)
+	p.tracks[1].SetPan(-20)
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, pan := decoded.tracks[1].Volume(), decoded.tracks[1].Pan(); v != 90 || pan != -20 {
+		t.Errorf("Expected volume 90 and pan -20 but got %v and %v", v, pan)
+	}
+	if v := decoded.tracks[0].Volume(); v != MaxVolume {
+		t.Errorf("Expected default volume but got %v", v)
+	}
+}
+
+func TestWriteMIDIMix(t *testing.T) {
+	p := &Pattern{tempo: 120, tracks: []*Track{{name: "kick", steps: Steps{true}}}}
+	p.tracks[0].SetVolume(100)
+	p.tracks[0].SetPan(MaxPan)
+	var buf bytes.Buffer
+	if err := WriteMIDI(&buf, p, 1); err != nil {
+		t.Fatal(err)
+	}
+	exp := []byte("\x00\xb9\x07\x64\x00\xb9\x0a\x7f\x00\x99\x24\x7f")
+	if !bytes.Contains(buf.Bytes(), exp) {
+		t.Errorf("Expected controllers %x in %x", exp, buf.Bytes())
+	}
+}
@@ -0,0 +1,175 @@
+package drum
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sync"
+)
+
+// voice is a sample sounding for a step, mixed by Render and the Mixer.
+type voice struct {
+	sample *Sample
+	track  *Track
+	pos    int // next frame of the sample, negative while delayed
+	l, r   float32
+	cut    int // frame of the sample the fade out starts at, -1 for none
+	fade   int // frames of the fade out
+}
+
+// newVoice returns the sample of the track at the velocity, scaled by the
+// track volume and placed by the track pan.
+func newVoice(t *Track, s *Sample, velocity uint8) voice {
+	gain := float64(velocity) / MaxVelocity * float64(t.Volume()) / MaxVolume
+	left, right := t.panGains()
+	return voice{sample: s, track: t, l: float32(gain * left), r: float32(gain * right), cut: -1, fade: int(chokeFade * float64(s.Rate))}
+}
+
+// choke fades the voice out from its current frame on.
+func (v *voice) choke() {
+	if v.cut < 0 || v.cut > v.pos {
+		v.cut = v.pos
+		if v.cut < 0 {
+			v.cut = 0
+		}
+	}
+}
+
+// length returns the frames of the sample the voice sounds for.
+func (v *voice) length() int {
+	end := v.sample.Frames()
+	if v.cut >= 0 && v.cut+v.fade < end {
+		end = v.cut + v.fade
+	}
+	return end
+}
+
+// mix adds the next frames of the voice to left and right and reports
+// whether it still sounds afterwards.
+func (v *voice) mix(left, right []float32) bool {
+	end := v.length()
+	for f := range left {
+		i := v.pos + f
+		if i < 0 {
+			continue
+		}
+		if i >= end {
+			break
+		}
+		l, r := v.l, v.r
+		if v.cut >= 0 && i >= v.cut {
+			// fade out over the frames after the cut
+			g := 1 - float32(i-v.cut+1)/float32(v.fade+1)
+			l, r = l*g, r*g
+		}
+		left[f] += v.sample.Left[i] * l
+		right[f] += v.sample.Right[i] * r
+	}
+	v.pos += len(left)
+	return v.pos < end
+}
+
+// Mixer sounds the step events of a Player with the samples of a kit as a
+// stream of stereo frames, the real time counterpart of Render. Handle is a
+// Player handler, Read feeds audio devices. Tracks choke the tracks of their
+// group like in Render.
+type Mixer struct {
+	mu          sync.Mutex
+	kit         *Kit
+	rate        int
+	voices      []voice
+	left, right []float32 // buffers of Read
+}
+
+// NewMixer returns a mixer of the samples of the kit, which must all have
+// the same sample rate.
+func NewMixer(kit *Kit) (*Mixer, error) {
+	rate := 0
+	check := func(s *Sample) error {
+		if rate == 0 {
+			rate = s.Rate
+		} else if s.Rate != rate {
+			return ErrSampleRate
+		}
+		return nil
+	}
+	for _, s := range kit.byID {
+		if err := check(s); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range kit.byName {
+		if err := check(s); err != nil {
+			return nil, err
+		}
+	}
+	if rate == 0 {
+		return nil, errors.New("mixer: empty kit")
+	}
+	return &Mixer{kit: kit, rate: rate}, nil
+}
+
+// Rate returns the sample rate of the frames in frames per second.
+func (m *Mixer) Rate() int {
+	return m.rate
+}
+
+// Handle starts the samples of the tracks of the step, each delayed by its
+// micro timing offset. Tracks without sample in the kit are silent.
+func (m *Mixer) Handle(ev StepEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, t := range ev.Tracks {
+		s, ok := m.kit.Sample(t)
+		if !ok {
+			continue
+		}
+		if g := m.kit.ChokeGroup(t); g != 0 {
+			for n := range m.voices {
+				if o := m.voices[n].track; o != t && m.kit.ChokeGroup(o) == g {
+					m.voices[n].choke()
+				}
+			}
+		}
+		v := newVoice(t, s, ev.Velocities[i])
+		// the leading silence of the sample is skipped
+		v.pos = m.kit.startFrames(s)
+		if i < len(ev.Offsets) && ev.Offsets[i] > 0 {
+			v.pos -= int(math.Round(ev.Offsets[i].Seconds() * float64(m.rate)))
+		}
+		m.voices = append(m.voices, v)
+	}
+}
+
+// Mix sets left and right to the next frames of the sounding samples.
+func (m *Mixer) Mix(left, right []float32) {
+	for i := range left {
+		left[i], right[i] = 0, 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sounding := m.voices[:0]
+	for _, v := range m.voices {
+		if v.mix(left, right) {
+			sounding = append(sounding, v)
+		}
+	}
+	m.voices = sounding
+}
+
+// Read implements io.Reader returning the next frames as interleaved 32 bit
+// little endian floats, left first, the format audio devices read.
+func (m *Mixer) Read(p []byte) (int, error) {
+	const frameSize = 8
+	n := len(p) / frameSize
+	if cap(m.left) < n {
+		m.left, m.right = make([]float32, n), make([]float32, n)
+	}
+	left, right := m.left[:n], m.right[:n]
+	m.Mix(left, right)
+	for i := range left {
+		binary.LittleEndian.PutUint32(p[i*frameSize:], math.Float32bits(left[i]))
+		binary.LittleEndian.PutUint32(p[i*frameSize+4:], math.Float32bits(right[i]))
+	}
+	return n * frameSize, nil
+}
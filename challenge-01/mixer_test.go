@@ -0,0 +1,58 @@
+package drum
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMixer(t *testing.T) {
+	kick, hat := &Track{name: "kick"}, &Track{name: "hh-open", pan: MinPan}
+	closed := &Track{name: "hh-close"}
+	kit := NewKit("test")
+	kit.Add("kick", &Sample{Rate: 1000, Left: []float32{1, 0.5}, Right: []float32{1, 0.5}})
+	ring := &Sample{Rate: 1000, Left: make([]float32, 100), Right: make([]float32, 100)}
+	for i := range ring.Left {
+		ring.Left[i], ring.Right[i] = 1, 1
+	}
+	kit.Add("hh-open", ring)
+	kit.Add("hh-close", &Sample{Rate: 1000, Left: []float32{0}, Right: []float32{0}})
+	m, err := NewMixer(kit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Rate() != 1000 {
+		t.Errorf("Expected the rate of the kit but got %d", m.Rate())
+	}
+
+	m.Handle(StepEvent{Tracks: []*Track{kick, hat}, Velocities: []uint8{MaxVelocity, 64}, Offsets: []time.Duration{0, 2 * time.Millisecond}})
+	left, right := make([]float32, 4), make([]float32, 4)
+	m.Mix(left, right)
+	center := float32(math.Cos(math.Pi / 4 * 64 / 63.5))
+	if !near(left[0], center) || !near(left[1], center/2) || left[2] != 64.0/127 || right[2] != 0 {
+		t.Errorf("unexpected frames %v %v", left, right)
+	}
+
+	// the closed hat chokes the open one
+	kit.SetChokeGroup("hh-open", 1)
+	kit.SetChokeGroup("hh-close", 1)
+	m.Handle(StepEvent{Tracks: []*Track{closed}, Velocities: []uint8{MaxVelocity}})
+	out := make([]byte, 8*10)
+	if n, err := m.Read(out); err != nil || n != len(out) {
+		t.Fatalf("Expected %d bytes but got %d, %v", len(out), n, err)
+	}
+	first := math.Float32frombits(binary.LittleEndian.Uint32(out))
+	last := math.Float32frombits(binary.LittleEndian.Uint32(out[8*9:]))
+	if first == 0 || first >= 64.0/127 || last != 0 {
+		t.Errorf("Expected the open hat to fade out but got %v and %v", first, last)
+	}
+	if len(m.voices) != 0 {
+		t.Errorf("Expected no voices left but got %d", len(m.voices))
+	}
+
+	kit.Add("snare", &Sample{Rate: 44100})
+	if _, err := NewMixer(kit); err != ErrSampleRate {
+		t.Errorf("Expected %v but got %v", ErrSampleRate, err)
+	}
+}
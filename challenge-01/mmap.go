@@ -0,0 +1,110 @@
+package drum
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"os"
+)
+
+// errMmapUnsupported is returned by mmap on platforms without memory mapped
+// files.
+var errMmapUnsupported = errors.New("memory mapping not supported")
+
+// mappedFile reads a file from its memory mapping or, when the file could not
+// be mapped, with ReadAt calls.
+type mappedFile struct {
+	file *os.File
+	data []byte // nil when not mapped
+}
+
+func openMapped(path string) (*mappedFile, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	m := mappedFile{file: file}
+	if data, err := mmap(file, info.Size()); err == nil {
+		m.data = data
+	}
+	return &m, info.Size(), nil
+}
+
+func (m *mappedFile) ReadAt(b []byte, off int64) (int, error) {
+	if m.data == nil {
+		return m.file.ReadAt(b, off)
+	}
+	if off < 0 || off > int64(len(m.data)) {
+		return 0, errors.New("read out of range")
+	}
+	n := copy(b, m.data[off:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (m *mappedFile) Close() error {
+	var err error
+	if m.data != nil {
+		err = munmap(m.data)
+		m.data = nil
+	}
+	if e := m.file.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// OpenBankMapped opens the zip archive found at path like OpenBank but maps
+// it into memory, so that large banks are not read as a whole and a pattern
+// is read from disk only when it is accessed. The offsets of the patterns are
+// indexed when the bank is opened: patterns stored without compression, as
+// written by the hardware, are decoded directly from the mapped memory
+// without the CRC32 check of the zip reader.
+// Where memory mapping is not available the archive is read with ReadAt
+// calls instead. Patterns do not reference the mapping and stay valid after
+// Close.
+func OpenBankMapped(path string) (*Bank, error) {
+	m, size, err := openMapped(path)
+	if err != nil {
+		return nil, err
+	}
+	b, err := newMappedBank(m, size)
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+func newMappedBank(m *mappedFile, size int64) (*Bank, error) {
+	r, err := zip.NewReader(m, size)
+	if err != nil {
+		return nil, err
+	}
+	b := newBank(r, m)
+	if m.data == nil {
+		return b, nil
+	}
+	for _, e := range b.entries {
+		if e.file.Method != zip.Store {
+			continue
+		}
+		off, err := e.file.DataOffset()
+		if err != nil {
+			return nil, err
+		}
+		n := int64(e.file.UncompressedSize64)
+		if off < 0 || n < 0 || off+n > size {
+			return nil, zip.ErrFormat
+		}
+		e.mapped = m.data[off : off+n : off+n]
+	}
+	return b, nil
+}
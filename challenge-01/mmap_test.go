@@ -0,0 +1,98 @@
+package drum
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeStoredBank writes a bank with the pattern_1 fixture stored without
+// compression and pattern_2 deflated.
+func writeStoredBank(t *testing.T, dir string) string {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, method := range map[string]uint16{"pattern_1.splice": zip.Store, "pattern_2.splice": zip.Deflate} {
+		raw, err := ioutil.ReadFile(path.Join("fixtures", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(raw)
+	}
+	zw.Close()
+	bankPath := filepath.Join(dir, "backup.zip")
+	if err := ioutil.WriteFile(bankPath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return bankPath
+}
+
+func TestOpenBankMapped(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	bankPath := writeStoredBank(t, dir)
+
+	open := map[string]func() (*Bank, error){
+		"mapped": func() (*Bank, error) { return OpenBankMapped(bankPath) },
+		"fallback": func() (*Bank, error) {
+			f, _ := os.Open(bankPath)
+			info, _ := f.Stat()
+			return newMappedBank(&mappedFile{file: f}, info.Size())
+		},
+	}
+	for msg, fn := range open {
+		b, err := fn()
+		if err != nil {
+			t.Fatalf("%s: %v", msg, err)
+		}
+		var patterns []*Pattern
+		for e := range b.Entries() {
+			if mapped := e.mapped != nil; mapped != (msg == "mapped" && e.file.Method == zip.Store) {
+				t.Errorf("%s: unexpected mapping of %s", msg, e.Name())
+			}
+			n := 0
+			for _, err := range e.TracksSeq() {
+				if err != nil {
+					t.Fatal(err)
+				}
+				n++
+			}
+			p, err := e.Pattern()
+			if err != nil {
+				t.Fatalf("%s: %v", msg, err)
+			}
+			if n != len(p.tracks) {
+				t.Errorf("%s: Expected %d tracks but iterated %d", msg, len(p.tracks), n)
+			}
+			patterns = append(patterns, p)
+		}
+		if err := b.Close(); err != nil {
+			t.Fatal(err)
+		}
+		for i, p := range patterns {
+			exp, _ := DecodeFile(path.Join("fixtures", b.entries[i].Name()))
+			if !reflect.DeepEqual(p, exp) {
+				t.Errorf("%s: Expected '%v' but got '%v'", msg, exp, p)
+			}
+		}
+	}
+
+	if _, err := OpenBankMapped(filepath.Join(dir, "missing.zip")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+	ioutil.WriteFile(filepath.Join(dir, "empty.zip"), nil, 0644)
+	if _, err := OpenBankMapped(filepath.Join(dir, "empty.zip")); err == nil {
+		t.Error("Expected error for empty file")
+	}
+}
@@ -0,0 +1,82 @@
+package drum
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Morph returns a blend of the patterns a and b, so that a performance can
+// move from one groove to the other over several bars by raising t from 0 to
+// 1 bar by bar. t is clamped to that range; Morph(a, b, 0, rng) is a copy of
+// a and Morph(a, b, 1, rng) a copy of b.
+//
+// Tracks are matched by id in the order of the patterns like by Diff. Every
+// step in which matched tracks differ is taken from b with the probability
+// t, together with its velocity and timing. Tracks only in a lose each
+// enabled step with the probability t and tracks only in b, which follow the
+// tracks of a, gain each enabled step with the probability t. The tempo and
+// the swing are interpolated linearly, all other values are taken from the
+// pattern closer to t. The result only depends on the state of rng like for
+// Humanize.
+func Morph(a, b *Pattern, t float64, rng *rand.Rand) *Pattern {
+	switch {
+	case math.IsNaN(t) || t <= 0:
+		return a.Clone()
+	case t >= 1:
+		return b.Clone()
+	}
+	near := a
+	if t >= 0.5 {
+		near = b
+	}
+	c := near.Clone()
+	c.tempo = a.tempo + float32(t)*(b.tempo-a.tempo)
+	c.swing = uint8(math.Round(float64(a.swing) + t*(float64(b.swing)-float64(a.swing))))
+	c.tracks = nil
+
+	matched := make([]bool, len(b.tracks))
+	for _, ta := range a.tracks {
+		var tb *Track
+		for i, o := range b.tracks {
+			if !matched[i] && o.id == ta.id {
+				matched[i], tb = true, o
+				break
+			}
+		}
+		c.tracks = append(c.tracks, morphTrack(ta, tb, t, rng))
+	}
+	for i, tb := range b.tracks {
+		if !matched[i] {
+			c.tracks = append(c.tracks, morphTrack(nil, tb, t, rng))
+		}
+	}
+	return c
+}
+
+// morphTrack blends the steps of a and b, one of which is nil for a track
+// missing in its pattern.
+func morphTrack(a, b *Track, t float64, rng *rand.Rand) *Track {
+	near := a
+	if b != nil && (a == nil || t >= 0.5) {
+		near = b
+	}
+	c := near.Clone()
+	var silent Track
+	if a == nil {
+		a = &silent
+	}
+	if b == nil {
+		b = &silent
+	}
+	for i := range c.steps {
+		if a.steps[i] == b.steps[i] && a.velocity[i] == b.velocity[i] && a.timing[i] == b.timing[i] {
+			continue
+		}
+		src := a
+		if rng.Float64() < t {
+			src = b
+		}
+		c.steps[i], c.velocity[i], c.timing[i] = src.steps[i], src.velocity[i], src.timing[i]
+	}
+	return c
+}
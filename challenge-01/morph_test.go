@@ -0,0 +1,62 @@
+package drum
+
+import (
+	"math/rand"
+	"path"
+	"testing"
+)
+
+func TestMorph(t *testing.T) {
+	a, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := DecodeFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.tracks = append(b.tracks, &Track{id: 99, name: "extra", steps: Steps{true, true, true, true}})
+
+	if m := Morph(a, b, 0, rand.New(rand.NewSource(1))); !m.Equal(a) {
+		t.Errorf("Expected a but got %v", m)
+	}
+	if m := Morph(a, b, 1.5, rand.New(rand.NewSource(1))); !m.Equal(b) {
+		t.Errorf("Expected b but got %v", m)
+	}
+
+	m := Morph(a, b, 0.25, rand.New(rand.NewSource(1)))
+	if exp := a.tempo + 0.25*(b.tempo-a.tempo); m.tempo != exp {
+		t.Errorf("Expected tempo %v but got %v", exp, m.tempo)
+	}
+	if m.version != a.version {
+		t.Errorf("Expected the version of a but got %q", m.version)
+	}
+	if !m.Equal(Morph(a, b, 0.25, rand.New(rand.NewSource(1)))) {
+		t.Error("Expected the same result for the same seed")
+	}
+	byID := func(p *Pattern, id uint32) *Track {
+		for _, t := range p.tracks {
+			if t.id == id {
+				return t
+			}
+		}
+		return nil
+	}
+	for _, tm := range m.tracks {
+		ta, tb := byID(a, tm.id), byID(b, tm.id)
+		for i, enabled := range tm.steps {
+			fromA := ta != nil && ta.steps[i] == enabled || ta == nil && !enabled
+			fromB := tb != nil && tb.steps[i] == enabled || tb == nil && !enabled
+			if !fromA && !fromB {
+				t.Errorf("track (%d) step %d is neither from a nor b", tm.id, i)
+			}
+		}
+	}
+	extra := m.tracks[len(m.tracks)-1]
+	if extra.id != 99 || extra.name != "extra" {
+		t.Errorf("Expected the track of b appended but got %v", extra)
+	}
+	if len(m.tracks) != len(a.tracks)+1 {
+		t.Errorf("Expected %d tracks but got %d", len(a.tracks)+1, len(m.tracks))
+	}
+}
@@ -0,0 +1,42 @@
+package drum
+
+// Mute toggles the mute state of the track and reports whether it is muted
+// now. Muted tracks are not played or exported.
+func (t *Track) Mute() bool {
+	t.muted = !t.muted
+	return t.muted
+}
+
+// Muted reports whether the track is muted.
+func (t *Track) Muted() bool {
+	return t.muted
+}
+
+// Solo toggles the solo state of the track and reports whether it is soloed
+// now. As long as any track of a pattern is soloed, only the soloed tracks
+// are played or exported.
+func (t *Track) Solo() bool {
+	t.solo = !t.solo
+	return t.solo
+}
+
+// Soloed reports whether the track is soloed.
+func (t *Track) Soloed() bool {
+	return t.solo
+}
+
+// hasSolo reports whether any track of the pattern is soloed.
+func (p *Pattern) hasSolo() bool {
+	for _, t := range p.tracks {
+		if t.solo {
+			return true
+		}
+	}
+	return false
+}
+
+// audible reports whether the track is heard. A muted track is never heard,
+// not even when it is soloed.
+func (t *Track) audible(solo bool) bool {
+	return !t.muted && (!solo || t.solo)
+}
@@ -0,0 +1,39 @@
+package drum
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestMuteSolo(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_5.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kick, hihat := p.tracks[0], p.tracks[1]
+	if !kick.Mute() || !kick.Muted() {
+		t.Fatal("expected kick to be muted")
+	}
+	if !strings.Contains(p.String(), "(1) Kick [muted]\t|") {
+		t.Errorf("expected muted annotation in:\n%v", p)
+	}
+	if !kick.Equal(&Track{id: kick.id, name: kick.name, steps: kick.steps}) {
+		t.Error("expected mute state to be ignored by Equal")
+	}
+
+	var got []string
+	for _, e := range p.schedule(1) {
+		got = append(got, p.tracks[e.track].name)
+	}
+	if exp := "[HiHat HiHat HiHat HiHat HiHat HiHat HiHat HiHat]"; fmt.Sprint(got) != exp {
+		t.Errorf("Expected '%v' but got '%v'", exp, got)
+	}
+
+	kick.Mute()
+	hihat.Solo()
+	if events := p.schedule(1); len(events) != 8 || events[0].track != 1 {
+		t.Errorf("expected only the soloed track but got %v", events)
+	}
+}
@@ -0,0 +1,49 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// ErrOffline is returned by networked subsystems when offline mode is on.
+var ErrOffline = errors.New("offline mode")
+
+// OfflineEnv is the environment variable that turns on offline mode when set
+// to a non empty value other than "0".
+const OfflineEnv = "SPLICE_OFFLINE"
+
+var offline int32
+
+func init() {
+	if v := os.Getenv(OfflineEnv); v != "" && v != "0" {
+		SetOffline(true)
+	}
+}
+
+// SetOffline turns the offline mode on or off. In offline mode every
+// subsystem of this package and its subpackages refuses network egress
+// while all local features keep working. Local control channels like Unix
+// sockets are not affected.
+func SetOffline(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&offline, v)
+}
+
+// IsOffline reports whether the offline mode is on.
+func IsOffline() bool {
+	return atomic.LoadInt32(&offline) == 1
+}
+
+// AllowNetwork must be called by every subsystem before it opens a network
+// connection. It returns an error wrapping ErrOffline when offline mode is on.
+func AllowNetwork(subsystem string) error {
+	if IsOffline() {
+		return fmt.Errorf("%s: %w", subsystem, ErrOffline)
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package drum
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOffline(t *testing.T) {
+	defer SetOffline(IsOffline())
+	SetOffline(false)
+	if err := AllowNetwork("sync"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	SetOffline(true)
+	if err := AllowNetwork("sync"); !errors.Is(err, ErrOffline) {
+		t.Errorf("expected error '%s' but got '%v'", ErrOffline, err)
+	}
+}
@@ -0,0 +1,5 @@
+//go:build !race
+
+package drum
+
+const raceEnabled = false
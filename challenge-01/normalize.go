@@ -0,0 +1,48 @@
+package drum
+
+import (
+	"sort"
+	"strings"
+)
+
+// NormalizeOption configures Normalize.
+type NormalizeOption func(*normalizeOptions)
+
+type normalizeOptions struct {
+	lowerCase bool
+}
+
+// WithLowerCaseNames makes Normalize lower case the track names.
+func WithLowerCaseNames() NormalizeOption {
+	return func(o *normalizeOptions) {
+		o.lowerCase = true
+	}
+}
+
+// Normalize removes the cosmetic differences between files of the same
+// pattern exported by different firmware, so that Equal, Diff and Hash
+// compare what is played: white space around the version and the track
+// names is trimmed, the padding of the version field as read is dropped and
+// the tracks are sorted by name and id, tracks of the same name and id in
+// their current order. The track ids themselves are kept, see NormalizeIDs.
+func Normalize(p *Pattern, opts ...NormalizeOption) {
+	var o normalizeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	p.version = strings.TrimSpace(p.version)
+	p.rawVersion = nil
+	for _, t := range p.tracks {
+		t.name = strings.TrimSpace(t.name)
+		if o.lowerCase {
+			t.name = strings.ToLower(t.name)
+		}
+	}
+	sort.SliceStable(p.tracks, func(i, j int) bool {
+		a, b := p.tracks[i], p.tracks[j]
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		return a.id < b.id
+	})
+}
@@ -0,0 +1,51 @@
+package drum
+
+import (
+	"path"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported := p.Clone()
+	exported.version = " " + p.version + " "
+	exported.rawVersion = append([]byte(exported.version), 0, 'x')
+	// reverse the tracks and pad their names like another firmware would
+	for i, j := 0, len(exported.tracks)-1; i < j; i, j = i+1, j-1 {
+		exported.tracks[i], exported.tracks[j] = exported.tracks[j], exported.tracks[i]
+	}
+	for _, tr := range exported.tracks {
+		tr.name = "  " + tr.name + "\t"
+	}
+	if exported.Equal(p) {
+		t.Fatal("Expected the exported pattern to differ before normalizing")
+	}
+	Normalize(exported)
+	Normalize(p)
+	if !exported.Equal(p) || len(Diff(exported, p)) != 0 || exported.Hash() != p.Hash() {
+		t.Errorf("Expected equal patterns after normalizing but got %v", Diff(exported, p))
+	}
+	if exported.rawVersion != nil {
+		t.Errorf("Expected the version padding dropped but got %q", exported.rawVersion)
+	}
+	if exp := "clap"; p.tracks[0].name != exp {
+		t.Errorf("Expected the tracks sorted by name but got %s first", p.tracks[0].name)
+	}
+
+	kick, err := NewTrack(1, "Kick ", Steps{true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewTrack(0, "kick", Steps{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &Pattern{version: "0.808", tempo: 120, tracks: []*Track{kick, other}}
+	Normalize(q, WithLowerCaseNames())
+	if q.tracks[0] != other || q.tracks[1].name != "kick" {
+		t.Errorf("Expected lower case names sorted by id but got %v", q)
+	}
+}
@@ -0,0 +1,261 @@
+package drum
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// percussionNote is the position of a drum on the five line percussion
+// staff and its name in the LilyPond drum mode.
+type percussionNote struct {
+	step     string // display step on the staff
+	octave   int
+	notehead string // MusicXML notehead, empty for a normal one
+	lily     string
+}
+
+// percussionNotes map General MIDI notes to the common drum set notation.
+var percussionNotes = map[uint8]percussionNote{
+	36: {"F", 4, "", "bd"},
+	37: {"C", 5, "circle-x", "ss"},
+	38: {"C", 5, "", "sn"},
+	39: {"C", 5, "x", "hc"},
+	41: {"A", 4, "", "tomfl"},
+	42: {"G", 5, "x", "hhc"},
+	44: {"D", 4, "x", "hhp"},
+	45: {"A", 4, "", "toml"},
+	46: {"G", 5, "circle-x", "hho"},
+	47: {"D", 5, "", "tommh"},
+	49: {"A", 5, "x", "cymc"},
+	50: {"E", 5, "", "tomh"},
+	51: {"F", 5, "x", "cymr"},
+	54: {"E", 5, "x", "tamb"},
+	56: {"E", 5, "triangle", "cb"},
+	63: {"E", 5, "", "cghh"},
+	64: {"C", 5, "", "cgl"},
+	70: {"E", 5, "x", "mar"},
+	75: {"B", 4, "x", "cl"},
+}
+
+// percussionNoteOf returns the notation of the track. Unknown drums are
+// written like claves.
+func percussionNoteOf(t *Track) percussionNote {
+	note, _ := ResolveGMNote(t.name)
+	if n, ok := percussionNotes[note]; ok {
+		return n
+	}
+	return percussionNotes[fallbackGMNote]
+}
+
+// chart returns the indexes of the audible tracks hit at every step.
+func (p *Pattern) chart() [stepsLength][]int {
+	var c [stepsLength][]int
+	solo := p.hasSolo()
+	for i, t := range p.tracks {
+		if !t.audible(solo) {
+			continue
+		}
+		for step, enabled := range t.steps {
+			if enabled {
+				c[step] = append(c[step], i)
+			}
+		}
+	}
+	return c
+}
+
+// restRuns returns the number of consecutive rests starting at every step
+// within its beat, or 0 for steps with notes or covered by a previous rest.
+func restRuns(c [stepsLength][]int) [stepsLength]int {
+	var runs [stepsLength]int
+	for step := 0; step < stepsLength; {
+		if len(c[step]) > 0 {
+			step++
+			continue
+		}
+		n := 1
+		for step+n < stepsLength && (step+n)%blockSize != 0 && len(c[step+n]) == 0 {
+			n++
+		}
+		runs[step] = n
+		step += n
+	}
+	return runs
+}
+
+// sixteenthTypes are the note types of durations of 1 to 4 sixteenths.
+var sixteenthTypes = [...]struct {
+	name   string
+	dotted bool
+	lily   int // LilyPond duration
+}{
+	{"16th", false, 16}, {"eighth", false, 8}, {"eighth", true, 8}, {"quarter", false, 4},
+}
+
+type musicXMLScore struct {
+	XMLName xml.Name            `xml:"score-partwise"`
+	Version string              `xml:"version,attr"`
+	Title   string              `xml:"work>work-title"`
+	Parts   []musicXMLScorePart `xml:"part-list>score-part"`
+	Part    musicXMLPart        `xml:"part"`
+}
+
+type musicXMLScorePart struct {
+	ID          string               `xml:"id,attr"`
+	Name        string               `xml:"part-name"`
+	Instruments []musicXMLInstrument `xml:"score-instrument"`
+}
+
+type musicXMLInstrument struct {
+	ID   string `xml:"id,attr"`
+	Name string `xml:"instrument-name"`
+}
+
+type musicXMLPart struct {
+	ID      string          `xml:"id,attr"`
+	Measure musicXMLMeasure `xml:"measure"`
+}
+
+type musicXMLMeasure struct {
+	Number     int               `xml:"number,attr"`
+	Attributes musicXMLAttribute `xml:"attributes"`
+	Direction  musicXMLDirection `xml:"direction"`
+	Notes      []musicXMLNote    `xml:"note"`
+}
+
+type musicXMLAttribute struct {
+	Divisions int    `xml:"divisions"`
+	Fifths    int    `xml:"key>fifths"`
+	Beats     int    `xml:"time>beats"`
+	BeatType  int    `xml:"time>beat-type"`
+	Clef      string `xml:"clef>sign"`
+}
+
+type musicXMLDirection struct {
+	Placement string  `xml:"placement,attr"`
+	BeatUnit  string  `xml:"direction-type>metronome>beat-unit"`
+	PerMinute float64 `xml:"direction-type>metronome>per-minute"`
+	Sound     struct {
+		Tempo float64 `xml:"tempo,attr"`
+	} `xml:"sound"`
+}
+
+type musicXMLNote struct {
+	Chord      *struct{}          `xml:"chord"`
+	Rest       *struct{}          `xml:"rest"`
+	Unpitched  *musicXMLUnpitched `xml:"unpitched"`
+	Duration   int                `xml:"duration"`
+	Instrument *musicXMLRef       `xml:"instrument"`
+	Voice      int                `xml:"voice"`
+	Type       string             `xml:"type"`
+	Dot        *struct{}          `xml:"dot"`
+	Stem       string             `xml:"stem,omitempty"`
+	Notehead   string             `xml:"notehead,omitempty"`
+}
+
+type musicXMLUnpitched struct {
+	Step   string `xml:"display-step"`
+	Octave int    `xml:"display-octave"`
+}
+
+type musicXMLRef struct {
+	ID string `xml:"id,attr"`
+}
+
+// ToMusicXML writes the pattern as a single measure of MusicXML percussion
+// notation with the tempo marking to w. Tracks are placed on the common
+// drum set staff positions by their General MIDI drum. Muted tracks are left
+// out, swing and micro timing are not notated.
+func (p *Pattern) ToMusicXML(w io.Writer) error {
+	instrumentID := func(track int) string { return fmt.Sprintf("P1-I%d", track+1) }
+	score := musicXMLScore{
+		Version: "3.1",
+		Title:   p.version,
+		Part: musicXMLPart{ID: "P1", Measure: musicXMLMeasure{
+			Number:     1,
+			Attributes: musicXMLAttribute{Divisions: blockSize, Beats: 4, BeatType: 4, Clef: "percussion"},
+			Direction:  musicXMLDirection{Placement: "above", BeatUnit: "quarter", PerMinute: tempo64(p.tempo)},
+		}},
+	}
+	score.Part.Measure.Direction.Sound.Tempo = tempo64(p.tempo)
+	part := musicXMLScorePart{ID: "P1", Name: "Drums"}
+	for i, t := range p.tracks {
+		part.Instruments = append(part.Instruments, musicXMLInstrument{instrumentID(i), t.name})
+	}
+	score.Parts = []musicXMLScorePart{part}
+	c := p.chart()
+	runs := restRuns(c)
+	for step, tracks := range c {
+		if n := runs[step]; n > 0 {
+			typ := sixteenthTypes[n-1]
+			note := musicXMLNote{Rest: &struct{}{}, Duration: n, Voice: 1, Type: typ.name}
+			if typ.dotted {
+				note.Dot = &struct{}{}
+			}
+			score.Part.Measure.Notes = append(score.Part.Measure.Notes, note)
+		}
+		for n, i := range tracks {
+			pn := percussionNoteOf(p.tracks[i])
+			note := musicXMLNote{
+				Unpitched:  &musicXMLUnpitched{pn.step, pn.octave},
+				Duration:   1,
+				Instrument: &musicXMLRef{instrumentID(i)},
+				Voice:      1,
+				Type:       sixteenthTypes[0].name,
+				Stem:       "up",
+				Notehead:   pn.notehead,
+			}
+			if n > 0 {
+				note.Chord = &struct{}{}
+			}
+			score.Part.Measure.Notes = append(score.Part.Measure.Notes, note)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<!DOCTYPE score-partwise PUBLIC "-//Recordare//DTD MusicXML 3.1 Partwise//EN" "http://www.musicxml.org/dtds/partwise.dtd">` + "\n")
+	enc := xml.NewEncoder(bw)
+	enc.Indent("", "  ")
+	if err := enc.Encode(score); err != nil {
+		return err
+	}
+	bw.WriteString("\n")
+	return bw.Flush()
+}
+
+// ToLilyPond writes the pattern as a single measure in the LilyPond drum
+// mode with the tempo marking to w, see ToMusicXML.
+func (p *Pattern) ToLilyPond(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "\\version \"2.18.2\"\n\\header { title = %q }\n\n", p.version)
+	fmt.Fprintf(bw, "\\drums {\n  \\tempo 4 = %v\n  \\time 4/4\n ", tempo64(p.tempo))
+	c := p.chart()
+	runs := restRuns(c)
+	for step, tracks := range c {
+		if n := runs[step]; n > 0 {
+			typ := sixteenthTypes[n-1]
+			fmt.Fprintf(bw, " r%d", typ.lily)
+			if typ.dotted {
+				bw.WriteString(".")
+			}
+		}
+		if len(tracks) == 0 {
+			continue
+		}
+		names := make([]string, len(tracks))
+		for n, i := range tracks {
+			names[n] = percussionNoteOf(p.tracks[i]).lily
+		}
+		if len(names) == 1 {
+			fmt.Fprintf(bw, " %s16", names[0])
+		} else {
+			fmt.Fprintf(bw, " <%s>16", strings.Join(names, " "))
+		}
+	}
+	bw.WriteString("\n}\n")
+	return bw.Flush()
+}
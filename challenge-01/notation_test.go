@@ -0,0 +1,66 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/xml"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestToLilyPond(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_5.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := p.ToLilyPond(&buf); err != nil {
+		t.Fatal(err)
+	}
+	exp := "\\tempo 4 = 999\n  \\time 4/4\n  <bd hhc>16 r16 hhc16 r16 hhc16 r16 hhc16 r16 <bd hhc>16 r16 hhc16 r16 hhc16 r16 hhc16 r16\n}"
+	if !strings.Contains(buf.String(), exp) {
+		t.Errorf("Expected '%v' in:\n%v", exp, buf.String())
+	}
+
+	p.tracks[1].Mute()
+	buf.Reset()
+	if err := p.ToLilyPond(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if exp := " bd16 r8. r4 bd16 r8. r4\n"; !strings.Contains(buf.String(), exp) {
+		t.Errorf("Expected '%v' in:\n%v", exp, buf.String())
+	}
+}
+
+func TestToMusicXML(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_5.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := p.ToMusicXML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var score musicXMLScore
+	if err := xml.Unmarshal(buf.Bytes(), &score); err != nil {
+		t.Fatal(err)
+	}
+	m := score.Part.Measure
+	if m.Direction.PerMinute != 999 || len(score.Parts) != 1 || len(score.Parts[0].Instruments) != 2 {
+		t.Errorf("unexpected score: %+v", score)
+	}
+	// kick and hihat chord, a rest and a hihat
+	if len(m.Notes) < 3 || m.Notes[0].Unpitched.Step != "F" || m.Notes[1].Chord == nil ||
+		m.Notes[1].Notehead != "x" || m.Notes[2].Rest == nil {
+		t.Errorf("unexpected notes: %+v", m.Notes)
+	}
+	duration := 0
+	for _, n := range m.Notes {
+		if n.Chord == nil {
+			duration += n.Duration
+		}
+	}
+	if duration != stepsLength {
+		t.Errorf("Expected a measure of %d sixteenths but got %d", stepsLength, duration)
+	}
+}
@@ -0,0 +1,73 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var chunkNudge = chunkID{'N', 'U', 'D', 'G'}
+
+// FeatureNudge is the chunk of the timing offsets of whole tracks.
+const FeatureNudge Feature = "nudge"
+
+// Nudge returns the timing offset of all steps of the track as fraction of
+// a step. Negative values play the track early.
+func (t *Track) Nudge() float64 {
+	return float64(t.nudge) / ticksPerStep
+}
+
+// SetNudge moves all steps of the track by the offset as fraction of a step
+// between -0.5 and 0.5, like a lazy snare at 0.1 or a pushed hi-hat at
+// -0.1. The nudge adds to the micro timing of the steps, see SetTiming, and
+// is stored with the same resolution of 1/24 step.
+func (t *Track) SetNudge(offset float64) error {
+	if math.IsNaN(offset) || math.Abs(offset) > 0.5 {
+		return fmt.Errorf("invalid nudge %v", offset)
+	}
+	t.nudge = int8(math.Round(offset * ticksPerStep))
+	return nil
+}
+
+// offset returns the timing offset of the step in ticks including the
+// nudge of the track.
+func (t *Track) offset(step int) int {
+	return int(t.timing[step]) + int(t.nudge)
+}
+
+// The nudge chunk stores |Track index (2 bytes)|Nudge in ticks (1 byte,
+// signed)| for every nudged track.
+
+func decodeNudgeChunk(data []byte, p *Pattern) error {
+	const entryLength = 3
+	if len(data)%entryLength != 0 {
+		return errors.New("invalid size")
+	}
+	for ; len(data) > 0; data = data[entryLength:] {
+		index := int(data[0]) | int(data[1])<<8
+		if index >= len(p.tracks) {
+			return fmt.Errorf("track index %d out of range", index)
+		}
+		if n := int8(data[2]); n < -maxTimingTicks || n > maxTimingTicks {
+			return fmt.Errorf("invalid nudge of %d ticks", n)
+		}
+		p.tracks[index].nudge = int8(data[2])
+	}
+	return nil
+}
+
+func encodeNudgeChunk(p *Pattern) []byte {
+	var data []byte
+	for i, t := range p.tracks {
+		if t.nudge != 0 {
+			data = append(data, byte(i), byte(i>>8), byte(t.nudge))
+		}
+	}
+	return data
+}
+
+func clearNudge(p *Pattern) {
+	for _, t := range p.tracks {
+		t.nudge = 0
+	}
+}
@@ -0,0 +1,91 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// nudgePattern returns a kick on step 0 and a lazy snare on step 4.
+func nudgePattern(t *testing.T) *Pattern {
+	p, err := NewPattern("0.808-alpha", 120,
+		&Track{name: "kick", steps: Steps{true}},
+		&Track{id: 1, name: "snare", steps: Steps{4: true}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.tracks[1].SetNudge(0.25); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestSetNudge(t *testing.T) {
+	tr := &Track{}
+	for _, n := range []float64{-0.6, 0.51, 1} {
+		if err := tr.SetNudge(n); err == nil {
+			t.Errorf("Expected an error for nudge %v", n)
+		}
+	}
+	if err := tr.SetNudge(-0.5); err != nil || tr.Nudge() != -0.5 {
+		t.Errorf("Expected nudge -0.5 but got %v: %v", tr.Nudge(), err)
+	}
+	tr.SetTiming(0, 0.25)
+	if got := tr.offset(0); got != -6 {
+		t.Errorf("Expected the nudge added to the timing but got %d ticks", got)
+	}
+}
+
+func TestNudgeSchedule(t *testing.T) {
+	p := nudgePattern(t)
+	events := p.schedule(1)
+	if len(events) != 2 || events[0].tick != 0 || events[1].tick != 4*ticksPerStep+6 {
+		t.Errorf("Expected the snare 6 ticks late but got %+v", events)
+	}
+
+	pl := NewPlayer(p, nil)
+	var offsets []time.Duration
+	for i := 0; i < 5; i++ {
+		ev, _, err := pl.advance(time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		offsets = append(offsets, ev.Offsets...)
+	}
+	// a step at 120 BPM is 125ms
+	if len(offsets) != 2 || offsets[0] != 0 || offsets[1] != 125*time.Millisecond/4 {
+		t.Errorf("Expected the snare a quarter step late but got %v", offsets)
+	}
+}
+
+func TestNudgeRoundTrip(t *testing.T) {
+	p := nudgePattern(t)
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(p) || decoded.tracks[1].Nudge() != 0.25 {
+		t.Errorf("Expected the nudge to round trip but got %v", decoded.tracks[1].Nudge())
+	}
+	if got := decoded.Features(); len(got) != 1 || got[0] != FeatureNudge {
+		t.Errorf("Expected the nudge feature but got %v", got)
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Pattern
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !fromJSON.Equal(p) {
+		t.Errorf("Expected the nudge to round trip JSON but got %s", data)
+	}
+}
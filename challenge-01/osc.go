@@ -0,0 +1,170 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultOSCAddress is the address template used by OSCSender unless set
+// with WithOSCAddress.
+const DefaultOSCAddress = "/drum/{track}/{step}"
+
+// ntpEpochOffset is the number of seconds from 1900, the start of the OSC
+// time tags, to the Unix epoch.
+const ntpEpochOffset = 2208988800
+
+// OSCSender sends a message for every track triggered by the Player as Open
+// Sound Control packets, for example to drive Max/MSP, Pure Data or lighting
+// rigs. Every message carries the velocity as int32 and the micro timing
+// offset in seconds as float32.
+type OSCSender struct {
+	mu      sync.Mutex
+	w       io.Writer
+	tmpl    string
+	bundle  bool
+	latency time.Duration
+	err     error
+}
+
+// OSCOption configures an OSCSender.
+type OSCOption func(*OSCSender)
+
+// WithOSCAddress sets the address template. The placeholders {track},
+// {id}, {step} and {section} are replaced by the track name, the track id,
+// the step from 0 and the song section. Characters of the name not allowed
+// in OSC addresses are replaced by '_'.
+func WithOSCAddress(template string) OSCOption {
+	return func(s *OSCSender) { s.tmpl = template }
+}
+
+// WithOSCBundles wraps every message in a bundle time tagged with the time
+// the step should be heard at, including its micro timing offset, plus
+// latency. Receivers supporting bundles schedule the messages sample
+// accurate as long as the latency covers the network delay.
+func WithOSCBundles(latency time.Duration) OSCOption {
+	return func(s *OSCSender) { s.bundle, s.latency = true, latency }
+}
+
+// NewOSCSender returns a sender writing every packet with a single call to
+// w, as needed for datagram connections.
+func NewOSCSender(w io.Writer, opts ...OSCOption) *OSCSender {
+	s := &OSCSender{w: w, tmpl: DefaultOSCAddress}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// DialOSC returns a sender to the UDP address, see NewOSCSender. It fails
+// with ErrOffline in offline mode.
+func DialOSC(addr string, opts ...OSCOption) (*OSCSender, error) {
+	if err := AllowNetwork("osc"); err != nil {
+		return nil, err
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("osc: %v", err)
+	}
+	return NewOSCSender(conn, opts...), nil
+}
+
+// Send sends the messages for the tracks triggered by the event.
+func (s *OSCSender) Send(ev StepEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, t := range ev.Tracks {
+		addr := strings.NewReplacer(
+			"{track}", oscName(t.name),
+			"{id}", strconv.FormatUint(uint64(t.id), 10),
+			"{step}", strconv.Itoa(ev.Step),
+			"{section}", strconv.Itoa(ev.Section),
+		).Replace(s.tmpl)
+		msg := oscMessage(addr, int32(ev.Velocities[i]), float32(ev.Offsets[i].Seconds()))
+		if s.bundle {
+			msg = oscBundle(ev.Time.Add(ev.Offsets[i]+s.latency), msg)
+		}
+		if _, err := s.w.Write(msg); err != nil {
+			return fmt.Errorf("osc: %v", err)
+		}
+	}
+	return nil
+}
+
+// Handler returns a Player handler sending every step event. Send errors
+// don't stop playing, the first one is returned by Err.
+func (s *OSCSender) Handler() func(StepEvent) {
+	return func(ev StepEvent) {
+		if err := s.Send(ev); err != nil {
+			s.mu.Lock()
+			if s.err == nil {
+				s.err = err
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Err returns the first error of the handler.
+func (s *OSCSender) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close closes the connection when the writer is an io.Closer.
+func (s *OSCSender) Close() error {
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// oscName replaces the characters with a special meaning in OSC address
+// patterns.
+func oscName(name string) string {
+	if name == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || strings.ContainsRune("#*,/?[]{}", r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// oscMessage encodes a message with an int32 and a float32 argument.
+func oscMessage(addr string, i int32, f float32) []byte {
+	var buf bytes.Buffer
+	writeOSCString(&buf, addr)
+	writeOSCString(&buf, ",if")
+	binary.Write(&buf, binary.BigEndian, i)
+	binary.Write(&buf, binary.BigEndian, math.Float32bits(f))
+	return buf.Bytes()
+}
+
+// oscBundle wraps the message in a bundle with the time tag of t.
+func oscBundle(t time.Time, msg []byte) []byte {
+	var buf bytes.Buffer
+	writeOSCString(&buf, "#bundle")
+	secs := uint64(t.Unix() + ntpEpochOffset)
+	frac := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	binary.Write(&buf, binary.BigEndian, secs<<32|frac)
+	binary.Write(&buf, binary.BigEndian, int32(len(msg)))
+	buf.Write(msg)
+	return buf.Bytes()
+}
+
+// writeOSCString writes s null terminated and padded to 4 bytes.
+func writeOSCString(buf *bytes.Buffer, s string) {
+	buf.WriteString(s)
+	buf.Write(make([]byte, 4-len(s)%4))
+}
@@ -0,0 +1,85 @@
+package drum
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+// packetRecorder records every write as a packet.
+type packetRecorder [][]byte
+
+func (r *packetRecorder) Write(b []byte) (int, error) {
+	*r = append(*r, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func TestOSCSender(t *testing.T) {
+	kick, _ := NewTrack(3, "Kick Drum", Steps{})
+	hat, _ := NewTrack(4, "hh", Steps{})
+	at := time.Unix(1, int64(time.Second/4))
+	ev := StepEvent{
+		Step:       5,
+		Time:       at,
+		Tracks:     []*Track{kick, hat},
+		Velocities: []uint8{100, 127},
+		Offsets:    []time.Duration{0, time.Second / 4},
+	}
+
+	var rec packetRecorder
+	if err := NewOSCSender(&rec).Send(ev); err != nil {
+		t.Fatal(err)
+	}
+	exp := append([]byte("/drum/Kick_Drum/5\x00\x00\x00,if\x00"), 0, 0, 0, 100, 0, 0, 0, 0)
+	if len(rec) != 2 || !bytes.Equal(rec[0], exp) {
+		t.Fatalf("expected %q but got %q", exp, rec)
+	}
+
+	rec = nil
+	s := NewOSCSender(&rec, WithOSCAddress("/{section}/{id}"), WithOSCBundles(time.Second))
+	if err := s.Send(ev); err != nil {
+		t.Fatal(err)
+	}
+	msg := append([]byte("/0/4\x00\x00\x00\x00,if\x00"), 0, 0, 0, 127, 0x3e, 0x80, 0, 0)
+	// 1.25s + 0.25s offset + 1s latency after the Unix epoch
+	exp = append([]byte("#bundle\x00"), 0x83, 0xaa, 0x7e, 0x82, 0x80, 0, 0, 0, 0, 0, 0, byte(len(msg)))
+	exp = append(exp, msg...)
+	if len(rec) != 2 || !bytes.Equal(rec[1], exp) {
+		t.Errorf("expected %q but got %q", exp, rec)
+	}
+}
+
+func TestDialOSC(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	SetOffline(true)
+	if _, err := DialOSC(conn.LocalAddr().String()); err == nil {
+		t.Error("expected offline error")
+	}
+	SetOffline(false)
+
+	s, err := DialOSC(conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	kick, _ := NewTrack(0, "kick", Steps{})
+	s.Handler()(StepEvent{Tracks: []*Track{kick}, Velocities: []uint8{1}, Offsets: []time.Duration{0}})
+	if s.Err() != nil {
+		t.Fatal(s.Err())
+	}
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf[:n], []byte("/drum/kick/0\x00")) {
+		t.Errorf("unexpected packet %q", buf[:n])
+	}
+}
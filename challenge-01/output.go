@@ -0,0 +1,147 @@
+package drum
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/alpe/go-challenge/challenge-01/internal/yaml"
+)
+
+// OutputSchema is the version of the documents written by
+// OutputFormat.Write. It is increased when fields are removed or change
+// their meaning; new fields are added without changing it, so consumers
+// should ignore fields they do not know.
+const OutputSchema = 1
+
+// The kinds of output documents.
+const (
+	KindPattern  = "pattern"
+	KindDiff     = "diff"
+	KindIssues   = "issues"
+	KindAnalysis = "analysis"
+)
+
+// OutputHeader starts every output document with its schema version and
+// kind, so consumers can check both before reading the rest.
+type OutputHeader struct {
+	Schema int    `json:"schema"`
+	Kind   string `json:"kind"`
+}
+
+// PatternOutput is the document of a pattern, in its JSON representation
+// or, written as YAML, in its YAML representation.
+type PatternOutput struct {
+	OutputHeader
+	File    string   `json:"file"`
+	Pattern *Pattern `json:"pattern"`
+}
+
+// NewPatternOutput returns the document of the pattern read from file.
+func NewPatternOutput(file string, p *Pattern) PatternOutput {
+	return PatternOutput{OutputHeader{OutputSchema, KindPattern}, file, p}
+}
+
+// yamlValue returns the document with the YAML representation of the
+// pattern.
+func (o PatternOutput) yamlValue() interface{} {
+	return struct {
+		OutputHeader
+		File    string      `json:"file"`
+		Pattern patternYAML `json:"pattern"`
+	}{o.OutputHeader, o.File, o.Pattern.toYAML()}
+}
+
+// DiffOutput is the document of the differences between two patterns, see
+// Diff.
+type DiffOutput struct {
+	OutputHeader
+	From        string       `json:"from"`
+	To          string       `json:"to"`
+	Equal       bool         `json:"equal"`
+	Differences []Difference `json:"differences"`
+}
+
+// NewDiffOutput returns the document of the differences from the pattern a
+// read from the file from to the pattern b read from to.
+func NewDiffOutput(from, to string, a, b *Pattern) DiffOutput {
+	diffs := Diff(a, b)
+	if diffs == nil {
+		diffs = []Difference{}
+	}
+	return DiffOutput{OutputHeader{OutputSchema, KindDiff}, from, to, len(diffs) == 0, diffs}
+}
+
+// IssuesOutput is the document of the issues found by Validate. Valid is
+// false when any issue has SeverityError.
+type IssuesOutput struct {
+	OutputHeader
+	File   string  `json:"file"`
+	Valid  bool    `json:"valid"`
+	Issues []Issue `json:"issues"`
+}
+
+// NewIssuesOutput returns the document of the issues of the pattern read
+// from file.
+func NewIssuesOutput(file string, issues []Issue) IssuesOutput {
+	if issues == nil {
+		issues = []Issue{}
+	}
+	return IssuesOutput{OutputHeader{OutputSchema, KindIssues}, file, !HasErrors(issues), issues}
+}
+
+// AnalysisOutput is the document of the reports of Analyze, one per file.
+type AnalysisOutput struct {
+	OutputHeader
+	Files []FileReport `json:"files"`
+}
+
+// FileReport is the report of the pattern read from File.
+type FileReport struct {
+	File   string `json:"file"`
+	Report Report `json:"report"`
+}
+
+// NewAnalysisOutput returns the document of the reports of the patterns
+// read from files, in the same order.
+func NewAnalysisOutput(files []string, reports []Report) AnalysisOutput {
+	o := AnalysisOutput{OutputHeader{OutputSchema, KindAnalysis}, []FileReport{}}
+	for i, r := range reports {
+		o.Files = append(o.Files, FileReport{files[i], r})
+	}
+	return o
+}
+
+// OutputFormat is the format output documents are written in.
+type OutputFormat string
+
+// The output formats.
+const (
+	OutputJSON OutputFormat = "json"
+	OutputYAML OutputFormat = "yaml"
+)
+
+// Write writes the document v, like a PatternOutput, to w: as indented
+// JSON or as YAML with the same keys.
+func (f OutputFormat) Write(w io.Writer, v interface{}) error {
+	switch f {
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case OutputYAML:
+		if y, ok := v.(interface{ yamlValue() interface{} }); ok {
+			v = y.yamlValue()
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if data, err = yaml.FromJSON(data); err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	return fmt.Errorf("unknown output format %q", string(f))
+}
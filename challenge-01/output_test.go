@@ -0,0 +1,61 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutputFormat(t *testing.T) {
+	p, err := DecodeFile(filepath.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := p.Clone()
+	changed.SetTempo(98)
+
+	var buf bytes.Buffer
+	if err := OutputJSON.Write(&buf, NewDiffOutput("a.splice", "b.splice", p, changed)); err != nil {
+		t.Fatal(err)
+	}
+	var diff DiffOutput
+	if err := json.Unmarshal(buf.Bytes(), &diff); err != nil {
+		t.Fatal(err)
+	}
+	if diff.Schema != OutputSchema || diff.Kind != KindDiff || diff.Equal || len(diff.Differences) != 1 {
+		t.Errorf("unexpected document %s", buf.Bytes())
+	}
+	if exp, got := (Difference{Field: "tempo", From: "120", To: "98"}), diff.Differences[0]; got != exp {
+		t.Errorf("Expected %v but got %v", exp, got)
+	}
+
+	buf.Reset()
+	p.tracks[0].name = ""
+	if err := OutputJSON.Write(&buf, NewIssuesOutput("a.splice", Validate(p))); err != nil {
+		t.Fatal(err)
+	}
+	var issues IssuesOutput
+	if err := json.Unmarshal(buf.Bytes(), &issues); err != nil {
+		t.Fatal(err)
+	}
+	if !issues.Valid || len(issues.Issues) != 1 || issues.Issues[0].Severity != SeverityWarning {
+		t.Errorf("unexpected document %s", buf.Bytes())
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"severity": "warning"`)) {
+		t.Errorf("Expected the severity by name in %s", buf.Bytes())
+	}
+
+	buf.Reset()
+	if err := OutputYAML.Write(&buf, NewPatternOutput("a.splice", changed)); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "schema: 1\nkind: pattern\nfile: a.splice\npattern:\n  version: 0.808-alpha\n  tempo: 98\n"; !strings.HasPrefix(buf.String(), exp) {
+		t.Errorf("Expected prefix\n%s\nbut got\n%s", exp, buf.String())
+	}
+
+	if err := OutputFormat("xml").Write(&buf, issues); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
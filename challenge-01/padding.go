@@ -0,0 +1,81 @@
+package drum
+
+import (
+	"bytes"
+	"io"
+)
+
+// payloadPadding fills payloads up to a multiple of the block size. The zero
+// value pads nothing.
+type payloadPadding struct {
+	block int
+	fill  byte
+}
+
+// WithPayloadPadding makes the encoder fill the payload with the fill byte
+// up to a multiple of blockSize bytes, as some old hardware reads payloads
+// in fixed blocks. The declared payload size and the checksum include the
+// padding. Such files must be decoded with WithPaddedPayload. Block sizes
+// below 2 pad nothing.
+func WithPayloadPadding(blockSize int, fill byte) EncodeOption {
+	return func(o *encodeOptions) {
+		o.padding = payloadPadding{blockSize, fill}
+	}
+}
+
+// WithPaddedPayload makes the decoder strip the padding written by
+// WithPayloadPadding with the same block size and fill byte: the rest of
+// the payload after a track is dropped when it is shorter than a block and
+// holds nothing but the fill byte. A last track of only fill bytes, like an
+// unnamed track with id 0 and no steps for the fill byte 0, is taken for
+// padding.
+func WithPaddedPayload(blockSize int, fill byte) DecodeOption {
+	return func(o *decodeOptions) {
+		o.padding = payloadPadding{blockSize, fill}
+	}
+}
+
+// pad appends the padding to the payload in buf.
+func (p payloadPadding) pad(buf *bytes.Buffer) {
+	if p.block < 2 {
+		return
+	}
+	for buf.Len()%p.block != 0 {
+		buf.WriteByte(p.fill)
+	}
+}
+
+// strips reports whether the rest of the payload is padding.
+func (p payloadPadding) strips(rest []byte) bool {
+	if p.block < 2 || len(rest) >= p.block {
+		return false
+	}
+	for _, b := range rest {
+		if b != p.fill {
+			return false
+		}
+	}
+	return true
+}
+
+// peek reads the rest of the payload from r when it may be padding. It
+// returns the reader and logger to decode the remaining tracks from, which
+// replay the rest read unless it is padding.
+func (p payloadPadding) peek(r *io.LimitedReader, log *decodeLogger) (*io.LimitedReader, *decodeLogger, error) {
+	if p.block < 2 || r.N >= int64(p.block) {
+		return r, log, nil
+	}
+	offset := log.offset()
+	rest := make([]byte, r.N)
+	n, err := io.ReadFull(r, rest)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, nil, err
+	}
+	if n == len(rest) && p.strips(rest) {
+		log.field("padding", offset, n)
+		return &io.LimitedReader{}, log, nil
+	}
+	// a short payload fails in the track like without padding
+	br, log := log.replay(rest[:n], offset)
+	return &io.LimitedReader{R: br, N: int64(len(rest))}, log, nil
+}
@@ -0,0 +1,77 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestPayloadPadding(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoders := map[string]func(data []byte, opts ...DecodeOption) (*Pattern, error){
+		"Decode": func(data []byte, opts ...DecodeOption) (*Pattern, error) {
+			return Decode(bytes.NewReader(data), opts...)
+		},
+		"DecodeBytes": DecodeBytes,
+	}
+	for _, fill := range []byte{0, 0xff} {
+		for _, block := range []int{16, 256} {
+			var buf bytes.Buffer
+			if err := Encode(&buf, p, WithPayloadPadding(block, fill), WithChecksumTrailer()); err != nil {
+				t.Fatal(err)
+			}
+			raw := buf.Bytes()
+			if size := binary.BigEndian.Uint64(raw[typeHeaderLength:]); size%uint64(block) != 0 || size < 197 {
+				t.Errorf("Expected the payload padded to blocks of %d but got %d bytes", block, size)
+			}
+			for name, decode := range decoders {
+				got, err := decode(raw, WithPaddedPayload(block, fill), WithChecksum())
+				if err != nil {
+					t.Fatalf("%s: block %d fill %d: %v", name, block, fill, err)
+				}
+				if !got.Equal(p) || len(got.Tracks()) != len(p.Tracks()) {
+					t.Errorf("%s: block %d fill %d: Expected the padding stripped but got %v", name, block, fill, got)
+				}
+			}
+		}
+	}
+
+	// block sizes below 2 pad nothing
+	var buf bytes.Buffer
+	if err := Encode(&buf, p, WithPayloadPadding(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 14+197 {
+		t.Errorf("Expected block size 0 to pad nothing but got %d bytes", buf.Len())
+	}
+}
+
+func TestPayloadPaddingLogger(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw bytes.Buffer
+	if err := Encode(&raw, p, WithPayloadPadding(256, 0)); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := Decode(&raw, WithPaddedPayload(256, 0), WithLogger(testLogger(&buf))); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for _, line := range []string{
+		`level=DEBUG msg="parsed field" field="payload size" offset=6 value=256`,
+		`level=DEBUG msg="parsed field" field="track steps" offset=59 value=x---x---x---x---`,
+		`level=DEBUG msg="parsed field" field=padding offset=211 value=59`,
+	} {
+		if !contains(lines, line) {
+			t.Errorf("Expected record %s in\n%s", line, buf.String())
+		}
+	}
+}
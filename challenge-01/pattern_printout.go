@@ -1,8 +1,12 @@
 package drum
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
+	"io"
+	"sync"
+	"unicode/utf8"
 )
 
 const (
@@ -12,29 +16,245 @@ const (
 	symbolStepDisabled = '-'
 )
 
+// FormatOption configures the printout of a Formatter.
+type FormatOption func(*Formatter)
+
+// WithSymbols sets the symbols of enabled and disabled steps.
+func WithSymbols(enabled, disabled rune) FormatOption {
+	return func(f *Formatter) {
+		f.theme.Enabled, f.theme.Disabled = enabled, disabled
+	}
+}
+
+// WithTheme sets the symbols and block separator, or the Braille mode, of
+// the theme. See ThemeByName for themes by name.
+func WithTheme(t Theme) FormatOption {
+	return func(f *Formatter) {
+		f.theme = t
+	}
+}
+
+// WithBlockSize sets the number of steps between block separators instead of
+// the beats of the time signature. Values below 1 are ignored.
+func WithBlockSize(n int) FormatOption {
+	return func(f *Formatter) {
+		if n > 0 {
+			f.blockSize = n
+		}
+	}
+}
+
+// WithBeatNumbers adds a line with the beat numbers above the tracks.
+func WithBeatNumbers() FormatOption {
+	return func(f *Formatter) {
+		f.beatNumbers = true
+	}
+}
+
+// WithoutHeader leaves out the version and tempo lines.
+func WithoutHeader() FormatOption {
+	return func(f *Formatter) {
+		f.header = false
+	}
+}
+
+// Formatter writes patterns in the printout format. The zero value is not
+// usable, use NewFormatter.
+type Formatter struct {
+	theme       Theme
+	blockSize   int // 0 to separate the beats of the time signature
+	beatNumbers bool
+	header      bool
+}
+
+// NewFormatter returns a formatter of the default printout format changed by
+// the options.
+func NewFormatter(opts ...FormatOption) *Formatter {
+	f := &Formatter{theme: ClassicTheme, header: true}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// defaultFormatter is used by Pattern.String.
+var defaultFormatter = NewFormatter()
+
+// Format writes the pattern with a formatter configured by the options to w.
+func Format(w io.Writer, p *Pattern, opts ...FormatOption) error {
+	return NewFormatter(opts...).Format(w, p)
+}
+
+// Format writes the pattern to w.
+func (f *Formatter) Format(w io.Writer, p *Pattern) error {
+	_, err := f.writeTo(w, p)
+	return err
+}
+
+// printoutWriter is what the printout is written to, implemented by
+// bytes.Buffer for String and bufio.Writer for streaming.
+type printoutWriter interface {
+	io.Writer
+	WriteString(s string) (int, error)
+	WriteRune(r rune) (int, error)
+}
+
+// printoutWriters are reused by writeTo to stream printouts without
+// allocating a buffer per pattern.
+var printoutWriters = sync.Pool{
+	New: func() interface{} {
+		cw := &countingWriter{}
+		return &printoutStream{cw: cw, bw: bufio.NewWriter(cw)}
+	},
+}
+
+type printoutStream struct {
+	cw *countingWriter
+	bw *bufio.Writer
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// writeTo writes the printout of the pattern through a pooled buffered
+// writer and returns the bytes written to w.
+func (f *Formatter) writeTo(w io.Writer, p *Pattern) (int64, error) {
+	s := printoutWriters.Get().(*printoutStream)
+	defer printoutWriters.Put(s)
+	*s.cw = countingWriter{w: w}
+	s.bw.Reset(s.cw)
+	f.format(s.bw, p)
+	err := s.bw.Flush()
+	s.cw.w = nil
+	return s.cw.n, err
+}
+
+func (f *Formatter) format(w printoutWriter, p *Pattern) {
+	if f.header {
+		fmt.Fprintf(w, "Saved with HW Version: %s\n", p.version)
+		var tempo [24]byte
+		w.WriteString("Tempo: ")
+		w.Write(append(appendTempo(tempo[:0], p.tempo), '\n'))
+		if p.timeSig != (TimeSignature{}) {
+			fmt.Fprintf(w, "Time signature: %v\n", p.timeSig)
+		}
+		appendMetadata(w, p.meta)
+		if len(p.labels) > 0 {
+			w.WriteString("Labels: ")
+			appendLabels(w, p.labels)
+			w.WriteString("\n")
+		}
+		if p.barOrder != nil {
+			fmt.Fprintf(w, "Bar order: %s\n", FormatBarOrder(p.BarOrder()))
+		}
+	}
+	steps, block := p.BarSteps(), f.blockSize
+	if block == 0 {
+		block = p.timeSig.groupSteps()
+	}
+	if f.theme.Braille {
+		f.formatBraille(w, p, block)
+		return
+	}
+	if f.beatNumbers {
+		f.appendBeatNumbers(w, steps, block, p.timeSig.groupSteps())
+	}
+	for _, t := range p.tracks {
+		appendTrackLabel(w, t)
+		w.WriteRune('\t')
+		if p.Bars() == 1 {
+			f.appendSteps(w, t.steps[:p.trackLength(t)], block)
+			w.WriteString("\n")
+			continue
+		}
+		// the bars are stacked, each labelled with its letter
+		for bar := 0; bar < p.Bars(); bar++ {
+			if bar > 0 {
+				w.WriteRune('\t')
+			}
+			w.WriteString(barName(bar))
+			f.appendSteps(w, t.barSteps(bar)[:p.trackLength(t)], block)
+			w.WriteString("\n")
+		}
+	}
+}
+
 // String returns the Pattern in the printout format as a string.
 func (p Pattern) String() string {
 	w := new(bytes.Buffer)
-	fmt.Fprintf(w, "Saved with HW Version: %s\n", p.version)
-	fmt.Fprintf(w, "Tempo: %v\n", p.tempo)
-	for _, t := range p.tracks {
-		fmt.Fprintf(w, "(%v) %v\t", t.id, t.name)
-		appendSteps(w, t.steps)
-		w.WriteString("\n")
-	}
+	defaultFormatter.format(w, &p)
 	return w.String()
 }
 
-func appendSteps(w *bytes.Buffer, s Steps) {
+// WriteTo implements io.WriterTo writing the pattern in the printout format,
+// the same text String returns, to w without building it in memory first.
+func (p *Pattern) WriteTo(w io.Writer) (int64, error) {
+	return defaultFormatter.writeTo(w, p)
+}
+
+// appendTrackLabel writes the id and name of the track with its mute or
+// solo state.
+func appendTrackLabel(w printoutWriter, t *Track) {
+	fmt.Fprintf(w, "(%v) %v", t.id, t.name)
+	switch {
+	case t.muted:
+		w.WriteString(" [muted]")
+	case t.solo:
+		w.WriteString(" [solo]")
+	}
+}
+
+// appendSteps writes the steps with a separator every block steps.
+func (f *Formatter) appendSteps(w printoutWriter, s []bool, block int) {
+	sep := f.theme.Separator
 	for i, enabled := range s {
-		if i%blockSize == 0 {
-			w.WriteRune(blockSeparator)
+		if sep != 0 && i%block == 0 {
+			w.WriteRune(sep)
 		}
 		if enabled {
-			w.WriteRune(symbolStepEnabled)
+			w.WriteRune(f.theme.Enabled)
 		} else {
-			w.WriteRune(symbolStepDisabled)
+			w.WriteRune(f.theme.Disabled)
 		}
 	}
-	w.WriteRune(blockSeparator)
+	if sep != 0 {
+		w.WriteRune(sep)
+	}
+}
+
+// appendBeatNumbers writes the number of every beat of beat steps above its
+// first step. Numbers of more than one digit continue over the following
+// steps.
+func (f *Formatter) appendBeatNumbers(w printoutWriter, steps, block, beat int) {
+	w.WriteRune('\t')
+	sep := f.theme.Separator
+	pending := ""
+	for i := 0; i < steps; i++ {
+		if sep != 0 && i%block == 0 {
+			w.WriteRune(sep)
+		}
+		if i%beat == 0 {
+			pending = fmt.Sprint(i/beat + 1)
+		}
+		if pending == "" {
+			w.WriteRune(' ')
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(pending)
+		w.WriteRune(r)
+		pending = pending[n:]
+	}
+	if sep != 0 {
+		w.WriteRune(sep)
+	}
+	w.WriteString("\n")
 }
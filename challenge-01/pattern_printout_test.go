@@ -0,0 +1,112 @@
+package drum
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_5.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		opts []FormatOption
+		exp  string
+	}{
+		{nil, p.String()},
+		{[]FormatOption{WithoutHeader(), WithSymbols('█', '·'), WithBlockSize(8)},
+			"(1) Kick\t|█·······|█·······|\n(2) HiHat\t|█·█·█·█·|█·█·█·█·|\n"},
+		{[]FormatOption{WithoutHeader(), WithBeatNumbers()},
+			"\t|1   |2   |3   |4   |\n(1) Kick\t|x---|----|x---|----|\n(2) HiHat\t|x-x-|x-x-|x-x-|x-x-|\n"},
+		{[]FormatOption{WithoutHeader(), WithTheme(DotsTheme), WithBlockSize(8)},
+			"(1) Kick\t ●○○○○○○○ ●○○○○○○○ \n(2) HiHat\t ●○●○●○●○ ●○●○●○●○ \n"},
+		{[]FormatOption{WithoutHeader(), WithTheme(BrailleTheme)},
+			"(1) Kick / (2) HiHat\t⡇⡄⡄⡄⡇⡄⡄⡄\n"},
+		{[]FormatOption{WithoutHeader(), WithTheme(Theme{Separator: '|', Braille: true})},
+			"(1) Kick / (2) HiHat\t|⡇⡄|⡄⡄|⡇⡄|⡄⡄|\n"},
+	}
+	for _, testCase := range testCases {
+		var buf bytes.Buffer
+		if err := Format(&buf, p, testCase.opts...); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != testCase.exp {
+			t.Errorf("Expected '%v' but got '%v'", testCase.exp, got)
+		}
+	}
+}
+
+func TestThemeByName(t *testing.T) {
+	RegisterTheme("Stars", Theme{Enabled: '*', Disabled: '.'})
+	defer func() {
+		themesMu.Lock()
+		delete(themes, "stars")
+		themesMu.Unlock()
+	}()
+	if got, ok := ThemeByName("BRAILLE"); !ok || !got.Braille {
+		t.Errorf("Expected the Braille theme but got %v", got)
+	}
+	if got, ok := ThemeByName("stars"); !ok || got.Enabled != '*' {
+		t.Errorf("Expected the registered theme but got %v", got)
+	}
+	if exp, got := "[blocks braille classic dots stars]", fmt.Sprint(ThemeNames()); got != exp {
+		t.Errorf("Expected %v but got %v", exp, got)
+	}
+	p := &Pattern{tracks: []*Track{{id: 1, name: "kick", steps: Steps{true}}, {id: 2, name: "snare", steps: Steps{false, true}, muted: true}, {id: 3, name: "hat", length: 3, steps: Steps{true, true, true, true}}}}
+	var buf bytes.Buffer
+	if err := Format(&buf, p, WithoutHeader(), WithTheme(BrailleTheme)); err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := "(1) kick / (2) snare [muted]\t⢣⠀⠀⠀⠀⠀⠀⠀\n(3) hat\t⠛⠃\n", buf.String(); got != exp {
+		t.Errorf("Expected '%v' but got '%v'", exp, got)
+	}
+}
+
+func TestPatternWriteTo(t *testing.T) {
+	for _, name := range []string{"pattern_1.splice", "pattern_5.splice"} {
+		p, err := DecodeFile(path.Join("fixtures", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		n, err := p.WriteTo(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, exp := buf.String(), p.String(); got != exp {
+			t.Errorf("%s: Expected '%v' but got '%v'", name, exp, got)
+		}
+		if n != int64(buf.Len()) {
+			t.Errorf("%s: Expected %d bytes written but got %d", name, buf.Len(), n)
+		}
+	}
+}
+
+// BenchmarkExportPrintout writes the printouts of 10000 patterns.
+func BenchmarkExportPrintout(b *testing.B) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("String", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < 10000; j++ {
+				io.WriteString(ioutil.Discard, p.String())
+			}
+		}
+	})
+	b.Run("WriteTo", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < 10000; j++ {
+				p.WriteTo(ioutil.Discard)
+			}
+		}
+	})
+}
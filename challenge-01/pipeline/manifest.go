@@ -0,0 +1,182 @@
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+	"github.com/alpe/go-challenge/challenge-01/internal/yaml"
+)
+
+// manifest is the YAML document of a pipeline.
+type manifest struct {
+	Source struct {
+		Dir  string `json:"dir"`
+		Bank string `json:"bank"`
+	} `json:"source"`
+	Defaults   int               `json:"defaults"` // version, see drum.DefaultsFor
+	Workers    int               `json:"workers"`
+	Transforms []json.RawMessage `json:"transforms"`
+	Sinks      []sinkManifest    `json:"sinks"`
+}
+
+// sinkManifest configures a sink, exactly one of the directories is set.
+type sinkManifest struct {
+	Splice string `json:"splice"`
+	MIDI   string `json:"midi"`
+	JSON   string `json:"json"`
+	Bars   int    `json:"bars"` // of midi, 1 when not set
+}
+
+// transforms are the transforms of manifests by name. Transforms with a
+// value are written as mapping from the name to the value, the others as
+// name only.
+var transforms = map[string]func(value json.RawMessage) (Transform, error){
+	"validate": func(value json.RawMessage) (Transform, error) {
+		return Validate(), nil
+	},
+	"normalizeIDs": func(value json.RawMessage) (Transform, error) {
+		return NormalizeIDs(), nil
+	},
+	"retempo": func(value json.RawMessage) (Transform, error) {
+		var bpm float32
+		if err := json.Unmarshal(value, &bpm); err != nil || value == nil {
+			return nil, fmt.Errorf("retempo needs the tempo in bpm")
+		}
+		return Retempo(bpm), nil
+	},
+	"script": func(value json.RawMessage) (Transform, error) {
+		var src string
+		if err := json.Unmarshal(value, &src); err != nil || value == nil {
+			return nil, fmt.Errorf("script needs the source of the script")
+		}
+		s, err := drum.ParseScript(src)
+		if err != nil {
+			return nil, err
+		}
+		return Script(s), nil
+	},
+}
+
+// LoadManifest returns the pipeline of the YAML manifest at path. Paths in the
+// manifest are relative to its directory.
+func LoadManifest(path string) (*Pipeline, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pl, err := ParseManifest(bytes.NewReader(data), filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return pl, nil
+}
+
+// ParseManifest returns the pipeline of a YAML manifest with the source, the
+// transforms and the sinks, see the package documentation. The source is a
+// dir or a bank, the transforms validate, normalizeIDs, retempo and script, the
+// sinks splice, midi and json directories. The source decodes and the splice
+// sinks encode with the defaults of the version given as defaults, 1 when
+// not set, see drum.DefaultsFor. Relative paths are resolved against base.
+// Unknown keys are rejected to catch typos.
+func ParseManifest(r io.Reader, base string) (*Pipeline, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = yaml.ToJSON(data); err != nil {
+		return nil, fmt.Errorf("parse manifest: %v", err)
+	}
+	var m manifest
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("parse manifest: %v", err)
+	}
+	resolve := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(base, path)
+	}
+	config := drum.V1Defaults()
+	if m.Defaults != 0 {
+		if config, err = drum.DefaultsFor(m.Defaults); err != nil {
+			return nil, err
+		}
+	}
+	pl := &Pipeline{Workers: m.Workers}
+	switch {
+	case m.Source.Dir != "" && m.Source.Bank == "":
+		pl.Source = Dir(resolve(m.Source.Dir), config.DecodeOptions...)
+	case m.Source.Bank != "" && m.Source.Dir == "":
+		pl.Source = Bank(resolve(m.Source.Bank), config.DecodeOptions...)
+	default:
+		return nil, fmt.Errorf("source needs either a dir or a bank")
+	}
+	for i, raw := range m.Transforms {
+		t, err := parseTransform(raw)
+		if err != nil {
+			return nil, fmt.Errorf("transform %d: %v", i+1, err)
+		}
+		pl.Transforms = append(pl.Transforms, t)
+	}
+	if len(m.Sinks) == 0 {
+		return nil, fmt.Errorf("no sinks")
+	}
+	for i, sm := range m.Sinks {
+		s, err := sm.sink(resolve, config)
+		if err != nil {
+			return nil, fmt.Errorf("sink %d: %v", i+1, err)
+		}
+		pl.Sinks = append(pl.Sinks, s)
+	}
+	return pl, nil
+}
+
+// parseTransform returns the transform of a name or of a mapping from the
+// name to its value.
+func parseTransform(raw json.RawMessage) (Transform, error) {
+	var name string
+	var value json.RawMessage
+	if err := json.Unmarshal(raw, &name); err != nil {
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &m); err != nil || len(m) != 1 {
+			return nil, fmt.Errorf("expected a name or a name with a value but got %s", raw)
+		}
+		for name, value = range m {
+		}
+	}
+	newTransform, ok := transforms[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown transform %q", name)
+	}
+	return newTransform(value)
+}
+
+func (sm sinkManifest) sink(resolve func(string) string, config drum.Config) (Sink, error) {
+	var sinks []Sink
+	if sm.Splice != "" {
+		sinks = append(sinks, SpliceDir(resolve(sm.Splice), config.EncodeFileOptions()...))
+	}
+	if sm.MIDI != "" {
+		bars := sm.Bars
+		if bars == 0 {
+			bars = 1
+		}
+		sinks = append(sinks, MIDIDir(resolve(sm.MIDI), bars))
+	} else if sm.Bars != 0 {
+		return nil, fmt.Errorf("bars are only used by midi")
+	}
+	if sm.JSON != "" {
+		sinks = append(sinks, JSONDir(resolve(sm.JSON)))
+	}
+	if len(sinks) != 1 {
+		return nil, fmt.Errorf("expected one of splice, midi or json")
+	}
+	return sinks[0], nil
+}
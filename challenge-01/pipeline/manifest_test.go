@@ -0,0 +1,119 @@
+package pipeline
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+func TestLoadManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestPatterns(t, filepath.Join(dir, "patterns"))
+	doc := `# the pack release
+source:
+  dir: patterns
+workers: 2
+transforms:
+- validate
+- normalizeIDs
+- retempo: 90
+- script: "if track.index == 0: pattern.swing = 25"
+sinks:
+- splice: out
+- midi: out/midi
+  bars: 4
+- json: out/json
+`
+	path := filepath.Join(dir, "pipeline.yaml")
+	if err := ioutil.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pl, err := LoadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range pl.Sinks {
+		names = append(names, s.Name())
+	}
+	for _, tr := range pl.Transforms {
+		names = append(names, tr.Name())
+	}
+	if exp := "splice midi json validate normalizeIDs retempo script"; strings.Join(names, " ") != exp || pl.Workers != 2 {
+		t.Errorf("Expected the stages %s but got %v", exp, names)
+	}
+	report, err := pl.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Processed != 3 {
+		t.Errorf("Expected 3 patterns to be processed but got %v", report)
+	}
+	p, err := drum.DecodeFile(filepath.Join(dir, "out", "sub", "pattern_3.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Tempo() != 90 || p.Swing() != 25 {
+		t.Errorf("Expected the new tempo and swing but got %v and %v", p.Tempo(), p.Swing())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out", "midi", "pattern_1.mid")); err != nil {
+		t.Error(err)
+	}
+
+	for doc, exp := range map[string]string{
+		"source: {dir: a, bank: b}\nsinks: [{splice: out}]\n":                   "source needs either a dir or a bank",
+		"source: {dir: a}\nsinks: []\n":                                         "no sinks",
+		"source: {dir: a}\ntransforms: [reverse]\nsinks: [{json: o}]\n":         `transform 1: unknown transform "reverse"`,
+		"source: {dir: a}\ntransforms: [retempo]\nsinks: [{json: o}]\n":         "transform 1: retempo needs the tempo in bpm",
+		"source: {dir: a}\ntransforms: [{script: 'x ='}]\nsinks: [{json: o}]\n": "transform 1: invalid script: line 1",
+		"source: {dir: a}\ntransforms: [{a: 1, b: 2}]\nsinks: [{json: o}]\n":    "transform 1: expected a name or a name with a value",
+		"source: {dir: a}\nsinks: [{json: o, midi: m}]\n":                       "sink 1: expected one of splice, midi or json",
+		"source: {dir: a}\nsinks: [{json: o, bars: 2}]\n":                       "sink 1: bars are only used by midi",
+		"source: {dir: a}\nsink: [{json: o}]\n":                                 `parse manifest: json: unknown field "sink"`,
+		"source: [\n":                                                           "parse manifest: ",
+		"defaults: 3\nsource: {dir: a}\nsinks: [{json: o}]\n":                   "unknown defaults 3",
+	} {
+		if _, err := ParseManifest(strings.NewReader(doc), dir); err == nil || !strings.Contains(err.Error(), exp) {
+			t.Errorf("%q: Expected an error with '%v' but got %v", doc, exp, err)
+		}
+	}
+}
+
+func TestManifestDefaults(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestPatterns(t, filepath.Join(dir, "patterns"))
+	for _, doc := range []string{
+		"source: {dir: patterns}\nsinks: [{splice: v1}]\n",
+		"defaults: 2\nsource: {dir: patterns}\nsinks: [{splice: v2}]\n",
+	} {
+		pl, err := ParseManifest(strings.NewReader(doc), dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the second run replaces the files of the first one
+		for i := 0; i < 2; i++ {
+			if _, err := pl.Run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "v1", "pattern_1.splice.bak")); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup with the version 1 defaults but got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "v2", "pattern_1.splice.bak")); err != nil {
+		t.Errorf("Expected a backup with the version 2 defaults but got %v", err)
+	}
+}
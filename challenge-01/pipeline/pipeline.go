@@ -0,0 +1,395 @@
+// Package pipeline converts collections of drum patterns in batch.
+//
+// A Pipeline reads the patterns of a Source, like a directory of .splice
+// files or a bank, passes each through its Transforms, like validation or a
+// new tempo, and writes the results to all its Sinks, like directories of
+// .splice, MIDI or JSON files. Patterns are processed in parallel. A pattern
+// failing in any stage is skipped and recorded in the Report, the others are
+// processed anyway.
+//
+// Pipelines are built in code or loaded from a YAML manifest, see
+// LoadManifest:
+//
+//	defaults: 2
+//	source:
+//	  dir: patterns
+//	transforms:
+//	- validate
+//	- normalizeIDs
+//	- retempo: 120
+//	sinks:
+//	- splice: out/splice
+//	- midi: out/midi
+//	  bars: 4
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+const spliceFileExt = ".splice"
+
+// Entry is a pattern of a source, loaded by the worker processing it.
+type Entry struct {
+	// Name identifies the pattern within the source, sinks write it under
+	// this name. It is slash separated and has no file extension.
+	Name string
+	Load func() (*drum.Pattern, error)
+}
+
+// Source lists the patterns to process. Sources implementing io.Closer are
+// closed when the pipeline finished.
+type Source interface {
+	Entries() ([]Entry, error)
+}
+
+// Transform changes or checks a pattern. Transforms are called by many
+// workers at the same time, with another pattern each.
+type Transform interface {
+	Name() string
+	Apply(p *drum.Pattern) (*drum.Pattern, error)
+}
+
+// Sink writes the results. Sinks are called by many workers at the same time
+// and implementing io.Closer are closed when the pipeline finished.
+type Sink interface {
+	Name() string
+	Write(name string, p *drum.Pattern) error
+}
+
+// Pipeline processes all patterns of a source, see Run.
+type Pipeline struct {
+	Source     Source
+	Transforms []Transform
+	Sinks      []Sink
+	// Workers is the number of patterns processed at the same time,
+	// runtime.NumCPU when not positive.
+	Workers int
+}
+
+// Failure is a pattern that could not be processed.
+type Failure struct {
+	Name  string // of the entry
+	Stage string // "load", the name of the transform or of the sink
+	Err   error
+}
+
+func (f Failure) Error() string {
+	return fmt.Sprintf("%s: %s: %v", f.Name, f.Stage, f.Err)
+}
+
+// Report is the result of a run.
+type Report struct {
+	Entries   int       // of the source
+	Processed int       // entries written to all sinks
+	Failures  []Failure // sorted by entry name
+}
+
+// Err returns an error listing the failures, nil when there are none.
+func (r *Report) Err() error {
+	if len(r.Failures) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(r.Failures))
+	for i, f := range r.Failures {
+		msgs[i] = f.Error()
+	}
+	return fmt.Errorf("%d of %d patterns failed:\n%s", len(r.Failures), r.Entries, strings.Join(msgs, "\n"))
+}
+
+// String returns a summary of the run followed by the failures.
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %d patterns processed, %d failed\n", r.Processed, r.Entries, len(r.Failures))
+	for _, f := range r.Failures {
+		fmt.Fprintln(&b, f.Error())
+	}
+	return b.String()
+}
+
+// Run processes all entries of the source. Failures of single patterns are
+// recorded in the report, an error is only returned when the source can not
+// be listed, a source or sink fails to close or the context is done before
+// all entries are processed. The report is returned in all cases but the
+// first.
+func (pl *Pipeline) Run(ctx context.Context) (*Report, error) {
+	if pl.Source == nil {
+		return nil, fmt.Errorf("pipeline: no source")
+	}
+	entries, err := pl.Source.Entries()
+	if err != nil {
+		closeStage(pl.Source)
+		return nil, fmt.Errorf("pipeline: list source: %v", err)
+	}
+	workers := pl.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	report := &Report{Entries: len(entries)}
+	var mu sync.Mutex // guards report
+	next := make(chan Entry)
+	var wg sync.WaitGroup
+	for i := 0; i < min(workers, len(entries)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range next {
+				f := pl.process(e)
+				mu.Lock()
+				if f != nil {
+					report.Failures = append(report.Failures, *f)
+				} else {
+					report.Processed++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	err = nil // of the context or closing from here on
+feed:
+	for _, e := range entries {
+		select {
+		case next <- e:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+	sort.Slice(report.Failures, func(i, j int) bool {
+		return report.Failures[i].Name < report.Failures[j].Name
+	})
+	for _, s := range pl.Sinks {
+		if cerr := closeStage(s); cerr != nil && err == nil {
+			err = fmt.Errorf("pipeline: close %s: %v", s.Name(), cerr)
+		}
+	}
+	if cerr := closeStage(pl.Source); cerr != nil && err == nil {
+		err = fmt.Errorf("pipeline: close source: %v", cerr)
+	}
+	return report, err
+}
+
+// process runs a single entry through all stages and returns the failure,
+// nil on success.
+func (pl *Pipeline) process(e Entry) *Failure {
+	p, err := e.Load()
+	if err != nil {
+		return &Failure{e.Name, "load", err}
+	}
+	for _, t := range pl.Transforms {
+		if p, err = t.Apply(p); err != nil {
+			return &Failure{e.Name, t.Name(), err}
+		}
+	}
+	for _, s := range pl.Sinks {
+		if err := s.Write(e.Name, p); err != nil {
+			return &Failure{e.Name, s.Name(), err}
+		}
+	}
+	return nil
+}
+
+func closeStage(stage interface{}) error {
+	if c, ok := stage.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// entryName returns the name of an entry for the slash separated path.
+func entryName(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
+
+type dirSource struct {
+	dir  string
+	opts []drum.DecodeOption
+}
+
+// Dir returns a source of all .splice files within the directory tree,
+// decoded with the options, like the DecodeOptions of a drum.Config.
+// Entries are named by their path relative to dir.
+func Dir(dir string, opts ...drum.DecodeOption) Source {
+	return dirSource{dir, opts}
+}
+
+func (d dirSource) Entries() ([]Entry, error) {
+	var entries []Entry
+	err := filepath.Walk(d.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.EqualFold(filepath.Ext(path), spliceFileExt) {
+			return nil
+		}
+		rel, err := filepath.Rel(d.dir, path)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, Entry{
+			Name: entryName(filepath.ToSlash(rel)),
+			Load: func() (*drum.Pattern, error) { return drum.DecodeFile(path, d.opts...) },
+		})
+		return nil
+	})
+	return entries, err
+}
+
+// bankSource opens the bank when listed.
+type bankSource struct {
+	path string
+	opts []drum.DecodeOption
+	bank *drum.Bank
+}
+
+// Bank returns a source of all patterns of the bank at path, decoded with
+// the options. Entries are named by their file name within the archive.
+func Bank(path string, opts ...drum.DecodeOption) Source {
+	return &bankSource{path: path, opts: opts}
+}
+
+func (s *bankSource) Entries() ([]Entry, error) {
+	b, err := drum.OpenBank(s.path)
+	if err != nil {
+		return nil, err
+	}
+	s.bank = b
+	entries := make([]Entry, b.Len())
+	for i := range entries {
+		// every entry is loaded by one worker only, so the bank is not
+		// used concurrently for the same entry
+		e := b.Entry(i)
+		entries[i] = Entry{Name: entryName(e.Name()), Load: func() (*drum.Pattern, error) {
+			return e.Pattern(s.opts...)
+		}}
+	}
+	return entries, nil
+}
+
+func (s *bankSource) Close() error {
+	if s.bank == nil {
+		return nil
+	}
+	err := s.bank.Close()
+	s.bank = nil
+	return err
+}
+
+type transformFunc struct {
+	name string
+	fn   func(p *drum.Pattern) (*drum.Pattern, error)
+}
+
+// TransformFunc returns a transform calling fn.
+func TransformFunc(name string, fn func(p *drum.Pattern) (*drum.Pattern, error)) Transform {
+	return transformFunc{name, fn}
+}
+
+func (t transformFunc) Name() string {
+	return t.name
+}
+
+func (t transformFunc) Apply(p *drum.Pattern) (*drum.Pattern, error) {
+	return t.fn(p)
+}
+
+// Validate returns a transform failing for patterns with issues of
+// drum.SeverityError found by drum.Validate. Warnings are ignored.
+func Validate() Transform {
+	return TransformFunc("validate", func(p *drum.Pattern) (*drum.Pattern, error) {
+		var msgs []string
+		for _, i := range drum.Validate(p) {
+			if i.Severity == drum.SeverityError {
+				msgs = append(msgs, i.String())
+			}
+		}
+		if msgs != nil {
+			return nil, errors.New(strings.Join(msgs, "; "))
+		}
+		return p, nil
+	})
+}
+
+// NormalizeIDs returns a transform numbering the tracks like
+// drum.NormalizeIDs.
+func NormalizeIDs() Transform {
+	return TransformFunc("normalizeIDs", func(p *drum.Pattern) (*drum.Pattern, error) {
+		drum.NormalizeIDs(p)
+		return p, nil
+	})
+}
+
+// Retempo returns a transform setting the tempo to bpm.
+func Retempo(bpm float32) Transform {
+	return TransformFunc("retempo", func(p *drum.Pattern) (*drum.Pattern, error) {
+		return p.WithTempo(bpm)
+	})
+}
+
+// Script returns a transform applying the script, see drum.ParseScript.
+func Script(s *drum.Script) Transform {
+	return TransformFunc("script", s.Apply)
+}
+
+// dirSink writes every pattern to a file below dir.
+type dirSink struct {
+	name  string
+	dir   string
+	ext   string
+	write func(path string, p *drum.Pattern) error
+}
+
+func (s dirSink) Name() string {
+	return s.name
+}
+
+func (s dirSink) Write(name string, p *drum.Pattern) error {
+	path := filepath.Join(s.dir, filepath.FromSlash(name)+s.ext)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return s.write(path, p)
+}
+
+// SpliceDir returns a sink writing .splice files below dir, named like the
+// entries, encoded with the options, see drum.Config.EncodeFileOptions.
+func SpliceDir(dir string, opts ...drum.EncodeOption) Sink {
+	return dirSink{"splice", dir, spliceFileExt, func(path string, p *drum.Pattern) error {
+		return drum.EncodeFile(path, p, opts...)
+	}}
+}
+
+// MIDIDir returns a sink writing Standard MIDI Files of the given number of
+// bars below dir, see drum.WriteMIDI.
+func MIDIDir(dir string, bars int) Sink {
+	return dirSink{"midi", dir, ".mid", func(path string, p *drum.Pattern) error {
+		return drum.WriteMIDIFile(path, p, bars)
+	}}
+}
+
+// JSONDir returns a sink writing the JSON representation below dir, see
+// drum.Pattern.MarshalJSON.
+func JSONDir(dir string) Sink {
+	return dirSink{"json", dir, ".json", func(path string, p *drum.Pattern) error {
+		data, err := json.MarshalIndent(p, "", "  ")
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(path, append(data, '\n'), 0644)
+	}}
+}
@@ -0,0 +1,150 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// writeTestPatterns copies the fixtures to dir, pattern_3 into a sub
+// directory, and adds a broken file.
+func writeTestPatterns(t *testing.T, dir string) {
+	for _, name := range []string{"pattern_1.splice", "pattern_2.splice", "sub/pattern_3.splice"} {
+		data, err := ioutil.ReadFile(filepath.Join("..", "fixtures", filepath.Base(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "broken.splice"), []byte("SPLICE"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "README"), []byte("not a pattern"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	in := filepath.Join(dir, "in")
+	writeTestPatterns(t, in)
+	out := filepath.Join(dir, "out")
+	pl := &Pipeline{
+		Source: Dir(in),
+		Transforms: []Transform{
+			Validate(),
+			NormalizeIDs(),
+			Retempo(100),
+			TransformFunc("reject", func(p *drum.Pattern) (*drum.Pattern, error) {
+				if len(p.Tracks()) < 5 {
+					return nil, os.ErrInvalid
+				}
+				return p, nil
+			}),
+		},
+		Sinks:   []Sink{SpliceDir(filepath.Join(out, "splice")), MIDIDir(filepath.Join(out, "midi"), 2), JSONDir(filepath.Join(out, "json"))},
+		Workers: 2,
+	}
+	report, err := pl.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Entries != 4 || report.Processed != 2 || len(report.Failures) != 2 {
+		t.Fatalf("Expected 2 of 4 patterns to be processed but got %v", report)
+	}
+	for i, exp := range []Failure{{"broken", "load", nil}, {"pattern_2", "reject", os.ErrInvalid}} {
+		if f := report.Failures[i]; f.Name != exp.Name || f.Stage != exp.Stage || exp.Err != nil && f.Err != exp.Err {
+			t.Errorf("Expected failure %v but got %v", exp, f)
+		}
+	}
+	if err := report.Err(); err == nil || !strings.Contains(err.Error(), "2 of 4 patterns failed:\nbroken: load: ") {
+		t.Errorf("Unexpected error %v", err)
+	}
+	for _, name := range []string{"pattern_1", "pattern_2", "sub/pattern_3"} {
+		p, err := drum.DecodeFile(filepath.Join(out, "splice", filepath.FromSlash(name)+".splice"))
+		if name == "pattern_2" {
+			if err == nil {
+				t.Errorf("%s: Expected no output of the failed pattern", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Tempo() != 100 || p.Tracks()[0].ID() > uint32(len(p.Tracks())) {
+			t.Errorf("%s: Expected the tempo and the ids changed but got:\n%v", name, p)
+		}
+		if _, err := os.Stat(filepath.Join(out, "midi", filepath.FromSlash(name)+".mid")); err != nil {
+			t.Error(err)
+		}
+		data, err := ioutil.ReadFile(filepath.Join(out, "json", filepath.FromSlash(name)+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var jp drum.Pattern
+		if err := json.Unmarshal(data, &jp); err != nil || !jp.Equal(p) {
+			t.Errorf("%s: Expected the JSON of the pattern but got %v", name, err)
+		}
+	}
+}
+
+func TestPipelineBank(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var bank drum.Bank
+	for _, name := range []string{"pattern_1.splice", "pattern_2.splice"} {
+		p, err := drum.DecodeFile(filepath.Join("..", "fixtures", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bank.Add("backup/"+name, p)
+	}
+	bankPath := filepath.Join(dir, "backup.zip")
+	if err := bank.Save(bankPath); err != nil {
+		t.Fatal(err)
+	}
+	pl := &Pipeline{Source: Bank(bankPath), Sinks: []Sink{SpliceDir(dir)}}
+	report, err := pl.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Processed != 2 || report.Err() != nil {
+		t.Fatalf("Expected 2 patterns to be processed but got %v", report)
+	}
+	if _, err := drum.DecodeFile(filepath.Join(dir, "backup", "pattern_2.splice")); err != nil {
+		t.Error(err)
+	}
+	if exp := "2 of 2 patterns processed, 0 failed\n"; report.String() != exp {
+		t.Errorf("Expected '%v' but got '%v'", exp, report)
+	}
+
+	pl.Source = Bank(filepath.Join(dir, "missing.zip"))
+	if _, err := pl.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "list source") {
+		t.Errorf("Expected the source to fail but got %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pl.Source = Bank(bankPath)
+	if _, err := pl.Run(ctx); err != context.Canceled {
+		t.Errorf("Expected %v but got %v", context.Canceled, err)
+	}
+}
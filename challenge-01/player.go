@@ -0,0 +1,464 @@
+package drum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNoPattern is returned when the player has no pattern to play.
+var ErrNoPattern = errors.New("no pattern")
+
+// errEndOfSong stops playing when the last section of a song was played.
+var errEndOfSong = errors.New("end of song")
+
+// StepEvent is emitted by the Player for every step played.
+// Velocities and Offsets hold the values for the track at the same index.
+type StepEvent struct {
+	Step       int             // position within the pattern starting at 0
+	Section    int             // index of the song section, 0 without song
+	Time       time.Time       // time the step is scheduled for
+	Tracks     []*Track        // tracks triggered at this step
+	Velocities []uint8         // velocity per triggered track
+	Offsets    []time.Duration // micro timing offset per triggered track
+	Samples    []*Sample       // sample per triggered track, see SetKit
+	// Choked are the tracks to stop when still ringing as they share a choke
+	// group with a triggered track, see Track.SetChokeGroup.
+	Choked []*Track
+	// CountIn is set for the steps counted in before playing, see
+	// WithCountIn.
+	CountIn bool
+}
+
+// Player schedules the steps of a pattern in real time and hands them to a
+// handler function, for example to trigger samples or send MIDI notes.
+// All methods are thread safe and may be called while playing.
+type Player struct {
+	mu       sync.Mutex
+	pattern  *Pattern
+	synced   *SyncedPattern // followed pattern, see WithSyncedPattern
+	next     *Pattern       // pattern played from the next bar, see QueuePattern
+	scene    *Scene         // scene played from the next bar, see LaunchScene
+	position int            // next step to play
+	cycle    int            // steps played modulo Pattern.Cycle
+	loop     int            // bars played of the pattern, see Condition
+	fill     bool           // see SetFill
+	rng      *rand.Rand     // rolls the step probabilities, see WithRand
+	mutes    map[int]bool   // muted track indexes
+	tempo    float32        // tempo override, 0 for the pattern tempo
+	handler  func(StepEvent)
+	kit      *Kit
+	midiOut  io.Writer // real time MIDI output, see WithMIDIOut
+	sync     Sync      // timeline followed, see WithSync
+	notesOn  []byte    // notes sent to midiOut still on
+
+	song    *Song // song played, nil for a single pattern
+	section int   // current section of the song
+	repeat  int   // bars played of the current section
+
+	fills  *FillGenerator // of the song bars, see WithFills
+	filled bool           // the bar played last was a fill
+
+	loader   PatternLoader // of the section patterns, see WithPrefetch
+	depth    int           // sections loaded ahead
+	prefetch *prefetcher   // loading the sections ahead while playing
+
+	playing bool          // Play is running
+	current Transport     // of the step sounding, see Transport
+	unswung time.Duration // length of the step sounding without swing
+	late    bool          // the step sounding starts late by the swing
+
+	clockBase  time.Time // start of the grid step of the next MIDI clock pulse
+	clockPulse int       // of the next MIDI clock pulse within its grid step
+
+	region    *loopRegion // steps looped, see SetLoopRegion
+	countIn   int         // bars counted in by Play, see WithCountIn
+	countdown int         // steps left to count in
+	click     *Track      // metronome of the count in
+}
+
+// NewPlayer returns a player for the pattern that calls handler for every
+// step. The handler is called from the playing goroutine and should return
+// quickly to keep the timing.
+func NewPlayer(p *Pattern, handler func(StepEvent), opts ...PlayerOption) *Player {
+	pl := &Player{pattern: p, mutes: make(map[int]bool), handler: handler}
+	for _, opt := range opts {
+		opt(pl)
+	}
+	return pl
+}
+
+// PlayerOption configures a Player.
+type PlayerOption func(*Player)
+
+// WithRand makes the player roll the step probabilities with rng instead of
+// the global source, for example to play reproducibly with a fixed seed.
+func WithRand(rng *rand.Rand) PlayerOption {
+	return func(pl *Player) {
+		pl.rng = rng
+	}
+}
+
+// WithFills makes the player of a song play the fills of g: every bar
+// plays the variation of the section pattern returned by
+// FillGenerator.Variation. Single patterns play without fills.
+func WithFills(g *FillGenerator) PlayerOption {
+	return func(pl *Player) {
+		pl.fills = g
+	}
+}
+
+// NewSongPlayer returns a player for the song, see NewPlayer. The song is
+// played once from the first section.
+func NewSongPlayer(s *Song, handler func(StepEvent), opts ...PlayerOption) *Player {
+	pl := NewPlayer(nil, handler, opts...)
+	pl.SetSong(s)
+	return pl
+}
+
+// SetSong replaces the pattern or song played and rewinds to the start of
+// the song.
+func (pl *Player) SetSong(s *Song) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.song, pl.section, pl.repeat, pl.position, pl.loop, pl.cycle = s, 0, 0, 0, 0, 0
+	pl.filled = false
+	pl.pattern, pl.synced, pl.next, pl.scene = nil, nil, nil, nil
+	if len(s.Sections) > 0 {
+		pl.pattern = pl.sectionPattern(0)
+	}
+}
+
+// Play plays the pattern in a loop until stop is closed. A song is played
+// until its end. Playback continues from the current position.
+func (pl *Player) Play(stop <-chan struct{}) (err error) {
+	pl.setPlaying(true)
+	defer pl.setPlaying(false)
+	defer func() {
+		pl.mu.Lock()
+		pl.stopPrefetch()
+		pl.mu.Unlock()
+	}()
+	if ok, err := pl.awaitSection(stop); !ok || err != nil {
+		return err
+	}
+	pl.startCountIn()
+	if pl.midiOut != nil {
+		if err := pl.midiStart(); err != nil {
+			return err
+		}
+		defer func() {
+			if e := pl.midiStop(); err == nil {
+				err = e
+			}
+		}()
+	}
+	next := time.Now()
+	for {
+		if ok, err := pl.awaitSection(stop); !ok || err != nil {
+			return err
+		}
+		ev, d, err := pl.advance(next)
+		if err == errEndOfSong {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if pl.handler != nil {
+			pl.handler(ev)
+		}
+		if pl.midiOut == nil {
+			if !wait(stop, next.Add(d)) {
+				return nil
+			}
+		} else if ok, err := pl.midiStep(stop, ev, next, d); !ok || err != nil {
+			return err
+		}
+		next = next.Add(d)
+	}
+}
+
+// PlayContext plays like Play until the context is done and returns the
+// context error then.
+func (pl *Player) PlayContext(ctx context.Context) error {
+	if err := pl.Play(ctx.Done()); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// wait waits until t and reports false when stop was closed before.
+func wait(stop <-chan struct{}, t time.Time) bool {
+	timer := time.NewTimer(time.Until(t))
+	select {
+	case <-stop:
+		timer.Stop()
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// advance returns the event for the current step and the time until the
+// next one and moves the position forward.
+func (pl *Player) advance(at time.Time) (StepEvent, time.Duration, error) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	if pl.song != nil && pl.section >= len(pl.song.Sections) {
+		return StepEvent{}, 0, errEndOfSong
+	}
+	if pl.synced != nil {
+		pl.pattern = pl.synced.Load()
+	}
+	if pl.pattern == nil {
+		return StepEvent{}, 0, ErrNoPattern
+	}
+	tempo := pl.pattern.tempo
+	if pl.song != nil && pl.song.Sections[pl.section].Tempo != 0 {
+		tempo = pl.song.Sections[pl.section].Tempo
+	}
+	if pl.tempo != 0 {
+		tempo = pl.tempo
+	}
+	if pl.countdown > 0 {
+		return pl.countInStep(at, tempo)
+	}
+	synced := false
+	var untilNext time.Duration // time until the next step of the timeline
+	if pl.sync != nil {
+		if bpm, beat, ok := pl.sync.Timeline(at); ok && bpm > 0 {
+			pl.position, untilNext = syncStep(bpm, beat)
+			tempo, synced = float32(bpm), true
+		}
+	}
+	if tempo <= 0 {
+		return StepEvent{}, 0, ErrInvalidTempo
+	}
+	p, fill := pl.pattern, false
+	if pl.fills != nil && pl.song != nil {
+		p, fill = pl.fills.Variation(p, pl.repeat, pl.song.Sections[pl.section].Repeat, pl.filled)
+	}
+	if pl.position >= p.BarSteps() {
+		// the bar of a new pattern or the synced timeline is shorter
+		pl.position = 0
+	}
+	// tracks with their own length follow the cycle, which is kept in line
+	// with the position after seeks, syncs and pattern changes
+	bar := p.BarSteps()
+	pl.cycle %= p.Cycle()
+	pl.cycle += pl.position - pl.cycle%bar
+	ev := StepEvent{Step: pl.position, Section: pl.section, Time: at}
+	d := stepDuration(tempo)
+	if !synced {
+		d = p.stepLength(tempo, pl.position)
+	}
+	solo := p.hasSolo()
+	played := p.barOf(pl.loop)
+	for i, t := range p.tracks {
+		step := p.trackStep(t, pl.cycle)
+		if t.audible(solo) && !pl.mutes[i] && t.triggers(played, step, pl.loop, pl.fill, pl.rng) {
+			ev.Tracks = append(ev.Tracks, t)
+			ev.Velocities = append(ev.Velocities, t.Velocity(step))
+			ev.Offsets = append(ev.Offsets, time.Duration(float64(t.offset(step))/ticksPerStep*float64(d)))
+		}
+	}
+	ev.Choked = choked(p, pl.kit, ev.Tracks)
+	// with swing the first step of a pair is longer than the second
+	delay := swingDelay(d, p.swing)
+	pl.unswung, pl.late = d, pl.position%2 == 1 && delay > 0
+	switch {
+	case synced && pl.position%2 == 0:
+		d = untilNext + delay
+	case synced:
+		d = untilNext
+	case pl.position%2 == 0:
+		d += delay
+	default:
+		d -= delay
+	}
+	pl.current = Transport{Step: pl.position, Bar: pl.loop, BarSteps: bar, StepLength: d, Tempo: tempo, at: at}
+	next := (pl.position + 1) % bar
+	if r := pl.region; r != nil && r.to < bar && pl.position == r.to {
+		// the end of the region ends the bar
+		next = r.from
+	}
+	wrapped := next <= pl.position
+	pl.position = next
+	pl.cycle++
+	if wrapped {
+		pl.loop++
+		pl.filled = fill
+		switch {
+		case pl.next != nil:
+			pl.pattern, pl.next = pl.next, nil
+			pl.song, pl.synced = nil, nil
+		case pl.scene != nil:
+			pl.startScene()
+		case pl.song != nil:
+			pl.nextBar()
+		}
+		// the next section loads while the last step sounds
+		pl.prefetchAhead()
+	}
+	return ev, d, nil
+}
+
+// nextBar moves on to the next section of the song after the repeats of the
+// current one.
+func (pl *Player) nextBar() {
+	pl.repeat++
+	if pl.repeat < pl.song.Sections[pl.section].Repeat {
+		return
+	}
+	pl.repeat, pl.loop = 0, 0
+	pl.section++
+	if pl.section < len(pl.song.Sections) {
+		pl.pattern = pl.sectionPattern(pl.section)
+	}
+}
+
+// SetPattern replaces the pattern or song played. The position and mutes
+// are kept.
+func (pl *Player) SetPattern(p *Pattern) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.pattern = p
+	pl.song, pl.synced, pl.next, pl.scene = nil, nil, nil, nil
+}
+
+// QueuePattern replaces the pattern or song played at the start of the next
+// bar, so that the groove changes without a jump. The last queued pattern
+// wins when several are queued within a bar.
+func (pl *Player) QueuePattern(p *Pattern) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.next, pl.scene = p, nil
+}
+
+// Position returns the next step to be played.
+func (pl *Player) Position() int {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	return pl.position
+}
+
+// Seek sets the next step to be played.
+func (pl *Player) Seek(step int) error {
+	if step < 0 || step >= stepsLength {
+		return fmt.Errorf("step %d out of range", step)
+	}
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.position = step
+	return nil
+}
+
+// SetMute mutes or unmutes the track at the given index of the pattern.
+func (pl *Player) SetMute(track int, muted bool) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	if muted {
+		pl.mutes[track] = true
+	} else {
+		delete(pl.mutes, track)
+	}
+}
+
+// SetFill turns the fill state on or off, which selects the steps with a
+// fill condition, see Condition.
+func (pl *Player) SetFill(on bool) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.fill = on
+}
+
+// SetKit sets the kit whose samples are passed with the step events. The
+// kit should be validated for the pattern, tracks without sample get nil.
+func (pl *Player) SetKit(k *Kit) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.kit = k
+}
+
+// SetTempo overrides the tempo of the pattern. A value of 0 resets to the
+// pattern tempo.
+func (pl *Player) SetTempo(bpm float32) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.tempo = bpm
+}
+
+// playerState is the JSON representation of the player state.
+type playerState struct {
+	Pattern  []byte  `json:"pattern,omitempty"` // encoded in the SPLICE format
+	Position int     `json:"position"`
+	Loop     int     `json:"loop,omitempty"`  // bars played, selects the bar
+	Cycle    int     `json:"cycle,omitempty"` // of the tracks with their own length
+	Mutes    []int   `json:"mutes,omitempty"`
+	Tempo    float32 `json:"tempo,omitempty"`
+}
+
+// SaveState returns the current pattern, position, bar, mutes and tempo
+// override serialized as JSON so that a session can be restored with
+// LoadState. Songs, queued patterns and scenes are not saved: of a song
+// only the pattern of the current section is, which the restored player
+// plays in a loop.
+func (pl *Player) SaveState() ([]byte, error) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	s := playerState{Position: pl.position, Loop: pl.loop, Cycle: pl.cycle, Tempo: pl.tempo}
+	if pl.pattern != nil {
+		var buf bytes.Buffer
+		if err := Encode(&buf, pl.pattern); err != nil {
+			return nil, fmt.Errorf("encode pattern: %v", err)
+		}
+		s.Pattern = buf.Bytes()
+	}
+	for i := range pl.mutes {
+		s.Mutes = append(s.Mutes, i)
+	}
+	sort.Ints(s.Mutes)
+	return json.Marshal(s)
+}
+
+// LoadState restores a state returned by SaveState. Like SetPattern it
+// replaces the pattern or song played and drops queued patterns and scenes.
+func (pl *Player) LoadState(data []byte) error {
+	var s playerState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("parse state: %v", err)
+	}
+	if s.Position < 0 || s.Position >= stepsLength {
+		return fmt.Errorf("parse state: position %d out of range", s.Position)
+	}
+	if s.Loop < 0 || s.Cycle < 0 {
+		return fmt.Errorf("parse state: negative bar %d or cycle %d", s.Loop, s.Cycle)
+	}
+	var p *Pattern
+	if s.Pattern != nil {
+		var err error
+		if p, err = DecodeBytes(s.Pattern); err != nil {
+			return fmt.Errorf("decode pattern: %v", err)
+		}
+	}
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.pattern = p
+	pl.song, pl.synced, pl.next, pl.scene = nil, nil, nil, nil
+	pl.section, pl.repeat, pl.filled = 0, 0, false
+	pl.position, pl.loop, pl.cycle = s.Position, s.Loop, s.Cycle
+	pl.tempo = s.Tempo
+	pl.mutes = make(map[int]bool)
+	for _, i := range s.Mutes {
+		pl.mutes[i] = true
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package drum
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestPlayerPlay(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_5.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	var got []string
+	pl := NewPlayer(p, func(ev StepEvent) {
+		var names []string
+		for _, t := range ev.Tracks {
+			names = append(names, t.name)
+		}
+		got = append(got, fmt.Sprintf("%d%v", ev.Step, names))
+		if len(got) == 3 {
+			close(stop)
+		}
+	})
+	pl.SetMute(0, true)
+	pl.SetTempo(6000)
+	if err := pl.Play(stop); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "[0[HiHat] 1[] 2[HiHat]]"; fmt.Sprint(got) != exp {
+		t.Errorf("Expected '%v' but got '%v'", exp, got)
+	}
+	if exp, got := 3, pl.Position(); got != exp {
+		t.Errorf("Expected position %v but got %v", exp, got)
+	}
+}
+
+func TestPlayerState(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl := NewPlayer(p, nil)
+	pl.Seek(7)
+	pl.SetMute(2, true)
+	pl.SetTempo(140)
+	state, err := pl.SaveState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewPlayer(nil, nil)
+	if err := restored.LoadState(state); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Position() != 7 || !restored.mutes[2] || restored.tempo != 140 {
+		t.Errorf("state not restored: %+v", restored)
+	}
+	if exp, got := p.String(), restored.pattern.String(); got != exp {
+		t.Errorf("Expected pattern:\n%v\nbut got:\n%v", exp, got)
+	}
+
+	if err := restored.LoadState([]byte(`{"position":16}`)); err == nil {
+		t.Errorf("expected error for invalid position")
+	}
+}
+
+func TestPlayerStateBarsAndSong(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.AddBar(0)
+	p.tracks[0].SetBarStep(1, 1, true)
+	pl := NewPlayer(p, nil)
+	// stop at the second step of bar B
+	for range p.BarSteps() + 1 {
+		if _, _, err := pl.advance(time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	state, err := pl.SaveState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewSongPlayer(testSong(t), nil)
+	if err := restored.LoadState(state); err != nil {
+		t.Fatal(err)
+	}
+	ev, _, err := restored.advance(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Step != 1 || len(ev.Tracks) != 1 || ev.Tracks[0].Name() != "kick" {
+		t.Errorf("Expected the kick of bar B at step 1 but got %d %v", ev.Step, ev.Tracks)
+	}
+	// the song does not take over after the bar
+	for range 3 * p.BarSteps() {
+		if _, _, err := restored.advance(time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if restored.song != nil || !restored.pattern.Equal(p) {
+		t.Errorf("Expected the restored pattern to loop but got the song %v", restored.song)
+	}
+	if err := restored.LoadState([]byte(`{"position":0,"loop":-1}`)); err == nil {
+		t.Errorf("expected error for a negative bar")
+	}
+}
+
+func TestPlayerPlayContext(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	steps := 0
+	pl := NewPlayer(p, func(StepEvent) {
+		if steps++; steps == 2 {
+			cancel()
+		}
+	})
+	pl.SetTempo(6000)
+	if err := pl.PlayContext(ctx); err != context.Canceled {
+		t.Errorf("Expected error '%v' but got '%v'", context.Canceled, err)
+	}
+	if steps != 2 {
+		t.Errorf("Expected to stop after 2 steps but played %d", steps)
+	}
+
+	song := &Song{Sections: []Section{{Pattern: p, Repeat: 1}}}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	pl = NewSongPlayer(song, nil)
+	pl.SetTempo(6000)
+	if err := pl.PlayContext(ctx); err != nil {
+		t.Errorf("Expected the song to end but got %v", err)
+	}
+}
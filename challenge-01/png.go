@@ -0,0 +1,56 @@
+package drum
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strconv"
+)
+
+// DarkGridStyle is a dark theme for the graphical renderings, the light
+// theme is DefaultGridStyle.
+var DarkGridStyle = GridStyle{
+	CellSize:   24,
+	Enabled:    "#f5a623",
+	Disabled:   "#3a3a3a",
+	Beat:       "#555555",
+	Background: "#1e1e1e",
+	Text:       "#eeeeee",
+}
+
+// Image renders the step grid into an image. The size of the image scales
+// with the cell size of the style. As there is no font available, track
+// labels and tempo are not drawn.
+func (p *Pattern) Image(style GridStyle) *image.RGBA {
+	s := style.withDefaults()
+	l := newGridLayout(p, s)
+	// no space for texts
+	l.label, l.header = l.gap, l.gap
+	l.resize()
+	img := image.NewRGBA(image.Rect(0, 0, l.width, l.height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(parseColor(s.Background)), image.Point{}, draw.Src)
+	for i, row := range p.gridRows(s.Resolution) {
+		y := l.y(i)
+		for c, enabled := range row.cells {
+			r := image.Rect(l.x(c), y, l.x(c)+s.CellSize, y+s.CellSize)
+			draw.Draw(img, r, image.NewUniform(parseColor(s.cellColor(row.track, enabled, c%l.block == 0))), image.Point{}, draw.Src)
+		}
+	}
+	return img
+}
+
+// ToPNG writes the step grid as PNG image to w, see Image.
+func (p *Pattern) ToPNG(w io.Writer, style GridStyle) error {
+	return png.Encode(w, p.Image(style))
+}
+
+// parseColor parses a "#rrggbb" color. Invalid colors are black.
+func parseColor(s string) color.RGBA {
+	if validateColor(s) != nil || s == "" {
+		return color.RGBA{A: 0xff}
+	}
+	v, _ := strconv.ParseUint(s[1:], 16, 32)
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}
+}
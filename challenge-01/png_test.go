@@ -0,0 +1,52 @@
+package drum
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"path"
+	"testing"
+)
+
+func TestToPNG(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_5.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := p.ToPNG(&buf, DarkGridStyle); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := newGridLayout(p, DarkGridStyle.withDefaults())
+	l.label, l.header = l.gap, l.gap
+	at := func(step, track int) color.Color {
+		return img.At(l.x(step)+1, l.y(track)+1)
+	}
+	if exp, got := parseColor(DarkGridStyle.Enabled), at(0, 0); !sameColor(got, exp) {
+		t.Errorf("Expected enabled color %v but got %v", exp, got)
+	}
+	if exp, got := parseColor(DarkGridStyle.Beat), at(4, 0); !sameColor(got, exp) {
+		t.Errorf("Expected downbeat color %v but got %v", exp, got)
+	}
+	if exp, got := parseColor(DarkGridStyle.Disabled), at(5, 0); !sameColor(got, exp) {
+		t.Errorf("Expected disabled color %v but got %v", exp, got)
+	}
+	if exp, got := parseColor(DarkGridStyle.Background), img.At(0, 0); !sameColor(got, exp) {
+		t.Errorf("Expected background color %v but got %v", exp, got)
+	}
+
+	big := p.Image(GridStyle{CellSize: 48})
+	if small := p.Image(GridStyle{CellSize: 24}); big.Bounds().Dx() != 2*small.Bounds().Dx() {
+		t.Errorf("expected image to scale with the cell size: %v %v", big.Bounds(), small.Bounds())
+	}
+}
+
+func sameColor(a, b color.Color) bool {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
@@ -0,0 +1,115 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+)
+
+var chunkLength = chunkID{'L', 'O', 'O', 'P'}
+
+// FeatureLength is the chunk of the track loop lengths.
+const FeatureLength Feature = "length"
+
+// ErrInvalidLength is returned for a track length outside of 1 and 16 steps.
+var ErrInvalidLength = errors.New("invalid length")
+
+// Length returns the number of steps after which the track loops, 0 when it
+// follows the bar of the pattern.
+func (t *Track) Length() int {
+	return int(t.length)
+}
+
+// SetLength makes the track loop every n steps independently of the bar,
+// for polyrhythms like a clave looping every 12 steps over a kick looping
+// every 16. Steps beyond n are kept but not played. 0 makes the track
+// follow the bar again.
+func (t *Track) SetLength(n int) error {
+	if n < 0 || n > stepsLength {
+		return ErrInvalidLength
+	}
+	t.length = uint8(n)
+	return nil
+}
+
+// trackLength returns the number of steps after which the track loops.
+func (p *Pattern) trackLength(t *Track) int {
+	if t.length != 0 {
+		return int(t.length)
+	}
+	return p.BarSteps()
+}
+
+// trackStep returns the step of the track played n steps after the start.
+func (p *Pattern) trackStep(t *Track, n int) int {
+	return n % p.trackLength(t)
+}
+
+// Cycle returns the number of steps after which all tracks and the bar start
+// together again, the least common multiple of their lengths. It is the bar
+// length unless tracks have a length set.
+func (p *Pattern) Cycle() int {
+	cycle := p.BarSteps()
+	for _, t := range p.tracks {
+		if n := int(t.length); n != 0 {
+			cycle = cycle / gcd(cycle, n) * n
+		}
+	}
+	return cycle
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// hasLength reports whether any track of the pattern loops independently of
+// the bar.
+func (p *Pattern) hasLength() bool {
+	for _, t := range p.tracks {
+		if t.length != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// The length chunk stores |Track index (2 bytes)|Length (1 byte)| for every
+// track with a length set.
+
+func decodeLengthChunk(data []byte, p *Pattern) error {
+	const entryLength = 3
+	if len(data)%entryLength != 0 {
+		return errors.New("invalid size")
+	}
+	for ; len(data) > 0; data = data[entryLength:] {
+		index := int(data[0]) | int(data[1])<<8
+		if index >= len(p.tracks) {
+			return fmt.Errorf("track index %d out of range", index)
+		}
+		if data[2] == 0 {
+			return ErrInvalidLength
+		}
+		if err := p.tracks[index].SetLength(int(data[2])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeLengthChunk(p *Pattern) []byte {
+	var data []byte
+	for i, t := range p.tracks {
+		if t.length != 0 {
+			data = append(data, byte(i), byte(i>>8), t.length)
+		}
+	}
+	return data
+}
+
+func clearLength(p *Pattern) {
+	for _, t := range p.tracks {
+		t.length = 0
+	}
+}
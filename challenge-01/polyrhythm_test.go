@@ -0,0 +1,119 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func polyrhythmPattern(t *testing.T) *Pattern {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// (0) kick |x---|x---|x---|x---| looping every 6 steps plays x---x-
+	if err := p.tracks[0].SetLength(6); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestTrackLength(t *testing.T) {
+	p := polyrhythmPattern(t)
+	kick := p.tracks[0]
+	for _, n := range []int{-1, 17} {
+		if err := kick.SetLength(n); err != ErrInvalidLength {
+			t.Errorf("%d: Expected invalid length but got %v", n, err)
+		}
+	}
+	if got := p.Cycle(); got != 48 {
+		t.Errorf("Expected a cycle of 48 steps but got %d", got)
+	}
+	p.tracks[1].SetLength(12)
+	p.SetTimeSignature(TimeSignature{3, 4})
+	if got := p.Cycle(); got != 12 {
+		t.Errorf("Expected a cycle of 12 steps but got %d", got)
+	}
+	kick.SetLength(0)
+	p.tracks[1].SetLength(0)
+	if got := p.Cycle(); got != p.BarSteps() {
+		t.Errorf("Expected the cycle to be the bar but got %d", got)
+	}
+}
+
+func TestTrackLengthRoundTrip(t *testing.T) {
+	p := polyrhythmPattern(t)
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(p) || decoded.tracks[0].Length() != 6 {
+		t.Errorf("Expected the length to round trip but got %v", Diff(p, decoded))
+	}
+	if got := decoded.Features(); len(got) != 1 || got[0] != FeatureLength {
+		t.Errorf("Expected the length feature but got %v", got)
+	}
+
+	out := p.String()
+	if exp := "(0) kick\t|x---|x-|\n"; !strings.Contains(out, exp) {
+		t.Errorf("Expected %q in\n%s", exp, out)
+	}
+	var fromText Pattern
+	if err := fromText.UnmarshalText([]byte(out)); err != nil {
+		t.Fatal(err)
+	}
+	if got := fromText.tracks[0].Length(); got != 6 {
+		t.Errorf("Expected the length to round trip text but got %d", got)
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Pattern
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !fromJSON.Equal(p) {
+		t.Errorf("Expected the length to round trip JSON but got %s", data)
+	}
+}
+
+func TestTrackLengthPlayback(t *testing.T) {
+	p := polyrhythmPattern(t)
+	var scheduled []int
+	for _, e := range p.schedule(3) {
+		if e.track == 0 {
+			scheduled = append(scheduled, e.tick/ticksPerStep)
+		}
+	}
+	if exp := "[0 4 6 10 12 16 18 22 24 28 30 34 36 40 42 46]"; fmt.Sprint(scheduled) != exp {
+		t.Errorf("Expected kicks at %s but got %v", exp, scheduled)
+	}
+
+	pl := NewPlayer(p, nil)
+	var played []int
+	for i := 0; i < 3*stepsLength; i++ {
+		ev, _, err := pl.advance(time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ev.Tracks) > 0 && ev.Tracks[0] == p.tracks[0] {
+			played = append(played, i)
+		}
+		if ev.Step != i%stepsLength {
+			t.Fatalf("Expected step %d of the bar but got %d", i%stepsLength, ev.Step)
+		}
+	}
+	if fmt.Sprint(played) != fmt.Sprint(scheduled) {
+		t.Errorf("Expected the player to play the kicks at %v but got %v", scheduled, played)
+	}
+}
@@ -0,0 +1,134 @@
+package drum
+
+import (
+	"context"
+	"fmt"
+)
+
+// PatternLoader loads the pattern of the song section at the index, for
+// example by decoding a file from slow storage or fetching it from the
+// network. It should return early when the context is done.
+type PatternLoader func(ctx context.Context, section int) (*Pattern, error)
+
+// WithPrefetch makes the player of a song load the patterns of its sections
+// with load instead of taking them from the song, whose section patterns may
+// be nil then. While a section plays, up to depth sections ahead are loaded
+// in the background, so that the next pattern is ready at the section
+// boundary without a gap. Loading waits while depth patterns are ready and
+// is cancelled when playing stops or skips to another section or song.
+// Playing waits for a pattern that is not loaded in time and stops with the
+// error of a failed load.
+func WithPrefetch(load PatternLoader, depth int) PlayerOption {
+	return func(pl *Player) {
+		pl.loader, pl.depth = load, max(depth, 1)
+	}
+}
+
+// prefetcher loads the sections of a song from one on in a goroutine.
+type prefetcher struct {
+	song   *Song
+	next   int // section received next from loaded
+	loaded chan loadedSection
+	cancel context.CancelFunc
+}
+
+type loadedSection struct {
+	section int
+	pattern *Pattern
+	err     error
+}
+
+// startPrefetch starts loading the sections of the song from the index on.
+// One pattern waits to be sent while the channel holds the others, so that
+// depth patterns are loaded ahead at most.
+func startPrefetch(load PatternLoader, s *Song, from, depth int) *prefetcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	pf := &prefetcher{song: s, next: from, loaded: make(chan loadedSection, depth-1), cancel: cancel}
+	go func() {
+		for i := from; i < len(s.Sections); i++ {
+			p, err := load(ctx, i)
+			if err == nil && p == nil {
+				err = ErrNoPattern
+			}
+			select {
+			case pf.loaded <- loadedSection{i, p, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return pf
+}
+
+// awaitSection waits for the pattern of the current section when the song
+// is loaded with WithPrefetch and keeps the sections after it loading. It
+// reports false when stop was closed before.
+func (pl *Player) awaitSection(stop <-chan struct{}) (bool, error) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	for {
+		if !pl.prefetchAhead() || pl.pattern != nil {
+			return true, nil
+		}
+		pf := pl.prefetch
+		pl.mu.Unlock()
+		var l loadedSection
+		select {
+		case <-stop:
+			pl.mu.Lock()
+			return false, nil
+		case l = <-pf.loaded:
+		}
+		pl.mu.Lock()
+		if pl.prefetch != pf {
+			// skipped while waiting
+			continue
+		}
+		pf.next = l.section + 1
+		if l.err != nil {
+			return false, fmt.Errorf("load section %d: %w", l.section+1, l.err)
+		}
+		if l.section == pl.section && pl.pattern == nil {
+			pl.pattern = l.pattern
+		}
+	}
+}
+
+// prefetchAhead keeps the sections from the current one on loading when
+// the song is loaded with WithPrefetch, restarting after skips to another
+// section or song, and reports whether it does.
+func (pl *Player) prefetchAhead() bool {
+	if pl.loader == nil || pl.song == nil || pl.section >= len(pl.song.Sections) {
+		pl.stopPrefetch()
+		return false
+	}
+	want := pl.section
+	if pl.pattern != nil {
+		want++
+	}
+	if pf := pl.prefetch; pf == nil || pf.song != pl.song || pf.next != want {
+		pl.stopPrefetch()
+		pl.prefetch = startPrefetch(pl.loader, pl.song, want, pl.depth)
+	}
+	return true
+}
+
+// stopPrefetch cancels loading the sections ahead.
+func (pl *Player) stopPrefetch() {
+	if pl.prefetch != nil {
+		pl.prefetch.cancel()
+		pl.prefetch = nil
+	}
+}
+
+// sectionPattern returns the pattern of the section of the song played, nil
+// when it is loaded with WithPrefetch.
+func (pl *Player) sectionPattern(section int) *Pattern {
+	if pl.loader != nil {
+		return nil
+	}
+	return pl.song.Sections[section].Pattern
+}
@@ -0,0 +1,145 @@
+package drum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testLoader loads the patterns of the testSong sections, delayed like on
+// slow storage, and records the sections loaded. The sections are sent to
+// started, if set, as their loads start.
+type testLoader struct {
+	patterns []*Pattern
+	delay    time.Duration
+	started  chan int
+
+	mu     sync.Mutex
+	loads  []int
+	ctxs   []context.Context
+	failAt int // section failing to load, -1 for none
+}
+
+func (l *testLoader) load(ctx context.Context, section int) (*Pattern, error) {
+	l.mu.Lock()
+	l.loads = append(l.loads, section)
+	l.ctxs = append(l.ctxs, ctx)
+	l.mu.Unlock()
+	if l.started != nil {
+		l.started <- section
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(l.delay):
+	}
+	if section == l.failAt {
+		return nil, errors.New("network down")
+	}
+	return l.patterns[section%len(l.patterns)], nil
+}
+
+func (l *testLoader) loaded() []int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]int(nil), l.loads...)
+}
+
+func prefetchSong(t *testing.T, sections int) (*Song, *testLoader) {
+	full := testSong(t)
+	s := &Song{Title: "lazy"}
+	for i := 0; i < sections; i++ {
+		s.Sections = append(s.Sections, Section{Repeat: 1})
+	}
+	l := &testLoader{failAt: -1}
+	for _, sec := range full.Sections {
+		l.patterns = append(l.patterns, sec.Pattern)
+	}
+	return s, l
+}
+
+func TestPrefetchSongPlayer(t *testing.T) {
+	s, l := prefetchSong(t, 4)
+	l.delay = time.Millisecond
+	var sections []int
+	var tracks []int
+	pl := NewSongPlayer(s, func(ev StepEvent) {
+		if ev.Step == 0 {
+			sections = append(sections, ev.Section)
+			tracks = append(tracks, len(ev.Tracks))
+		}
+	}, WithPrefetch(l.load, 2))
+	pl.SetTempo(100000)
+	if err := pl.Play(make(chan struct{})); err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := "[0 1 2 3]", fmt.Sprint(sections); got != exp {
+		t.Errorf("Expected bars of sections %v but got %v", exp, got)
+	}
+	if exp, got := "[0 1 2 3]", fmt.Sprint(l.loaded()); got != exp {
+		t.Errorf("Expected the sections loaded once in order %v but got %v", exp, got)
+	}
+}
+
+func TestPrefetchBackpressure(t *testing.T) {
+	s, l := prefetchSong(t, 10)
+	l.started = make(chan int, len(s.Sections))
+	scene := make(chan struct{})
+	pl := NewSongPlayer(s, func(ev StepEvent) {
+		if ev.Section == 8 && ev.Step == 0 {
+			close(scene)
+		}
+	}, WithPrefetch(l.load, 2))
+	pl.SetTempo(60) // steps of 250ms, so section 0 plays for 4s
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- pl.Play(stop) }()
+	// the section playing and the two ahead; more loads wait for section 0
+	// to end
+	awaitLoads := func(exp ...int) {
+		t.Helper()
+		for _, section := range exp {
+			if got := <-l.started; got != section {
+				t.Fatalf("Expected the load of section %d but got %d", section, got)
+			}
+		}
+	}
+	awaitLoads(0, 1, 2)
+
+	// skipping cancels the loads ahead and loads from the scene on
+	s.Scenes = []Scene{{Name: "end", Section: 8}}
+	if err := pl.LaunchScene("end"); err != nil {
+		t.Fatal(err)
+	}
+	pl.Seek(15)
+	<-scene
+	awaitLoads(8, 9)
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := "[0 1 2 8 9]", fmt.Sprint(l.loaded()); got != exp {
+		t.Errorf("Expected loads %v but got %v", exp, got)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, ctx := range l.ctxs {
+		if ctx.Err() == nil {
+			t.Errorf("Expected the load of section %d cancelled after playing", l.loads[i])
+		}
+	}
+}
+
+func TestPrefetchError(t *testing.T) {
+	s, l := prefetchSong(t, 3)
+	l.failAt = 1
+	pl := NewSongPlayer(s, nil, WithPrefetch(l.load, 1))
+	pl.SetTempo(100000)
+	err := pl.Play(make(chan struct{}))
+	if err == nil || err.Error() != "load section 2: network down" {
+		t.Errorf("Expected the load error but got %v", err)
+	}
+}
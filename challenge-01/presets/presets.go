@@ -0,0 +1,124 @@
+// Package presets provides factory patterns of common grooves as starting
+// points for new patterns.
+//
+// Every preset is a single bar of a style with a typical tempo. The
+// constructors return a new pattern on every call, so it can be edited
+// freely:
+//
+//	p, err := presets.House(124)
+//	p, err := presets.New("bossa", 0) // at the default tempo of the preset
+package presets
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// Version is the version string of presets.
+const Version = "preset"
+
+// ErrUnknownPreset is returned by New for names of no preset.
+var ErrUnknownPreset = errors.New("unknown preset")
+
+// track is a track of a preset, the steps in the printout symbols.
+type track struct {
+	name  string
+	steps string
+}
+
+// preset is a factory pattern.
+type preset struct {
+	tempo  float32 // default
+	tracks []track
+}
+
+var presets = map[string]preset{
+	"rock": {110, []track{
+		{"kick", "x-------x-x-----"},
+		{"snare", "----x-------x---"},
+		{"hh-close", "x-x-x-x-x-x-x-x-"},
+		{"crash", "x---------------"},
+	}},
+	"house": {124, []track{
+		{"kick", "x---x---x---x---"},
+		{"clap", "----x-------x---"},
+		{"hh-open", "--x---x---x---x-"},
+		{"hh-close", "-x-x-x-x-x-x-x-x"},
+	}},
+	"hiphop": {90, []track{
+		{"kick", "x------x--x-----"},
+		{"snare", "----x-------x---"},
+		{"hh-close", "x-x-x-x-x-x-x-x-"},
+	}},
+	"bossa": {128, []track{
+		{"kick", "x--xx--xx--xx--x"},
+		{"rimshot", "x--x--x---x--x--"},
+		{"hh-close", "x-x-x-x-x-x-x-x-"},
+	}},
+}
+
+// Names returns the names of all presets in alphabetical order.
+func Names() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Tempo returns the default tempo of the preset in BPM, 0 when there is no
+// preset of the name.
+func Tempo(name string) float32 {
+	return presets[name].tempo
+}
+
+// New returns a new pattern of the named preset at the tempo in BPM, at the
+// default tempo of the preset when bpm is 0. Tracks are numbered from 0 in
+// their order.
+func New(name string, bpm float32) (*drum.Pattern, error) {
+	ps, ok := presets[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownPreset, name)
+	}
+	if bpm == 0 {
+		bpm = ps.tempo
+	}
+	tracks := make([]*drum.Track, len(ps.tracks))
+	for i, t := range ps.tracks {
+		var steps drum.Steps
+		for step, symbol := range t.steps {
+			steps[step] = symbol == 'x'
+		}
+		tr, err := drum.NewTrack(uint32(i), t.name, steps)
+		if err != nil {
+			return nil, err
+		}
+		tracks[i] = tr
+	}
+	return drum.NewPattern(Version, bpm, tracks...)
+}
+
+// Rock returns a rock beat with the kick on 1 and 3 and the snare on the
+// backbeat.
+func Rock(bpm float32) (*drum.Pattern, error) {
+	return New("rock", bpm)
+}
+
+// House returns a four to the floor house beat with offbeat open hi-hats.
+func House(bpm float32) (*drum.Pattern, error) {
+	return New("house", bpm)
+}
+
+// HipHop returns a boom bap beat with a syncopated kick.
+func HipHop(bpm float32) (*drum.Pattern, error) {
+	return New("hiphop", bpm)
+}
+
+// Bossa returns a bossa nova beat with the clave on the rimshot.
+func Bossa(bpm float32) (*drum.Pattern, error) {
+	return New("bossa", bpm)
+}
@@ -0,0 +1,75 @@
+package presets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+func TestPresets(t *testing.T) {
+	if exp, got := []string{"bossa", "hiphop", "house", "rock"}, Names(); !reflect.DeepEqual(exp, got) {
+		t.Errorf("expected %v but got %v", exp, got)
+	}
+	for _, name := range Names() {
+		p, err := New(name, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if p.Tempo() != Tempo(name) {
+			t.Errorf("%s: expected default tempo %v but got %v", name, Tempo(name), p.Tempo())
+		}
+		if issues := drum.Validate(p); len(issues) != 0 {
+			t.Errorf("%s: unexpected issues %v", name, issues)
+		}
+		var buf bytes.Buffer
+		if err := drum.Encode(&buf, p); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := drum.Decode(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !got.Equal(p) {
+			t.Errorf("%s: expected %v but got %v", name, p, got)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	p, err := House(120)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Tempo() != 120 || p.TrackByName("kick").Steps() != (drum.Steps{0: true, 4: true, 8: true, 12: true}) {
+		t.Errorf("unexpected pattern %v", p)
+	}
+	// every call returns a new pattern
+	p.TrackByName("kick").SetStep(1, true)
+	if q, _ := House(120); q.Equal(p) {
+		t.Error("expected the preset unchanged")
+	}
+	if _, err := New("polka", 0); !errors.Is(err, ErrUnknownPreset) {
+		t.Errorf("expected ErrUnknownPreset but got %v", err)
+	}
+	if _, err := Rock(-1); !errors.Is(err, drum.ErrInvalidTempo) {
+		t.Errorf("expected ErrInvalidTempo but got %v", err)
+	}
+}
+
+func ExampleNew() {
+	p, err := New("hiphop", 0)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print(p)
+	// Output:
+	// Saved with HW Version: preset
+	// Tempo: 90
+	// (0) kick	|x---|---x|--x-|----|
+	// (1) snare	|----|x---|----|x---|
+	// (2) hh-close	|x-x-|x-x-|x-x-|x-x-|
+}
@@ -0,0 +1,40 @@
+package drum
+
+import "sync"
+
+// WithProgress makes DecodeDir and Bank.LoadAll call fn with the number of
+// files decoded so far and the total after every file. Calls are never
+// concurrent but may come from other goroutines than the one decoding.
+func WithProgress(fn func(done, total int)) DecodeOption {
+	return func(o *decodeOptions) {
+		o.progress = fn
+	}
+}
+
+// progress counts the work done and reports it to a callback. All methods
+// are no-ops on a nil progress so that callers need no checks.
+type progress struct {
+	mu    sync.Mutex
+	fn    func(done, total int)
+	done  int
+	total int
+}
+
+// newProgress returns nil when fn is nil.
+func newProgress(fn func(done, total int), total int) *progress {
+	if fn == nil {
+		return nil
+	}
+	return &progress{fn: fn, total: total}
+}
+
+// step marks one more unit of work as done.
+func (p *progress) step() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	p.fn(p.done, p.total)
+}
@@ -0,0 +1,53 @@
+package drum
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestWithProgress(t *testing.T) {
+	var got []string
+	record := func(done, total int) {
+		got = append(got, fmt.Sprintf("%d/%d", done, total))
+	}
+	if _, err := DecodeDir("fixtures", WithProgress(record)); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "[1/5 2/5 3/5 4/5 5/5]"; fmt.Sprint(got) != exp {
+		t.Errorf("Expected %v but got %v", exp, got)
+	}
+
+	dir, err := ioutil.TempDir("", "bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	b, err := OpenBank(writeTestBank(t, dir, "pattern_1.splice", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	got = nil
+	if err := b.LoadAll(WithProgress(record)); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "[1/2 2/2]"; fmt.Sprint(got) != exp {
+		t.Errorf("Expected %v but got %v", exp, got)
+	}
+	if err := b.LoadAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Pattern{tempo: 120, tracks: []*Track{{name: "kick", steps: Steps{true, false, true}}}}
+	kit := NewKit("test")
+	kit.Add("kick", &Sample{Rate: 800, Left: []float32{1}, Right: []float32{1}})
+	got = nil
+	if _, err := Render(p, kit, 2, WithRenderProgress(record)); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "[1/4 2/4 3/4 4/4]"; fmt.Sprint(got) != exp {
+		t.Errorf("Expected %v but got %v", exp, got)
+	}
+}
@@ -0,0 +1,600 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Protocol buffer wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// errProtoTruncated is returned for a message ending within a field.
+var errProtoTruncated = errors.New("unexpected end of message")
+
+// ToProto returns the pattern as splice.v1.Pattern message in the protocol
+// buffer wire format, see proto/pattern.proto. Like the JSON representation
+// it holds the steps of all bars and the pattern and track extensions
+// interpreted by this package, including the mute and solo states, but no
+// unknown chunks or raw extra data.
+func ToProto(p *Pattern) []byte {
+	var m protoMessage
+	m.string(1, p.version)
+	m.fixed32(2, math.Float32bits(p.tempo))
+	for _, t := range p.tracks {
+		m.message(3, trackProto(t, int(p.extraBars)))
+	}
+	m.varint(4, uint64(p.swing))
+	if meta := p.meta; !meta.isZero() {
+		var mm protoMessage
+		mm.string(1, meta.Title)
+		mm.string(2, meta.Author)
+		for _, tag := range meta.Tags {
+			mm.bytes(3, []byte(tag), true)
+		}
+		if !meta.Created.IsZero() {
+			mm.varint(4, uint64(meta.Created.Unix()))
+		}
+		m.message(5, mm)
+	}
+	if p.timeSig != (TimeSignature{}) {
+		m.string(6, p.timeSig.String())
+	}
+	for _, pt := range p.tempoMap {
+		var tm protoMessage
+		tm.varint(1, uint64(pt.Step))
+		tm.fixed32(2, math.Float32bits(pt.Tempo))
+		tm.bool(3, pt.Ramp)
+		m.message(7, tm)
+	}
+	if p.extraBars != 0 {
+		m.varint(8, uint64(p.Bars()))
+	}
+	m.bytes(9, p.barOrder, false)
+	for _, l := range p.labels {
+		m.message(10, labelProto(l))
+	}
+	return m.Bytes()
+}
+
+func trackProto(t *Track, extraBars int) protoMessage {
+	var m protoMessage
+	m.varint(1, uint64(t.id))
+	m.string(2, t.name)
+	m.varint(3, stepBits(t.steps))
+	if t.hasVelocity() {
+		velocity := make([]byte, stepsLength)
+		for i := range velocity {
+			velocity[i] = t.Velocity(i)
+		}
+		m.bytes(4, velocity, false)
+	}
+	if t.hasTiming() {
+		var timing protoMessage
+		for i := range t.timing {
+			binary.Write(&timing, binary.LittleEndian, math.Float64bits(t.Timing(i)))
+		}
+		m.bytes(5, timing.Bytes(), false)
+	}
+	if t.attenuation != 0 {
+		m.key(6, wireVarint)
+		m.uvarint(uint64(t.Volume()))
+	}
+	// zigzag encoding of sint32
+	m.varint(7, uint64(uint32(int32(t.pan)<<1^int32(t.pan)>>31)))
+	if t.display != (Display{}) {
+		var dm protoMessage
+		dm.string(1, t.display.Color)
+		dm.string(2, t.display.Icon)
+		m.message(8, dm)
+	}
+	m.bool(9, t.muted)
+	m.bool(10, t.solo)
+	if bars := t.bars[:extraBars]; !isZeroBars(bars) {
+		var packed protoMessage
+		for _, s := range bars {
+			packed.uvarint(stepBits(s))
+		}
+		m.bytes(11, packed.Bytes(), false)
+	}
+	if t.hasProbability() {
+		m.bytes(12, probabilities(t), false)
+	}
+	if t.hasCondition() {
+		for i := range t.condition {
+			m.bytes(13, []byte(t.Condition(i).String()), true)
+		}
+	}
+	if t.hasRatchet() {
+		ratchet := make([]byte, stepsLength)
+		for i := range ratchet {
+			ratchet[i] = byte(t.Ratchet(i))
+		}
+		m.bytes(14, ratchet, false)
+	}
+	m.varint(15, uint64(t.length))
+	m.varint(16, uint64(t.choke))
+	if t.nudge != 0 {
+		m.key(17, wireFixed64)
+		binary.Write(&m, binary.LittleEndian, math.Float64bits(t.Nudge()))
+	}
+	if t.role != RoleAuto {
+		m.string(18, t.role.String())
+	}
+	for _, a := range t.Aliases() {
+		m.bytes(19, []byte(a), true)
+	}
+	for _, l := range t.Labels() {
+		m.message(20, labelProto(l))
+	}
+	return m
+}
+
+func labelProto(l Label) protoMessage {
+	var m protoMessage
+	m.string(1, l.Name)
+	m.string(2, l.Color)
+	return m
+}
+
+// stepBits returns the steps with bit n set when step n is enabled.
+func stepBits(s Steps) uint64 {
+	var bits uint64
+	for i, enabled := range s {
+		if enabled {
+			bits |= 1 << uint(i)
+		}
+	}
+	return bits
+}
+
+// stepsOfBits returns the steps of the bits written by stepBits.
+func stepsOfBits(bits uint64) Steps {
+	var s Steps
+	for i := range s {
+		s[i] = bits&(1<<uint(i)) != 0
+	}
+	return s
+}
+
+// FromProto returns the pattern of a splice.v1.Pattern message written by
+// ToProto or any other protocol buffer implementation. Unknown fields are
+// skipped and all values are validated like by the setters.
+func FromProto(data []byte) (*Pattern, error) {
+	var (
+		version  string
+		tempo    float32
+		tracks   []*Track
+		bars     []int // bars per track
+		swing    uint64
+		meta     Metadata
+		timeSig  string
+		tempoMap TempoMap
+		barCount uint64
+		barOrder []int
+		labels   []Label
+	)
+	err := readProto(data, func(f protoField) error {
+		switch {
+		case f.is(1, wireBytes):
+			version = string(f.data)
+		case f.is(2, wireFixed32):
+			tempo = math.Float32frombits(uint32(f.value))
+		case f.is(3, wireBytes):
+			t, n, err := trackFromProto(f.data)
+			if err != nil {
+				return fmt.Errorf("track %d: %v", len(tracks), err)
+			}
+			tracks, bars = append(tracks, t), append(bars, n)
+		case f.is(4, wireVarint):
+			swing = f.value
+		case f.is(5, wireBytes):
+			return readProto(f.data, func(f protoField) error {
+				switch {
+				case f.is(1, wireBytes):
+					meta.Title = string(f.data)
+				case f.is(2, wireBytes):
+					meta.Author = string(f.data)
+				case f.is(3, wireBytes):
+					meta.Tags = append(meta.Tags, string(f.data))
+				case f.is(4, wireVarint):
+					if created := int64(f.value); created != 0 {
+						meta.Created = time.Unix(created, 0).UTC()
+					}
+				}
+				return nil
+			})
+		case f.is(6, wireBytes):
+			timeSig = string(f.data)
+		case f.is(7, wireBytes):
+			var pt TempoPoint
+			err := readProto(f.data, func(f protoField) error {
+				switch {
+				case f.is(1, wireVarint):
+					pt.Step = int(min(f.value, math.MaxUint16))
+				case f.is(2, wireFixed32):
+					pt.Tempo = math.Float32frombits(uint32(f.value))
+				case f.is(3, wireVarint):
+					pt.Ramp = f.value != 0
+				}
+				return nil
+			})
+			tempoMap = append(tempoMap, pt)
+			return err
+		case f.is(8, wireVarint):
+			barCount = f.value
+		case f.is(9, wireBytes):
+			for _, b := range f.data {
+				barOrder = append(barOrder, int(b))
+			}
+		case f.is(10, wireBytes):
+			l, err := labelFromProto(f.data)
+			if err != nil {
+				return err
+			}
+			labels = append(labels, l)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parse proto: %v", err)
+	}
+	p, err := NewPattern(version, tempo, tracks...)
+	if err != nil {
+		return nil, fmt.Errorf("parse proto: %v", err)
+	}
+	if swing > math.MaxUint8 {
+		return nil, fmt.Errorf("parse proto: %v", ErrInvalidSwing)
+	}
+	if err := p.SetSwing(uint8(swing)); err != nil {
+		return nil, fmt.Errorf("parse proto: %v", err)
+	}
+	if err := p.SetMetadata(meta); err != nil {
+		return nil, fmt.Errorf("parse proto: %v", err)
+	}
+	if timeSig != "" {
+		ts, err := ParseTimeSignature(timeSig)
+		if err != nil {
+			return nil, fmt.Errorf("parse proto: %v", err)
+		}
+		p.SetTimeSignature(ts)
+	}
+	if tempoMap != nil {
+		if err := p.SetTempoMap(tempoMap); err != nil {
+			return nil, fmt.Errorf("parse proto: %v", err)
+		}
+	}
+	if barCount > MaxBars {
+		return nil, fmt.Errorf("parse proto: %w: %d bars", ErrInvalidBar, barCount)
+	}
+	if barCount > 1 {
+		p.extraBars = uint8(barCount - 1)
+	}
+	for i, n := range bars {
+		if n > int(p.extraBars) {
+			return nil, fmt.Errorf("parse proto: track %d: %w: %d bars", i, ErrInvalidBar, n+1)
+		}
+	}
+	if err := p.SetBarOrder(barOrder); err != nil {
+		return nil, fmt.Errorf("parse proto: %v", err)
+	}
+	if err := p.SetLabels(labels...); err != nil {
+		return nil, fmt.Errorf("parse proto: %v", err)
+	}
+	return p, nil
+}
+
+// trackFromProto returns the track of a splice.v1.Track message and the
+// number of bars after the first it has steps for.
+func trackFromProto(data []byte) (*Track, int, error) {
+	t := new(Track)
+	var (
+		timing      []float64
+		volume      = uint64(MaxVolume)
+		pan         int64
+		bars        []uint64
+		probability []byte
+		conditions  []string
+		ratchet     []byte
+		length      uint64
+		choke       uint64
+		nudge       float64
+		role        string
+		aliases     []string
+		labels      []Label
+	)
+	err := readProto(data, func(f protoField) error {
+		switch {
+		case f.is(1, wireVarint):
+			t.id = uint32(f.value)
+		case f.is(2, wireBytes):
+			t.name = string(f.data)
+		case f.is(3, wireVarint):
+			t.steps = stepsOfBits(f.value)
+		case f.is(4, wireBytes):
+			if len(f.data) != stepsLength {
+				return fmt.Errorf("expected %d velocities", stepsLength)
+			}
+			for i, v := range f.data {
+				if err := t.SetVelocity(i, v); err != nil {
+					return err
+				}
+			}
+		case f.is(5, wireBytes): // packed
+			for b := f.data; len(b) > 0; b = b[8:] {
+				if len(b) < 8 {
+					return errProtoTruncated
+				}
+				timing = append(timing, math.Float64frombits(binary.LittleEndian.Uint64(b)))
+			}
+		case f.is(5, wireFixed64):
+			timing = append(timing, math.Float64frombits(f.value))
+		case f.is(6, wireVarint):
+			volume = f.value
+		case f.is(7, wireVarint):
+			pan = int64(f.value>>1) ^ -int64(f.value&1)
+		case f.is(8, wireBytes):
+			return readProto(f.data, func(f protoField) error {
+				switch {
+				case f.is(1, wireBytes):
+					t.display.Color = string(f.data)
+				case f.is(2, wireBytes):
+					t.display.Icon = string(f.data)
+				}
+				return nil
+			})
+		case f.is(9, wireVarint):
+			t.muted = f.value != 0
+		case f.is(10, wireVarint):
+			t.solo = f.value != 0
+		case f.is(11, wireBytes): // packed
+			for b := f.data; len(b) > 0; {
+				v, n := binary.Uvarint(b)
+				if n <= 0 {
+					return errProtoTruncated
+				}
+				bars, b = append(bars, v), b[n:]
+			}
+		case f.is(11, wireVarint):
+			bars = append(bars, f.value)
+		case f.is(12, wireBytes):
+			probability = f.data
+		case f.is(13, wireBytes):
+			conditions = append(conditions, string(f.data))
+		case f.is(14, wireBytes):
+			ratchet = f.data
+		case f.is(15, wireVarint):
+			length = f.value
+		case f.is(16, wireVarint):
+			choke = f.value
+		case f.is(17, wireFixed64):
+			nudge = math.Float64frombits(f.value)
+		case f.is(18, wireBytes):
+			role = string(f.data)
+		case f.is(19, wireBytes):
+			aliases = append(aliases, string(f.data))
+		case f.is(20, wireBytes):
+			l, err := labelFromProto(f.data)
+			if err != nil {
+				return err
+			}
+			labels = append(labels, l)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(t.name) > math.MaxUint8 {
+		return nil, 0, ErrNameTooLong
+	}
+	if timing != nil && len(timing) != stepsLength {
+		return nil, 0, fmt.Errorf("expected %d timing offsets", stepsLength)
+	}
+	for i, o := range timing {
+		if err := t.SetTiming(i, o); err != nil {
+			return nil, 0, err
+		}
+	}
+	if volume > MaxVolume {
+		return nil, 0, ErrInvalidVolume
+	}
+	t.SetVolume(uint8(volume))
+	if pan < MinPan || pan > MaxPan {
+		return nil, 0, ErrInvalidPan
+	}
+	t.pan = int8(pan)
+	if err := validateColor(t.display.Color); err != nil {
+		return nil, 0, err
+	}
+	if len(bars) >= MaxBars {
+		return nil, 0, fmt.Errorf("%w: %d bars", ErrInvalidBar, len(bars)+1)
+	}
+	for i, b := range bars {
+		t.bars[i] = stepsOfBits(b)
+	}
+	if probability != nil && len(probability) != stepsLength {
+		return nil, 0, fmt.Errorf("expected %d probabilities", stepsLength)
+	}
+	for i, p := range probability {
+		if err := t.SetProbability(i, p); err != nil {
+			return nil, 0, err
+		}
+	}
+	if conditions != nil && len(conditions) != stepsLength {
+		return nil, 0, fmt.Errorf("expected %d conditions", stepsLength)
+	}
+	for i, s := range conditions {
+		c, err := ParseCondition(s)
+		if err != nil {
+			return nil, 0, err
+		}
+		t.SetCondition(i, c)
+	}
+	if ratchet != nil && len(ratchet) != stepsLength {
+		return nil, 0, fmt.Errorf("expected %d ratchet counts", stepsLength)
+	}
+	for i, n := range ratchet {
+		if err := t.SetRatchet(i, int(n)); err != nil {
+			return nil, 0, err
+		}
+	}
+	if err := t.SetLength(int(min(length, math.MaxUint8+1))); err != nil {
+		return nil, 0, err
+	}
+	if err := t.SetChokeGroup(int(min(choke, math.MaxUint8+1))); err != nil {
+		return nil, 0, err
+	}
+	if err := t.SetNudge(nudge); err != nil {
+		return nil, 0, err
+	}
+	if role != "" {
+		r, err := ParseRole(role)
+		if err != nil {
+			return nil, 0, err
+		}
+		t.role = r
+	}
+	for _, a := range aliases {
+		if len(a) > math.MaxUint8 {
+			return nil, 0, ErrNameTooLong
+		}
+	}
+	t.aliases = joinShortStrings(aliases)
+	if err := t.SetLabels(labels...); err != nil {
+		return nil, 0, err
+	}
+	return t, len(bars), nil
+}
+
+func labelFromProto(data []byte) (Label, error) {
+	var l Label
+	err := readProto(data, func(f protoField) error {
+		switch {
+		case f.is(1, wireBytes):
+			l.Name = string(f.data)
+		case f.is(2, wireBytes):
+			l.Color = string(f.data)
+		}
+		return nil
+	})
+	return l, err
+}
+
+// protoMessage builds a message in the protocol buffer wire format. Fields
+// with the default value are left out like proto3 does.
+type protoMessage struct {
+	bytes.Buffer
+}
+
+func (m *protoMessage) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	m.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (m *protoMessage) key(field int, wireType int) {
+	m.uvarint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (m *protoMessage) varint(field int, v uint64) {
+	if v != 0 {
+		m.key(field, wireVarint)
+		m.uvarint(v)
+	}
+}
+
+func (m *protoMessage) bool(field int, v bool) {
+	if v {
+		m.varint(field, 1)
+	}
+}
+
+func (m *protoMessage) fixed32(field int, v uint32) {
+	if v != 0 {
+		m.key(field, wireFixed32)
+		binary.Write(m, binary.LittleEndian, v)
+	}
+}
+
+// bytes writes a length delimited field. Empty values are only written for
+// repeated fields.
+func (m *protoMessage) bytes(field int, b []byte, repeated bool) {
+	if len(b) > 0 || repeated {
+		m.key(field, wireBytes)
+		m.uvarint(uint64(len(b)))
+		m.Write(b)
+	}
+}
+
+func (m *protoMessage) string(field int, s string) {
+	m.bytes(field, []byte(s), false)
+}
+
+// message writes an embedded message, even when it is empty.
+func (m *protoMessage) message(field int, sub protoMessage) {
+	m.bytes(field, sub.Bytes(), true)
+}
+
+// protoField is a single field read from a message.
+type protoField struct {
+	number   int
+	wireType int
+	value    uint64 // varint and fixed values
+	data     []byte // length delimited values
+}
+
+func (f protoField) is(number, wireType int) bool {
+	return f.number == number && f.wireType == wireType
+}
+
+// readProto calls fn for every field of the message in wire order.
+func readProto(b []byte, fn func(protoField) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errProtoTruncated
+		}
+		b = b[n:]
+		f := protoField{number: int(key >> 3), wireType: int(key & 7)}
+		if f.number == 0 {
+			return errors.New("invalid field number 0")
+		}
+		switch f.wireType {
+		case wireVarint:
+			if f.value, n = binary.Uvarint(b); n <= 0 {
+				return errProtoTruncated
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return errProtoTruncated
+			}
+			f.value, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return errProtoTruncated
+			}
+			f.value, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return errProtoTruncated
+			}
+			f.data, b = b[n:n+int(size)], b[n+int(size):]
+		default:
+			return fmt.Errorf("unsupported wire type %d of field %d", f.wireType, f.number)
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
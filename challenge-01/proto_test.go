@@ -0,0 +1,113 @@
+package drum
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestProto(t *testing.T) {
+	track, _ := NewTrack(1, "k", Steps{true})
+	p, _ := NewPattern("a", 120, track)
+	// version "a", tempo 120 as float, track with id 1, name "k" and step 0
+	exp := []byte{0x0a, 1, 'a', 0x15, 0, 0, 0xf0, 0x42, 0x1a, 7, 0x08, 1, 0x12, 1, 'k', 0x18, 1}
+	if got := ToProto(p); !bytes.Equal(got, exp) {
+		t.Errorf("Expected % x but got % x", exp, got)
+	}
+
+	p, err := DecodeFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetSwing(20)
+	p.SetMetadata(Metadata{Title: "Groove", Author: "alpe", Tags: []string{"house"}, Created: time.Unix(1430000000, 0).UTC()})
+	p.tracks[0].SetVelocity(0, 64)
+	p.tracks[1].SetTiming(2, -0.25)
+	p.tracks[2].SetVolume(0)
+	p.tracks[2].SetPan(-12)
+	p.tracks[3].SetDisplay(Display{Color: "#ff0000", Icon: "hat"})
+	p.tracks[3].Mute()
+	p.SetTimeSignature(TimeSignature{7, 8})
+	p.SetTempoMap(TempoMap{{Step: 8, Tempo: 140, Ramp: true}})
+	p.SetLabels(Label{"live-set", "#00ff00"})
+	p.AddBar(0)
+	p.tracks[0].SetBarStep(1, 3, true)
+	p.SetBarOrder([]int{0, 0, 1})
+	p.tracks[0].SetProbability(4, 50)
+	p.tracks[0].SetCondition(8, Condition{Loop: 1, Cycle: 4})
+	p.tracks[1].SetRatchet(2, 3)
+	p.tracks[1].SetLength(12)
+	p.tracks[2].SetChokeGroup(2)
+	p.tracks[2].SetNudge(0.125)
+	p.tracks[2].SetRole(RoleFX)
+	p.tracks[2].SetLabels(Label{Name: "ghost"})
+	p.RenameTrack(p.tracks[1].name, "snare 2")
+	got, err := FromProto(ToProto(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp, _ = p.MarshalJSON()
+	if data, _ := got.MarshalJSON(); !bytes.Equal(data, exp) {
+		t.Errorf("Expected %s but got %s", exp, data)
+	}
+
+	// fields unknown to this version are skipped
+	data := append(ToProto(p), 0x78, 5, 0x82, 0x01, 2, 'x', 'y')
+	if _, err := FromProto(data); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestProtoFixtures(t *testing.T) {
+	for i := 1; i <= 5; i++ {
+		p, err := DecodeFile(path.Join("fixtures", fmt.Sprintf("pattern_%d.splice", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := FromProto(ToProto(p))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := Diff(p, got); len(d) != 0 || got.Version() != p.Version() {
+			t.Errorf("pattern_%d: expected no differences but got %v", i, d)
+		}
+	}
+}
+
+func TestInvalidProto(t *testing.T) {
+	valid := ToProto(&Pattern{version: "a", tempo: 120})
+	specs := map[string][]byte{
+		"truncated":      valid[:len(valid)-1],
+		"zero tempo":     {0x0a, 1, 'a'},
+		"group":          append(valid, 0x1b),
+		"field 0":        append(valid, 0x00, 1),
+		"size":           append(valid, 0x1a, 10, 0x08),
+		"swing":          append(valid, 0x20, 101),
+		"velocity":       append(valid, 0x1a, 3, 0x22, 1, 64),
+		"volume":         append(valid, 0x1a, 3, 0x30, 0x80, 0x01),
+		"pan":            append(valid, 0x1a, 2, 0x38, 0x80),
+		"color":          append(valid, 0x1a, 5, 0x42, 3, 0x0a, 1, 'x'),
+		"timing":         append(valid, 0x1a, 11, 0x29, 0, 0, 0, 0, 0, 0, 0xe0, 0x3f),
+		"empty tag":      append(valid, 0x2a, 2, 0x1a, 0),
+		"long version":   append(valid, append([]byte{0x0a, 33}, bytes.Repeat([]byte{'v'}, 33)...)...),
+		"missing track":  append(valid, 0x1a),
+		"time signature": append(valid, 0x32, 3, '7', '/', '0'),
+		"bars":           append(valid, 0x40, 9),
+		"track bars":     append(valid, 0x1a, 3, 0x5a, 1, 1),
+		"bar order":      append(valid, 0x4a, 1, 1),
+		"probability":    append(valid, 0x1a, 3, 0x62, 1, 50),
+		"condition":      append(valid, 0x1a, 3, 0x6a, 1, 'x'),
+		"ratchet":        append(valid, 0x1a, 3, 0x72, 1, 2),
+		"length":         append(valid, 0x1a, 2, 0x78, 17),
+		"choke":          append(valid, 0x1a, 3, 0x80, 0x01, 17),
+		"role":           append(valid, 0x1a, 4, 0x92, 0x01, 1, 'x'),
+		"label":          append(valid, 0x52, 0),
+	}
+	for msg, data := range specs {
+		if _, err := FromProto(data); err == nil {
+			t.Errorf("%s: Expected error", msg)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// ErrInvalidGrid is returned for a quantization grid that does not divide
+// the 16 steps of a pattern and for resolutions Resample does not support.
+var ErrInvalidGrid = errors.New("invalid grid")
+
+// Quantize returns a copy of the pattern with every enabled step moved to
+// the closest line of a grid of the given number of steps, 1 for sixteenths
+// and 2 for eighths, and the micro timing offsets removed. When several
+// steps land on the same line, the loudest velocity is kept. Steps land on
+// lines of the track length, lines beyond it wrap to the start.
+func Quantize(p *Pattern, grid int) (*Pattern, error) {
+	if grid < 1 || stepsLength%grid != 0 {
+		return nil, fmt.Errorf("%w %d", ErrInvalidGrid, grid)
+	}
+	c := p.Clone()
+	for i, t := range c.tracks {
+		src := p.tracks[i]
+		length := p.trackLength(src)
+		t.steps, t.velocity, t.timing = Steps{}, [stepsLength]uint8{}, [stepsLength]int8{}
+		for step, enabled := range src.steps {
+			if !enabled || step >= length {
+				continue
+			}
+			pos := float64(step) + float64(src.timing[step])/ticksPerStep
+			line := int(math.Round(pos/float64(grid))) * grid % length
+			if line < 0 {
+				line += length
+			}
+			if !t.steps[line] || src.Velocity(step) > t.Velocity(line) {
+				t.velocity[line] = src.velocity[step]
+			}
+			t.steps[line] = true
+		}
+	}
+	return c, nil
+}
+
+// Density returns the share of the steps of the track loop that are
+// enabled, from 0 to 1.
+func (p *Pattern) Density(t *Track) float64 {
+	length := p.trackLength(t)
+	return float64(len(onSteps(t, length))) / float64(length)
+}
+
+// AdjustDensity adds or removes hits of the track until the given share of
+// the steps of its loop is enabled. Hits are added on the strongest free
+// positions first, beats before eighths before sixteenths, and removed from
+// the weakest positions first, the softest hits before louder ones. The
+// choice between equal positions only depends on the state of rng.
+func (p *Pattern) AdjustDensity(t *Track, density float64, rng *rand.Rand) error {
+	if math.IsNaN(density) || density < 0 || density > 1 {
+		return fmt.Errorf("invalid density %v", density)
+	}
+	length := p.trackLength(t)
+	target := int(math.Round(density * float64(length)))
+	on := onSteps(t, length)
+	for n := len(on); n < target; n++ {
+		t.steps[pickStep(t, length, false, rng)] = true
+	}
+	for n := len(on); n > target; n-- {
+		step := pickStep(t, length, true, rng)
+		t.steps[step] = false
+		t.velocity[step], t.timing[step] = 0, 0
+	}
+	return nil
+}
+
+// onSteps returns the enabled steps of the first length steps.
+func onSteps(t *Track, length int) []int {
+	var steps []int
+	for step := 0; step < length; step++ {
+		if t.steps[step] {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// pickStep returns a random step among the enabled or free steps with the
+// strongest position to add a hit or the weakest position and velocity to
+// remove one from.
+func pickStep(t *Track, length int, enabled bool, rng *rand.Rand) int {
+	var best []int
+	bestRank := 0
+	for step := 0; step < length; step++ {
+		if t.steps[step] != enabled {
+			continue
+		}
+		rank := -metricLevel(step)
+		if enabled {
+			rank = metricLevel(step)*(MaxVelocity+1) + MaxVelocity - int(t.Velocity(step))
+		}
+		switch {
+		case len(best) == 0 || rank > bestRank:
+			best, bestRank = []int{step}, rank
+		case rank == bestRank:
+			best = append(best, step)
+		}
+	}
+	return best[rng.Intn(len(best))]
+}
+
+// metricLevel returns 0 for steps on a beat, 1 for other eighths and 2 for
+// the remaining sixteenths.
+func metricLevel(step int) int {
+	switch {
+	case step%4 == 0:
+		return 0
+	case step%2 == 0:
+		return 1
+	default:
+		return 2
+	}
+}
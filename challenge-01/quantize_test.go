@@ -0,0 +1,76 @@
+package drum
+
+import (
+	"errors"
+	"math/rand"
+	"path"
+	"testing"
+)
+
+func TestQuantize(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hihat := p.tracks[3] // --x---x-x-x---x-
+	hihat.SetTiming(2, -0.5)
+	hihat.SetTiming(14, 0.25)
+	hihat.SetVelocity(6, 40)
+	hihat.SetVelocity(8, 100)
+	got, err := Quantize(p, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := got.tracks[3]; stepsString(q.steps) != "|--x-|--x-|x-x-|--x-|" || q.timing != [stepsLength]int8{} {
+		t.Errorf("Expected the timing to be removed but got %s %v", stepsString(q.steps), q.timing)
+	}
+
+	got, err = Quantize(p, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2 early is 1.5 and lands on 0, 6 and 8 both land on 8, 10 on 12 and
+	// 14 late is 14.25 and wraps to 0
+	q := got.tracks[3]
+	if stepsString(q.steps) != "|x---|----|x---|x---|" || q.Velocity(8) != 100 {
+		t.Errorf("unexpected quantized steps %s velocity %d", stepsString(q.steps), q.Velocity(8))
+	}
+	if p.tracks[3].timing[2] == 0 {
+		t.Error("Expected the pattern to be unchanged")
+	}
+	if _, err := Quantize(p, 3); !errors.Is(err, ErrInvalidGrid) {
+		t.Errorf("Expected %v but got %v", ErrInvalidGrid, err)
+	}
+}
+
+func TestAdjustDensity(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	kick, hihat := p.tracks[0], p.tracks[3]
+	if d := p.Density(kick); d != 0.25 {
+		t.Errorf("Expected a density of 0.25 but got %v", d)
+	}
+	if err := p.AdjustDensity(kick, 0.5, rng); err != nil {
+		t.Fatal(err)
+	}
+	if s := stepsString(kick.steps); s != "|x-x-|x-x-|x-x-|x-x-|" {
+		t.Errorf("Expected the eighths to be filled first but got %s", s)
+	}
+	hihat.SetVelocity(2, 30)
+	if err := p.AdjustDensity(hihat, 0.125, rng); err != nil {
+		t.Fatal(err)
+	}
+	if s := stepsString(hihat.steps); s != "|----|----|x---|--x-|" && s != "|----|--x-|x---|----|" && s != "|----|----|x-x-|----|" {
+		t.Errorf("Expected the beat and a loud eighth to be kept but got %s", s)
+	}
+	kick.SetLength(12)
+	if err := p.AdjustDensity(kick, 1, rng); err != nil || stepsString(kick.steps) != "|xxxx|xxxx|xxxx|x-x-|" {
+		t.Errorf("Expected only the loop of 12 steps to be filled, got %s %v", stepsString(kick.steps), err)
+	}
+	if err := p.AdjustDensity(kick, 1.5, rng); err == nil {
+		t.Error("Expected an error for a density above 1")
+	}
+}
@@ -0,0 +1,236 @@
+package drum
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Select parses query and returns an editable view of the addressed steps.
+// Changes made through the Selection are written back to the pattern.
+//
+// A query consists of an optional step range followed by an optional track
+// filter, for example:
+//
+//	beats 2-3 of tracks matching 'tom*'
+//	step 5 of tracks matching 'kick'
+//	beat 1
+//	tracks matching 'hh-*'
+//
+// Beats and steps are counted from 1 through the bars of the pattern, so in
+// 4/4 step 17 and beat 5 start bar B. A beat spans the steps of a beat of
+// the time signature, 4 in 4/4 and 6, a dotted quarter, in 6/8. Tracks with
+// their own length only have the steps they play before looping selected.
+// Track names are matched case insensitive with path.Match glob rules.
+// Without a range all steps of all bars are selected, without a filter all
+// tracks.
+func (p *Pattern) Select(query string) (*Selection, error) {
+	tokens, err := tokenizeQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("select %q: %v", query, err)
+	}
+	q := queryParser{tokens: tokens}
+	from, to, err := q.parseRange(p.timeSig.groupSteps(), p.Bars()*p.BarSteps())
+	if err != nil {
+		return nil, fmt.Errorf("select %q: %v", query, err)
+	}
+	glob, err := q.parseFilter()
+	if err != nil {
+		return nil, fmt.Errorf("select %q: %v", query, err)
+	}
+	if !q.done() {
+		return nil, fmt.Errorf("select %q: unexpected %q", query, q.peek())
+	}
+
+	s := Selection{p: p, from: from, to: to}
+	for _, t := range p.tracks {
+		ok, err := path.Match(glob, strings.ToLower(t.name))
+		if err != nil {
+			return nil, fmt.Errorf("select %q: %v", query, err)
+		}
+		if ok {
+			s.tracks = append(s.tracks, t)
+		}
+	}
+	return &s, nil
+}
+
+// Selection is a view of a step range over a subset of tracks of a pattern.
+// It does not copy any data, so all methods operate on the parent pattern.
+type Selection struct {
+	p        *Pattern
+	tracks   []*Track
+	from, to int // step range [from, to) through the bars
+}
+
+// Len returns the number of selected tracks.
+func (s *Selection) Len() int {
+	return len(s.tracks)
+}
+
+// Range returns the selected step interval as zero based [from, to),
+// counted through the bars of the pattern.
+func (s *Selection) Range() (from, to int) {
+	return s.from, s.to
+}
+
+// Enable turns all selected steps on.
+func (s *Selection) Enable() {
+	s.apply(func(bool) bool { return true })
+}
+
+// Disable turns all selected steps off.
+func (s *Selection) Disable() {
+	s.apply(func(bool) bool { return false })
+}
+
+// Toggle inverts all selected steps.
+func (s *Selection) Toggle() {
+	s.apply(func(v bool) bool { return !v })
+}
+
+func (s *Selection) apply(fn func(bool) bool) {
+	for _, t := range s.tracks {
+		s.each(t, func(steps *Steps, step int) {
+			steps[step] = fn(steps[step])
+		})
+	}
+}
+
+// each calls fn with the bar and step of every selected step of the track
+// played before it loops.
+func (s *Selection) each(t *Track, fn func(steps *Steps, step int)) {
+	bar := s.p.BarSteps()
+	length := s.p.trackLength(t)
+	for i := s.from; i < s.to; i++ {
+		if step := i % bar; step < length {
+			fn(t.barSteps(i/bar), step)
+		}
+	}
+}
+
+// String returns the selected tracks in the printout format with only the
+// selected steps shown and a separator between bars.
+func (s Selection) String() string {
+	var b strings.Builder
+	for _, t := range s.tracks {
+		fmt.Fprintf(&b, "(%v) %v\t|", t.id, t.name)
+		first := true
+		s.each(t, func(steps *Steps, step int) {
+			if step == 0 && !first {
+				b.WriteRune(blockSeparator)
+			}
+			first = false
+			if steps[step] {
+				b.WriteRune(symbolStepEnabled)
+			} else {
+				b.WriteRune(symbolStepDisabled)
+			}
+		})
+		b.WriteString("|\n")
+	}
+	return b.String()
+}
+
+type queryParser struct {
+	tokens []string
+	pos    int
+}
+
+func (q *queryParser) done() bool {
+	return q.pos >= len(q.tokens)
+}
+
+func (q *queryParser) peek() string {
+	if q.done() {
+		return ""
+	}
+	return q.tokens[q.pos]
+}
+
+func (q *queryParser) next() string {
+	t := q.peek()
+	q.pos++
+	return t
+}
+
+// parseRange parses "beat[s] n[-m]" or "step[s] n[-m]" into a zero based
+// step interval within the given number of steps.
+func (q *queryParser) parseRange(beat, steps int) (int, int, error) {
+	var unit int
+	switch strings.ToLower(q.peek()) {
+	case "beat", "beats":
+		unit = beat
+	case "step", "steps":
+		unit = 1
+	default:
+		return 0, steps, nil
+	}
+	q.next()
+	spec := q.next()
+	first, last := spec, spec
+	if n := strings.IndexByte(spec, '-'); n >= 0 {
+		first, last = spec[:n], spec[n+1:]
+	}
+	from, err := strconv.Atoi(first)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q", spec)
+	}
+	to, err := strconv.Atoi(last)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q", spec)
+	}
+	if from < 1 || to < from || to*unit > steps {
+		return 0, 0, fmt.Errorf("range %q out of bounds", spec)
+	}
+	return (from - 1) * unit, to * unit, nil
+}
+
+// parseFilter parses "[of] tracks matching '<glob>'" and returns the
+// lower cased glob.
+func (q *queryParser) parseFilter() (string, error) {
+	if strings.EqualFold(q.peek(), "of") {
+		q.next()
+	}
+	if q.done() {
+		return "*", nil
+	}
+	if t := strings.ToLower(q.next()); t != "tracks" && t != "track" {
+		return "", fmt.Errorf("expected tracks but got %q", t)
+	}
+	if t := strings.ToLower(q.next()); t != "matching" {
+		return "", fmt.Errorf("expected matching but got %q", t)
+	}
+	if q.done() {
+		return "", fmt.Errorf("missing track pattern")
+	}
+	return strings.ToLower(q.next()), nil
+}
+
+// tokenizeQuery splits a query at white spaces. Single or double quoted
+// strings are returned as one token without the quotes.
+func tokenizeQuery(s string) ([]string, error) {
+	var tokens []string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return tokens, nil
+		}
+		if c := s[0]; c == '\'' || c == '"' {
+			n := strings.IndexByte(s[1:], c)
+			if n < 0 {
+				return nil, fmt.Errorf("unterminated quote")
+			}
+			tokens = append(tokens, s[1:n+1])
+			s = s[n+2:]
+			continue
+		}
+		n := strings.IndexAny(s, " \t")
+		if n < 0 {
+			n = len(s)
+		}
+		tokens = append(tokens, s[:n])
+		s = s[n:]
+	}
+}
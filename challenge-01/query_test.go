@@ -0,0 +1,123 @@
+package drum
+
+import (
+	"path"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	testCases := []struct {
+		query string
+		exp   string
+	}{
+		{"beats 2-3 of tracks matching 'tom*'", ""},
+		{"beats 2-3 of tracks matching '*tom'", `(5) low-tom	|---x----|
+(12) mid-tom	|----x---|
+(9) hi-tom	|-----x--|
+`},
+		{"step 1 of tracks matching 'KICK'", "(40) kick	|x|\n"},
+		{"beat 4 tracks matching \"hh-*\"", "(3) hh-open	|--x-|\n"},
+	}
+	for _, testCase := range testCases {
+		p, err := DecodeFile(path.Join("fixtures", "pattern_3.splice"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := p.Select(testCase.query)
+		if err != nil {
+			t.Fatalf("unexpected error for '%s': %v", testCase.query, err)
+		}
+		if got := s.String(); got != testCase.exp {
+			t.Errorf("Expected '%v' but got '%v' for query '%v'", testCase.exp, got, testCase.query)
+		}
+	}
+}
+
+func TestSelectionWritesBack(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_3.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := p.Select("beat 1 of tracks matching 'hi-tom'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Enable()
+	if exp, got := "(9) hi-tom\t|xxxx|\n", s.String(); got != exp {
+		t.Errorf("Expected '%v' but got '%v'", exp, got)
+	}
+	if !p.tracks[5].steps[0] || !p.tracks[5].steps[3] || p.tracks[5].steps[4] {
+		t.Errorf("changes were not written back: %v", p.tracks[5].steps)
+	}
+}
+
+func TestSelectInvalidQuery(t *testing.T) {
+	var p Pattern
+	for _, q := range []string{"beats 0-1", "beats 4-5", "steps x", "tracks 'kick'", "beat 1 foo", "tracks matching 'kick"} {
+		if _, err := p.Select(q); err == nil {
+			t.Errorf("expected error for query '%s'", q)
+		}
+	}
+}
+
+func TestSelectBars(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.AddBar(0)
+	p.tracks[1].SetLength(12)
+	testCases := []struct {
+		query string
+		exp   string
+	}{
+		{"steps 13-20 of tracks matching 'kick'", "(0) kick\t|x---|x---|\n"},
+		{"steps 13-20 of tracks matching 'snare'", "(1) snare\t|----|\n"},
+		{"beat 8 of tracks matching 'kick'", "(0) kick\t|x---|\n"},
+	}
+	for _, testCase := range testCases {
+		s, err := p.Select(testCase.query)
+		if err != nil {
+			t.Fatalf("unexpected error for '%s': %v", testCase.query, err)
+		}
+		if got := s.String(); got != testCase.exp {
+			t.Errorf("Expected '%v' but got '%v' for query '%v'", testCase.exp, got, testCase.query)
+		}
+	}
+	if _, err := p.Select("beats 8-9"); err == nil {
+		t.Error("expected error for a range beyond the last bar")
+	}
+
+	s, err := p.Select("steps 9-32 of tracks matching 'snare'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Enable()
+	a, b := p.tracks[1].steps, p.tracks[1].bars[0]
+	if a[7] || !a[8] || !a[11] || !b[0] || !b[11] {
+		t.Errorf("Expected the steps up to the snare length enabled in both bars but got %v %v", a, b)
+	}
+}
+
+func TestSelectTimeSignature(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetTimeSignature(TimeSignature{6, 8}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := p.Select("beat 2 of tracks matching 'kick'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, to := s.Range(); from != 6 || to != 12 {
+		t.Errorf("Expected the dotted quarter [6, 12) but got [%d, %d)", from, to)
+	}
+	if exp, got := "(0) kick\t|--x---|\n", s.String(); got != exp {
+		t.Errorf("Expected '%v' but got '%v'", exp, got)
+	}
+	if _, err := p.Select("beats 2-3"); err == nil {
+		t.Error("expected error for a range beyond the bar")
+	}
+}
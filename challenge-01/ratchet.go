@@ -0,0 +1,75 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+)
+
+var chunkRatchet = chunkID{'R', 'T', 'C', 'H'}
+
+// FeatureRatchet is the per step ratchet chunk.
+const FeatureRatchet Feature = "ratchet"
+
+// MaxRatchet is the most triggers a step can be split into.
+const MaxRatchet = 4
+
+// ErrInvalidRatchet is returned for a ratchet count outside of 1 and
+// MaxRatchet.
+var ErrInvalidRatchet = errors.New("invalid ratchet")
+
+// Ratchet returns the number of times the step is triggered, evenly spaced
+// within the step, for rolls and flams. It is 1 unless set.
+func (t *Track) Ratchet(step int) int {
+	if n := t.ratchet[step]; n != 0 {
+		return int(n)
+	}
+	return 1
+}
+
+// SetRatchet sets the number of times the step is triggered from 1 to
+// MaxRatchet.
+func (t *Track) SetRatchet(step, n int) error {
+	if step < 0 || step >= stepsLength {
+		return ErrStepOutOfRange
+	}
+	if n < 1 || n > MaxRatchet {
+		return ErrInvalidRatchet
+	}
+	if n == 1 {
+		n = 0
+	}
+	t.ratchet[step] = uint8(n)
+	return nil
+}
+
+// hasRatchet reports whether any step of the track has a ratchet set.
+func (t *Track) hasRatchet() bool {
+	return t.ratchet != [stepsLength]uint8{}
+}
+
+// The ratchet chunk is a step chunk like the velocity chunk with the number
+// of triggers per step, 0 meaning 1.
+
+func decodeRatchetChunk(data []byte, p *Pattern) error {
+	return decodeStepChunk(data, p, func(t *Track, values []byte) error {
+		for i, v := range values {
+			if v > MaxRatchet {
+				return fmt.Errorf("invalid ratchet %d", v)
+			}
+			t.ratchet[i] = v
+		}
+		return nil
+	})
+}
+
+func encodeRatchetChunk(p *Pattern) []byte {
+	return encodeStepChunk(p, (*Track).hasRatchet, func(t *Track) []byte {
+		return t.ratchet[:]
+	})
+}
+
+func clearRatchet(p *Pattern) {
+	for _, t := range p.tracks {
+		t.ratchet = [stepsLength]uint8{}
+	}
+}
@@ -0,0 +1,68 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"testing"
+)
+
+func TestRatchet(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// (1) snare |----|x---|----|x---|
+	snare := p.tracks[1]
+	if err := snare.SetRatchet(4, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := snare.SetRatchet(12, MaxRatchet+1); err != ErrInvalidRatchet {
+		t.Errorf("Expected invalid ratchet but got %v", err)
+	}
+	if snare.Ratchet(4) != 3 || snare.Ratchet(12) != 1 {
+		t.Errorf("Unexpected ratchets %v", snare.ratchet)
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(p) {
+		t.Errorf("Expected ratchets to round trip but got %v", Diff(p, decoded))
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Pattern
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !fromJSON.Equal(p) {
+		t.Errorf("Expected ratchets to round trip JSON but got %v", Diff(p, &fromJSON))
+	}
+
+	var ticks []int
+	for _, e := range p.schedule(1) {
+		if e.track == 1 {
+			ticks = append(ticks, e.tick)
+		}
+	}
+	if exp := []int{96, 104, 112, 288}; len(ticks) != len(exp) || ticks[0] != exp[0] || ticks[1] != exp[1] || ticks[2] != exp[2] || ticks[3] != exp[3] {
+		t.Errorf("Expected snare at %v but got %v", exp, ticks)
+	}
+
+	buf.Reset()
+	if err := WriteMIDI(&buf, p, 1); err != nil {
+		t.Fatal(err)
+	}
+	// snare (38) note on, 4 ticks, note off
+	if !bytes.Contains(buf.Bytes(), []byte{0x99, 38, MaxVelocity, 4, 0x89, 38, 0}) {
+		t.Errorf("Expected shortened notes for the ratchet in % x", buf.Bytes())
+	}
+}
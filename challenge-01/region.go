@@ -0,0 +1,80 @@
+package drum
+
+import (
+	"fmt"
+	"time"
+)
+
+// loopRegion is the range of steps looped by a player, both included.
+type loopRegion struct {
+	from, to int
+}
+
+// SetLoopRegion makes the player loop the steps from to to, counted from 0
+// and both included, like 8 to 15 to rehearse the second half of the bar.
+// The end of the region counts as the end of the bar for queued patterns,
+// songs and conditions. Playing continues up to the region when the
+// position is outside of it. Regions beyond the bar of the pattern playing
+// are ignored.
+func (pl *Player) SetLoopRegion(from, to int) error {
+	if from < 0 || to < from || to >= stepsLength {
+		return fmt.Errorf("loop region %d to %d out of range", from, to)
+	}
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.region = &loopRegion{from, to}
+	return nil
+}
+
+// ClearLoopRegion makes the player play the whole bar again.
+func (pl *Player) ClearLoopRegion() {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.region = nil
+}
+
+// WithCountIn makes Play start with the bars counted in by a metronome,
+// see Metronome. The steps of the count in have StepEvent.CountIn set and
+// the click track as the only track.
+func WithCountIn(bars int) PlayerOption {
+	return func(pl *Player) { pl.countIn = bars }
+}
+
+// startCountIn starts counting in the bars of WithCountIn.
+func (pl *Player) startCountIn() {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	if pl.countIn <= 0 || pl.pattern == nil && pl.synced == nil {
+		return
+	}
+	p := pl.pattern
+	if pl.synced != nil {
+		p = pl.synced.Load()
+	}
+	click, err := Metronome(p, MetronomeConfig{Accent: true})
+	if err != nil {
+		return
+	}
+	pl.click, pl.countdown = click, pl.countIn*p.BarSteps()
+}
+
+// countInStep returns the event of the next step of the count in and the
+// time until the step after it.
+func (pl *Player) countInStep(at time.Time, tempo float32) (StepEvent, time.Duration, error) {
+	if tempo <= 0 {
+		return StepEvent{}, 0, ErrInvalidTempo
+	}
+	bar := pl.pattern.BarSteps()
+	step := (bar - pl.countdown%bar) % bar
+	pl.countdown--
+	d := stepDuration(tempo)
+	pl.unswung, pl.late = d, false
+	pl.current = Transport{Step: step, BarSteps: bar, StepLength: d, Tempo: tempo, CountIn: true, at: at}
+	ev := StepEvent{Step: step, Section: pl.section, Time: at, CountIn: true}
+	if pl.click.steps[step] {
+		ev.Tracks = []*Track{pl.click}
+		ev.Velocities = []uint8{pl.click.Velocity(step)}
+		ev.Offsets = []time.Duration{0}
+	}
+	return ev, d, nil
+}
@@ -0,0 +1,81 @@
+package drum
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLoopRegion(t *testing.T) {
+	a := &Pattern{tempo: 120, tracks: []*Track{{name: "kick", steps: Steps{true}}}}
+	b := &Pattern{tempo: 120, tracks: []*Track{{name: "snare", steps: Steps{true}}}}
+	pl := NewPlayer(a, nil)
+	if err := pl.SetLoopRegion(4, 16); err == nil {
+		t.Error("Expected an error for a region beyond the bar")
+	}
+	if err := pl.SetLoopRegion(12, 13); err != nil {
+		t.Fatal(err)
+	}
+	pl.Seek(10)
+	var steps []int
+	for i := 0; i < 6; i++ {
+		if i == 3 {
+			pl.QueuePattern(b)
+			if tr := pl.Transport(time.Now()); !tr.Looping || tr.LoopFrom != 12 || tr.LoopTo != 13 || !tr.Queued {
+				t.Errorf("unexpected transport %+v", tr)
+			}
+		}
+		ev, _, err := pl.advance(time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		steps = append(steps, ev.Step)
+	}
+	if exp := "[10 11 12 13 12 13]"; fmt.Sprint(steps) != exp {
+		t.Errorf("Expected steps %s but got %v", exp, steps)
+	}
+	// the queued pattern starts at the end of the region
+	if pl.pattern != b || pl.loop != 2 {
+		t.Errorf("Expected the queued pattern after 2 loops but got %d loops", pl.loop)
+	}
+	pl.ClearLoopRegion()
+	for i := 0; i < 3; i++ {
+		ev, _, _ := pl.advance(time.Now())
+		steps = append(steps, ev.Step)
+	}
+	if exp := "[10 11 12 13 12 13 12 13 14]"; fmt.Sprint(steps) != exp {
+		t.Errorf("Expected steps %s but got %v", exp, steps)
+	}
+}
+
+func TestCountIn(t *testing.T) {
+	p := &Pattern{tempo: 120, tracks: []*Track{{name: "kick", steps: Steps{true}}}}
+	var clicks []string
+	bars := 0
+	stop := make(chan struct{})
+	pl := NewPlayer(p, nil, WithCountIn(1))
+	pl.handler = func(ev StepEvent) {
+		if ev.CountIn {
+			if tr := pl.Transport(time.Now()); !tr.CountIn {
+				t.Errorf("Expected the transport counting in at step %d", ev.Step)
+			}
+			if len(ev.Tracks) > 0 {
+				clicks = append(clicks, fmt.Sprintf("%d:%d", ev.Step, ev.Velocities[0]))
+			}
+			return
+		}
+		if len(ev.Tracks) != 1 || ev.Tracks[0].Name() != "kick" {
+			t.Errorf("Expected the pattern after the count in but got %v", ev.Tracks)
+		}
+		close(stop)
+		bars++
+	}
+	// a fast tempo keeps the test short
+	pl.SetTempo(MaxTempo)
+	if err := pl.Play(stop); err != nil {
+		t.Fatal(err)
+	}
+	if exp := fmt.Sprintf("[0:%d 4:%d 8:%d 12:%d]", MaxVelocity, metronomeBeatVelocity, metronomeBeatVelocity, metronomeBeatVelocity); fmt.Sprint(clicks) != exp || bars != 1 {
+		t.Errorf("Expected the clicks %s but got %v", exp, clicks)
+	}
+}
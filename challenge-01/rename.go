@@ -0,0 +1,193 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var chunkAlias = chunkID{'A', 'L', 'I', 'S'}
+
+// FeatureAlias is the chunk of the previous names of renamed tracks.
+const FeatureAlias Feature = "alias"
+
+var (
+	// ErrTrackNotFound is returned by RenameTrack for unknown track names.
+	ErrTrackNotFound = errors.New("track not found")
+	// ErrDuplicateTrackName is returned by RenameTrack for names of other
+	// tracks.
+	ErrDuplicateTrackName = errors.New("duplicate track name")
+)
+
+// Aliases returns the previous names of the track set by RenameTrack,
+// oldest first.
+func (t *Track) Aliases() []string {
+	return splitShortStrings(t.aliases)
+}
+
+// RenameOption configures RenameTrack.
+type RenameOption func(*renameOptions)
+
+type renameOptions struct {
+	kit *Kit
+	gm  *GMMap
+}
+
+// WithRenameKit makes RenameTrack alias the new name to the sample the kit
+// mapped the track to, so that the track keeps its sample and choke group.
+func WithRenameKit(k *Kit) RenameOption {
+	return func(o *renameOptions) {
+		o.kit = k
+	}
+}
+
+// WithRenameGMMap makes RenameTrack alias the new name to the General MIDI
+// note the old name resolved to in m, so that exports keep the note.
+func WithRenameGMMap(m *GMMap) RenameOption {
+	return func(o *renameOptions) {
+		o.gm = m
+	}
+}
+
+// RenameTrack renames the track found by TrackByName(old) and records the
+// old name in its aliases. A role inferred from the old name is kept as
+// override when the new name infers another one. The new name must not be
+// longer than 255 bytes nor name another track. Renaming to the same name
+// does nothing.
+//
+// Kits and GM maps given as options are updated to map the new name like
+// the old one. Library indexes of the pattern are updated with
+// library.Index.Update after saving it.
+func (p *Pattern) RenameTrack(old, new string, opts ...RenameOption) error {
+	var o renameOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	t := p.TrackByName(old)
+	if t == nil {
+		return fmt.Errorf("%w %q", ErrTrackNotFound, old)
+	}
+	if len(new) > math.MaxUint8 {
+		return ErrNameTooLong
+	}
+	if other := p.TrackByName(new); other != nil && other != t {
+		return fmt.Errorf("%w %q", ErrDuplicateTrackName, new)
+	}
+	prev := t.name
+	if prev == new {
+		return nil
+	}
+	if t.role == RoleAuto && RoleOf(new) != RoleOf(prev) {
+		t.role = RoleOf(prev)
+	}
+	if o.gm != nil {
+		if note, ok := o.gm.Resolve(prev); ok {
+			if n, ok := o.gm.Resolve(new); !ok || n != note {
+				o.gm.Alias(new, note)
+			}
+		}
+	}
+	var sample *Sample
+	var choke int
+	if o.kit != nil {
+		sample, _ = o.kit.Sample(t)
+		choke = o.kit.ChokeGroup(t)
+	}
+	t.name = new
+	t.addAlias(prev)
+	if o.kit != nil {
+		o.kit.keepMapping(t, prev, sample, choke)
+	}
+	return nil
+}
+
+// addAlias appends the name to the aliases, dropping earlier entries of it
+// and of the current name. The oldest aliases are dropped beyond 255.
+func (t *Track) addAlias(name string) {
+	key, cur := normalizeName(name), normalizeName(t.name)
+	var aliases []string
+	for _, a := range splitShortStrings(t.aliases) {
+		if k := normalizeName(a); k != key && k != cur {
+			aliases = append(aliases, a)
+		}
+	}
+	aliases = append(aliases, name)
+	if n := len(aliases) - math.MaxUint8; n > 0 {
+		aliases = aliases[n:]
+	}
+	t.aliases = joinShortStrings(aliases)
+}
+
+// keepMapping makes the renamed track use the sample and choke group it had
+// under the previous name.
+func (k *Kit) keepMapping(t *Track, prev string, s *Sample, choke int) {
+	key := normalizeName(prev)
+	if got, _ := k.Sample(t); s != nil && got != s {
+		switch {
+		case k.byName[key] == s:
+			k.Alias(t.name, prev)
+		case k.byName[k.aliases[key]] == s:
+			k.aliases[normalizeName(t.name)] = k.aliases[key]
+		default:
+			// found by the General MIDI note of the previous name
+			k.Add(t.name, s)
+		}
+	}
+	if k.ChokeGroup(t) != choke {
+		k.chokes[normalizeName(t.name)] = choke
+	}
+}
+
+// The alias chunk holds the aliases of the tracks having any:
+//
+//	|Track index (2 bytes)|Alias count (1 byte)|Length (1 byte)|Alias|...
+func decodeAliasChunk(data []byte, p *Pattern) error {
+	r := bytes.NewReader(data)
+	for r.Len() > 0 {
+		var index uint16
+		if err := binary.Read(r, binary.LittleEndian, &index); err != nil {
+			return fmt.Errorf("parse track index: %v", err)
+		}
+		if int(index) >= len(p.tracks) {
+			return fmt.Errorf("track index %d out of range", index)
+		}
+		n, err := r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("parse alias count: %v", err)
+		}
+		aliases := make([]string, 0, n)
+		for i := 0; i < int(n); i++ {
+			a, err := readShortString(r)
+			if err != nil {
+				return fmt.Errorf("parse alias: %v", err)
+			}
+			aliases = append(aliases, a)
+		}
+		p.tracks[index].aliases = joinShortStrings(aliases)
+	}
+	return nil
+}
+
+func encodeAliasChunk(p *Pattern) []byte {
+	var buf bytes.Buffer
+	for i, t := range p.tracks {
+		if t.aliases == "" {
+			continue
+		}
+		buf.Write(binary.LittleEndian.AppendUint16(nil, uint16(i)))
+		buf.WriteByte(uint8(len(splitShortStrings(t.aliases))))
+		buf.WriteString(t.aliases)
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+func clearAliases(p *Pattern) {
+	for _, t := range p.tracks {
+		t.aliases = ""
+	}
+}
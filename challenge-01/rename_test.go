@@ -0,0 +1,108 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRenameTrack(t *testing.T) {
+	p := &Pattern{version: "0.909", tempo: 120, tracks: []*Track{{name: "kick"}, {name: "sd"}, {name: "Boom"}}}
+	kick, snare := &Sample{}, &Sample{}
+	k := NewKit("test")
+	k.Add("kick", kick)
+	k.Add("snare", snare)
+	k.Alias("sd", "snare")
+	if err := k.SetChokeGroup("kick", 2); err != nil {
+		t.Fatal(err)
+	}
+	m := NewGMMap()
+	note, _ := m.Resolve("kick")
+
+	if err := p.RenameTrack("Kick", "Thump", WithRenameKit(k), WithRenameGMMap(m)); err != nil {
+		t.Fatal(err)
+	}
+	thump := p.tracks[0]
+	if thump.Name() != "Thump" || fmt.Sprint(thump.Aliases()) != "[kick]" {
+		t.Errorf("Expected Thump with alias kick but got %q %v", thump.Name(), thump.Aliases())
+	}
+	if thump.Role() != RoleKick {
+		t.Errorf("Expected role %v but got %v", RoleKick, thump.Role())
+	}
+	if s, _ := k.Sample(thump); s != kick {
+		t.Errorf("Expected the kick sample for %q", thump.Name())
+	}
+	if got := k.ChokeGroup(thump); got != 2 {
+		t.Errorf("Expected choke group 2 but got %d", got)
+	}
+	if got, _ := m.Resolve("Thump"); got != note {
+		t.Errorf("Expected note %d but got %d", note, got)
+	}
+
+	if err := p.RenameTrack("sd", "rim", WithRenameKit(k)); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := k.Sample(p.tracks[1]); s != snare {
+		t.Errorf("Expected the snare sample for %q", p.tracks[1].Name())
+	}
+
+	if err := p.RenameTrack("thump", "kick"); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(thump.Aliases()); got != "[Thump]" {
+		t.Errorf("Expected aliases [Thump] but got %v", got)
+	}
+
+	specs := map[string]struct {
+		old, new string
+		exp      error
+	}{
+		"unknown":   {"clap", "hat", ErrTrackNotFound},
+		"duplicate": {"kick", "boom", ErrDuplicateTrackName},
+		"too long":  {"kick", strings.Repeat("k", 256), ErrNameTooLong},
+	}
+	for msg, spec := range specs {
+		if err := p.RenameTrack(spec.old, spec.new); !errors.Is(err, spec.exp) {
+			t.Errorf("%s: Expected %v but got %v", msg, spec.exp, err)
+		}
+	}
+}
+
+func TestAliasRoundTrip(t *testing.T) {
+	p := &Pattern{version: "0.909", tempo: 120, tracks: []*Track{{name: "kick"}, {name: "snare"}}}
+	for _, name := range []string{"bd", "Bass Drum", ""} {
+		if err := p.RenameTrack(p.tracks[0].Name(), name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(p) || fmt.Sprint(decoded.tracks[0].Aliases()) != "[kick bd Bass Drum]" {
+		t.Errorf("Expected the aliases but got %v", decoded.tracks[0].Aliases())
+	}
+	if exp, got := "[alias role]", fmt.Sprint(p.Features()); got != exp {
+		t.Errorf("Expected features %v but got %v", exp, got)
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Pattern
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !fromJSON.Equal(p) {
+		t.Errorf("Expected %v but got %v", p, &fromJSON)
+	}
+}
@@ -0,0 +1,268 @@
+package drum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrSampleRate is returned when the samples of a rendering differ in their
+// sample rate.
+var ErrSampleRate = errors.New("sample rates differ")
+
+// RenderOption configures the rendering.
+type RenderOption func(*renderOptions)
+
+// renderChunk is the number of frames mixed at once by a worker.
+const renderChunk = 4096
+
+type renderOptions struct {
+	progress func(done, total int)
+	workers  int // see WithRenderWorkers
+	ducking  *Ducking
+	effects  map[string]TrackEffects // by normalized track name
+	limiter  *float64                // ceiling in dBFS
+}
+
+// WithRenderProgress makes the rendering call fn with the number of notes
+// mixed so far and the total after every note, see WithProgress. Notes are
+// mixed in chunks by several workers, so they are not reported in order.
+func WithRenderProgress(fn func(done, total int)) RenderOption {
+	return func(o *renderOptions) {
+		o.progress = fn
+	}
+}
+
+// WithRenderWorkers sets the number of goroutines mixing chunks of the
+// rendering, GOMAXPROCS by default. The result is the same for any number.
+func WithRenderWorkers(n int) RenderOption {
+	return func(o *renderOptions) {
+		o.workers = n
+	}
+}
+
+// Render mixes the pattern repeated for the number of bars into a stereo
+// sample using the samples of the kit. Every step is scaled by its
+// velocity and the track volume and placed by the track pan. Swing, timing
+// offsets, the tempo map and mute and solo states are applied. Sounds are
+// cut by the tracks of their choke group, see Track.SetChokeGroup. The
+// result has exactly the length of the bars so that it loops, sounds
+// ringing longer are cut. Samples with a start offset in the kit start
+// early, so that their sound lands on the grid. Tracks can be ducked by the kick and processed
+// by effects, see WithDucking, WithTrackEffects and WithLimiter.
+func Render(p *Pattern, kit *Kit, bars int, opts ...RenderOption) (*Sample, error) {
+	return RenderContext(context.Background(), p, kit, bars, opts...)
+}
+
+// RenderContext renders like Render and stops with the context error when
+// the context is done before all steps are mixed.
+func RenderContext(ctx context.Context, p *Pattern, kit *Kit, bars int, opts ...RenderOption) (*Sample, error) {
+	var o renderOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := kit.Validate(p); err != nil {
+		return nil, fmt.Errorf("render: %w", err)
+	}
+	rate := 0
+	for _, t := range p.tracks {
+		s, _ := kit.Sample(t)
+		if rate == 0 {
+			rate = s.Rate
+		} else if s.Rate != rate {
+			return nil, ErrSampleRate
+		}
+	}
+	if rate == 0 {
+		return nil, errors.New("render: no tracks")
+	}
+	if d := o.ducking; d != nil {
+		if err := d.validate(rate); err != nil {
+			return nil, err
+		}
+	}
+	for _, fx := range o.effects {
+		if err := fx.validate(rate); err != nil {
+			return nil, err
+		}
+	}
+	if c := o.limiter; c != nil && (math.IsNaN(*c) || math.IsInf(*c, 0)) {
+		return nil, fmt.Errorf("render: invalid limiter ceiling %v", *c)
+	}
+	// frames per tick
+	tickLength := 60 / tempo64(p.tempo) / ticksPerBeat * float64(rate)
+	frameAt := func(tick int) int {
+		if len(p.tempoMap) == 0 {
+			return int(math.Round(float64(tick) * tickLength))
+		}
+		return int(math.Round(p.timeAt(p.tempo, float64(tick)/ticksPerStep).Seconds() * float64(rate)))
+	}
+	frames := frameAt(bars * p.BarSteps() * ticksPerStep)
+	newSample := func() *Sample {
+		return &Sample{Rate: rate, Left: make([]float32, frames), Right: make([]float32, frames)}
+	}
+	out := newSample()
+	// ducked tracks are mixed into their own bus first, tracks with effects
+	// into a sample of their own
+	bus := out
+	var hits []int
+	if o.ducking != nil {
+		bus = newSample()
+	}
+	mixOf := func(t *Track) *Sample {
+		if o.ducking != nil && o.ducking.ducks(t) {
+			return bus
+		}
+		return out
+	}
+	processed := make([]*Sample, len(p.tracks))
+	events := p.schedule(bars)
+	cuts := chokeFrames(p, kit, events, frameAt)
+	progress := newProgress(o.progress, len(events))
+	notes := make([]renderNote, 0, len(events))
+	for n, e := range events {
+		t := p.tracks[e.track]
+		s, _ := kit.Sample(t)
+		at := frameAt(e.tick)
+		if d := o.ducking; d != nil && t.Role() == d.trigger() {
+			hits = append(hits, at)
+		}
+		// the sample starts early by its leading silence, which is cut at the
+		// start of the rendering
+		start := at - kit.startFrames(s)
+		dst := mixOf(t)
+		if _, ok := o.effects[normalizeName(t.name)]; ok {
+			if processed[e.track] == nil {
+				processed[e.track] = newSample()
+			}
+			dst = processed[e.track]
+		}
+		v := newVoice(t, s, e.velocity)
+		if cuts[n] >= 0 {
+			v.cut = cuts[n] - start
+		}
+		end := min(start+v.length(), frames)
+		if start >= end {
+			progress.step()
+			continue
+		}
+		notes = append(notes, renderNote{v, start, end, dst, int32((end-1)/renderChunk - max(start, 0)/renderChunk + 1)})
+	}
+	if err := mixChunks(ctx, notes, frames, o.workers, progress); err != nil {
+		return nil, err
+	}
+	var wg sync.WaitGroup
+	for i, s := range processed {
+		if s != nil {
+			wg.Add(1)
+			go func(fx TrackEffects, s *Sample) {
+				defer wg.Done()
+				fx.apply(s)
+			}(o.effects[normalizeName(p.tracks[i].name)], s)
+		}
+	}
+	wg.Wait()
+	for i, s := range processed {
+		if s == nil {
+			continue
+		}
+		t := p.tracks[i]
+		dst := mixOf(t)
+		for f := range s.Left {
+			dst.Left[f] += s.Left[f]
+			dst.Right[f] += s.Right[f]
+		}
+	}
+	if o.ducking != nil {
+		o.ducking.apply(out, bus, hits)
+	}
+	if o.limiter != nil {
+		limit(out, *o.limiter)
+	}
+	return out, nil
+}
+
+// renderNote is a voice sounding from the start frame up to the end frame of
+// a rendering, mixed into dst.
+type renderNote struct {
+	voice      voice
+	start, end int
+	dst        *Sample
+	chunks     int32 // left to mix, the note is done at 0
+}
+
+// mixChunks mixes the notes in chunks of renderChunk frames on the workers.
+// Every frame adds the notes in their order whichever worker mixes it, so
+// the result does not depend on the number of workers.
+func mixChunks(ctx context.Context, notes []renderNote, frames, workers int, progress *progress) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	chunks := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for from := range chunks {
+				to := min(from+renderChunk, frames)
+				for i := range notes {
+					n := &notes[i]
+					if n.start >= to || n.end <= from {
+						continue
+					}
+					v := n.voice
+					first := max(from, n.start)
+					v.pos = first - n.start
+					v.mix(n.dst.Left[first:to], n.dst.Right[first:to])
+					if atomic.AddInt32(&n.chunks, -1) == 0 {
+						progress.step()
+					}
+				}
+			}
+		}()
+	}
+	var err error
+	for from := 0; from < frames && err == nil; from += renderChunk {
+		select {
+		case chunks <- from:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	close(chunks)
+	wg.Wait()
+	if err == nil {
+		err = ctx.Err()
+	}
+	return err
+}
+
+// RenderWAV renders the pattern, see Render, and writes it as WAV file to w.
+func RenderWAV(w io.Writer, p *Pattern, kit *Kit, bars int, opts ...RenderOption) error {
+	s, err := Render(p, kit, bars, opts...)
+	if err != nil {
+		return err
+	}
+	return WriteWAV(w, s)
+}
+
+// RenderWAVFile renders the pattern, see Render, and writes it as WAV file
+// to path.
+func RenderWAVFile(path string, p *Pattern, kit *Kit, bars int, opts ...RenderOption) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := RenderWAV(file, p, kit, bars, opts...); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
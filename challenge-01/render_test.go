@@ -0,0 +1,140 @@
+package drum
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	// 120 BPM at 800 frames per second gives 100 frames per step
+	p := &Pattern{tempo: 120, tracks: []*Track{
+		{name: "kick", steps: Steps{true}},
+		{name: "hat", steps: Steps{false, true}},
+	}}
+	p.tracks[1].SetPan(MinPan)
+	p.tracks[1].SetVelocity(1, 64)
+	click := &Sample{Rate: 800, Left: []float32{1, 0.5}, Right: []float32{1, 0.5}}
+	kit := NewKit("test")
+	kit.Add("kick", click)
+	out, err := Render(p, kit, 1)
+	if !errors.Is(err, ErrMissingSample) {
+		t.Errorf("expected error '%s' but got '%v'", ErrMissingSample, err)
+	}
+	kit.Add("hat", click)
+	out, err = Render(p, kit, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := 1600, out.Frames(); got != exp {
+		t.Fatalf("Expected %d frames but got %d", exp, got)
+	}
+	center := float32(math.Cos(math.Pi / 4 * 64 / 63.5))
+	if l, r := out.Left[0], out.Right[0]; !near(l, center) || !near(r, float32(math.Sin(math.Pi/4*64/63.5))) {
+		t.Errorf("unexpected kick level %v %v", l, r)
+	}
+	if l, r := out.Left[100], out.Right[100]; !near(l, 64.0/127) || r != 0 {
+		t.Errorf("unexpected hat level %v %v", l, r)
+	}
+}
+
+func TestWAVRoundTrip(t *testing.T) {
+	s := &Sample{Rate: 44100, Left: []float32{0, 0.5, -1, 2}, Right: []float32{0.25, 0, -0.5, -2}}
+	var buf bytes.Buffer
+	if err := WriteWAV(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadWAV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := &Sample{Rate: 44100, Left: []float32{0, 0.5, -1, 1}, Right: []float32{0.25, 0, -0.5, -1}}
+	if got.Rate != exp.Rate || got.Frames() != exp.Frames() {
+		t.Fatalf("Expected %v but got %v", exp, got)
+	}
+	for i := range exp.Left {
+		if !near(got.Left[i], exp.Left[i]) || !near(got.Right[i], exp.Right[i]) {
+			t.Errorf("Expected frame %v %v but got %v %v", exp.Left[i], exp.Right[i], got.Left[i], got.Right[i])
+		}
+	}
+}
+
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-3
+}
+
+func TestRenderContext(t *testing.T) {
+	p := &Pattern{tempo: 120, tracks: []*Track{{name: "kick", steps: Steps{true}}}}
+	kit := NewKit("test")
+	kit.Add("kick", &Sample{Rate: 800, Left: []float32{1}, Right: []float32{1}})
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := RenderContext(ctx, p, kit, 4); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := RenderContext(ctx, p, kit, 4); err != context.Canceled {
+		t.Errorf("Expected error '%v' but got '%v'", context.Canceled, err)
+	}
+}
+
+// sineKit returns a kit with a sine of its own for every track of the
+// pattern, each ringing for a second.
+func sineKit(p *Pattern) *Kit {
+	kit := NewKit("sines")
+	for i, t := range p.tracks {
+		kit.Add(t.name, sine(float64(100*(i+1))))
+	}
+	return kit
+}
+
+func TestRenderWorkers(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kit := sineKit(p)
+	opts := []RenderOption{
+		WithTrackEffects("snare", TrackEffects{LowPass: 1000, Drive: 0.5}),
+		WithTrackEffects("clap", TrackEffects{HighPass: 200}),
+		WithDucking(Ducking{Depth: 0.5}),
+		WithLimiter(-1),
+	}
+	want, err := Render(p, kit, 8, append(opts, WithRenderWorkers(1))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{2, 3, 8, 0} {
+		got, err := Render(p, kit, 8, append(opts, WithRenderWorkers(workers))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected the same rendering with %d workers", workers)
+		}
+	}
+}
+
+// BenchmarkRender renders 64 bars of a pattern with different numbers of
+// workers, the time per rendering should drop near linearly up to the
+// number of cores.
+func BenchmarkRender(b *testing.B) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	kit := sineKit(p)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := Render(p, kit, 64, WithRenderWorkers(workers)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// RepairReport describes the changes made by Repair.
+type RepairReport struct {
+	DeclaredSize   int64 // payload size found in the header
+	PayloadSize    int64 // payload size of the repaired pattern
+	TruncatedTrack int   // bytes of the incomplete final track dropped
+	Padding        int   // trailing padding bytes dropped
+}
+
+// Repaired reports whether anything was changed.
+func (r RepairReport) Repaired() bool {
+	return r.DeclaredSize != r.PayloadSize || r.TruncatedTrack > 0 || r.Padding > 0
+}
+
+// String returns one line per change.
+func (r RepairReport) String() string {
+	var lines []string
+	if r.DeclaredSize != r.PayloadSize {
+		lines = append(lines, fmt.Sprintf("payload size corrected from %d to %d bytes", r.DeclaredSize, r.PayloadSize))
+	}
+	if r.TruncatedTrack > 0 {
+		lines = append(lines, fmt.Sprintf("dropped truncated final track of %d bytes", r.TruncatedTrack))
+	}
+	if r.Padding > 0 {
+		lines = append(lines, fmt.Sprintf("dropped %d bytes of padding", r.Padding))
+	}
+	if len(lines) == 0 {
+		return "no repairs needed"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Repair decodes a drum machine file from r like Decode but fixes common
+// corruptions of files pulled from the hardware's SD card first:
+//
+//   - a payload size larger than the data is recomputed from the tracks found
+//   - a final track cut off by the end of the data is dropped
+//   - trailing padding of zero or 0xff bytes is dropped
+//
+// The report tells exactly what was changed. Files that are broken otherwise
+// return the error of the decoder.
+func Repair(r io.Reader) (*Pattern, RepairReport, error) {
+	var report RepairReport
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, report, err
+	}
+	const headerLength = typeHeaderLength + 8
+	if len(data) < headerLength || !bytes.HasPrefix(data, []byte(spliceTypePattern)) {
+		// let the decoder report the error
+		p, err := DecodeBytes(data)
+		return p, report, err
+	}
+	report.DeclaredSize = int64(binary.BigEndian.Uint64(data[typeHeaderLength:]))
+	body := data[headerLength:]
+	limit := len(body)
+	sizeValid := report.DeclaredSize >= 0 && report.DeclaredSize <= int64(len(body))
+	if sizeValid {
+		limit = int(report.DeclaredSize)
+	}
+	if limit < maxVersionLength+4 {
+		return nil, report, errors.New("repair: no pattern header")
+	}
+
+	end := maxVersionLength + 4
+	for end < limit {
+		if !sizeValid && isPadding(body[end:limit]) {
+			report.Padding = limit - end
+			break
+		}
+		n := trackHeaderLength + stepsLength
+		if end+trackHeaderLength <= limit {
+			n += int(body[end+4])
+		}
+		if end+n > limit {
+			report.TruncatedTrack = limit - end
+			break
+		}
+		end += n
+	}
+	rest := body[limit:]
+	if len(rest) > 0 && isPadding(rest) {
+		report.Padding, rest = len(rest), nil
+	}
+	report.PayloadSize = int64(end)
+
+	repaired := make([]byte, 0, headerLength+end+len(rest))
+	repaired = append(repaired, data[:typeHeaderLength]...)
+	repaired = binary.BigEndian.AppendUint64(repaired, uint64(report.PayloadSize))
+	repaired = append(repaired, body[:end]...)
+	repaired = append(repaired, rest...)
+	p, err := DecodeBytes(repaired)
+	if err != nil {
+		return nil, report, err
+	}
+	return p, report, nil
+}
+
+// isPadding reports whether b consists of zero or 0xff bytes only.
+func isPadding(b []byte) bool {
+	return len(bytes.Trim(b, "\x00")) == 0 || len(bytes.Trim(b, "\xff")) == 0
+}
@@ -0,0 +1,65 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestRepair(t *testing.T) {
+	raw, err := ioutil.ReadFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	withSize := func(data []byte, size uint64) []byte {
+		data = append([]byte(nil), data...)
+		binary.BigEndian.PutUint64(data[typeHeaderLength:], size)
+		return data
+	}
+	specs := map[string]struct {
+		data   []byte
+		tracks int
+		exp    RepairReport
+	}{
+		"intact":         {raw, 6, RepairReport{197, 197, 0, 0}},
+		"size too large": {withSize(raw, 1000), 6, RepairReport{1000, 197, 0, 0}},
+		"negative size":  {withSize(raw, 1<<63), 6, RepairReport{-1 << 63, 197, 0, 0}},
+		"padding":        {append(append([]byte(nil), raw...), make([]byte, 512)...), 6, RepairReport{197, 197, 0, 512}},
+		"padded payload": {append(withSize(raw, 600), bytes.Repeat([]byte{0xff}, 300)...), 6, RepairReport{600, 197, 0, 300}},
+		"truncated":      {raw[:len(raw)-5], 5, RepairReport{197, 169, 23, 0}},
+		"truncated name": {withSize(raw[:len(raw)-20], 1000), 5, RepairReport{1000, 169, 8, 0}},
+	}
+	exp, _ := DecodeBytes(raw)
+	for msg, spec := range specs {
+		p, report, err := Repair(bytes.NewReader(spec.data))
+		if err != nil {
+			t.Fatalf("%s: %v", msg, err)
+		}
+		if report != spec.exp {
+			t.Errorf("%s: Expected report %+v but got %+v", msg, spec.exp, report)
+		}
+		if report.Repaired() == (msg == "intact") {
+			t.Errorf("%s: unexpected repaired state of %v", msg, report)
+		}
+		if len(p.tracks) != spec.tracks || !p.tracks[spec.tracks-1].Equal(exp.tracks[spec.tracks-1]) {
+			t.Errorf("%s: Expected the first %d tracks of '%v' but got '%v'", msg, spec.tracks, exp, p)
+		}
+	}
+
+	exp2 := "payload size corrected from 1000 to 169 bytes\ndropped truncated final track of 8 bytes"
+	if got := specs["truncated name"].exp.String(); got != exp2 {
+		t.Errorf("Expected '%s' but got '%s'", exp2, got)
+	}
+
+	for msg, data := range map[string][]byte{
+		"format":        []byte("SPLICX"),
+		"header":        raw[:30],
+		"invalid steps": append(raw[:len(raw)-1:len(raw)-1], 2),
+	} {
+		if _, _, err := Repair(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: Expected error", msg)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package drum
+
+import (
+	"fmt"
+	"math"
+)
+
+// maxGridSteps is the finest resolution of a Grid, a cell per tick of the
+// micro timing.
+const maxGridSteps = stepsLength * ticksPerStep
+
+// Rounding decides which cell a step lands on when it falls between the cells
+// of a grid, see WithRounding.
+type Rounding int
+
+const (
+	// RoundNearest moves steps to the closest cell, steps halfway between
+	// two cells to the later one.
+	RoundNearest Rounding = iota
+	// RoundDown moves steps to the cell at or before them.
+	RoundDown
+	// RoundUp moves steps to the cell at or after them.
+	RoundUp
+)
+
+// ResampleOption configures Resample.
+type ResampleOption func(*resampleOptions)
+
+type resampleOptions struct {
+	rounding Rounding
+}
+
+// WithRounding sets the rounding of steps between the cells of the target
+// grid, RoundNearest by default.
+func WithRounding(r Rounding) ResampleOption {
+	return func(o *resampleOptions) {
+		o.rounding = r
+	}
+}
+
+// Grid is a bar of a pattern at another resolution than the 16 steps of
+// Steps, like 8 cells for eighths or 32 for thirty-second notes, see
+// Resample.
+type Grid struct {
+	Version     string
+	Tempo       float32
+	StepsPerBar int
+	Tracks      []GridTrack
+}
+
+// GridTrack is a track of a Grid.
+type GridTrack struct {
+	ID   uint32
+	Name string
+	// Velocities holds a velocity for every cell, 0 when it is off.
+	Velocities []uint8
+}
+
+// Resample returns the pattern on a grid of stepsPerBar cells. Steps land
+// on the cell of their position including the micro timing offset, rounded
+// as set by WithRounding, and positions beyond the last cell wrap to the
+// start. When several steps land on the same cell, like when converting
+// sixteenths to eighths, the loudest velocity is kept. Extensions other
+// than the velocities and the timing are not part of the grid.
+func Resample(p *Pattern, stepsPerBar int, opts ...ResampleOption) (*Grid, error) {
+	if stepsPerBar < 1 || stepsPerBar > maxGridSteps {
+		return nil, fmt.Errorf("%w %d", ErrInvalidGrid, stepsPerBar)
+	}
+	o := newResampleOptions(opts)
+	g := &Grid{Version: p.version, Tempo: p.tempo, StepsPerBar: stepsPerBar}
+	for _, t := range p.tracks {
+		velocities := make([]uint8, stepsPerBar)
+		for step, enabled := range t.steps {
+			if enabled {
+				pos := float64(step) + float64(t.timing[step])/ticksPerStep
+				place(velocities, pos/stepsLength, t.Velocity(step), o.rounding)
+			}
+		}
+		g.Tracks = append(g.Tracks, GridTrack{ID: t.id, Name: t.name, Velocities: velocities})
+	}
+	return g, nil
+}
+
+// Resample returns the grid at another resolution, with the rounding and
+// the collisions of Resample.
+func (g *Grid) Resample(stepsPerBar int, opts ...ResampleOption) (*Grid, error) {
+	if stepsPerBar < 1 || stepsPerBar > maxGridSteps {
+		return nil, fmt.Errorf("%w %d", ErrInvalidGrid, stepsPerBar)
+	}
+	o := newResampleOptions(opts)
+	r := &Grid{Version: g.Version, Tempo: g.Tempo, StepsPerBar: stepsPerBar}
+	for _, t := range g.Tracks {
+		velocities := make([]uint8, stepsPerBar)
+		for cell, v := range t.Velocities {
+			if v != 0 {
+				place(velocities, float64(cell)/float64(len(t.Velocities)), v, o.rounding)
+			}
+		}
+		r.Tracks = append(r.Tracks, GridTrack{ID: t.ID, Name: t.Name, Velocities: velocities})
+	}
+	return r, nil
+}
+
+// Pattern returns the grid as pattern of 16 steps, resampled like by
+// Resample when the grid has another resolution.
+func (g *Grid) Pattern(opts ...ResampleOption) (*Pattern, error) {
+	sixteenths, err := g.Resample(stepsLength, opts...)
+	if err != nil {
+		return nil, err
+	}
+	tracks := make([]*Track, len(sixteenths.Tracks))
+	for i, gt := range sixteenths.Tracks {
+		t, err := NewTrack(gt.ID, gt.Name, Steps{})
+		if err != nil {
+			return nil, err
+		}
+		for step, v := range gt.Velocities {
+			if v == 0 {
+				continue
+			}
+			t.steps[step] = true
+			if err := t.SetVelocity(step, v); err != nil {
+				return nil, fmt.Errorf("track %d: %v", gt.ID, err)
+			}
+		}
+		tracks[i] = t
+	}
+	return NewPattern(g.Version, g.Tempo, tracks...)
+}
+
+func newResampleOptions(opts []ResampleOption) resampleOptions {
+	var o resampleOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// place sets the cell at the position, as a fraction of the bar, to the
+// velocity unless it holds a louder one.
+func place(velocities []uint8, pos float64, v uint8, r Rounding) {
+	n := len(velocities)
+	// round away the float error of exact positions like 3/32*16
+	x := math.Round(pos*float64(n)*1e9) / 1e9
+	var cell int
+	switch r {
+	case RoundDown:
+		cell = int(math.Floor(x))
+	case RoundUp:
+		cell = int(math.Ceil(x))
+	default:
+		cell = int(math.Floor(x + 0.5))
+	}
+	cell %= n
+	if cell < 0 {
+		cell += n
+	}
+	if v > velocities[cell] {
+		velocities[cell] = v
+	}
+}
@@ -0,0 +1,103 @@
+package drum
+
+import (
+	"errors"
+	"path"
+	"testing"
+)
+
+// gridString returns the cells of a grid track in the printout symbols.
+func gridString(t GridTrack) string {
+	b := make([]byte, len(t.Velocities))
+	for i, v := range t.Velocities {
+		b[i] = symbolStepDisabled
+		if v != 0 {
+			b[i] = symbolStepEnabled
+		}
+	}
+	return string(b)
+}
+
+func TestResample(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hihat := p.tracks[3] // --x---x-x-x---x-
+	hihat.SetVelocity(10, 40)
+	hihat.SetStep(11, true)
+	hihat.SetVelocity(11, 100)
+	hihat.SetTiming(14, 0.5)
+
+	g, err := Resample(p, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 14 half a step late lands between 28 and 30
+	if exp, got := "----x-------x---x---x-x------x--", gridString(g.Tracks[3]); got != exp {
+		t.Errorf("Expected '%v' but got '%v'", exp, got)
+	}
+	if g.Version != p.version || g.Tempo != p.tempo || g.Tracks[3].ID != hihat.id || g.Tracks[3].Velocities[20] != 40 {
+		t.Errorf("unexpected grid %+v", g)
+	}
+
+	// 11 lands halfway between eighths 5 and 6, rounded down it collides
+	// with 10 and the louder velocity is kept, 14.5 wraps when rounded up
+	specs := map[Rounding]struct {
+		steps    string
+		velocity uint8 // of eighth 5
+	}{
+		RoundNearest: {"-x-xxxxx", 40},
+		RoundDown:    {"-x-xxx-x", 100},
+		RoundUp:      {"xx-xxxx-", 40},
+	}
+	for r, exp := range specs {
+		g, err := Resample(p, 8, WithRounding(r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := gridString(g.Tracks[3]); got != exp.steps || g.Tracks[3].Velocities[5] != exp.velocity {
+			t.Errorf("rounding %d: expected '%v' with velocity %d but got '%v' with %d", r, exp.steps, exp.velocity, got, g.Tracks[3].Velocities[5])
+		}
+	}
+
+	if _, err := Resample(p, 0); !errors.Is(err, ErrInvalidGrid) {
+		t.Errorf("Expected %v but got %v", ErrInvalidGrid, err)
+	}
+}
+
+func TestGridPattern(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.tracks[0].SetVelocity(0, 64)
+	g, err := Resample(p, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sixteenths survive a finer grid unchanged
+	got, err := g.Pattern()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tr := range got.tracks {
+		if tr.steps != p.tracks[i].steps || tr.velocity != p.tracks[i].velocity || tr.name != p.tracks[i].name {
+			t.Errorf("track %d: expected %v %v but got %v %v", i, p.tracks[i].steps, p.tracks[i].velocity, tr.steps, tr.velocity)
+		}
+	}
+
+	// a 32nd between two sixteenths lands on the later one by default and
+	// on the earlier one when rounding down
+	g.Tracks[0].Velocities = make([]uint8, 32)
+	g.Tracks[0].Velocities[3] = 90
+	for r, exp := range map[Rounding]int{RoundNearest: 2, RoundDown: 1} {
+		got, err := g.Pattern(WithRounding(r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if steps := got.tracks[0].steps; !steps[exp] || got.tracks[0].Velocity(exp) != 90 {
+			t.Errorf("rounding %d: expected step %d with velocity 90 but got %v", r, exp+1, stepsString(steps))
+		}
+	}
+}
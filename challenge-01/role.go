@@ -0,0 +1,142 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var chunkRole = chunkID{'R', 'O', 'L', 'E'}
+
+// FeatureRole is the chunk of the role overrides of tracks.
+const FeatureRole Feature = "role"
+
+// ErrInvalidRole is returned for unknown roles.
+var ErrInvalidRole = errors.New("invalid role")
+
+// Role is the kit piece a track plays, so that exporters, the mixer and the
+// analysis can treat instruments alike whatever the track is named.
+type Role uint8
+
+// The roles. RoleAuto is no role, it makes Track.Role infer the role from
+// the name.
+const (
+	RoleAuto Role = iota
+	RoleKick
+	RoleSnare // snares, claps and rimshots
+	RoleHat   // hi-hats and cymbals
+	RoleTom
+	RolePerc // hand percussion like congas, shakers or the cowbell
+	RoleFX   // anything else, like effects or melodic samples
+)
+
+var roleNames = [...]string{"auto", "kick", "snare", "hat", "tom", "perc", "fx"}
+
+// Roles returns all roles but RoleAuto.
+func Roles() []Role {
+	return []Role{RoleKick, RoleSnare, RoleHat, RoleTom, RolePerc, RoleFX}
+}
+
+func (r Role) String() string {
+	if int(r) < len(roleNames) {
+		return roleNames[r]
+	}
+	return fmt.Sprintf("Role(%d)", uint8(r))
+}
+
+// ParseRole returns the role of a name written by Role.String.
+func ParseRole(s string) (Role, error) {
+	for r, name := range roleNames {
+		if name == s {
+			return Role(r), nil
+		}
+	}
+	return RoleAuto, fmt.Errorf("%w %q", ErrInvalidRole, s)
+}
+
+// RoleOf returns the role inferred from a track name by its General MIDI
+// note, see ResolveGMNote. Names naming no drum are RoleFX.
+func RoleOf(name string) Role {
+	note, ok := ResolveGMNote(name)
+	if !ok {
+		return RoleFX
+	}
+	switch note {
+	case 35, 36:
+		return RoleKick
+	case 37, 38, 39, 40:
+		return RoleSnare
+	case 42, 44, 46, 49, 51, 52, 53, 55, 57, 59:
+		return RoleHat
+	case 41, 43, 45, 47, 48, 50:
+		return RoleTom
+	}
+	return RolePerc
+}
+
+// Role returns the role set with SetRole or else the role inferred from the
+// name.
+func (t *Track) Role() Role {
+	if t.role != RoleAuto {
+		return t.role
+	}
+	return RoleOf(t.name)
+}
+
+// SetRole overrides the role inferred from the name, for names like "Boom"
+// that name no drum. RoleAuto removes the override.
+func (t *Track) SetRole(r Role) error {
+	if r > RoleFX {
+		return fmt.Errorf("%w %d", ErrInvalidRole, r)
+	}
+	t.role = r
+	return nil
+}
+
+// TracksByRole returns the tracks of the role in file order.
+func (p *Pattern) TracksByRole(r Role) []*Track {
+	return p.FilterTracks(func(t *Track) bool { return t.Role() == r })
+}
+
+// The role chunk holds the overrides only:
+//
+//	|Track index (2 bytes)|Role (1 byte)|
+const roleChunkEntryLength = 3
+
+func decodeRoleChunk(data []byte, p *Pattern) error {
+	if len(data)%roleChunkEntryLength != 0 {
+		return errors.New("invalid size")
+	}
+	for ; len(data) > 0; data = data[roleChunkEntryLength:] {
+		index := binary.LittleEndian.Uint16(data)
+		if int(index) >= len(p.tracks) {
+			return fmt.Errorf("track index %d out of range", index)
+		}
+		if err := p.tracks[index].SetRole(Role(data[2])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeRoleChunk(p *Pattern) []byte {
+	var buf bytes.Buffer
+	for i, t := range p.tracks {
+		if t.role == RoleAuto {
+			continue
+		}
+		buf.Write(binary.LittleEndian.AppendUint16(nil, uint16(i)))
+		buf.WriteByte(byte(t.role))
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+func clearRole(p *Pattern) {
+	for _, t := range p.tracks {
+		t.role = RoleAuto
+	}
+}
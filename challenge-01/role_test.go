@@ -0,0 +1,96 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestRoleOf(t *testing.T) {
+	specs := map[string]Role{
+		"kick":      RoleKick,
+		"BD":        RoleKick,
+		"snare":     RoleSnare,
+		"clap":      RoleSnare,
+		"hh-open":   RoleHat,
+		"crash":     RoleHat,
+		"Low Tom":   RoleTom,
+		"cowbell":   RolePerc,
+		"shaker":    RolePerc,
+		"laser zap": RoleFX,
+	}
+	for name, exp := range specs {
+		if got := RoleOf(name); got != exp {
+			t.Errorf("%s: expected %v but got %v", name, exp, got)
+		}
+	}
+}
+
+func TestTracksByRole(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hats []string
+	for _, tr := range p.TracksByRole(RoleHat) {
+		hats = append(hats, tr.Name())
+	}
+	if exp, got := "[hh-open hh-close]", fmt.Sprint(hats); got != exp {
+		t.Errorf("Expected %v but got %v", exp, got)
+	}
+	cowbell := p.TrackByName("cowbell")
+	if err := cowbell.SetRole(RoleFX); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.TracksByRole(RoleFX); len(got) != 1 || got[0] != cowbell {
+		t.Errorf("Expected the cowbell overridden to fx but got %v", got)
+	}
+	if got := p.TracksByRole(RolePerc); len(got) != 0 {
+		t.Errorf("Expected no percussion but got %v", got)
+	}
+	if err := cowbell.SetRole(RoleFX + 1); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("Expected %v but got %v", ErrInvalidRole, err)
+	}
+}
+
+func TestRoleRoundTrip(t *testing.T) {
+	p := &Pattern{version: "0.909", tempo: 120, tracks: []*Track{{name: "kick"}, {name: "Boom"}}}
+	p.tracks[1].SetRole(RoleKick)
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(p) || len(decoded.TracksByRole(RoleKick)) != 2 {
+		t.Errorf("Expected both tracks as kicks but got %v", decoded.TracksByRole(RoleKick))
+	}
+	if exp, got := "[role]", fmt.Sprint(p.Features()); got != exp {
+		t.Errorf("Expected features %v but got %v", exp, got)
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`"name":"Boom","steps":"----------------","role":"kick"`)) {
+		t.Errorf("Expected the role in %s", data)
+	}
+	var fromJSON Pattern
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !fromJSON.Equal(p) {
+		t.Errorf("Expected %v but got %v", p, &fromJSON)
+	}
+	if err := json.Unmarshal([]byte(`{"version":"v","tempo":120,"tracks":[{"id":1,"name":"x","steps":"----------------","role":"cowbell"}]}`), &fromJSON); err == nil || !strings.Contains(err.Error(), `invalid role "cowbell"`) {
+		t.Errorf("Expected an invalid role but got %v", err)
+	}
+}
@@ -0,0 +1,270 @@
+package drum
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SaveOption configures how EncodeFile, Bank.Save and SaveBatch write files.
+type SaveOption func(*saveOptions)
+
+type saveOptions struct {
+	backups int
+	perm    os.FileMode
+}
+
+// WithBackups keeps the n previous versions of replaced files, the last one
+// as name.bak and the older ones as name.bak.2 up to name.bak.n.
+func WithBackups(n int) SaveOption {
+	return func(o *saveOptions) {
+		o.backups = n
+	}
+}
+
+// WithFileMode sets the permissions of new files, 0644 by default. Replaced
+// files keep their permissions.
+func WithFileMode(perm os.FileMode) SaveOption {
+	return func(o *saveOptions) {
+		o.perm = perm
+	}
+}
+
+// renameFile is os.Rename, replaced by tests to fail.
+var renameFile = os.Rename
+
+// WriteFileAtomic writes the file at path with write. The data goes to a
+// temporary file in the same directory first, which is synced to disk and
+// renamed over path, so that a crash never leaves a partly written file
+// behind and readers see either the old or the new content.
+func WriteFileAtomic(path string, write func(w io.Writer) error, opts ...SaveOption) error {
+	b := NewSaveBatch(opts...)
+	b.Add(path, write)
+	_, err := b.Commit()
+	return err
+}
+
+// SaveBatch saves several files all or nothing, like the patterns of a bank
+// exported to a directory. All files are written to temporary files first
+// and only then renamed over their targets. When writing or renaming one of
+// them fails, the files already replaced are restored.
+type SaveBatch struct {
+	opts  saveOptions
+	files []*batchFile
+}
+
+type batchFile struct {
+	path   string
+	write  func(w io.Writer) error
+	tmp    string // the new content until it is renamed to path
+	orig   string // the previous content until commit or rollback, empty for new files
+	moved  bool   // orig was renamed from path instead of linked
+	placed bool
+}
+
+// NewSaveBatch returns an empty batch.
+func NewSaveBatch(opts ...SaveOption) *SaveBatch {
+	o := saveOptions{perm: 0644}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &SaveBatch{opts: o}
+}
+
+// Add adds the file at path with the content written by write.
+func (b *SaveBatch) Add(path string, write func(w io.Writer) error) {
+	b.files = append(b.files, &batchFile{path: path, write: write})
+}
+
+// AddPattern adds the file at path with the encoded pattern.
+func (b *SaveBatch) AddPattern(path string, p *Pattern, opts ...EncodeOption) {
+	b.Add(path, func(w io.Writer) error {
+		return Encode(w, p, opts...)
+	})
+}
+
+// SaveReport tells what a SaveBatch did with its files.
+type SaveReport struct {
+	Saved      []string // files replaced or created, in the order added
+	RolledBack []string // files restored after a later file failed
+	Failed     string   // the file that failed, empty on success
+	Errors     []error  // files left in an unknown state, which need attention
+}
+
+func (r SaveReport) String() string {
+	if r.Failed == "" {
+		s := fmt.Sprintf("saved %d files", len(r.Saved))
+		for _, err := range r.Errors {
+			s += "\n\t" + err.Error()
+		}
+		return s
+	}
+	s := fmt.Sprintf("%s failed, rolled back %d files", r.Failed, len(r.RolledBack))
+	if len(r.RolledBack) > 0 {
+		s += ": " + strings.Join(r.RolledBack, ", ")
+	}
+	for _, err := range r.Errors {
+		s += "\n\t" + err.Error()
+	}
+	return s
+}
+
+// Commit writes all files added since the last commit. It returns the error
+// of the file that failed, the report lists the files rolled back. Files
+// that could neither be saved nor restored, and backups that could not be
+// kept, are listed in the Errors of the report.
+func (b *SaveBatch) Commit() (SaveReport, error) {
+	files := b.files
+	b.files = nil
+	defer func() {
+		for _, f := range files {
+			if f.tmp != "" {
+				os.Remove(f.tmp)
+			}
+		}
+	}()
+	var r SaveReport
+	for _, f := range files {
+		if err := f.prepare(b.opts.perm); err != nil {
+			r.Failed = f.path
+			return r, fmt.Errorf("save %s: %w", f.path, err)
+		}
+	}
+	for i, f := range files {
+		if err := f.place(); err != nil {
+			r.Failed = f.path
+			rollback(&r, files[:i+1])
+			return r, fmt.Errorf("save %s: %w", f.path, err)
+		}
+	}
+	dirs := make(map[string]bool)
+	for _, f := range files {
+		r.Saved = append(r.Saved, f.path)
+		if err := f.keepBackup(b.opts.backups); err != nil {
+			r.Errors = append(r.Errors, fmt.Errorf("back up %s: %w", f.path, err))
+		}
+		dirs[filepath.Dir(f.path)] = true
+	}
+	for dir := range dirs {
+		syncDir(dir)
+	}
+	return r, nil
+}
+
+// prepare writes the content to a synced temporary file next to the target.
+func (f *batchFile) prepare(perm os.FileMode) error {
+	if fi, err := os.Stat(f.path); err == nil {
+		if fi.IsDir() {
+			return fmt.Errorf("is a directory")
+		}
+		perm = fi.Mode().Perm()
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), "."+filepath.Base(f.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	f.tmp = tmp.Name()
+	w := bufio.NewWriter(tmp)
+	err = f.write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// place renames the temporary file over the target, keeping the previous
+// content as a hard link, or under another name where links are not
+// supported.
+func (f *batchFile) place() error {
+	if _, err := os.Lstat(f.path); err == nil {
+		orig := f.tmp + ".orig"
+		if err := os.Link(f.path, orig); err != nil {
+			if err := renameFile(f.path, orig); err != nil {
+				return err
+			}
+			f.moved = true
+		}
+		f.orig = orig
+	}
+	if err := renameFile(f.tmp, f.path); err != nil {
+		return err
+	}
+	f.tmp, f.placed = "", true
+	return nil
+}
+
+// rollback restores the previous content of the files in reverse order.
+func rollback(r *SaveReport, files []*batchFile) {
+	for i := len(files) - 1; i >= 0; i-- {
+		f := files[i]
+		var err error
+		switch {
+		case !f.placed && !f.moved:
+			// the target was not touched
+			if f.orig != "" {
+				os.Remove(f.orig)
+			}
+			continue
+		case f.orig != "":
+			err = renameFile(f.orig, f.path)
+		default:
+			err = os.Remove(f.path)
+		}
+		if err != nil {
+			r.Errors = append(r.Errors, fmt.Errorf("restore %s: %w", f.path, err))
+			continue
+		}
+		if f.placed {
+			r.RolledBack = append(r.RolledBack, f.path)
+		}
+	}
+}
+
+// keepBackup rotates the backups of the target and keeps the previous
+// content as the last one, or removes it when no backups are kept.
+func (f *batchFile) keepBackup(n int) error {
+	if f.orig == "" {
+		return nil
+	}
+	if n <= 0 {
+		return os.Remove(f.orig)
+	}
+	for i := n; i > 1; i-- {
+		if err := renameFile(backupName(f.path, i-1), backupName(f.path, i)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return renameFile(f.orig, backupName(f.path, 1))
+}
+
+// backupName returns the name of the i-th most recent backup of the file.
+func backupName(path string, i int) string {
+	if i == 1 {
+		return path + ".bak"
+	}
+	return fmt.Sprintf("%s.bak.%d", path, i)
+}
+
+// syncDir syncs the directory so that renames in it survive a crash.
+// Systems that cannot sync directories already persist renames.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,151 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeString(s string) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+// dirContents returns the files of the directory with their content,
+// sorted by name.
+func dirContents(t *testing.T, dir string) string {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, fi := range infos {
+		b, _ := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		files = append(files, fi.Name()+"="+string(b))
+	}
+	return strings.Join(files, " ")
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "save")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.splice")
+	if err := WriteFileAtomic(path, writeString("1"), WithFileMode(0600)); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"2", "3", "4"} {
+		if err := WriteFileAtomic(path, writeString(s), WithBackups(2)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exp, got := "a.splice=4 a.splice.bak=3 a.splice.bak.2=2", dirContents(t, dir); got != exp {
+		t.Errorf("Expected %v but got %v", exp, got)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("Expected the file mode kept but got %v", fi.Mode())
+	}
+
+	failing := errors.New("disk full")
+	err = WriteFileAtomic(path, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return failing
+	})
+	if !errors.Is(err, failing) {
+		t.Errorf("Expected %v but got %v", failing, err)
+	}
+	if exp, got := "a.splice=4 a.splice.bak=3 a.splice.bak.2=2", dirContents(t, dir); got != exp {
+		t.Errorf("Expected the files unchanged %v but got %v", exp, got)
+	}
+}
+
+func TestSaveBatchRollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "save")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a, b, c := filepath.Join(dir, "a"), filepath.Join(dir, "b"), filepath.Join(dir, "c")
+	if err := ioutil.WriteFile(a, []byte("old a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(c, []byte("old c"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	failing := errors.New("rename failed")
+	defer func() { renameFile = os.Rename }()
+	renameFile = func(from, to string) error {
+		if to == c {
+			return failing
+		}
+		return os.Rename(from, to)
+	}
+
+	batch := NewSaveBatch(WithBackups(1))
+	batch.Add(a, writeString("new a"))
+	batch.Add(b, writeString("new b"))
+	batch.Add(c, writeString("new c"))
+	report, err := batch.Commit()
+	if !errors.Is(err, failing) {
+		t.Errorf("Expected %v but got %v", failing, err)
+	}
+	if exp, got := "a=old a c=old c", dirContents(t, dir); got != exp {
+		t.Errorf("Expected the files restored %v but got %v", exp, got)
+	}
+	if exp, got := fmt.Sprintf("%s failed, rolled back 2 files: %s, %s", c, b, a), report.String(); got != exp {
+		t.Errorf("Expected report '%v' but got '%v'", exp, got)
+	}
+
+	renameFile = os.Rename
+	batch.Add(a, writeString("new a"))
+	batch.Add(b, writeString("new b"))
+	if report, err = batch.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := "a=new a a.bak=old a b=new b c=old c", dirContents(t, dir); got != exp {
+		t.Errorf("Expected %v but got %v", exp, got)
+	}
+	if exp, got := "saved 2 files", report.String(); got != exp {
+		t.Errorf("Expected report '%v' but got '%v'", exp, got)
+	}
+}
+
+func TestBankExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "save")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p1, err := DecodeFile(filepath.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bank Bank
+	bank.Add("grooves/one.splice", p1)
+	bank.Add("../two.splice", p1)
+	report, err := bank.Export(filepath.Join(dir, "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Saved) != 2 {
+		t.Errorf("unexpected report %v", report)
+	}
+	for _, name := range []string{"grooves/one.splice", "two.splice"} {
+		p, err := DecodeFile(filepath.Join(dir, "out", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.Equal(p1) {
+			t.Errorf("Expected %v but got %v", p1, p)
+		}
+	}
+}
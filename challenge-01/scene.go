@@ -0,0 +1,164 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ErrUnknownScene is returned when launching a scene the song does not have.
+var ErrUnknownScene = errors.New("unknown scene")
+
+// ErrInvalidScene is returned for a scene without name, with a name used
+// twice or with a section or track outside of the song.
+var ErrInvalidScene = errors.New("invalid scene")
+
+// Scene is a named state of a song launched by a Player like a scene of a
+// session: the section played and the tracks muted. A scene without mutes is
+// a cue point of the song.
+type Scene struct {
+	Name    string
+	Section int   // index of the song section played
+	Mutes   []int // indexes of the tracks of the section pattern muted
+}
+
+func (sc Scene) validate(s *Song) error {
+	if sc.Name == "" || len(sc.Name) > math.MaxUint8 {
+		return fmt.Errorf("%w %q", ErrInvalidScene, sc.Name)
+	}
+	if sc.Section < 0 || sc.Section >= len(s.Sections) || sc.Section > math.MaxUint16 {
+		return fmt.Errorf("%w %q: section %d out of range", ErrInvalidScene, sc.Name, sc.Section)
+	}
+	if len(sc.Mutes) > math.MaxUint8 {
+		return fmt.Errorf("%w %q: too many mutes", ErrInvalidScene, sc.Name)
+	}
+	p := s.Sections[sc.Section].Pattern
+	for _, i := range sc.Mutes {
+		// the tracks of patterns loaded while playing are not known
+		if i < 0 || p != nil && i >= len(p.tracks) {
+			return fmt.Errorf("%w %q: track index %d out of range", ErrInvalidScene, sc.Name, i)
+		}
+	}
+	return nil
+}
+
+// validateScenes checks the scenes of the song and that their names are
+// unique.
+func (s *Song) validateScenes() error {
+	names := make(map[string]bool)
+	for _, sc := range s.Scenes {
+		if err := sc.validate(s); err != nil {
+			return err
+		}
+		key := normalizeName(sc.Name)
+		if names[key] {
+			return fmt.Errorf("%w %q: name used twice", ErrInvalidScene, sc.Name)
+		}
+		names[key] = true
+	}
+	return nil
+}
+
+// Scene returns the scene of the name, compared like in Pattern.TrackByName.
+func (s *Song) Scene(name string) (Scene, bool) {
+	key := normalizeName(name)
+	for _, sc := range s.Scenes {
+		if normalizeName(sc.Name) == key {
+			return sc, true
+		}
+	}
+	return Scene{}, false
+}
+
+// LaunchScene plays the scene of the song played with the name from the
+// start of the next bar, so that the arrangement changes without a jump. The
+// mutes of the player are replaced by the ones of the scene and the song
+// continues with the sections after the one of the scene. The last scene or
+// pattern queued wins when several are launched within a bar.
+func (pl *Player) LaunchScene(name string) error {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	if pl.song == nil {
+		return fmt.Errorf("%w %q", ErrUnknownScene, name)
+	}
+	sc, ok := pl.song.Scene(name)
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownScene, name)
+	}
+	return pl.queueScene(sc)
+}
+
+// LaunchSceneIndex plays the scene of the song played at the index from the
+// start of the next bar, see LaunchScene.
+func (pl *Player) LaunchSceneIndex(i int) error {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	if pl.song == nil || i < 0 || i >= len(pl.song.Scenes) {
+		return fmt.Errorf("%w %d", ErrUnknownScene, i)
+	}
+	return pl.queueScene(pl.song.Scenes[i])
+}
+
+func (pl *Player) queueScene(sc Scene) error {
+	if err := sc.validate(pl.song); err != nil {
+		return err
+	}
+	pl.scene, pl.next = &sc, nil
+	return nil
+}
+
+// startScene moves on to the section of the queued scene.
+func (pl *Player) startScene() {
+	sc := pl.scene
+	pl.scene = nil
+	pl.section, pl.repeat, pl.loop = sc.Section, 0, 0
+	pl.pattern = pl.sectionPattern(sc.Section)
+	pl.mutes = make(map[int]bool)
+	for _, i := range sc.Mutes {
+		pl.mutes[i] = true
+	}
+}
+
+// Scenes follow the sections of a song file, marked by a repeat count of 0
+// which no section has:
+//
+//	|0 (2 bytes)|Name length (1 byte)|Name (n bytes)|Section (2 bytes)|Mute count (1 byte)|Mutes (2 bytes each)|
+
+func decodeScene(r io.Reader) (Scene, error) {
+	var sc Scene
+	var err error
+	if sc.Name, err = readShortString(r); err != nil {
+		return Scene{}, err
+	}
+	var header struct {
+		Section uint16
+		Mutes   uint8
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return Scene{}, err
+	}
+	sc.Section = int(header.Section)
+	if header.Mutes > 0 {
+		mutes := make([]uint16, header.Mutes)
+		if err := binary.Read(r, binary.LittleEndian, mutes); err != nil {
+			return Scene{}, err
+		}
+		for _, i := range mutes {
+			sc.Mutes = append(sc.Mutes, int(i))
+		}
+	}
+	return sc, nil
+}
+
+func encodeScene(w *bytes.Buffer, sc Scene) {
+	binary.Write(w, binary.LittleEndian, uint16(0))
+	writeShortString(w, sc.Name)
+	binary.Write(w, binary.LittleEndian, uint16(sc.Section))
+	binary.Write(w, binary.LittleEndian, uint8(len(sc.Mutes)))
+	for _, i := range sc.Mutes {
+		binary.Write(w, binary.LittleEndian, uint16(i))
+	}
+}
@@ -0,0 +1,92 @@
+package drum
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSceneRoundTrip(t *testing.T) {
+	s := testSong(t)
+	s.Scenes = []Scene{{Name: "intro", Section: 0, Mutes: []int{1, 2}}, {Name: "drop", Section: 1}}
+	var buf bytes.Buffer
+	if err := EncodeSong(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeSong(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Scenes, s.Scenes) {
+		t.Errorf("Expected scenes %+v but got %+v", s.Scenes, decoded.Scenes)
+	}
+	if len(decoded.Sections) != len(s.Sections) {
+		t.Errorf("Expected %d sections but got %d", len(s.Sections), len(decoded.Sections))
+	}
+	if sc, ok := decoded.Scene("Drop"); !ok || sc.Section != 1 {
+		t.Errorf("Expected the scene by name but got %+v", sc)
+	}
+}
+
+func TestInvalidScene(t *testing.T) {
+	for _, sc := range [][]Scene{
+		{{Name: ""}},
+		{{Name: "end", Section: 2}},
+		{{Name: "mute", Mutes: []int{99}}},
+		{{Name: "a"}, {Name: "A", Section: 1}},
+	} {
+		s := testSong(t)
+		s.Scenes = sc
+		if err := EncodeSong(&bytes.Buffer{}, s); !errors.Is(err, ErrInvalidScene) {
+			t.Errorf("Expected %v for %+v but got %v", ErrInvalidScene, sc, err)
+		}
+	}
+}
+
+func TestLaunchScene(t *testing.T) {
+	s := testSong(t)
+	s.Scenes = []Scene{{Name: "drop", Section: 1, Mutes: []int{0}}}
+	pl := NewSongPlayer(s, nil)
+	if err := pl.LaunchScene("build"); !errors.Is(err, ErrUnknownScene) {
+		t.Errorf("Expected %v but got %v", ErrUnknownScene, err)
+	}
+	if err := pl.LaunchSceneIndex(1); !errors.Is(err, ErrUnknownScene) {
+		t.Errorf("Expected %v but got %v", ErrUnknownScene, err)
+	}
+	pl.SetMute(2, true)
+	var sections []int
+	for i := 0; i < 2*stepsLength; i++ {
+		if i == 3 {
+			if err := pl.LaunchScene("drop"); err != nil {
+				t.Fatal(err)
+			}
+			if !pl.Transport(time.Now()).Queued {
+				t.Error("Expected the scene queued")
+			}
+		}
+		ev, _, err := pl.advance(time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.Step == 0 {
+			sections = append(sections, ev.Section)
+		}
+	}
+	// the scene starts with the next bar instead of the repeat of section 0
+	if exp := []int{0, 1}; !reflect.DeepEqual(sections, exp) {
+		t.Errorf("Expected sections %v but got %v", exp, sections)
+	}
+	if exp := map[int]bool{0: true}; !reflect.DeepEqual(pl.mutes, exp) {
+		t.Errorf("Expected the mutes of the scene but got %v", pl.mutes)
+	}
+	if _, _, err := pl.advance(time.Now()); !errors.Is(err, errEndOfSong) {
+		t.Errorf("Expected the song to end after the scene section but got %v", err)
+	}
+
+	pl.SetPattern(s.Sections[0].Pattern)
+	if err := pl.LaunchSceneIndex(0); !errors.Is(err, ErrUnknownScene) {
+		t.Errorf("Expected %v without song but got %v", ErrUnknownScene, err)
+	}
+}
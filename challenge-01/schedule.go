@@ -0,0 +1,83 @@
+package drum
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// Exporters place steps on a grid of ticks. 24 ticks per sixteenth note
+// (96 per quarter note) are fine enough for swing and timing offsets.
+const (
+	ticksPerStep = 24
+	ticksPerBeat = ticksPerStep * blockSize
+)
+
+// noteEvent is a single trigger of a track.
+type noteEvent struct {
+	tick     int   // position from the start of the first bar
+	track    int   // index of the track within the pattern
+	velocity uint8 // MIDI velocity 1-127
+	step     int   // step of the track, see Track.SetLength
+	length   int   // ticks until the next trigger of a ratchet, a step without
+}
+
+// schedule returns the note events of the pattern repeated for the number of
+// bars, sorted by tick and track. Muted tracks and tracks outside of the
+// solo group are left out. Trig conditions are evaluated without fill and
+// probabilities are rolled with a fixed seed, so exports are reproducible.
+// Ratchets add evenly spaced events within the step. Bars are as long as
+// the time signature of the pattern, tracks with their own length loop
+// independently of them.
+func (p *Pattern) schedule(bars int) []noteEvent {
+	return p.scheduleTracks(bars, true)
+}
+
+// scheduleTracks is like schedule but only leaves out tracks which are not
+// heard when audibleOnly is set. Exporters to formats with their own mute
+// state schedule all tracks.
+func (p *Pattern) scheduleTracks(bars int, audibleOnly bool) []noteEvent {
+	var events []noteEvent
+	swing := swingTicks(p.swing)
+	solo := p.hasSolo()
+	var rng *rand.Rand
+	for _, t := range p.tracks {
+		if t.hasProbability() {
+			rng = rand.New(rand.NewSource(1))
+			break
+		}
+	}
+	barSteps := p.BarSteps()
+	for bar := 0; bar < bars; bar++ {
+		for i, t := range p.tracks {
+			if audibleOnly && !t.audible(solo) {
+				continue
+			}
+			for s := 0; s < barSteps; s++ {
+				n := bar*barSteps + s
+				step := p.trackStep(t, n)
+				if !t.triggers(p.barOf(bar), step, bar, false, rng) {
+					continue
+				}
+				tick := n*ticksPerStep + t.offset(step)
+				if s%2 == 1 {
+					tick += swing
+				}
+				if tick < 0 {
+					tick = 0
+				}
+				ratchet := t.Ratchet(step)
+				for r := 0; r < ratchet; r++ {
+					e := noteEvent{tick: tick + r*ticksPerStep/ratchet, track: i, velocity: t.Velocity(step), step: step, length: ticksPerStep / ratchet}
+					events = append(events, e)
+				}
+			}
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].tick != events[j].tick {
+			return events[i].tick < events[j].tick
+		}
+		return events[i].track < events[j].track
+	})
+	return events
+}
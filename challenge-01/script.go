@@ -0,0 +1,990 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidScript is returned by ParseScript for scripts with syntax errors.
+var ErrInvalidScript = errors.New("invalid script")
+
+// Script is a small program editing patterns, see ParseScript.
+type Script struct {
+	stmts []scriptStmt
+}
+
+// ParseScript parses a script to batch-edit patterns without writing Go:
+//
+//	# move the clap a step later and push the hats
+//	if track.name == "clap": steps.shift(1)
+//	if "hh" in lower(track.name): track.nudge = -0.1; track.volume = 100
+//	if track.index == 0: pattern.tempo *= 1.1
+//
+// Apply runs the script once for every track with the variables pattern,
+// track and steps set, so changes of the pattern are guarded by a condition
+// on the track like above. Statements are assignments with =, +=, -=, *= and
+// /=, calls and single line if statements running the statements after the
+// colon, separated by newlines or semicolons. Everything after # is a
+// comment. Values are numbers, strings in single or double quotes and the
+// booleans true and false. Expressions use the operators or, and, not, ==,
+// !=, <, <=, >, >=, in for substrings, +, -, *, / and % with the usual
+// precedence and the functions lower, abs and round.
+//
+// The objects mirror Pattern, Track and Steps:
+//
+//	pattern.tempo    number, see Pattern.SetTempo
+//	pattern.swing    number, see Pattern.SetSwing
+//	pattern.version  string, read only
+//	pattern.tracks   number of tracks, read only
+//	track.id         number, read only
+//	track.index      number of the track within the pattern, read only
+//	track.name       string
+//	track.muted      boolean
+//	track.solo       boolean
+//	track.volume     number, see Track.SetVolume
+//	track.pan        number, see Track.SetPan
+//	track.nudge      number, see Track.SetNudge
+//	track.length     number, see Track.SetLength
+//	track.choke      number, see Track.SetChokeGroup
+//	track.role       string, read only
+//	steps[i]         boolean of step i of the first bar, from 0 to the
+//	                 length of the track minus 1
+//	steps.count      number of enabled steps of the first bar, read only
+//	steps.shift(n)   moves the steps n steps later, see Pattern.Rotate
+//	steps.reverse()  plays the steps backwards, see Pattern.Reverse
+//	steps.clear()    disables the steps of all bars
+//
+// Scripts are sandboxed: they have no loops and no access to anything but
+// the pattern, so every run ends after a single pass over the statements.
+func ParseScript(src string) (*Script, error) {
+	toks, err := lexScript(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &scriptParser{toks: toks}
+	var stmts []scriptStmt
+	for p.peek().kind != scriptEOF {
+		if p.peek().kind == scriptEOL {
+			p.next()
+			continue
+		}
+		line, err := p.line()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, line...)
+	}
+	return &Script{stmts}, nil
+}
+
+// Apply returns a copy of the pattern changed by the script, leaving p
+// unchanged. Errors tell the line of the script and the id of the track.
+func (s *Script) Apply(p *Pattern) (*Pattern, error) {
+	c := p.Clone()
+	for i, t := range c.tracks {
+		e := &scriptEnv{vars: map[string]interface{}{
+			"pattern": scriptPattern{c},
+			"track":   scriptTrack{t, i},
+			"steps":   scriptSteps{c, t},
+		}}
+		if err := e.run(s.stmts); err != nil {
+			return nil, fmt.Errorf("track %d: %w", t.id, err)
+		}
+	}
+	return c, nil
+}
+
+// Tokens of scripts.
+const (
+	scriptEOF = iota
+	scriptEOL
+	scriptNumber
+	scriptString
+	scriptName
+	scriptOp
+)
+
+type scriptToken struct {
+	kind int
+	text string
+	num  float64
+	line int
+}
+
+// scriptOps are the operators and punctuation, longest first.
+var scriptOps = []string{"==", "!=", "<=", ">=", "+=", "-=", "*=", "/=",
+	"+", "-", "*", "/", "%", "<", ">", "=", "(", ")", "[", "]", ",", ".", ":", ";"}
+
+func lexScript(src string) ([]scriptToken, error) {
+	var toks []scriptToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			toks = append(toks, scriptToken{kind: scriptEOL, line: line})
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			end := strings.IndexAny(src[i+1:], string(c)+"\n")
+			if end < 0 || src[i+1+end] == '\n' {
+				return nil, fmt.Errorf("%w: line %d: unterminated string", ErrInvalidScript, line)
+			}
+			toks = append(toks, scriptToken{kind: scriptString, text: src[i+1 : i+1+end], line: line})
+			i += end + 2
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			n, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: invalid number %q", ErrInvalidScript, line, src[i:j])
+			}
+			toks = append(toks, scriptToken{kind: scriptNumber, text: src[i:j], num: n, line: line})
+			i = j
+		case nameByte(c):
+			j := i
+			for j < len(src) && (nameByte(src[j]) || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			toks = append(toks, scriptToken{kind: scriptName, text: src[i:j], line: line})
+			i = j
+		default:
+			op := ""
+			for _, o := range scriptOps {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("%w: line %d: unexpected %q", ErrInvalidScript, line, c)
+			}
+			toks = append(toks, scriptToken{kind: scriptOp, text: op, line: line})
+			i += len(op)
+		}
+	}
+	return append(toks, scriptToken{kind: scriptEOF, line: line}), nil
+}
+
+func nameByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// scriptStmt is an assignment of value to target, the evaluation of value
+// for a call, or an if statement running body when cond is true.
+type scriptStmt struct {
+	line   int
+	cond   scriptExpr
+	body   []scriptStmt
+	target scriptExpr
+	op     string // of the assignment
+	value  scriptExpr
+}
+
+type scriptParser struct {
+	toks []scriptToken
+	pos  int
+}
+
+func (p *scriptParser) peek() scriptToken {
+	return p.toks[p.pos]
+}
+
+func (p *scriptParser) next() scriptToken {
+	t := p.toks[p.pos]
+	if t.kind != scriptEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token when it is the operator or name text.
+func (p *scriptParser) accept(text string) bool {
+	if t := p.peek(); (t.kind == scriptOp || t.kind == scriptName) && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *scriptParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: line %d: %s", ErrInvalidScript, p.peek().line, fmt.Sprintf(format, args...))
+}
+
+// unexpected returns the error for the next token.
+func (p *scriptParser) unexpected() error {
+	switch t := p.peek(); t.kind {
+	case scriptEOF:
+		return p.errorf("unexpected end of script")
+	case scriptEOL:
+		return p.errorf("unexpected end of line")
+	default:
+		return p.errorf("unexpected %q", t.text)
+	}
+}
+
+// line parses the statements up to the end of the line.
+func (p *scriptParser) line() ([]scriptStmt, error) {
+	var stmts []scriptStmt
+	for {
+		s, err := p.stmt()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, s)
+		if s.cond != nil || !p.accept(";") {
+			break
+		}
+	}
+	if k := p.peek().kind; k != scriptEOL && k != scriptEOF {
+		return nil, p.unexpected()
+	}
+	return stmts, nil
+}
+
+func (p *scriptParser) stmt() (scriptStmt, error) {
+	s := scriptStmt{line: p.peek().line}
+	if p.accept("if") {
+		cond, err := p.expr()
+		if err != nil {
+			return s, err
+		}
+		if !p.accept(":") {
+			return s, p.errorf("expected : after the condition")
+		}
+		if s.body, err = p.line(); err != nil {
+			return s, err
+		}
+		s.cond = cond
+		return s, nil
+	}
+	x, err := p.expr()
+	if err != nil {
+		return s, err
+	}
+	for _, op := range []string{"=", "+=", "-=", "*=", "/="} {
+		if !p.accept(op) {
+			continue
+		}
+		switch x.(type) {
+		case nameExpr, memberExpr, indexExpr:
+		default:
+			return s, p.errorf("cannot assign to an expression")
+		}
+		s.target, s.op = x, op
+		s.value, err = p.expr()
+		return s, err
+	}
+	if _, ok := x.(callExpr); !ok {
+		return s, p.errorf("expected an assignment or a call")
+	}
+	s.value = x
+	return s, nil
+}
+
+// scriptPrecedence are the binary operators from the lowest to the highest
+// precedence.
+var scriptPrecedence = [][]string{
+	{"or"},
+	{"and"},
+	{"==", "!=", "<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *scriptParser) expr() (scriptExpr, error) {
+	return p.binary(0)
+}
+
+func (p *scriptParser) binary(level int) (scriptExpr, error) {
+	if level == len(scriptPrecedence) {
+		return p.unary()
+	}
+	if level == 2 && p.accept("not") {
+		x, err := p.binary(level)
+		return unaryExpr{"not", x}, err
+	}
+	x, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		for _, o := range scriptPrecedence[level] {
+			if p.accept(o) {
+				op = o
+				break
+			}
+		}
+		if op == "" {
+			return x, nil
+		}
+		y, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = binaryExpr{op, x, y}
+	}
+}
+
+func (p *scriptParser) unary() (scriptExpr, error) {
+	if p.accept("-") {
+		x, err := p.unary()
+		return unaryExpr{"-", x}, err
+	}
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			t := p.next()
+			if t.kind != scriptName {
+				p.pos--
+				return nil, p.errorf("expected a name after .")
+			}
+			x = memberExpr{x, t.text}
+		case p.accept("["):
+			i, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.accept("]") {
+				return nil, p.errorf("expected ]")
+			}
+			x = indexExpr{x, i}
+		case p.accept("("):
+			var args []scriptExpr
+			for !p.accept(")") {
+				if len(args) > 0 && !p.accept(",") {
+					return nil, p.errorf("expected , or )")
+				}
+				a, err := p.expr()
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, a)
+			}
+			x = callExpr{x, args}
+		default:
+			return x, nil
+		}
+	}
+}
+
+func (p *scriptParser) primary() (scriptExpr, error) {
+	switch t := p.peek(); {
+	case t.kind == scriptNumber:
+		p.next()
+		return literalExpr{t.num}, nil
+	case t.kind == scriptString:
+		p.next()
+		return literalExpr{t.text}, nil
+	case p.accept("true"):
+		return literalExpr{true}, nil
+	case p.accept("false"):
+		return literalExpr{false}, nil
+	case p.accept("("):
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, p.errorf("expected )")
+		}
+		return x, nil
+	case t.kind == scriptName && !scriptKeywords[t.text]:
+		p.next()
+		return nameExpr(t.text), nil
+	}
+	return nil, p.unexpected()
+}
+
+var scriptKeywords = map[string]bool{"if": true, "and": true, "or": true, "not": true, "in": true, "true": true, "false": true}
+
+type (
+	scriptExpr interface {
+		eval(e *scriptEnv) (interface{}, error)
+	}
+	literalExpr struct{ v interface{} }
+	nameExpr    string
+	memberExpr  struct {
+		x    scriptExpr
+		name string
+	}
+	indexExpr struct{ x, index scriptExpr }
+	callExpr  struct {
+		fn   scriptExpr
+		args []scriptExpr
+	}
+	unaryExpr struct {
+		op string
+		x  scriptExpr
+	}
+	binaryExpr struct {
+		op   string
+		x, y scriptExpr
+	}
+)
+
+// scriptObject is a pattern, track or steps value of a script.
+type scriptObject interface {
+	get(name string) (interface{}, error)
+	set(name string, v interface{}) error
+	call(name string, args []interface{}) (interface{}, error)
+}
+
+// scriptEnv holds the variables of a run of a script.
+type scriptEnv struct {
+	vars map[string]interface{}
+}
+
+func (e *scriptEnv) run(stmts []scriptStmt) error {
+	for _, s := range stmts {
+		if err := e.exec(s); err != nil {
+			return fmt.Errorf("script line %d: %w", s.line, err)
+		}
+	}
+	return nil
+}
+
+func (e *scriptEnv) exec(s scriptStmt) error {
+	if s.cond != nil {
+		v, err := s.cond.eval(e)
+		if err != nil {
+			return err
+		}
+		ok, err := scriptBool(v)
+		if err != nil || !ok {
+			return err
+		}
+		for _, b := range s.body {
+			if err := e.exec(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	v, err := s.value.eval(e)
+	if err != nil || s.target == nil {
+		return err
+	}
+	if s.op != "=" {
+		old, err := s.target.eval(e)
+		if err != nil {
+			return err
+		}
+		if v, err = binaryOp(s.op[:1], old, v); err != nil {
+			return err
+		}
+	}
+	switch t := s.target.(type) {
+	case nameExpr:
+		if _, ok := e.vars[string(t)].(scriptObject); ok {
+			return fmt.Errorf("cannot assign to %s", t)
+		}
+		e.vars[string(t)] = v
+		return nil
+	case memberExpr:
+		obj, err := object(t.x, e)
+		if err != nil {
+			return err
+		}
+		return obj.set(t.name, v)
+	case indexExpr:
+		x, err := t.x.eval(e)
+		if err != nil {
+			return err
+		}
+		steps, ok := x.(scriptSteps)
+		if !ok {
+			return fmt.Errorf("cannot index %s", scriptType(x))
+		}
+		i, err := t.index.eval(e)
+		if err != nil {
+			return err
+		}
+		return steps.setStep(i, v)
+	}
+	return nil
+}
+
+func (x literalExpr) eval(e *scriptEnv) (interface{}, error) {
+	return x.v, nil
+}
+
+func (x nameExpr) eval(e *scriptEnv) (interface{}, error) {
+	v, ok := e.vars[string(x)]
+	if !ok {
+		return nil, fmt.Errorf("unknown variable %s", string(x))
+	}
+	return v, nil
+}
+
+func (x memberExpr) eval(e *scriptEnv) (interface{}, error) {
+	obj, err := object(x.x, e)
+	if err != nil {
+		return nil, err
+	}
+	return obj.get(x.name)
+}
+
+func (x indexExpr) eval(e *scriptEnv) (interface{}, error) {
+	v, err := x.x.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	steps, ok := v.(scriptSteps)
+	if !ok {
+		return nil, fmt.Errorf("cannot index %s", scriptType(v))
+	}
+	i, err := x.index.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	step, err := steps.index(i)
+	if err != nil {
+		return nil, err
+	}
+	return steps.t.steps[step], nil
+}
+
+func (x callExpr) eval(e *scriptEnv) (interface{}, error) {
+	args := make([]interface{}, len(x.args))
+	for i, a := range x.args {
+		v, err := a.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	switch fn := x.fn.(type) {
+	case memberExpr:
+		obj, err := object(fn.x, e)
+		if err != nil {
+			return nil, err
+		}
+		return obj.call(fn.name, args)
+	case nameExpr:
+		f, ok := scriptFuncs[string(fn)]
+		if !ok {
+			return nil, fmt.Errorf("unknown function %s", string(fn))
+		}
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s expects 1 argument but got %d", string(fn), len(args))
+		}
+		return f(args[0])
+	}
+	return nil, errors.New("cannot call an expression")
+}
+
+func (x unaryExpr) eval(e *scriptEnv) (interface{}, error) {
+	v, err := x.x.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	if x.op == "not" {
+		b, err := scriptBool(v)
+		return !b, err
+	}
+	n, err := scriptNum(v)
+	return -n, err
+}
+
+func (x binaryExpr) eval(e *scriptEnv) (interface{}, error) {
+	a, err := x.x.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	if x.op == "and" || x.op == "or" {
+		ok, err := scriptBool(a)
+		if err != nil || ok == (x.op == "or") {
+			return ok, err
+		}
+		b, err := x.y.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		return scriptBool(b)
+	}
+	b, err := x.y.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	return binaryOp(x.op, a, b)
+}
+
+func binaryOp(op string, a, b interface{}) (interface{}, error) {
+	switch op {
+	case "==":
+		return a == b, nil
+	case "!=":
+		return a != b, nil
+	case "in":
+		sa, ok1 := a.(string)
+		sb, ok2 := b.(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("in expects strings but got %s and %s", scriptType(a), scriptType(b))
+		}
+		return strings.Contains(sb, sa), nil
+	}
+	if sa, ok := a.(string); ok {
+		sb, ok := b.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot use %s with string and %s", op, scriptType(b))
+		}
+		switch op {
+		case "+":
+			return sa + sb, nil
+		case "<":
+			return sa < sb, nil
+		case "<=":
+			return sa <= sb, nil
+		case ">":
+			return sa > sb, nil
+		case ">=":
+			return sa >= sb, nil
+		}
+		return nil, fmt.Errorf("cannot use %s with strings", op)
+	}
+	na, err := scriptNum(a)
+	if err != nil {
+		return nil, err
+	}
+	nb, err := scriptNum(b)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "+":
+		return na + nb, nil
+	case "-":
+		return na - nb, nil
+	case "*":
+		return na * nb, nil
+	case "/", "%":
+		if nb == 0 {
+			return nil, errors.New("division by zero")
+		}
+		if op == "%" {
+			return math.Mod(na, nb), nil
+		}
+		return na / nb, nil
+	case "<":
+		return na < nb, nil
+	case "<=":
+		return na <= nb, nil
+	case ">":
+		return na > nb, nil
+	default:
+		return na >= nb, nil
+	}
+}
+
+var scriptFuncs = map[string]func(v interface{}) (interface{}, error){
+	"lower": func(v interface{}) (interface{}, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("lower expects a string but got %s", scriptType(v))
+		}
+		return strings.ToLower(s), nil
+	},
+	"abs": func(v interface{}) (interface{}, error) {
+		n, err := scriptNum(v)
+		return math.Abs(n), err
+	},
+	"round": func(v interface{}) (interface{}, error) {
+		n, err := scriptNum(v)
+		return math.Round(n), err
+	},
+}
+
+// object evaluates x to a pattern, track or steps value.
+func object(x scriptExpr, e *scriptEnv) (scriptObject, error) {
+	v, err := x.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := v.(scriptObject)
+	if !ok {
+		return nil, fmt.Errorf("%s has no fields", scriptType(v))
+	}
+	return obj, nil
+}
+
+func scriptType(v interface{}) string {
+	switch v.(type) {
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case scriptPattern:
+		return "pattern"
+	case scriptTrack:
+		return "track"
+	case scriptSteps:
+		return "steps"
+	default:
+		return "nothing"
+	}
+}
+
+func scriptNum(v interface{}) (float64, error) {
+	n, ok := v.(float64)
+	if !ok {
+		return 0, fmt.Errorf("expected a number but got %s", scriptType(v))
+	}
+	return n, nil
+}
+
+func scriptBool(v interface{}) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected a boolean but got %s", scriptType(v))
+	}
+	return b, nil
+}
+
+func scriptStr(v interface{}) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("expected a string but got %s", scriptType(v))
+	}
+	return s, nil
+}
+
+// scriptInt returns v as whole number from min to max or invalid.
+func scriptInt(v interface{}, min, max int, invalid error) (int, error) {
+	n, err := scriptNum(v)
+	if err != nil {
+		return 0, err
+	}
+	if n != math.Trunc(n) || n < float64(min) || n > float64(max) {
+		return 0, fmt.Errorf("%w %v", invalid, n)
+	}
+	return int(n), nil
+}
+
+type scriptPattern struct{ p *Pattern }
+
+func (s scriptPattern) get(name string) (interface{}, error) {
+	switch name {
+	case "tempo":
+		return float64(s.p.tempo), nil
+	case "swing":
+		return float64(s.p.swing), nil
+	case "version":
+		return s.p.version, nil
+	case "tracks":
+		return float64(len(s.p.tracks)), nil
+	}
+	return nil, fmt.Errorf("pattern has no field %s", name)
+}
+
+func (s scriptPattern) set(name string, v interface{}) error {
+	switch name {
+	case "tempo":
+		n, err := scriptNum(v)
+		if err != nil {
+			return err
+		}
+		return s.p.SetTempo(float32(n))
+	case "swing":
+		n, err := scriptInt(v, 0, MaxSwing, ErrInvalidSwing)
+		if err != nil {
+			return err
+		}
+		return s.p.SetSwing(uint8(n))
+	case "version", "tracks":
+		return fmt.Errorf("pattern.%s is read only", name)
+	}
+	return fmt.Errorf("pattern has no field %s", name)
+}
+
+func (s scriptPattern) call(name string, args []interface{}) (interface{}, error) {
+	return nil, fmt.Errorf("pattern has no method %s", name)
+}
+
+type scriptTrack struct {
+	t     *Track
+	index int
+}
+
+func (s scriptTrack) get(name string) (interface{}, error) {
+	t := s.t
+	switch name {
+	case "id":
+		return float64(t.id), nil
+	case "index":
+		return float64(s.index), nil
+	case "name":
+		return t.name, nil
+	case "muted":
+		return t.muted, nil
+	case "solo":
+		return t.solo, nil
+	case "volume":
+		return float64(t.Volume()), nil
+	case "pan":
+		return float64(t.pan), nil
+	case "nudge":
+		return t.Nudge(), nil
+	case "length":
+		return float64(t.length), nil
+	case "choke":
+		return float64(t.choke), nil
+	case "role":
+		return t.Role().String(), nil
+	}
+	return nil, fmt.Errorf("track has no field %s", name)
+}
+
+func (s scriptTrack) set(name string, v interface{}) error {
+	t := s.t
+	switch name {
+	case "name":
+		n, err := scriptStr(v)
+		if err != nil {
+			return err
+		}
+		if len(n) > math.MaxUint8 {
+			return ErrNameTooLong
+		}
+		t.name = n
+	case "muted", "solo":
+		b, err := scriptBool(v)
+		if err != nil {
+			return err
+		}
+		if name == "muted" {
+			t.muted = b
+		} else {
+			t.solo = b
+		}
+	case "volume":
+		n, err := scriptInt(v, 0, MaxVolume, ErrInvalidVolume)
+		if err != nil {
+			return err
+		}
+		return t.SetVolume(uint8(n))
+	case "pan":
+		n, err := scriptInt(v, MinPan, MaxPan, ErrInvalidPan)
+		if err != nil {
+			return err
+		}
+		return t.SetPan(int8(n))
+	case "nudge":
+		n, err := scriptNum(v)
+		if err != nil {
+			return err
+		}
+		return t.SetNudge(n)
+	case "length":
+		n, err := scriptInt(v, 0, stepsLength, ErrInvalidLength)
+		if err != nil {
+			return err
+		}
+		return t.SetLength(n)
+	case "choke":
+		n, err := scriptInt(v, 0, MaxChokeGroup, ErrInvalidChokeGroup)
+		if err != nil {
+			return err
+		}
+		return t.SetChokeGroup(n)
+	case "id", "index", "role":
+		return fmt.Errorf("track.%s is read only", name)
+	default:
+		return fmt.Errorf("track has no field %s", name)
+	}
+	return nil
+}
+
+func (s scriptTrack) call(name string, args []interface{}) (interface{}, error) {
+	return nil, fmt.Errorf("track has no method %s", name)
+}
+
+// scriptSteps are the steps of a track of the pattern p, indexed within its
+// first bar. Shifting and reversing moves them like the transforms of
+// Pattern.
+type scriptSteps struct {
+	p *Pattern
+	t *Track
+}
+
+// index returns the step of the index i within the loop of the track.
+func (s scriptSteps) index(i interface{}) (int, error) {
+	return scriptInt(i, 0, s.p.trackLength(s.t)-1, ErrStepOutOfRange)
+}
+
+func (s scriptSteps) get(name string) (interface{}, error) {
+	if name == "count" {
+		n := 0
+		for _, on := range s.t.steps[:s.p.trackLength(s.t)] {
+			if on {
+				n++
+			}
+		}
+		return float64(n), nil
+	}
+	return nil, fmt.Errorf("steps have no field %s", name)
+}
+
+func (s scriptSteps) set(name string, v interface{}) error {
+	if name == "count" {
+		return errors.New("steps.count is read only")
+	}
+	return fmt.Errorf("steps have no field %s", name)
+}
+
+func (s scriptSteps) setStep(i, v interface{}) error {
+	step, err := s.index(i)
+	if err != nil {
+		return err
+	}
+	on, err := scriptBool(v)
+	if err != nil {
+		return err
+	}
+	return s.t.SetStep(step, on)
+}
+
+func (s scriptSteps) call(name string, args []interface{}) (interface{}, error) {
+	want := 0
+	if name == "shift" {
+		want = 1
+	}
+	if len(args) != want {
+		return nil, fmt.Errorf("steps.%s expects %d arguments but got %d", name, want, len(args))
+	}
+	switch name {
+	case "shift":
+		n, err := scriptInt(args[0], math.MinInt32, math.MaxInt32, errors.New("invalid shift"))
+		if err != nil {
+			return nil, err
+		}
+		s.p.transformTrack(s.t, rotateSteps(n))
+	case "reverse":
+		s.p.transformTrack(s.t, reverseSteps)
+	case "clear":
+		for b := 0; b < MaxBars; b++ {
+			*s.t.barSteps(b) = Steps{}
+		}
+	default:
+		return nil, fmt.Errorf("steps have no method %s", name)
+	}
+	return nil, nil
+}
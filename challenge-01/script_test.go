@@ -0,0 +1,131 @@
+package drum
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestScriptApply(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := ParseScript(`# edit the fixture
+if track.name == 'clap': steps.shift(1)
+if track.name in "snare clap" and not track.muted: track.volume = 100; track.nudge = -0.125
+if track.index == 0: pattern.tempo *= 1.5; pattern.swing = 50 + 4 % 3
+if track.name == "kick" and steps.count >= 4 and steps[0]: track.name = lower("KICK") + "-" + "808"; steps[1] = true
+if track.id == 4: steps.reverse(); track.pan = -abs(round(20.4))
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Apply(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.tempo != 120 || p.tracks[2].steps[5] {
+		t.Error("Expected the pattern unchanged")
+	}
+	if got.tempo != 180 || got.swing != 51 {
+		t.Errorf("Expected the tempo and swing changed once but got %v and %d", got.tempo, got.swing)
+	}
+	kick, snare, clap, hh := got.tracks[0], got.tracks[1], got.tracks[2], got.tracks[4]
+	if kick.name != "kick-808" || !kick.steps[1] {
+		t.Errorf("Expected the kick renamed with step 1 enabled but got %v", kick)
+	}
+	if clap.steps != (Steps{5: true, 7: true}) {
+		t.Errorf("Expected the clap shifted but got %v", clap.steps)
+	}
+	for _, tr := range []*Track{snare, clap} {
+		if tr.Volume() != 100 || tr.Nudge() != -0.125 {
+			t.Errorf("Expected %s at volume 100 nudged early but got %d and %v", tr.name, tr.Volume(), tr.Nudge())
+		}
+	}
+	if exp := p.Reverse().tracks[4].steps; hh.steps != exp || hh.pan != -20 {
+		t.Errorf("Expected the hi-hat reversed and panned but got %v at %d", hh.steps, hh.pan)
+	}
+}
+
+func TestScriptStepsLikeTransforms(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.AddBar(0); err != nil {
+		t.Fatal(err)
+	}
+	for _, tr := range p.tracks {
+		tr.SetRatchet(2, 2)
+		tr.SetProbability(2, 50)
+		tr.SetLength(12)
+	}
+	s, err := ParseScript("steps.shift(3); steps.reverse()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Apply(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := p.Rotate(3).Reverse(); !got.Equal(exp) {
+		t.Errorf("Expected the same as the transforms but got\n%s\nexpected\n%s", got, exp)
+	}
+}
+
+func TestScriptErrors(t *testing.T) {
+	for src, exp := range map[string]string{
+		"if track.name == 'clap' steps.shift(1)": "invalid script: line 1: expected : after the condition",
+		"\n\ntrack.name = 'x":                    "invalid script: line 3: unterminated string",
+		"steps.count":                            "invalid script: line 1: expected an assignment or a call",
+		"1 = 2":                                  "invalid script: line 1: cannot assign to an expression",
+		"x = (1 + 2":                             "invalid script: line 1: expected )",
+		"x = 1 2":                                "invalid script: line 1: unexpected \"2\"",
+		"x = if":                                 "invalid script: line 1: unexpected \"if\"",
+		"x = 1 $ 2":                              "invalid script: line 1: unexpected '$'",
+		"x = 1 +":                                "invalid script: line 1: unexpected end of script",
+	} {
+		if _, err := ParseScript(src); !errors.Is(err, ErrInvalidScript) || err.Error() != exp {
+			t.Errorf("%q: expected %s but got %v", src, exp, err)
+		}
+	}
+
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for src, exp := range map[string]string{
+		"x = y":                               "track 0: script line 1: unknown variable y",
+		"\npattern.tempo = 0":                 "track 0: script line 2: invalid tempo",
+		"track.volume = 128":                  "track 0: script line 1: invalid volume 128",
+		"steps[16] = true":                    "track 0: script line 1: step out of range 16",
+		"track.length = 12; steps[12] = true": "track 0: script line 1: step out of range 12",
+		"steps[0] = 1":                        "track 0: script line 1: expected a boolean but got number",
+		"track.id = 2":                        "track 0: script line 1: track.id is read only",
+		"track.color = 'red'":                 "track 0: script line 1: track has no field color",
+		"steps.shift()":                       "track 0: script line 1: steps.shift expects 1 arguments but got 0",
+		"track = 1":                           "track 0: script line 1: cannot assign to track",
+		"x = track.name + 1":                  "track 0: script line 1: cannot use + with string and number",
+		"x = 1 / 0":                           "track 0: script line 1: division by zero",
+		"if track.name: x = 1":                "track 0: script line 1: expected a boolean but got string",
+		"if track.id == 1: x = y":             "track 1: script line 1: unknown variable y",
+		"x = 1 in 'a'":                        "track 0: script line 1: in expects strings but got number and string",
+		"x = track.name.size":                 "track 0: script line 1: string has no fields",
+		"x = steps()":                         "track 0: script line 1: unknown function steps",
+		"x = lower(1)":                        "track 0: script line 1: lower expects a string but got number",
+	} {
+		s, err := ParseScript(src)
+		if err != nil {
+			t.Fatalf("%q: %v", src, err)
+		}
+		if _, err := s.Apply(p); err == nil || err.Error() != exp {
+			t.Errorf("%q: expected %s but got %v", src, exp, err)
+		}
+	}
+	s, _ := ParseScript("pattern.tempo = 0")
+	if _, err := s.Apply(p); !errors.Is(err, ErrInvalidTempo) || !strings.HasPrefix(err.Error(), "track 0") {
+		t.Errorf("Expected %v but got %v", ErrInvalidTempo, err)
+	}
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// problemWriteTimeout is the time to answer requests that timed out.
+const problemWriteTimeout = time.Second
+
+// problem is an error response as RFC 7807 problem details.
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Class is the drum.ErrorClass of decoding failures.
+	Class string `json:"class,omitempty"`
+}
+
+// writeProblem answers with the status and the problem details.
+func writeProblem(w http.ResponseWriter, status int, detail, class string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Class:  class,
+	})
+}
+
+// writeDecodeProblem answers a failed read or decode of an upload: 413 for
+// uploads above the limits, 408 for uploads not received in time and 400
+// for malformed ones.
+func writeDecodeProblem(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeProblem(w, http.StatusRequestEntityTooLarge, err.Error(), "")
+	case errors.Is(err, drum.ErrLimitExceeded):
+		writeProblem(w, http.StatusRequestEntityTooLarge, err.Error(), drum.ErrorClass(err))
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		// the write deadline passed with the read deadline
+		http.NewResponseController(w).SetWriteDeadline(time.Now().Add(problemWriteTimeout))
+		w.Header().Set("Connection", "close")
+		writeProblem(w, http.StatusRequestTimeout, err.Error(), "")
+	default:
+		writeProblem(w, http.StatusBadRequest, err.Error(), drum.ErrorClass(err))
+	}
+}
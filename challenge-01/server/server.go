@@ -0,0 +1,331 @@
+// Package server exposes the pattern codecs and visualizations over HTTP.
+//
+// The handler serves the following endpoints:
+//
+//	POST /decode       .splice file in the body or as multipart field "file", returns JSON
+//	POST /encode       JSON pattern in the body, returns the .splice file
+//	GET  /render.svg   ?pattern=<URL safe base64 of a .splice file>[&theme=dark][&cell=<px>]
+//	GET  /play         ?pattern=<URL safe base64 of a .splice file>, WebSocket, see handler.play
+//	GET  /metrics      Prometheus metrics of the decodes and renders, see package metrics
+//
+// Patterns passed in the query of /render.svg make grooves shareable as a
+// plain link.
+//
+// Uploads are capped at the size of the largest file within the decoder
+// limits, the number of decodes running at the same time and the time of a
+// request are limited, see the options of Handler. Errors are answered as
+// RFC 7807 problem details with the content type application/problem+json:
+//
+//	{"type":"about:blank","title":"Payload Too Large","status":413,"detail":"...","class":"payload too large"}
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strconv"
+	"time"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+	"github.com/alpe/go-challenge/challenge-01/metrics"
+)
+
+// MaxPatternSize is the largest request body accepted by /encode and of
+// WebSocket messages. Uploads to /decode are capped by the decoder limits,
+// see WithLimits.
+const MaxPatternSize = 1 << 20
+
+// maxFormOverhead is accepted on top of the file size for the headers and
+// boundaries of multipart uploads.
+const maxFormOverhead = 16 << 10
+
+// maxCellSize limits the size of rendered images.
+const maxCellSize = 200
+
+// defaultTimeout is the time a request may take, see WithTimeout.
+const defaultTimeout = 10 * time.Second
+
+// service is the service label of the metrics.
+const service = "http"
+
+// recordDecode is passed to every decode of a request.
+var recordDecode = drum.WithStats(metrics.DecodeObserver(service))
+
+// Option configures the handler.
+type Option func(*handler)
+
+// WithLimits replaces the default limits of the decoder, drum.DefaultLimits.
+// Uploads larger than the largest file within the limits are rejected with
+// 413 before they are decoded. Limits{} lifts the caps for trusted clients.
+func WithLimits(l drum.Limits) Option {
+	return func(h *handler) {
+		h.limits = l
+	}
+}
+
+// WithMaxConcurrentDecodes limits the number of patterns decoded at the same
+// time, twice the number of CPUs by default. Requests above the limit are
+// not queued but answered with 503 and a Retry-After header, so that a
+// flood of uploads cannot pile up memory. Zero or less disables the limit.
+func WithMaxConcurrentDecodes(n int) Option {
+	return func(h *handler) {
+		h.decodes = nil
+		if n > 0 {
+			h.decodes = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithTimeout sets the time to read a request and write its response, 10
+// seconds by default. Uploads not received in time are answered with 408.
+// WebSocket streams of /play are not limited. Zero or less disables the
+// timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(h *handler) {
+		h.timeout = d
+	}
+}
+
+// WithAllowedOrigins allows web pages of the origins, like
+// "https://studio.example.com", to open the WebSocket of /play. Pages of
+// the same host as the server are always allowed, and so are clients
+// without an Origin header, which are not browsers. Other origins are
+// rejected with 403, so that foreign pages cannot control the playback of
+// a visitor's server.
+func WithAllowedOrigins(origins ...string) Option {
+	return func(h *handler) {
+		h.origins = append(h.origins, origins...)
+	}
+}
+
+// handler serves the endpoints.
+type handler struct {
+	config  drum.Config
+	limits  drum.Limits
+	decodes chan struct{} // semaphore of the decodes running, nil when not limited
+	timeout time.Duration
+	origins []string // allowed for WebSockets besides the host
+}
+
+// New returns the handler serving all endpoints, which decodes, encodes and
+// plays patterns with the options of the config, like drum.V2Defaults().
+// The limits of the options apply on top of the config.
+func New(c drum.Config, opts ...Option) http.Handler {
+	h := &handler{config: c, limits: drum.DefaultLimits(), timeout: defaultTimeout}
+	WithMaxConcurrentDecodes(2 * runtime.NumCPU())(h)
+	for _, o := range opts {
+		o(h)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/decode", h.decode)
+	mux.HandleFunc("/encode", h.encode)
+	mux.HandleFunc("/render.svg", h.render)
+	mux.HandleFunc("/play", h.play)
+	mux.Handle("/metrics", metrics.Handler())
+	return mux
+}
+
+// Handler returns the handler serving all endpoints with the defaults of
+// drum.V1Defaults().
+//
+// Deprecated: Use New, which takes the defaults to serve with.
+func Handler(opts ...Option) http.Handler {
+	return New(drum.V1Defaults(), opts...)
+}
+
+// Serve serves the endpoints of New on the TCP address addr. It fails with
+// drum.ErrOffline in offline mode.
+func Serve(addr string, c drum.Config, opts ...Option) error {
+	if err := drum.AllowNetwork("server"); err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           New(c, opts...),
+		ReadHeaderTimeout: defaultTimeout,
+	}
+	return srv.ListenAndServe()
+}
+
+// ListenAndServe serves the endpoints with the defaults of
+// drum.V1Defaults() on the TCP address addr.
+//
+// Deprecated: Use Serve, which takes the defaults to serve with.
+func ListenAndServe(addr string, opts ...Option) error {
+	return Serve(addr, drum.V1Defaults(), opts...)
+}
+
+// setDeadline applies the timeout to the request. Writers not supporting
+// deadlines, like recorders of tests, are served without.
+func (h *handler) setDeadline(w http.ResponseWriter) {
+	if h.timeout <= 0 {
+		return
+	}
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(h.timeout)
+	rc.SetReadDeadline(deadline)
+	rc.SetWriteDeadline(deadline)
+}
+
+// acquire reserves a decode and returns the function releasing it. When all
+// decodes are taken, it answers with 503 and returns nil.
+func (h *handler) acquire(w http.ResponseWriter) func() {
+	if h.decodes == nil {
+		return func() {}
+	}
+	select {
+	case h.decodes <- struct{}{}:
+		return func() { <-h.decodes }
+	default:
+		w.Header().Set("Retry-After", "1")
+		writeProblem(w, http.StatusServiceUnavailable, "too many concurrent decodes, retry later", "")
+		return nil
+	}
+}
+
+// decodeOptions returns the options of every decode of a request, those of
+// the config followed by the limits.
+func (h *handler) decodeOptions() []drum.DecodeOption {
+	opts := append([]drum.DecodeOption(nil), h.config.DecodeOptions...)
+	return append(opts, drum.WithLimits(h.limits), recordDecode)
+}
+
+func (h *handler) decode(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	h.setDeadline(w)
+	multipart := false
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "multipart/form-data" {
+		multipart = true
+	}
+	if max := h.limits.MaxFileSize(); max > 0 {
+		if multipart {
+			max += maxFormOverhead
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+	}
+	release := h.acquire(w)
+	if release == nil {
+		return
+	}
+	defer release()
+	var body io.Reader = r.Body
+	if multipart {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			writeDecodeProblem(w, fmt.Errorf("read upload: %w", err))
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+	p, err := drum.Decode(body, h.decodeOptions()...)
+	if err != nil {
+		writeDecodeProblem(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(p)
+}
+
+func (h *handler) encode(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	h.setDeadline(w)
+	var p drum.Pattern
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxPatternSize)).Decode(&p); err != nil {
+		writeDecodeProblem(w, fmt.Errorf("parse pattern: %w", err))
+		return
+	}
+	var buf bytes.Buffer
+	if err := h.config.Encode(&buf, &p); err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="pattern.splice"`)
+	buf.WriteTo(w)
+}
+
+func (h *handler) render(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	h.setDeadline(w)
+	q := r.URL.Query()
+	style := drum.DefaultGridStyle
+	if q.Get("theme") == "dark" {
+		style = drum.DarkGridStyle
+	}
+	if v := q.Get("cell"); v != "" {
+		cell, err := strconv.Atoi(v)
+		if err != nil || cell < 1 || cell > maxCellSize {
+			writeProblem(w, http.StatusBadRequest, fmt.Sprintf("invalid cell size %q", v), "")
+			return
+		}
+		style.CellSize = cell
+	}
+	if v := q.Get("zoom"); v != "" {
+		switch r, _ := strconv.Atoi(v); drum.Resolution(r) {
+		case drum.Eighth, drum.Sixteenth, drum.ThirtySecond:
+			style.Resolution = drum.Resolution(r)
+		default:
+			writeProblem(w, http.StatusBadRequest, fmt.Sprintf("invalid zoom %q, expected 8, 16 or 32", v), "")
+			return
+		}
+	}
+	p, ok := h.patternParam(w, q)
+	if !ok {
+		return
+	}
+	var buf bytes.Buffer
+	start := time.Now()
+	if err := p.ToSVG(&buf, style); err != nil {
+		writeProblem(w, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+	metrics.ObserveRender(service, "svg", time.Since(start))
+	w.Header().Set("Content-Type", "image/svg+xml")
+	buf.WriteTo(w)
+}
+
+// patternParam decodes the pattern query parameter. On failure the problem
+// has been written.
+func (h *handler) patternParam(w http.ResponseWriter, q url.Values) (*drum.Pattern, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(q.Get("pattern"))
+	if err != nil || len(data) == 0 || len(data) > MaxPatternSize {
+		writeProblem(w, http.StatusBadRequest, "parse pattern: invalid or missing pattern parameter", "")
+		return nil, false
+	}
+	release := h.acquire(w)
+	if release == nil {
+		return nil, false
+	}
+	defer release()
+	p, err := drum.DecodeBytes(data, h.decodeOptions()...)
+	if err != nil {
+		writeDecodeProblem(w, err)
+		return nil, false
+	}
+	return p, true
+}
+
+// allowMethod answers requests with another method than m with 405.
+func allowMethod(w http.ResponseWriter, r *http.Request, m string) bool {
+	if r.Method == m {
+		return true
+	}
+	w.Header().Set("Allow", m)
+	writeProblem(w, http.StatusMethodNotAllowed, "", "")
+	return false
+}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+func TestDecodeEncode(t *testing.T) {
+	raw, err := ioutil.ReadFile(filepath.Join("..", "fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(New(drum.V1Defaults()))
+	defer srv.Close()
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	fw, _ := mw.CreateFormFile("file", "pattern_1.splice")
+	fw.Write(raw)
+	mw.Close()
+	for name, req := range map[string]struct {
+		contentType string
+		body        []byte
+	}{
+		"raw":       {"application/octet-stream", raw},
+		"multipart": {mw.FormDataContentType(), form.Bytes()},
+	} {
+		resp, err := http.Post(srv.URL+"/decode", req.contentType, bytes.NewReader(req.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		js, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(js), `"version": "0.808-alpha"`) {
+			t.Fatalf("%s: unexpected response %d: %s", name, resp.StatusCode, js)
+		}
+
+		resp, err = http.Post(srv.URL+"/encode", "application/json", bytes.NewReader(js))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: unexpected response %d: %s", name, resp.StatusCode, got)
+		}
+		p, err := drum.Decode(bytes.NewReader(got))
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp, _ := drum.Decode(bytes.NewReader(raw))
+		if !p.Equal(exp) {
+			t.Errorf("%s: expected %v but got %v", name, exp, p)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	raw, err := ioutil.ReadFile(filepath.Join("..", "fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(drum.V1Defaults())
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/render.svg?theme=dark&cell=10&pattern="+base64.RawURLEncoding.EncodeToString(raw), nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/svg+xml" || !strings.HasPrefix(rec.Body.String(), "<svg") {
+		t.Errorf("unexpected response %d: %s", rec.Code, rec.Body)
+	}
+	cells := strings.Count(rec.Body.String(), "<rect")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/render.svg?zoom=8&pattern="+base64.RawURLEncoding.EncodeToString(raw), nil))
+	if zoomed := strings.Count(rec.Body.String(), "<rect"); rec.Code != http.StatusOK || zoomed-1 != (cells-1)/2 {
+		t.Errorf("Expected half the cells zoomed out but got %d of %d", zoomed, cells)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	specs := map[string]struct {
+		method, target, body string
+		code                 int
+	}{
+		"decode garbage":   {"POST", "/decode", "garbage", http.StatusBadRequest},
+		"decode with get":  {"GET", "/decode", "", http.StatusMethodNotAllowed},
+		"encode invalid":   {"POST", "/encode", `{"version":"v","tempo":0,"tracks":[]}`, http.StatusBadRequest},
+		"render missing":   {"GET", "/render.svg", "", http.StatusBadRequest},
+		"render bad cell":  {"GET", "/render.svg?cell=-1&pattern=U1BMSUNF", "", http.StatusBadRequest},
+		"render bad zoom":  {"GET", "/render.svg?zoom=4&pattern=U1BMSUNF", "", http.StatusBadRequest},
+		"render with post": {"POST", "/render.svg", "", http.StatusMethodNotAllowed},
+	}
+	h := New(drum.V1Defaults())
+	for msg, spec := range specs {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(spec.method, spec.target, strings.NewReader(spec.body)))
+		if rec.Code != spec.code {
+			t.Errorf("%s: expected status %d but got %d: %s", msg, spec.code, rec.Code, rec.Body)
+		}
+		var prob problem
+		if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+			t.Errorf("%s: unexpected content type %q", msg, ct)
+		} else if err := json.Unmarshal(rec.Body.Bytes(), &prob); err != nil || prob.Status != spec.code {
+			t.Errorf("%s: unexpected problem %s: %v", msg, rec.Body, err)
+		}
+	}
+}
+
+func TestUploadLimits(t *testing.T) {
+	raw, err := ioutil.ReadFile(filepath.Join("..", "fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	fw, _ := mw.CreateFormFile("file", "huge.splice")
+	fw.Write(make([]byte, drum.DefaultLimits().MaxFileSize()+maxFormOverhead))
+	mw.Close()
+	specs := map[string]struct {
+		limits      drum.Limits
+		contentType string
+		body        []byte
+		code        int
+		class       string
+	}{
+		"within":          {drum.DefaultLimits(), "application/octet-stream", raw, http.StatusOK, ""},
+		"body too large":  {drum.DefaultLimits(), mw.FormDataContentType(), form.Bytes(), http.StatusRequestEntityTooLarge, ""},
+		"too many tracks": {drum.Limits{MaxTracks: 2}, "application/octet-stream", raw, http.StatusRequestEntityTooLarge, "too many tracks"},
+		"malformed":       {drum.DefaultLimits(), "application/octet-stream", raw[:20], http.StatusBadRequest, "truncated payload"},
+	}
+	for msg, spec := range specs {
+		req := httptest.NewRequest("POST", "/decode", bytes.NewReader(spec.body))
+		req.Header.Set("Content-Type", spec.contentType)
+		rec := httptest.NewRecorder()
+		New(drum.V1Defaults(), WithLimits(spec.limits)).ServeHTTP(rec, req)
+		if rec.Code != spec.code {
+			t.Errorf("%s: expected status %d but got %d: %s", msg, spec.code, rec.Code, rec.Body)
+			continue
+		}
+		if spec.code == http.StatusOK {
+			continue
+		}
+		var prob problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &prob); err != nil {
+			t.Fatalf("%s: %v", msg, err)
+		}
+		if prob.Class != spec.class {
+			t.Errorf("%s: expected class %q but got %q", msg, spec.class, prob.Class)
+		}
+	}
+}
+
+func TestMaxConcurrentDecodes(t *testing.T) {
+	h := &handler{limits: drum.DefaultLimits()}
+	WithMaxConcurrentDecodes(1)(h)
+	h.decodes <- struct{}{} // a decode is running
+	rec := httptest.NewRecorder()
+	h.decode(rec, httptest.NewRequest("POST", "/decode", strings.NewReader("SPLICE")))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("unexpected response %d: %s", rec.Code, rec.Body)
+	}
+	<-h.decodes
+	rec = httptest.NewRecorder()
+	h.decode(rec, httptest.NewRequest("POST", "/decode", strings.NewReader("SPLICE")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d but got %d: %s", http.StatusBadRequest, rec.Code, rec.Body)
+	}
+}
+
+func TestSlowUpload(t *testing.T) {
+	srv := httptest.NewServer(New(drum.V1Defaults(), WithTimeout(50*time.Millisecond)))
+	defer srv.Close()
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// the body is announced but never sent completely
+	fmt.Fprintf(conn, "POST /decode HTTP/1.1\r\nHost: splice\r\nContent-Length: 100\r\n\r\nSPLICE")
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestTimeout {
+		body, _ := ioutil.ReadAll(resp.Body)
+		t.Errorf("expected status %d but got %d: %s", http.StatusRequestTimeout, resp.StatusCode, body)
+	}
+}
+
+func TestServeOffline(t *testing.T) {
+	drum.SetOffline(true)
+	defer drum.SetOffline(false)
+	if err := Serve("127.0.0.1:0", drum.V1Defaults()); err == nil || !strings.Contains(err.Error(), "offline") {
+		t.Errorf("expected offline error, got %v", err)
+	}
+}
+
+func TestNewConfig(t *testing.T) {
+	raw, err := ioutil.ReadFile(filepath.Join("..", "fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the tempo of the fixture follows the header and the version
+	binary.LittleEndian.PutUint32(raw[14+32:], math.Float32bits(0))
+	for _, spec := range []struct {
+		h   http.Handler
+		exp float64
+	}{
+		{Handler(), 0},
+		{New(drum.V2Defaults()), drum.DefaultTempo},
+	} {
+		rec := httptest.NewRecorder()
+		spec.h.ServeHTTP(rec, httptest.NewRequest("POST", "/decode", bytes.NewReader(raw)))
+		var got struct{ Tempo float64 }
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || got.Tempo != spec.exp {
+			t.Errorf("expected tempo %v, got %v: %v", spec.exp, got.Tempo, err)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	raw, err := ioutil.ReadFile(filepath.Join("..", "fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(New(drum.V1Defaults()))
+	defer srv.Close()
+	resp, err := http.Post(srv.URL+"/decode", "application/octet-stream", strings.NewReader("GIF89a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	resp, err = http.Get(srv.URL + "/render.svg?pattern=" + base64.RawURLEncoding.EncodeToString(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, exp := range []string{
+		`splice_decodes_total{service="http",result="unsupported_file_format"} `,
+		`splice_decodes_total{service="http",result="ok"} `,
+		`splice_render_duration_seconds_count{service="http",format="svg"} `,
+	} {
+		if !strings.Contains(string(body), exp) {
+			t.Errorf("Expected '%v' in:\n%s", exp, body)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// eventBuffer is the number of step events queued for a slow client before
+// events are dropped.
+const eventBuffer = 64
+
+// stepEventJSON is the message sent for every step played.
+type stepEventJSON struct {
+	Step    int           `json:"step"`
+	Section int           `json:"section,omitempty"`
+	Time    time.Time     `json:"time"`
+	Tracks  []triggerJSON `json:"tracks"`
+}
+
+// triggerJSON is a track triggered at a step. Index is the position of the
+// track in the pattern as used by the mute and solo commands.
+type triggerJSON struct {
+	Index    int     `json:"index"`
+	ID       uint32  `json:"id"`
+	Name     string  `json:"name"`
+	Velocity uint8   `json:"velocity"`
+	Offset   float64 `json:"offset"` // micro timing offset in milliseconds
+}
+
+// command is a message sent by the client:
+//
+//	{"cmd": "hello", "features": ["velocity"]} features the client supports
+//	{"cmd": "tempo", "tempo": 128}             0 resets to the pattern tempo
+//	{"cmd": "mute", "track": 1, "on": true}
+//	{"cmd": "solo", "track": 1, "on": true}
+//	{"cmd": "pattern", "pattern": {...}}       pattern JSON as returned by /decode
+type command struct {
+	Cmd      string         `json:"cmd"`
+	Tempo    float32        `json:"tempo"`
+	Track    int            `json:"track"`
+	On       bool           `json:"on"`
+	Pattern  *drum.Pattern  `json:"pattern"`
+	Features []drum.Feature `json:"features"`
+}
+
+// helloJSON answers the hello command with the features supported by both
+// sides and the playing pattern without the data of the other features,
+// which are listed as dropped. Patterns sent by the client afterwards keep
+// the data of the dropped features, see drum.Pattern.Upgrade.
+type helloJSON struct {
+	Features []drum.Feature `json:"features"`
+	Dropped  []drum.Feature `json:"dropped,omitempty"`
+	Pattern  *drum.Pattern  `json:"pattern"`
+}
+
+// errorJSON is sent when a command fails. The connection stays open.
+type errorJSON struct {
+	Error string `json:"error"`
+}
+
+// stream plays a pattern for a single WebSocket client.
+type stream struct {
+	conn   *wsConn
+	player *drum.Player
+	events chan []byte
+
+	mu       sync.Mutex
+	pattern  *drum.Pattern // pattern handed to the player, never modified
+	index    map[*drum.Track]int
+	features []drum.Feature // negotiated by hello, nil for all
+}
+
+// play upgrades to a WebSocket and streams the step events of the
+// pattern from the query as JSON messages in real time until the client
+// disconnects. Client commands change the tempo, mute and solo tracks or
+// swap the pattern while playing.
+func (h *handler) play(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.patternParam(w, r.URL.Query())
+	if !ok {
+		return
+	}
+	conn, err := upgrade(w, r, h.origins)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	s := &stream{conn: conn, events: make(chan []byte, eventBuffer), index: make(map[*drum.Track]int)}
+	s.player = h.config.NewPlayer(nil, s.handle)
+	s.setPattern(p)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go s.write(done)
+	go func() {
+		if err := s.player.Play(stop); err != nil {
+			s.send(errorJSON{err.Error()})
+		}
+	}()
+	s.read()
+	close(stop)
+	close(done)
+}
+
+// setPattern hands a new pattern to the player. Tracks of earlier patterns
+// keep their index for events still in flight.
+func (s *stream) setPattern(p *drum.Pattern) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pattern = p
+	for i, t := range p.Tracks() {
+		s.index[t] = i
+	}
+	s.player.SetPattern(p)
+}
+
+// handle queues a step event. It is called by the player and must not block.
+func (s *stream) handle(ev drum.StepEvent) {
+	msg := stepEventJSON{Step: ev.Step, Section: ev.Section, Time: ev.Time, Tracks: []triggerJSON{}}
+	s.mu.Lock()
+	for i, t := range ev.Tracks {
+		msg.Tracks = append(msg.Tracks, triggerJSON{
+			Index:    s.index[t],
+			ID:       t.ID(),
+			Name:     t.Name(),
+			Velocity: ev.Velocities[i],
+			Offset:   ev.Offsets[i].Seconds() * 1000,
+		})
+	}
+	s.mu.Unlock()
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	select {
+	case s.events <- data:
+	default: // client too slow, drop the event
+	}
+}
+
+// write sends the queued events until done is closed.
+func (s *stream) write(done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case data := <-s.events:
+			if s.conn.writeFrame(opText, data) != nil {
+				return
+			}
+		}
+	}
+}
+
+// send writes a message immediately.
+func (s *stream) send(v interface{}) {
+	if data, err := json.Marshal(v); err == nil {
+		s.conn.writeFrame(opText, data)
+	}
+}
+
+// read executes client commands until the connection is closed.
+func (s *stream) read() {
+	for {
+		data, err := s.conn.readMessage()
+		if err != nil {
+			return
+		}
+		var c command
+		if err := json.Unmarshal(data, &c); err != nil {
+			s.send(errorJSON{fmt.Sprintf("parse command: %v", err)})
+			continue
+		}
+		if err := s.exec(c); err != nil {
+			s.send(errorJSON{err.Error()})
+		}
+	}
+}
+
+func (s *stream) exec(c command) error {
+	switch c.Cmd {
+	case "hello":
+		features := drum.Negotiate(drum.SupportedFeatures(), c.Features)
+		if features == nil {
+			features = []drum.Feature{}
+		}
+		s.mu.Lock()
+		s.features = features
+		p, dropped := s.pattern.Downgrade(features)
+		s.mu.Unlock()
+		s.send(helloJSON{features, dropped, p})
+	case "tempo":
+		if c.Tempo != 0 && (c.Tempo < drum.MinTempo || c.Tempo > drum.MaxTempo) {
+			return fmt.Errorf("tempo: %v", drum.ErrInvalidTempo)
+		}
+		s.player.SetTempo(c.Tempo)
+	case "mute", "solo":
+		s.mu.Lock()
+		tracks := s.pattern.Tracks()
+		s.mu.Unlock()
+		if c.Track < 0 || c.Track >= len(tracks) {
+			return fmt.Errorf("%s: track %d out of range", c.Cmd, c.Track)
+		}
+		if c.Cmd == "mute" {
+			s.player.SetMute(c.Track, c.On)
+			return nil
+		}
+		s.mu.Lock()
+		p := s.pattern.Clone()
+		s.mu.Unlock()
+		if t := p.Tracks()[c.Track]; t.Soloed() != c.On {
+			t.Solo()
+		}
+		s.setPattern(p)
+	case "pattern":
+		if c.Pattern == nil {
+			return fmt.Errorf("pattern: missing pattern")
+		}
+		p := c.Pattern
+		s.mu.Lock()
+		current, features := s.pattern, s.features
+		s.mu.Unlock()
+		if features != nil {
+			var err error
+			if p, err = p.Upgrade(current, features); err != nil {
+				return fmt.Errorf("pattern: %v", err)
+			}
+		}
+		s.setPattern(p)
+	default:
+		return fmt.Errorf("unknown command %q", c.Cmd)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package server
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// wsClient is a minimal WebSocket client for the tests.
+type wsClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialPlay(t *testing.T, url, fixture string) *wsClient {
+	raw, err := ioutil.ReadFile(filepath.Join("..", "fixtures", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dialPlayRaw(t, url, raw)
+}
+
+func dialPlayRaw(t *testing.T, url string, raw []byte) *wsClient {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	fmt.Fprintf(conn, "GET /play?pattern=%s HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n",
+		base64.RawURLEncoding.EncodeToString(raw))
+	c := &wsClient{conn, bufio.NewReader(conn)}
+	resp, err := http.ReadResponse(c.r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// accept value of the example in RFC 6455
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected handshake response %v", resp)
+	}
+	return c
+}
+
+func (c *wsClient) send(t *testing.T, op byte, msg []byte) {
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | op, 0x80 | byte(len(msg))}
+	if len(msg) >= 126 {
+		frame = []byte{0x80 | op, 0x80 | 126, byte(len(msg) >> 8), byte(len(msg))}
+	}
+	frame = append(frame, mask[:]...)
+	for i, b := range msg {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (c *wsClient) receive(t *testing.T) (byte, []byte) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		t.Fatal(err)
+	}
+	size := int(header[1] & 0x7f)
+	if size == 126 {
+		var n uint16
+		binary.Read(c.r, binary.BigEndian, &n)
+		size = int(n)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		t.Fatal(err)
+	}
+	return header[0] & 0x0f, data
+}
+
+// event returns the next step event, failing on error messages.
+func (c *wsClient) event(t *testing.T) stepEventJSON {
+	for {
+		op, data := c.receive(t)
+		if op != opText {
+			continue
+		}
+		var ev stepEventJSON
+		if err := json.Unmarshal(data, &ev); err != nil || strings.Contains(string(data), `"error"`) {
+			t.Fatalf("unexpected message %s", data)
+		}
+		return ev
+	}
+}
+
+func TestPlay(t *testing.T) {
+	srv := httptest.NewServer(New(drum.V1Defaults()))
+	defer srv.Close()
+	c := dialPlay(t, srv.URL, "pattern_1.splice")
+	defer c.conn.Close()
+
+	ev := c.event(t)
+	if ev.Step != 0 || len(ev.Tracks) != 2 || ev.Tracks[0].Name != "kick" || ev.Tracks[1].Index != 4 {
+		t.Fatalf("unexpected first event %+v", ev)
+	}
+	c.send(t, opText, []byte(`{"cmd":"tempo","tempo":999}`))
+	c.send(t, opText, []byte(`{"cmd":"solo","track":0,"on":true}`))
+	// wait for the solo to apply at the next downbeat
+	for i := 0; i < 3*16; i++ {
+		ev = c.event(t)
+		if ev.Step == 0 && len(ev.Tracks) == 1 {
+			break
+		}
+	}
+	if ev.Step != 0 || len(ev.Tracks) != 1 || ev.Tracks[0].Index != 0 {
+		t.Fatalf("expected only the soloed kick, got %+v", ev)
+	}
+
+	c.send(t, opText, []byte(`{"cmd":"mute","track":9}`))
+	for {
+		op, data := c.receive(t)
+		if op == opText && strings.Contains(string(data), `"error":"mute: track 9 out of range"`) {
+			break
+		}
+	}
+	c.send(t, opPing, []byte("hi"))
+	for {
+		op, data := c.receive(t)
+		if op == opPong && string(data) == "hi" {
+			break
+		}
+	}
+	c.send(t, opClose, nil)
+	for {
+		if op, _ := c.receive(t); op == opClose {
+			break
+		}
+	}
+}
+
+func TestPlayHello(t *testing.T) {
+	p, err := drum.DecodeFile(filepath.Join("..", "fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Tracks()[0].SetVelocity(1, 40)
+	raw, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(New(drum.V1Defaults()))
+	defer srv.Close()
+	c := dialPlayRaw(t, srv.URL, raw)
+	defer c.conn.Close()
+
+	c.send(t, opText, []byte(`{"cmd":"hello","features":["display","stems"]}`))
+	var hello helloJSON
+	for {
+		op, data := c.receive(t)
+		if op == opText && strings.Contains(string(data), `"features"`) {
+			if err := json.Unmarshal(data, &hello); err != nil {
+				t.Fatal(err)
+			}
+			break
+		}
+	}
+	if fmt.Sprint(hello.Features, hello.Dropped) != "[display] [velocity]" || hello.Pattern.Tracks()[0].Velocity(1) != drum.MaxVelocity {
+		t.Fatalf("Expected the pattern without velocities but got %+v", hello)
+	}
+
+	// the client enables the kick on step 1 and sends the pattern back
+	hello.Pattern.Tracks()[0].SetStep(1, true)
+	data, err := json.Marshal(struct {
+		Cmd     string        `json:"cmd"`
+		Pattern *drum.Pattern `json:"pattern"`
+	}{"pattern", hello.Pattern})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.send(t, opText, data)
+	c.send(t, opText, []byte(`{"cmd":"tempo","tempo":999}`))
+	for i := 0; i < 3*16; i++ {
+		if ev := c.event(t); ev.Step == 1 && len(ev.Tracks) > 0 {
+			if ev.Tracks[0].Name != "kick" || ev.Tracks[0].Velocity != 40 {
+				t.Errorf("Expected the kick with the velocity kept by the server but got %+v", ev)
+			}
+			return
+		}
+	}
+	t.Error("Expected the kick on step 1")
+}
+
+func TestPlayHandshake(t *testing.T) {
+	h := New(drum.V1Defaults())
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/play?pattern=U1BMSUNF", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected bad request for an invalid pattern, got %d", rec.Code)
+	}
+	raw, _ := ioutil.ReadFile(filepath.Join("..", "fixtures", "pattern_1.splice"))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/play?pattern="+base64.RawURLEncoding.EncodeToString(raw), nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected bad request without upgrade, got %d", rec.Code)
+	}
+}
+
+func TestPlayOrigin(t *testing.T) {
+	raw, _ := ioutil.ReadFile(filepath.Join("..", "fixtures", "pattern_1.splice"))
+	target := "http://splice.local/play?pattern=" + base64.RawURLEncoding.EncodeToString(raw)
+	h := New(drum.V1Defaults(), WithAllowedOrigins("https://studio.example.com"))
+	for origin, exp := range map[string]int{
+		"":                           http.StatusInternalServerError, // past the check, recorders cannot be hijacked
+		"http://splice.local":        http.StatusInternalServerError,
+		"https://Studio.example.com": http.StatusInternalServerError,
+		"https://evil.example.com":   http.StatusForbidden,
+		"null":                       http.StatusForbidden,
+	} {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != exp {
+			t.Errorf("%q: expected status %d, got %d", origin, exp, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This file implements the server side of the WebSocket protocol (RFC 6455)
+// as far as needed to exchange JSON text messages with a browser.
+
+// websocketGUID is appended to the client key to compute the accept header.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// writeTimeout bounds the time a slow client may block a write.
+const writeTimeout = 10 * time.Second
+
+// WebSocket opcodes.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+var errMessageTooLarge = errors.New("websocket message too large")
+
+// wsConn is an established WebSocket connection. Writes are safe for
+// concurrent use, reads must happen from a single goroutine.
+type wsConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	mu   sync.Mutex // guards writes
+}
+
+// upgrade answers the opening handshake and takes over the connection. On
+// failure an error response has been written. Browsers send the origin of
+// the page opening the socket, which must be the host or one of origins,
+// see WithAllowedOrigins.
+func upgrade(w http.ResponseWriter, r *http.Request, origins []string) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	switch {
+	case r.Method != http.MethodGet:
+		w.Header().Set("Allow", http.MethodGet)
+		writeProblem(w, http.StatusMethodNotAllowed, "", "")
+		return nil, errors.New("websocket: method not allowed")
+	case !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket"):
+		writeProblem(w, http.StatusBadRequest, "websocket upgrade expected", "")
+		return nil, errors.New("websocket: not an upgrade request")
+	case r.Header.Get("Sec-WebSocket-Version") != "13":
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		writeProblem(w, http.StatusUpgradeRequired, "unsupported websocket version", "")
+		return nil, errors.New("websocket: unsupported version")
+	case key == "":
+		writeProblem(w, http.StatusBadRequest, "missing websocket key", "")
+		return nil, errors.New("websocket: missing key")
+	case !originAllowed(r, origins):
+		writeProblem(w, http.StatusForbidden, "websocket origin not allowed", "")
+		return nil, errors.New("websocket: origin not allowed")
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		writeProblem(w, http.StatusInternalServerError, "websocket not supported", "")
+		return nil, errors.New("websocket: connection cannot be hijacked")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("websocket: %v", err)
+	}
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(sum[:]))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("websocket: %v", err)
+	}
+	return &wsConn{conn: conn, r: rw.Reader}, nil
+}
+
+// originAllowed reports whether the Origin header of the request is missing,
+// of the host of the request or one of origins, ignoring case.
+func originAllowed(r *http.Request, origins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, o := range origins {
+		if strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerContains reports whether the comma separated header contains the
+// token, ignoring case.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h[name] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readMessage returns the next text or binary message. Pings are answered
+// and fragments joined. It returns io.EOF when the client closed the
+// connection.
+func (c *wsConn) readMessage() ([]byte, error) {
+	var msg []byte
+	for {
+		fin, op, data, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opClose:
+			c.writeFrame(opClose, data)
+			return nil, io.EOF
+		case opPing:
+			if err := c.writeFrame(opPong, data); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opText, opBinary, opContinuation:
+		default:
+			return nil, fmt.Errorf("websocket: unknown opcode %d", op)
+		}
+		if len(msg)+len(data) > MaxPatternSize {
+			return nil, errMessageTooLarge
+		}
+		msg = append(msg, data...)
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+// readFrame reads a single frame and unmasks its payload.
+func (c *wsConn) readFrame() (fin bool, op byte, data []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, op = header[0]&0x80 != 0, header[0]&0x0f
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, errors.New("websocket: unmasked client frame")
+	}
+	size := uint64(header[1] & 0x7f)
+	switch size {
+	case 126:
+		var n uint16
+		err = binary.Read(c.r, binary.BigEndian, &n)
+		size = uint64(n)
+	case 127:
+		err = binary.Read(c.r, binary.BigEndian, &size)
+	}
+	if err != nil {
+		return false, 0, nil, err
+	}
+	if size > MaxPatternSize {
+		return false, 0, nil, errMessageTooLarge
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.r, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	data = make([]byte, size)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range data {
+		data[i] ^= mask[i%4]
+	}
+	return fin, op, data, nil
+}
+
+// writeFrame writes data as a single unmasked frame.
+func (c *wsConn) writeFrame(op byte, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	header := []byte{0x80 | op, 0}
+	switch n := len(data); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = append(header, byte(n>>8), byte(n))
+	default:
+		header[1] = 127
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(n))
+		header = append(header, size[:]...)
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write(append(header, data...)); err != nil {
+		return fmt.Errorf("websocket: %v", err)
+	}
+	return nil
+}
+
+// Close closes the underlying connection.
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
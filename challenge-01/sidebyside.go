@@ -0,0 +1,153 @@
+package drum
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Markers in front of the lines of FormatSideBySide.
+const (
+	sideBySideSame    = ' '
+	sideBySideChanged = '*'
+	sideBySideRemoved = '-'
+	sideBySideAdded   = '+'
+	sideBySideStep    = '^'
+)
+
+// sideBySideRow is a line of FormatSideBySide before the columns are padded.
+type sideBySideRow struct {
+	marker rune
+	label  string
+	a, b   string
+	steps  []int // differing steps, marked in a line below
+	ta, tb *Track
+}
+
+// FormatSideBySide writes the printouts of a and b next to each other to w
+// with the tracks aligned by name, so two takes of a pattern can be compared
+// at a glance. Every line starts with a marker: '*' when the values differ,
+// '-' for tracks only in a and '+' for tracks only in b. The differing steps
+// of changed tracks are marked with '^' in the line below. Tracks are
+// matched in order by their name ignoring case and separators, the ids are
+// shown as "(a/b)" when they differ.
+func FormatSideBySide(w io.Writer, a, b *Pattern) error {
+	rows := []sideBySideRow{
+		headerRow("version", a.version, b.version),
+		headerRow("tempo", fmt.Sprint(a.tempo), fmt.Sprint(b.tempo)),
+	}
+	if a.timeSig != (TimeSignature{}) || b.timeSig != (TimeSignature{}) {
+		rows = append(rows, headerRow("time signature", a.TimeSignature().String(), b.TimeSignature().String()))
+	}
+	blockA, blockB := a.timeSig.groupSteps(), b.timeSig.groupSteps()
+	matched := make([]bool, len(b.tracks))
+	for _, ta := range a.tracks {
+		row := sideBySideRow{
+			marker: sideBySideRemoved,
+			label:  fmt.Sprintf("(%v) %v", ta.id, ta.name),
+			a:      sideBySideSteps(a, ta, blockA),
+			ta:     ta,
+		}
+		for i, tb := range b.tracks {
+			if matched[i] || normalizeName(tb.name) != normalizeName(ta.name) {
+				continue
+			}
+			matched[i] = true
+			row.tb, row.b = tb, sideBySideSteps(b, tb, blockB)
+			if tb.id != ta.id {
+				row.label = fmt.Sprintf("(%v/%v) %v", ta.id, tb.id, ta.name)
+			}
+			row.steps = differingSteps(a, ta, b, tb)
+			row.marker = sideBySideSame
+			if !ta.Equal(tb) {
+				row.marker = sideBySideChanged
+			}
+			break
+		}
+		rows = append(rows, row)
+	}
+	for i, tb := range b.tracks {
+		if !matched[i] {
+			rows = append(rows, sideBySideRow{
+				marker: sideBySideAdded,
+				label:  fmt.Sprintf("(%v) %v", tb.id, tb.name),
+				b:      sideBySideSteps(b, tb, blockB),
+				tb:     tb,
+			})
+		}
+	}
+
+	labelWidth, aWidth := 0, 0
+	for _, r := range rows {
+		labelWidth = max(labelWidth, utf8.RuneCountInString(r.label))
+		aWidth = max(aWidth, utf8.RuneCountInString(r.a))
+	}
+	bw := bufio.NewWriter(w)
+	for _, r := range rows {
+		line := fmt.Sprintf("%c %s  %s  %s", r.marker, pad(r.label, labelWidth), pad(r.a, aWidth), r.b)
+		bw.WriteString(strings.TrimRight(line, " "))
+		bw.WriteString("\n")
+		if len(r.steps) == 0 {
+			continue
+		}
+		marks := []rune(strings.Repeat(" ", 2+labelWidth+2+aWidth+2+utf8.RuneCountInString(r.b)))
+		for _, n := range r.steps {
+			if n < a.trackLength(r.ta) {
+				marks[2+labelWidth+2+stepColumn(n, blockA)] = sideBySideStep
+			}
+			if n < b.trackLength(r.tb) {
+				marks[2+labelWidth+2+aWidth+2+stepColumn(n, blockB)] = sideBySideStep
+			}
+		}
+		bw.WriteString(strings.TrimRight(string(marks), " "))
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
+
+func headerRow(label, a, b string) sideBySideRow {
+	r := sideBySideRow{marker: sideBySideSame, label: label, a: a, b: b}
+	if a != b {
+		r.marker = sideBySideChanged
+	}
+	return r
+}
+
+// sideBySideSteps returns the steps of the track in the default printout
+// with the state of the track.
+func sideBySideSteps(p *Pattern, t *Track, block int) string {
+	var sb strings.Builder
+	defaultFormatter.appendSteps(&sb, t.steps[:p.trackLength(t)], block)
+	switch {
+	case t.muted:
+		sb.WriteString(" [muted]")
+	case t.solo:
+		sb.WriteString(" [solo]")
+	}
+	return sb.String()
+}
+
+// differingSteps returns the steps played by only one of the tracks, a step
+// beyond the length of a track is not played.
+func differingSteps(a *Pattern, ta *Track, b *Pattern, tb *Track) []int {
+	la, lb := a.trackLength(ta), b.trackLength(tb)
+	var steps []int
+	for n := 0; n < max(la, lb); n++ {
+		if (n < la && ta.steps[n]) != (n < lb && tb.steps[n]) {
+			steps = append(steps, n)
+		}
+	}
+	return steps
+}
+
+// stepColumn returns the column of the step within the printed steps.
+func stepColumn(n, block int) int {
+	return 1 + n + n/block
+}
+
+// pad appends spaces to s up to width runes.
+func pad(s string, width int) string {
+	return s + strings.Repeat(" ", max(0, width-utf8.RuneCountInString(s)))
+}
@@ -0,0 +1,58 @@
+package drum
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestFormatSideBySide(t *testing.T) {
+	a, err := DecodeFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := a.Clone()
+	b.SetTempo(120)
+	b.tracks[0].steps[1] = true
+	b.tracks[1].id = 9
+	b.tracks[3].SetPan(-10)
+	b.tracks = append(b.tracks, &Track{id: 7, name: "Clap"})
+	b.tracks[2].muted = true
+	var buf bytes.Buffer
+	if err := FormatSideBySide(&buf, a, b); err != nil {
+		t.Fatal(err)
+	}
+	exp := `  version      0.808-alpha            0.808-alpha
+* tempo        98.4                   120
+* (0) kick     |x---|----|x---|----|  |xx--|----|x---|----|
+                 ^                      ^
+* (1/9) snare  |----|x---|----|x---|  |----|x---|----|x---|
+  (3) hh-open  |--x-|--x-|x-x-|--x-|  |--x-|--x-|x-x-|--x-| [muted]
+* (5) cowbell  |----|----|x---|----|  |----|----|x---|----|
++ (7) Clap                            |----|----|----|----|
+`
+	if got := buf.String(); got != exp {
+		t.Errorf("Expected:\n%v\nbut got:\n%v", exp, got)
+	}
+
+	buf.Reset()
+	if err := FormatSideBySide(&buf, a, a.Clone()); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if !strings.HasPrefix(line, "  ") {
+			t.Errorf("Expected no differences but got '%v'", line)
+		}
+	}
+
+	buf.Reset()
+	b = a.Clone()
+	b.tracks = b.tracks[1:]
+	if err := FormatSideBySide(&buf, a, b); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "- (0) kick     |x---|----|x---|----|\n"; !strings.Contains(buf.String(), exp) {
+		t.Errorf("Expected '%v' in:\n%v", exp, buf.String())
+	}
+}
@@ -0,0 +1,390 @@
+package drum
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Patterns and banks are signed with ed25519 keys. The signature of a
+// pattern is detached, usually stored next to the file with the extension
+// SignatureExt, and covers the file data as it is, so that files are
+// verified before they are decoded. Banks carry a signed manifest with the
+// SHA-256 of every pattern file, see Bank.Sign.
+var (
+	// ErrUnsigned is returned when a pattern or bank has no signature.
+	ErrUnsigned = errors.New("not signed")
+	// ErrUnknownKey is returned when the signing key is not trusted.
+	ErrUnknownKey = errors.New("unknown signing key")
+	// ErrInvalidSignature is returned when the signature does not match the
+	// data, which was changed after signing or signed by another key.
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrManifestMismatch is returned when the patterns of a bank differ
+	// from its signed manifest.
+	ErrManifestMismatch = errors.New("bank differs from manifest")
+)
+
+// SignatureExt is the extension of detached signature files.
+const SignatureExt = ".sig"
+
+// bankManifestName is the file of the signed manifest within a bank.
+const bankManifestName = "MANIFEST.sig.json"
+
+// VerificationError is returned when a pattern or bank fails verification.
+type VerificationError struct {
+	Name  string // of the file or bank entry, empty when unknown
+	KeyID string // of the signing key, empty when unsigned
+	Err   error  // one of the errors above
+}
+
+func (e *VerificationError) Error() string {
+	msg := "verify"
+	if e.Name != "" {
+		msg += " " + e.Name
+	}
+	if e.KeyID != "" {
+		msg += " (key " + e.KeyID + ")"
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// Signature is a detached ed25519 signature with the id of its key.
+type Signature struct {
+	KeyID string
+	Value []byte
+}
+
+// MarshalText writes the signature as "ed25519 <key id> <base64 value>".
+func (s Signature) MarshalText() ([]byte, error) {
+	return []byte("ed25519 " + s.KeyID + " " + base64.StdEncoding.EncodeToString(s.Value) + "\n"), nil
+}
+
+// UnmarshalText parses a signature written by MarshalText.
+func (s *Signature) UnmarshalText(text []byte) error {
+	fields := strings.Fields(string(text))
+	if len(fields) != 3 || fields[0] != "ed25519" {
+		return fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	value, err := base64.StdEncoding.DecodeString(fields[2])
+	if err != nil || len(value) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	s.KeyID, s.Value = fields[1], value
+	return nil
+}
+
+// KeyID returns the id signatures name the key with: the first 8 bytes of
+// the SHA-256 of the key in hex.
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// GenerateKey returns a new key pair for signing.
+func GenerateKey() (ed25519.PublicKey, ed25519.PrivateKey, error) {
+	return ed25519.GenerateKey(rand.Reader)
+}
+
+// Sign returns the signature of the data.
+func Sign(data []byte, key ed25519.PrivateKey) Signature {
+	return Signature{KeyID: KeyID(key.Public().(ed25519.PublicKey)), Value: ed25519.Sign(key, data)}
+}
+
+// SignPattern encodes the pattern and returns the file data with its
+// signature.
+func SignPattern(p *Pattern, key ed25519.PrivateKey, opts ...EncodeOption) ([]byte, Signature, error) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, p, opts...); err != nil {
+		return nil, Signature{}, err
+	}
+	return buf.Bytes(), Sign(buf.Bytes(), key), nil
+}
+
+// VerifyPattern checks the signature of the pattern file data with the
+// trusted keys and decodes the data only when it is valid. Failures are
+// returned as *VerificationError.
+func VerifyPattern(data []byte, sig Signature, keys Keyring, opts ...DecodeOption) (*Pattern, error) {
+	if err := keys.Verify(data, sig); err != nil {
+		return nil, err
+	}
+	return DecodeBytes(data, opts...)
+}
+
+// VerifyFile verifies the pattern file at path with the signature in the
+// file next to it, see SignatureExt, and decodes it.
+func VerifyFile(path string, keys Keyring, opts ...DecodeOption) (*Pattern, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := ReadSignature(path + SignatureExt)
+	if err != nil {
+		return nil, &VerificationError{Name: path, Err: err}
+	}
+	p, err := VerifyPattern(data, sig, keys, opts...)
+	if v, ok := err.(*VerificationError); ok {
+		v.Name = path
+	}
+	return p, err
+}
+
+// ReadSignature reads a signature file. Missing files return ErrUnsigned.
+func ReadSignature(path string) (Signature, error) {
+	text, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Signature{}, ErrUnsigned
+	}
+	if err != nil {
+		return Signature{}, err
+	}
+	var sig Signature
+	err = sig.UnmarshalText(text)
+	return sig, err
+}
+
+// WriteSignature writes the signature to the file at path.
+func WriteSignature(path string, sig Signature) error {
+	text, _ := sig.MarshalText()
+	return ioutil.WriteFile(path, text, 0644)
+}
+
+// Keyring holds the trusted public keys by their KeyID.
+type Keyring map[string]ed25519.PublicKey
+
+// NewKeyring returns a keyring trusting the keys.
+func NewKeyring(keys ...ed25519.PublicKey) Keyring {
+	k := make(Keyring)
+	for _, pub := range keys {
+		k.Add(pub)
+	}
+	return k
+}
+
+// Add trusts the key.
+func (k Keyring) Add(pub ed25519.PublicKey) {
+	k[KeyID(pub)] = pub
+}
+
+// Verify checks that the signature of the data was made by a trusted key.
+// Failures are returned as *VerificationError.
+func (k Keyring) Verify(data []byte, sig Signature) error {
+	if sig.KeyID == "" && sig.Value == nil {
+		return &VerificationError{Err: ErrUnsigned}
+	}
+	pub, ok := k[sig.KeyID]
+	if !ok {
+		return &VerificationError{KeyID: sig.KeyID, Err: ErrUnknownKey}
+	}
+	if !ed25519.Verify(pub, data, sig.Value) {
+		return &VerificationError{KeyID: sig.KeyID, Err: ErrInvalidSignature}
+	}
+	return nil
+}
+
+// LoadKeyring trusts the public keys of all files with the extension .pub
+// in dir, as written by SavePublicKey.
+func LoadKeyring(dir string) (Keyring, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pub"))
+	if err != nil {
+		return nil, err
+	}
+	k := make(Keyring)
+	for _, path := range paths {
+		pub, err := LoadPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		k.Add(pub)
+	}
+	return k, nil
+}
+
+// SavePrivateKey writes the key PEM encoded in PKCS #8 to the file at path,
+// readable by the owner only.
+func SavePrivateKey(path string, key ed25519.PrivateKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+}
+
+// LoadPrivateKey reads a key written by SavePrivateKey.
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if k, ok := key.(ed25519.PrivateKey); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("%s: not an ed25519 key", path)
+}
+
+// SavePublicKey writes the key PEM encoded in PKIX to the file at path.
+func SavePublicKey(path string, pub ed25519.PublicKey) error {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644)
+}
+
+// LoadPublicKey reads a key written by SavePublicKey.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if k, ok := key.(ed25519.PublicKey); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("%s: not an ed25519 key", path)
+}
+
+func readPEM(path, typ string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != typ {
+		return nil, fmt.Errorf("%s: no PEM %s", path, strings.ToLower(typ))
+	}
+	return block.Bytes, nil
+}
+
+// bankManifest lists the SHA-256 of the pattern files of a bank by name.
+// The signature covers the JSON encoding of the files.
+type bankManifest struct {
+	Files     map[string]string `json:"files"`
+	KeyID     string            `json:"keyId"`
+	Signature []byte            `json:"signature"`
+}
+
+// Sign adds a manifest with the SHA-256 of every pattern file signed with
+// the key, written with the bank. Patterns changed afterwards fail Verify
+// until the bank is signed again.
+func (b *Bank) Sign(key ed25519.PrivateKey) error {
+	files, err := b.fileHashes()
+	if err != nil {
+		return err
+	}
+	signed, _ := json.Marshal(files)
+	sig := Sign(signed, key)
+	b.manifest, err = json.MarshalIndent(bankManifest{files, sig.KeyID, sig.Value}, "", "  ")
+	return err
+}
+
+// Verify checks that the bank has a manifest signed by a trusted key that
+// lists exactly its pattern files. Failures are returned as
+// *VerificationError naming the bank entry when one differs. Banks are
+// verified before their patterns are used, decoded patterns are compared
+// as they encode.
+func (b *Bank) Verify(keys Keyring) error {
+	data, err := b.manifestData()
+	if err != nil {
+		return &VerificationError{Err: err}
+	}
+	var m bankManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return &VerificationError{Err: fmt.Errorf("%w: malformed manifest: %v", ErrInvalidSignature, err)}
+	}
+	signed, _ := json.Marshal(m.Files)
+	if err := keys.Verify(signed, Signature{m.KeyID, m.Signature}); err != nil {
+		return err
+	}
+	files, err := b.fileHashes()
+	if err != nil {
+		return err
+	}
+	var names []string
+	for name := range m.Files {
+		names = append(names, name)
+	}
+	for name := range files {
+		if _, ok := m.Files[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if files[name] != m.Files[name] {
+			return &VerificationError{Name: name, KeyID: m.KeyID, Err: ErrManifestMismatch}
+		}
+	}
+	return nil
+}
+
+// manifestData returns the manifest of Sign or the one in the archive.
+func (b *Bank) manifestData() ([]byte, error) {
+	if b.manifest != nil {
+		return b.manifest, nil
+	}
+	for _, f := range b.others {
+		if f.Name == bankManifestName {
+			return readZipFile(f)
+		}
+	}
+	return nil, ErrUnsigned
+}
+
+// fileHashes returns the SHA-256 of the pattern files by name, of the
+// encoded pattern for changed entries.
+func (b *Bank) fileHashes() (map[string]string, error) {
+	files := make(map[string]string, len(b.entries))
+	for _, e := range b.entries {
+		var data []byte
+		var err error
+		if e.changed {
+			var buf bytes.Buffer
+			err = Encode(&buf, e.pattern)
+			data = buf.Bytes()
+		} else if e.mapped != nil {
+			data = e.mapped
+		} else {
+			data, err = readZipFile(e.file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", e.name, err)
+		}
+		sum := sha256.Sum256(data)
+		files[e.name] = hex.EncodeToString(sum[:])
+	}
+	return files, nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
@@ -0,0 +1,141 @@
+package drum
+
+import (
+	"errors"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+func TestSignPattern(t *testing.T) {
+	pub, key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, _ := GenerateKey()
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, sig, err := SignPattern(p, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := VerifyPattern(data, sig, NewKeyring(other, pub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(p) {
+		t.Errorf("Expected %v but got %v", p, got)
+	}
+
+	tampered := append([]byte(nil), data...)
+	tampered[len(tampered)-1] ^= 1
+	specs := map[string]struct {
+		data []byte
+		sig  Signature
+		keys Keyring
+		exp  error
+	}{
+		"tampered":    {tampered, sig, NewKeyring(pub), ErrInvalidSignature},
+		"unknown key": {data, sig, NewKeyring(other), ErrUnknownKey},
+		"unsigned":    {data, Signature{}, NewKeyring(pub), ErrUnsigned},
+	}
+	for name, spec := range specs {
+		t.Run(name, func(t *testing.T) {
+			_, err := VerifyPattern(spec.data, spec.sig, spec.keys)
+			var v *VerificationError
+			if !errors.Is(err, spec.exp) || !errors.As(err, &v) {
+				t.Errorf("Expected a VerificationError with %v but got %v", spec.exp, err)
+			}
+		})
+	}
+}
+
+func TestSignatureFiles(t *testing.T) {
+	dir := t.TempDir()
+	pub, key, _ := GenerateKey()
+	if err := SavePrivateKey(filepath.Join(dir, "publisher.key"), key); err != nil {
+		t.Fatal(err)
+	}
+	if err := SavePublicKey(filepath.Join(dir, "publisher.pub"), pub); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadPrivateKey(filepath.Join(dir, "publisher.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadKeyring(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := keys[KeyID(pub)]; !ok || len(keys) != 1 {
+		t.Fatalf("Expected the public key in the keyring but got %v", keys)
+	}
+
+	data, err := ioutil.ReadFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "beat.splice")
+	ioutil.WriteFile(file, data, 0644)
+	if _, err := VerifyFile(file, keys); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("Expected ErrUnsigned but got %v", err)
+	}
+	if err := WriteSignature(file+SignatureExt, Sign(data, loaded)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyFile(file, keys); err != nil {
+		t.Errorf("Expected a valid signature but got %v", err)
+	}
+	ioutil.WriteFile(file+SignatureExt, []byte("ed25519 x"), 0644)
+	if _, err := VerifyFile(file, keys); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected a malformed signature but got %v", err)
+	}
+}
+
+func TestSignBank(t *testing.T) {
+	dir := t.TempDir()
+	pub, key, _ := GenerateKey()
+	keys := NewKeyring(pub)
+	b, err := OpenBank(writeTestBank(t, dir, "pattern_1.splice", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if err := b.Verify(keys); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("Expected ErrUnsigned but got %v", err)
+	}
+	if err := b.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	signed := filepath.Join(dir, "signed.zip")
+	if err := b.Save(signed); err != nil {
+		t.Fatal(err)
+	}
+
+	sb, err := OpenBank(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sb.Close()
+	if err := sb.Verify(keys); err != nil {
+		t.Fatalf("Expected a valid bank but got %v", err)
+	}
+	if err := sb.Verify(NewKeyring()); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey but got %v", err)
+	}
+	p, _ := sb.Lookup("pattern_2.splice").Pattern()
+	p.SetTempo(99)
+	sb.Add("extra.splice", p)
+	err = sb.Verify(keys)
+	var v *VerificationError
+	if !errors.Is(err, ErrManifestMismatch) || !errors.As(err, &v) || v.Name != "extra.splice" {
+		t.Errorf("Expected the added entry to differ from the manifest but got %v", err)
+	}
+	sb.Remove("extra.splice")
+	if err := sb.Verify(keys); !errors.Is(err, ErrManifestMismatch) {
+		t.Errorf("Expected the changed tempo to differ from the manifest but got %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package drum
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/alpe/go-challenge/challenge-01/splice"
+)
+
+// Songs are stored in their own container. Every section embeds a complete
+// pattern file so that pattern extensions are kept.
+//
+//	|SPLSNG (6 bytes)|Payload size (8 bytes)|                           => File Header
+//	|Title length (1 byte)|Title (n bytes)|                             => Song
+//	|Repeat (2 bytes)|Tempo (4 bytes)|Pattern size (4 bytes)|Pattern|   => First Section
+//	...
+//	|0 (2 bytes)|Scene|                                                 => First Scene, see Scene
+//	...
+const spliceTypeSong = "SPLSNG"
+
+// ErrInvalidSection is returned for a song section without pattern or with
+// an invalid repeat count or tempo.
+var ErrInvalidSection = errors.New("invalid section")
+
+// Song is an arrangement of patterns played one after another.
+type Song struct {
+	Title    string
+	Sections []Section
+	Scenes   []Scene // launched by name, see Player.LaunchScene
+}
+
+// Section is a pattern within a song.
+type Section struct {
+	Pattern *Pattern
+	Repeat  int     // number of bars the pattern is played, at least 1
+	Tempo   float32 // tempo override, 0 for the tempo of the pattern
+}
+
+// tempo returns the tempo the section is played with.
+func (s Section) tempo() float32 {
+	if s.Tempo != 0 {
+		return s.Tempo
+	}
+	return s.Pattern.tempo
+}
+
+func (s Section) validate() error {
+	if s.Pattern == nil || s.Repeat < 1 || s.Repeat > math.MaxUint16 {
+		return ErrInvalidSection
+	}
+	if s.Tempo != 0 && !validTempo(s.Tempo) {
+		return ErrInvalidSection
+	}
+	return nil
+}
+
+// Bars returns the number of bars of the whole song.
+func (s *Song) Bars() int {
+	n := 0
+	for _, sec := range s.Sections {
+		n += sec.Repeat
+	}
+	return n
+}
+
+// Duration returns the playing time of the whole song.
+func (s *Song) Duration() time.Duration {
+	var d time.Duration
+	for _, sec := range s.Sections {
+		d += sec.Pattern.timeAt(sec.tempo(), float64(sec.Repeat*sec.Pattern.BarSteps()))
+	}
+	return d
+}
+
+// DecodeSongFile decodes the song file found at the provided path.
+func DecodeSongFile(path string) (*Song, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return DecodeSong(bufio.NewReader(file))
+}
+
+// DecodeSong decodes a song from r.
+func DecodeSong(r io.Reader) (*Song, error) {
+	typeHeader, err := readBytes(r, len(spliceTypeSong))
+	if err != nil {
+		return nil, fmt.Errorf("parse type header: %v", err)
+	}
+	if !bytes.Equal(typeHeader, []byte(spliceTypeSong)) {
+		return nil, ErrUnsupportedFileFormat
+	}
+	var payloadSize int64
+	if err := binary.Read(r, binary.BigEndian, &payloadSize); err != nil {
+		return nil, fmt.Errorf("parse payload size: %v", err)
+	}
+	lr := &io.LimitedReader{R: r, N: payloadSize}
+	var s Song
+	if s.Title, err = readShortString(lr); err != nil {
+		return nil, fmt.Errorf("parse title: %v", err)
+	}
+	for lr.N > 0 {
+		var repeat uint16
+		if err := binary.Read(lr, binary.LittleEndian, &repeat); err != nil {
+			return nil, fmt.Errorf("parse section %d: %v", len(s.Sections)+1, err)
+		}
+		if repeat == 0 {
+			sc, err := decodeScene(lr)
+			if err != nil {
+				return nil, fmt.Errorf("parse scene %d: %v", len(s.Scenes)+1, err)
+			}
+			s.Scenes = append(s.Scenes, sc)
+			continue
+		}
+		sec, err := decodeSection(lr, repeat)
+		if err != nil {
+			return nil, fmt.Errorf("parse section %d: %v", len(s.Sections)+1, err)
+		}
+		s.Sections = append(s.Sections, sec)
+	}
+	if err := s.validateScenes(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func decodeSection(r io.Reader, repeat uint16) (Section, error) {
+	var header struct {
+		Tempo float32
+		Size  uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return Section{}, err
+	}
+	data, err := readBytes(r, int(header.Size))
+	if err != nil {
+		return Section{}, fmt.Errorf("read pattern: %v", err)
+	}
+	p, err := DecodeBytes(data)
+	if err != nil {
+		return Section{}, err
+	}
+	sec := Section{Pattern: p, Repeat: int(repeat), Tempo: header.Tempo}
+	return sec, sec.validate()
+}
+
+// EncodeSongFile writes the song to the provided path.
+func EncodeSongFile(path string, s *Song) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := EncodeSong(file, s); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// EncodeSong writes the song to w.
+func EncodeSong(w io.Writer, s *Song) error {
+	if len(s.Title) > math.MaxUint8 {
+		return fmt.Errorf("song title too long")
+	}
+	payload := new(bytes.Buffer)
+	writeShortString(payload, s.Title)
+	for i, sec := range s.Sections {
+		if err := sec.validate(); err != nil {
+			return fmt.Errorf("encode section %d: %v", i+1, err)
+		}
+		var p bytes.Buffer
+		if err := Encode(&p, sec.Pattern); err != nil {
+			return fmt.Errorf("encode section %d: %v", i+1, err)
+		}
+		binary.Write(payload, binary.LittleEndian, uint16(sec.Repeat))
+		binary.Write(payload, binary.LittleEndian, sec.Tempo)
+		binary.Write(payload, binary.LittleEndian, uint32(p.Len()))
+		p.WriteTo(payload)
+	}
+	if err := s.validateScenes(); err != nil {
+		return err
+	}
+	for _, sc := range s.Scenes {
+		encodeScene(payload, sc)
+	}
+	if err := splice.WriteFrame(w, spliceTypeSong, payload.Bytes()); err != nil {
+		return fmt.Errorf("write frame: %v", err)
+	}
+	return nil
+}
+
+// WriteSongMIDI writes the whole song as Standard MIDI File to w. Tempo
+// changes between sections are written as tempo events.
+func WriteSongMIDI(w io.Writer, s *Song) error {
+	sections := make([]midiSection, len(s.Sections))
+	for i, sec := range s.Sections {
+		if err := sec.validate(); err != nil {
+			return fmt.Errorf("section %d: %v", i+1, err)
+		}
+		sections[i] = midiSection{sec.Pattern, sec.Repeat, sec.tempo()}
+	}
+	return writeMIDI(w, sections)
+}
@@ -0,0 +1,86 @@
+package drum
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"testing"
+)
+
+func testSong(t *testing.T) *Song {
+	a, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := DecodeFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Song{Title: "demo", Sections: []Section{{Pattern: a, Repeat: 2}, {Pattern: b, Repeat: 1, Tempo: 140}}}
+}
+
+func TestSongRoundTrip(t *testing.T) {
+	s := testSong(t)
+	var buf bytes.Buffer
+	if err := EncodeSong(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeSong(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Title != s.Title || len(decoded.Sections) != len(s.Sections) {
+		t.Fatalf("Expected %+v but got %+v", s, decoded)
+	}
+	for i, sec := range s.Sections {
+		got := decoded.Sections[i]
+		if got.Repeat != sec.Repeat || got.Tempo != sec.Tempo || !got.Pattern.Equal(sec.Pattern) {
+			t.Errorf("Expected section %+v but got %+v", sec, got)
+		}
+	}
+	if exp, got := 3, decoded.Bars(); got != exp {
+		t.Errorf("Expected %v bars but got %v", exp, got)
+	}
+}
+
+func TestSongInvalidSection(t *testing.T) {
+	s := testSong(t)
+	s.Sections[1].Repeat = 0
+	if err := EncodeSong(&bytes.Buffer{}, s); err == nil {
+		t.Error("expected error for section without repeats")
+	}
+}
+
+func TestWriteSongMIDI(t *testing.T) {
+	song := &Song{Sections: []Section{
+		{Pattern: &Pattern{tempo: 120}, Repeat: 1},
+		{Pattern: &Pattern{tempo: 120}, Repeat: 1, Tempo: 60},
+	}}
+	var buf bytes.Buffer
+	if err := WriteSongMIDI(&buf, song); err != nil {
+		t.Fatal(err)
+	}
+	// 500000us per beat at the start, 1000000us per beat after one bar
+	for _, exp := range []string{"\x00\xff\x51\x03\x07\xa1\x20", "\x83\x00\xff\x51\x03\x0f\x42\x40"} {
+		if !bytes.Contains(buf.Bytes(), []byte(exp)) {
+			t.Errorf("Expected tempo event %x in %x", exp, buf.Bytes())
+		}
+	}
+}
+
+func TestSongPlayer(t *testing.T) {
+	s := testSong(t)
+	var sections []int
+	pl := NewSongPlayer(s, func(ev StepEvent) {
+		if ev.Step == 0 {
+			sections = append(sections, ev.Section)
+		}
+	})
+	pl.SetTempo(100000)
+	if err := pl.Play(make(chan struct{})); err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := "[0 0 1]", fmt.Sprint(sections); got != exp {
+		t.Errorf("Expected bars of sections %v but got %v", exp, got)
+	}
+}
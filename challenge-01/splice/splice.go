@@ -0,0 +1,168 @@
+// Package splice reads and writes the container of the drum machine files,
+// so that other tools can store their own payloads in the same format.
+//
+// A frame starts with a type of TypeLength bytes, like "SPLICE" for patterns
+// or "SPLSNG" for songs, followed by the payload size as big endian 64 bit
+// integer and the payload:
+//
+//	if err := splice.WriteFrame(w, "MYTOOL", payload); err != nil {
+//		return err
+//	}
+//	f, err := splice.ReadFrame(r, 1<<20)
+//
+// Decoders of whole streams are registered by type, the drum package
+// registers the pattern and song decoders, and Decode dispatches to them.
+package splice
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Layout of the frame header.
+const (
+	TypeLength   = 6
+	HeaderLength = TypeLength + 8
+)
+
+var (
+	// ErrInvalidType is returned for types not TypeLength bytes long.
+	ErrInvalidType = errors.New("invalid frame type")
+	// ErrFrameTooLarge is returned for payloads larger than allowed.
+	ErrFrameTooLarge = errors.New("frame too large")
+	// ErrUnknownType is returned by Decode for types without decoder.
+	ErrUnknownType = errors.New("unknown frame type")
+)
+
+// Header is the start of a frame.
+type Header struct {
+	Type string
+	Size int64 // of the payload in bytes
+}
+
+// Frame is a payload with its type.
+type Frame struct {
+	Type    string
+	Payload []byte
+}
+
+// ReadHeader reads a frame header from r, leaving r at the payload.
+// Truncated headers return io.ErrUnexpectedEOF, or io.EOF when r is empty.
+func ReadHeader(r io.Reader) (Header, error) {
+	var b [HeaderLength]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return Header{}, err
+	}
+	h := Header{Type: string(b[:TypeLength]), Size: int64(binary.BigEndian.Uint64(b[TypeLength:]))}
+	if h.Size < 0 {
+		return h, fmt.Errorf("%w: negative payload size %d", ErrFrameTooLarge, h.Size)
+	}
+	return h, nil
+}
+
+// WriteHeader writes a frame header to w.
+func WriteHeader(w io.Writer, h Header) error {
+	if len(h.Type) != TypeLength {
+		return fmt.Errorf("%w %q: want %d bytes", ErrInvalidType, h.Type, TypeLength)
+	}
+	var b [HeaderLength]byte
+	copy(b[:], h.Type)
+	binary.BigEndian.PutUint64(b[TypeLength:], uint64(h.Size))
+	_, err := w.Write(b[:])
+	return err
+}
+
+// ReadFrame reads a whole frame from r. Payloads larger than maxSize bytes
+// return ErrFrameTooLarge before anything is allocated for them, a maxSize
+// of 0 or less allows any size.
+func ReadFrame(r io.Reader, maxSize int64) (Frame, error) {
+	h, err := ReadHeader(r)
+	if err != nil {
+		return Frame{}, fmt.Errorf("read frame header: %w", err)
+	}
+	if maxSize > 0 && h.Size > maxSize {
+		return Frame{}, fmt.Errorf("%w: %q payload of %d bytes, limit %d", ErrFrameTooLarge, h.Type, h.Size, maxSize)
+	}
+	// read in steps, so a size beyond the stream does not allocate it all
+	var payload []byte
+	for int64(len(payload)) < h.Size {
+		n := min(h.Size-int64(len(payload)), 64<<10)
+		payload = append(payload, make([]byte, n)...)
+		if _, err := io.ReadFull(r, payload[int64(len(payload))-n:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return Frame{}, fmt.Errorf("read %q payload: %w", h.Type, err)
+		}
+	}
+	return Frame{Type: h.Type, Payload: payload}, nil
+}
+
+// WriteFrame writes the payload as frame of typ to w.
+func WriteFrame(w io.Writer, typ string, payload []byte) error {
+	if err := WriteHeader(w, Header{Type: typ, Size: int64(len(payload))}); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// DecodeFunc decodes a whole stream starting with a frame of its type.
+type DecodeFunc func(r io.Reader) (interface{}, error)
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = make(map[string]DecodeFunc)
+)
+
+// Register makes decode handle the frames of typ in Decode. Like
+// database/sql drivers, decoders are registered in init functions, so
+// Register panics for invalid or already registered types.
+func Register(typ string, decode DecodeFunc) {
+	if len(typ) != TypeLength {
+		panic(fmt.Sprintf("splice: register invalid type %q", typ))
+	}
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	if _, ok := decoders[typ]; ok {
+		panic(fmt.Sprintf("splice: register type %q twice", typ))
+	}
+	decoders[typ] = decode
+}
+
+// Types returns the registered types in order.
+func Types() []string {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	types := make([]string, 0, len(decoders))
+	for typ := range decoders {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Decode peeks at the type of the frame r starts with and decodes the
+// stream, including the header, with the decoder registered for it. It
+// returns the type with the decoded value.
+func Decode(r io.Reader) (string, interface{}, error) {
+	br := bufio.NewReader(r)
+	b, err := br.Peek(TypeLength)
+	if err != nil {
+		return "", nil, fmt.Errorf("read frame type: %w", err)
+	}
+	typ := string(b)
+	decodersMu.RLock()
+	decode, ok := decoders[typ]
+	decodersMu.RUnlock()
+	if !ok {
+		return typ, nil, fmt.Errorf("%w %q", ErrUnknownType, typ)
+	}
+	v, err := decode(br)
+	return typ, v, err
+}
@@ -0,0 +1,93 @@
+package splice
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteFrame(&buf, "TESTFR", []byte("payload")); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFrame(&buf, "EMPTY_", nil); err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := 2*HeaderLength+len("payload"), buf.Len(); got != exp {
+		t.Errorf("Expected %d bytes but got %d", exp, got)
+	}
+	for _, exp := range []Frame{{"TESTFR", []byte("payload")}, {"EMPTY_", nil}} {
+		got, err := ReadFrame(&buf, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Type != exp.Type || !bytes.Equal(got.Payload, exp.Payload) {
+			t.Errorf("Expected %q but got %q", exp, got)
+		}
+	}
+	if _, err := ReadFrame(&buf, 0); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected EOF after the last frame but got %v", err)
+	}
+}
+
+func TestFrameErrors(t *testing.T) {
+	if err := WriteFrame(ioutil.Discard, "SHORT", nil); !errors.Is(err, ErrInvalidType) {
+		t.Errorf("Expected ErrInvalidType but got %v", err)
+	}
+	var buf bytes.Buffer
+	WriteFrame(&buf, "TESTFR", make([]byte, 100))
+	data := buf.Bytes()
+	if _, err := ReadFrame(bytes.NewReader(data), 99); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("Expected ErrFrameTooLarge but got %v", err)
+	}
+	if _, err := ReadFrame(bytes.NewReader(data[:50]), 0); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected a truncated payload but got %v", err)
+	}
+	if _, err := ReadFrame(bytes.NewReader(data[:10]), 0); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected a truncated header but got %v", err)
+	}
+	// a size far beyond the stream fails without allocating it
+	huge := append([]byte("TESTFR"), 0, 0, 0, 0x10, 0, 0, 0, 0)
+	if _, err := ReadFrame(bytes.NewReader(huge), 0); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected a truncated payload but got %v", err)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	Register("TESTRG", func(r io.Reader) (interface{}, error) {
+		f, err := ReadFrame(r, 0)
+		return string(f.Payload), err
+	})
+	defer func() {
+		decodersMu.Lock()
+		delete(decoders, "TESTRG")
+		decodersMu.Unlock()
+	}()
+	if !reflect.DeepEqual(Types(), []string{"TESTRG"}) {
+		t.Errorf("Expected the registered type but got %q", Types())
+	}
+	var buf bytes.Buffer
+	WriteFrame(&buf, "TESTRG", []byte("hello"))
+	typ, v, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ != "TESTRG" || v != "hello" {
+		t.Errorf("Expected the decoded payload but got %q %v", typ, v)
+	}
+	if _, _, err := Decode(bytes.NewReader([]byte("OTHER_ and more"))); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("Expected ErrUnknownType but got %v", err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected a panic registering a type twice")
+			}
+		}()
+		Register("TESTRG", nil)
+	}()
+}
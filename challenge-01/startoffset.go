@@ -0,0 +1,111 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrInvalidStartOffset is returned for negative start offsets and offsets
+// beyond the end of the sample.
+var ErrInvalidStartOffset = errors.New("invalid start offset")
+
+// onsetThreshold is the level relative to the peak of a sample that
+// DetectOnset takes for the start of the sound, -30 dB.
+const onsetThreshold = 0.0316
+
+// DetectOnset returns the leading silence of the sample: the time until
+// its level first rises to 30 dB below its peak, moved back to the zero
+// crossing before so that the attack starts without a click. Silent
+// samples have no onset and return 0.
+func DetectOnset(s *Sample) time.Duration {
+	level := func(f int) float64 {
+		return math.Abs(float64(s.Left[f]) + float64(s.Right[f]))
+	}
+	peak := 0.0
+	for f := range s.Left {
+		peak = math.Max(peak, level(f))
+	}
+	if peak == 0 {
+		return 0
+	}
+	onset := 0
+	for f := range s.Left {
+		if level(f) >= peak*onsetThreshold {
+			onset = f
+			break
+		}
+	}
+	sign := func(f int) float32 { return s.Left[f] + s.Right[f] }
+	for onset > 0 && sign(onset-1) != 0 && (sign(onset-1) > 0) == (sign(onset) > 0) {
+		onset--
+	}
+	return framesDuration(onset, s.Rate)
+}
+
+func framesDuration(frames, rate int) time.Duration {
+	return time.Duration(frames) * time.Second / time.Duration(rate)
+}
+
+// SetStartOffset sets the leading silence of the sample with the given name
+// or alias, which Render compensates by starting the sample earlier so
+// that its sound lands on the grid, and the Mixer by skipping it. Offset 0
+// removes it. It returns an error wrapping ErrMissingSample when the kit
+// has no sample of the name.
+func (k *Kit) SetStartOffset(name string, offset time.Duration) error {
+	key := normalizeName(name)
+	s, ok := k.byName[key]
+	if !ok {
+		if s, ok = k.byName[k.aliases[key]]; !ok {
+			return fmt.Errorf("%w %q", ErrMissingSample, name)
+		}
+	}
+	if offset < 0 || (offset > 0 && offset >= framesDuration(s.Frames(), s.Rate)) {
+		return fmt.Errorf("%w %v of %q", ErrInvalidStartOffset, offset, name)
+	}
+	if offset == 0 {
+		delete(k.offsets, s)
+		return nil
+	}
+	k.offsets[s] = offset
+	return nil
+}
+
+// DetectStartOffsets sets the start offset of every sample of the kit that
+// has none to its onset, see DetectOnset.
+func (k *Kit) DetectStartOffsets() {
+	detect := func(s *Sample) {
+		if _, ok := k.offsets[s]; ok {
+			return
+		}
+		if d := DetectOnset(s); d > 0 {
+			k.offsets[s] = d
+		}
+	}
+	for _, s := range k.byName {
+		detect(s)
+	}
+	for _, s := range k.byID {
+		detect(s)
+	}
+}
+
+// StartOffset returns the start offset of the sample of the track, 0 for
+// none or tracks without sample.
+func (k *Kit) StartOffset(t *Track) time.Duration {
+	s, ok := k.Sample(t)
+	if !ok {
+		return 0
+	}
+	return k.offsets[s]
+}
+
+// startFrames returns the start offset of the sample in frames. The kit may
+// be nil.
+func (k *Kit) startFrames(s *Sample) int {
+	if k == nil {
+		return 0
+	}
+	return int(math.Round(k.offsets[s].Seconds() * float64(s.Rate)))
+}
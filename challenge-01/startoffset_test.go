@@ -0,0 +1,52 @@
+package drum
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStartOffset(t *testing.T) {
+	// 120 BPM at 800 frames per second gives 100 frames per step, the
+	// sample sounds after 10 frames of silence and a little noise
+	lead := &Sample{Rate: 800, Left: make([]float32, 40), Right: make([]float32, 40)}
+	lead.Left[5], lead.Right[5] = 0.001, 0.001
+	for f := 10; f < 40; f++ {
+		lead.Left[f], lead.Right[f] = 1, 1
+	}
+	if exp, got := 10*time.Second/800, DetectOnset(lead); got != exp {
+		t.Errorf("Expected onset %v but got %v", exp, got)
+	}
+	if got := DetectOnset(&Sample{Rate: 800, Left: make([]float32, 4), Right: make([]float32, 4)}); got != 0 {
+		t.Errorf("Expected no onset of silence but got %v", got)
+	}
+
+	p := &Pattern{tempo: 120, tracks: []*Track{{name: "kick", steps: Steps{true, false, false, false, true}}}}
+	kit := NewKit("test")
+	kit.Add("kick", lead)
+	if err := kit.SetStartOffset("snare", time.Millisecond); !errors.Is(err, ErrMissingSample) {
+		t.Errorf("Expected %v but got %v", ErrMissingSample, err)
+	}
+	if err := kit.SetStartOffset("kick", time.Second); !errors.Is(err, ErrInvalidStartOffset) {
+		t.Errorf("Expected %v but got %v", ErrInvalidStartOffset, err)
+	}
+	kit.DetectStartOffsets()
+	if exp, got := 10*time.Second/800, kit.StartOffset(p.tracks[0]); got != exp {
+		t.Errorf("Expected offset %v but got %v", exp, got)
+	}
+	out, err := Render(p, kit, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first hit is trimmed, the second starts early
+	if out.Left[0] == 0 || out.Left[399] != 0 || out.Left[400] == 0 || out.Left[429] == 0 || out.Left[430] != 0 {
+		t.Errorf("Expected the sound on steps 0 and 4 but got %v", out.Left[:440])
+	}
+
+	if err := kit.SetStartOffset("kick", 0); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ = Render(p, kit, 1); out.Left[0] != 0 || out.Left[410] == 0 {
+		t.Errorf("Expected the leading silence kept without offset")
+	}
+}
@@ -0,0 +1,190 @@
+package drum
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// DecodeStats describes a single decode, see WithStats.
+type DecodeStats struct {
+	// BytesRead is the number of bytes read from the reader, all of the
+	// data for DecodeBytes.
+	BytesRead int64
+	// DeclaredPayload is the payload size of the file header, 0 when the
+	// header could not be read.
+	DeclaredPayload int64
+	// PayloadRead is the number of payload bytes parsed, less than declared
+	// for truncated files.
+	PayloadRead int64
+	// TrackBoundary is the payload offset behind the last track parsed, the
+	// declared payload size for well formed files. A declared size between
+	// it and the end of the next track fails with ErrPayloadSizeMismatch.
+	TrackBoundary int64
+	Tracks        int // tracks parsed
+	Duration      time.Duration
+	Err           error // nil on success
+}
+
+// WithStats makes Decode, DecodeBytes, DecodeInto, DecodeFile and
+// DecodeStream call fn with the statistics of every decode when it
+// finished, also when it failed. Pass the method Observe of DecodeCounters
+// to aggregate them.
+func WithStats(fn func(DecodeStats)) DecodeOption {
+	return func(o *decodeOptions) {
+		o.stats = fn
+	}
+}
+
+// statsRecorder collects the statistics of a single decode. All methods are
+// no-ops on a nil recorder, the default without WithStats.
+type statsRecorder struct {
+	stats DecodeStats
+	start time.Time
+	fn    func(DecodeStats)
+}
+
+func newStatsRecorder(fn func(DecodeStats)) *statsRecorder {
+	if fn == nil {
+		return nil
+	}
+	return &statsRecorder{start: time.Now(), fn: fn}
+}
+
+// reader returns r counting the bytes read when recording.
+func (s *statsRecorder) reader(r io.Reader) (io.Reader, *offsetReader) {
+	if s == nil {
+		return r, nil
+	}
+	or := &offsetReader{r: r}
+	return or, or
+}
+
+func (s *statsRecorder) declare(size int64) {
+	if s != nil {
+		s.stats.DeclaredPayload = size
+	}
+}
+
+func (s *statsRecorder) track() {
+	if s != nil {
+		s.stats.Tracks++
+	}
+}
+
+func (s *statsRecorder) boundary(n int64) {
+	if s != nil {
+		s.stats.TrackBoundary = n
+	}
+}
+
+func (s *statsRecorder) payloadRead(n int64) {
+	if s != nil {
+		s.stats.PayloadRead = n
+	}
+}
+
+// finish reports the statistics with the bytes read so far.
+func (s *statsRecorder) finish(read *offsetReader, n int64, err error) {
+	if s == nil {
+		return
+	}
+	s.stats.BytesRead = n
+	if read != nil {
+		s.stats.BytesRead = read.n
+	}
+	s.stats.Duration = time.Since(s.start)
+	s.stats.Err = err
+	s.fn(s.stats)
+}
+
+// errorClasses are the failure classes counted by DecodeCounters.
+var errorClasses = []error{
+	ErrUnsupportedFileFormat,
+	ErrTruncatedPayload,
+	ErrPayloadSizeMismatch,
+	ErrPayloadTooLarge,
+	ErrTooManyTracks,
+	ErrNameTooLong,
+	ErrInvalidStepValue,
+	ErrChecksumMismatch,
+	ErrTrailingData,
+	ErrLimitExceeded,
+}
+
+// DecodeCounters aggregates the statistics of many decodes for monitoring,
+// for example of a service decoding uploads. It is safe for concurrent use
+// and implements expvar.Var, so it can be published as is:
+//
+//	var decodes drum.DecodeCounters
+//	expvar.Publish("splice_decodes", &decodes)
+//	p, err := drum.Decode(upload, drum.WithStats(decodes.Observe))
+type DecodeCounters struct {
+	mu       sync.Mutex
+	snapshot DecodeSnapshot
+}
+
+// DecodeSnapshot is the state of DecodeCounters.
+type DecodeSnapshot struct {
+	Decodes         int64            `json:"decodes"`
+	Failures        int64            `json:"failures"`
+	FailuresByClass map[string]int64 `json:"failuresByClass,omitempty"` // by the message of the Err variable, "other" for the rest
+	BytesRead       int64            `json:"bytesRead"`
+	DeclaredPayload int64            `json:"declaredPayload"`
+	PayloadRead     int64            `json:"payloadRead"`
+	Tracks          int64            `json:"tracks"`
+	Duration        time.Duration    `json:"durationNanoseconds"`
+}
+
+// Observe adds the statistics of a decode.
+func (c *DecodeCounters) Observe(s DecodeStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.snapshot.Decodes++
+	c.snapshot.BytesRead += s.BytesRead
+	c.snapshot.DeclaredPayload += s.DeclaredPayload
+	c.snapshot.PayloadRead += s.PayloadRead
+	c.snapshot.Tracks += int64(s.Tracks)
+	c.snapshot.Duration += s.Duration
+	if s.Err != nil {
+		c.snapshot.Failures++
+		if c.snapshot.FailuresByClass == nil {
+			c.snapshot.FailuresByClass = make(map[string]int64)
+		}
+		c.snapshot.FailuresByClass[ErrorClass(s.Err)]++
+	}
+}
+
+// Snapshot returns a copy of the counters.
+func (c *DecodeCounters) Snapshot() DecodeSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.snapshot
+	if s.FailuresByClass != nil {
+		s.FailuresByClass = make(map[string]int64, len(c.snapshot.FailuresByClass))
+		for class, n := range c.snapshot.FailuresByClass {
+			s.FailuresByClass[class] = n
+		}
+	}
+	return s
+}
+
+// String returns the counters as JSON, see expvar.Var.
+func (c *DecodeCounters) String() string {
+	b, _ := json.Marshal(c.Snapshot())
+	return string(b)
+}
+
+// ErrorClass returns the message of the first of the Err variables of the
+// decoder the error wraps, like "truncated payload", or "other". It keeps
+// the labels of metrics few.
+func ErrorClass(err error) string {
+	for _, class := range errorClasses {
+		if errors.Is(err, class) {
+			return class.Error()
+		}
+	}
+	return "other"
+}
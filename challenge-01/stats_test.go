@@ -0,0 +1,91 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestWithStats(t *testing.T) {
+	raw, err := ioutil.ReadFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	declared := int64(len(raw) - typeHeaderLength - 8)
+	decoders := map[string]func(data []byte, opts ...DecodeOption) error{
+		"Decode": func(data []byte, opts ...DecodeOption) error {
+			_, err := Decode(bytes.NewReader(data), opts...)
+			return err
+		},
+		"DecodeBytes": func(data []byte, opts ...DecodeOption) error {
+			_, err := DecodeBytes(data, opts...)
+			return err
+		},
+		"DecodeInto": func(data []byte, opts ...DecodeOption) error {
+			return DecodeInto(bytes.NewReader(data), new(Pattern), opts...)
+		},
+		"DecodeStream": func(data []byte, opts ...DecodeOption) error {
+			return DecodeStream(bytes.NewReader(data), func(PatternHeader, *Track) error { return nil }, opts...)
+		},
+	}
+	for name, decode := range decoders {
+		var got []DecodeStats
+		record := WithStats(func(s DecodeStats) { got = append(got, s) })
+		if err := decode(raw, record); err != nil {
+			t.Fatal(err)
+		}
+		truncated := raw[:len(raw)-10]
+		if err := decode(truncated, record); err == nil {
+			t.Fatalf("%s: Expected the truncated file to fail", name)
+		}
+		if len(got) != 2 {
+			t.Fatalf("%s: Expected 2 stats but got %v", name, got)
+		}
+		s := got[0]
+		if s.BytesRead != int64(len(raw)) || s.DeclaredPayload != declared || s.PayloadRead != declared || s.TrackBoundary != declared || s.Tracks != 6 || s.Err != nil || s.Duration <= 0 {
+			t.Errorf("%s: Unexpected stats %+v", name, s)
+		}
+		s = got[1]
+		if s.BytesRead != int64(len(truncated)) || s.DeclaredPayload != declared || s.PayloadRead != declared-10 || s.TrackBoundary != 169 || s.Tracks != 5 || ErrorClass(s.Err) != "truncated payload" {
+			t.Errorf("%s: Unexpected stats of the truncated file %+v", name, s)
+		}
+	}
+}
+
+func TestDecodeCounters(t *testing.T) {
+	raw, err := ioutil.ReadFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c DecodeCounters
+	for _, data := range [][]byte{raw, raw[:40], []byte("GIF89a"), raw} {
+		DecodeBytes(data, WithStats(c.Observe))
+	}
+	s := c.Snapshot()
+	if s.Decodes != 4 || s.Failures != 2 || s.Tracks != 8 || s.BytesRead != int64(2*len(raw)+40+6) {
+		t.Errorf("Unexpected counters %+v", s)
+	}
+	exp := map[string]int64{"truncated payload": 1, "unsupported file format": 1}
+	if len(s.FailuresByClass) != len(exp) || s.FailuresByClass["truncated payload"] != 1 || s.FailuresByClass["unsupported file format"] != 1 {
+		t.Errorf("Expected the failures %v but got %v", exp, s.FailuresByClass)
+	}
+	s.FailuresByClass["other"] = 1
+	if c.Snapshot().FailuresByClass["other"] != 0 {
+		t.Errorf("Expected a copy of the counters")
+	}
+
+	expvar.Publish("splice_decodes_test", &c)
+	var published DecodeSnapshot
+	if err := json.Unmarshal([]byte(expvar.Get("splice_decodes_test").String()), &published); err != nil {
+		t.Fatal(err)
+	}
+	if published.Decodes != 4 || published.FailuresByClass["truncated payload"] != 1 {
+		t.Errorf("Unexpected published counters %+v", published)
+	}
+	if class := ErrorClass(ErrNoPattern); class != "other" {
+		t.Errorf("Expected other but got %s", class)
+	}
+}
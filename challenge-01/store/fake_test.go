@@ -0,0 +1,253 @@
+package store
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// fakeDB is a database/sql driver and connector running the statements of
+// this package on maps, so that Save, Load and the queries are tested
+// without SQLite. Statements are matched by their start, other statements
+// fail. A transaction is rolled back by restoring a copy of the data taken
+// at its start.
+type fakeDB struct {
+	mu       sync.Mutex
+	version  int64
+	patterns map[int64]fakePattern
+	tags     map[int64]map[string]bool
+	lastID   int64
+	saved    *fakeDB // data at the start of the transaction
+}
+
+type fakePattern struct {
+	hash, version string
+	tempo         float64
+	title, author string
+	data          []byte
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{patterns: make(map[int64]fakePattern), tags: make(map[int64]map[string]bool)}
+}
+
+// copy returns a copy of the data.
+func (db *fakeDB) copy() *fakeDB {
+	c := newFakeDB()
+	c.version, c.lastID = db.version, db.lastID
+	for id, p := range db.patterns {
+		c.patterns[id] = p
+	}
+	for id, tags := range db.tags {
+		c.tags[id] = make(map[string]bool)
+		for tag := range tags {
+			c.tags[id][tag] = true
+		}
+	}
+	return c
+}
+
+func (db *fakeDB) Open(string) (driver.Conn, error)             { return fakeConn{db}, nil }
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{db}, nil }
+func (db *fakeDB) Driver() driver.Driver                        { return db }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{c.db, strings.Join(strings.Fields(query), " ")}, nil
+}
+func (c fakeConn) Close() error { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.saved = c.db.copy()
+	return fakeTx{c.db}, nil
+}
+
+type fakeTx struct{ db *fakeDB }
+
+func (tx fakeTx) Commit() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.saved = nil
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	saved := tx.db.saved
+	tx.db.version, tx.db.lastID, tx.db.patterns, tx.db.tags = saved.version, saved.lastID, saved.patterns, saved.tags
+	tx.db.saved = nil
+	return nil
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+type fakeResult struct{ id, n int64 }
+
+func (r fakeResult) LastInsertId() (int64, error) { return r.id, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return r.n, nil }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	db := s.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	switch q := s.query; {
+	case strings.HasPrefix(q, "CREATE "):
+		return fakeResult{}, nil
+	case strings.HasPrefix(q, "INSERT INTO schema_version "):
+		db.version = args[0].(int64)
+		return fakeResult{n: 1}, nil
+	case strings.HasPrefix(q, "INSERT INTO patterns "):
+		p := fakePattern{args[0].(string), args[1].(string), args[2].(float64), args[3].(string), args[4].(string), args[5].([]byte)}
+		if err := db.unique(0, p.hash); err != nil {
+			return nil, err
+		}
+		db.lastID++
+		db.patterns[db.lastID] = p
+		return fakeResult{id: db.lastID, n: 1}, nil
+	case strings.HasPrefix(q, "UPDATE patterns SET hash = ?, version = ?, tempo = ?, title = ?, author = ?, data = ? WHERE id = ?"):
+		id := args[6].(int64)
+		if _, ok := db.patterns[id]; !ok {
+			return fakeResult{}, nil
+		}
+		p := fakePattern{args[0].(string), args[1].(string), args[2].(float64), args[3].(string), args[4].(string), args[5].([]byte)}
+		if err := db.unique(id, p.hash); err != nil {
+			return nil, err
+		}
+		db.patterns[id] = p
+		return fakeResult{n: 1}, nil
+	case strings.HasPrefix(q, "DELETE FROM tags WHERE pattern_id = ?"):
+		id := args[0].(int64)
+		n := int64(len(db.tags[id]))
+		delete(db.tags, id)
+		return fakeResult{n: n}, nil
+	case strings.HasPrefix(q, "INSERT OR IGNORE INTO tags "):
+		id := args[0].(int64)
+		if db.tags[id] == nil {
+			db.tags[id] = make(map[string]bool)
+		}
+		db.tags[id][args[1].(string)] = true
+		return fakeResult{n: 1}, nil
+	case strings.HasPrefix(q, "DELETE FROM patterns WHERE id = ?"):
+		id := args[0].(int64)
+		if _, ok := db.patterns[id]; !ok {
+			return fakeResult{}, nil
+		}
+		delete(db.patterns, id)
+		return fakeResult{n: 1}, nil
+	}
+	return nil, fmt.Errorf("unsupported statement %q", s.query)
+}
+
+// unique fails when a pattern other than the one with the id has the hash.
+func (db *fakeDB) unique(id int64, hash string) error {
+	for other, p := range db.patterns {
+		if other != id && p.hash == hash {
+			return errors.New("UNIQUE constraint failed: patterns.hash")
+		}
+	}
+	return nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	db := s.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	rows := &fakeRows{}
+	switch q := s.query; {
+	case strings.HasPrefix(q, "SELECT MAX(version) FROM schema_version"):
+		var v driver.Value
+		if db.version != 0 {
+			v = db.version
+		}
+		rows.add(v)
+	case strings.HasPrefix(q, "SELECT id FROM patterns WHERE hash = ?"):
+		for _, id := range db.ids() {
+			if db.patterns[id].hash == args[0].(string) {
+				rows.add(id)
+			}
+		}
+	case strings.HasPrefix(q, "SELECT data FROM patterns WHERE id = ?"):
+		if p, ok := db.patterns[args[0].(int64)]; ok {
+			rows.add(p.data)
+		}
+	case strings.HasPrefix(q, "SELECT id, hash, version, tempo, title, author FROM patterns ORDER BY id"):
+		for _, id := range db.ids() {
+			rows.addInfo(id, db.patterns[id])
+		}
+	case strings.HasPrefix(q, "SELECT p.id, p.hash, p.version, p.tempo, p.title, p.author FROM patterns p JOIN tags t"):
+		tags := args[:len(args)-1]
+		for _, id := range db.ids() {
+			n := 0
+			for _, tag := range tags {
+				if db.tags[id][tag.(string)] {
+					n++
+				}
+			}
+			if n > 0 && int64(n) == args[len(args)-1].(int64) {
+				rows.addInfo(id, db.patterns[id])
+			}
+		}
+	case strings.HasPrefix(q, "SELECT tag FROM tags WHERE pattern_id = ? ORDER BY tag"):
+		var tags []string
+		for tag := range db.tags[args[0].(int64)] {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			rows.add(tag)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported query %q", s.query)
+	}
+	return rows, nil
+}
+
+// ids returns the ids of the patterns in order.
+func (db *fakeDB) ids() []int64 {
+	var ids []int64
+	for id := range db.patterns {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) add(values ...driver.Value) { r.rows = append(r.rows, values) }
+
+func (r *fakeRows) addInfo(id int64, p fakePattern) {
+	r.add(id, p.hash, p.version, p.tempo, p.title, p.author)
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	return make([]string, len(r.rows[0]))
+}
+func (r *fakeRows) Close() error { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
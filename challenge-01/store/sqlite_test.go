@@ -0,0 +1,30 @@
+//go:build sqlite
+
+// Run with a SQLite driver installed:
+//
+//	go get github.com/mattn/go-sqlite3
+//	go test -tags sqlite ./store
+
+package store
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1) // every connection has its own in memory database
+	for i := 0; i < 2; i++ {
+		if err := Migrate(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testStore(t, db)
+}
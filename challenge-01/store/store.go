@@ -0,0 +1,314 @@
+// Package store persists drum patterns in a SQL database using
+// database/sql. The statements target SQLite, the driver must be imported
+// by the application, for example:
+//
+//	import _ "github.com/mattn/go-sqlite3"
+//
+//	db, err := sql.Open("sqlite3", "patterns.db")
+//	...
+//	err = store.Migrate(db)
+//
+// Patterns are stored in the SPLICE format, so nothing is lost, and the
+// fields needed for queries are stored in columns next to it. The tags are
+// the ones of the pattern metadata.
+package store
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// ErrNotFound is returned when there is no pattern with the id.
+var ErrNotFound = errors.New("pattern not found")
+
+// migrations are the statements upgrading the schema from the version of
+// their index to the next one. Applied migrations must never be changed.
+var migrations = [][]string{
+	{
+		`CREATE TABLE patterns (
+			id      INTEGER PRIMARY KEY,
+			hash    TEXT NOT NULL UNIQUE,
+			version TEXT NOT NULL,
+			tempo   REAL NOT NULL,
+			title   TEXT NOT NULL,
+			author  TEXT NOT NULL,
+			data    BLOB NOT NULL
+		)`,
+		`CREATE TABLE tags (
+			pattern_id INTEGER NOT NULL REFERENCES patterns(id) ON DELETE CASCADE,
+			tag        TEXT NOT NULL,
+			PRIMARY KEY (pattern_id, tag)
+		)`,
+		`CREATE INDEX tags_tag ON tags(tag)`,
+	},
+}
+
+// Migrate creates or upgrades the schema to the current version. Every
+// migration is applied in its own transaction.
+func Migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`); err != nil {
+		return fmt.Errorf("migrate: %v", err)
+	}
+	var version sql.NullInt64
+	if err := db.QueryRow(`SELECT MAX(version) FROM schema_version`).Scan(&version); err != nil {
+		return fmt.Errorf("migrate: %v", err)
+	}
+	if int(version.Int64) > len(migrations) {
+		return fmt.Errorf("migrate: schema version %d is newer than this package", version.Int64)
+	}
+	for v := int(version.Int64); v < len(migrations); v++ {
+		err := inTx(db, func(tx *sql.Tx) error {
+			for _, stmt := range migrations[v] {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			_, err := tx.Exec(`INSERT INTO schema_version (version) VALUES (?)`, v+1)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migrate to version %d: %v", v+1, err)
+		}
+	}
+	return nil
+}
+
+func inTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Info describes a stored pattern without decoding it.
+type Info struct {
+	ID      int64
+	Hash    string // see drum.Pattern.Hash
+	Version string
+	Tempo   float32
+	Title   string
+	Author  string
+	Tags    []string
+}
+
+// Save stores the pattern and returns its id. Patterns are stored by their
+// content hash, see drum.Pattern.Hash, which ignores the metadata: a pattern
+// with the hash of a stored one replaces it with its title, author, tags and
+// data, so saving a groove twice keeps one row, and so do two patterns with
+// the same steps but different titles or tags. Use Update to change a stored
+// pattern by its id.
+func Save(db *sql.DB, p *drum.Pattern) (int64, error) {
+	r, err := newRow(p)
+	if err != nil {
+		return 0, fmt.Errorf("save: %v", err)
+	}
+	var id int64
+	err = inTx(db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(`SELECT id FROM patterns WHERE hash = ?`, r.hash).Scan(&id)
+		switch {
+		case err == sql.ErrNoRows:
+			res, err := tx.Exec(`INSERT INTO patterns (hash, version, tempo, title, author, data) VALUES (?, ?, ?, ?, ?, ?)`,
+				r.hash, r.version, r.tempo, r.title, r.author, r.data)
+			if err != nil {
+				return err
+			}
+			if id, err = res.LastInsertId(); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			if _, err := r.update(tx, id); err != nil {
+				return err
+			}
+		}
+		return r.setTags(tx, id)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("save: %v", err)
+	}
+	return id, nil
+}
+
+// Update replaces the pattern with the id, for example after editing it.
+// ErrNotFound is returned when there is no pattern with the id and an error
+// when another stored pattern has the same content hash.
+func Update(db *sql.DB, id int64, p *drum.Pattern) error {
+	r, err := newRow(p)
+	if err != nil {
+		return fmt.Errorf("update: %v", err)
+	}
+	var n int64
+	err = inTx(db, func(tx *sql.Tx) error {
+		var err error
+		if n, err = r.update(tx, id); err != nil || n == 0 {
+			return err
+		}
+		return r.setTags(tx, id)
+	})
+	if err != nil {
+		return fmt.Errorf("update: %v", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// row holds the columns and tags of a pattern.
+type row struct {
+	hash, version string
+	tempo         float32
+	title, author string
+	data          []byte
+	tags          []string
+}
+
+func newRow(p *drum.Pattern) (row, error) {
+	var data bytes.Buffer
+	if err := drum.Encode(&data, p); err != nil {
+		return row{}, err
+	}
+	m := p.Metadata()
+	return row{p.Hash(), p.Version(), p.Tempo(), m.Title, m.Author, data.Bytes(), m.Tags}, nil
+}
+
+// update writes the row to the pattern with the id and returns the number
+// of rows changed.
+func (r row) update(tx *sql.Tx, id int64) (int64, error) {
+	res, err := tx.Exec(`UPDATE patterns SET hash = ?, version = ?, tempo = ?, title = ?, author = ?, data = ? WHERE id = ?`,
+		r.hash, r.version, r.tempo, r.title, r.author, r.data, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// setTags replaces the tags of the pattern with the id.
+func (r row) setTags(tx *sql.Tx, id int64) error {
+	if _, err := tx.Exec(`DELETE FROM tags WHERE pattern_id = ?`, id); err != nil {
+		return err
+	}
+	for _, tag := range r.tags {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO tags (pattern_id, tag) VALUES (?, ?)`, id, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load returns the pattern with the id.
+func Load(db *sql.DB, id int64) (*drum.Pattern, error) {
+	var data []byte
+	err := db.QueryRow(`SELECT data FROM patterns WHERE id = ?`, id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load: %v", err)
+	}
+	p, err := drum.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("load pattern %d: %v", id, err)
+	}
+	return p, nil
+}
+
+// Delete removes the pattern with the id.
+func Delete(db *sql.DB, id int64) error {
+	var n int64
+	err := inTx(db, func(tx *sql.Tx) error {
+		// SQLite only cascades with foreign keys turned on
+		if _, err := tx.Exec(`DELETE FROM tags WHERE pattern_id = ?`, id); err != nil {
+			return err
+		}
+		res, err := tx.Exec(`DELETE FROM patterns WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("delete: %v", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// List returns all stored patterns ordered by id.
+func List(db *sql.DB) ([]Info, error) {
+	return query(db, `SELECT id, hash, version, tempo, title, author FROM patterns ORDER BY id`)
+}
+
+// FindByTags returns the patterns having all of the tags ordered by id.
+func FindByTags(db *sql.DB, tags ...string) ([]Info, error) {
+	if len(tags) == 0 {
+		return List(db)
+	}
+	args := make([]interface{}, 0, len(tags)+1)
+	for _, tag := range tags {
+		args = append(args, tag)
+	}
+	args = append(args, len(tags))
+	return query(db, `SELECT p.id, p.hash, p.version, p.tempo, p.title, p.author FROM patterns p
+		JOIN tags t ON t.pattern_id = p.id
+		WHERE t.tag IN (?`+strings.Repeat(", ?", len(tags)-1)+`)
+		GROUP BY p.id HAVING COUNT(DISTINCT t.tag) = ? ORDER BY p.id`, args...)
+}
+
+// query returns the infos selected by stmt with their tags.
+func query(db *sql.DB, stmt string, args ...interface{}) ([]Info, error) {
+	rows, err := db.Query(stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %v", err)
+	}
+	var infos []Info
+	for rows.Next() {
+		var i Info
+		if err := rows.Scan(&i.ID, &i.Hash, &i.Version, &i.Tempo, &i.Title, &i.Author); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("query: %v", err)
+		}
+		infos = append(infos, i)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query: %v", err)
+	}
+	for n := range infos {
+		if infos[n].Tags, err = tagsOf(db, infos[n].ID); err != nil {
+			return nil, fmt.Errorf("query: %v", err)
+		}
+	}
+	return infos, nil
+}
+
+func tagsOf(db *sql.DB, id int64) ([]string, error) {
+	rows, err := db.Query(`SELECT tag FROM tags WHERE pattern_id = ? ORDER BY tag`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
@@ -0,0 +1,242 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// recorder is a database/sql driver and connector recording the executed statements. The
+// schema version query returns version, all other queries no rows.
+type recorder struct {
+	mu      sync.Mutex
+	version int64
+	stmts   []string
+	fail    string // prefix of the statement to fail
+}
+
+func (r *recorder) Open(string) (driver.Conn, error) { return recorderConn{r}, nil }
+
+type recorderConn struct{ r *recorder }
+
+func (c recorderConn) Prepare(query string) (driver.Stmt, error) {
+	return recorderStmt{c.r, query}, nil
+}
+func (c recorderConn) Close() error { return nil }
+func (c recorderConn) Begin() (driver.Tx, error) {
+	c.r.record("BEGIN")
+	return recorderTx{c.r}, nil
+}
+
+type recorderTx struct{ r *recorder }
+
+func (tx recorderTx) Commit() error   { tx.r.record("COMMIT"); return nil }
+func (tx recorderTx) Rollback() error { tx.r.record("ROLLBACK"); return nil }
+
+type recorderStmt struct {
+	r     *recorder
+	query string
+}
+
+func (s recorderStmt) Close() error  { return nil }
+func (s recorderStmt) NumInput() int { return -1 }
+func (s recorderStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.r.record(s.query)
+	if s.r.fail != "" && strings.HasPrefix(s.query, s.r.fail) {
+		return nil, errors.New("failed")
+	}
+	return driver.RowsAffected(1), nil
+}
+func (s recorderStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.r.record(s.query)
+	if strings.Contains(s.query, "MAX(version)") {
+		return &versionRows{version: s.r.version}, nil
+	}
+	return &versionRows{done: true}, nil
+}
+
+func (r *recorder) record(stmt string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stmts = append(r.stmts, summary(stmt))
+}
+
+// summary returns the command and the first name of a statement, for
+// example "CREATE patterns".
+func summary(stmt string) string {
+	words := strings.Fields(stmt)
+	for _, w := range words[1:] {
+		switch w {
+		case "TABLE", "INDEX", "IF", "NOT", "EXISTS", "INTO":
+			continue
+		}
+		return words[0] + " " + w
+	}
+	return words[0]
+}
+
+type versionRows struct {
+	version int64
+	done    bool
+}
+
+func (r *versionRows) Columns() []string { return []string{"version"} }
+func (r *versionRows) Close() error      { return nil }
+func (r *versionRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	if r.version == 0 {
+		dest[0] = nil
+	} else {
+		dest[0] = r.version
+	}
+	return nil
+}
+
+// Connect implements driver.Connector.
+func (r *recorder) Connect(context.Context) (driver.Conn, error) { return recorderConn{r}, nil }
+
+// Driver implements driver.Connector.
+func (r *recorder) Driver() driver.Driver { return r }
+
+func TestMigrate(t *testing.T) {
+	r := &recorder{}
+	if err := Migrate(sql.OpenDB(r)); err != nil {
+		t.Fatal(err)
+	}
+	exp := "[CREATE schema_version SELECT MAX(version) BEGIN CREATE patterns CREATE tags CREATE tags_tag INSERT schema_version COMMIT]"
+	if got := fmt.Sprint(r.stmts); got != exp {
+		t.Errorf("Expected %v but got %v", exp, got)
+	}
+
+	r = &recorder{version: int64(len(migrations))}
+	if err := Migrate(sql.OpenDB(r)); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.stmts) != 2 {
+		t.Errorf("Expected no migration when up to date but got %v", r.stmts)
+	}
+
+	r = &recorder{version: int64(len(migrations) + 1)}
+	if err := Migrate(sql.OpenDB(r)); err == nil {
+		t.Error("Expected error for a newer schema")
+	}
+
+	r = &recorder{fail: "CREATE INDEX"}
+	if err := Migrate(sql.OpenDB(r)); err == nil || r.stmts[len(r.stmts)-1] != "ROLLBACK" {
+		t.Errorf("Expected rollback of a failed migration but got %v, %v", err, r.stmts)
+	}
+}
+
+func TestStore(t *testing.T) {
+	db := sql.OpenDB(newFakeDB())
+	defer db.Close()
+	for i := 0; i < 2; i++ {
+		if err := Migrate(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testStore(t, db)
+}
+
+// testStore tests the functions of the package on a migrated, empty
+// database.
+func testStore(t *testing.T, db *sql.DB) {
+	var ids []int64
+	for i, tags := range [][]string{{"house", "4x4"}, {"hip-hop"}, {"house"}} {
+		p, err := drum.DecodeFile(filepath.Join("..", "fixtures", fmt.Sprintf("pattern_%d.splice", i+1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.SetMetadata(drum.Metadata{Title: fmt.Sprint("groove ", i+1), Tags: tags}); err != nil {
+			t.Fatal(err)
+		}
+		id, err := Save(db, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+		loaded, err := Load(db, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !loaded.Equal(p) || loaded.Metadata().Title != p.Metadata().Title {
+			t.Errorf("Expected %v but got %v", p, loaded)
+		}
+	}
+
+	// saving the same groove again replaces it
+	p, _ := Load(db, ids[0])
+	if err := p.SetMetadata(drum.Metadata{Title: "renamed", Tags: []string{"house"}}); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := Save(db, p); err != nil || id != ids[0] {
+		t.Errorf("Expected id %d but got %d, %v", ids[0], id, err)
+	}
+
+	infos, err := FindByTags(db, "house")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Title != "renamed" || fmt.Sprint(infos[0].Tags) != "[house]" || infos[1].ID != ids[2] {
+		t.Errorf("unexpected infos %+v", infos)
+	}
+	if infos, _ := FindByTags(db, "house", "hip-hop"); len(infos) != 0 {
+		t.Errorf("Expected no pattern with both tags but got %+v", infos)
+	}
+
+	if err := Delete(db, ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(db, ids[1]); err != ErrNotFound {
+		t.Errorf("Expected %v but got %v", ErrNotFound, err)
+	}
+	if err := Delete(db, ids[1]); err != ErrNotFound {
+		t.Errorf("Expected %v but got %v", ErrNotFound, err)
+	}
+	if infos, _ := List(db); len(infos) != 2 {
+		t.Errorf("Expected 2 patterns but got %+v", infos)
+	}
+
+	// the same steps with another title replace the stored pattern
+	p, _ = Load(db, ids[2])
+	if err := p.SetMetadata(drum.Metadata{Title: "same steps"}); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := Save(db, p); err != nil || id != ids[2] {
+		t.Errorf("Expected id %d but got %d, %v", ids[2], id, err)
+	}
+
+	// an edited pattern is updated by its id
+	p.Tracks()[0].SetStep(1, !p.Tracks()[0].Steps()[1])
+	if err := Update(db, ids[2], p); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := Load(db, ids[2]); err != nil || !loaded.Equal(p) {
+		t.Errorf("Expected the edited pattern but got %v, %v", loaded, err)
+	}
+	if infos, _ := List(db); len(infos) != 2 || infos[1].Hash != p.Hash() || infos[1].Tags != nil {
+		t.Errorf("Expected the updated hash and no tags but got %+v", infos)
+	}
+	first, _ := Load(db, ids[0])
+	if err := Update(db, ids[2], first); err == nil {
+		t.Error("Expected an error when updating to the content of another pattern")
+	}
+	if loaded, _ := Load(db, ids[2]); !loaded.Equal(p) {
+		t.Errorf("Expected the failed update rolled back but got %v", loaded)
+	}
+	if err := Update(db, ids[1], p); err != ErrNotFound {
+		t.Errorf("Expected %v but got %v", ErrNotFound, err)
+	}
+}
@@ -0,0 +1,53 @@
+package drum
+
+import (
+	"hash/crc32"
+	"io"
+)
+
+// PatternHeader holds the pattern fields stored in front of the tracks.
+type PatternHeader struct {
+	Version string
+	Tempo   float32
+
+	rawVersion []byte
+}
+
+// DecodeStream decodes a drum machine file from r without keeping its tracks
+// in memory. fn is called with the header for every track as soon as it is
+// parsed and decoding stops with the error when fn returns one.
+//
+// Only the payload and the checksum trailer, see WithChecksum, are read. The
+// extensions behind them need the complete pattern and are not applied, so
+// r is left at the end of the pattern data.
+func DecodeStream(r io.Reader, fn func(header PatternHeader, t *Track) error, opts ...DecodeOption) (err error) {
+	o := newDecodeOptions(opts)
+	o.rec = newStatsRecorder(o.stats)
+	r, read := o.rec.reader(r)
+	defer func() { o.rec.finish(read, 0, err) }()
+	r, log := newDecodeLogger(r, o.logger)
+	p, err := newPayloadReader(r, o, log)
+	if err != nil {
+		return err
+	}
+	declared := p.N
+	defer func() { o.rec.payloadRead(declared - p.N) }()
+	crc := crc32.NewIEEE()
+	if o.checksum {
+		p.R = io.TeeReader(p.R, crc)
+	}
+	header, err := decodeHeader(p, log)
+	if err != nil {
+		return err
+	}
+	err = decodeTracks(p, o, log, func(t *Track) error {
+		return fn(header, t)
+	})
+	if err != nil {
+		return err
+	}
+	if o.checksum {
+		return verifyChecksum(r, crc, log)
+	}
+	return nil
+}
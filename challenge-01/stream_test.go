@@ -0,0 +1,58 @@
+package drum
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestDecodeStream(t *testing.T) {
+	raw, err := ioutil.ReadFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := Decode(bytes.NewReader(raw))
+	var tracks []*Track
+	err = DecodeStream(bytes.NewReader(raw), func(h PatternHeader, tr *Track) error {
+		if h.Version != p.Version() || h.Tempo != p.Tempo() {
+			t.Errorf("Expected header %q %v but got %q %v", p.Version(), p.Tempo(), h.Version, h.Tempo)
+		}
+		tracks = append(tracks, tr)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := NewPattern(p.Version(), p.Tempo(), tracks...); !got.Equal(p) {
+		t.Errorf("Expected tracks of '%v' but got '%v'", p, got)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = DecodeStream(bytes.NewReader(raw), func(PatternHeader, *Track) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("Expected to stop after the first track but got %v after %d", err, calls)
+	}
+
+	// the reader is left behind the checksum for the next pattern
+	var buf bytes.Buffer
+	Encode(&buf, p, WithChecksumTrailer())
+	buf.WriteString("next")
+	if err := DecodeStream(&buf, func(PatternHeader, *Track) error { return nil }, WithChecksum()); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "next" {
+		t.Errorf("Expected remaining data but got %q", buf.String())
+	}
+
+	bad := append([]byte(nil), raw...)
+	bad[len(bad)-1] = 2
+	if err := DecodeStream(bytes.NewReader(bad), func(PatternHeader, *Track) error { return nil }); err == nil {
+		t.Error("Expected error for invalid step")
+	}
+}
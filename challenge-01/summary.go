@@ -0,0 +1,87 @@
+package drum
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// summaryEllipsis marks the steps, tracks or text left out of a summary.
+const summaryEllipsis = "…"
+
+// summarySteps are the numbers of steps per track tried by Summary, from the
+// whole bar down to the first beat.
+var summarySteps = []int{stepsLength, 8, 4}
+
+// Summary returns the pattern as a single line for list views, like
+//
+//	98.4bpm 5trk kick:x---x---x---x--- snare:----x-------x---
+//
+// with the tempo, the number of tracks and the steps of every track. Spaces
+// and colons of track names are replaced by underscores. A width above 0 is
+// the largest number of characters returned. When the line is longer, the
+// steps are cut to the first 8 and then 4 steps followed by "…", then the
+// last tracks are left out and counted like "+3", then all tracks, and
+// finally the line is cut with "…".
+func (p *Pattern) Summary(width int) string {
+	header := strconv.FormatFloat(float64(p.tempo), 'g', -1, 32) + "bpm " + strconv.Itoa(len(p.tracks)) + "trk"
+	fits := func(s string) bool {
+		return width <= 0 || utf8.RuneCountInString(s) <= width
+	}
+	var tokens []string
+	for _, n := range summarySteps {
+		tokens = tokens[:0]
+		for _, t := range p.tracks {
+			tokens = append(tokens, t.summary(n))
+		}
+		if line := joinSummary(header, tokens); fits(line) {
+			return line
+		}
+	}
+	for k := len(tokens) - 1; k >= 0; k-- {
+		line := joinSummary(header, tokens[:k]) + fmt.Sprintf(" +%d", len(tokens)-k)
+		if fits(line) {
+			return line
+		}
+	}
+	if fits(header) {
+		return header
+	}
+	if width == 1 {
+		return summaryEllipsis
+	}
+	r := []rune(header)
+	if len(r) > width {
+		r = r[:width-1]
+	}
+	return string(r) + summaryEllipsis
+}
+
+func joinSummary(header string, tokens []string) string {
+	if len(tokens) == 0 {
+		return header
+	}
+	return header + " " + strings.Join(tokens, " ")
+}
+
+// summary returns the name and the first n steps of the track.
+func (t *Track) summary(n int) string {
+	var b strings.Builder
+	b.WriteString(summaryName(t.name))
+	b.WriteByte(':')
+	for _, enabled := range t.steps[:n] {
+		if enabled {
+			b.WriteRune(symbolStepEnabled)
+		} else {
+			b.WriteRune(symbolStepDisabled)
+		}
+	}
+	if n < stepsLength {
+		b.WriteString(summaryEllipsis)
+	}
+	return b.String()
+}
+
+// summaryName keeps every track a single token of the summary.
+var summaryName = strings.NewReplacer(" ", "_", "\t", "_", ":", "_").Replace
@@ -0,0 +1,41 @@
+package drum
+
+import (
+	"path/filepath"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSummary(t *testing.T) {
+	p, err := DecodeFile(filepath.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	specs := map[int]string{
+		0:   "120bpm 6trk kick:x---x---x---x--- snare:----x-------x--- clap:----x-x--------- hh-open:--x---x-x-x---x- hh-close:x---x-------x--x cowbell:----------x-----",
+		120: "120bpm 6trk kick:x---x---… snare:----x---… clap:----x-x-… hh-open:--x---x-… hh-close:x---x---… cowbell:--------…",
+		100: "120bpm 6trk kick:x---… snare:----… clap:----… hh-open:--x-… hh-close:x---… cowbell:----…",
+		80:  "120bpm 6trk kick:x---… snare:----… clap:----… hh-open:--x-… hh-close:x---… +1",
+		40:  "120bpm 6trk kick:x---… snare:----… +4",
+		11:  "120bpm 6trk",
+		8:   "120bpm …",
+		1:   "…",
+	}
+	for width, exp := range specs {
+		got := p.Summary(width)
+		if got != exp {
+			t.Errorf("width %d: expected '%v' but got '%v'", width, exp, got)
+		}
+		if width > 0 && utf8.RuneCountInString(got) > width {
+			t.Errorf("width %d: summary too long: %v", width, got)
+		}
+	}
+}
+
+func TestSummaryNames(t *testing.T) {
+	tr, _ := NewTrack(1, "low tom:2", Steps{true})
+	p, _ := NewPattern("v", 98.4, tr)
+	if exp, got := "98.4bpm 1trk low_tom_2:x---------------", p.Summary(0); got != exp {
+		t.Errorf("expected '%v' but got '%v'", exp, got)
+	}
+}
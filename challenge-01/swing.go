@@ -0,0 +1,63 @@
+package drum
+
+import (
+	"errors"
+	"time"
+)
+
+var chunkSwing = chunkID{'S', 'W', 'N', 'G'}
+
+// FeatureSwing is the swing amount chunk.
+const FeatureSwing Feature = "swing"
+
+// MaxSwing is the maximum swing amount in percent.
+const MaxSwing = 100
+
+// ErrInvalidSwing is returned for a swing amount above MaxSwing.
+var ErrInvalidSwing = errors.New("invalid swing")
+
+// Swing returns the swing amount in percent. 0 plays straight sixteenths.
+func (p *Pattern) Swing() uint8 {
+	return p.swing
+}
+
+// SetSwing sets the swing amount in percent. Every off-beat sixteenth, the
+// second step of each pair, is delayed by the amount times half a step, so
+// 66 gives a triplet feel and 100 makes the first step of a pair three times
+// as long as the second one.
+func (p *Pattern) SetSwing(percent uint8) error {
+	if percent > MaxSwing {
+		return ErrInvalidSwing
+	}
+	p.swing = percent
+	return nil
+}
+
+// swingDelay returns by how much an off-beat step of the given length is
+// delayed.
+func swingDelay(step time.Duration, swing uint8) time.Duration {
+	return step * time.Duration(swing) / (2 * MaxSwing)
+}
+
+// swingTicks returns by how many ticks an off-beat step is delayed.
+func swingTicks(swing uint8) int {
+	return (ticksPerStep*int(swing) + MaxSwing) / (2 * MaxSwing)
+}
+
+func decodeSwingChunk(data []byte, p *Pattern) error {
+	if len(data) != 1 {
+		return errors.New("invalid size")
+	}
+	return p.SetSwing(data[0])
+}
+
+func encodeSwingChunk(p *Pattern) []byte {
+	if p.swing == 0 {
+		return nil
+	}
+	return []byte{p.swing}
+}
+
+func clearSwing(p *Pattern) {
+	p.swing = 0
+}
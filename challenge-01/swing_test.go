@@ -0,0 +1,70 @@
+package drum
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSwing(t *testing.T) {
+	p := &Pattern{version: "0.909", tempo: 120}
+	if err := p.SetSwing(MaxSwing + 1); err != ErrInvalidSwing {
+		t.Errorf("expected error '%s' but got '%v'", ErrInvalidSwing, err)
+	}
+	if err := p.SetSwing(50); err != nil {
+		t.Fatal(err)
+	}
+	// 125ms per step, off-beats delayed by a quarter step
+	for n, exp := range []time.Duration{0, 156250 * time.Microsecond, 250 * time.Millisecond, 406250 * time.Microsecond} {
+		if got := p.StepTime(n); got != exp {
+			t.Errorf("Expected step time %v but got %v for step %d", exp, got, n)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := uint8(50), decoded.Swing(); got != exp {
+		t.Errorf("Expected swing %v but got %v", exp, got)
+	}
+}
+
+func TestScheduleSwing(t *testing.T) {
+	p := &Pattern{tempo: 120, swing: 100, tracks: []*Track{{steps: Steps{true, true}}}}
+	events := p.schedule(2)
+	var ticks []int
+	for _, e := range events {
+		ticks = append(ticks, e.tick)
+	}
+	exp := []int{0, 36, 384, 420}
+	if len(ticks) != len(exp) {
+		t.Fatalf("Expected ticks %v but got %v", exp, ticks)
+	}
+	for i := range exp {
+		if ticks[i] != exp[i] {
+			t.Errorf("Expected ticks %v but got %v", exp, ticks)
+		}
+	}
+}
+
+func TestWriteMIDI(t *testing.T) {
+	p := &Pattern{tempo: 120, tracks: []*Track{{name: "kick", steps: Steps{true}}}}
+	var buf bytes.Buffer
+	if err := WriteMIDI(&buf, p, 1); err != nil {
+		t.Fatal(err)
+	}
+	exp := []byte("MThd\x00\x00\x00\x06\x00\x00\x00\x01\x00\x60MTrk\x00\x00\x00\x1c" +
+		"\x00\xff\x51\x03\x07\xa1\x20" + // tempo 500000us per beat
+		"\x00\xff\x58\x04\x04\x02\x18\x08" + // 4/4
+		"\x00\x99\x24\x7f" + // note on kick
+		"\x0c\x89\x24\x00" + // note off after 12 ticks
+		"\x82\x74\xff\x2f\x00") // end of track at 384 ticks
+	if got := buf.Bytes(); !bytes.Equal(got, exp) {
+		t.Errorf("Expected:\n%x\nbut got:\n%x", exp, got)
+	}
+}
@@ -0,0 +1,35 @@
+package drum
+
+import (
+	"math"
+	"time"
+)
+
+// Sync is a shared timeline, like a Link session, the Player locks its tempo
+// and phase to.
+type Sync interface {
+	// Timeline returns the tempo in beats per minute and the beat position
+	// at t. ok is false while there is no timeline to follow.
+	Timeline(t time.Time) (tempo, beat float64, ok bool)
+}
+
+// WithSync makes the player follow the timeline of s whenever it has one.
+// The tempo overrides the pattern tempo and SetTempo, and every bar of the
+// pattern starts on a multiple of four beats of the timeline. Tempo changes
+// are picked up at the next step without jumps.
+func WithSync(s Sync) PlayerOption {
+	return func(pl *Player) { pl.sync = s }
+}
+
+// syncStep returns the step of the timeline at t and the time until the
+// next step. A step played up to a quarter step early, or late by jitter or
+// swing, still counts as the step on the grid.
+func syncStep(tempo, beat float64) (step int, d time.Duration) {
+	n := math.Floor(beat*blockSize + 0.25)
+	step = int(math.Mod(n, stepsLength))
+	if step < 0 {
+		step += stepsLength
+	}
+	next := (n+1)/blockSize - beat // in beats
+	return step, time.Duration(next * float64(time.Minute) / tempo)
+}
@@ -0,0 +1,72 @@
+package drum
+
+import (
+	"path"
+	"testing"
+	"time"
+)
+
+// fixedSync is a timeline at a constant tempo that starts at origin.
+type fixedSync struct {
+	tempo  float64
+	origin time.Time
+	beats  float64 // beat at origin
+}
+
+func (s fixedSync) Timeline(t time.Time) (float64, float64, bool) {
+	return s.tempo, s.beats + t.Sub(s.origin).Minutes()*s.tempo, true
+}
+
+func TestSyncStep(t *testing.T) {
+	specs := []struct {
+		beat float64
+		step int
+		d    time.Duration
+	}{
+		{0, 0, 125 * time.Millisecond},
+		{1, 4, 125 * time.Millisecond},
+		{1.01, 4, 120 * time.Millisecond},
+		{0.2, 1, 150 * time.Millisecond},
+		{3.95, 0, 150 * time.Millisecond}, // early for the next bar
+		{4.3, 1, 100 * time.Millisecond},  // swung
+		{-0.25, 15, 125 * time.Millisecond},
+	}
+	for _, spec := range specs {
+		step, d := syncStep(120, spec.beat)
+		if step != spec.step || (d-spec.d).Round(time.Millisecond) != 0 {
+			t.Errorf("beat %v: expected step %d in %v but got %d in %v", spec.beat, spec.step, spec.d, step, d)
+		}
+	}
+}
+
+func TestPlayerSync(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// joining half way through the bar of a session at 2000 BPM
+	sync := fixedSync{tempo: 2000, origin: time.Now(), beats: 6}
+	stop := make(chan struct{})
+	var steps []int
+	var times []time.Time
+	pl := NewPlayer(p, func(ev StepEvent) {
+		steps = append(steps, ev.Step)
+		times = append(times, ev.Time)
+		if len(steps) == 4 {
+			close(stop)
+		}
+	}, WithSync(sync))
+	pl.SetTempo(60)
+	if err := pl.Play(stop); err != nil {
+		t.Fatal(err)
+	}
+	if steps[0] != 8 || steps[3] != 11 {
+		t.Errorf("expected steps 8 to 11 but got %v", steps)
+	}
+	for i, at := range times {
+		_, beat, _ := sync.Timeline(at)
+		if got := beat * blockSize; got < float64(24+i)-0.01 || got > float64(25+i) {
+			t.Errorf("step %d played at grid position %v", i, got)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package drum
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// SyncedPattern shares a pattern between an editor and readers like the
+// Player running in other goroutines. Readers get immutable snapshots with
+// Load, editors change the pattern with Edit, which applies the edit to a
+// copy and swaps it in atomically. Readers therefore never see a partial
+// edit and never block editors, and a snapshot stays valid while newer ones
+// are stored.
+//
+// Snapshots must not be modified, including their tracks. Edits are
+// serialized, so concurrent editors do not lose each other's changes.
+type SyncedPattern struct {
+	mu      sync.Mutex // serializes Edit and Store
+	current atomic.Pointer[Pattern]
+}
+
+// NewSyncedPattern returns a synced pattern starting with a copy of p, so
+// that p can still be modified by the caller.
+func NewSyncedPattern(p *Pattern) *SyncedPattern {
+	s := &SyncedPattern{}
+	s.current.Store(p.Clone())
+	return s
+}
+
+// Load returns the current snapshot.
+func (s *SyncedPattern) Load() *Pattern {
+	return s.current.Load()
+}
+
+// Edit applies the edit to a copy of the current snapshot and stores the
+// copy as the new snapshot. When the edit returns an error, the snapshot is
+// kept and the error returned.
+func (s *SyncedPattern) Edit(edit func(p *Pattern) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.current.Load().Clone()
+	if err := edit(p); err != nil {
+		return err
+	}
+	s.current.Store(p)
+	return nil
+}
+
+// Store replaces the snapshot with a copy of p.
+func (s *SyncedPattern) Store(p *Pattern) {
+	c := p.Clone()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current.Store(c)
+}
+
+// WithSyncedPattern makes the player play the snapshots of s. The current
+// snapshot is loaded before every step, so edits made with s.Edit are heard
+// from the next step on. SetPattern and SetSong stop following s.
+func WithSyncedPattern(s *SyncedPattern) PlayerOption {
+	return func(pl *Player) {
+		pl.synced = s
+		pl.pattern = s.Load()
+	}
+}
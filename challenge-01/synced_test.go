@@ -0,0 +1,85 @@
+package drum
+
+import (
+	"errors"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSyncedPattern(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSyncedPattern(p)
+	before := s.Load()
+	if err := s.Edit(func(p *Pattern) error { return p.tracks[0].SetStep(1, true) }); err != nil {
+		t.Fatal(err)
+	}
+	if before.tracks[0].steps[1] || p.tracks[0].steps[1] || !s.Load().tracks[0].steps[1] {
+		t.Error("Expected the edit to only change the new snapshot")
+	}
+	errEdit := errors.New("edit failed")
+	current := s.Load()
+	if err := s.Edit(func(p *Pattern) error { return errEdit }); err != errEdit || s.Load() != current {
+		t.Errorf("Expected a failed edit to keep the snapshot but got %v", err)
+	}
+	s.Store(p)
+	if s.Load() == p || s.Load().tracks[0].steps[1] {
+		t.Error("Expected a copy of the stored pattern")
+	}
+
+	pl := NewPlayer(nil, nil, WithSyncedPattern(s))
+	s.Edit(func(p *Pattern) error { return p.tracks[1].SetStep(1, true) })
+	pl.advance(time.Now())
+	if ev, _, _ := pl.advance(time.Now()); len(ev.Tracks) != 1 || ev.Tracks[0].name != "snare" {
+		t.Errorf("Expected the player to follow the edit but got %v", ev.Tracks)
+	}
+}
+
+// TestSyncedPatternRace edits the pattern while it is played, run it with
+// -race.
+func TestSyncedPatternRace(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetTempo(MaxTempo)
+	s := NewSyncedPattern(p)
+	stop := make(chan struct{})
+	pl := NewPlayer(nil, func(ev StepEvent) {
+		for _, t := range ev.Tracks {
+			_ = t.Steps()
+			_ = t.Name()
+		}
+	}, WithSyncedPattern(s))
+	done := make(chan error)
+	go func() { done <- pl.Play(stop) }()
+
+	var wg sync.WaitGroup
+	const editors, edits = 4, 25
+	for i := 0; i < editors; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < edits; j++ {
+				s.Edit(func(p *Pattern) error {
+					t := p.tracks[i]
+					t.SetStep(j%stepsLength, !t.steps[j%stepsLength])
+					return p.SetSwing(p.swing + 1)
+				})
+				time.Sleep(time.Millisecond)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Load().Swing(); got != editors*edits {
+		t.Errorf("Expected %d edits but got %d", editors*edits, got)
+	}
+}
@@ -0,0 +1,78 @@
+package drum
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+// The range of tempos in beats per minute considered sane. It matches the
+// range of the hardware.
+const (
+	MinTempo = 20
+	MaxTempo = 999
+)
+
+// ErrInvalidTempo is returned for a tempo outside of MinTempo and MaxTempo.
+var ErrInvalidTempo = errors.New("invalid tempo")
+
+func validTempo(tempo float32) bool {
+	return tempo >= MinTempo && tempo <= MaxTempo
+}
+
+// StepDuration returns the length of a single step, a sixteenth note.
+func (p *Pattern) StepDuration() time.Duration {
+	return stepDuration(p.tempo)
+}
+
+// BarDuration returns the length of a bar of the pattern, see BarSteps.
+func (p *Pattern) BarDuration() time.Duration {
+	return p.timeAt(p.tempo, float64(p.BarSteps()))
+}
+
+// StepTime returns the offset of the n-th step from the start of playback,
+// counting from 0, including the swing delay and the tempo map. Steps
+// beyond the pattern length continue in the next bar. Offsets are computed
+// from the start instead of summing up step durations so that rounding
+// errors do not accumulate.
+func (p *Pattern) StepTime(n int) time.Duration {
+	t := p.timeAt(p.tempo, float64(n))
+	if n%2 == 1 {
+		t += swingDelay(p.stepLength(p.tempo, n-1), p.swing)
+	}
+	return t
+}
+
+// WithTempo returns a copy of the pattern with the given tempo.
+func (p *Pattern) WithTempo(bpm float32) (*Pattern, error) {
+	if !validTempo(bpm) {
+		return nil, ErrInvalidTempo
+	}
+	c := p.Clone()
+	c.tempo, c.tempoCorrected = bpm, false
+	return c, nil
+}
+
+// stepDuration returns the length of a sixteenth note at the given tempo in
+// beats (quarter notes) per minute.
+func stepDuration(tempo float32) time.Duration {
+	return stepTime(tempo, 1)
+}
+
+// stepTime returns the offset of the n-th step at the given tempo rounded to
+// the nanosecond. It returns 0 for a tempo that is not positive.
+func stepTime(tempo float32, n int) time.Duration {
+	if tempo <= 0 {
+		return 0
+	}
+	return time.Duration(math.Round(float64(n) * float64(time.Minute) / tempo64(tempo) / blockSize))
+}
+
+// tempo64 converts the tempo to float64 by its shortest decimal
+// representation, which is the value printed and entered by users. A plain
+// conversion would turn 98.4 into 98.40000152587891.
+func tempo64(tempo float32) float64 {
+	v, _ := strconv.ParseFloat(FormatTempo(tempo), 64)
+	return v
+}
@@ -0,0 +1,46 @@
+package drum
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTempoDurations(t *testing.T) {
+	testCases := []struct {
+		tempo float32
+		step  time.Duration
+		bar   time.Duration
+	}{
+		{120, 125 * time.Millisecond, 2 * time.Second},
+		{240, 62500 * time.Microsecond, time.Second},
+		{98.4, 152439024 * time.Nanosecond, 2439024390 * time.Nanosecond},
+	}
+	for _, testCase := range testCases {
+		p := &Pattern{tempo: testCase.tempo}
+		if got := p.StepDuration(); got != testCase.step {
+			t.Errorf("Expected step duration %v but got %v for tempo %v", testCase.step, got, testCase.tempo)
+		}
+		if got := p.BarDuration(); got != testCase.bar {
+			t.Errorf("Expected bar duration %v but got %v for tempo %v", testCase.bar, got, testCase.tempo)
+		}
+		if got := p.StepTime(stepsLength); got != testCase.bar {
+			t.Errorf("Expected step time %v but got %v for tempo %v", testCase.bar, got, testCase.tempo)
+		}
+	}
+}
+
+func TestWithTempo(t *testing.T) {
+	p := &Pattern{version: "0.909", tempo: 120}
+	c, err := p.WithTempo(90)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.tempo != 90 || p.tempo != 120 {
+		t.Errorf("unexpected tempos %v %v", c.tempo, p.tempo)
+	}
+	for _, bpm := range []float32{0, -1, MinTempo - 1, MaxTempo + 1} {
+		if _, err := p.WithTempo(bpm); err != ErrInvalidTempo {
+			t.Errorf("expected error '%s' for tempo %v but got '%v'", ErrInvalidTempo, bpm, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package drum
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultTempo is the tempo WithTempoCorrection falls back to unless told
+// otherwise.
+const DefaultTempo = 120
+
+// CodeTempoCorrected is the code of the issue reported for a tempo that was
+// corrected while decoding, see WithTempoCorrection.
+const CodeTempoCorrected = "tempo-corrected"
+
+// maxTempoOctaves is how often a tempo is doubled or halved at most to get
+// into the range of MinTempo and MaxTempo.
+const maxTempoOctaves = 4
+
+// WithTempoCorrection makes the decoder replace tempos outside of MinTempo
+// and MaxTempo, which come from files of broken firmware or tools, with a
+// plausible one, see CorrectTempo. The tempo as read is kept for Validate,
+// which reports the correction with CodeTempoCorrected. A fallback outside
+// of the range is replaced by DefaultTempo.
+func WithTempoCorrection(fallback float32) DecodeOption {
+	if !validTempo(fallback) {
+		fallback = DefaultTempo
+	}
+	return func(o *decodeOptions) {
+		o.tempoFallback = fallback
+	}
+}
+
+// CorrectTempo returns a plausible tempo for a tempo outside of MinTempo
+// and MaxTempo. A tempo off by up to four octaves, for example written at
+// double time or in frames instead of beats, is doubled or halved into the
+// range. Anything else, like 0, negative values, NaN or bit patterns of
+// other data, gets the fallback. Tempos in the range are returned as is.
+func CorrectTempo(tempo, fallback float32) float32 {
+	if validTempo(tempo) {
+		return tempo
+	}
+	t := float64(tempo)
+	if t > 0 && !math.IsInf(t, 0) {
+		for i := 0; i < maxTempoOctaves; i++ {
+			if t < MinTempo {
+				t *= 2
+			} else {
+				t /= 2
+			}
+			if validTempo(float32(t)) {
+				return float32(t)
+			}
+		}
+	}
+	return fallback
+}
+
+// correctTempo applies the tempo correction of the options to the decoded
+// pattern.
+func (o decodeOptions) correctTempo(p *Pattern, log *decodeLogger) {
+	if o.tempoFallback == 0 || validTempo(p.tempo) {
+		return
+	}
+	p.decodedTempo, p.tempoCorrected = p.tempo, true
+	p.tempo = CorrectTempo(p.tempo, o.tempoFallback)
+	log.warn("tempo corrected", int64(typeHeaderLength+8+maxVersionLength), "tempo", p.decodedTempo, "corrected", p.tempo)
+}
+
+// DecodedTempo returns the tempo as read from the file and whether it was
+// replaced, see WithTempoCorrection.
+func (p *Pattern) DecodedTempo() (float32, bool) {
+	if !p.tempoCorrected {
+		return p.tempo, false
+	}
+	return p.decodedTempo, true
+}
+
+func ruleTempoCorrected(p *Pattern) []Issue {
+	if !p.tempoCorrected {
+		return nil
+	}
+	msg := fmt.Sprintf("tempo %v was corrected to %v BPM", p.decodedTempo, p.tempo)
+	return []Issue{{SeverityWarning, CodeTempoCorrected, -1, msg}}
+}
@@ -0,0 +1,63 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"path"
+	"testing"
+)
+
+func TestCorrectTempo(t *testing.T) {
+	nan := math.Float32frombits(0x7fc00000)
+	for _, testCase := range []struct{ tempo, exp float32 }{
+		{120, 120}, {10000, 625}, {1960, 980}, {10, 20}, {2.5, 20}, {1, 100},
+		{0, 100}, {-120, 100}, {nan, 100}, {1e30, 100}, {1e-40, 100},
+	} {
+		if got := CorrectTempo(testCase.tempo, 100); got != testCase.exp {
+			t.Errorf("Expected %v for %v but got %v", testCase.exp, testCase.tempo, got)
+		}
+	}
+}
+
+func TestWithTempoCorrection(t *testing.T) {
+	data, err := ioutil.ReadFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint32(data[typeHeaderLength+8+maxVersionLength:], math.Float32bits(0))
+	p, err := DecodeBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.tempo != 0 || !HasErrors(Validate(p)) {
+		t.Errorf("Expected the tempo to be kept and flagged but got %v", p.tempo)
+	}
+
+	for name, decode := range map[string]func() (*Pattern, error){
+		"decode": func() (*Pattern, error) {
+			return Decode(bytes.NewReader(data), WithTempoCorrection(0))
+		},
+		"decode into": func() (*Pattern, error) {
+			var p Pattern
+			return &p, DecodeInto(bytes.NewReader(data), &p, WithTempoCorrection(0))
+		},
+	} {
+		p, err := decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tempo, corrected := p.DecodedTempo(); p.tempo != DefaultTempo || tempo != 0 || !corrected {
+			t.Errorf("%s: expected the default tempo but got %v from %v", name, p.tempo, tempo)
+		}
+		issues := Validate(p)
+		if HasErrors(issues) || len(issues) != 1 || issues[0].Code != CodeTempoCorrected {
+			t.Errorf("%s: unexpected issues %v", name, issues)
+		}
+		p.SetTempo(90)
+		if _, corrected := p.DecodedTempo(); corrected {
+			t.Errorf("%s: expected a set tempo to replace the correction", name)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package drum
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+var chunkTempoMap = chunkID{'T', 'M', 'A', 'P'}
+
+// FeatureTempoMap is the chunk of tempo changes within the bar.
+const FeatureTempoMap Feature = "tempo"
+
+// ErrInvalidTempoMap is returned for tempo points that are not sorted by
+// step, outside of the bar or with a tempo outside of MinTempo and MaxTempo.
+var ErrInvalidTempoMap = errors.New("invalid tempo map")
+
+// TempoPoint sets the tempo at the start of a step. With Ramp the tempo
+// moves linearly from the previous point to Tempo, for an accelerando or
+// ritardando, instead of changing at once.
+type TempoPoint struct {
+	Step  int
+	Tempo float32
+	Ramp  bool
+}
+
+// TempoMap is the tempo along the steps of a bar as points sorted by step.
+// Before the first point the tempo of the first point applies and after the
+// last point its tempo is kept until the end of the bar.
+type TempoMap []TempoPoint
+
+// Tempo returns the tempo at the position in steps from the start of the
+// bar, interpolated within ramps. It returns 0 for an empty map.
+func (m TempoMap) Tempo(step float64) float32 {
+	if len(m) == 0 {
+		return 0
+	}
+	i := m.segment(step)
+	if i < 0 {
+		return m[0].Tempo
+	}
+	from, to := m.tempos(i)
+	if from == to {
+		return float32(from)
+	}
+	f := (step - float64(m[i].Step)) / float64(m[i+1].Step-m[i].Step)
+	return float32(from + f*(to-from))
+}
+
+// Time returns the offset of the position in steps from the start of the
+// bar. Ramps are integrated exactly, so the result matches the continuously
+// changing tempo. It returns 0 for an empty map.
+func (m TempoMap) Time(step float64) time.Duration {
+	if len(m) == 0 {
+		return 0
+	}
+	minutes := math.Min(step, float64(m[0].Step)) / blockSize / tempo64(m[0].Tempo)
+	for i := range m {
+		start := float64(m[i].Step)
+		if step <= start {
+			break
+		}
+		end := step
+		if i+1 < len(m) && float64(m[i+1].Step) < end {
+			end = float64(m[i+1].Step)
+		}
+		from, to := m.tempos(i)
+		if from == to {
+			minutes += (end - start) / blockSize / from
+			continue
+		}
+		// the tempo rises by k per step, so a step at tempo v lasts 1/v
+		// quarter notes per minute and integrates to ln(v) / k
+		k := (to - from) / float64(m[i+1].Step-m[i].Step)
+		minutes += math.Log((from+k*(end-start))/from) / k / blockSize
+	}
+	return time.Duration(math.Round(minutes * float64(time.Minute)))
+}
+
+// segment returns the index of the last point at or before the step, -1
+// before the first point.
+func (m TempoMap) segment(step float64) int {
+	i := -1
+	for i+1 < len(m) && float64(m[i+1].Step) <= step {
+		i++
+	}
+	return i
+}
+
+// tempos returns the tempo at the start and the end of the segment from
+// point i to the next one.
+func (m TempoMap) tempos(i int) (from, to float64) {
+	from = tempo64(m[i].Tempo)
+	if i+1 < len(m) && m[i+1].Ramp {
+		return from, tempo64(m[i+1].Tempo)
+	}
+	return from, from
+}
+
+func (m TempoMap) valid() bool {
+	for i, pt := range m {
+		if pt.Step < 0 || pt.Step >= stepsLength || !validTempo(pt.Tempo) {
+			return false
+		}
+		if i > 0 && pt.Step <= m[i-1].Step {
+			return false
+		}
+	}
+	return true
+}
+
+func (m TempoMap) equal(o TempoMap) bool {
+	if len(m) != len(o) {
+		return false
+	}
+	for i := range m {
+		if m[i] != o[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TempoMap returns the tempo changes within the bar starting with the
+// pattern tempo at step 0. The map is repeated in every bar.
+func (p *Pattern) TempoMap() TempoMap {
+	return append(TempoMap{{Step: 0, Tempo: p.tempo}}, p.tempoMap...)
+}
+
+// SetTempoMap sets the tempo changes within the bar. A point at step 0
+// without ramp sets the pattern tempo, so that the map returned by TempoMap
+// can be set again; a nil map removes all changes. The scheduler, the MIDI
+// and WAV export and the Player honor the map, tempo overrides of songs and
+// players scale it.
+func (p *Pattern) SetTempoMap(m TempoMap) error {
+	if !m.valid() {
+		return ErrInvalidTempoMap
+	}
+	tempo := p.tempo
+	if len(m) > 0 && m[0].Step == 0 {
+		if m[0].Ramp {
+			return ErrInvalidTempoMap
+		}
+		tempo, m = m[0].Tempo, m[1:]
+	}
+	p.tempo = tempo
+	p.tempoMap = nil
+	if len(m) > 0 {
+		p.tempoMap = append(TempoMap(nil), m...)
+	}
+	return nil
+}
+
+// timeAt returns the offset of the position in steps from the start of the
+// first bar when played at the tempo instead of the pattern tempo, which
+// scales the tempo map.
+func (p *Pattern) timeAt(tempo float32, step float64) time.Duration {
+	if len(p.tempoMap) == 0 {
+		if tempo <= 0 {
+			return 0
+		}
+		return time.Duration(math.Round(step * float64(time.Minute) / tempo64(tempo) / blockSize))
+	}
+	m := p.TempoMap()
+	bar := float64(p.BarSteps())
+	bars := math.Floor(step / bar)
+	d := bars*float64(m.Time(bar)) + float64(m.Time(step-bars*bar))
+	return time.Duration(math.Round(d * tempo64(p.tempo) / tempo64(tempo)))
+}
+
+// stepLength returns the length of the n-th step from the start of the first
+// bar at the tempo, see timeAt.
+func (p *Pattern) stepLength(tempo float32, n int) time.Duration {
+	if len(p.tempoMap) == 0 {
+		return stepDuration(tempo)
+	}
+	return p.timeAt(tempo, float64(n+1)) - p.timeAt(tempo, float64(n))
+}
+
+// The tempo map chunk stores the points after step 0 as
+// |Step (1 byte)|Ramp (1 byte)|Tempo (4 bytes float32 little endian)|.
+
+func decodeTempoMapChunk(data []byte, p *Pattern) error {
+	const pointSize = 6
+	if len(data)%pointSize != 0 {
+		return errors.New("invalid size")
+	}
+	var m TempoMap
+	for ; len(data) > 0; data = data[pointSize:] {
+		m = append(m, TempoPoint{
+			Step:  int(data[0]),
+			Ramp:  data[1] != 0,
+			Tempo: math.Float32frombits(binary.LittleEndian.Uint32(data[2:])),
+		})
+		if data[1] > 1 || m[0].Step == 0 {
+			return ErrInvalidTempoMap
+		}
+	}
+	return p.SetTempoMap(m)
+}
+
+func encodeTempoMapChunk(p *Pattern) []byte {
+	if len(p.tempoMap) == 0 {
+		return nil
+	}
+	var data []byte
+	for _, pt := range p.tempoMap {
+		ramp := byte(0)
+		if pt.Ramp {
+			ramp = 1
+		}
+		data = append(data, byte(pt.Step), ramp)
+		data = binary.LittleEndian.AppendUint32(data, math.Float32bits(pt.Tempo))
+	}
+	return data
+}
+
+func clearTempoMap(p *Pattern) {
+	p.tempoMap = nil
+}
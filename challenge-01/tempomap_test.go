@@ -0,0 +1,167 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestTempoMapQueries(t *testing.T) {
+	m := TempoMap{{Step: 0, Tempo: 120}, {Step: 4, Tempo: 120}, {Step: 12, Tempo: 180, Ramp: true}}
+	specs := map[float64]float32{-1: 120, 2: 120, 4: 120, 8: 150, 10: 165, 12: 180, 15.5: 180}
+	for step, exp := range specs {
+		if got := m.Tempo(step); got != exp {
+			t.Errorf("Expected tempo %v at step %v but got %v", exp, step, got)
+		}
+	}
+	// the integral matches the sum of many short steps
+	var exp float64
+	const n = 100000
+	for i := 0; i < n; i++ {
+		step := 16 * (float64(i) + 0.5) / n
+		exp += 16.0 / n * float64(time.Minute) / blockSize / float64(m.Tempo(step))
+	}
+	if got := float64(m.Time(16)); got < exp-float64(time.Microsecond) || got > exp+float64(time.Microsecond) {
+		t.Errorf("Expected bar time %v but got %v", time.Duration(exp), time.Duration(got))
+	}
+	if got := (TempoMap{{Step: 0, Tempo: 120}}).Time(16); got != 2*time.Second {
+		t.Errorf("Expected a bar of 2s but got %v", got)
+	}
+	if got := (TempoMap{}).Tempo(1); got != 0 {
+		t.Errorf("Expected 0 for an empty map but got %v", got)
+	}
+}
+
+func tempoMapPattern(t *testing.T) *Pattern {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 120 BPM doubled for the second half of the bar
+	p.tempo = 120
+	if err := p.SetTempoMap(TempoMap{{Step: 8, Tempo: 240}}); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestSetTempoMap(t *testing.T) {
+	p := tempoMapPattern(t)
+	invalid := []TempoMap{
+		{{Step: 16, Tempo: 120}},
+		{{Step: 4, Tempo: 120}, {Step: 4, Tempo: 130}},
+		{{Step: 4, Tempo: 1000}},
+		{{Step: 0, Tempo: 120, Ramp: true}},
+	}
+	for _, m := range invalid {
+		if err := p.SetTempoMap(m); err != ErrInvalidTempoMap {
+			t.Errorf("%v: Expected invalid tempo map but got %v", m, err)
+		}
+	}
+	m := p.TempoMap()
+	if len(m) != 2 || m[0] != (TempoPoint{0, 120, false}) || m[1] != (TempoPoint{8, 240, false}) {
+		t.Fatalf("Unexpected tempo map %v", m)
+	}
+	c := p.Clone()
+	if err := c.SetTempoMap(TempoMap{{Step: 0, Tempo: 100}, {Step: 8, Tempo: 240}}); err != nil {
+		t.Fatal(err)
+	}
+	if c.tempo != 100 || p.tempo != 120 {
+		t.Errorf("Expected a point at step 0 to set the tempo of the clone only")
+	}
+	if err := c.SetTempoMap(nil); err != nil || len(c.TempoMap()) != 1 {
+		t.Errorf("Expected nil to remove the tempo changes but got %v", c.TempoMap())
+	}
+}
+
+func TestTempoMapRoundTrip(t *testing.T) {
+	p := tempoMapPattern(t)
+	p.tempoMap = append(p.tempoMap, TempoPoint{Step: 15, Tempo: 200, Ramp: true})
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(p) {
+		t.Errorf("Expected the tempo map to round trip but got %v", Diff(p, decoded))
+	}
+	if got := decoded.Features(); len(got) != 1 || got[0] != FeatureTempoMap {
+		t.Errorf("Expected the tempo feature but got %v", got)
+	}
+	if d := Diff(p, tempoMapPattern(t)); len(d) != 1 || d[0].Field != "tempo map" {
+		t.Errorf("Expected a tempo map difference but got %v", d)
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Pattern
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !fromJSON.Equal(p) {
+		t.Errorf("Expected the tempo map to round trip JSON but got %s", data)
+	}
+}
+
+func TestTempoMapTiming(t *testing.T) {
+	p := tempoMapPattern(t)
+	// 8 steps of 125ms and 8 steps of 62.5ms
+	if got, exp := p.BarDuration(), 1500*time.Millisecond; got != exp {
+		t.Errorf("Expected bar duration %v but got %v", exp, got)
+	}
+	if got, exp := p.StepTime(20), 1500*time.Millisecond+500*time.Millisecond; got != exp {
+		t.Errorf("Expected step 20 at %v but got %v", exp, got)
+	}
+	s := &Song{Sections: []Section{{Pattern: p, Repeat: 2}, {Pattern: p, Repeat: 1, Tempo: 60}}}
+	if got, exp := s.Duration(), 2*1500*time.Millisecond+3*time.Second; got != exp {
+		t.Errorf("Expected song duration %v but got %v", exp, got)
+	}
+
+	pl := NewPlayer(p, nil)
+	var total time.Duration
+	for i := 0; i < stepsLength; i++ {
+		_, d, err := pl.advance(time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += d
+	}
+	if total != p.BarDuration() {
+		t.Errorf("Expected the player to play a bar in %v but got %v", p.BarDuration(), total)
+	}
+}
+
+func TestTempoMapMIDI(t *testing.T) {
+	p := tempoMapPattern(t)
+	var buf bytes.Buffer
+	if err := WriteMIDI(&buf, p, 2); err != nil {
+		t.Fatal(err)
+	}
+	slow := []byte{0xff, 0x51, 3, 0x07, 0xa1, 0x20} // 500000us
+	fast := []byte{0xff, 0x51, 3, 0x03, 0xd0, 0x90} // 250000us
+	if n := bytes.Count(buf.Bytes(), slow); n != 2 {
+		t.Errorf("Expected 2 events at 120 BPM but got %d", n)
+	}
+	if n := bytes.Count(buf.Bytes(), fast); n != 2 {
+		t.Errorf("Expected 2 events at 240 BPM but got %d", n)
+	}
+
+	// a ramp changes the tempo every step
+	if err := p.SetTempoMap(TempoMap{{Step: 8, Tempo: 120}, {Step: 12, Tempo: 180, Ramp: true}}); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := WriteMIDI(&buf, p, 1); err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(buf.Bytes(), []byte{0xff, 0x51, 3}); n != 6 {
+		t.Errorf("Expected 6 tempo events but got %d", n)
+	}
+}
@@ -0,0 +1,75 @@
+package drum
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Tempos are written to text formats like the printout, grids, CSV and YAML
+// with FormatTempo and read back with ParseTempo, so that text round trips
+// never change the float32 value of a tempo.
+
+// FormatTempo returns the tempo in BPM as the shortest decimal that parses
+// back to the same float32, like "120" or "98.4". It never uses an
+// exponent and always a point as decimal separator, whatever the locale.
+func FormatTempo(bpm float32) string {
+	return string(appendTempo(nil, bpm))
+}
+
+// appendTempo appends the tempo formatted like by FormatTempo to b.
+func appendTempo(b []byte, bpm float32) []byte {
+	return strconv.AppendFloat(b, float64(bpm), 'f', -1, 32)
+}
+
+// ParseTempo parses a tempo in BPM as written by FormatTempo or typed by
+// users: "120", "120.0" and "120,0" with a decimal comma are the same
+// tempo, surrounding space and a "BPM" suffix are ignored. The value is
+// rounded to the nearest float32. Range checks are left to the callers,
+// infinite or NaN tempos are rejected.
+func ParseTempo(s string) (float32, error) {
+	v := strings.TrimSpace(s)
+	if len(v) >= 3 && strings.EqualFold(v[len(v)-3:], "bpm") {
+		v = strings.TrimSpace(v[:len(v)-3])
+	}
+	if strings.Count(v, ",") == 1 && !strings.Contains(v, ".") {
+		v = strings.Replace(v, ",", ".", 1)
+	}
+	f, err := strconv.ParseFloat(v, 32)
+	if err != nil || v == "" || strings.ContainsAny(v, "xXpPnNiI_") {
+		return 0, fmt.Errorf("%w %q", ErrInvalidTempo, s)
+	}
+	return float32(f), nil
+}
+
+// jsonTempo is a tempo in JSON and YAML representations. It is written as
+// number by FormatTempo and read from numbers or strings by ParseTempo, so
+// that YAML edited by hand may use a decimal comma.
+type jsonTempo float32
+
+func (t jsonTempo) MarshalJSON() ([]byte, error) {
+	if math.IsNaN(float64(t)) || math.IsInf(float64(t), 0) {
+		return nil, fmt.Errorf("%w %v", ErrInvalidTempo, float32(t))
+	}
+	return appendTempo(nil, float32(t)), nil
+}
+
+func (t *jsonTempo) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	bpm, err := ParseTempo(s)
+	if err != nil {
+		return err
+	}
+	*t = jsonTempo(bpm)
+	return nil
+}
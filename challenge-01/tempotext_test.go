@@ -0,0 +1,91 @@
+package drum
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestFormatTempo(t *testing.T) {
+	specs := map[float32]string{
+		120:                        "120",
+		98.4:                       "98.4",
+		20.25:                      "20.25",
+		999:                        "999",
+		0.000001:                   "0.000001",
+		math.Nextafter32(120, 121): "120.00001",
+	}
+	for bpm, exp := range specs {
+		if got := FormatTempo(bpm); got != exp {
+			t.Errorf("Expected %q for %v but got %q", exp, bpm, got)
+		}
+	}
+}
+
+func TestParseTempo(t *testing.T) {
+	specs := map[string]float32{
+		"120":       120,
+		"120.0":     120,
+		"120,0":     120,
+		" 98,4 ":    98.4,
+		"98.4 BPM":  98.4,
+		"98.4bpm":   98.4,
+		"120.00001": math.Nextafter32(120, 121),
+	}
+	for s, exp := range specs {
+		got, err := ParseTempo(s)
+		if err != nil || got != exp {
+			t.Errorf("Expected %v for %q but got %v, %v", exp, s, got, err)
+		}
+	}
+	for _, s := range []string{"", "BPM", "1,234.5", "1,2,3", "NaN", "inf", "0x1p7", "1_20", "fast"} {
+		if _, err := ParseTempo(s); !errors.Is(err, ErrInvalidTempo) {
+			t.Errorf("Expected ErrInvalidTempo for %q but got %v", s, err)
+		}
+	}
+}
+
+// TestTempoTextRoundTrip writes random float32 tempos to the text formats
+// and expects to read back the same bits.
+func TestTempoTextRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		bpm := math.Float32frombits(math.Float32bits(MinTempo) + uint32(r.Intn(int(math.Float32bits(MaxTempo)-math.Float32bits(MinTempo)))))
+		kick, _ := NewTrack(0, "kick", Steps{true})
+		p, err := NewPattern("0.808", bpm, kick)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var printout Pattern
+		if err := printout.UnmarshalText([]byte(p.String())); err != nil || printout.tempo != bpm {
+			t.Fatalf("Expected %v from the printout but got %v, %v", bpm, printout.tempo, err)
+		}
+		var buf bytes.Buffer
+		p.ToCSV(&buf)
+		if got, err := FromCSV(&buf); err != nil || got.tempo != bpm {
+			t.Fatalf("Expected %v from CSV but got %v", bpm, err)
+		}
+		buf.Reset()
+		p.ToYAML(&buf)
+		if got, err := FromYAML(&buf); err != nil || got.tempo != bpm {
+			t.Fatalf("Expected %v from YAML but got %v", bpm, err)
+		}
+		grid := "tempo: " + strings.Replace(FormatTempo(bpm), ".", ",", 1) + "\nkick: x---\n"
+		if got, err := ParseGrid(strings.NewReader(grid)); err != nil || got.tempo != bpm {
+			t.Fatalf("Expected %v from the grid %q but got %v", bpm, grid, err)
+		}
+	}
+}
+
+func TestYAMLDecimalCommaTempo(t *testing.T) {
+	p, err := FromYAML(strings.NewReader("version: \"0.808\"\ntempo: \"98,4\"\ntracks: []\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.tempo != 98.4 {
+		t.Errorf("Expected 98.4 but got %v", p.tempo)
+	}
+}
@@ -0,0 +1,175 @@
+package drum
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ANSI escape sequences used by the TerminalRenderer.
+const (
+	ansiReset     = "\x1b[0m"
+	ansiBold      = "\x1b[1m"
+	ansiDim       = "\x1b[2m"
+	ansiReverse   = "\x1b[7m"
+	ansiGreen     = "\x1b[32m"
+	ansiClearLine = "\x1b[2K"
+	ansiClearDown = "\x1b[J"
+)
+
+// TerminalRenderer prints patterns with ANSI colors and redraws them in
+// place, for example to show a moving playhead while a Player is running.
+// Enabled steps and the track name are in the display color of the track,
+// enabled steps of tracks without color green, the first step of the bar is
+// bold and muted tracks are dimmed. The symbol of the display icon is shown
+// in front of the name. When the output is not a terminal the plain printout
+// is written without any escape sequences and the playhead is marked on an
+// extra line.
+type TerminalRenderer struct {
+	mu    sync.Mutex
+	w     io.Writer
+	color bool
+	lines int // lines of the last rendering to redraw
+}
+
+// NewTerminalRenderer returns a renderer writing to w. Colors are used when
+// w is a terminal and neither the NO_COLOR environment variable is set nor
+// TERM is "dumb".
+func NewTerminalRenderer(w io.Writer) *TerminalRenderer {
+	return &TerminalRenderer{w: w, color: isTerminal(w)}
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// SetColor turns colors and redrawing in place on or off.
+func (r *TerminalRenderer) SetColor(on bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.color = on
+}
+
+// Render prints the pattern with the playhead at the given step, or without
+// playhead for a negative step. With colors the previous rendering is
+// replaced.
+func (r *TerminalRenderer) Render(p *Pattern, playhead int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var buf bytes.Buffer
+	if r.color && r.lines > 0 {
+		fmt.Fprintf(&buf, "\x1b[%dA", r.lines)
+	}
+	lines := 0
+	line := func(s string) {
+		if r.color {
+			buf.WriteString(ansiClearLine)
+		}
+		buf.WriteString(s)
+		buf.WriteByte('\n')
+		lines++
+	}
+	line(fmt.Sprintf("Saved with HW Version: %s", p.version))
+	line("Tempo: " + FormatTempo(p.tempo))
+	for _, t := range p.tracks {
+		line(r.trackLine(t, playhead))
+	}
+	if !r.color && playhead >= 0 && playhead < stepsLength {
+		// the printout has a separator in front of every block
+		line("\t" + strings.Repeat(" ", playhead+playhead/blockSize+1) + "^")
+	}
+	if r.color {
+		buf.WriteString(ansiClearDown)
+	}
+	r.lines = lines
+	_, err := buf.WriteTo(r.w)
+	return err
+}
+
+// ansiColor returns the escape sequence of a "#rrggbb" color as 24 bit
+// foreground color, "" for an empty color.
+func ansiColor(c string) string {
+	v, err := strconv.ParseUint(strings.TrimPrefix(c, "#"), 16, 32)
+	if err != nil || validateColor(c) != nil {
+		return ""
+	}
+	return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", v>>16, v>>8&0xff, v&0xff)
+}
+
+func (r *TerminalRenderer) trackLine(t *Track, playhead int) string {
+	var b strings.Builder
+	name := t.name
+	if icon := t.display.Symbol(); icon != 0 {
+		name = string(icon) + " " + name
+	}
+	color := ""
+	if r.color {
+		color = ansiColor(t.display.Color)
+	}
+	if color != "" {
+		name = color + name + ansiReset
+	}
+	fmt.Fprintf(&b, "(%v) %v", t.id, name)
+	switch {
+	case t.muted:
+		b.WriteString(" [muted]")
+	case t.solo:
+		b.WriteString(" [solo]")
+	}
+	b.WriteRune('\t')
+	for i, enabled := range t.steps {
+		if i%blockSize == 0 {
+			b.WriteRune(blockSeparator)
+		}
+		symbol := symbolStepDisabled
+		if enabled {
+			symbol = symbolStepEnabled
+		}
+		if !r.color {
+			b.WriteRune(symbol)
+			continue
+		}
+		if t.muted {
+			b.WriteString(ansiDim)
+		}
+		switch {
+		case enabled && color != "":
+			b.WriteString(color)
+		case enabled:
+			b.WriteString(ansiGreen)
+		}
+		if i == 0 {
+			b.WriteString(ansiBold)
+		}
+		if i == playhead {
+			b.WriteString(ansiReverse)
+		}
+		b.WriteRune(symbol)
+		b.WriteString(ansiReset)
+	}
+	b.WriteRune(blockSeparator)
+	return b.String()
+}
+
+// Handler returns a Player handler that renders the pattern with the
+// playhead at every step. Without colors the pattern is only printed at the
+// start of every bar to keep logs readable.
+func (r *TerminalRenderer) Handler(p *Pattern) func(StepEvent) {
+	return func(ev StepEvent) {
+		r.mu.Lock()
+		color := r.color
+		r.mu.Unlock()
+		if color || ev.Step == 0 {
+			r.Render(p, ev.Step)
+		}
+	}
+}
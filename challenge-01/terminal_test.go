@@ -0,0 +1,75 @@
+package drum
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestTerminalRenderer(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_5.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	r := NewTerminalRenderer(&buf)
+	if err := r.Render(p, 5); err != nil {
+		t.Fatal(err)
+	}
+	exp := p.String() + "\t       ^\n"
+	if got := buf.String(); got != exp {
+		t.Errorf("Expected '%v' but got '%v'", exp, got)
+	}
+
+	buf.Reset()
+	r.SetColor(true)
+	r.Render(p, 0)
+	r.Render(p, 1)
+	out := buf.String()
+	if !strings.Contains(out, "\x1b[4A") {
+		t.Errorf("expected redraw of 4 lines in %q", out)
+	}
+	if !strings.Contains(out, ansiGreen+ansiBold+ansiReverse+"x"+ansiReset) {
+		t.Errorf("expected highlighted playhead in %q", out)
+	}
+}
+
+func TestTerminalRendererDisplay(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.tracks[0].SetDisplay(Display{Color: "#ff8800", Icon: "kick"})
+	p.tracks[1].SetDisplay(Display{Icon: "tambourine"})
+	var buf bytes.Buffer
+	r := NewTerminalRenderer(&buf)
+	if err := r.Render(p, -1); err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range []string{"\n(0) ● kick\t|x---|", "\n(1) T snare\t|----|"} {
+		if !strings.Contains(buf.String(), exp) {
+			t.Errorf("Expected %q in %q", exp, buf.String())
+		}
+	}
+
+	buf.Reset()
+	r.SetColor(true)
+	r.Render(p, -1)
+	orange := "\x1b[38;2;255;136;0m"
+	if exp := "(0) " + orange + "● kick" + ansiReset + "\t|" + orange + ansiBold + "x" + ansiReset; !strings.Contains(buf.String(), exp) {
+		t.Errorf("Expected the kick in its color %q in %q", exp, buf.String())
+	}
+	if !strings.Contains(buf.String(), "(2) clap\t|"+ansiBold+strings.Repeat("-"+ansiReset, 4)+"|"+ansiGreen+"x") {
+		t.Errorf("Expected green steps of tracks without color in %q", buf.String())
+	}
+}
+
+func TestDisplaySymbol(t *testing.T) {
+	specs := map[string]rune{"": 0, "kick": '●', "Hat": '△', "tambourine": 'T', "émoji": 'É'}
+	for icon, exp := range specs {
+		if got := (Display{Icon: icon}).Symbol(); got != exp {
+			t.Errorf("Expected %q for %q but got %q", exp, icon, got)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Package testutil provides helpers for tests of code working with drum
+// patterns: golden files, comparisons with readable step-grid diffs and
+// fixtures written as .splice files from pattern literals.
+//
+//	want := testutil.Fixture{Tempo: 120, Tracks: []testutil.FixtureTrack{
+//		{ID: 0, Name: "kick", Steps: "x---|x---|x---|x---"},
+//	}}.Pattern(t)
+//	testutil.AssertPatternsEqual(t, want, testutil.LoadGolden(t, "testdata/kick.splice"))
+//
+// The helpers report failures through the testing.TB passed to them.
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	drum "github.com/alpe/go-challenge/challenge-01"
+)
+
+// DefaultVersion is the version of fixtures without one.
+const DefaultVersion = "fixture"
+
+// LoadGolden decodes the .splice file at path and fails the test when it
+// cannot be read.
+func LoadGolden(t testing.TB, path string) *drum.Pattern {
+	t.Helper()
+	p, err := drum.DecodeFile(path)
+	if err != nil {
+		t.Fatalf("load golden file: %v", err)
+	}
+	return p
+}
+
+// AssertPatternsEqual fails the test unless want and got are equal as
+// defined by drum.Pattern.Equal. The failure lists the differences of
+// drum.Diff followed by both step grids side by side with the changed
+// steps marked.
+func AssertPatternsEqual(t testing.TB, want, got *drum.Pattern) {
+	t.Helper()
+	if want == nil || got == nil {
+		if want != got {
+			t.Errorf("Expected pattern %v but got %v", want, got)
+		}
+		return
+	}
+	if want.Equal(got) {
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteString("patterns differ (want, got):\n")
+	for _, d := range drum.Diff(want, got) {
+		fmt.Fprintf(&buf, "  %v\n", d)
+	}
+	buf.WriteByte('\n')
+	drum.FormatSideBySide(&buf, want, got) // writing to a buffer does not fail
+	t.Error(buf.String())
+}
+
+// Fixture is a pattern literal for tests. The steps of the tracks are
+// written like in the printout.
+type Fixture struct {
+	Version string // DefaultVersion when empty
+	Tempo   float32
+	Tracks  []FixtureTrack
+}
+
+// FixtureTrack is a track of a Fixture. Steps are "x" or "X" for enabled and
+// "-" or "." for disabled, spaces and "|" may group them. Less than 16 steps
+// are padded with disabled steps.
+type FixtureTrack struct {
+	ID    uint32
+	Name  string
+	Steps string
+}
+
+// Pattern returns the pattern of the fixture and fails the test when it is
+// invalid.
+func (f Fixture) Pattern(t testing.TB) *drum.Pattern {
+	t.Helper()
+	p, err := f.build()
+	if err != nil {
+		t.Fatalf("fixture: %v", err)
+	}
+	return p
+}
+
+// Bytes returns the fixture encoded in the .splice format.
+func (f Fixture) Bytes(t testing.TB) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := drum.Encode(&buf, f.Pattern(t)); err != nil {
+		t.Fatalf("fixture: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// Write writes the fixture as .splice file to path.
+func (f Fixture) Write(t testing.TB, path string) {
+	t.Helper()
+	if err := drum.EncodeFile(path, f.Pattern(t)); err != nil {
+		t.Fatalf("fixture: %v", err)
+	}
+}
+
+func (f Fixture) build() (*drum.Pattern, error) {
+	version := f.Version
+	if version == "" {
+		version = DefaultVersion
+	}
+	tracks := make([]*drum.Track, len(f.Tracks))
+	for i, ft := range f.Tracks {
+		steps, err := parseSteps(ft.Steps)
+		if err != nil {
+			return nil, fmt.Errorf("track %d: %v", ft.ID, err)
+		}
+		if tracks[i], err = drum.NewTrack(ft.ID, ft.Name, steps); err != nil {
+			return nil, fmt.Errorf("track %d: %v", ft.ID, err)
+		}
+	}
+	return drum.NewPattern(version, f.Tempo, tracks...)
+}
+
+func parseSteps(symbols string) (drum.Steps, error) {
+	var steps drum.Steps
+	n := 0
+	for _, r := range strings.NewReplacer(" ", "", "\t", "", "|", "").Replace(symbols) {
+		if n == len(steps) {
+			return steps, fmt.Errorf("more than %d steps in %q", len(steps), symbols)
+		}
+		switch r {
+		case 'x', 'X':
+			steps[n] = true
+		case '-', '.':
+		default:
+			return steps, fmt.Errorf("invalid step %q in %q", r, symbols)
+		}
+		n++
+	}
+	return steps, nil
+}
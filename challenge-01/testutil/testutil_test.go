@@ -0,0 +1,106 @@
+package testutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// recorder records the failures of the helpers instead of failing the test.
+type recorder struct {
+	testing.TB
+	failed bool
+	msg    string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Error(args ...interface{}) {
+	r.failed, r.msg = true, fmt.Sprint(args...)
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failed, r.msg = true, fmt.Sprintf(format, args...)
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// record runs fn in a goroutine so that fatal failures end it.
+func record(fn func(t testing.TB)) *recorder {
+	r := &recorder{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(r)
+	}()
+	<-done
+	return r
+}
+
+var kick = Fixture{Version: "0.808-alpha", Tempo: 120, Tracks: []FixtureTrack{
+	{ID: 0, Name: "kick", Steps: "x---|x---|x---|x---"},
+	{ID: 1, Name: "snare", Steps: "----x-------x"},
+}}
+
+func TestFixture(t *testing.T) {
+	dir, err := ioutil.TempDir("", "testutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kick.splice")
+	kick.Write(t, path)
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(kick.Bytes(t)) {
+		t.Error("Expected the written file to hold the bytes of the fixture")
+	}
+	p := LoadGolden(t, path)
+	exp := "Saved with HW Version: 0.808-alpha\nTempo: 120\n(0) kick\t|x---|x---|x---|x---|\n(1) snare\t|----|x---|----|x---|\n"
+	if p.String() != exp {
+		t.Errorf("Expected\n%s\nbut got\n%s", exp, p)
+	}
+	AssertPatternsEqual(t, kick.Pattern(t), p)
+
+	if r := record(func(t testing.TB) { LoadGolden(t, filepath.Join(dir, "missing.splice")) }); !strings.HasPrefix(r.msg, "load golden file: ") {
+		t.Errorf("Expected the test to fail but got %q", r.msg)
+	}
+	for _, steps := range []string{"x-o-", "x---x---x---x---x"} {
+		f := Fixture{Tempo: 120, Tracks: []FixtureTrack{{ID: 3, Name: "hh", Steps: steps}}}
+		if r := record(func(t testing.TB) { f.Pattern(t) }); !strings.HasPrefix(r.msg, "fixture: track 3: ") {
+			t.Errorf("%q: expected the test to fail but got %q", steps, r.msg)
+		}
+	}
+	if r := record(func(t testing.TB) { Fixture{}.Pattern(t) }); !strings.HasPrefix(r.msg, "fixture: ") {
+		t.Errorf("Expected the fixture without tempo to fail but got %q", r.msg)
+	}
+}
+
+func TestAssertPatternsEqual(t *testing.T) {
+	want := kick.Pattern(t)
+	got := want.Clone()
+	if err := got.Tracks()[1].SetStep(14, true); err != nil {
+		t.Fatal(err)
+	}
+	r := record(func(t testing.TB) { AssertPatternsEqual(t, want, got) })
+	for _, exp := range []string{"patterns differ", "track (1) snare steps: |----|x---|----|x---| -> |----|x---|----|x-x-|", "^"} {
+		if !strings.Contains(r.msg, exp) {
+			t.Errorf("Expected the failure to contain %q but got\n%s", exp, r.msg)
+		}
+	}
+	if r := record(func(t testing.TB) { AssertPatternsEqual(t, want, want.Clone()) }); r.failed {
+		t.Errorf("Expected equal patterns but got %s", r.msg)
+	}
+	if r := record(func(t testing.TB) { AssertPatternsEqual(t, want, nil) }); !r.failed {
+		t.Error("Expected a missing pattern to fail")
+	}
+}
@@ -0,0 +1,228 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MarshalText implements encoding.TextMarshaler with the printout format of
+// String. Only the data shown in the printout is kept: version, tempo,
+// metadata and the tracks with their mute and solo states. Extensions like
+// swing, velocities or display metadata are lost.
+func (p *Pattern) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler for the printout format
+// of String. The values are validated like by NewPattern and NewTrack.
+func (p *Pattern) UnmarshalText(text []byte) error {
+	lines := strings.Split(strings.TrimRight(string(text), "\n"), "\n")
+	if len(lines) < 2 {
+		return errors.New("parse printout: missing header")
+	}
+	version, ok := cutPrefix(lines[0], "Saved with HW Version: ")
+	if !ok {
+		return fmt.Errorf("parse printout line 1: expected version but got %q", lines[0])
+	}
+	v, ok := cutPrefix(lines[1], "Tempo: ")
+	tempo, err := ParseTempo(v)
+	if !ok || err != nil {
+		return fmt.Errorf("parse printout line 2: expected tempo but got %q", lines[1])
+	}
+	var m Metadata
+	var ts TimeSignature
+	var order []int
+	n := 2
+	for ; n < len(lines) && !strings.HasPrefix(lines[n], "("); n++ {
+		if v, ok := cutPrefix(lines[n], "Time signature: "); ok {
+			if ts, err = ParseTimeSignature(v); err != nil {
+				return fmt.Errorf("parse printout line %d: %v", n+1, err)
+			}
+			continue
+		}
+		if v, ok := cutPrefix(lines[n], "Bar order: "); ok {
+			if order, err = ParseBarOrder(v); err != nil {
+				return fmt.Errorf("parse printout line %d: %v", n+1, err)
+			}
+			continue
+		}
+		if err := parseMetadataLine(&m, lines[n]); err != nil {
+			return fmt.Errorf("parse printout line %d: %v", n+1, err)
+		}
+	}
+	var tracks []*Track
+	var bars []int // of every track
+	for ; n < len(lines); n++ {
+		if v, ok := cutPrefix(lines[n], "\t"); ok && len(tracks) > 0 {
+			if err := parseBarLine(tracks[len(tracks)-1], bars[len(bars)-1], v, ts); err != nil {
+				return fmt.Errorf("parse printout line %d: %v", n+1, err)
+			}
+			bars[len(bars)-1]++
+			continue
+		}
+		t, err := parseTrackLine(lines[n], ts)
+		if err != nil {
+			return fmt.Errorf("parse printout line %d: %v", n+1, err)
+		}
+		tracks = append(tracks, t)
+		bars = append(bars, 1)
+	}
+	np, err := NewPattern(version, float32(tempo), tracks...)
+	if err != nil {
+		return fmt.Errorf("parse printout: %v", err)
+	}
+	for i, b := range bars {
+		if b != bars[0] {
+			return fmt.Errorf("parse printout: %w: track %d has %d bars, expected %d", ErrInvalidBar, tracks[i].id, b, bars[0])
+		}
+		np.extraBars = uint8(b - 1)
+	}
+	if err := np.SetBarOrder(order); err != nil {
+		return fmt.Errorf("parse printout: %v", err)
+	}
+	if err := np.SetMetadata(m); err != nil {
+		return fmt.Errorf("parse printout: %v", err)
+	}
+	np.SetTimeSignature(ts)
+	*p = *np
+	return nil
+}
+
+// parseMetadataLine sets the metadata field of a header line written by
+// appendMetadata.
+func parseMetadataLine(m *Metadata, line string) error {
+	n := strings.Index(line, ": ")
+	if n < 0 {
+		return fmt.Errorf("unexpected %q", line)
+	}
+	value := line[n+2:]
+	switch line[:n] {
+	case "Title":
+		m.Title = value
+	case "Author":
+		m.Author = value
+	case "Tags":
+		m.Tags = strings.Split(value, ", ")
+	case "Created":
+		created, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return fmt.Errorf("invalid creation date %q", value)
+		}
+		m.Created = created
+	default:
+		return fmt.Errorf("unknown header %q", line[:n])
+	}
+	return nil
+}
+
+// parseTrackLine parses "(id) name[ [muted]|[solo]]\t|x---|...|" with the
+// steps grouped like by Formatter for the time signature. Tracks with more
+// or less steps than the bar get their length set, see Track.SetLength. The
+// steps of patterns with several bars are labelled "A|x---|...|".
+func parseTrackLine(line string, ts TimeSignature) (*Track, error) {
+	tab := strings.LastIndexByte(line, '\t')
+	end := strings.IndexByte(line, ')')
+	if tab < 0 || end < 0 || end > tab || line[0] != '(' {
+		return nil, fmt.Errorf("invalid track %q", line)
+	}
+	id, err := strconv.ParseUint(line[1:end], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid track id %q", line[1:end])
+	}
+	name, ok := cutPrefix(line[end+1:tab], " ")
+	if !ok {
+		return nil, fmt.Errorf("invalid track %q", line)
+	}
+	var muted, solo bool
+	if s, ok := cutSuffix(name, " [muted]"); ok {
+		name, muted = s, true
+	} else if s, ok := cutSuffix(name, " [solo]"); ok {
+		name, solo = s, true
+	}
+	symbols := line[tab+1:]
+	if s, ok := cutPrefix(symbols, barName(0)); ok {
+		symbols = s
+	}
+	steps, n, err := parseStepBlocks(symbols, ts)
+	if err != nil {
+		return nil, err
+	}
+	t, err := NewTrack(uint32(id), name, steps)
+	if err != nil {
+		return nil, err
+	}
+	t.muted, t.solo = muted, solo
+	if n != ts.barSteps() {
+		t.length = uint8(n)
+	}
+	return t, nil
+}
+
+// parseBarLine sets the steps of the bar of the track from the bar line
+// "B|x---|...|" following the track line.
+func parseBarLine(t *Track, bar int, line string, ts TimeSignature) error {
+	if bar >= MaxBars {
+		return fmt.Errorf("%w: more than %d bars", ErrInvalidBar, MaxBars)
+	}
+	symbols, ok := cutPrefix(line, barName(bar))
+	if !ok {
+		return fmt.Errorf("expected bar %s but got %q", barName(bar), line)
+	}
+	steps, n, err := parseStepBlocks(symbols, ts)
+	if err != nil {
+		return err
+	}
+	if length := int(t.length); n != length && (length != 0 || n != ts.barSteps()) {
+		return fmt.Errorf("expected the steps of bar A but got %q", symbols)
+	}
+	*t.barSteps(bar) = steps
+	return nil
+}
+
+// parseStepBlocks parses "|x---|x-|" and returns the steps and their number.
+func parseStepBlocks(symbols string, ts TimeSignature) (Steps, int, error) {
+	var steps Steps
+	// "|x---|x-|" splits into "", "x---", "x-" and ""
+	blocks := strings.Split(symbols, string(blockSeparator))
+	if len(blocks) < 3 || blocks[0] != "" || blocks[len(blocks)-1] != "" {
+		return steps, 0, fmt.Errorf("invalid steps %q", symbols)
+	}
+	blocks = blocks[1 : len(blocks)-1]
+	size := ts.groupSteps()
+	n := 0
+	for i, b := range blocks {
+		// only the last block may be shorter
+		if b == "" || len(b) > size || len(b) < size && i < len(blocks)-1 {
+			return steps, 0, fmt.Errorf("invalid steps %q", symbols)
+		}
+		for _, r := range b {
+			switch {
+			case n == stepsLength:
+				return steps, 0, fmt.Errorf("invalid steps %q", symbols)
+			case r == symbolStepEnabled:
+				steps[n] = true
+			case r != symbolStepDisabled:
+				return steps, 0, fmt.Errorf("invalid steps %q", symbols)
+			}
+			n++
+		}
+	}
+	return steps, n, nil
+}
+
+func cutPrefix(s, prefix string) (string, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+func cutSuffix(s, suffix string) (string, bool) {
+	if !strings.HasSuffix(s, suffix) {
+		return s, false
+	}
+	return s[:len(s)-len(suffix)], true
+}
@@ -0,0 +1,73 @@
+package drum
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTextMarshaler(t *testing.T) {
+	for i := 1; i <= 5; i++ {
+		name := fmt.Sprintf("pattern_%d.splice", i)
+		p, err := DecodeFile(path.Join("fixtures", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := p.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(text) != p.String() {
+			t.Errorf("%s: Expected the printout but got '%s'", name, text)
+		}
+		var got Pattern
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !got.Equal(p) || got.Version() != p.Version() || got.Tempo() != p.Tempo() {
+			t.Errorf("%s: Expected '%v' but got '%v'", name, p, got)
+		}
+	}
+
+	p, _ := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	p.SetMetadata(Metadata{Title: "Four", Author: "alpe", Tags: []string{"a", "b c"}, Created: time.Date(2015, 3, 1, 0, 0, 0, 0, time.UTC)})
+	p.tracks[0].Mute()
+	p.tracks[1].Solo()
+	p.tracks[2].name = "clap (909)\tx"
+	text, _ := p.MarshalText()
+	var got Pattern
+	if err := got.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != p.String() || fmt.Sprint(got.Metadata()) != fmt.Sprint(p.Metadata()) {
+		t.Errorf("Expected '%v' but got '%v'", p, got)
+	}
+}
+
+func TestInvalidText(t *testing.T) {
+	const header = "Saved with HW Version: 0.808\nTempo: 120\n"
+	specs := map[string]string{
+		"empty":          "",
+		"no version":     "Version: 0.808\nTempo: 120\n",
+		"invalid tempo":  "Saved with HW Version: 0.808\nTempo: fast\n",
+		"zero tempo":     "Saved with HW Version: 0.808\nTempo: 0\n",
+		"unknown header": header + "Genre: house\n",
+		"invalid date":   header + "Created: yesterday\n",
+		"missing id":     header + "kick\t|x---|----|----|----|\n",
+		"invalid id":     header + "(k) kick\t|x---|----|----|----|\n",
+		"no steps":       header + "(0) kick\t||\n",
+		"long steps":     header + "(0) kick\t|x---|----|----|----|-|\n",
+		"invalid steps":  header + "(0) kick\t|x---|----|----|---o|\n",
+		"separator":      header + "(0) kick\t|x----|---|----|----|\n",
+		"missing tab":    header + "(0) kick |x---|----|----|----|\n",
+	}
+	for msg, text := range specs {
+		if err := new(Pattern).UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("%s: Expected error", msg)
+		} else if !strings.HasPrefix(err.Error(), "parse printout") {
+			t.Errorf("%s: unexpected error %v", msg, err)
+		}
+	}
+}
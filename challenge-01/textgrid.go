@@ -0,0 +1,99 @@
+package drum
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseGrid reads a pattern from a plain text grid meant to be written by
+// hand, unlike the printout parsed by UnmarshalText:
+//
+//	# a basic rock beat
+//	tempo: 98
+//	version: 0.808-alpha
+//	kick:  x---x---x---x---
+//	snare: ----x-------x---
+//	hh:    x-x-x-x-x-x-x-x-
+//
+// Every "name: steps" line is a track with the ids counted from 0. Steps
+// are "x" or "X" for enabled and "-" or "." for disabled, spaces and "|"
+// may group them. Tracks with less than 16 steps loop on their own, see
+// Track.SetLength. The optional tempo and version headers default to 120
+// BPM and "grid". Empty lines and lines starting with "#" are skipped.
+func ParseGrid(r io.Reader) (*Pattern, error) {
+	version := "grid"
+	tempo := float32(120)
+	var tracks []*Track
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ':')
+		if i < 0 {
+			return nil, fmt.Errorf("parse grid line %d: expected name: steps but got %q", n, line)
+		}
+		key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		switch strings.ToLower(key) {
+		case "tempo":
+			v, err := ParseTempo(value)
+			if err != nil {
+				return nil, fmt.Errorf("parse grid line %d: invalid tempo %q", n, value)
+			}
+			tempo = v
+		case "version":
+			version = value
+		default:
+			t, err := parseGridTrack(uint32(len(tracks)), key, value)
+			if err != nil {
+				return nil, fmt.Errorf("parse grid line %d: %v", n, err)
+			}
+			tracks = append(tracks, t)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("parse grid: %v", err)
+	}
+	p, err := NewPattern(version, tempo, tracks...)
+	if err != nil {
+		return nil, fmt.Errorf("parse grid: %v", err)
+	}
+	return p, nil
+}
+
+// parseGridTrack returns the track of a grid line.
+func parseGridTrack(id uint32, name, symbols string) (*Track, error) {
+	if name == "" {
+		return nil, fmt.Errorf("missing track name of %q", symbols)
+	}
+	var steps Steps
+	n := 0
+	for _, r := range symbols {
+		switch r {
+		case ' ', '\t', blockSeparator:
+			continue
+		case symbolStepEnabled, 'X':
+			if n < stepsLength {
+				steps[n] = true
+			}
+		case symbolStepDisabled, '.':
+		default:
+			return nil, fmt.Errorf("invalid step %q in %q", r, symbols)
+		}
+		n++
+	}
+	if n == 0 || n > stepsLength {
+		return nil, fmt.Errorf("expected 1 to %d steps but got %d", stepsLength, n)
+	}
+	t, err := NewTrack(id, name, steps)
+	if err != nil {
+		return nil, err
+	}
+	if n < stepsLength {
+		t.length = uint8(n)
+	}
+	return t, nil
+}
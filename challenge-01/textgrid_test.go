@@ -0,0 +1,62 @@
+package drum
+
+import (
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestParseGrid(t *testing.T) {
+	p, err := ParseGrid(strings.NewReader(`# pattern 1
+Version: 0.808-alpha
+tempo: 120
+
+kick:  x---|x---|x---|x---
+snare: ----|x---|----|x---
+clap:  ---- x-x- ---- ----
+shaker: x..X.
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Version() != "0.808-alpha" || p.Tempo() != 120 || len(p.tracks) != 4 {
+		t.Fatalf("Unexpected pattern\n%v", p)
+	}
+	for i, tr := range p.tracks[:3] {
+		if e := exp.tracks[i]; tr.id != uint32(i) || tr.name != e.name || tr.steps != e.steps || tr.length != 0 {
+			t.Errorf("Expected track %v but got %v", e, tr)
+		}
+	}
+	if tr := p.tracks[3]; tr.steps != (Steps{true, 3: true}) || tr.length != 5 {
+		t.Errorf("Expected a looping track of 5 steps but got %v of %d", tr.steps, tr.length)
+	}
+
+	p, err = ParseGrid(strings.NewReader("kick: x---x---x---x---\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Version() != "grid" || p.Tempo() != 120 {
+		t.Errorf("Expected the default headers but got %q at %v BPM", p.Version(), p.Tempo())
+	}
+}
+
+func TestParseGridErrors(t *testing.T) {
+	for text, exp := range map[string]string{
+		"kick x---":                           "parse grid line 1: expected name: steps",
+		"\nkick: x-o-":                        "parse grid line 2: invalid step 'o'",
+		"kick: x---x---x---x---x":             "parse grid line 1: expected 1 to 16 steps but got 17",
+		"kick:":                               "parse grid line 1: expected 1 to 16 steps but got 0",
+		": x---":                              "parse grid line 1: missing track name",
+		"tempo: fast":                         "parse grid line 1: invalid tempo",
+		"tempo: 0\nkick: x---":                "parse grid: invalid tempo",
+		"version: " + strings.Repeat("v", 33): "parse grid: ",
+	} {
+		if _, err := ParseGrid(strings.NewReader(text)); err == nil || !strings.HasPrefix(err.Error(), exp) {
+			t.Errorf("%q: expected %s but got %v", text, exp, err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package drum
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Theme sets how a Formatter draws the steps of tracks.
+type Theme struct {
+	Enabled, Disabled rune
+	Separator         rune // between blocks, 0 for none
+	// Braille draws two tracks per line in Braille dots instead of the
+	// symbols, one character for two steps of both tracks, for dense
+	// overviews of many patterns. Separators are only written between
+	// blocks of an even number of steps, beat numbers are left out.
+	Braille bool
+}
+
+// The built in themes.
+var (
+	ClassicTheme = Theme{Enabled: symbolStepEnabled, Disabled: symbolStepDisabled, Separator: blockSeparator}
+	BlocksTheme  = Theme{Enabled: '█', Disabled: '·', Separator: '│'}
+	DotsTheme    = Theme{Enabled: '●', Disabled: '○', Separator: ' '}
+	BrailleTheme = Theme{Braille: true}
+)
+
+var (
+	themesMu sync.RWMutex
+	themes   = map[string]Theme{
+		"classic": ClassicTheme,
+		"blocks":  BlocksTheme,
+		"dots":    DotsTheme,
+		"braille": BrailleTheme,
+	}
+)
+
+// RegisterTheme makes the theme available by name to ThemeByName, for
+// example to select it in command line tools. It replaces a theme of the
+// same name.
+func RegisterTheme(name string, t Theme) {
+	themesMu.Lock()
+	defer themesMu.Unlock()
+	themes[strings.ToLower(name)] = t
+}
+
+// ThemeByName returns the theme registered with the given name, case
+// insensitive.
+func ThemeByName(name string) (Theme, bool) {
+	themesMu.RLock()
+	defer themesMu.RUnlock()
+	t, ok := themes[strings.ToLower(name)]
+	return t, ok
+}
+
+// ThemeNames returns the names of all registered themes sorted.
+func ThemeNames() []string {
+	themesMu.RLock()
+	defer themesMu.RUnlock()
+	names := make([]string, 0, len(themes))
+	for n := range themes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// The dots of a step in a Braille cell, the first track in the upper and the
+// second in the lower half, each step a column of two dots.
+const brailleBlank = 0x2800
+
+var brailleDots = [2][2]rune{{0x03, 0x18}, {0x44, 0xa0}}
+
+// brailleCell returns the Braille character of the two steps from step on
+// of both tracks.
+func brailleCell(tracks [2][]bool, step int) rune {
+	r := rune(brailleBlank)
+	for half, s := range tracks {
+		for col := 0; col < 2; col++ {
+			if i := step + col; i < len(s) && s[i] {
+				r |= brailleDots[half][col]
+			}
+		}
+	}
+	return r
+}
+
+// formatBraille writes the tracks in pairs, labelled with both names.
+func (f *Formatter) formatBraille(w printoutWriter, p *Pattern, block int) {
+	for i := 0; i < len(p.tracks); i += 2 {
+		pair := p.tracks[i:min(i+2, len(p.tracks))]
+		for j, t := range pair {
+			if j > 0 {
+				w.WriteString(" / ")
+			}
+			appendTrackLabel(w, t)
+		}
+		w.WriteRune('\t')
+		for bar := 0; bar < p.Bars(); bar++ {
+			if bar > 0 {
+				w.WriteRune('\t')
+			}
+			if p.Bars() > 1 {
+				w.WriteString(barName(bar))
+			}
+			var steps [2][]bool
+			for j, t := range pair {
+				steps[j] = t.barSteps(bar)[:p.trackLength(t)]
+			}
+			f.appendBraille(w, steps, block)
+			w.WriteString("\n")
+		}
+	}
+}
+
+// appendBraille writes the steps of both tracks as Braille cells.
+func (f *Formatter) appendBraille(w printoutWriter, steps [2][]bool, block int) {
+	sep := f.theme.Separator
+	if block%2 != 0 {
+		sep = 0
+	}
+	n := max(len(steps[0]), len(steps[1]))
+	for i := 0; i < n; i += 2 {
+		if sep != 0 && i%block == 0 {
+			w.WriteRune(sep)
+		}
+		w.WriteRune(brailleCell(steps, i))
+	}
+	if sep != 0 {
+		w.WriteRune(sep)
+	}
+}
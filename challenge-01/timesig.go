@@ -0,0 +1,97 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidTimeSignature is returned for a time signature whose bar does
+// not fit into the 16 steps of a pattern.
+var ErrInvalidTimeSignature = errors.New("invalid time signature")
+
+// TimeSignature is the meter of a pattern. A bar spans the steps of
+// Numerator notes of the length 1/Denominator, which must fit into the 16
+// sixteenth steps of a pattern, so 3/4, 6/8 or 7/8 are possible but 5/4 is
+// not. The zero value means 4/4.
+type TimeSignature struct {
+	Numerator, Denominator uint8
+}
+
+func (ts TimeSignature) String() string {
+	ts = ts.orDefault()
+	return fmt.Sprintf("%d/%d", ts.Numerator, ts.Denominator)
+}
+
+// ParseTimeSignature parses a time signature like "3/4".
+func ParseTimeSignature(s string) (TimeSignature, error) {
+	a, b, ok := strings.Cut(s, "/")
+	n, err1 := strconv.ParseUint(a, 10, 8)
+	d, err2 := strconv.ParseUint(b, 10, 8)
+	ts := TimeSignature{uint8(n), uint8(d)}
+	if !ok || err1 != nil || err2 != nil || !ts.valid() {
+		return TimeSignature{}, fmt.Errorf("%w %q", ErrInvalidTimeSignature, s)
+	}
+	return ts, nil
+}
+
+func (ts TimeSignature) orDefault() TimeSignature {
+	if ts == (TimeSignature{}) {
+		return TimeSignature{4, 4}
+	}
+	return ts
+}
+
+func (ts TimeSignature) valid() bool {
+	if ts == (TimeSignature{}) {
+		return true
+	}
+	switch ts.Denominator {
+	case 4, 8, 16:
+	default:
+		return false
+	}
+	return ts.Numerator > 0 && ts.barSteps() <= stepsLength
+}
+
+// barSteps returns the number of steps of a bar.
+func (ts TimeSignature) barSteps() int {
+	ts = ts.orDefault()
+	return int(ts.Numerator) * stepsLength / int(ts.Denominator)
+}
+
+// groupSteps returns the number of steps grouped in the printout, a beat or
+// in compound meters like 6/8 three of them.
+func (ts TimeSignature) groupSteps() int {
+	ts = ts.orDefault()
+	n := stepsLength / int(ts.Denominator)
+	if ts.Denominator > 4 && ts.Numerator > 3 && ts.Numerator%3 == 0 {
+		n *= 3
+	}
+	return n
+}
+
+// TimeSignature returns the time signature of the pattern, 4/4 unless set.
+func (p *Pattern) TimeSignature() TimeSignature {
+	return p.timeSig.orDefault()
+}
+
+// SetTimeSignature sets the time signature of the pattern. Steps beyond the
+// end of a shorter bar are kept but not played, printed or exported. It is
+// stored in the metadata chunk.
+func (p *Pattern) SetTimeSignature(ts TimeSignature) error {
+	if !ts.valid() {
+		return ErrInvalidTimeSignature
+	}
+	if ts == (TimeSignature{4, 4}) {
+		ts = TimeSignature{}
+	}
+	p.timeSig = ts
+	return nil
+}
+
+// BarSteps returns the number of steps played per bar, 16 in 4/4.
+func (p *Pattern) BarSteps() int {
+	return p.timeSig.barSteps()
+}
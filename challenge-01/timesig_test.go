@@ -0,0 +1,179 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimeSignature(t *testing.T) {
+	specs := map[string]int{"4/4": 16, "3/4": 12, "6/8": 12, "7/8": 14, "15/16": 15}
+	for s, steps := range specs {
+		ts, err := ParseTimeSignature(s)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		if got := ts.String(); got != s {
+			t.Errorf("Expected %q but got %q", s, got)
+		}
+		if got := ts.barSteps(); got != steps {
+			t.Errorf("%s: Expected %d steps but got %d", s, steps, got)
+		}
+	}
+	for _, s := range []string{"5/4", "0/4", "3/3", "9/8", "3", "x/4"} {
+		if _, err := ParseTimeSignature(s); err == nil {
+			t.Errorf("%s: Expected error", s)
+		}
+	}
+	if got := (TimeSignature{}).String(); got != "4/4" {
+		t.Errorf("Expected zero value to be 4/4 but got %q", got)
+	}
+}
+
+func timeSigPattern(t *testing.T, s string) *Pattern {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, err := ParseTimeSignature(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetTimeSignature(ts); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestTimeSignatureRoundTrip(t *testing.T) {
+	p := timeSigPattern(t, "6/8")
+	if err := p.SetTimeSignature(TimeSignature{5, 4}); err != ErrInvalidTimeSignature {
+		t.Errorf("Expected invalid time signature but got %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.TimeSignature(); got != (TimeSignature{6, 8}) {
+		t.Errorf("Expected 6/8 but got %v", got)
+	}
+	if !decoded.Equal(p) {
+		t.Errorf("Expected the pattern to round trip but got %v", Diff(p, decoded))
+	}
+
+	text, err := p.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromText Pattern
+	if err := fromText.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if fromText.TimeSignature() != p.TimeSignature() {
+		t.Errorf("Expected the time signature to round trip text but got %v", fromText.TimeSignature())
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Pattern
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !fromJSON.Equal(p) {
+		t.Errorf("Expected the time signature to round trip JSON but got %s", data)
+	}
+
+	p.SetTimeSignature(TimeSignature{4, 4})
+	if p.timeSig != (TimeSignature{}) || NewFormatter().Format(&buf, p) != nil || strings.Contains(buf.String(), "Time signature") {
+		t.Errorf("Expected 4/4 to be the default")
+	}
+}
+
+func TestTimeSignaturePrintout(t *testing.T) {
+	specs := map[string]string{
+		"3/4": "Time signature: 3/4\n",
+		"6/8": "Time signature: 6/8\n",
+	}
+	steps := map[string]string{
+		"3/4": "(0) kick\t|x---|x---|x---|\n",
+		"6/8": "(0) kick\t|x---x-|--x---|\n",
+	}
+	for s, header := range specs {
+		out := timeSigPattern(t, s).String()
+		if !strings.Contains(out, header) {
+			t.Errorf("%s: Expected header %q in\n%s", s, header, out)
+		}
+		if !strings.Contains(out, steps[s]) {
+			t.Errorf("%s: Expected track %q in\n%s", s, steps[s], out)
+		}
+	}
+	var buf bytes.Buffer
+	Format(&buf, timeSigPattern(t, "3/4"), WithBeatNumbers(), WithoutHeader())
+	if exp := "\t|1   |2   |3   |\n"; !strings.HasPrefix(buf.String(), exp) {
+		t.Errorf("Expected beat numbers %q but got\n%s", exp, buf.String())
+	}
+}
+
+func TestTimeSignatureMIDI(t *testing.T) {
+	specs := map[string][]byte{
+		"3/4": {0xff, 0x58, 4, 3, 2, 24, 8},
+		"6/8": {0xff, 0x58, 4, 6, 3, 36, 8},
+	}
+	for s, exp := range specs {
+		p := timeSigPattern(t, s)
+		var buf bytes.Buffer
+		if err := WriteMIDI(&buf, p, 2); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(buf.Bytes(), exp) {
+			t.Errorf("%s: Expected time signature event % x", s, exp)
+		}
+		// the kick plays beats 1 to 3 in both bars
+		var kicks int
+		for _, e := range p.schedule(2) {
+			if e.track == 0 {
+				kicks++
+				if e.tick >= 2*12*ticksPerStep {
+					t.Errorf("%s: Unexpected note at tick %d after the second bar", s, e.tick)
+				}
+			}
+		}
+		if kicks != 6 {
+			t.Errorf("%s: Expected 6 kicks but got %d", s, kicks)
+		}
+	}
+}
+
+func TestTimeSignaturePlayer(t *testing.T) {
+	p := timeSigPattern(t, "3/4")
+	pl := NewPlayer(p, nil)
+	for i := 0; i < 12; i++ {
+		ev, _, err := pl.advance(time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.Step != i {
+			t.Fatalf("Expected step %d but got %d", i, ev.Step)
+		}
+	}
+	if got := pl.Position(); got != 0 {
+		t.Errorf("Expected the bar to wrap after 12 steps but got position %d", got)
+	}
+	if got, exp := p.BarDuration(), 3*time.Minute/time.Duration(p.tempo); got < exp-time.Millisecond || got > exp+time.Millisecond {
+		t.Errorf("Expected bar duration %v but got %v", exp, got)
+	}
+
+	pl.Seek(14)
+	if ev, _, _ := pl.advance(time.Now()); ev.Step != 0 {
+		t.Errorf("Expected a position beyond the bar to wrap but got step %d", ev.Step)
+	}
+}
@@ -0,0 +1,97 @@
+package drum
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	// trackerTicksPerLine is the default speed of Renoise, the ratchets are
+	// written as retrigger effects relying on it.
+	trackerTicksPerLine = 12
+	// trackerMaxVolume is the full volume of the volume column.
+	trackerMaxVolume = 0x80
+	// trackerEmpty is a column without a note.
+	trackerEmpty = "--- .. .. .. ...."
+)
+
+// trackerNoteNames are the note names within an octave as trackers show them.
+var trackerNoteNames = [12]string{"C-", "C#", "D-", "D#", "E-", "F-", "F#", "G-", "G#", "A-", "A#", "B-"}
+
+// WriteTracker writes the pattern in tracker notation to w: one line per step
+// of a bar and one column per track, with the General MIDI drum of the track
+// as note in the octave numbering of Renoise (C-4 is MIDI note 48), the
+// track index as instrument, the velocity in the volume column and the micro
+// timing in the delay column. Notes played early are moved to the end of
+// the line before when that step is empty. Ratchets become retrigger effects
+// assuming 12 ticks per line. The mix and the swing are not written.
+func WriteTracker(w io.Writer, p *Pattern) error {
+	bw := bufio.NewWriter(w)
+	rows := p.BarSteps()
+	fmt.Fprintf(bw, "; Saved with HW Version: %s\n", p.version)
+	fmt.Fprintf(bw, "; %s BPM, %d lines per beat, %d ticks per line\n", FormatTempo(p.tempo), stepsLength/4, trackerTicksPerLine)
+	bw.WriteString("  ")
+	for _, t := range p.tracks {
+		name := fmt.Sprintf("(%d) %s", t.id, t.name)
+		if len(name) > len(trackerEmpty) {
+			name = name[:len(trackerEmpty)]
+		}
+		fmt.Fprintf(bw, " | %-*s", len(trackerEmpty), name)
+	}
+	bw.WriteString(" |\n")
+
+	cells := make([][]string, rows)
+	for row := range cells {
+		cells[row] = make([]string, len(p.tracks))
+		for i := range cells[row] {
+			cells[row][i] = trackerEmpty
+		}
+	}
+	for i, t := range p.tracks {
+		for n := 0; n < rows; n++ {
+			step := p.trackStep(t, n)
+			if !t.steps[step] {
+				continue
+			}
+			row := n
+			delay := int(math.Round(float64(t.offset(step)) * 256 / ticksPerStep))
+			if delay < 0 {
+				if prev := (n + rows - 1) % rows; !t.steps[p.trackStep(t, prev)] {
+					row, delay = prev, delay+256
+				} else {
+					delay = 0
+				}
+			}
+			cells[row][i] = trackerCell(t, step, i, delay)
+		}
+	}
+	for row, line := range cells {
+		fmt.Fprintf(bw, "%02d", row)
+		for _, cell := range line {
+			fmt.Fprintf(bw, " | %s", cell)
+		}
+		bw.WriteString(" |\n")
+	}
+	return bw.Flush()
+}
+
+// trackerCell returns the column of the step of track t with the instrument
+// and the delay in 1/256 of a line.
+func trackerCell(t *Track, step, instrument, delay int) string {
+	note, _ := ResolveGMNote(t.name)
+	volume := ".."
+	if v := t.Velocity(step); v != MaxVelocity {
+		volume = fmt.Sprintf("%02X", int(math.Round(float64(v)*trackerMaxVolume/MaxVelocity)))
+	}
+	delayColumn := ".."
+	if delay > 0 {
+		delayColumn = fmt.Sprintf("%02X", min(delay, 0xFF))
+	}
+	effect := "...."
+	if n := t.Ratchet(step); n > 1 {
+		effect = fmt.Sprintf("0R%02X", trackerTicksPerLine/n)
+	}
+	return fmt.Sprintf("%s%d %02X %s %s %s", trackerNoteNames[note%12], note/12, instrument%256, volume, delayColumn, effect)
+}
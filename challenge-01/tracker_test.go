@@ -0,0 +1,39 @@
+package drum
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestWriteTracker(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kick, snare := p.tracks[0], p.tracks[1]
+	kick.SetVelocity(0, 64)
+	kick.SetRatchet(8, 3)
+	snare.SetStep(3, false)
+	snare.SetTiming(4, -0.25)
+	snare.SetTiming(12, 0.25)
+	var buf bytes.Buffer
+	if err := WriteTracker(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) != 3+stepsLength+1 || !strings.HasPrefix(lines[2], "   | (0) kick") {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+	for row, exp := range map[int]string{
+		0:  "00 | C-3 00 41 .. .... | --- .. .. .. .... |",
+		3:  "03 | --- .. .. .. .... | D-3 01 .. C0 .... |",
+		8:  "08 | C-3 00 .. .. 0R04 | --- .. .. .. .... |",
+		12: "12 | C-3 00 .. .. .... | D-3 01 .. 40 .... |",
+	} {
+		if got := lines[3+row]; !strings.HasPrefix(got, exp) {
+			t.Errorf("row %d: expected %q but got %q", row, exp, got)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTrailingData is returned for data behind the payload that is not an
+// extension block when decoding with ErrorOnTrailing.
+var ErrTrailingData = errors.New("trailing data")
+
+// TrailingPolicy tells the decoder what to do with data behind the payload,
+// the checksum trailer and the extensions that it cannot interpret, like the
+// bytes appended to pattern_5.splice.
+type TrailingPolicy int
+
+const (
+	// CaptureTrailing keeps the data as raw extra, see Pattern.RawExtra, so
+	// that it is written back by the encoder. This is the default.
+	CaptureTrailing TrailingPolicy = iota
+	// IgnoreTrailing drops the data.
+	IgnoreTrailing
+	// ErrorOnTrailing fails the decoding with ErrTrailingData.
+	ErrorOnTrailing
+)
+
+// WithTrailing sets the policy for data behind the payload that is not an
+// extension block.
+func WithTrailing(policy TrailingPolicy) DecodeOption {
+	return func(o *decodeOptions) {
+		o.trailing = policy
+	}
+}
+
+// applyTrailing applies the policy to the raw extra of the decoded pattern.
+func applyTrailing(p *Pattern, policy TrailingPolicy) error {
+	if p.rawExtra == nil {
+		return nil
+	}
+	switch policy {
+	case IgnoreTrailing:
+		p.rawExtra = nil
+	case ErrorOnTrailing:
+		return fmt.Errorf("%w: %d bytes", ErrTrailingData, len(p.rawExtra))
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package drum
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestTrailingPolicy(t *testing.T) {
+	raw, err := ioutil.ReadFile(path.Join("fixtures", "pattern_5.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoders := map[string]func(data []byte, opts ...DecodeOption) (*Pattern, error){
+		"Decode": func(data []byte, opts ...DecodeOption) (*Pattern, error) {
+			return Decode(bytes.NewReader(data), opts...)
+		},
+		"DecodeBytes": DecodeBytes,
+	}
+	for name, decode := range decoders {
+		p, err := decode(raw)
+		if err != nil || len(p.RawExtra()) != 31 {
+			t.Errorf("%s: Expected the trailing data captured by default but got %v", name, err)
+		}
+		p, err = decode(raw, WithTrailing(CaptureTrailing))
+		if err != nil || len(p.RawExtra()) != 31 {
+			t.Errorf("%s: Expected the trailing data captured but got %v", name, err)
+		}
+		p, err = decode(raw, WithTrailing(IgnoreTrailing))
+		if err != nil || p.RawExtra() != nil {
+			t.Errorf("%s: Expected the trailing data ignored but got %v", name, err)
+		}
+		if len(p.Tracks()) != 2 {
+			t.Errorf("%s: Expected 2 tracks but got %d", name, len(p.Tracks()))
+		}
+		if _, err := decode(raw, WithTrailing(ErrorOnTrailing)); !errors.Is(err, ErrTrailingData) {
+			t.Errorf("%s: Expected trailing data error but got %v", name, err)
+		}
+
+		// extension blocks are not trailing data
+		var buf bytes.Buffer
+		if err := Encode(&buf, &Pattern{version: "0.808", tempo: 120, swing: 50}); err != nil {
+			t.Fatal(err)
+		}
+		if p, err := decode(buf.Bytes(), WithTrailing(ErrorOnTrailing)); err != nil || p.Swing() != 50 {
+			t.Errorf("%s: Expected the extensions decoded but got %v", name, err)
+		}
+	}
+}
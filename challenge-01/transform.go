@@ -0,0 +1,215 @@
+package drum
+
+// The transforms below return a new pattern and leave p unchanged. They move
+// the steps of every bar within the loop of each track, see Track.Length,
+// and all data of a step moves with it: the velocity, the micro timing, the
+// probability, the condition and the ratchet. Steps beyond the loop of a
+// track are kept as they are, and transforms that change the length either
+// repeat or cut the result to the loop.
+
+// Reverse returns a copy of the pattern with the steps of every track played
+// backwards. Timing offsets are negated, as a step played early is late when
+// time runs backwards.
+func (p *Pattern) Reverse() *Pattern {
+	return p.transform(reverseSteps)
+}
+
+var reverseSteps = stepMap{
+	from: func(n, i int) []int {
+		return []int{n - 1 - i}
+	},
+	timing: func(_ int, ticks int8) int8 {
+		return -ticks
+	},
+}
+
+// DoubleTime returns a copy of the pattern played twice as fast. Every pair
+// of steps is compressed into one step which is enabled when any of them is,
+// gets the values of the first enabled one and the resulting half of the
+// loop is repeated to fill it.
+func (p *Pattern) DoubleTime() *Pattern {
+	return p.transform(stepMap{
+		from: func(n, i int) []int {
+			j := 2 * (i % max(n/2, 1))
+			if j+1 >= n {
+				return []int{j}
+			}
+			return []int{j, j + 1}
+		},
+		timing: func(j int, ticks int8) int8 {
+			if j%2 != 0 {
+				return 0
+			}
+			return ticks / 2
+		},
+	})
+}
+
+// HalfTime returns a copy of the pattern played at half the speed. Every
+// step is stretched over two steps, so every bar fills two: bar A of the
+// copy plays the first half of bar A and bar B its second half, the bars
+// after follow in pairs and so does the play order. As the data of a step
+// is shared by the bars, a stretched step takes that of the first half
+// unless only the second half plays it. A pattern of more than half of
+// MaxBars bars, or with a play order too long to double, can not grow; of
+// its bars only the first half is kept.
+func (p *Pattern) HalfTime() *Pattern {
+	bars := p.Bars()
+	if 2*bars > MaxBars || 2*len(p.barOrder) > maxBarOrder {
+		return p.transform(halfSteps)
+	}
+	c := p.Clone()
+	c.extraBars = uint8(2*bars - 1)
+	for _, t := range c.tracks {
+		c.halfTimeTrack(t, bars)
+	}
+	if p.barOrder != nil {
+		c.barOrder = make([]uint8, 0, 2*len(p.barOrder))
+		for _, b := range p.barOrder {
+			c.barOrder = append(c.barOrder, 2*b, 2*b+1)
+		}
+	}
+	return c
+}
+
+var halfSteps = stepMap{
+	from: func(_, i int) []int {
+		if i%2 != 0 {
+			return nil
+		}
+		return []int{i / 2}
+	},
+	timing: halfTiming,
+}
+
+func halfTiming(_ int, ticks int8) int8 {
+	return int8(clamp(2*int(ticks), -maxTimingTicks, maxTimingTicks))
+}
+
+// halfTimeTrack stretches the steps of the first bars of the track of p
+// over twice as many bars in place.
+func (p *Pattern) halfTimeTrack(t *Track, bars int) {
+	src := *t
+	n := p.trackLength(t)
+	for i := 0; i < stepsLength; i++ {
+		// the source step of the first and of the second half of the loop
+		sources := []int{i / 2, (n+1)/2 + i/2}
+		for b := 0; b < bars; b++ {
+			for half, j := range sources {
+				on := src.barSteps(b)[i]
+				if i < n {
+					on = i%2 == 0 && j < n && src.barSteps(b)[j]
+				}
+				t.barSteps(2*b + half)[i] = on
+			}
+		}
+		if i >= n {
+			continue
+		}
+		if i%2 != 0 {
+			t.takeStep(&src, i, nil, bars, nil)
+			continue
+		}
+		if sources[1] >= n {
+			sources = sources[:1]
+		}
+		t.takeStep(&src, i, sources, bars, halfTiming)
+	}
+}
+
+// RotateBars returns a copy of the pattern with the bars moved n bars later,
+// wrapping around the last bar. Negative values move them earlier. The play
+// order names bars by their position and is kept, and so is the data of the
+// steps, which the bars share.
+func (p *Pattern) RotateBars(n int) *Pattern {
+	c := p.Clone()
+	bars := p.Bars()
+	for i, t := range c.tracks {
+		for b := range bars {
+			*t.barSteps(((b+n)%bars + bars) % bars) = *p.tracks[i].barSteps(b)
+		}
+	}
+	return c
+}
+
+// Rotate returns a copy of the pattern with the steps of every track moved n
+// steps later, wrapping around the end of the loop of the track. Negative
+// values move the steps earlier. Every bar is rotated on its own, so n
+// counts steps; see RotateBars to move whole bars.
+func (p *Pattern) Rotate(n int) *Pattern {
+	return p.transform(rotateSteps(n))
+}
+
+func rotateSteps(n int) stepMap {
+	return stepMap{from: func(length, i int) []int {
+		return []int{((i-n)%length + length) % length}
+	}}
+}
+
+// stepMap moves the steps of a track. For the step i of a track looping
+// every n steps, from returns the source steps it is made of, none for an
+// empty step. The step is enabled in a bar when any of them is in that bar
+// and takes the data of the first one enabled in any bar, with the timing
+// converted by timing unless it is nil.
+type stepMap struct {
+	from   func(n, i int) []int
+	timing func(j int, ticks int8) int8
+}
+
+// transform returns a clone of p with the steps of every track moved by m.
+func (p *Pattern) transform(m stepMap) *Pattern {
+	c := p.Clone()
+	for _, t := range c.tracks {
+		c.transformTrack(t, m)
+	}
+	return c
+}
+
+// transformTrack moves the steps of the track of p by m in place.
+func (p *Pattern) transformTrack(t *Track, m stepMap) {
+	bars := p.Bars()
+	src := *t
+	n := p.trackLength(t)
+	for i := 0; i < n; i++ {
+		sources := m.from(n, i)
+		for b := 0; b < bars; b++ {
+			on := false
+			for _, j := range sources {
+				on = on || src.barSteps(b)[j]
+			}
+			t.barSteps(b)[i] = on
+		}
+		t.takeStep(&src, i, sources, bars, m.timing)
+	}
+}
+
+// takeStep sets the data of the step i of t to that of the first of the
+// source steps of src enabled in any of the bars, or of the first source
+// when none is, with the timing converted by timing unless it is nil.
+// Without sources the step gets the defaults.
+func (t *Track) takeStep(src *Track, i int, sources []int, bars int, timing func(j int, ticks int8) int8) {
+	t.velocity[i], t.timing[i], t.probability[i], t.condition[i], t.ratchet[i] = 0, 0, 0, 0, 0
+	if len(sources) == 0 {
+		return
+	}
+	played := func(j int) bool {
+		for b := 0; b < bars; b++ {
+			if src.barSteps(b)[j] {
+				return true
+			}
+		}
+		return false
+	}
+	j := sources[0]
+	for _, s := range sources {
+		if played(s) {
+			j = s
+			break
+		}
+	}
+	t.velocity[i], t.timing[i] = src.velocity[j], src.timing[j]
+	t.probability[i], t.condition[i], t.ratchet[i] = src.probability[j], src.condition[j], src.ratchet[j]
+	if timing != nil {
+		t.timing[i] = timing(j, src.timing[j])
+	}
+}
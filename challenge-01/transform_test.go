@@ -0,0 +1,185 @@
+package drum
+
+import (
+	"path"
+	"slices"
+	"testing"
+)
+
+func TestTransforms(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// (3) hh-open |--x-|--x-|x-x-|--x-|
+	hh := p.tracks[3]
+	hh.SetVelocity(2, 100)
+	hh.SetTiming(2, -0.25)
+	steps := func(p *Pattern) string {
+		return stepSymbols(p.tracks[3].steps)
+	}
+	specs := map[string]struct {
+		p        *Pattern
+		exp      string
+		step     int
+		velocity uint8
+		timing   float64
+	}{
+		"reverse":   {p.Reverse(), "-x---x-x-x---x--", 13, 100, 0.25},
+		"double":    {p.DoubleTime(), "-x-xxx-x-x-xxx-x", 1, 100, -0.125},
+		"half":      {p.HalfTime(), "----x-------x---", 4, 100, -0.5},
+		"rotate":    {p.Rotate(3), "-x---x---x-x-x--", 5, 100, -0.25},
+		"rotate -1": {p.Rotate(-1), "-x---x-x-x---x--", 1, 100, -0.25},
+		"rotate 16": {p.Rotate(16), "--x---x-x-x---x-", 2, 100, -0.25},
+	}
+	for msg, spec := range specs {
+		if got := steps(spec.p); got != spec.exp {
+			t.Errorf("%s: Expected %s but got %s", msg, spec.exp, got)
+		}
+		tr := spec.p.tracks[3]
+		if v := tr.Velocity(spec.step); v != spec.velocity {
+			t.Errorf("%s: Expected velocity %d but got %d", msg, spec.velocity, v)
+		}
+		if o := tr.Timing(spec.step); o != spec.timing {
+			t.Errorf("%s: Expected timing %v but got %v", msg, spec.timing, o)
+		}
+	}
+	if exp, got := "--x---x-x-x---x-", steps(p); got != exp {
+		t.Errorf("Expected the pattern unchanged but got %s", got)
+	}
+	if !p.Reverse().Reverse().Equal(p) {
+		t.Error("Expected reversing twice to restore the pattern")
+	}
+}
+
+func TestTransformsMoveTrigs(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// (3) hh-open |--x-|--x-|x-x-|--x-|
+	hh := p.tracks[3]
+	hh.SetProbability(2, 25)
+	hh.SetCondition(2, Condition{Loop: 1, Cycle: 4})
+	specs := map[string]struct {
+		p    *Pattern
+		step int
+	}{
+		"reverse": {p.Reverse(), 13},
+		"double":  {p.DoubleTime(), 1},
+		"half":    {p.HalfTime(), 4},
+		"rotate":  {p.Rotate(1), 3},
+	}
+	for msg, spec := range specs {
+		tr := spec.p.tracks[3]
+		if !tr.steps[spec.step] {
+			t.Errorf("%s: Expected step %d enabled", msg, spec.step)
+		}
+		if got := tr.Probability(spec.step); got != 25 {
+			t.Errorf("%s: Expected probability 25 but got %d", msg, got)
+		}
+		if got := tr.Condition(spec.step); got != (Condition{Loop: 1, Cycle: 4}) {
+			t.Errorf("%s: Expected condition 1:4 but got %v", msg, got)
+		}
+		if got := tr.Probability(2); spec.step != 2 && got != 100 {
+			t.Errorf("%s: Expected the probability moved from step 2 but got %d", msg, got)
+		}
+	}
+}
+
+func TestTransformsMoveBarsAndRatchets(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// (3) hh-open |--x-|--x-|x-x-|--x-|
+	hh := p.tracks[3]
+	hh.SetRatchet(2, 3)
+	bar, err := p.AddBar(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hh.SetBarStep(bar, 2, false)
+	hh.SetBarStep(bar, 0, true)
+
+	r := p.Rotate(1)
+	tr := r.tracks[3]
+	if got := tr.Ratchet(3); got != 3 {
+		t.Errorf("Expected ratchet 3 on step 3 but got %d", got)
+	}
+	if got := tr.Ratchet(2); got != 1 {
+		t.Errorf("Expected no ratchet on step 2 but got %d", got)
+	}
+	steps, _ := tr.BarSteps(bar)
+	if exp, got := "-x-----x-x-x---x", stepSymbols(steps); got != exp {
+		t.Errorf("Expected bar B %s but got %s", exp, got)
+	}
+
+	// (0) kick |x---|x---|x---|x---|, step 15 beyond the loop is kept
+	kick := p.tracks[0]
+	if err := kick.SetLength(12); err != nil {
+		t.Fatal(err)
+	}
+	kick.steps[15] = true
+	if exp, got := "---x---x---xx--x", stepSymbols(p.Reverse().tracks[0].steps); got != exp {
+		t.Errorf("Expected the loop of 12 reversed to %s but got %s", exp, got)
+	}
+	if exp, got := "-x---x---x--x--x", stepSymbols(p.Rotate(1).tracks[0].steps); got != exp {
+		t.Errorf("Expected the loop of 12 rotated to %s but got %s", exp, got)
+	}
+}
+
+func TestHalfTimeAndRotateBars(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// (3) hh-open |--x-|--x-|x-x-|--x-|, in bar B |x---|--x-|x-x-|--x-|
+	hh := p.tracks[3]
+	hh.SetVelocity(0, 60)
+	hh.SetVelocity(4, 80)
+	bar, _ := p.AddBar(0)
+	hh.SetBarStep(bar, 2, false)
+	hh.SetBarStep(bar, 0, true)
+	p.SetBarOrder([]int{0, 0, 1})
+
+	bars := func(tr *Track) []string {
+		var s []string
+		for bar := range MaxBars {
+			steps, _ := tr.BarSteps(bar)
+			s = append(s, stepSymbols(steps))
+		}
+		return s
+	}
+	h := p.HalfTime()
+	if h.Bars() != 4 || FormatBarOrder(h.BarOrder()) != "ABABCD" {
+		t.Fatalf("Expected 4 bars in the order ABABCD but got %d in %s", h.Bars(), FormatBarOrder(h.BarOrder()))
+	}
+	exp := []string{"----x-------x---", "x---x-------x---", "x-----------x---", "x---x-------x---", "----------------", "----------------", "----------------", "----------------"}
+	if got := bars(h.tracks[3]); !slices.Equal(got, exp) {
+		t.Errorf("Expected the bars %v but got %v", exp, got)
+	}
+	// step 0 plays step 0 of bar B and step 8, which takes its velocity
+	if v := h.tracks[3].Velocity(0); v != 60 {
+		t.Errorf("Expected the velocity of the first half 60 but got %d", v)
+	}
+	if v, exp := h.tracks[3].Velocity(4), hh.Velocity(2); v != exp {
+		t.Errorf("Expected the velocity %d of step 2 but got %d", exp, v)
+	}
+
+	for p.Bars() < 5 {
+		p.AddBar(0)
+	}
+	if h := p.HalfTime(); h.Bars() != 5 || stepSymbols(h.tracks[3].steps) != "----x-------x---" {
+		t.Errorf("Expected 5 bars of the first halves but got %d %s", h.Bars(), stepSymbols(h.tracks[3].steps))
+	}
+
+	r := p.RotateBars(-1)
+	exp = []string{"x-----x-x-x---x-", "--x---x-x-x---x-", "--x---x-x-x---x-", "--x---x-x-x---x-", "--x---x-x-x---x-", "----------------", "----------------", "----------------"}
+	if got := bars(r.tracks[3]); !slices.Equal(got, exp) {
+		t.Errorf("Expected the bars %v but got %v", exp, got)
+	}
+	if !p.RotateBars(5).Equal(p) || !p.RotateBars(2).RotateBars(-2).Equal(p) {
+		t.Error("Expected rotating by the bars or back to restore the pattern")
+	}
+}
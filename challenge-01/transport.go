@@ -0,0 +1,99 @@
+package drum
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotPlaying is returned when recording into a player that is not
+// playing.
+var ErrNotPlaying = errors.New("not playing")
+
+// Transport is the position of a player at a point in time, see
+// Player.Transport.
+type Transport struct {
+	Playing    bool
+	Step       int           // step sounding, starting at 0
+	Bar        int           // bars played before the one sounding
+	BarSteps   int           // steps of the bar sounding
+	Elapsed    time.Duration // since the step started
+	StepLength time.Duration // of the step sounding including swing
+	Tempo      float32       // in BPM
+	CountIn    bool          // the step is counted in, see WithCountIn
+	Queued     bool          // a pattern or scene plays from the next bar, see QueuePattern
+	// Looping is set with a loop region from the steps LoopFrom to LoopTo,
+	// see Player.SetLoopRegion.
+	Looping          bool
+	LoopFrom, LoopTo int
+
+	at time.Time // the step started
+}
+
+// Transport returns the position of the player at the time, the step
+// sounding and how far into it the time is. While stopped the position is
+// the one of the last step played.
+func (pl *Player) Transport(at time.Time) Transport {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	tr := pl.current
+	tr.Playing, tr.Queued = pl.playing, pl.next != nil || pl.scene != nil
+	if r := pl.region; r != nil {
+		tr.Looping, tr.LoopFrom, tr.LoopTo = true, r.from, r.to
+	}
+	if !tr.at.IsZero() && at.After(tr.at) {
+		tr.Elapsed = at.Sub(tr.at)
+	}
+	return tr
+}
+
+func (pl *Player) setPlaying(on bool) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.playing = on
+}
+
+// Nearest returns the step nearest to the position and the bars played
+// before it, which is the next step once the position is past the middle
+// of the step sounding. The step after the end of a loop region is its
+// first.
+func (tr Transport) Nearest() (step, bar int) {
+	step, bar = tr.Step, tr.Bar
+	if tr.Elapsed*2 < tr.StepLength {
+		return step, bar
+	}
+	switch {
+	case tr.Looping && step == tr.LoopTo && tr.LoopTo < tr.BarSteps:
+		return tr.LoopFrom, bar + 1
+	case step+1 >= tr.BarSteps:
+		return 0, bar + 1
+	}
+	return step + 1, bar
+}
+
+// Record enables the step of the track nearest to the position of the
+// transport and sets its velocity, for notes played along live, and returns
+// the step. Patterns with several bars record into the bar playing.
+func (p *Pattern) Record(t *Track, velocity uint8, tr Transport) (int, error) {
+	if !tr.Playing || tr.CountIn {
+		return 0, ErrNotPlaying
+	}
+	n, bar := tr.Nearest()
+	step := p.trackStep(t, bar*p.BarSteps()+n)
+	if err := t.SetVelocity(step, velocity); err != nil {
+		return 0, err
+	}
+	t.barSteps(p.barOf(bar))[step] = true
+	return step, nil
+}
+
+// TrackByNote returns the first track whose name resolves to the General
+// MIDI note, see ResolveGMNote, to record notes of MIDI input. It returns
+// nil when no track plays the note.
+func (p *Pattern) TrackByNote(note uint8) *Track {
+	for _, t := range p.tracks {
+		if n, ok := ResolveGMNote(t.name); ok && n == note {
+			return t
+		}
+	}
+	return nil
+}
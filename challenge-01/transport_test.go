@@ -0,0 +1,66 @@
+package drum
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTransport(t *testing.T) {
+	kick := &Track{name: "kick"}
+	p := &Pattern{tempo: 120, tracks: []*Track{kick}}
+	pl := NewPlayer(p, nil)
+	start := time.Now()
+	if tr := pl.Transport(start); tr.Playing || tr.Step != 0 || tr.Elapsed != 0 {
+		t.Errorf("unexpected transport before playing %+v", tr)
+	}
+	pl.setPlaying(true)
+	// at 120 BPM a step takes 125ms
+	for i := 0; i < stepsLength+2; i++ {
+		if _, _, err := pl.advance(start.Add(time.Duration(i) * 125 * time.Millisecond)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr := pl.Transport(start.Add(17*125*time.Millisecond + 50*time.Millisecond))
+	if !tr.Playing || tr.Step != 1 || tr.Bar != 1 || tr.Elapsed != 50*time.Millisecond || tr.StepLength != 125*time.Millisecond || tr.Tempo != 120 {
+		t.Errorf("unexpected transport %+v", tr)
+	}
+
+	specs := []struct {
+		tr        Transport
+		step, bar int
+	}{
+		{Transport{Step: 1, Bar: 1, BarSteps: 16, Elapsed: 62 * time.Millisecond, StepLength: 125 * time.Millisecond}, 1, 1},
+		{Transport{Step: 1, Bar: 1, BarSteps: 16, Elapsed: 63 * time.Millisecond, StepLength: 125 * time.Millisecond}, 2, 1},
+		{Transport{Step: 15, Bar: 1, BarSteps: 16, Elapsed: 100 * time.Millisecond, StepLength: 125 * time.Millisecond}, 0, 2},
+	}
+	for _, spec := range specs {
+		if step, bar := spec.tr.Nearest(); step != spec.step || bar != spec.bar {
+			t.Errorf("%+v: expected step %d of bar %d but got %d of %d", spec.tr, spec.step, spec.bar, step, bar)
+		}
+	}
+}
+
+func TestRecord(t *testing.T) {
+	p := fillPattern(t)
+	kick := p.tracks[0]
+	tr := Transport{Playing: true, Step: 5, Bar: 3, BarSteps: 16, Elapsed: 100 * time.Millisecond, StepLength: 125 * time.Millisecond}
+	step, err := p.Record(kick, 90, tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the fourth bar played is the fill B
+	if b, _ := kick.BarSteps(1); step != 6 || !b[6] || kick.Steps()[6] || kick.Velocity(6) != 90 {
+		t.Errorf("Expected step 7 of bar B recorded but got %d in %v", step, b)
+	}
+	tr.Playing = false
+	if _, err := p.Record(kick, 90, tr); !errors.Is(err, ErrNotPlaying) {
+		t.Errorf("Expected %v but got %v", ErrNotPlaying, err)
+	}
+	if got := p.TrackByNote(36); got != kick {
+		t.Errorf("Expected the kick for note 36 but got %v", got)
+	}
+	if got := p.TrackByNote(38); got != nil {
+		t.Errorf("Expected no track for note 38 but got %v", got)
+	}
+}
@@ -0,0 +1,255 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+var (
+	chunkProbability = chunkID{'P', 'R', 'O', 'B'}
+	chunkCondition   = chunkID{'C', 'O', 'N', 'D'}
+)
+
+// Features of the conditional trigger parameters.
+const (
+	FeatureProbability Feature = "probability"
+	FeatureCondition   Feature = "condition"
+)
+
+// maxCycle is the longest cycle of loops a Condition can count.
+const maxCycle = 8
+
+var (
+	// ErrInvalidProbability is returned for a probability above 100 percent.
+	ErrInvalidProbability = errors.New("invalid probability")
+	// ErrInvalidCondition is returned for a condition that cannot be met or
+	// stored.
+	ErrInvalidCondition = errors.New("invalid condition")
+)
+
+// Fill restricts a step to loops played with or without fill.
+type Fill uint8
+
+// Fill modes of a Condition.
+const (
+	AnyFill  Fill = iota // play regardless of the fill state
+	FillOnly             // play only while fill is on
+	NotFill              // play only while fill is off
+)
+
+// Condition restricts the loops an enabled step is triggered in, like the
+// trig conditions of hardware sequencers. Loops are the bars played since
+// the start counting from 1. With a Cycle the step plays in the Loop-th of
+// every Cycle loops, so {Loop: 4, Cycle: 4} plays every 4th loop. The zero
+// value plays in every loop.
+type Condition struct {
+	Loop, Cycle uint8 // 1 <= Loop <= Cycle <= 8, or both 0
+	Fill        Fill
+}
+
+func (c Condition) String() string {
+	var parts []string
+	if c.Cycle > 1 {
+		parts = append(parts, fmt.Sprintf("%d:%d", c.Loop, c.Cycle))
+	}
+	switch c.Fill {
+	case FillOnly:
+		parts = append(parts, "fill")
+	case NotFill:
+		parts = append(parts, "!fill")
+	}
+	if len(parts) == 0 {
+		return "always"
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseCondition parses a condition in the format of Condition.String, like
+// "1:4", "fill", "!fill", "3:4 !fill" or "always".
+func ParseCondition(s string) (Condition, error) {
+	var c Condition
+	for _, f := range strings.Fields(s) {
+		switch f {
+		case "always":
+		case "fill":
+			c.Fill = FillOnly
+		case "!fill":
+			c.Fill = NotFill
+		default:
+			a, b, ok := strings.Cut(f, ":")
+			loop, err1 := strconv.ParseUint(a, 10, 8)
+			cycle, err2 := strconv.ParseUint(b, 10, 8)
+			if !ok || err1 != nil || err2 != nil {
+				return c, fmt.Errorf("%w %q", ErrInvalidCondition, s)
+			}
+			c.Loop, c.Cycle = uint8(loop), uint8(cycle)
+		}
+	}
+	if !c.valid() {
+		return Condition{}, fmt.Errorf("%w %q", ErrInvalidCondition, s)
+	}
+	return c, nil
+}
+
+func (c Condition) valid() bool {
+	if c.Fill > NotFill {
+		return false
+	}
+	if c.Loop == 0 && c.Cycle == 0 {
+		return true
+	}
+	return c.Loop >= 1 && c.Loop <= c.Cycle && c.Cycle <= maxCycle
+}
+
+// holds reports whether the condition is met in the loop counted from 0.
+func (c Condition) holds(loop int, fill bool) bool {
+	switch {
+	case c.Fill == FillOnly && !fill, c.Fill == NotFill && fill:
+		return false
+	case c.Cycle > 1:
+		return loop%int(c.Cycle) == int(c.Loop)-1
+	}
+	return true
+}
+
+// byte packs the condition as stored in the condition chunk:
+// bits 0-2 Loop-1, bits 3-5 Cycle-1 and bits 6-7 the fill mode.
+func (c Condition) byte() byte {
+	b := byte(c.Fill) << 6
+	if c.Cycle > 1 {
+		b |= (c.Loop-1)&7 | (c.Cycle-1)<<3
+	}
+	return b
+}
+
+func conditionOf(b byte) Condition {
+	c := Condition{Fill: Fill(b >> 6)}
+	if cycle := (b>>3)&7 + 1; cycle > 1 {
+		c.Loop, c.Cycle = b&7+1, cycle
+	}
+	return c
+}
+
+// Probability returns the chance of the step to be triggered in percent,
+// 100 unless set.
+func (t *Track) Probability(step int) uint8 {
+	if p := t.probability[step]; p != 0 {
+		return p
+	}
+	return 100
+}
+
+// SetProbability sets the chance of the step to be triggered in percent.
+// 0 resets it to 100.
+func (t *Track) SetProbability(step int, percent uint8) error {
+	if step < 0 || step >= stepsLength {
+		return ErrStepOutOfRange
+	}
+	if percent > 100 {
+		return ErrInvalidProbability
+	}
+	if percent == 100 {
+		percent = 0
+	}
+	t.probability[step] = percent
+	return nil
+}
+
+// Condition returns the trig condition of the step.
+func (t *Track) Condition(step int) Condition {
+	return conditionOf(t.condition[step])
+}
+
+// SetCondition sets the trig condition of the step.
+func (t *Track) SetCondition(step int, c Condition) error {
+	if step < 0 || step >= stepsLength {
+		return ErrStepOutOfRange
+	}
+	if !c.valid() {
+		return ErrInvalidCondition
+	}
+	t.condition[step] = c.byte()
+	return nil
+}
+
+// hasProbability reports whether any step of the track has a probability set.
+func (t *Track) hasProbability() bool {
+	return t.probability != [stepsLength]uint8{}
+}
+
+// hasCondition reports whether any step of the track has a condition set.
+func (t *Track) hasCondition() bool {
+	return t.condition != [stepsLength]uint8{}
+}
+
+// triggers reports whether the step of the bar is played in the loop
+// counted from 0. The probability is rolled with rng, the global source
+// when it is nil.
+func (t *Track) triggers(bar, step, loop int, fill bool, rng *rand.Rand) bool {
+	if !t.barSteps(bar)[step] || !conditionOf(t.condition[step]).holds(loop, fill) {
+		return false
+	}
+	p := t.probability[step]
+	if p == 0 {
+		return true
+	}
+	if rng == nil {
+		return rand.Intn(100) < int(p)
+	}
+	return rng.Intn(100) < int(p)
+}
+
+// The probability and condition chunks are step chunks like the velocity
+// chunk. Probabilities are percent with 0 meaning 100, conditions are packed
+// as described at Condition.byte.
+
+func decodeProbabilityChunk(data []byte, p *Pattern) error {
+	return decodeStepChunk(data, p, func(t *Track, values []byte) error {
+		for i, v := range values {
+			if v > 100 {
+				return fmt.Errorf("invalid probability %d", v)
+			}
+			t.probability[i] = v
+		}
+		return nil
+	})
+}
+
+func encodeProbabilityChunk(p *Pattern) []byte {
+	return encodeStepChunk(p, (*Track).hasProbability, func(t *Track) []byte {
+		return t.probability[:]
+	})
+}
+
+func clearProbability(p *Pattern) {
+	for _, t := range p.tracks {
+		t.probability = [stepsLength]uint8{}
+	}
+}
+
+func decodeConditionChunk(data []byte, p *Pattern) error {
+	return decodeStepChunk(data, p, func(t *Track, values []byte) error {
+		for i, v := range values {
+			if c := conditionOf(v); !c.valid() || c.byte() != v {
+				return fmt.Errorf("invalid condition %#x", v)
+			}
+			t.condition[i] = v
+		}
+		return nil
+	})
+}
+
+func encodeConditionChunk(p *Pattern) []byte {
+	return encodeStepChunk(p, (*Track).hasCondition, func(t *Track) []byte {
+		return t.condition[:]
+	})
+}
+
+func clearCondition(p *Pattern) {
+	for _, t := range p.tracks {
+		t.condition = [stepsLength]uint8{}
+	}
+}
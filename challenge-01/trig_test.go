@@ -0,0 +1,155 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestParseCondition(t *testing.T) {
+	specs := map[string]Condition{
+		"always":    {},
+		"1:4":       {Loop: 1, Cycle: 4},
+		"fill":      {Fill: FillOnly},
+		"3:8 !fill": {Loop: 3, Cycle: 8, Fill: NotFill},
+	}
+	for s, exp := range specs {
+		c, err := ParseCondition(s)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		if c != exp {
+			t.Errorf("%s: Expected %+v but got %+v", s, exp, c)
+		}
+		if got := c.String(); got != s {
+			t.Errorf("Expected %q but got %q", s, got)
+		}
+		if got := conditionOf(c.byte()); got != c {
+			t.Errorf("%s: Expected packing to round trip but got %+v", s, got)
+		}
+	}
+	for _, s := range []string{"5:4", "0:4", "1:9", "x", "1:"} {
+		if _, err := ParseCondition(s); err == nil {
+			t.Errorf("%s: Expected error", s)
+		}
+	}
+}
+
+func trigPattern(t *testing.T) *Pattern {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// (0) kick |x---|x---|x---|x---|
+	kick := p.tracks[0]
+	if err := kick.SetCondition(4, Condition{Loop: 2, Cycle: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := kick.SetCondition(8, Condition{Fill: FillOnly}); err != nil {
+		t.Fatal(err)
+	}
+	if err := kick.SetProbability(12, 50); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestTrigRoundTrip(t *testing.T) {
+	p := trigPattern(t)
+	kick := p.tracks[0]
+	if err := kick.SetProbability(0, 101); err != ErrInvalidProbability {
+		t.Errorf("Expected invalid probability but got %v", err)
+	}
+	if err := kick.SetCondition(0, Condition{Loop: 3, Cycle: 2}); err != ErrInvalidCondition {
+		t.Errorf("Expected invalid condition but got %v", err)
+	}
+	if kick.Probability(0) != 100 || kick.Probability(12) != 50 {
+		t.Errorf("Unexpected probabilities %v", probabilities(kick))
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(p) {
+		t.Errorf("Expected trig conditions to round trip but got %v", Diff(p, decoded))
+	}
+	if exp, got := []Feature{FeatureCondition, FeatureProbability}, decoded.Features(); len(got) != 2 || got[0] != exp[0] || got[1] != exp[1] {
+		t.Errorf("Expected features %v but got %v", exp, got)
+	}
+	plain := p.Clone()
+	plain.tracks[0].probability, plain.tracks[0].condition = [stepsLength]uint8{}, [stepsLength]uint8{}
+	if d := Diff(p, plain); len(d) != 2 {
+		t.Errorf("Expected probability and condition differences but got %v", d)
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Pattern
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !fromJSON.Equal(p) {
+		t.Errorf("Expected trig conditions to round trip JSON but got %v", Diff(p, &fromJSON))
+	}
+}
+
+func TestTrigSchedule(t *testing.T) {
+	p := trigPattern(t)
+	kicks := func(events []noteEvent) []int {
+		var steps []int
+		for _, e := range events {
+			if e.track == 0 {
+				steps = append(steps, e.tick/ticksPerStep)
+			}
+		}
+		return steps
+	}
+	got := kicks(p.schedule(4))
+	// step 0 in every bar, step 4 only in every 2nd bar, step 8 never
+	// without fill and step 12 rolled with the fixed seed
+	exp := 4 + 2
+	if len(got) < exp || len(got) > exp+4 {
+		t.Errorf("Expected %d to %d kicks but got %v", exp, exp+4, got)
+	}
+	for _, step := range got {
+		if step%stepsLength == 8 || step == 4 || step == 36 {
+			t.Errorf("Unexpected kick at %d in %v", step, got)
+		}
+	}
+	if again := kicks(p.schedule(4)); len(again) != len(got) {
+		t.Errorf("Expected reproducible exports but got %v and %v", got, again)
+	}
+}
+
+func TestTrigPlayer(t *testing.T) {
+	p := trigPattern(t)
+	pl := NewPlayer(p, nil, WithRand(rand.New(rand.NewSource(1))))
+	played := make(map[int]int)
+	for i := 0; i < 4*stepsLength; i++ {
+		if i == 2*stepsLength {
+			pl.SetFill(true)
+		}
+		ev, _, err := pl.advance(time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tr := range ev.Tracks {
+			if tr == p.tracks[0] {
+				played[ev.Step]++
+			}
+		}
+	}
+	if played[0] != 4 || played[4] != 2 || played[8] != 2 || played[12] > 4 {
+		t.Errorf("Unexpected kicks per step %v", played)
+	}
+}
@@ -0,0 +1,160 @@
+package drum
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// Severity tells how serious an Issue found by Validate is.
+type Severity int
+
+const (
+	// SeverityWarning marks data that is valid but most likely not intended.
+	SeverityWarning Severity = iota
+	// SeverityError marks data that the hardware rejects or misinterprets.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// MarshalText writes the severity as "warning" or "error".
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText parses a severity written by MarshalText.
+func (s *Severity) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "warning":
+		*s = SeverityWarning
+	case "error":
+		*s = SeverityError
+	default:
+		return fmt.Errorf("unknown severity %q", text)
+	}
+	return nil
+}
+
+// The codes of the issues reported by the default rules. They are stable so
+// that tools can match on them.
+const (
+	CodeEmptyPattern     = "empty-pattern"
+	CodeTempoOutOfRange  = "tempo-out-of-range"
+	CodeDuplicateTrackID = "duplicate-track-id"
+	CodeEmptyTrackName   = "empty-track-name"
+	CodeNonPrintableName = "non-printable-track-name"
+	CodeSilentTrack      = "silent-track"
+)
+
+// Issue is a single problem of a pattern found by Validate.
+type Issue struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Track    int      `json:"track"` // index of the track, -1 for pattern fields
+	Message  string   `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%v: %s: %s", i.Severity, i.Code, i.Message)
+}
+
+// Rule checks a pattern for a single kind of issue.
+type Rule func(p *Pattern) []Issue
+
+// DefaultRules returns the rules applied by Validate.
+func DefaultRules() []Rule {
+	return []Rule{ruleEmptyPattern, ruleTempo, ruleTempoCorrected, ruleDuplicateIDs, ruleTrackNames, ruleSilentTracks}
+}
+
+// Validate checks the pattern with the default rules and returns the issues
+// found, pattern issues first and track issues in track order per rule.
+func Validate(p *Pattern) []Issue {
+	return ValidateRules(p, DefaultRules()...)
+}
+
+// ValidateRules checks the pattern with the given rules in order.
+func ValidateRules(p *Pattern, rules ...Rule) []Issue {
+	var issues []Issue
+	for _, rule := range rules {
+		issues = append(issues, rule(p)...)
+	}
+	return issues
+}
+
+// HasErrors reports whether any of the issues has SeverityError.
+func HasErrors(issues []Issue) bool {
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleEmptyPattern(p *Pattern) []Issue {
+	if len(p.tracks) > 0 {
+		return nil
+	}
+	return []Issue{{SeverityError, CodeEmptyPattern, -1, "pattern has no tracks"}}
+}
+
+func ruleTempo(p *Pattern) []Issue {
+	if validTempo(p.tempo) {
+		return nil
+	}
+	msg := fmt.Sprintf("tempo %v outside of %d to %d BPM", p.tempo, MinTempo, MaxTempo)
+	return []Issue{{SeverityError, CodeTempoOutOfRange, -1, msg}}
+}
+
+func ruleDuplicateIDs(p *Pattern) []Issue {
+	var issues []Issue
+	seen := make(map[uint32]bool)
+	for i, t := range p.tracks {
+		if seen[t.id] {
+			msg := fmt.Sprintf("track (%d) %s reuses the id of an earlier track", t.id, t.name)
+			issues = append(issues, Issue{SeverityError, CodeDuplicateTrackID, i, msg})
+		}
+		seen[t.id] = true
+	}
+	return issues
+}
+
+func ruleTrackNames(p *Pattern) []Issue {
+	var issues []Issue
+	for i, t := range p.tracks {
+		switch {
+		case t.name == "":
+			msg := fmt.Sprintf("track (%d) has no name", t.id)
+			issues = append(issues, Issue{SeverityWarning, CodeEmptyTrackName, i, msg})
+		case !printable(t.name):
+			msg := fmt.Sprintf("track (%d) name %q has non-printable characters", t.id, t.name)
+			issues = append(issues, Issue{SeverityError, CodeNonPrintableName, i, msg})
+		}
+	}
+	return issues
+}
+
+func ruleSilentTracks(p *Pattern) []Issue {
+	var issues []Issue
+	for i, t := range p.tracks {
+		if t.steps == (Steps{}) {
+			msg := fmt.Sprintf("track (%d) %s has no enabled steps", t.id, t.name)
+			issues = append(issues, Issue{SeverityWarning, CodeSilentTrack, i, msg})
+		}
+	}
+	return issues
+}
+
+func printable(s string) bool {
+	for _, r := range s {
+		if r == unicode.ReplacementChar || !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package drum
+
+import (
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	p := &Pattern{tempo: 1000, tracks: []*Track{
+		{id: 1, name: "kick", steps: Steps{true}},
+		{id: 2, name: "", steps: Steps{true}},
+		{id: 1, name: "snare\x01", steps: Steps{}},
+	}}
+	exp := []Issue{
+		{SeverityError, CodeTempoOutOfRange, -1, "tempo 1000 outside of 20 to 999 BPM"},
+		{SeverityError, CodeDuplicateTrackID, 2, "track (1) snare\x01 reuses the id of an earlier track"},
+		{SeverityWarning, CodeEmptyTrackName, 1, "track (2) has no name"},
+		{SeverityError, CodeNonPrintableName, 2, `track (1) name "snare\x01" has non-printable characters`},
+		{SeverityWarning, CodeSilentTrack, 2, "track (1) snare\x01 has no enabled steps"},
+	}
+	issues := Validate(p)
+	if !reflect.DeepEqual(exp, issues) {
+		t.Errorf("Expected %v but got %v", exp, issues)
+	}
+	if !HasErrors(issues) {
+		t.Error("Expected errors")
+	}
+
+	issues = Validate(&Pattern{tempo: 120})
+	exp = []Issue{{SeverityError, CodeEmptyPattern, -1, "pattern has no tracks"}}
+	if !reflect.DeepEqual(exp, issues) {
+		t.Errorf("Expected %v but got %v", exp, issues)
+	}
+	if got := issues[0].String(); got != "error: empty-pattern: pattern has no tracks" {
+		t.Errorf("Unexpected string %q", got)
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues := Validate(p); len(issues) != 0 {
+		t.Errorf("Expected no issues but got %v", issues)
+	}
+	tooSlow := func(p *Pattern) []Issue {
+		if p.tempo < 130 {
+			return []Issue{{SeverityWarning, "too-slow", -1, "too slow"}}
+		}
+		return nil
+	}
+	issues := ValidateRules(p, tooSlow)
+	if len(issues) != 1 || issues[0].Code != "too-slow" || HasErrors(issues) {
+		t.Errorf("Unexpected issues %v", issues)
+	}
+}
@@ -0,0 +1,172 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	chunkVelocity = chunkID{'V', 'E', 'L', 'O'}
+	chunkTiming   = chunkID{'T', 'I', 'M', 'E'}
+)
+
+// Features of the per step parameters.
+const (
+	FeatureVelocity Feature = "velocity"
+	FeatureTiming   Feature = "timing"
+)
+
+// MaxVelocity is the loudest velocity of a step, used for all steps of
+// patterns without velocity data.
+const MaxVelocity = defaultVelocity
+
+// maxTimingTicks limits the micro timing offset to half a step in both
+// directions.
+const maxTimingTicks = ticksPerStep / 2
+
+// ErrStepOutOfRange is returned for a step index outside of the pattern.
+var ErrStepOutOfRange = errors.New("step out of range")
+
+// Velocity returns the velocity of the step from 1 to MaxVelocity.
+func (t *Track) Velocity(step int) uint8 {
+	if v := t.velocity[step]; v != 0 {
+		return v
+	}
+	return MaxVelocity
+}
+
+// SetVelocity sets the velocity of the step. 0 resets it to MaxVelocity.
+func (t *Track) SetVelocity(step int, v uint8) error {
+	if step < 0 || step >= stepsLength {
+		return ErrStepOutOfRange
+	}
+	if v > MaxVelocity {
+		return fmt.Errorf("invalid velocity %d", v)
+	}
+	if v == MaxVelocity {
+		v = 0
+	}
+	t.velocity[step] = v
+	return nil
+}
+
+// Timing returns the micro timing offset of the step as fraction of a step.
+// Negative values play the step early.
+func (t *Track) Timing(step int) float64 {
+	return float64(t.timing[step]) / ticksPerStep
+}
+
+// SetTiming sets the micro timing offset of the step as fraction of a step
+// between -0.5 and 0.5. It is stored with a resolution of 1/24 step.
+func (t *Track) SetTiming(step int, offset float64) error {
+	if step < 0 || step >= stepsLength {
+		return ErrStepOutOfRange
+	}
+	if math.IsNaN(offset) || math.Abs(offset) > 0.5 {
+		return fmt.Errorf("invalid timing offset %v", offset)
+	}
+	t.timing[step] = int8(math.Round(offset * ticksPerStep))
+	return nil
+}
+
+// hasVelocity reports whether any step of the track has a velocity set.
+func (t *Track) hasVelocity() bool {
+	return t.velocity != [stepsLength]uint8{}
+}
+
+// hasTiming reports whether any step of the track has a timing offset.
+func (t *Track) hasTiming() bool {
+	return t.timing != [stepsLength]int8{}
+}
+
+// The velocity and timing chunks store an entry for every track with data:
+// |Track index (2 bytes)|Value per step (16 bytes)|
+// Velocities are unsigned with 0 meaning MaxVelocity, timing offsets are
+// signed ticks of 1/24 step.
+const stepChunkEntryLength = 2 + stepsLength
+
+func decodeStepChunk(data []byte, p *Pattern, apply func(t *Track, values []byte) error) error {
+	if len(data)%stepChunkEntryLength != 0 {
+		return errors.New("invalid size")
+	}
+	for ; len(data) > 0; data = data[stepChunkEntryLength:] {
+		index := binary.LittleEndian.Uint16(data)
+		if int(index) >= len(p.tracks) {
+			return fmt.Errorf("track index %d out of range", index)
+		}
+		if err := apply(p.tracks[index], data[2:stepChunkEntryLength]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeStepChunk(p *Pattern, has func(t *Track) bool, values func(t *Track) []byte) []byte {
+	var buf bytes.Buffer
+	for i, t := range p.tracks {
+		if !has(t) {
+			continue
+		}
+		buf.Write(binary.LittleEndian.AppendUint16(nil, uint16(i)))
+		buf.Write(values(t))
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+func decodeVelocityChunk(data []byte, p *Pattern) error {
+	return decodeStepChunk(data, p, func(t *Track, values []byte) error {
+		for i, v := range values {
+			if v > MaxVelocity {
+				return fmt.Errorf("invalid velocity %d", v)
+			}
+			t.velocity[i] = v
+		}
+		return nil
+	})
+}
+
+func encodeVelocityChunk(p *Pattern) []byte {
+	return encodeStepChunk(p, (*Track).hasVelocity, func(t *Track) []byte {
+		return t.velocity[:]
+	})
+}
+
+func clearVelocity(p *Pattern) {
+	for _, t := range p.tracks {
+		t.velocity = [stepsLength]uint8{}
+	}
+}
+
+func decodeTimingChunk(data []byte, p *Pattern) error {
+	return decodeStepChunk(data, p, func(t *Track, values []byte) error {
+		for i, v := range values {
+			if o := int8(v); o > maxTimingTicks || o < -maxTimingTicks {
+				return fmt.Errorf("invalid timing offset %d", o)
+			}
+			t.timing[i] = int8(v)
+		}
+		return nil
+	})
+}
+
+func encodeTimingChunk(p *Pattern) []byte {
+	return encodeStepChunk(p, (*Track).hasTiming, func(t *Track) []byte {
+		b := make([]byte, stepsLength)
+		for i, o := range t.timing {
+			b[i] = byte(o)
+		}
+		return b
+	})
+}
+
+func clearTiming(p *Pattern) {
+	for _, t := range p.tracks {
+		t.timing = [stepsLength]int8{}
+	}
+}
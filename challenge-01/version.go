@@ -0,0 +1,110 @@
+package drum
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidVersion is returned for a version string not of the form
+// major.minor with an optional -tag.
+var ErrInvalidVersion = errors.New("invalid version")
+
+// Version is a firmware version like 0.808-alpha as stored in the version
+// field of a pattern. Versions are ordered by major and minor number, a
+// tagged pre-release before the release without a tag and tags by name, so
+// 0.808-alpha < 0.808-beta < 0.808 < 0.909.
+type Version struct {
+	Major, Minor int
+	Tag          string
+}
+
+// The firmware versions of the fixtures.
+var (
+	Firmware708Alpha = Version{0, 708, "alpha"}
+	Firmware808Alpha = Version{0, 808, "alpha"}
+	Firmware909      = Version{0, 909, ""}
+)
+
+// ParseVersion parses a version like "0.808-alpha".
+func ParseVersion(s string) (Version, error) {
+	num, tag, tagged := strings.Cut(s, "-")
+	a, b, ok := strings.Cut(num, ".")
+	major, err1 := strconv.Atoi(a)
+	minor, err2 := strconv.Atoi(b)
+	if !ok || err1 != nil || err2 != nil || major < 0 || minor < 0 || tagged && tag == "" ||
+		strings.HasPrefix(a, "+") || strings.HasPrefix(b, "+") {
+		return Version{}, fmt.Errorf("%w %q", ErrInvalidVersion, s)
+	}
+	return Version{major, minor, tag}, nil
+}
+
+func (v Version) String() string {
+	if v.Tag == "" {
+		return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+	}
+	return fmt.Sprintf("%d.%d-%s", v.Major, v.Minor, v.Tag)
+}
+
+// Compare returns -1 when v is before o, 1 when it is after o and 0 when
+// both are the same version.
+func (v Version) Compare(o Version) int {
+	switch {
+	case v.Major != o.Major:
+		return sign(v.Major - o.Major)
+	case v.Minor != o.Minor:
+		return sign(v.Minor - o.Minor)
+	case v.Tag == o.Tag:
+		return 0
+	case v.Tag == "":
+		return 1
+	case o.Tag == "":
+		return -1
+	default:
+		return strings.Compare(v.Tag, o.Tag)
+	}
+}
+
+// Less reports whether v is before o.
+func (v Version) Less(o Version) bool {
+	return v.Compare(o) < 0
+}
+
+// AtLeast reports whether v is o or later, for behavior that depends on
+// the firmware.
+func (v Version) AtLeast(o Version) bool {
+	return v.Compare(o) >= 0
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (v Version) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (v *Version) UnmarshalText(text []byte) error {
+	parsed, err := ParseVersion(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+// FirmwareVersion returns the version string of the pattern parsed, see
+// ParseVersion. Patterns written by other tools may have version strings
+// that are no versions.
+func (p *Pattern) FirmwareVersion() (Version, error) {
+	return ParseVersion(p.version)
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,55 @@
+package drum
+
+import (
+	"encoding/json"
+	"errors"
+	"path"
+	"sort"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	for s, exp := range map[string]Version{
+		"0.808-alpha": Firmware808Alpha, "0.909": Firmware909, "1.10-rc-2": {1, 10, "rc-2"},
+	} {
+		v, err := ParseVersion(s)
+		if err != nil || v != exp {
+			t.Errorf("Expected %v for %q but got %v %v", exp, s, v, err)
+		}
+		if v.String() != s {
+			t.Errorf("Expected %q but got %q", s, v)
+		}
+	}
+	for _, s := range []string{"", "808", "0.808-", "0.x", "-1.2", "1.+2", "0.9.7", "h2pattern"} {
+		if _, err := ParseVersion(s); !errors.Is(err, ErrInvalidVersion) {
+			t.Errorf("Expected %v for %q but got %v", ErrInvalidVersion, s, err)
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	exp := []Version{Firmware708Alpha, Firmware808Alpha, {0, 808, "beta"}, {0, 808, ""}, Firmware909, {1, 0, ""}}
+	got := []Version{Firmware909, {1, 0, ""}, {0, 808, ""}, Firmware708Alpha, {0, 808, "beta"}, Firmware808Alpha}
+	sort.Slice(got, func(i, j int) bool { return got[i].Less(got[j]) })
+	for i := range exp {
+		if got[i] != exp[i] {
+			t.Fatalf("Expected %v but got %v", exp, got)
+		}
+	}
+	if !Firmware909.AtLeast(Firmware808Alpha) || Firmware708Alpha.AtLeast(Firmware808Alpha) || Firmware909.Compare(Firmware909) != 0 {
+		t.Error("unexpected comparison")
+	}
+
+	p, err := DecodeFile(path.Join("fixtures", "pattern_4.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := p.FirmwareVersion(); err != nil || v != Firmware909 {
+		t.Errorf("Expected %v but got %v %v", Firmware909, v, err)
+	}
+	b, _ := json.Marshal(struct{ V Version }{Firmware808Alpha})
+	var decoded struct{ V Version }
+	if err := json.Unmarshal(b, &decoded); err != nil || decoded.V != Firmware808Alpha {
+		t.Errorf("Expected a JSON round trip but got %s %v", b, err)
+	}
+}
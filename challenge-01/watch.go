@@ -0,0 +1,162 @@
+package drum
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPollInterval is how often a Watcher checks the files by default.
+const DefaultPollInterval = 500 * time.Millisecond
+
+// WatchEvent is sent by a Watcher when a watched file changed.
+type WatchEvent struct {
+	Path    string
+	Pattern *Pattern // decoded pattern, nil when Err is set
+	Err     error    // decoding error, for example of a file still written
+}
+
+// WatchOption configures a Watcher.
+type WatchOption func(*Watcher)
+
+// WithPollInterval sets how often the files are checked for changes.
+func WithPollInterval(d time.Duration) WatchOption {
+	return func(w *Watcher) {
+		if d > 0 {
+			w.interval = d
+		}
+	}
+}
+
+// WithWatchPlayer makes the watcher hand every reloaded pattern to the
+// player, which switches to it at the next bar, see Player.QueuePattern.
+func WithWatchPlayer(pl *Player) WatchOption {
+	return func(w *Watcher) {
+		w.player = pl
+	}
+}
+
+// WithWatchDecodeOptions sets the options the changed files are decoded with.
+func WithWatchDecodeOptions(opts ...DecodeOption) WatchOption {
+	return func(w *Watcher) {
+		w.decode = opts
+	}
+}
+
+// Watcher reloads patterns when their files change on disk, for example
+// when the hardware writes to an SD card mounted on the computer. The
+// package has no dependencies, so files are polled by their modification
+// time and size instead of using file system notifications.
+type Watcher struct {
+	path     string
+	dir      bool
+	interval time.Duration
+	player   *Player
+	decode   []DecodeOption
+	events   chan WatchEvent
+	stop     chan struct{}
+	once     sync.Once
+	done     chan struct{}
+}
+
+// fileState is what a change of a file is detected by.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewWatcher watches the .splice file at path or, for a directory, all
+// .splice files in it. Files existing when the watcher is created are not
+// sent until they change, files created later are sent when they appear.
+func NewWatcher(path string, opts ...WatchOption) (*Watcher, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		path:     path,
+		dir:      info.IsDir(),
+		interval: DefaultPollInterval,
+		events:   make(chan WatchEvent),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	states := w.scan()
+	go w.run(states)
+	return w, nil
+}
+
+// Events returns the channel the changes are sent to. It is closed by Close.
+func (w *Watcher) Events() <-chan WatchEvent {
+	return w.events
+}
+
+// Close stops watching and closes the events channel.
+func (w *Watcher) Close() error {
+	w.once.Do(func() { close(w.stop) })
+	<-w.done
+	return nil
+}
+
+func (w *Watcher) run(states map[string]fileState) {
+	defer close(w.done)
+	defer close(w.events)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+		current := w.scan()
+		var changed []string
+		for path, s := range current {
+			if old, ok := states[path]; !ok || old != s {
+				changed = append(changed, path)
+			}
+		}
+		sort.Strings(changed)
+		states = current
+		for _, path := range changed {
+			ev := WatchEvent{Path: path}
+			ev.Pattern, ev.Err = DecodeFile(path, w.decode...)
+			if ev.Err == nil && w.player != nil {
+				w.player.QueuePattern(ev.Pattern)
+			}
+			select {
+			case w.events <- ev:
+			case <-w.stop:
+				return
+			}
+		}
+	}
+}
+
+// scan returns the state of the watched files that exist.
+func (w *Watcher) scan() map[string]fileState {
+	states := make(map[string]fileState)
+	if !w.dir {
+		if info, err := os.Stat(w.path); err == nil {
+			states[w.path] = fileState{info.ModTime(), info.Size()}
+		}
+		return states
+	}
+	infos, err := ioutil.ReadDir(w.path)
+	if err != nil {
+		return states
+	}
+	for _, info := range infos {
+		if info.Mode().IsRegular() && strings.EqualFold(filepath.Ext(info.Name()), ".splice") {
+			states[filepath.Join(w.path, info.Name())] = fileState{info.ModTime(), info.Size()}
+		}
+	}
+	return states
+}
@@ -0,0 +1,130 @@
+package drum
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func nextWatchEvent(t *testing.T, w *Watcher) WatchEvent {
+	select {
+	case ev := <-w.Events():
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a watch event")
+	}
+	return WatchEvent{}
+}
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	data1, err := ioutil.ReadFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data2, err := ioutil.ReadFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "a.splice")
+	if err := ioutil.WriteFile(file, data1, 0644); err != nil {
+		t.Fatal(err)
+	}
+	p1, _ := DecodeBytes(data1)
+	p2, _ := DecodeBytes(data2)
+
+	pl := NewPlayer(p1, nil)
+	w, err := NewWatcher(dir, WithPollInterval(5*time.Millisecond), WithWatchPlayer(pl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// a new file and a file that is not a pattern
+	ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644)
+	other := filepath.Join(dir, "b.splice")
+	if err := ioutil.WriteFile(other, data2, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if ev := nextWatchEvent(t, w); ev.Path != other || ev.Err != nil || !ev.Pattern.Equal(p2) {
+		t.Errorf("Expected %s to be loaded but got %+v", other, ev)
+	}
+
+	// a partly written file reports the error
+	later := time.Now().Add(time.Hour)
+	ioutil.WriteFile(file, data1[:20], 0644)
+	os.Chtimes(file, later, later)
+	if ev := nextWatchEvent(t, w); ev.Path != file || ev.Err == nil {
+		t.Errorf("Expected a decoding error but got %+v", ev)
+	}
+	ioutil.WriteFile(file, data1, 0644)
+	os.Chtimes(file, later.Add(time.Hour), later.Add(time.Hour))
+	if ev := nextWatchEvent(t, w); ev.Path != file || !ev.Pattern.Equal(p1) {
+		t.Errorf("Expected %s to be reloaded but got %+v", file, ev)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-w.Events(); ok {
+		t.Error("Expected the events to be closed")
+	}
+}
+
+func TestQueuePattern(t *testing.T) {
+	p1, err := DecodeFile(path.Join("fixtures", "pattern_1.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := DecodeFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl := NewPlayer(p1, nil)
+	pl.Seek(8)
+	pl.QueuePattern(p2)
+	for i := 8; i < stepsLength; i++ {
+		ev, _, _ := pl.advance(time.Now())
+		for _, tr := range ev.Tracks {
+			for _, queued := range p2.tracks {
+				if tr == queued {
+					t.Fatalf("Expected the queued pattern to wait for the next bar at step %d", i)
+				}
+			}
+		}
+	}
+	if ev, _, _ := pl.advance(time.Now()); len(ev.Tracks) == 0 || ev.Tracks[0] != p2.tracks[0] {
+		t.Errorf("Expected the queued pattern after the bar but got %v", ev.Tracks)
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	if _, err := NewWatcher(filepath.Join("fixtures", "missing.splice")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "pattern.splice")
+	data, _ := ioutil.ReadFile(path.Join("fixtures", "pattern_3.splice"))
+	ioutil.WriteFile(file, data, 0644)
+	w, err := NewWatcher(file, WithPollInterval(5*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(file, later, later)
+	if ev := nextWatchEvent(t, w); ev.Path != file || ev.Err != nil {
+		t.Errorf("Expected the file to be reloaded but got %+v", ev)
+	}
+}
@@ -0,0 +1,154 @@
+package drum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// ErrUnsupportedFormat is returned for audio files other than uncompressed
+// PCM with one or two channels and 8, 16 or 24 bits.
+var ErrUnsupportedFormat = errors.New("unsupported audio format")
+
+// Sample is stereo audio with values from -1 to 1. Mono audio has the same
+// data in both channels.
+type Sample struct {
+	Rate        int // frames per second
+	Left, Right []float32
+}
+
+// Frames returns the length of the sample in frames.
+func (s *Sample) Frames() int {
+	return len(s.Left)
+}
+
+// ReadWAVFile reads the WAV file found at path, see ReadWAV.
+func ReadWAVFile(path string) (*Sample, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadWAV(file)
+}
+
+// ReadWAV reads an uncompressed PCM WAV file.
+func ReadWAV(r io.Reader) (*Sample, error) {
+	var header struct {
+		RIFF [4]byte
+		Size uint32
+		WAVE [4]byte
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("parse wav header: %v", err)
+	}
+	if string(header.RIFF[:]) != "RIFF" || string(header.WAVE[:]) != "WAVE" {
+		return nil, ErrUnsupportedFormat
+	}
+	var format struct {
+		Format, Channels uint16
+		Rate, ByteRate   uint32
+		Align, Bits      uint16
+	}
+	var haveFormat bool
+	for {
+		var chunk struct {
+			ID   [4]byte
+			Size uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &chunk); err != nil {
+			return nil, fmt.Errorf("parse wav chunk: %v", err)
+		}
+		data, err := readBytes(r, int(chunk.Size+chunk.Size%2))
+		if err != nil {
+			return nil, fmt.Errorf("parse wav %s chunk: %v", chunk.ID[:], err)
+		}
+		switch string(chunk.ID[:]) {
+		case "fmt ":
+			if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &format); err != nil {
+				return nil, fmt.Errorf("parse wav format: %v", err)
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return nil, fmt.Errorf("parse wav: data before format")
+			}
+			const formatPCM = 1
+			if format.Format != formatPCM {
+				return nil, ErrUnsupportedFormat
+			}
+			return decodePCM(data[:chunk.Size], int(format.Channels), int(format.Bits), int(format.Rate), false)
+		}
+	}
+}
+
+// decodePCM converts interleaved integer PCM data. WAV files store little
+// endian values with unsigned 8 bit samples, AIFF files big endian values
+// which are always signed.
+func decodePCM(data []byte, channels, bits, rate int, bigEndian bool) (*Sample, error) {
+	if channels < 1 || channels > 2 || (bits != 8 && bits != 16 && bits != 24) {
+		return nil, ErrUnsupportedFormat
+	}
+	width := bits / 8
+	frames := len(data) / (width * channels)
+	values := make([]float32, frames*channels)
+	for i := range values {
+		b := data[i*width : (i+1)*width]
+		var v int32
+		for n := range b {
+			if bigEndian {
+				v = v<<8 | int32(b[n])
+			} else {
+				v = v<<8 | int32(b[width-1-n])
+			}
+		}
+		// shift the sign bit into place
+		v <<= uint(32 - bits)
+		if bits == 8 && !bigEndian {
+			v ^= math.MinInt32
+		}
+		values[i] = float32(float64(v) / -math.MinInt32)
+	}
+	s := &Sample{Rate: rate}
+	if channels == 1 {
+		s.Left, s.Right = values, values
+		return s, nil
+	}
+	s.Left, s.Right = make([]float32, frames), make([]float32, frames)
+	for i := 0; i < frames; i++ {
+		s.Left[i], s.Right[i] = values[2*i], values[2*i+1]
+	}
+	return s, nil
+}
+
+// WriteWAV writes the sample as 16 bit stereo PCM WAV file to w. Values
+// outside of -1 and 1 are clipped.
+func WriteWAV(w io.Writer, s *Sample) error {
+	const channels, bits = 2, 16
+	dataSize := uint32(s.Frames() * channels * bits / 8)
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, 36+dataSize)
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, []uint32{16})
+	binary.Write(&buf, binary.LittleEndian, []uint16{1, channels})
+	binary.Write(&buf, binary.LittleEndian, []uint32{uint32(s.Rate), uint32(s.Rate * channels * bits / 8)})
+	binary.Write(&buf, binary.LittleEndian, []uint16{channels * bits / 8, bits})
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, dataSize)
+	pcm := make([]int16, 0, s.Frames()*channels)
+	for i := range s.Left {
+		pcm = append(pcm, toPCM16(s.Left[i]), toPCM16(s.Right[i]))
+	}
+	binary.Write(&buf, binary.LittleEndian, pcm)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func toPCM16(v float32) int16 {
+	return int16(math.Round(float64(math.Max(-1, math.Min(float64(v), 1))) * math.MaxInt16))
+}
@@ -0,0 +1,54 @@
+package drum
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// WebMIDIPattern is the JSON structure consumed by common browser step
+// sequencers built on the Web MIDI API.
+type WebMIDIPattern struct {
+	BPM          float64        `json:"bpm"`
+	StepsPerBeat int            `json:"stepsPerBeat"`
+	Tracks       []WebMIDITrack `json:"tracks"`
+}
+
+// WebMIDITrack is a single track of a WebMIDIPattern. Steps contains one
+// velocity per step from 0 (off) to 127.
+type WebMIDITrack struct {
+	ID      uint32 `json:"id"`
+	Name    string `json:"name"`
+	Note    uint8  `json:"note"`
+	Channel uint8  `json:"channel"`
+	Steps   []int  `json:"steps"`
+}
+
+// gmPercussionChannel is the zero based General MIDI percussion channel 10.
+const gmPercussionChannel = 9
+
+// defaultVelocity is the MIDI velocity of an enabled step.
+const defaultVelocity = 127
+
+// WebMIDI converts the pattern into the Web MIDI JSON structure. Track names
+// are mapped to General MIDI percussion notes.
+func (p *Pattern) WebMIDI() WebMIDIPattern {
+	w := WebMIDIPattern{BPM: tempo64(p.tempo), StepsPerBeat: blockSize}
+	for _, t := range p.tracks {
+		note, _ := ResolveGMNote(t.name)
+		wt := WebMIDITrack{ID: t.id, Name: t.name, Note: note, Channel: gmPercussionChannel}
+		for step, enabled := range t.steps {
+			v := 0
+			if enabled {
+				v = int(t.Velocity(step))
+			}
+			wt.Steps = append(wt.Steps, v)
+		}
+		w.Tracks = append(w.Tracks, wt)
+	}
+	return w
+}
+
+// WriteWebMIDI writes the pattern as Web MIDI JSON to w.
+func WriteWebMIDI(w io.Writer, p *Pattern) error {
+	return json.NewEncoder(w).Encode(p.WebMIDI())
+}
@@ -0,0 +1,42 @@
+package drum
+
+import (
+	"bytes"
+	"path"
+	"testing"
+)
+
+func TestWriteWebMIDI(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_5.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteWebMIDI(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	exp := `{"bpm":999,"stepsPerBeat":4,"tracks":[` +
+		`{"id":1,"name":"Kick","note":36,"channel":9,"steps":[127,0,0,0,0,0,0,0,127,0,0,0,0,0,0,0]},` +
+		`{"id":2,"name":"HiHat","note":42,"channel":9,"steps":[127,0,127,0,127,0,127,0,127,0,127,0,127,0,127,0]}]}` + "\n"
+	if got := buf.String(); got != exp {
+		t.Errorf("Expected:\n%v\nbut got:\n%v", exp, got)
+	}
+}
+
+func TestGMNote(t *testing.T) {
+	testCases := []struct {
+		name string
+		note uint8
+		ok   bool
+	}{
+		{"kick", 36, true}, {"SubKick", 36, true}, {"hh-open", 46, true}, {"hh-close", 42, true},
+		{"HiHat", 42, true}, {"Low Conga", 64, true}, {"low-tom", 45, true}, {"BD", 36, true},
+		{"cowbell", 56, true}, {"laser", fallbackGMNote, false},
+	}
+	for _, testCase := range testCases {
+		note, ok := ResolveGMNote(testCase.name)
+		if note != testCase.note || ok != testCase.ok {
+			t.Errorf("Expected %v %v but got %v %v for %q", testCase.note, testCase.ok, note, ok, testCase.name)
+		}
+	}
+}
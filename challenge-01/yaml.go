@@ -0,0 +1,129 @@
+package drum
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+
+	"github.com/alpe/go-challenge/challenge-01/internal/yaml"
+)
+
+// patternYAML is the YAML representation of a pattern, the JSON one with
+// the values of steps as numbers instead of base64.
+type patternYAML struct {
+	patternJSON `yaml:",inline"`
+	Tracks      []trackYAML `json:"tracks" yaml:"tracks"`
+}
+
+type trackYAML struct {
+	trackJSON   `yaml:",inline"`
+	Velocity    []int `json:"velocity,omitempty" yaml:"velocity,omitempty"`
+	Probability []int `json:"probability,omitempty" yaml:"probability,omitempty"`
+}
+
+func (p *Pattern) toYAML() patternYAML {
+	v := patternYAML{patternJSON: p.toJSON(), Tracks: []trackYAML{}}
+	for _, tj := range v.patternJSON.Tracks {
+		ty := trackYAML{trackJSON: tj}
+		for _, b := range tj.Velocity {
+			ty.Velocity = append(ty.Velocity, int(b))
+		}
+		for _, b := range tj.Probability {
+			ty.Probability = append(ty.Probability, int(b))
+		}
+		ty.trackJSON.Velocity, ty.trackJSON.Probability = nil, nil
+		v.Tracks = append(v.Tracks, ty)
+	}
+	v.patternJSON.Tracks = nil
+	return v
+}
+
+// pattern returns the pattern of the YAML representation with all values
+// validated.
+func (v patternYAML) pattern() (*Pattern, error) {
+	v.patternJSON.Tracks = nil
+	for _, ty := range v.Tracks {
+		tj := ty.trackJSON
+		var err error
+		if tj.Velocity, err = byteValues(ty.Velocity); err != nil {
+			return nil, fmt.Errorf("track %d: velocity %v", tj.ID, err)
+		}
+		if tj.Probability, err = byteValues(ty.Probability); err != nil {
+			return nil, fmt.Errorf("track %d: probability %v", tj.ID, err)
+		}
+		v.patternJSON.Tracks = append(v.patternJSON.Tracks, tj)
+	}
+	return v.patternJSON.pattern()
+}
+
+// byteValues returns the values as bytes for the validation by the setters.
+func byteValues(values []int) ([]uint8, error) {
+	var b []uint8
+	for _, v := range values {
+		if v < 0 || v > math.MaxUint8 {
+			return nil, fmt.Errorf("%d out of range", v)
+		}
+		b = append(b, uint8(v))
+	}
+	return b, nil
+}
+
+// MarshalYAML returns the value written for the pattern by YAML libraries
+// like gopkg.in/yaml.v3: the keys of the JSON representation with the steps
+// as strings like "x---x---x---x---" and velocities and probabilities as
+// numbers.
+func (p *Pattern) MarshalYAML() (interface{}, error) {
+	return p.toYAML(), nil
+}
+
+// UnmarshalYAML sets the pattern for YAML libraries supporting unmarshalers
+// in the style of gopkg.in/yaml.v2, which yaml.v3 supports too. All values
+// are validated like by UnmarshalJSON.
+func (p *Pattern) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v patternYAML
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	np, err := v.pattern()
+	if err != nil {
+		return err
+	}
+	*p = *np
+	return nil
+}
+
+// ToYAML writes the pattern as YAML document to w without a YAML library,
+// the same document YAML libraries write from MarshalYAML. Lists like the
+// velocities of a track are written on one line.
+func (p *Pattern) ToYAML(w io.Writer) error {
+	data, err := json.Marshal(p.toYAML())
+	if err != nil {
+		return err
+	}
+	if data, err = yaml.FromJSON(data); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// FromYAML reads a pattern written by ToYAML or by a YAML library from
+// MarshalYAML. Block and flow style, quoted and plain values and comments
+// are understood, anchors and multi line values are not. All values are
+// validated like by UnmarshalJSON.
+func FromYAML(r io.Reader) (*Pattern, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = yaml.ToJSON(data); err != nil {
+		return nil, fmt.Errorf("parse yaml: %v", err)
+	}
+	var v patternYAML
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("parse yaml: %v", err)
+	}
+	return v.pattern()
+}
@@ -0,0 +1,73 @@
+package drum
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestYAML(t *testing.T) {
+	p, err := DecodeFile(path.Join("fixtures", "pattern_2.splice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.tracks[0].SetVelocity(0, 64)
+	p.tracks[1].SetProbability(4, 50)
+	p.tracks[2].SetPan(-10)
+	p.SetMetadata(Metadata{Title: "yes", Tags: []string{"four on the floor"}})
+	var buf bytes.Buffer
+	if err := p.ToYAML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range []string{
+		"version: 0.808-alpha\ntempo: 98.4\n",
+		"  title: \"yes\"\n  tags: [four on the floor]\n",
+		"  - id: 0\n    name: kick\n    steps: x-------x-------\n    velocity: [64, 127,",
+		"    probability: [100, 100, 100, 100, 50, 100,",
+		"  - id: 3\n    name: hh-open\n    steps: --x---x-x-x---x-\n    pan: -10\n",
+	} {
+		if !strings.Contains(buf.String(), exp) {
+			t.Errorf("Expected '%v' in:\n%v", exp, buf.String())
+		}
+	}
+	got, err := FromYAML(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(p) || got.Metadata().Title != "yes" {
+		t.Errorf("Expected the pattern back but got %v", Diff(p, got))
+	}
+
+	// written by hand or other tools
+	doc := `# a groove
+version: "0.909"
+tempo: 120
+tracks:
+- {id: 1, name: Kick, steps: "x---x---x---x---"}
+- id: 2
+  name: 'Hi Hat'   # closed
+  steps: x-x-x-x-x-x-x-x-
+  velocity: [100, 80, 100, 80, 100, 80, 100, 80, 100, 80, 100, 80, 100, 80, 100, 80]
+`
+	got, err = FromYAML(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "Saved with HW Version: 0.909\nTempo: 120\n(1) Kick\t|x---|x---|x---|x---|\n(2) Hi Hat\t|x-x-|x-x-|x-x-|x-x-|\n"
+	if got.String() != exp || got.tracks[1].Velocity(1) != 80 {
+		t.Errorf("Expected '%v' but got '%v'", exp, got)
+	}
+
+	for doc, exp := range map[string]string{
+		"version: x\ntempo: -1\ntracks: []\n":                              ErrInvalidTempo.Error(),
+		"version: x\ntempo: 120\ntracks:\n- id: 1\n  steps: x\n":           "expected 16 steps",
+		"version: x\ntempo: 120\ntracks:\n- id: 1\n  velocity: [300]\n":    "velocity 300 out of range",
+		"version: x\ntempo: 120\ntracks: [\n":                              "parse yaml",
+		"version: x\ntempo: 120\ntracks:\n- id: 1\n  steps: |\n    x---\n": "unsupported",
+	} {
+		if _, err := FromYAML(strings.NewReader(doc)); err == nil || !strings.Contains(err.Error(), exp) {
+			t.Errorf("Expected an error with '%v' but got %v", exp, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package drum
+
+import "fmt"
+
+// Resolution is the number of grid cells per bar a step grid is shown with.
+type Resolution int
+
+// Supported zoom levels. Sixteenth is the native resolution of Steps.
+const (
+	Eighth       Resolution = 8
+	Sixteenth    Resolution = stepsLength
+	ThirtySecond Resolution = 32
+)
+
+func (r Resolution) String() string {
+	return fmt.Sprintf("1/%d", int(r))
+}
+
+// ZoomIn returns the next finer resolution or r when it is the finest one.
+func (r Resolution) ZoomIn() Resolution {
+	if r < ThirtySecond {
+		return r * 2
+	}
+	return r
+}
+
+// ZoomOut returns the next coarser resolution or r when it is the coarsest one.
+func (r Resolution) ZoomOut() Resolution {
+	if r > Eighth {
+		return r / 2
+	}
+	return r
+}
+
+// orDefault returns Sixteenth for resolutions not set.
+func (r Resolution) orDefault() Resolution {
+	if r <= 0 {
+		return Sixteenth
+	}
+	return r
+}
+
+// Cell returns the grid cell showing the step in the resolution.
+func (r Resolution) Cell(step int) int {
+	return step * int(r.orDefault()) / stepsLength
+}
+
+// Zoom returns the steps as grid of the given resolution for display.
+// Zooming out aggregates neighbouring steps into one cell which is enabled
+// when any of them is. Zooming in expands every step into multiple cells of
+// which only the first one can be enabled.
+func (s Steps) Zoom(r Resolution) []bool {
+	return zoomSteps(s[:], r)
+}
+
+// ZoomBars returns the steps of the track as grid of the given resolution,
+// one row per bar of the pattern, see Steps.Zoom. A row shows the steps
+// played before the track loops, which are fewer than 16 for tracks with
+// their own length or bars of other time signatures.
+func (p *Pattern) ZoomBars(t *Track, r Resolution) [][]bool {
+	rows := make([][]bool, p.Bars())
+	for bar := range rows {
+		rows[bar] = zoomSteps(t.barSteps(bar)[:p.trackLength(t)], r)
+	}
+	return rows
+}
+
+// zoomSteps returns the steps as cells of the resolution. The last cell
+// covers the remaining steps when they do not fill it.
+func zoomSteps(steps []bool, r Resolution) []bool {
+	r = r.orDefault()
+	cells := make([]bool, (len(steps)*int(r)+stepsLength-1)/stepsLength)
+	for i, enabled := range steps {
+		if enabled {
+			cells[r.Cell(i)] = true
+		}
+	}
+	return cells
+}
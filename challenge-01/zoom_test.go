@@ -0,0 +1,83 @@
+package drum
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStepsZoom(t *testing.T) {
+	s := Steps{true, false, false, true, false, false, false, false, true}
+	testCases := []struct {
+		r   Resolution
+		exp string
+	}{
+		{Eighth, "xx--x---"},
+		{Sixteenth, "x--x----x-------"},
+		{ThirtySecond, "x-----x---------x---------------"},
+	}
+	for _, testCase := range testCases {
+		var got []byte
+		for _, enabled := range s.Zoom(testCase.r) {
+			if enabled {
+				got = append(got, symbolStepEnabled)
+			} else {
+				got = append(got, symbolStepDisabled)
+			}
+		}
+		if string(got) != testCase.exp {
+			t.Errorf("Expected '%v' but got '%v' for resolution %v", testCase.exp, string(got), testCase.r)
+		}
+	}
+}
+
+func TestResolutionZoom(t *testing.T) {
+	if got := Eighth.ZoomOut(); got != Eighth {
+		t.Errorf("Expected %v but got %v", Eighth, got)
+	}
+	if got := Eighth.ZoomIn().ZoomIn(); got != ThirtySecond {
+		t.Errorf("Expected %v but got %v", ThirtySecond, got)
+	}
+	if got := ThirtySecond.ZoomIn(); got != ThirtySecond {
+		t.Errorf("Expected %v but got %v", ThirtySecond, got)
+	}
+}
+
+func TestZoomBars(t *testing.T) {
+	kick, _ := NewTrack(0, "kick", Steps{true, false, false, true, false, false, false, false, true})
+	clave, _ := NewTrack(1, "clave", Steps{true, false, false, true, false, false, true, false, false, false, true, false, true})
+	clave.SetLength(12)
+	p, _ := NewPattern("", 120, kick, clave)
+	p.AddBar(0)
+	kick.SetBarStep(1, 15, true)
+	testCases := []struct {
+		track *Track
+		r     Resolution
+		exp   string
+	}{
+		{kick, Eighth, "xx--x--- xx--x--x"},
+		{kick, ThirtySecond, "x-----x---------x--------------- x-----x---------x-------------x-"},
+		{clave, Eighth, "xx-x-x xx-x-x"},
+		{clave, Sixteenth, "x--x--x---x- x--x--x---x-"},
+	}
+	for _, testCase := range testCases {
+		var rows []string
+		for _, cells := range p.ZoomBars(testCase.track, testCase.r) {
+			row := make([]byte, len(cells))
+			for i, enabled := range cells {
+				row[i] = symbolStepDisabled
+				if enabled {
+					row[i] = symbolStepEnabled
+				}
+			}
+			rows = append(rows, string(row))
+		}
+		if got := strings.Join(rows, " "); got != testCase.exp {
+			t.Errorf("%s at %v: Expected '%v' but got '%v'", testCase.track.name, testCase.r, testCase.exp, got)
+		}
+	}
+
+	p.SetTimeSignature(TimeSignature{7, 8})
+	if got := p.ZoomBars(kick, Eighth); len(got[0]) != 7 {
+		t.Errorf("Expected 7 cells of a 7/8 bar but got %d", len(got[0]))
+	}
+}